@@ -1,7 +1,10 @@
 package fastjson
 
 import (
+	"fmt"
+	"sort"
 	"strconv"
+	"unsafe"
 )
 
 // Arena may be used for fast creation and re-use of Values.
@@ -18,6 +21,26 @@ import (
 type Arena struct {
 	b []byte
 	c cache
+
+	// kvs is a chunk for object entries allocated via NewObjectCapacity.
+	kvs []kv
+
+	// kvsAllocated is the number of entries allocated via getKVs
+	// since the last Reset call.
+	kvsAllocated int
+
+	// checkpointID is the id of the last checkpoint returned from Checkpoint.
+	checkpointID uint64
+
+	// checkpoints contains ranges of ids for valid checkpoints
+	// sorted by ids.
+	checkpoints []checkpointRange
+}
+
+// checkpointRange is a range of consecutive ids for valid checkpoints.
+type checkpointRange struct {
+	min uint64
+	max uint64
 }
 
 // Reset resets all the Values allocated by a.
@@ -26,6 +49,198 @@ type Arena struct {
 func (a *Arena) Reset() {
 	a.b = a.b[:0]
 	a.c.reset()
+	a.kvsAllocated = 0
+	a.checkpoints = a.checkpoints[:0]
+}
+
+// ArenaCheckpoint is a checkpoint for Arena.ResetTo.
+//
+// It is returned by Arena.Checkpoint.
+type ArenaCheckpoint struct {
+	a            *Arena
+	id           uint64
+	bLen         int
+	vsLen        int
+	kvsAllocated int
+}
+
+// Checkpoint returns a checkpoint for the current state of a.
+//
+// Pass the checkpoint to ResetTo for resetting Values allocated by a
+// after the checkpoint.
+func (a *Arena) Checkpoint() ArenaCheckpoint {
+	a.checkpointID++
+	id := a.checkpointID
+	if n := len(a.checkpoints); n > 0 && a.checkpoints[n-1].max+1 == id {
+		a.checkpoints[n-1].max = id
+	} else {
+		a.checkpoints = append(a.checkpoints, checkpointRange{
+			min: id,
+			max: id,
+		})
+	}
+	return ArenaCheckpoint{
+		a:            a,
+		id:           id,
+		bLen:         len(a.b),
+		vsLen:        len(a.c.vs),
+		kvsAllocated: a.kvsAllocated,
+	}
+}
+
+// ResetTo resets all the Values allocated by a after the given checkpoint.
+//
+// Values allocated before the checkpoint remain valid, while Values allocated
+// after the checkpoint cannot be used after the ResetTo call. Make sure
+// Values allocated before the checkpoint don't refer to Values allocated
+// after it, e.g. via Set calls.
+//
+// ResetTo may be called multiple times with the same checkpoint.
+// Checkpoints created after cp become invalid after the call.
+//
+// ResetTo panics if cp has been created by another Arena or if cp
+// has been invalidated by Reset.
+func (a *Arena) ResetTo(cp ArenaCheckpoint) {
+	if cp.a != a {
+		panic(fmt.Errorf("ArenaCheckpoint passed to Arena.ResetTo has been created by another Arena"))
+	}
+	cps := a.checkpoints
+	n := sort.Search(len(cps), func(i int) bool {
+		return cps[i].max >= cp.id
+	})
+	if n == len(cps) || cps[n].min > cp.id {
+		panic(fmt.Errorf("ArenaCheckpoint passed to Arena.ResetTo is stale; it has been invalidated by Reset or ResetTo call"))
+	}
+	// Invalidate checkpoints created after cp.
+	cps[n].max = cp.id
+	a.checkpoints = cps[:n+1]
+
+	a.b = a.b[:cp.bLen]
+	a.c.vs = a.c.vs[:cp.vsLen]
+	// The chunk for object entries isn't rolled back, since the entries
+	// may be still referred by the reset values, which keep them for re-use.
+	a.kvsAllocated = cp.kvsAllocated
+}
+
+// AllocatedBytes returns the number of bytes occupied by Values allocated by a
+// since the last Reset call.
+//
+// It includes strings, Values and object entries allocated via
+// NewObjectCapacity and similar constructors. Entries appended to objects
+// beyond their capacity aren't included.
+//
+// Unlike MemoryFootprint, it doesn't include the memory retained by a
+// for future allocations, so it may be used for limiting the size
+// of Values allocated by a.
+func (a *Arena) AllocatedBytes() int {
+	return len(a.b) + len(a.c.vs)*int(unsafe.Sizeof(Value{})) + a.kvsAllocated*int(unsafe.Sizeof(kv{}))
+}
+
+// MemoryFootprint returns the approximate number of bytes retained by a.
+//
+// It includes the capacities of the buffer for strings, the value cache
+// and the current chunk for object entries. Object entries outside
+// the current chunk aren't included. These are the previous chunks still
+// referred by the cached Values, and entries appended to objects beyond
+// their capacity.
+//
+// The returned value may be used for metrics. It is also used by ArenaPool
+// created via NewArenaPool for dropping oversized arenas.
+func (a *Arena) MemoryFootprint() int {
+	return cap(a.b) + a.c.memoryFootprint() + cap(a.kvs)*int(unsafe.Sizeof(kv{}))
+}
+
+// BufferCap returns the capacity in bytes of the internal buffer,
+// which holds strings allocated by a.
+//
+// The returned value may be exported as a gauge metric.
+func (a *Arena) BufferCap() int {
+	return cap(a.b)
+}
+
+// ValueCacheCap returns the number of Values a may allocate
+// without additional memory allocations.
+//
+// The returned value may be exported as a gauge metric.
+func (a *Arena) ValueCacheCap() int {
+	return cap(a.c.vs)
+}
+
+// Parse parses s containing JSON.
+//
+// All the Values and strings for the parsed JSON are allocated from a,
+// so the returned value may be freely mixed with other Values created by a.
+//
+// The returned value is valid until Reset is called on a.
+// The returned error is *ParseError.
+func (a *Arena) Parse(s string) (*Value, error) {
+	sOrig := s
+	s = skipWS(skipBOM(s))
+	bLen := len(a.b)
+	a.b = append(a.b, s...)
+
+	v, tail, err := parseValue(b2s(a.b[bLen:]), &a.c, 0)
+	if err != nil {
+		return nil, newParseError(sOrig, tail, fmt.Sprintf("cannot parse JSON: %s; unparsed tail: %q", err, startEndString(tail)))
+	}
+	tail = skipWS(tail)
+	if len(tail) > 0 {
+		return nil, newParseError(sOrig, tail, fmt.Sprintf("unexpected tail: %q", startEndString(tail)))
+	}
+	return v, nil
+}
+
+// ParseBytes parses b containing JSON.
+//
+// All the Values and strings for the parsed JSON are allocated from a,
+// so the returned value may be freely mixed with other Values created by a.
+//
+// The returned value is valid until Reset is called on a.
+func (a *Arena) ParseBytes(b []byte) (*Value, error) {
+	return a.Parse(b2s(b))
+}
+
+// deepCopy returns a copy of v, which is entirely allocated from a.
+//
+// Strings and object keys in v are unescaped before copying.
+func (a *Arena) deepCopy(v *Value) *Value {
+	switch v.Type() {
+	case TypeObject:
+		v.o.unescapeKeys()
+		vc := a.NewObject()
+		vc.o.keysInterned = v.o.keysInterned
+		for _, kv := range v.o.kvs {
+			kvc := vc.o.getKV()
+			if v.o.keysInterned {
+				// Interned keys are immutable, so they may be shared.
+				kvc.k = kv.k
+			} else {
+				kvc.k = a.copyString(kv.k)
+			}
+			kvc.v = a.deepCopy(kv.v)
+		}
+		return vc
+	case TypeArray:
+		vc := a.NewArray()
+		for _, vv := range v.a {
+			vc.a = append(vc.a, a.deepCopy(vv))
+		}
+		return vc
+	case TypeString, TypeNumber:
+		vc := a.c.getValue()
+		vc.t = v.t
+		vc.s = a.copyString(v.s)
+		return vc
+	default:
+		// null, true and false values are immutable, so they may be shared.
+		return v
+	}
+}
+
+func (a *Arena) copyString(s string) string {
+	bLen := len(a.b)
+	a.b = append(a.b, s...)
+	return b2s(a.b[bLen:])
 }
 
 // NewObject returns new empty object value.
@@ -37,9 +252,50 @@ func (a *Arena) NewObject() *Value {
 	v := a.c.getValue()
 	v.t = TypeObject
 	v.o.reset()
+	// Keys for programmatically constructed objects are always stored
+	// in unescaped form, so they are escaped by MarshalTo.
+	v.o.keysUnescaped = true
+	return v
+}
+
+// NewObjectCapacity returns new empty object value with the capacity
+// for n entries.
+//
+// The entries are allocated from a, so up to n entries may be added
+// to the returned object via Set calls without additional memory allocations.
+//
+// The returned object is valid until Reset is called on a.
+func (a *Arena) NewObjectCapacity(n int) *Value {
+	v := a.NewObject()
+	if cap(v.o.kvs) < n {
+		v.o.kvs = a.getKVs(n)
+	}
 	return v
 }
 
+// minKVsChunkLen is the minimum number of entries in the chunk allocated by Arena.getKVs.
+const minKVsChunkLen = 256
+
+// getKVs returns empty kvs with the capacity n.
+func (a *Arena) getKVs(n int) []kv {
+	if cap(a.kvs)-len(a.kvs) < n {
+		// Allocate new chunk. The previous chunk cannot be re-used,
+		// since its parts may be still referred by objects in a.c,
+		// which are re-used after Reset.
+		chunkLen := minKVsChunkLen
+		if n > chunkLen {
+			chunkLen = n
+		}
+		a.kvs = make([]kv, 0, chunkLen)
+	}
+	a.kvsAllocated += n
+	bLen := len(a.kvs)
+	a.kvs = a.kvs[:bLen+n]
+	// Limit the capacity, so appending to the returned kvs
+	// doesn't overwrite the kvs returned by subsequent calls.
+	return a.kvs[bLen : bLen : bLen+n]
+}
+
 // NewArray returns new empty array value.
 //
 // New entries may be added to the returned array via Set* calls.
@@ -52,6 +308,94 @@ func (a *Arena) NewArray() *Value {
 	return v
 }
 
+// NewObjectFromMap returns new object value containing entries from m.
+//
+// The entries are sorted by keys, so the result doesn't depend
+// on the map iteration order. nil values in m are stored as null.
+//
+// This is faster than adding entries one-by-one via Set call, since
+// NewObjectFromMap doesn't check for duplicate keys.
+//
+// The returned object is valid until Reset is called on a.
+func (a *Arena) NewObjectFromMap(m map[string]*Value) *Value {
+	v := a.NewObject()
+	if len(m) == 0 {
+		return v
+	}
+	if cap(v.o.kvs) < len(m) {
+		v.o.kvs = a.getKVs(len(m))
+	}
+	for k, vv := range m {
+		if vv == nil {
+			vv = valueNull
+		}
+		v.o.kvs = append(v.o.kvs, kv{
+			k: k,
+			v: vv,
+		})
+	}
+	sort.Sort(kvsByKey(v.o.kvs))
+	return v
+}
+
+type kvsByKey []kv
+
+func (kvs kvsByKey) Len() int           { return len(kvs) }
+func (kvs kvsByKey) Less(i, j int) bool { return kvs[i].k < kvs[j].k }
+func (kvs kvsByKey) Swap(i, j int)      { kvs[i], kvs[j] = kvs[j], kvs[i] }
+
+// NewArrayFromStrings returns new array value containing string values from ss.
+//
+// The returned array is valid until Reset is called on a.
+func (a *Arena) NewArrayFromStrings(ss []string) *Value {
+	v := a.NewArrayCapacity(len(ss))
+	for _, s := range ss {
+		v.a = append(v.a, a.NewString(s))
+	}
+	return v
+}
+
+// NewArrayFromInts returns new array value containing number values from ns.
+//
+// The returned array is valid until Reset is called on a.
+func (a *Arena) NewArrayFromInts(ns []int64) *Value {
+	v := a.NewArrayCapacity(len(ns))
+	for _, n := range ns {
+		vv := a.c.getValue()
+		vv.t = TypeNumber
+		bLen := len(a.b)
+		a.b = appendInt(a.b, n)
+		vv.s = b2s(a.b[bLen:])
+		v.a = append(v.a, vv)
+	}
+	return v
+}
+
+// NewArrayFromFloats returns new array value containing number values from fs.
+//
+// The returned array is valid until Reset is called on a.
+func (a *Arena) NewArrayFromFloats(fs []float64) *Value {
+	v := a.NewArrayCapacity(len(fs))
+	for _, f := range fs {
+		v.a = append(v.a, a.NewNumberFloat64(f))
+	}
+	return v
+}
+
+// NewArrayCapacity returns new empty array value with the capacity
+// for n items.
+//
+// Up to n items may be added to the returned array via SetArrayItem calls
+// without re-allocating the underlying slice. This is useful for building
+// big arrays. Use Value.GrowArray for reserving the capacity later.
+//
+// The returned array is valid until Reset is called on a.
+func (a *Arena) NewArrayCapacity(n int) *Value {
+	v := a.NewArray()
+	v.GrowArray(n)
+	return v
+}
+
 // NewString returns new string value containing s.
 //
 // The returned string is valid until Reset is called on a.
@@ -78,6 +422,9 @@ func (a *Arena) NewStringBytes(b []byte) *Value {
 
 // NewNumberFloat64 returns new number value containing f.
 //
+// NaN and Inf values are marshaled as NaN, +Inf and -Inf, which aren't
+// valid JSON, though they are accepted by Parser.
+//
 // The returned number is valid until Reset is called on a.
 func (a *Arena) NewNumberFloat64(f float64) *Value {
 	v := a.c.getValue()
@@ -95,13 +442,40 @@ func (a *Arena) NewNumberInt(n int) *Value {
 	v := a.c.getValue()
 	v.t = TypeNumber
 	bLen := len(a.b)
-	a.b = strconv.AppendInt(a.b, int64(n), 10)
+	a.b = appendInt(a.b, int64(n))
+	v.s = b2s(a.b[bLen:])
+	return v
+}
+
+// NewNumberInt64 returns new number value containing n.
+//
+// The returned number is valid until Reset is called on a.
+func (a *Arena) NewNumberInt64(n int64) *Value {
+	v := a.c.getValue()
+	v.t = TypeNumber
+	bLen := len(a.b)
+	a.b = appendInt(a.b, n)
+	v.s = b2s(a.b[bLen:])
+	return v
+}
+
+// NewNumberUint64 returns new number value containing n.
+//
+// The returned number is valid until Reset is called on a.
+func (a *Arena) NewNumberUint64(n uint64) *Value {
+	v := a.c.getValue()
+	v.t = TypeNumber
+	bLen := len(a.b)
+	a.b = appendUint(a.b, n)
 	v.s = b2s(a.b[bLen:])
 	return v
 }
 
 // NewNumberString returns new number value containing s.
 //
+// s isn't validated, so the marshaled value may be invalid JSON if s
+// isn't a valid JSON number. Use NewNumberStringErr for untrusted s.
+//
 // The returned number is valid until Reset is called on a.
 func (a *Arena) NewNumberString(s string) *Value {
 	v := a.c.getValue()
@@ -110,6 +484,22 @@ func (a *Arena) NewNumberString(s string) *Value {
 	return v
 }
 
+// NewNumberStringErr returns new number value containing s.
+//
+// Unlike NewNumberString, it returns an error if s isn't a valid JSON number.
+//
+// The returned number is valid until Reset is called on a.
+func (a *Arena) NewNumberStringErr(s string) (*Value, error) {
+	tail, err := validateNumber(s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse number %q: %s", s, err)
+	}
+	if len(tail) > 0 {
+		return nil, fmt.Errorf("unexpected tail in number %q: %q", s, tail)
+	}
+	return a.NewNumberString(s), nil
+}
+
 // NewNull returns null value.
 func (a *Arena) NewNull() *Value {
 	return valueNull
@@ -1,9 +1,16 @@
 package fastjson
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
+	"unsafe"
 )
 
 func TestArena(t *testing.T) {
@@ -75,3 +82,740 @@ func testArena(a *Arena) error {
 	}
 	return nil
 }
+
+func TestArenaParse(t *testing.T) {
+	var a Arena
+	var p Parser
+
+	o := a.NewObject()
+	v, err := a.Parse(`{"foo": "bar\nbaz", "x": [1, 2]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	o.Set("first", v)
+	v, err = a.ParseBytes([]byte(` ["qwe", {"y": null}] `))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	o.Set("second", v)
+	o.Set("third", a.NewString("xx"))
+
+	// Parsing with an unrelated Parser mustn't break the values allocated by a.
+	if _, err := p.Parse(`{"foo":"aaaaaaaaaaaaaaaaaaaaaaa","x":[3,4,5,6,7,8,9]}`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	str := o.String()
+	strExpected := `{"first":{"foo":"bar\nbaz","x":[1,2]},"second":["qwe",{"y":null}],"third":"xx"}`
+	if str != strExpected {
+		t.Fatalf("unexpected json\ngot\n%s\nwant\n%s", str, strExpected)
+	}
+	if s := o.Get("first", "foo").GetStringBytes(); string(s) != "bar\nbaz" {
+		t.Fatalf("unexpected string; got %q; want %q", s, "bar\nbaz")
+	}
+
+	// Invalid JSON
+	if _, err := a.Parse(`{"foo"`); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	if _, err := a.Parse(`[1] tail`); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
+
+func TestArenaPoolMaxRetainedBytes(t *testing.T) {
+	ap := NewArenaPool(1024)
+
+	a := ap.Get()
+	aa := a.NewArray()
+	for i := 0; i < 1000; i++ {
+		aa.SetArrayItem(i, a.NewString("foobar"))
+	}
+	if n := a.MemoryFootprint(); n <= 1024 {
+		t.Fatalf("too small memory footprint for the arena: %d bytes", n)
+	}
+	ap.Put(a)
+	for i := 0; i < 10; i++ {
+		if ap.Get() == a {
+			t.Fatalf("the oversized arena mustn't be retained by the pool")
+		}
+	}
+}
+
+func TestArenaPoolMaxRetainedBytesObjectEntries(t *testing.T) {
+	ap := NewArenaPool(64 * 1024)
+
+	// Object entries must be included in the memory footprint.
+	a := ap.Get()
+	a.NewObjectCapacity(10000)
+	if n, nMin := a.MemoryFootprint(), 10000*int(unsafe.Sizeof(kv{})); n < nMin {
+		t.Fatalf("too small memory footprint for the arena; got %d bytes; want at least %d bytes", n, nMin)
+	}
+	ap.Put(a)
+	for i := 0; i < 10; i++ {
+		if ap.Get() == a {
+			t.Fatalf("the arena with oversized chunk for object entries mustn't be retained by the pool")
+		}
+	}
+}
+
+func TestArenaPoolStats(t *testing.T) {
+	var apZero ArenaPool
+	apZero.Put(apZero.Get())
+	if as := apZero.Stats(); as != (PoolStats{}) {
+		t.Fatalf("unexpected stats for the zero value pool; got %+v; want zero stats", as)
+	}
+
+	ap := NewArenaPoolWithStats(1024)
+	a1 := ap.Get()
+	a2 := ap.Get()
+	a1.NewString("foo")
+	aa := a2.NewArray()
+	for i := 0; i < 1000; i++ {
+		aa.SetArrayItem(i, a2.NewString("foobar"))
+	}
+	if n := a2.BufferCap(); n < 6000 {
+		t.Fatalf("too small BufferCap; got %d; want at least %d", n, 6000)
+	}
+	if n := a2.ValueCacheCap(); n < 1001 {
+		t.Fatalf("too small ValueCacheCap; got %d; want at least %d", n, 1001)
+	}
+	ap.Put(a1)
+	ap.Put(a2)
+	as := ap.Stats()
+	asExpected := PoolStats{
+		Gets:     2,
+		Puts:     2,
+		News:     2,
+		Discards: 1,
+	}
+	if as != asExpected {
+		t.Fatalf("unexpected stats; got %+v; want %+v", as, asExpected)
+	}
+}
+
+func TestArenaNewObjectCapacity(t *testing.T) {
+	var a Arena
+	for i := 0; i < 3; i++ {
+		o1 := a.NewObjectCapacity(2)
+		o2 := a.NewObjectCapacity(300)
+		o3 := a.NewObjectCapacity(2)
+
+		// Exceed the capacity for o1 - this mustn't corrupt o3.
+		for j := 0; j < 5; j++ {
+			o1.Set(fmt.Sprintf("a%d", j), a.NewNumberInt(j))
+		}
+		o2.Set("b", a.NewTrue())
+		o3.Set("c", a.NewNull())
+		o3.Set("d", o2)
+
+		str := o1.String()
+		strExpected := `{"a0":0,"a1":1,"a2":2,"a3":3,"a4":4}`
+		if str != strExpected {
+			t.Fatalf("unexpected json\ngot\n%s\nwant\n%s", str, strExpected)
+		}
+		str = o3.String()
+		strExpected = `{"c":null,"d":{"b":true}}`
+		if str != strExpected {
+			t.Fatalf("unexpected json\ngot\n%s\nwant\n%s", str, strExpected)
+		}
+		a.Reset()
+	}
+}
+
+func TestArenaNewArrayCapacity(t *testing.T) {
+	var a Arena
+	for i := 0; i < 3; i++ {
+		// Fill arrays with values, so the subsequent iterations may detect
+		// stale values after Reset.
+		v1 := a.NewArrayCapacity(10)
+		if n := cap(v1.a); n < 10 {
+			t.Fatalf("unexpected capacity; got %d; want at least 10", n)
+		}
+		v2 := a.NewArray()
+		for j := 0; j < 10; j++ {
+			if j >= i {
+				v1.SetArrayItem(j, a.NewNumberInt(j))
+			}
+			v2.SetArrayItem(j, a.NewString("x"))
+		}
+		str := v1.String()
+		strExpected := `[` + strings.Repeat(`null,`, i) + strings.Join([]string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}[i:], ",") + `]`
+		if str != strExpected {
+			t.Fatalf("unexpected json\ngot\n%s\nwant\n%s", str, strExpected)
+		}
+
+		// Reserved capacity must be used without re-allocation.
+		v2.GrowArray(100)
+		if n := cap(v2.a); n < 110 {
+			t.Fatalf("unexpected capacity after GrowArray; got %d; want at least 110", n)
+		}
+		p := &v2.a[0]
+		v2.SetArrayItem(109, a.NewTrue())
+		if &v2.a[0] != p {
+			t.Fatalf("SetArrayItem mustn't re-allocate the reserved capacity")
+		}
+		str = v2.String()
+		strExpected = `[` + strings.Repeat(`"x",`, 10) + strings.Repeat(`null,`, 99) + `true]`
+		if str != strExpected {
+			t.Fatalf("unexpected json\ngot\n%s\nwant\n%s", str, strExpected)
+		}
+
+		// Items between the previous length and idx must be null,
+		// even if the capacity contains stale values.
+		v3 := a.NewArrayCapacity(5)
+		v3.SetArrayItem(3, a.NewFalse())
+		if str := v3.String(); str != `[null,null,null,false]` {
+			t.Fatalf("unexpected json\ngot\n%s\nwant\n%s", str, `[null,null,null,false]`)
+		}
+		a.Reset()
+	}
+
+	// GrowArray must be no-op for non-arrays.
+	v := a.NewObject()
+	c := cap(v.a)
+	v.GrowArray(10)
+	if cap(v.a) != c {
+		t.Fatalf("GrowArray mustn't allocate items for objects")
+	}
+	var vNil *Value
+	vNil.GrowArray(10)
+}
+
+func TestSetArrayItemPreSized(t *testing.T) {
+	const n = 1000
+	var a Arena
+	v1 := a.NewArray()
+	v2 := a.NewArrayCapacity(n)
+	v3 := a.NewArray()
+	v3.GrowArray(n)
+	for i := 0; i < n; i++ {
+		item := a.NewNumberInt(i)
+		v1.SetArrayItem(i, item)
+		v2.SetArrayItem(i, item)
+		v3.SetArrayItem(n-1-i, a.NewNumberInt(n-1-i))
+	}
+	data := v1.MarshalTo(nil)
+	if data2 := v2.MarshalTo(nil); string(data2) != string(data) {
+		t.Fatalf("unexpected json for pre-sized array\ngot\n%s\nwant\n%s", data2, data)
+	}
+	if data3 := v3.MarshalTo(nil); string(data3) != string(data) {
+		t.Fatalf("unexpected json for the array filled in reverse order\ngot\n%s\nwant\n%s", data3, data)
+	}
+	if c := cap(v2.a); c != n {
+		t.Fatalf("unexpected capacity for pre-sized array; got %d; want %d", c, n)
+	}
+}
+
+func TestArenaBulkConstructors(t *testing.T) {
+	var a Arena
+	f := func(v *Value, strExpected string) {
+		t.Helper()
+		str := string(v.MarshalTo(nil))
+		if str != strExpected {
+			t.Fatalf("unexpected json\ngot\n%s\nwant\n%s", str, strExpected)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		o := a.NewObjectFromMap(map[string]*Value{
+			"foo":   a.NewArrayFromStrings([]string{"a", "b\"c", ""}),
+			"bar":   a.NewArrayFromInts([]int64{1, -2, 9223372036854775807}),
+			"baz":   a.NewArrayFromFloats([]float64{1.5, -0.25, 1e100}),
+			"a\nb":  nil,
+			"empty": a.NewObjectFromMap(nil),
+		})
+		f(o, `{"a\nb":null,"bar":[1,-2,9223372036854775807],"baz":[1.5,-0.25,1e+100],"empty":{},"foo":["a","b\"c",""]}`)
+		f(a.NewArrayFromStrings(nil), `[]`)
+		f(a.NewArrayFromInts(nil), `[]`)
+		f(a.NewArrayFromFloats(nil), `[]`)
+
+		// The constructed object must be modifiable.
+		o.Set("foo", a.NewTrue())
+		o.Set("x", a.NewFalse())
+		f(o, `{"a\nb":null,"bar":[1,-2,9223372036854775807],"baz":[1.5,-0.25,1e+100],"empty":{},"foo":true,"x":false}`)
+		if sb := o.GetStringBytes("a\nb"); sb != nil {
+			t.Fatalf("unexpected non-nil string: %q", sb)
+		}
+		if n := o.GetInt("bar", "1"); n != -2 {
+			t.Fatalf("unexpected number; got %d; want %d", n, -2)
+		}
+
+		// Reset must invalidate all the values, so they are re-used.
+		a.Reset()
+		v := a.NewArrayFromInts([]int64{42})
+		a.Reset()
+		vNew := a.NewArrayFromStrings([]string{"x"})
+		if v != vNew {
+			t.Fatalf("expecting re-used value after Reset")
+		}
+		f(vNew, `["x"]`)
+		a.Reset()
+	}
+}
+
+func TestArenaNewNumber64(t *testing.T) {
+	var a Arena
+	var p Parser
+	fInt := func(n int64, strExpected string) {
+		t.Helper()
+		v := a.NewNumberInt64(n)
+		str := string(v.MarshalTo(nil))
+		if str != strExpected {
+			t.Fatalf("unexpected json; got %s; want %s", str, strExpected)
+		}
+		vp, err := p.Parse(str)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", str, err)
+		}
+		nn, err := vp.Int64()
+		if err != nil {
+			t.Fatalf("cannot obtain int64 from %s: %s", str, err)
+		}
+		if nn != n {
+			t.Fatalf("unexpected int64; got %d; want %d", nn, n)
+		}
+	}
+	fUint := func(n uint64, strExpected string) {
+		t.Helper()
+		v := a.NewNumberUint64(n)
+		str := string(v.MarshalTo(nil))
+		if str != strExpected {
+			t.Fatalf("unexpected json; got %s; want %s", str, strExpected)
+		}
+		vp, err := p.Parse(str)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", str, err)
+		}
+		nn, err := vp.Uint64()
+		if err != nil {
+			t.Fatalf("cannot obtain uint64 from %s: %s", str, err)
+		}
+		if nn != n {
+			t.Fatalf("unexpected uint64; got %d; want %d", nn, n)
+		}
+	}
+	fInt(0, "0")
+	fInt(-1, "-1")
+	fInt(1234567890, "1234567890")
+	fInt(math.MaxInt64, "9223372036854775807")
+	fInt(math.MinInt64, "-9223372036854775808")
+	fUint(0, "0")
+	fUint(9, "9")
+	fUint(10, "10")
+	fUint(1<<63, "9223372036854775808")
+	fUint(math.MaxUint64, "18446744073709551615")
+
+	// Numbers must remain valid after creating other values in the arena.
+	a.Reset()
+	o := a.NewObject()
+	o.Set("id", a.NewNumberUint64(math.MaxUint64))
+	o.Set("n", a.NewNumberInt64(math.MinInt64))
+	o.Set("s", a.NewString("foobar"))
+	str := o.String()
+	strExpected := `{"id":18446744073709551615,"n":-9223372036854775808,"s":"foobar"}`
+	if str != strExpected {
+		t.Fatalf("unexpected json\ngot\n%s\nwant\n%s", str, strExpected)
+	}
+}
+
+func TestArenaCheckpoint(t *testing.T) {
+	var a Arena
+	f := func(v *Value, strExpected string) {
+		t.Helper()
+		str := string(v.MarshalTo(nil))
+		if str != strExpected {
+			t.Fatalf("unexpected json\ngot\n%s\nwant\n%s", str, strExpected)
+		}
+	}
+
+	if n := a.AllocatedBytes(); n != 0 {
+		t.Fatalf("unexpected AllocatedBytes for empty arena; got %d; want 0", n)
+	}
+	header := a.NewObject()
+	header.Set("name", a.NewString("header"))
+	header.Set("items", a.NewArrayFromInts([]int64{1, 2, 3}))
+	headerStr := `{"name":"header","items":[1,2,3]}`
+	cp := a.Checkpoint()
+	cpBytes := a.AllocatedBytes()
+	if cpBytes <= 0 {
+		t.Fatalf("unexpected AllocatedBytes after creating values; got %d", cpBytes)
+	}
+
+	for i := 0; i < 3; i++ {
+		doc := a.NewObject()
+		doc.Set("id", a.NewNumberInt(i))
+		doc.Set("body", a.NewString(strings.Repeat("x", 100)))
+		v, err := a.Parse(`{"foo":[1,"bar",{"baz":null}]}`)
+		if err != nil {
+			t.Fatalf("cannot parse JSON: %s", err)
+		}
+		doc.Set("parsed", v)
+		f(doc, fmt.Sprintf(`{"id":%d,"body":"%s","parsed":{"foo":[1,"bar",{"baz":null}]}}`, i, strings.Repeat("x", 100)))
+		if n := a.AllocatedBytes(); n <= cpBytes {
+			t.Fatalf("AllocatedBytes must grow after creating values; got %d; want more than %d", n, cpBytes)
+		}
+
+		a.ResetTo(cp)
+		if n := a.AllocatedBytes(); n != cpBytes {
+			t.Fatalf("unexpected AllocatedBytes after ResetTo; got %d; want %d", n, cpBytes)
+		}
+
+		// Values created before the checkpoint must remain valid.
+		f(header, headerStr)
+	}
+
+	// Values created after ResetTo must re-use the memory.
+	v1 := a.NewString("foo")
+	a.ResetTo(cp)
+	v2 := a.NewString("bar")
+	if v1 != v2 {
+		t.Fatalf("expecting re-used value after ResetTo")
+	}
+	f(v2, `"bar"`)
+	f(header, headerStr)
+
+	// Nested checkpoints
+	cpNested := a.Checkpoint()
+	a.NewString("baz")
+	a.ResetTo(cpNested)
+	a.ResetTo(cp)
+	f(header, headerStr)
+
+	a.Reset()
+	if n := a.AllocatedBytes(); n != 0 {
+		t.Fatalf("unexpected AllocatedBytes after Reset; got %d; want 0", n)
+	}
+}
+
+func TestArenaCheckpointMisuse(t *testing.T) {
+	f := func(name string, misuse func()) {
+		t.Helper()
+		defer func() {
+			t.Helper()
+			if r := recover(); r == nil {
+				t.Fatalf("expecting panic for %s", name)
+			}
+		}()
+		misuse()
+	}
+
+	var a1, a2 Arena
+	f("another arena", func() {
+		cp := a1.Checkpoint()
+		a2.ResetTo(cp)
+	})
+	f("checkpoint after Reset", func() {
+		cp := a1.Checkpoint()
+		a1.Reset()
+		a1.ResetTo(cp)
+	})
+	f("checkpoint invalidated by ResetTo", func() {
+		a1.NewString("foo")
+		cp1 := a1.Checkpoint()
+		a1.NewString("bar")
+		cp2 := a1.Checkpoint()
+		a1.ResetTo(cp1)
+		a1.ResetTo(cp2)
+	})
+	f("checkpoint invalidated by ResetTo after allocations", func() {
+		a1.Reset()
+		cp1 := a1.Checkpoint()
+		a1.NewString("foo")
+		cp2 := a1.Checkpoint()
+		a1.ResetTo(cp1)
+		a1.NewString("bar")
+		a1.NewString("baz")
+		a1.ResetTo(cp2)
+	})
+	f("checkpoint invalidated by ResetTo to the earlier checkpoint", func() {
+		a1.Reset()
+		cp1 := a1.Checkpoint()
+		cp2 := a1.Checkpoint()
+		cp3 := a1.Checkpoint()
+		a1.ResetTo(cp3)
+		a1.ResetTo(cp1)
+		a1.ResetTo(cp2)
+	})
+}
+
+func TestArenaCheckpointIDs(t *testing.T) {
+	var a Arena
+	cp1 := a.Checkpoint()
+	a.NewString("foo")
+	cp2 := a.Checkpoint()
+	a.ResetTo(cp1)
+
+	// Checkpoints created after ResetTo must remain valid.
+	a.NewString("bar")
+	cp3 := a.Checkpoint()
+	cp4 := a.Checkpoint()
+	a.NewString("baz")
+	a.ResetTo(cp4)
+	a.ResetTo(cp3)
+	a.ResetTo(cp1)
+	a.ResetTo(cp1)
+	if n := a.AllocatedBytes(); n != 0 {
+		t.Fatalf("unexpected AllocatedBytes after ResetTo; got %d; want 0", n)
+	}
+	if len(a.checkpoints) != 1 {
+		t.Fatalf("unexpected number of checkpoint ranges; got %d; want 1", len(a.checkpoints))
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expecting panic for the checkpoint invalidated by ResetTo")
+			}
+		}()
+		a.ResetTo(cp2)
+	}()
+}
+
+func TestArenaCheckpointObjectCapacity(t *testing.T) {
+	var a Arena
+	cp := a.Checkpoint()
+	cpBytes := a.AllocatedBytes()
+	for i := 0; i < 3; i++ {
+		o := a.NewObjectCapacity(100)
+		n := a.AllocatedBytes()
+		nExpected := cpBytes + int(unsafe.Sizeof(Value{}))
+		if i == 0 {
+			// The reset object keeps its entries for re-use after ResetTo,
+			// so they are allocated only once.
+			nExpected += 100 * int(unsafe.Sizeof(kv{}))
+		}
+		if n != nExpected {
+			t.Fatalf("unexpected AllocatedBytes after NewObjectCapacity; got %d; want %d", n, nExpected)
+		}
+		for j := 0; j < 100; j++ {
+			o.Set(strconv.Itoa(j), a.NewNull())
+		}
+		a.ResetTo(cp)
+		if n := a.AllocatedBytes(); n != cpBytes {
+			t.Fatalf("unexpected AllocatedBytes after ResetTo; got %d; want %d", n, cpBytes)
+		}
+	}
+	a.NewObjectCapacity(10)
+	a.Reset()
+	if n := a.AllocatedBytes(); n != 0 {
+		t.Fatalf("unexpected AllocatedBytes after Reset; got %d; want 0", n)
+	}
+}
+
+func TestArenaMarshalValidJSON(t *testing.T) {
+	ss := []string{
+		"",
+		"foo",
+		"line1\nline2",
+		"\x00\x01\x1f\x7f",
+		"quote\"back\\slash/",
+		"\xff",
+		"\xed\xa0\x80",
+		"surrogate\xed\xb0\x80\"",
+		"\xe2\x80\xa8\xe2\x80\xa9",
+		"\xf0\x9f\x98\x80",
+		"\xf3\xa0\x80\x81",
+		"<script>&amp;",
+	}
+
+	var a Arena
+	o := a.NewObject()
+	m := make(map[string]*Value)
+	for i, s := range ss {
+		o.Set(s, a.NewString(s))
+		m[s] = a.NewStringBytes([]byte(s))
+		o.GetObject().Set(fmt.Sprintf("key_%d_%s", i, s), a.NewNumberInt(i))
+	}
+	o.Set("map", a.NewObjectFromMap(m))
+	o.Set("strings", a.NewArrayFromStrings(ss))
+	o.Set("ints", a.NewArrayFromInts([]int64{0, -1, math.MinInt64, math.MaxInt64}))
+	o.Set("floats", a.NewArrayFromFloats([]float64{0, -1.5, 1e300, math.SmallestNonzeroFloat64}))
+	arr := a.NewArray()
+	arr.SetArrayItem(0, a.NewNumberInt64(math.MinInt64))
+	arr.SetArrayItem(1, a.NewNumberUint64(math.MaxUint64))
+	arr.SetArrayItem(2, a.NewNumberFloat64(-0.0))
+	arr.SetArrayItem(5, a.NewNull())
+	arr.SetArrayItem(6, a.NewTrue())
+	arr.SetArrayItem(7, a.NewFalse())
+	for _, s := range []string{"0", "-0", "1.5e-10", "123E+5"} {
+		v, err := a.NewNumberStringErr(s)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		arr.SetArrayItem(len(arr.GetArray()), v)
+	}
+	o.Set("arr", arr)
+
+	// Values unescaped by Type calls must be escaped properly too.
+	v, err := a.Parse(`{"a\u0000b":"\ud800\"","c":["\n\t"]}`)
+	if err != nil {
+		t.Fatalf("cannot parse JSON: %s", err)
+	}
+	v.Normalize()
+	o.Set("parsed", v)
+
+	data := o.MarshalTo(nil)
+	if err := ValidateBytes(data); err != nil {
+		t.Fatalf("marshaled value isn't accepted by Validate: %s\n%s", err, data)
+	}
+	if !json.Valid(data) {
+		t.Fatalf("marshaled value isn't accepted by json.Valid:\n%s", data)
+	}
+
+	// Strings must survive marshaling and parsing if they contain valid UTF-8.
+	vv, err := Parse(string(data))
+	if err != nil {
+		t.Fatalf("cannot parse marshaled value: %s", err)
+	}
+	for i, s := range ss {
+		if !utf8.ValidString(s) {
+			continue
+		}
+		if sb := vv.GetStringBytes(s); string(sb) != s {
+			t.Fatalf("unexpected string for key %q; got %q; want %q", s, sb, s)
+		}
+		if sb := vv.GetStringBytes("strings", strconv.Itoa(i)); string(sb) != s {
+			t.Fatalf("unexpected string #%d; got %q; want %q", i, sb, s)
+		}
+	}
+	if sb := vv.GetStringBytes("\ufffd"); string(sb) != "\ufffd" {
+		t.Fatalf("invalid UTF-8 must be replaced by U+FFFD; got %q", sb)
+	}
+}
+
+func TestArenaMarshalEscapedKeys(t *testing.T) {
+	keys := []string{
+		`quote"key`,
+		`back\slash`,
+		"new\nline",
+		"tab\tctrl\x01",
+		"\u043a\u043b\u044e\u0447",
+		"\xf0\x9f\x98\x80",
+	}
+
+	f := func(name string, v *Value) {
+		t.Helper()
+		data := v.MarshalTo(nil)
+		if err := ValidateBytes(data); err != nil {
+			t.Fatalf("%s: marshaled value isn't accepted by Validate: %s\n%s", name, err, data)
+		}
+		if !json.Valid(data) {
+			t.Fatalf("%s: marshaled value isn't accepted by json.Valid:\n%s", name, data)
+		}
+		if n := v.MarshalLen(); n != len(data) {
+			t.Fatalf("%s: unexpected MarshalLen; got %d; want %d", name, n, len(data))
+		}
+		var bb bytes.Buffer
+		if _, err := v.WriteTo(&bb); err != nil {
+			t.Fatalf("%s: unexpected error in WriteTo: %s", name, err)
+		}
+		if bb.String() != string(data) {
+			t.Fatalf("%s: unexpected WriteTo result; got\n%s\nwant\n%s", name, bb.Bytes(), data)
+		}
+		vv, err := ParseBytes(data)
+		if err != nil {
+			t.Fatalf("%s: cannot parse marshaled value: %s", name, err)
+		}
+		for _, k := range keys {
+			if !vv.Exists(k) {
+				t.Fatalf("%s: missing key %q in\n%s", name, k, data)
+			}
+		}
+	}
+
+	var a Arena
+
+	v := a.NewObject()
+	for i, k := range keys {
+		v.Set(k, a.NewNumberInt(i))
+	}
+	f("Set", v)
+
+	v = a.NewObjectCapacity(len(keys))
+	o := v.GetObject()
+	for i, k := range keys {
+		if err := o.SetAt(0, k, a.NewNumberInt(i)); err != nil {
+			t.Fatalf("unexpected error in SetAt: %s", err)
+		}
+	}
+	f("SetAt", v)
+
+	v = a.NewObject()
+	o = v.GetObject()
+	for i, k := range keys {
+		o.Upsert("tmp", func(existing *Value) *Value { return a.NewNumberInt(i) })
+		if !o.Rename("tmp", k) {
+			t.Fatalf("cannot rename the key to %q", k)
+		}
+	}
+	f("Upsert+Rename", v)
+
+	m := make(map[string]*Value)
+	mi := make(map[string]interface{})
+	for i, k := range keys {
+		m[k] = a.NewNumberInt(i)
+		mi[k] = i
+	}
+	f("NewObjectFromMap", a.NewObjectFromMap(m))
+
+	vi, err := a.NewFromInterface(mi)
+	if err != nil {
+		t.Fatalf("unexpected error in NewFromInterface: %s", err)
+	}
+	f("NewFromInterface", vi)
+
+	v = a.NewObject()
+	for i, k := range keys {
+		if err := v.SetPArena(&a, []interface{}{k, k}, a.NewNumberInt(i)); err != nil {
+			t.Fatalf("unexpected error in SetPArena: %s", err)
+		}
+		if err := v.SetAnyArena(&a, []interface{}{k, "nested"}, mi); err != nil {
+			t.Fatalf("unexpected error in SetAnyArena: %s", err)
+		}
+	}
+	f("SetPArena+SetAnyArena", v)
+
+	mf := make(map[string]string)
+	for _, k := range keys {
+		// Backslash must be escaped in flattened paths.
+		mf[strings.Replace(k, `\`, `\\`, -1)+".x"] = k
+	}
+	vu, err := Unflatten(mf, &a)
+	if err != nil {
+		t.Fatalf("unexpected error in Unflatten: %s", err)
+	}
+	f("Unflatten", vu)
+
+	// Keys added to the parsed object with raw keys must be escaped too.
+	vp, err := a.Parse(`{"raw\"key":1,"\u0061":2}`)
+	if err != nil {
+		t.Fatalf("cannot parse JSON: %s", err)
+	}
+	for i, k := range keys {
+		vp.Set(k, a.NewNumberInt(i))
+	}
+	f("parsed+Set", vp)
+	f("Clone", vp.Clone())
+}
+
+func TestArenaNewNumberStringErr(t *testing.T) {
+	var a Arena
+	for _, s := range []string{"", "-", "12,3", "1.", ".5", "01", "1e", "NaN", "inf", "0x10", "1 "} {
+		v, err := a.NewNumberStringErr(s)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+		if v != nil {
+			t.Fatalf("expecting nil value for %q; got %s", s, v)
+		}
+	}
+	for _, s := range []string{"0", "-12", "12.3", "1e5", "-1.5E-3"} {
+		v, err := a.NewNumberStringErr(s)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		if v.String() != s {
+			t.Fatalf("unexpected value; got %s; want %s", v, s)
+		}
+	}
+}
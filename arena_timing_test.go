@@ -1,28 +1,81 @@
 package fastjson
 
 import (
+	"fmt"
 	"sync/atomic"
 	"testing"
 )
 
 func BenchmarkArenaTypicalUse(b *testing.B) {
-	// Determine the length of created object
-	var aa Arena
-	obj := benchCreateArenaObject(&aa)
-	objLen := len(obj.String())
-	b.SetBytes(int64(objLen))
-	b.ReportAllocs()
-	b.RunParallel(func(pb *testing.PB) {
-		var buf []byte
-		var a Arena
-		var sink int
-		for pb.Next() {
-			obj := benchCreateArenaObject(&a)
-			buf = obj.MarshalTo(buf[:0])
-			a.Reset()
-			sink += len(buf)
-		}
-		atomic.AddUint64(&Sink, uint64(sink))
+	f := func(b *testing.B, createObject func(a *Arena) *Value) {
+		// Determine the length of created object
+		var aa Arena
+		obj := createObject(&aa)
+		objLen := len(obj.String())
+		b.SetBytes(int64(objLen))
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			var buf []byte
+			var a Arena
+			var sink int
+			for pb.Next() {
+				obj := createObject(&a)
+				buf = obj.MarshalTo(buf[:0])
+				a.Reset()
+				sink += len(buf)
+			}
+			atomic.AddUint64(&Sink, uint64(sink))
+		})
+	}
+	b.Run("small-object", func(b *testing.B) {
+		f(b, benchCreateArenaObject)
+	})
+	b.Run("100-keys", func(b *testing.B) {
+		f(b, func(a *Arena) *Value {
+			return benchCreateArenaObjectWithManyKeys(a, a.NewObject())
+		})
+	})
+	b.Run("100-keys-capacity", func(b *testing.B) {
+		f(b, func(a *Arena) *Value {
+			return benchCreateArenaObjectWithManyKeys(a, a.NewObjectCapacity(len(benchArenaKeys)))
+		})
+	})
+}
+
+func BenchmarkArenaObjectWithManyKeys(b *testing.B) {
+	var keys []string
+	for i := 0; i < 100; i++ {
+		keys = append(keys, fmt.Sprintf("key_%d", i))
+	}
+	f := func(b *testing.B, newObject func(a *Arena) *Value) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			var sink int
+			for pb.Next() {
+				// Use fresh arena on every iteration in order to measure
+				// allocations for object entries.
+				var a Arena
+				s := a.NewString("foobar")
+				for i := 0; i < 10; i++ {
+					o := newObject(&a)
+					for _, key := range keys {
+						o.Set(key, s)
+					}
+					sink += o.GetObject().Len()
+				}
+			}
+			atomic.AddUint64(&Sink, uint64(sink))
+		})
+	}
+	b.Run("NewObject", func(b *testing.B) {
+		f(b, func(a *Arena) *Value {
+			return a.NewObject()
+		})
+	})
+	b.Run("NewObjectCapacity", func(b *testing.B) {
+		f(b, func(a *Arena) *Value {
+			return a.NewObjectCapacity(len(keys))
+		})
 	})
 }
 
@@ -41,4 +94,122 @@ func benchCreateArenaObject(a *Arena) *Value {
 	return o
 }
 
+var benchArenaKeys = func() []string {
+	var keys []string
+	for i := 0; i < 100; i++ {
+		keys = append(keys, fmt.Sprintf("key_%d", i))
+	}
+	return keys
+}()
+
+func benchCreateArenaObjectWithManyKeys(a *Arena, o *Value) *Value {
+	for i, key := range benchArenaKeys {
+		o.Set(key, a.NewNumberInt(i))
+	}
+	return o
+}
+
 var Sink uint64
+
+func BenchmarkArenaNewArrayCapacity(b *testing.B) {
+	const n = 1000000
+	f := func(b *testing.B, newArray func(a *Arena) *Value) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			// Use fresh arena on every iteration, since Reset preserves
+			// the capacity of arrays for the re-used values.
+			var a Arena
+			v := newArray(&a)
+			// Share the item in order to measure only the array growth.
+			item := a.NewNumberInt(i)
+			for j := 0; j < n; j++ {
+				v.SetArrayItem(j, item)
+			}
+			atomic.AddUint64(&Sink, uint64(len(v.a)))
+		}
+	}
+	b.Run("NewArray", func(b *testing.B) {
+		f(b, func(a *Arena) *Value {
+			return a.NewArray()
+		})
+	})
+	b.Run("NewArrayCapacity", func(b *testing.B) {
+		f(b, func(a *Arena) *Value {
+			return a.NewArrayCapacity(n)
+		})
+	})
+	b.Run("GrowArray", func(b *testing.B) {
+		f(b, func(a *Arena) *Value {
+			v := a.NewArray()
+			v.GrowArray(n)
+			return v
+		})
+	})
+}
+
+func BenchmarkArenaBulkConstructors(b *testing.B) {
+	const n = 1000
+	ss := make([]string, n)
+	ns := make([]int64, n)
+	m := make(map[string]*Value, n)
+	for i := 0; i < n; i++ {
+		ss[i] = fmt.Sprintf("key_%d", i)
+		ns[i] = int64(i)
+		m[ss[i]] = valueTrue
+	}
+	f := func(b *testing.B, create func(a *Arena) *Value) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			var a Arena
+			var sink int
+			for pb.Next() {
+				v := create(&a)
+				sink += int(v.Type())
+				a.Reset()
+			}
+			atomic.AddUint64(&Sink, uint64(sink))
+		})
+	}
+	b.Run("object-Set", func(b *testing.B) {
+		f(b, func(a *Arena) *Value {
+			o := a.NewObject()
+			for _, k := range ss {
+				o.Set(k, m[k])
+			}
+			return o
+		})
+	})
+	b.Run("object-NewObjectFromMap", func(b *testing.B) {
+		f(b, func(a *Arena) *Value {
+			return a.NewObjectFromMap(m)
+		})
+	})
+	b.Run("strings-SetArrayItem", func(b *testing.B) {
+		f(b, func(a *Arena) *Value {
+			v := a.NewArray()
+			for i, s := range ss {
+				v.SetArrayItem(i, a.NewString(s))
+			}
+			return v
+		})
+	})
+	b.Run("strings-NewArrayFromStrings", func(b *testing.B) {
+		f(b, func(a *Arena) *Value {
+			return a.NewArrayFromStrings(ss)
+		})
+	})
+	b.Run("ints-SetArrayItem", func(b *testing.B) {
+		f(b, func(a *Arena) *Value {
+			v := a.NewArray()
+			for i, n := range ns {
+				v.SetArrayItem(i, a.NewNumberInt(int(n)))
+			}
+			return v
+		})
+	})
+	b.Run("ints-NewArrayFromInts", func(b *testing.B) {
+		f(b, func(a *Arena) *Value {
+			return a.NewArrayFromInts(ns)
+		})
+	})
+}
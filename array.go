@@ -0,0 +1,97 @@
+package fastjson
+
+import (
+	"fmt"
+)
+
+// Float64Array appends numbers from the array identified by keys path
+// in v to dst and returns the result.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// An error is returned if the keys path doesn't exist, if it doesn't refer
+// to an array or if the array contains non-number items. dst is returned
+// without modifications on error.
+func (v *Value) Float64Array(dst []float64, keys ...string) ([]float64, error) {
+	a, err := v.getArray(keys)
+	if err != nil {
+		return dst, err
+	}
+	dstLen := len(dst)
+	for i, vv := range a {
+		if vv.t != TypeNumber {
+			return dst[:dstLen], fmt.Errorf("element %d is not a number; it contains %s", i, vv.Type())
+		}
+		f, err := vv.cachedFloat64()
+		if err != nil {
+			return dst[:dstLen], wrapErrorf(err, "cannot parse element %d: %s", i, err)
+		}
+		dst = append(dst, f)
+	}
+	return dst, nil
+}
+
+// Int64Array appends integers from the array identified by keys path
+// in v to dst and returns the result.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// An error is returned if the keys path doesn't exist, if it doesn't refer
+// to an array or if the array contains items other than int64 numbers.
+// dst is returned without modifications on error.
+func (v *Value) Int64Array(dst []int64, keys ...string) ([]int64, error) {
+	a, err := v.getArray(keys)
+	if err != nil {
+		return dst, err
+	}
+	dstLen := len(dst)
+	for i, vv := range a {
+		if vv.t != TypeNumber {
+			return dst[:dstLen], fmt.Errorf("element %d is not a number; it contains %s", i, vv.Type())
+		}
+		n, err := vv.cachedInt64()
+		if err != nil {
+			return dst[:dstLen], wrapErrorf(err, "cannot parse element %d: %s", i, err)
+		}
+		dst = append(dst, n)
+	}
+	return dst, nil
+}
+
+// StringArray appends strings from the array identified by keys path
+// in v to dst and returns the result.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// The strings are unescaped and copied, so they remain valid after Parse
+// is called on the Parser returned v.
+//
+// An error is returned if the keys path doesn't exist, if it doesn't refer
+// to an array or if the array contains non-string items. dst is returned
+// without modifications on error.
+func (v *Value) StringArray(dst []string, keys ...string) ([]string, error) {
+	a, err := v.getArray(keys)
+	if err != nil {
+		return dst, err
+	}
+	dstLen := len(dst)
+	for i, vv := range a {
+		if vv.Type() != TypeString {
+			return dst[:dstLen], fmt.Errorf("element %d is not a string; it contains %s", i, vv.Type())
+		}
+		// Convert via []byte in order to make a copy of vv.s.
+		dst = append(dst, string(s2b(vv.s)))
+	}
+	return dst, nil
+}
+
+func (v *Value) getArray(keys []string) ([]*Value, error) {
+	vv := v.Get(keys...)
+	if vv == nil {
+		return nil, fmt.Errorf("cannot find value at %q", keys)
+	}
+	if vv.t != TypeArray {
+		return nil, fmt.Errorf("value at %q doesn't contain array; it contains %s", keys, vv.Type())
+	}
+	return vv.a, nil
+}
@@ -0,0 +1,98 @@
+package fastjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValueTypedArrays(t *testing.T) {
+	v := MustParse(`{
+		"floats": [1, -2.5, 3e2, 0],
+		"ints": [1, -2, 9223372036854775807],
+		"strings": ["foo", "b\nar", ""],
+		"empty": [],
+		"mixed": [1, "2", 3],
+		"nested": {"arr": [[1, 2], ["x", "y"]]},
+		"obj": {"a": 1},
+		"bigint": [1, 9223372036854775808],
+		"floatint": [1, 2.5]
+	}`)
+
+	fs, err := v.Float64Array([]float64{42}, "floats")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(fs, []float64{42, 1, -2.5, 300, 0}) {
+		t.Fatalf("unexpected floats: %v", fs)
+	}
+	ns, err := v.Int64Array(nil, "ints")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(ns, []int64{1, -2, 9223372036854775807}) {
+		t.Fatalf("unexpected ints: %v", ns)
+	}
+	ss, err := v.StringArray(nil, "strings")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(ss, []string{"foo", "b\nar", ""}) {
+		t.Fatalf("unexpected strings: %q", ss)
+	}
+
+	// Nested paths
+	fs, err = v.Float64Array(fs[:0], "nested", "arr", "0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(fs, []float64{1, 2}) {
+		t.Fatalf("unexpected floats: %v", fs)
+	}
+	ss, err = v.StringArray(ss[:0], "nested", "arr", "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(ss, []string{"x", "y"}) {
+		t.Fatalf("unexpected strings: %q", ss)
+	}
+
+	// Empty arrays
+	fs, err = v.Float64Array(nil, "empty")
+	if err != nil || len(fs) != 0 {
+		t.Fatalf("unexpected result for empty array: %v, %v", fs, err)
+	}
+
+	// Errors
+	fError := func(err error, errExpected string) {
+		t.Helper()
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if !strings.Contains(err.Error(), errExpected) {
+			t.Fatalf("unexpected error; got %q; must contain %q", err, errExpected)
+		}
+	}
+	dst := []float64{42}
+	fs, err = v.Float64Array(dst, "mixed")
+	fError(err, "element 1 is not a number; it contains string")
+	if !reflect.DeepEqual(fs, dst) {
+		t.Fatalf("dst must be returned without modifications on error; got %v", fs)
+	}
+	_, err = v.Int64Array(nil, "mixed")
+	fError(err, "element 1 is not a number")
+	_, err = v.StringArray(nil, "mixed")
+	fError(err, "element 0 is not a string; it contains number")
+	_, err = v.StringArray(nil, "nested", "arr")
+	fError(err, "element 0 is not a string; it contains array")
+	_, err = v.Int64Array(nil, "bigint")
+	fError(err, "cannot parse element 1")
+	_, err = v.Int64Array(nil, "floatint")
+	fError(err, "cannot parse element 1")
+	_, err = v.Float64Array(nil, "missing")
+	fError(err, `cannot find value at ["missing"]`)
+	_, err = v.Float64Array(nil, "obj")
+	fError(err, `value at ["obj"] doesn't contain array; it contains object`)
+	_, err = v.StringArray(nil, "nested", "arr", "5")
+	fError(err, "cannot find value")
+}
@@ -0,0 +1,53 @@
+package fastjson
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func BenchmarkValueFloat64Array(b *testing.B) {
+	v := MustParse(canadaFixture)
+	var points []*Value
+	for _, feature := range v.GetArray("features") {
+		for _, ring := range feature.GetArray("geometry", "coordinates") {
+			points = append(points, ring.GetArray()...)
+		}
+	}
+	b.Run("Float64Array", func(b *testing.B) {
+		b.SetBytes(int64(len(canadaFixture)))
+		b.ReportAllocs()
+		var fs []float64
+		var sink float64
+		for i := 0; i < b.N; i++ {
+			for _, point := range points {
+				var err error
+				fs, err = point.Float64Array(fs[:0])
+				if err != nil {
+					b.Fatalf("unexpected error: %s", err)
+				}
+				sink += fs[0]
+			}
+		}
+		atomic.AddUint64(&Sink, uint64(sink))
+	})
+	b.Run("GetArray-Float64", func(b *testing.B) {
+		b.SetBytes(int64(len(canadaFixture)))
+		b.ReportAllocs()
+		var fs []float64
+		var sink float64
+		for i := 0; i < b.N; i++ {
+			for _, point := range points {
+				fs = fs[:0]
+				for _, vv := range point.GetArray() {
+					f, err := vv.Float64()
+					if err != nil {
+						b.Fatalf("unexpected error: %s", err)
+					}
+					fs = append(fs, f)
+				}
+				sink += fs[0]
+			}
+		}
+		atomic.AddUint64(&Sink, uint64(sink))
+	})
+}
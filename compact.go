@@ -0,0 +1,90 @@
+package fastjson
+
+// CompactOptions contains options for Value.Compact.
+//
+// Every option enables removing values of the given kind.
+// The zero CompactOptions removes nothing.
+type CompactOptions struct {
+	// Null enables removing null values.
+	Null bool
+
+	// EmptyString enables removing empty strings.
+	EmptyString bool
+
+	// EmptyObject enables removing empty objects.
+	EmptyObject bool
+
+	// EmptyArray enables removing empty arrays.
+	EmptyArray bool
+}
+
+// Compact removes object entries and array items with empty values
+// from v according to opts and returns the number of removed values.
+//
+// The removal is performed bottom-up, so objects and arrays, which become
+// empty after removing their contents, are removed too if opts allows it.
+// For instance, {"a":{"b":[],"c":null},"d":1} is compacted into {"d":1}
+// with three removed values if all the opts are enabled.
+//
+// v itself is never removed. Use IsEmpty for checking whether v became
+// empty after the compaction. The order of the remaining entries and
+// items is preserved.
+func (v *Value) Compact(opts CompactOptions) int {
+	if v == nil {
+		return 0
+	}
+	return compactValue(v, opts)
+}
+
+// IsEmpty returns true if v is empty according to opts, i.e. Compact
+// removes v from the parent object or array.
+//
+// false is returned for nil v.
+func (v *Value) IsEmpty(opts CompactOptions) bool {
+	if v == nil {
+		return false
+	}
+	switch v.t {
+	case TypeNull:
+		return opts.Null
+	case TypeString, typeRawString:
+		// Non-empty escaped string cannot be unescaped into an empty string,
+		// so there is no need in unescaping it.
+		return opts.EmptyString && len(v.s) == 0
+	case TypeObject:
+		return opts.EmptyObject && len(v.o.kvs) == 0
+	case TypeArray:
+		return opts.EmptyArray && len(v.a) == 0
+	default:
+		return false
+	}
+}
+
+func compactValue(v *Value, opts CompactOptions) int {
+	n := 0
+	switch v.t {
+	case TypeObject:
+		kvs := v.o.kvs[:0]
+		for _, kv := range v.o.kvs {
+			n += compactValue(kv.v, opts)
+			if kv.v.IsEmpty(opts) {
+				n++
+				continue
+			}
+			kvs = append(kvs, kv)
+		}
+		v.o.kvs = kvs
+	case TypeArray:
+		a := v.a[:0]
+		for _, vv := range v.a {
+			n += compactValue(vv, opts)
+			if vv.IsEmpty(opts) {
+				n++
+				continue
+			}
+			a = append(a, vv)
+		}
+		v.a = a
+	}
+	return n
+}
@@ -0,0 +1,110 @@
+package fastjson
+
+import (
+	"testing"
+)
+
+func TestValueCompact(t *testing.T) {
+	all := CompactOptions{
+		Null:        true,
+		EmptyString: true,
+		EmptyObject: true,
+		EmptyArray:  true,
+	}
+	f := func(s string, opts CompactOptions, resultExpected string, nExpected int, isEmptyExpected bool) {
+		t.Helper()
+		var p Parser
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", s, err)
+		}
+		n := v.Compact(opts)
+		if n != nExpected {
+			t.Fatalf("unexpected number of removed values for %s; got %d; want %d", s, n, nExpected)
+		}
+		result := v.MarshalTo(nil)
+		if string(result) != resultExpected {
+			t.Fatalf("unexpected result for %s;\ngot\n%s\nwant\n%s", s, result, resultExpected)
+		}
+		if isEmpty := v.IsEmpty(opts); isEmpty != isEmptyExpected {
+			t.Fatalf("unexpected IsEmpty result for %s; got %v; want %v", s, isEmpty, isEmptyExpected)
+		}
+
+		// Compact must be idempotent.
+		if n := v.Compact(opts); n != 0 {
+			t.Fatalf("unexpected number of removed values on the second Compact call for %s; got %d; want 0", s, n)
+		}
+		if s := v.String(); s != resultExpected {
+			t.Fatalf("unexpected result after the second Compact call;\ngot\n%s\nwant\n%s", s, resultExpected)
+		}
+	}
+
+	// Scalars
+	f(`null`, all, `null`, 0, true)
+	f(`""`, all, `""`, 0, true)
+	f(`" "`, all, `" "`, 0, false)
+	f(`0`, all, `0`, 0, false)
+	f(`false`, all, `false`, 0, false)
+	f(`null`, CompactOptions{}, `null`, 0, false)
+
+	// Flat containers
+	f(`{}`, all, `{}`, 0, true)
+	f(`[]`, all, `[]`, 0, true)
+	f(`{"a":null,"b":"","c":{},"d":[],"e":0,"f":false,"g":"x"}`, all, `{"e":0,"f":false,"g":"x"}`, 4, false)
+	f(`[null,"",{},[],0,false,"x"]`, all, `[0,false,"x"]`, 4, false)
+	f(`{"a":null,"b":"","c":{},"d":[]}`, CompactOptions{}, `{"a":null,"b":"","c":{},"d":[]}`, 0, false)
+
+	// Every option separately
+	f(`{"a":null,"b":"","c":{},"d":[]}`, CompactOptions{Null: true}, `{"b":"","c":{},"d":[]}`, 1, false)
+	f(`{"a":null,"b":"","c":{},"d":[]}`, CompactOptions{EmptyString: true}, `{"a":null,"c":{},"d":[]}`, 1, false)
+	f(`{"a":null,"b":"","c":{},"d":[]}`, CompactOptions{EmptyObject: true}, `{"a":null,"b":"","d":[]}`, 1, false)
+	f(`{"a":null,"b":"","c":{},"d":[]}`, CompactOptions{EmptyArray: true}, `{"a":null,"b":"","c":{}}`, 1, false)
+
+	// Nested cascades
+	f(`{"a":{"b":[],"c":[]},"d":1}`, all, `{"d":1}`, 3, false)
+	f(`{"a":{"b":[],"c":{}}}`, all, `{}`, 3, true)
+	f(`[[[[null]]],[{"x":[""]}],1]`, all, `[1]`, 8, false)
+	f(`[[[[null]]]]`, all, `[]`, 4, true)
+	f(`{"a":{"b":{"c":null}},"x":[{"y":null,"z":2}]}`, all, `{"x":[{"z":2}]}`, 4, false)
+
+	// Containers, which become empty, are kept if their removal is disabled
+	f(`{"a":{"b":null},"c":[null]}`, CompactOptions{Null: true}, `{"a":{},"c":[]}`, 2, false)
+	f(`{"a":{"b":null},"c":[null]}`, CompactOptions{Null: true, EmptyArray: true}, `{"a":{}}`, 3, false)
+	f(`{"a":{"b":null},"c":[null]}`, CompactOptions{Null: true, EmptyObject: true}, `{"c":[]}`, 3, false)
+
+	// Escaped strings and keys
+	f(`{"a\nb":"","c\"d":"A","e":"\n","f":["\t",""]}`, all, `{"c\"d":"A","e":"\n","f":["\t"]}`, 2, false)
+}
+
+func TestValueCompactNil(t *testing.T) {
+	var v *Value
+	if n := v.Compact(CompactOptions{Null: true}); n != 0 {
+		t.Fatalf("unexpected number of removed values for nil value; got %d; want 0", n)
+	}
+	if v.IsEmpty(CompactOptions{Null: true}) {
+		t.Fatalf("nil value mustn't be empty")
+	}
+}
+
+func TestValueCompactModified(t *testing.T) {
+	var a Arena
+	v := a.NewObject()
+	v.Set("empty", a.NewArray())
+	v.Set("null", a.NewNull())
+	v.Set("str", a.NewString(""))
+	arr := a.NewArray()
+	arr.SetArrayItem(3, a.NewNumberInt(1))
+	v.Set("arr", arr)
+
+	n := v.Compact(CompactOptions{
+		Null:        true,
+		EmptyString: true,
+		EmptyArray:  true,
+	})
+	if n != 6 {
+		t.Fatalf("unexpected number of removed values; got %d; want 6", n)
+	}
+	if s := v.String(); s != `{"arr":[1]}` {
+		t.Fatalf("unexpected result; got %s; want %s", s, `{"arr":[1]}`)
+	}
+}
@@ -0,0 +1,165 @@
+package fastjson
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultContextCheckInterval is the default number of parsed values
+// between ctx checks in Parser.ParseCtx and ValidateCtx.
+const DefaultContextCheckInterval = 1000
+
+// ContextCheckInterval sets the number of parsed values between ctx checks
+// in ParseCtx.
+//
+// Smaller intervals reduce the delay between ctx cancellation and ParseCtx
+// return, while increasing the overhead of ctx checks.
+// DefaultContextCheckInterval is used if n <= 0.
+func (p *Parser) ContextCheckInterval(n int) {
+	p.c.cc.checkInterval = n
+}
+
+// ParseCtx parses s containing JSON like Parse does, but aborts parsing
+// when ctx is canceled or its deadline is exceeded.
+//
+// ctx is checked before parsing and then every ContextCheckInterval
+// parsed values, so the parsing time for adversarial inputs may be limited
+// without running the parsing in a separate goroutine.
+//
+// The returned error is *ParseError. It wraps ctx.Err() if the parsing
+// has been aborted, so errors.Is(err, context.DeadlineExceeded) may be used
+// for detecting the timeout.
+//
+// ParseCtx has negligible overhead over Parse for ctx, which is never
+// canceled, such as context.Background().
+func (p *Parser) ParseCtx(ctx context.Context, s string) (*Value, error) {
+	if err := ctx.Err(); err != nil {
+		p.v = nil
+		return nil, newContextError(s, err)
+	}
+	if ctx.Done() == nil {
+		// ctx cannot be canceled, so there is no need in checking it.
+		return p.Parse(s)
+	}
+
+	p.c.cc.start(ctx)
+	v, err := p.Parse(s)
+	ctxErr := p.c.cc.stop()
+	if err != nil && ctxErr != nil {
+		err.(*ParseError).err = ctxErr
+	}
+	return v, err
+}
+
+// ParseBytesCtx parses b containing JSON like ParseBytes does, but aborts
+// parsing when ctx is canceled or its deadline is exceeded.
+//
+// See ParseCtx for details.
+func (p *Parser) ParseBytesCtx(ctx context.Context, b []byte) (*Value, error) {
+	return p.ParseCtx(ctx, b2s(b))
+}
+
+// ValidateCtx validates JSON s like Validate does, but aborts validation
+// when ctx is canceled or its deadline is exceeded.
+//
+// ctx is checked before validation and then every DefaultContextCheckInterval
+// validated values.
+//
+// The returned error is *ParseError. It wraps ctx.Err() if the validation
+// has been aborted.
+func ValidateCtx(ctx context.Context, s string) error {
+	if err := ctx.Err(); err != nil {
+		return newContextError(s, err)
+	}
+	if ctx.Done() == nil {
+		return Validate(s)
+	}
+
+	sOrig := s
+	s = skipWS(skipBOM(s))
+	vl := &validateLimits{
+		maxDepth: MaxDepth,
+	}
+	vl.cc.start(ctx)
+	tail, err := validateValue(s, 0, vl)
+	ctxErr := vl.cc.stop()
+	if err != nil {
+		pe := newParseError(sOrig, tail, fmt.Sprintf("cannot parse JSON: %s; unparsed tail: %q", err, startEndString(tail)))
+		pe.err = ctxErr
+		return pe
+	}
+	tail = skipWS(tail)
+	if len(tail) > 0 {
+		return newParseError(sOrig, tail, fmt.Sprintf("unexpected tail: %q", startEndString(tail)))
+	}
+	return nil
+}
+
+// ValidateBytesCtx validates JSON b like ValidateBytes does, but aborts
+// validation when ctx is canceled or its deadline is exceeded.
+//
+// See ValidateCtx for details.
+func ValidateBytesCtx(ctx context.Context, b []byte) error {
+	return ValidateCtx(ctx, b2s(b))
+}
+
+// newContextError returns ParseError wrapping ctx error err,
+// which occurred before parsing s.
+func newContextError(s string, err error) *ParseError {
+	pe := newParseError(s, s, fmt.Sprintf("cannot parse JSON: %s", err))
+	pe.err = err
+	return pe
+}
+
+// contextChecker checks ctx every checkInterval calls to check.
+type contextChecker struct {
+	// ctx is nil if it mustn't be checked.
+	ctx context.Context
+
+	// checkInterval is the number of check calls between ctx checks.
+	//
+	// DefaultContextCheckInterval is used if checkInterval <= 0.
+	checkInterval int
+
+	// calls is the number of check calls since the last ctx check.
+	calls int
+
+	// ctxErr is the ctx error returned from check.
+	ctxErr error
+}
+
+// start starts checking the given ctx.
+func (cc *contextChecker) start(ctx context.Context) {
+	cc.ctx = ctx
+	cc.calls = 0
+	cc.ctxErr = nil
+}
+
+// stop stops checking ctx and returns ctx error returned from check if any.
+func (cc *contextChecker) stop() error {
+	err := cc.ctxErr
+	// Do not hold references to ctx after the stop.
+	cc.ctx = nil
+	cc.ctxErr = nil
+	return err
+}
+
+// check returns ctx error if ctx is done.
+//
+// ctx is checked only every checkInterval calls in order to reduce overhead.
+func (cc *contextChecker) check() error {
+	cc.calls++
+	n := cc.checkInterval
+	if n <= 0 {
+		n = DefaultContextCheckInterval
+	}
+	if cc.calls < n {
+		return nil
+	}
+	cc.calls = 0
+	if err := cc.ctx.Err(); err != nil {
+		cc.ctxErr = err
+		return err
+	}
+	return nil
+}
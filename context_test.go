@@ -0,0 +1,173 @@
+package fastjson
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// abortingContext is canceled after the given number of Err calls.
+type abortingContext struct {
+	context.Context
+	done  chan struct{}
+	calls int
+	limit int
+}
+
+func newAbortingContext(limit int) *abortingContext {
+	return &abortingContext{
+		Context: context.Background(),
+		done:    make(chan struct{}),
+		limit:   limit,
+	}
+}
+
+func (ctx *abortingContext) Done() <-chan struct{} {
+	return ctx.done
+}
+
+func (ctx *abortingContext) Err() error {
+	ctx.calls++
+	if ctx.calls > ctx.limit {
+		return context.Canceled
+	}
+	return nil
+}
+
+// adversarialJSON returns deeply nested JSON with huge fan-out at every level.
+func adversarialJSON(depth, fanOut int) string {
+	var sb strings.Builder
+	for i := 0; i < depth; i++ {
+		sb.WriteString(`{"a":[`)
+		for j := 0; j < fanOut; j++ {
+			sb.WriteString(`[1,"x",{"b":2}],`)
+		}
+	}
+	sb.WriteString(`null`)
+	for i := 0; i < depth; i++ {
+		sb.WriteString(`]}`)
+	}
+	return sb.String()
+}
+
+func TestParseCtx(t *testing.T) {
+	s := adversarialJSON(140, 100)
+
+	f := func(name string, err error, want error) {
+		t.Helper()
+		if err == nil {
+			t.Fatalf("%s: expecting non-nil error", name)
+		}
+		pe, ok := err.(*ParseError)
+		if !ok {
+			t.Fatalf("%s: unexpected error type %T; want *ParseError", name, err)
+		}
+		if pe.Unwrap() != want {
+			t.Fatalf("%s: error %q must wrap %q", name, err, want)
+		}
+	}
+
+	t.Run("background", func(t *testing.T) {
+		var p Parser
+		v, err := p.ParseCtx(context.Background(), s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if n := len(v.GetArray("a")); n != 101 {
+			t.Fatalf("unexpected array length; got %d; want %d", n, 101)
+		}
+		if err := ValidateCtx(context.Background(), s); err != nil {
+			t.Fatalf("unexpected error in ValidateCtx: %s", err)
+		}
+	})
+
+	t.Run("not-canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var p Parser
+		if _, err := p.ParseCtx(ctx, s); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := ValidateCtx(ctx, s); err != nil {
+			t.Fatalf("unexpected error in ValidateCtx: %s", err)
+		}
+
+		// Syntax errors mustn't wrap ctx errors.
+		_, err := p.ParseCtx(ctx, `[1,2`)
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if uerr := err.(*ParseError).Unwrap(); uerr != nil {
+			t.Fatalf("unexpected wrapped error for %q: %v", err, uerr)
+		}
+		err = ValidateCtx(ctx, `[1,2`)
+		if err == nil {
+			t.Fatalf("expecting non-nil error in ValidateCtx")
+		}
+		if uerr := err.(*ParseError).Unwrap(); uerr != nil {
+			t.Fatalf("unexpected wrapped error for %q: %v", err, uerr)
+		}
+	})
+
+	t.Run("already-canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var p Parser
+		v, err := p.ParseCtx(ctx, s)
+		f("ParseCtx", err, context.Canceled)
+		if v != nil {
+			t.Fatalf("expecting nil value")
+		}
+		f("ParseBytesCtx", func() error { _, err := p.ParseBytesCtx(ctx, []byte(`{}`)); return err }(), context.Canceled)
+		f("ValidateCtx", ValidateCtx(ctx, s), context.Canceled)
+		f("ValidateBytesCtx", ValidateBytesCtx(ctx, []byte(`{}`)), context.Canceled)
+	})
+
+	t.Run("canceled-mid-parse", func(t *testing.T) {
+		var p Parser
+		p.ContextCheckInterval(10)
+		ctx := newAbortingContext(5)
+		_, err := p.ParseCtx(ctx, s)
+		f("ParseCtx", err, context.Canceled)
+		// The initial check plus 5 checks every 10 values.
+		if ctx.calls != 6 {
+			t.Fatalf("unexpected number of ctx checks; got %d; want %d", ctx.calls, 6)
+		}
+		pe := err.(*ParseError)
+		if pe.Offset <= 0 || pe.Offset >= len(s) {
+			t.Fatalf("unexpected error offset %d; it must be in the range (0..%d)", pe.Offset, len(s))
+		}
+
+		// The parser must remain usable after the abort.
+		v, err := p.Parse(`{"foo":"bar"}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if sb := v.GetStringBytes("foo"); string(sb) != "bar" {
+			t.Fatalf("unexpected value; got %q; want %q", sb, "bar")
+		}
+
+		// ContextCheckInterval must be copied by Clone.
+		ctx = newAbortingContext(1)
+		_, err = p.Clone().ParseCtx(ctx, s)
+		f("Clone.ParseCtx", err, context.Canceled)
+		if ctx.calls != 2 {
+			t.Fatalf("unexpected number of ctx checks; got %d; want %d", ctx.calls, 2)
+		}
+
+		ctx = newAbortingContext(3)
+		f("ValidateCtx", ValidateCtx(ctx, s), context.Canceled)
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		// Parsing the large document takes much longer than the timeout.
+		s := adversarialJSON(140, 3000)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		var p Parser
+		_, err := p.ParseCtx(ctx, s)
+		f("ParseCtx", err, context.DeadlineExceeded)
+		f("ValidateCtx", ValidateCtx(ctx, s), context.DeadlineExceeded)
+	})
+}
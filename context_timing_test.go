@@ -0,0 +1,67 @@
+package fastjson
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func BenchmarkParseCtx(b *testing.B) {
+	b.Run("large", func(b *testing.B) {
+		benchmarkParseCtx(b, largeFixture)
+	})
+	b.Run("canada", func(b *testing.B) {
+		benchmarkParseCtx(b, canadaFixture)
+	})
+	b.Run("twitter", func(b *testing.B) {
+		benchmarkParseCtx(b, twitterFixture)
+	})
+}
+
+func benchmarkParseCtx(b *testing.B, s string) {
+	b.Run("Parse", func(b *testing.B) {
+		benchmarkFastJSONParse(b, s)
+	})
+	b.Run("ParseCtx-background", func(b *testing.B) {
+		benchmarkFastJSONParseCtx(b, s, context.Background())
+	})
+	b.Run("ParseCtx-cancelable", func(b *testing.B) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		benchmarkFastJSONParseCtx(b, s, ctx)
+	})
+	b.Run("Validate", func(b *testing.B) {
+		benchmarkValidateFastJSON(b, s)
+	})
+	b.Run("ValidateCtx-cancelable", func(b *testing.B) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		b.ReportAllocs()
+		b.SetBytes(int64(len(s)))
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if err := ValidateCtx(ctx, s); err != nil {
+					panic(fmt.Errorf("unexpected error: %s", err))
+				}
+			}
+		})
+	})
+}
+
+func benchmarkFastJSONParseCtx(b *testing.B, s string, ctx context.Context) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(s)))
+	b.RunParallel(func(pb *testing.PB) {
+		p := benchPool.Get()
+		for pb.Next() {
+			v, err := p.ParseCtx(ctx, s)
+			if err != nil {
+				panic(fmt.Errorf("unexpected error: %s", err))
+			}
+			if v.Type() != TypeObject {
+				panic(fmt.Errorf("unexpected value type; got %s; want %s", v.Type(), TypeObject))
+			}
+		}
+		benchPool.Put(p)
+	})
+}
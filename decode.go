@@ -0,0 +1,219 @@
+package fastjson
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DecodeArray calls fn for each item of the array in v.
+//
+// i is the index of the item. The error returned from fn stops
+// the iteration and is returned from DecodeArray. An error is returned
+// if v isn't an array.
+//
+// DecodeArray together with Object.Scan allows decoding arrays of objects
+// into slices of structs without reflection:
+//
+//	var items []item
+//	err := v.DecodeArray(func(i int, v *fastjson.Value) error {
+//		o, err := v.Object()
+//		if err != nil {
+//			return err
+//		}
+//		var it item
+//		err = o.Scan(
+//			fastjson.KV{Key: "id", Dst: &it.ID},
+//			fastjson.KV{Key: "name", Dst: &it.Name},
+//		)
+//		if err != nil {
+//			return fmt.Errorf("cannot decode item #%d: %s", i, err)
+//		}
+//		items = append(items, it)
+//		return nil
+//	})
+func (v *Value) DecodeArray(fn func(i int, v *Value) error) error {
+	a, err := v.Array()
+	if err != nil {
+		return err
+	}
+	for i, vv := range a {
+		if err := fn(i, vv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// KV pairs object key with a pointer for storing the value for the key
+// in Object.Scan.
+//
+// The following pointer types are supported for Dst:
+//
+//   - *int64, *uint64 and *float64 for numbers
+//   - *string for strings
+//   - *bool for true and false
+//   - **Value for any value
+type KV struct {
+	Key string
+	Dst interface{}
+}
+
+// Scan stores values for the given keys into the corresponding Dst pointers.
+//
+// Scan walks o only once instead of walking it for every key as Get does.
+// The value for the first occurrence of the key is stored in the same way
+// as Get does. Dst pointers for missing keys are left untouched.
+//
+// An error naming the key is returned if the value type doesn't match
+// the Dst type, e.g. if the value for *int64 is a string or a fractional
+// number. Dst pointers for the keys located before the mismatched key
+// in o may be already filled in this case.
+//
+// Strings stored into *string and values stored into **Value refer
+// to the Parser memory, so they are valid until the next Parse call
+// on the Parser returned o.
+func (o *Object) Scan(dst ...KV) error {
+	if o == nil || len(dst) == 0 {
+		return nil
+	}
+	var foundBuf [4]uint64
+	found := foundBuf[:]
+	if n := (len(dst) + 63) / 64; n > len(found) {
+		found = make([]uint64, n)
+	}
+
+	if !o.keysUnescaped {
+		// Fast path - try searching for the keys without object keys unescaping.
+		hasEscapedKeys := false
+		for i := range dst {
+			if strings.IndexByte(dst[i].Key, '\\') >= 0 {
+				hasEscapedKeys = true
+				break
+			}
+		}
+		if !hasEscapedKeys {
+			n, err := o.scan(dst, found)
+			if err != nil || n == len(dst) {
+				return err
+			}
+		}
+	}
+
+	// Slow path - unescape object keys.
+	o.unescapeKeys()
+
+	_, err := o.scan(dst, found)
+	return err
+}
+
+// scan stores values for dst items, which aren't marked in found,
+// and returns the number of items marked in found.
+func (o *Object) scan(dst []KV, found []uint64) (int, error) {
+	// Build a hash table for dst keys, so every object key is compared
+	// only with dst keys having the same hash.
+	//
+	// heads contains 1-based index of the first dst item for every hash,
+	// while next contains 1-based index of the next dst item with the same hash.
+	var heads [64]int32
+	var nextBuf [16]int32
+	next := nextBuf[:]
+	if len(dst) > len(next) {
+		next = make([]int32, len(dst))
+	}
+	n := 0
+	for i := range dst {
+		if found[i/64]&(1<<uint(i%64)) != 0 {
+			n++
+			continue
+		}
+		h := scanKeyHash(dst[i].Key)
+		next[i] = heads[h]
+		heads[h] = int32(i + 1)
+	}
+
+	for i := range o.kvs {
+		if n == len(dst) {
+			break
+		}
+		kv := &o.kvs[i]
+		h := scanKeyHash(kv.k)
+		prev := int32(0)
+		for j := heads[h]; j > 0; j = next[j-1] {
+			idx := j - 1
+			key := dst[idx].Key
+			if key != kv.k {
+				prev = j
+				continue
+			}
+			if err := scanValue(kv.v, dst[idx].Dst); err != nil {
+				// Copy the key, since passing it to fmt.Errorf makes dst escaping
+				// to the heap at every Scan call.
+				keyCopy := string(append([]byte(nil), key...))
+				return n, fmt.Errorf("cannot scan value for key %q: %s", keyCopy, err)
+			}
+			found[idx/64] |= 1 << uint(idx%64)
+			n++
+
+			// Remove the found item from the hash table, so it isn't filled
+			// by subsequent items with duplicate keys.
+			if prev == 0 {
+				heads[h] = next[idx]
+			} else {
+				next[prev-1] = next[idx]
+			}
+		}
+	}
+	return n, nil
+}
+
+// scanKeyHash returns the hash for the given key in the range [0..63].
+//
+// The hash depends only on the key length, since this avoids loading
+// the contents of object keys, which don't match dst keys by length.
+func scanKeyHash(k string) uint {
+	return uint(len(k)) % 64
+}
+
+// scanValue stores v into dst.
+//
+// dst is left untouched on errors.
+func scanValue(v *Value, dst interface{}) error {
+	switch p := dst.(type) {
+	case *int64:
+		n, err := v.Int64()
+		if err != nil {
+			return err
+		}
+		*p = n
+	case *uint64:
+		n, err := v.Uint64()
+		if err != nil {
+			return err
+		}
+		*p = n
+	case *float64:
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		*p = f
+	case *string:
+		if v.Type() != TypeString {
+			return fmt.Errorf("value doesn't contain string; it contains %s", v.Type())
+		}
+		*p = v.s
+	case *bool:
+		b, err := v.Bool()
+		if err != nil {
+			return err
+		}
+		*p = b
+	case **Value:
+		*p = v
+	default:
+		// Do not pass dst to fmt.Errorf, since this makes dst escaping
+		// to the heap at every Scan call.
+		return fmt.Errorf("unsupported Dst type; it must be *int64, *uint64, *float64, *string, *bool or **Value")
+	}
+	return nil
+}
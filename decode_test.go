@@ -0,0 +1,192 @@
+package fastjson
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValueDecodeArray(t *testing.T) {
+	v := MustParse(`[{"a":1},{"a":2},{"a":3}]`)
+	var sum int64
+	var indexes []int
+	err := v.DecodeArray(func(i int, v *Value) error {
+		o, err := v.Object()
+		if err != nil {
+			return err
+		}
+		var a int64
+		if err := o.Scan(KV{Key: "a", Dst: &a}); err != nil {
+			return err
+		}
+		sum += a
+		indexes = append(indexes, i)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if sum != 6 || fmt.Sprint(indexes) != "[0 1 2]" {
+		t.Fatalf("unexpected result; got sum=%d, indexes=%v; want sum=6, indexes=[0 1 2]", sum, indexes)
+	}
+
+	// The error from fn must stop the iteration.
+	n := 0
+	err = v.DecodeArray(func(i int, v *Value) error {
+		n++
+		if i == 1 {
+			return fmt.Errorf("error at item #%d", i)
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "error at item #1" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("unexpected number of fn calls; got %d; want 2", n)
+	}
+
+	// Non-array value
+	err = MustParse(`{"a":1}`).DecodeArray(func(i int, v *Value) error {
+		t.Fatalf("unexpected fn call")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "value doesn't contain array") {
+		t.Fatalf("unexpected error for object: %v", err)
+	}
+	err = MustParse(`[]`).DecodeArray(func(i int, v *Value) error {
+		t.Fatalf("unexpected fn call")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error for empty array: %s", err)
+	}
+}
+
+func TestObjectScan(t *testing.T) {
+	o := MustParse(`{"i":-12,"u":18446744073709551615,"f":1.5,"s":"foo\nbar","b":true,"v":[1,2],"i":5,"n":null}`).GetObject()
+
+	var i int64
+	var u uint64
+	var f float64
+	var s string
+	var b bool
+	var v *Value
+	err := o.Scan(
+		KV{Key: "i", Dst: &i},
+		KV{Key: "u", Dst: &u},
+		KV{Key: "f", Dst: &f},
+		KV{Key: "s", Dst: &s},
+		KV{Key: "b", Dst: &b},
+		KV{Key: "v", Dst: &v},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// The first occurrence of the key must be used like in Get.
+	if i != -12 {
+		t.Fatalf("unexpected i; got %d; want -12", i)
+	}
+	if u != 18446744073709551615 {
+		t.Fatalf("unexpected u; got %d; want 18446744073709551615", u)
+	}
+	if f != 1.5 {
+		t.Fatalf("unexpected f; got %v; want 1.5", f)
+	}
+	if s != "foo\nbar" {
+		t.Fatalf("unexpected s; got %q; want %q", s, "foo\nbar")
+	}
+	if !b {
+		t.Fatalf("unexpected b; got false; want true")
+	}
+	if v.String() != "[1,2]" {
+		t.Fatalf("unexpected v; got %s; want [1,2]", v)
+	}
+
+	// Missing keys must leave Dst untouched.
+	i = 42
+	s = "unchanged"
+	if err := o.Scan(KV{Key: "missing", Dst: &i}, KV{Key: "missing2", Dst: &s}, KV{Key: "f", Dst: &f}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if i != 42 || s != "unchanged" {
+		t.Fatalf("unexpected values for missing keys; got i=%d, s=%q", i, s)
+	}
+	if err := o.Scan(); err != nil {
+		t.Fatalf("unexpected error for empty Scan: %s", err)
+	}
+	var oNil *Object
+	if err := oNil.Scan(KV{Key: "i", Dst: &i}); err != nil || i != 42 {
+		t.Fatalf("unexpected result for nil object; err=%v, i=%d", err, i)
+	}
+
+	// Type mismatches
+	fError := func(kv KV, errExpected string) {
+		t.Helper()
+		err := o.Scan(KV{Key: "i", Dst: new(int64)}, kv)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for key %q", kv.Key)
+		}
+		if !strings.Contains(err.Error(), errExpected) {
+			t.Fatalf("unexpected error for key %q; got %q; must contain %q", kv.Key, err, errExpected)
+		}
+	}
+	fError(KV{Key: "s", Dst: &i}, `cannot scan value for key "s": value doesn't contain number; it contains string`)
+	fError(KV{Key: "f", Dst: &i}, `cannot scan value for key "f"`)
+	fError(KV{Key: "i", Dst: &u}, `cannot scan value for key "i"`)
+	fError(KV{Key: "b", Dst: &f}, `cannot scan value for key "b": value doesn't contain number; it contains true`)
+	fError(KV{Key: "n", Dst: &s}, `cannot scan value for key "n": value doesn't contain string; it contains null`)
+	fError(KV{Key: "v", Dst: &b}, `cannot scan value for key "v": value doesn't contain bool; it contains array`)
+	fError(KV{Key: "u", Dst: new(int)}, `cannot scan value for key "u": unsupported Dst type`)
+
+	// Dst must be left untouched on type mismatch.
+	i = 42
+	if err := o.Scan(KV{Key: "s", Dst: &i}); err == nil || i != 42 {
+		t.Fatalf("unexpected result for mismatched type; err=%v, i=%d", err, i)
+	}
+}
+
+func TestObjectScanEscapedKeys(t *testing.T) {
+	o := MustParse(`{"a\nb":1,"\u0063":"x","d":true}`).GetObject()
+	var n int64
+	var s string
+	var b bool
+	if err := o.Scan(KV{Key: "a\nb", Dst: &n}, KV{Key: "c", Dst: &s}, KV{Key: "d", Dst: &b}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n != 1 || s != "x" || !b {
+		t.Fatalf("unexpected values; got n=%d, s=%q, b=%v; want n=1, s=\"x\", b=true", n, s, b)
+	}
+}
+
+func TestObjectScanManyKeys(t *testing.T) {
+	// More than 256 keys require heap-allocated bitset.
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i := 0; i < 300; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `"k%d":%d`, i, i)
+	}
+	sb.WriteString("}")
+	o := MustParse(sb.String()).GetObject()
+
+	dst := make([]int64, 301)
+	kvs := make([]KV, len(dst))
+	for i := range kvs {
+		kvs[i] = KV{Key: fmt.Sprintf("k%d", i), Dst: &dst[i]}
+		dst[i] = -1
+	}
+	if err := o.Scan(kvs...); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i := 0; i < 300; i++ {
+		if dst[i] != int64(i) {
+			t.Fatalf("unexpected value for k%d; got %d; want %d", i, dst[i], i)
+		}
+	}
+	if dst[300] != -1 {
+		t.Fatalf("unexpected value for missing key; got %d; want -1", dst[300])
+	}
+}
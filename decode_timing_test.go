@@ -0,0 +1,94 @@
+package fastjson
+
+import (
+	"testing"
+)
+
+type benchTopic struct {
+	id                 int64
+	title              string
+	likeCount          int64
+	hasSummary         bool
+	archetype          string
+	lastPosterUsername string
+}
+
+func BenchmarkObjectScan(b *testing.B) {
+	topics := MustParse(largeFixture).Get("topics", "topics")
+	if len(topics.GetArray()) == 0 {
+		panic("BUG: missing topics")
+	}
+	dst := make([]benchTopic, 0, len(topics.GetArray()))
+
+	b.Run("Get", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			dst = dst[:0]
+			err := topics.DecodeArray(func(i int, v *Value) error {
+				o, err := v.Object()
+				if err != nil {
+					return err
+				}
+				var t benchTopic
+				if t.id, err = o.Get("id").Int64(); err != nil {
+					return err
+				}
+				title, err := o.Get("title").StringBytes()
+				if err != nil {
+					return err
+				}
+				t.title = b2s(title)
+				if t.likeCount, err = o.Get("like_count").Int64(); err != nil {
+					return err
+				}
+				if t.hasSummary, err = o.Get("has_summary").Bool(); err != nil {
+					return err
+				}
+				archetype, err := o.Get("archetype").StringBytes()
+				if err != nil {
+					return err
+				}
+				t.archetype = b2s(archetype)
+				username, err := o.Get("last_poster_username").StringBytes()
+				if err != nil {
+					return err
+				}
+				t.lastPosterUsername = b2s(username)
+				dst = append(dst, t)
+				return nil
+			})
+			if err != nil {
+				panic(err)
+			}
+		}
+	})
+	b.Run("Scan", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			dst = dst[:0]
+			err := topics.DecodeArray(func(i int, v *Value) error {
+				o, err := v.Object()
+				if err != nil {
+					return err
+				}
+				var t benchTopic
+				err = o.Scan(
+					KV{Key: "id", Dst: &t.id},
+					KV{Key: "title", Dst: &t.title},
+					KV{Key: "like_count", Dst: &t.likeCount},
+					KV{Key: "has_summary", Dst: &t.hasSummary},
+					KV{Key: "archetype", Dst: &t.archetype},
+					KV{Key: "last_poster_username", Dst: &t.lastPosterUsername},
+				)
+				if err != nil {
+					return err
+				}
+				dst = append(dst, t)
+				return nil
+			})
+			if err != nil {
+				panic(err)
+			}
+		}
+	})
+}
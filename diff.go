@@ -0,0 +1,211 @@
+package fastjson
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ChangeKind is the kind of Change.
+type ChangeKind int
+
+const (
+	// ChangeAdded means the value exists only in the new JSON.
+	ChangeAdded ChangeKind = 1
+
+	// ChangeRemoved means the value exists only in the old JSON.
+	ChangeRemoved ChangeKind = 2
+
+	// ChangeModified means the value at the same path differs
+	// between the old and the new JSON.
+	ChangeModified ChangeKind = 3
+)
+
+// String returns string representation of k.
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	default:
+		panic(fmt.Errorf("BUG: unknown ChangeKind: %d", k))
+	}
+}
+
+// Change is a single difference between two JSON values returned from Diff.
+type Change struct {
+	// Path is the path to the changed value.
+	//
+	// Path elements are object keys (string) and array indexes (int)
+	// like in Walk. Path is empty for the root value.
+	Path []interface{}
+
+	// Kind is the kind of the change.
+	Kind ChangeKind
+
+	// Old is the value from the old JSON. It is nil for ChangeAdded.
+	Old *Value
+
+	// New is the value from the new JSON. It is nil for ChangeRemoved.
+	New *Value
+}
+
+// String returns human-readable representation of c.
+//
+// For example, "modified data.items[3].price: 10 -> 12".
+// Object keys in the path aren't escaped.
+func (c *Change) String() string {
+	dst := append([]byte(nil), c.Kind.String()...)
+	if len(c.Path) > 0 {
+		dst = append(dst, ' ')
+		dst = appendDiffPath(dst, c.Path)
+	}
+	dst = append(dst, ": "...)
+	switch c.Kind {
+	case ChangeAdded:
+		dst = c.New.MarshalTo(dst)
+	case ChangeRemoved:
+		dst = c.Old.MarshalTo(dst)
+	default:
+		dst = c.Old.MarshalTo(dst)
+		dst = append(dst, " -> "...)
+		dst = c.New.MarshalTo(dst)
+	}
+	return string(dst)
+}
+
+func appendDiffPath(dst []byte, path []interface{}) []byte {
+	for i, p := range path {
+		switch p := p.(type) {
+		case string:
+			if i > 0 {
+				dst = append(dst, '.')
+			}
+			dst = append(dst, p...)
+		case int:
+			dst = append(dst, '[')
+			dst = strconv.AppendInt(dst, int64(p), 10)
+			dst = append(dst, ']')
+		}
+	}
+	return dst
+}
+
+// Diff returns the changes, which turn a into b.
+//
+// Objects are compared as sets of unescaped keys, so the order of entries
+// doesn't matter. Arrays are compared item by item at the same indexes,
+// and the tail of the longer array is reported as added or removed items.
+// Numbers are compared by their values, so 1 and 1.0 are equal. Values
+// of distinct types at the same path are reported as modified.
+//
+// Objects and arrays aren't reported as modified themselves; only changes
+// inside them are reported in depth-first order. The same *Value passed
+// at the same path in a and b is skipped without comparing its contents.
+// nil a or b is treated as a missing value.
+//
+// Old and New in the returned changes point to values in a and b,
+// so they remain valid while a and b are valid.
+func Diff(a, b *Value) []Change {
+	var d differ
+	switch {
+	case a == nil && b == nil:
+	case a == nil:
+		d.addChange(ChangeAdded, nil, b)
+	case b == nil:
+		d.addChange(ChangeRemoved, a, nil)
+	default:
+		d.diff(a, b)
+	}
+	return d.changes
+}
+
+type differ struct {
+	// path is the path to the currently compared values.
+	path []interface{}
+
+	changes []Change
+}
+
+func (d *differ) addChange(kind ChangeKind, oldValue, newValue *Value) {
+	d.changes = append(d.changes, Change{
+		Path: append([]interface{}(nil), d.path...),
+		Kind: kind,
+		Old:  oldValue,
+		New:  newValue,
+	})
+}
+
+func (d *differ) diff(a, b *Value) {
+	if a == b {
+		return
+	}
+	t := a.Type()
+	if t != b.Type() {
+		d.addChange(ChangeModified, a, b)
+		return
+	}
+	switch t {
+	case TypeObject:
+		d.diffObjects(&a.o, &b.o)
+	case TypeArray:
+		n := len(a.a)
+		if n > len(b.a) {
+			n = len(b.a)
+		}
+		for i := 0; i < n; i++ {
+			d.path = append(d.path, i)
+			d.diff(a.a[i], b.a[i])
+			d.path = d.path[:len(d.path)-1]
+		}
+		for i := n; i < len(a.a); i++ {
+			d.path = append(d.path, i)
+			d.addChange(ChangeRemoved, a.a[i], nil)
+			d.path = d.path[:len(d.path)-1]
+		}
+		for i := n; i < len(b.a); i++ {
+			d.path = append(d.path, i)
+			d.addChange(ChangeAdded, nil, b.a[i])
+			d.path = d.path[:len(d.path)-1]
+		}
+	case TypeString:
+		if a.s != b.s {
+			d.addChange(ChangeModified, a, b)
+		}
+	case TypeNumber:
+		if !numbersEqual(a, b) {
+			d.addChange(ChangeModified, a, b)
+		}
+	}
+}
+
+func (d *differ) diffObjects(a, b *Object) {
+	a.unescapeKeys()
+	b.unescapeKeys()
+	for i, kv := range a.kvs {
+		// Fast path - entries are usually located at the same positions.
+		var w *Value
+		if i < len(b.kvs) && b.kvs[i].k == kv.k {
+			w = b.kvs[i].v
+		} else {
+			w = b.Get(kv.k)
+		}
+		d.path = append(d.path, kv.k)
+		if w == nil {
+			d.addChange(ChangeRemoved, kv.v, nil)
+		} else {
+			d.diff(kv.v, w)
+		}
+		d.path = d.path[:len(d.path)-1]
+	}
+	for i, kv := range b.kvs {
+		if i < len(a.kvs) && a.kvs[i].k == kv.k || a.Get(kv.k) != nil {
+			continue
+		}
+		d.path = append(d.path, kv.k)
+		d.addChange(ChangeAdded, nil, kv.v)
+		d.path = d.path[:len(d.path)-1]
+	}
+}
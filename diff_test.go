@@ -0,0 +1,120 @@
+package fastjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	f := func(a, b string, changesExpected []string) {
+		t.Helper()
+		va := MustParse(a)
+		vb := MustParse(b)
+		changes := Diff(va, vb)
+		var result []string
+		for i := range changes {
+			result = append(result, changes[i].String())
+		}
+		if !reflect.DeepEqual(result, changesExpected) {
+			t.Fatalf("unexpected changes for\n%s\n%s\ngot\n%q\nwant\n%q", a, b, result, changesExpected)
+		}
+
+		// The reverse diff must contain the same number of changes.
+		if n := len(Diff(vb, va)); n != len(changesExpected) {
+			t.Fatalf("unexpected number of changes in the reverse diff for\n%s\n%s\ngot %d; want %d", a, b, n, len(changesExpected))
+		}
+	}
+
+	// No changes
+	f(`null`, `null`, nil)
+	f(`"foo"`, `"foo"`, nil)
+	f(`[]`, `[]`, nil)
+	f(`{}`, `{}`, nil)
+	f(`{"a":1,"b":[1,2,{"c":"d"}],"e":{"f":null,"g":true}}`, `{"e":{"g":true,"f":null},"b":[1,2,{"c":"d"}],"a":1}`, nil)
+	f(`{"ab":"c\n"}`, `{"ab":"c\u000a"}`, nil)
+	f(`[1,1.0,100,-0]`, `[1.0,1,1e2,0]`, nil)
+
+	// Scalars
+	f(`1`, `2`, []string{"modified: 1 -> 2"})
+	f(`"foo"`, `"bar"`, []string{`modified: "foo" -> "bar"`})
+	f(`true`, `false`, []string{"modified: true -> false"})
+
+	// Added and removed keys
+	f(`{"a":1}`, `{"a":1,"b":2}`, []string{"added b: 2"})
+	f(`{"a":1,"b":2}`, `{"b":2}`, []string{"removed a: 1"})
+	f(`{"a":1,"b":2}`, `{"c":3,"a":1}`, []string{"removed b: 2", "added c: 3"})
+	f(`{"data":{"x":{"y":[1]}}}`, `{"data":{}}`, []string{`removed data.x: {"y":[1]}`})
+
+	// Array length changes
+	f(`[1,2]`, `[1,2,3,4]`, []string{"added [2]: 3", "added [3]: 4"})
+	f(`{"a":[1,2,3]}`, `{"a":[1]}`, []string{"removed a[1]: 2", "removed a[2]: 3"})
+	f(`[1,2,3]`, `[2,3]`, []string{"modified [0]: 1 -> 2", "modified [1]: 2 -> 3", "removed [2]: 3"})
+
+	// Type changes
+	f(`1`, `"1"`, []string{`modified: 1 -> "1"`})
+	f(`{"a":null}`, `{"a":{}}`, []string{"modified a: null -> {}"})
+	f(`{"a":[1]}`, `{"a":{"0":1}}`, []string{`modified a: [1] -> {"0":1}`})
+	f(`[true]`, `[false]`, []string{"modified [0]: true -> false"})
+
+	// Nested changes
+	f(`{"data":{"items":[{},{},{},{"price":10,"name":"x"}]}}`, `{"data":{"items":[{},{},{},{"name":"x","price":12}]}}`, []string{
+		"modified data.items[3].price: 10 -> 12",
+	})
+	f(`[[1,[2,[3]]],{"a":{"b":{"c":1}}}]`, `[[1,[2,[4]]],{"a":{"b":{"c":1,"d":2}}}]`, []string{
+		"modified [0][1][1][0]: 3 -> 4",
+		"added [1].a.b.d: 2",
+	})
+}
+
+func TestDiffChange(t *testing.T) {
+	a := MustParse(`{"x":{"y":[1,2]},"z":"foo"}`)
+	b := MustParse(`{"z":"bar","x":{"y":[1]},"w":null}`)
+	changes := Diff(a, b)
+	changesExpected := []Change{
+		{
+			Path: []interface{}{"x", "y", 1},
+			Kind: ChangeRemoved,
+			Old:  a.Get("x", "y", "1"),
+		},
+		{
+			Path: []interface{}{"z"},
+			Kind: ChangeModified,
+			Old:  a.Get("z"),
+			New:  b.Get("z"),
+		},
+		{
+			Path: []interface{}{"w"},
+			Kind: ChangeAdded,
+			New:  b.Get("w"),
+		},
+	}
+	if !reflect.DeepEqual(changes, changesExpected) {
+		t.Fatalf("unexpected changes\ngot\n%v\nwant\n%v", changes, changesExpected)
+	}
+}
+
+func TestDiffIdentical(t *testing.T) {
+	v := MustParse(largeFixture)
+	if changes := Diff(v, v); len(changes) != 0 {
+		t.Fatalf("unexpected changes for the same value: %v", changes)
+	}
+	w := MustParse(largeFixture)
+	if changes := Diff(v, w); len(changes) != 0 {
+		t.Fatalf("unexpected changes for equal values: %v", changes)
+	}
+}
+
+func TestDiffNil(t *testing.T) {
+	v := MustParse(`{"a":1}`)
+	if changes := Diff(nil, nil); len(changes) != 0 {
+		t.Fatalf("unexpected changes for nil values: %v", changes)
+	}
+	changes := Diff(nil, v)
+	if len(changes) != 1 || changes[0].String() != `added: {"a":1}` {
+		t.Fatalf("unexpected changes for nil old value: %v", changes)
+	}
+	changes = Diff(v, nil)
+	if len(changes) != 1 || changes[0].String() != `removed: {"a":1}` {
+		t.Fatalf("unexpected changes for nil new value: %v", changes)
+	}
+}
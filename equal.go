@@ -0,0 +1,60 @@
+package fastjson
+
+// Equal returns true if v and w contain equal JSON values.
+//
+// Strings and object keys are compared after unescaping.
+// Numbers are compared by their textual representation, so 1 and 1.0
+// aren't equal. Objects are equal if they contain equal entries
+// in the same order.
+func (v *Value) Equal(w *Value) bool {
+	if v == nil || w == nil {
+		return v == w
+	}
+	if v == w {
+		return true
+	}
+	t := v.Type()
+	if t != w.Type() {
+		return false
+	}
+	switch t {
+	case TypeObject:
+		return v.o.Equal(&w.o)
+	case TypeArray:
+		if len(v.a) != len(w.a) {
+			return false
+		}
+		for i, vv := range v.a {
+			if !vv.Equal(w.a[i]) {
+				return false
+			}
+		}
+		return true
+	case TypeString, TypeNumber:
+		return v.s == w.s
+	default:
+		// null, true and false
+		return true
+	}
+}
+
+// Equal returns true if o and other contain equal entries in the same order.
+//
+// See Value.Equal for details.
+func (o *Object) Equal(other *Object) bool {
+	if o == nil || other == nil {
+		return o == other
+	}
+	if len(o.kvs) != len(other.kvs) {
+		return false
+	}
+	o.unescapeKeys()
+	other.unescapeKeys()
+	for i, kv := range o.kvs {
+		kvOther := other.kvs[i]
+		if kv.k != kvOther.k || !kv.v.Equal(kvOther.v) {
+			return false
+		}
+	}
+	return true
+}
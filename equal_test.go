@@ -0,0 +1,54 @@
+package fastjson
+
+import (
+	"testing"
+)
+
+func TestValueEqual(t *testing.T) {
+	f := func(s1, s2 string, expected bool) {
+		t.Helper()
+		v1 := MustParse(s1)
+		v2 := MustParse(s2)
+		if v1.Equal(v2) != expected {
+			t.Fatalf("unexpected Equal result for %s and %s; got %v; want %v", s1, s2, !expected, expected)
+		}
+		if v2.Equal(v1) != expected {
+			t.Fatalf("unexpected Equal result for %s and %s; got %v; want %v", s2, s1, !expected, expected)
+		}
+	}
+
+	f(`null`, `null`, true)
+	f(`true`, `true`, true)
+	f(`true`, `false`, false)
+	f(`null`, `false`, false)
+	f(`123`, `123`, true)
+	f(`123`, `1.23e2`, false)
+	f(`123`, `"123"`, false)
+	f(`"foo"`, `"foo"`, true)
+	f(`"foo"`, `"bar"`, false)
+	f(`"f\u006fo"`, `"foo"`, true)
+	f(`[]`, `[]`, true)
+	f(`[]`, `{}`, false)
+	f(`[1,"x",null]`, `[1,"x",null]`, true)
+	f(`[1,"x",null]`, `[1,"x"]`, false)
+	f(`[1,"x",null]`, `[1,"y",null]`, false)
+	f(`{}`, `{}`, true)
+	f(`{"a":1,"b":[2]}`, `{"a":1,"b":[2]}`, true)
+	f(`{"\u0061":1,"b":[2]}`, `{"a":1,"b":[2]}`, true)
+	f(`{"a":1,"b":[2]}`, `{"b":[2],"a":1}`, false)
+	f(`{"a":1,"b":[2]}`, `{"a":1,"b":[3]}`, false)
+	f(`{"a":1,"b":[2]}`, `{"a":1}`, false)
+
+	// nil values
+	var v *Value
+	if !v.Equal(nil) {
+		t.Fatalf("nil values must be equal")
+	}
+	if v.Equal(MustParse(`null`)) {
+		t.Fatalf("nil value mustn't be equal to null")
+	}
+	var o *Object
+	if !o.Equal(nil) {
+		t.Fatalf("nil objects must be equal")
+	}
+}
@@ -0,0 +1,279 @@
+package fastjson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseError is returned by Parser.Parse*, Arena.Parse* and Validate*
+// on invalid JSON.
+//
+// Use errors.As for obtaining error details.
+type ParseError struct {
+	// Offset is the byte offset in the parsed JSON where the error occurred.
+	Offset int
+
+	// Line is 1-based line number for Offset.
+	Line int
+
+	// Column is 1-based byte column for Offset in the Line.
+	Column int
+
+	// Path is the path to the JSON element containing the error,
+	// such as "data.items[17]".
+	//
+	// Path is empty for errors in the top-level value.
+	Path string
+
+	// Expected is a short description of what was expected at Offset.
+	Expected string
+
+	msg string
+
+	// err is the wrapped error returned from Unwrap.
+	err error
+}
+
+// Error returns string representation for e.
+func (e *ParseError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the error wrapped by e.
+//
+// It returns ctx.Err() if the parsing has been aborted by ParseCtx
+// or ValidateCtx. nil is returned otherwise.
+func (e *ParseError) Unwrap() error {
+	return e.err
+}
+
+// newParseError returns ParseError with the given msg for the error
+// found at the given tail of s.
+func newParseError(s, tail, msg string) *ParseError {
+	offset := len(s) - len(tail)
+	path, expected := locateError(s[:offset])
+	line, column := lineColumn(s, offset)
+	return &ParseError{
+		Offset:   offset,
+		Line:     line,
+		Column:   column,
+		Path:     path,
+		Expected: expected,
+		msg:      msg,
+	}
+}
+
+// shift makes e relative to the data, which starts with the given prefix,
+// while e is relative to the data after the prefix.
+func (e *ParseError) shift(prefix string) {
+	if e.Line == 1 {
+		e.Column += len(prefix) - (strings.LastIndexByte(prefix, '\n') + 1)
+	}
+	e.Line += strings.Count(prefix, "\n")
+	e.Offset += len(prefix)
+}
+
+// lineColumn returns 1-based line and column for the given offset in s.
+func lineColumn(s string, offset int) (int, int) {
+	prefix := s[:offset]
+	line := 1 + strings.Count(prefix, "\n")
+	column := offset - strings.LastIndexByte(prefix, '\n')
+	return line, column
+}
+
+const (
+	locateStateValue = iota
+	locateStateValueOrEnd
+	locateStateKey
+	locateStateKeyOrEnd
+	locateStateColon
+	locateStateCommaOrEnd
+	locateStateEnd
+)
+
+type locateFrame struct {
+	path     string
+	key      string
+	idx      int
+	isObject bool
+}
+
+// locateError returns the path and the expected token for the error
+// located at the end of s.
+//
+// s must contain JSON, which has been successfully parsed.
+// So locateError may skip strict checks.
+func locateError(s string) (string, string) {
+	var stack []locateFrame
+	state := locateStateValue
+	for {
+		s = skipWS(s)
+		if len(s) == 0 {
+			return locatePath(stack, state), locateExpected(stack, state)
+		}
+		switch state {
+		case locateStateValue, locateStateValueOrEnd:
+			if state == locateStateValueOrEnd && s[0] == ']' {
+				stack = stack[:len(stack)-1]
+				state = locateStateAfterValue(stack)
+				s = s[1:]
+				continue
+			}
+			path := locatePath(stack, state)
+			switch s[0] {
+			case '{':
+				stack = append(stack, locateFrame{
+					path:     path,
+					isObject: true,
+				})
+				state = locateStateKeyOrEnd
+				s = s[1:]
+			case '[':
+				stack = append(stack, locateFrame{
+					path: path,
+				})
+				state = locateStateValueOrEnd
+				s = s[1:]
+			case '"':
+				_, tail, err := parseRawString(s[1:])
+				if err != nil {
+					return path, `closing '"'`
+				}
+				s = tail
+				state = locateStateAfterValue(stack)
+			default:
+				n := 0
+				for n < len(s) && !isValueDelimiter(s[n]) {
+					n++
+				}
+				if n == 0 {
+					return path, locateExpected(stack, state)
+				}
+				s = s[n:]
+				state = locateStateAfterValue(stack)
+			}
+		case locateStateKey, locateStateKeyOrEnd:
+			f := &stack[len(stack)-1]
+			if state == locateStateKeyOrEnd && s[0] == '}' {
+				stack = stack[:len(stack)-1]
+				state = locateStateAfterValue(stack)
+				s = s[1:]
+				continue
+			}
+			if s[0] != '"' {
+				return f.path, locateExpected(stack, state)
+			}
+			k, tail, err := parseRawKey(s[1:])
+			if err != nil {
+				return f.path, `closing '"'`
+			}
+			// Copy k before unescaping, since unescapeStringBestEffort modifies
+			// the string in place, while s may point to read-only memory.
+			f.key = unescapeStringBestEffort(string(append([]byte(nil), k...)))
+			state = locateStateColon
+			s = tail
+		case locateStateColon:
+			if s[0] != ':' {
+				return locatePath(stack, state), locateExpected(stack, state)
+			}
+			state = locateStateValue
+			s = s[1:]
+		case locateStateCommaOrEnd:
+			f := &stack[len(stack)-1]
+			switch {
+			case s[0] == ',' && f.isObject:
+				state = locateStateKey
+			case s[0] == ',':
+				f.idx++
+				state = locateStateValue
+			case s[0] == '}' && f.isObject, s[0] == ']' && !f.isObject:
+				stack = stack[:len(stack)-1]
+				state = locateStateAfterValue(stack)
+			default:
+				return f.path, locateExpected(stack, state)
+			}
+			s = s[1:]
+		default:
+			return "", locateExpected(stack, state)
+		}
+	}
+}
+
+func locateStateAfterValue(stack []locateFrame) int {
+	if len(stack) == 0 {
+		return locateStateEnd
+	}
+	return locateStateCommaOrEnd
+}
+
+func locatePath(stack []locateFrame, state int) string {
+	if len(stack) == 0 {
+		return ""
+	}
+	f := &stack[len(stack)-1]
+	if state != locateStateValue && state != locateStateValueOrEnd {
+		return f.path
+	}
+	if !f.isObject {
+		return f.path + "[" + strconv.Itoa(f.idx) + "]"
+	}
+	if len(f.path) == 0 {
+		return f.key
+	}
+	return f.path + "." + f.key
+}
+
+func locateExpected(stack []locateFrame, state int) string {
+	switch state {
+	case locateStateValue:
+		return "value"
+	case locateStateValueOrEnd:
+		return "value or ']'"
+	case locateStateKey:
+		return "object key"
+	case locateStateKeyOrEnd:
+		return "object key or '}'"
+	case locateStateColon:
+		return "':'"
+	case locateStateCommaOrEnd:
+		if stack[len(stack)-1].isObject {
+			return "',' or '}'"
+		}
+		return "',' or ']'"
+	default:
+		return "end of JSON"
+	}
+}
+
+func isValueDelimiter(ch byte) bool {
+	switch ch {
+	case ',', ':', '[', ']', '{', '}', '"', 0x20, 0x0A, 0x09, 0x0D:
+		return true
+	default:
+		return false
+	}
+}
+
+// wrapError is an error with a custom message, which wraps another error,
+// so errors.Is and errors.As work for the wrapped error.
+type wrapError struct {
+	msg string
+	err error
+}
+
+func (e *wrapError) Error() string {
+	return e.msg
+}
+
+func (e *wrapError) Unwrap() error {
+	return e.err
+}
+
+// wrapErrorf returns an error with the formatted message wrapping err.
+func wrapErrorf(err error, format string, args ...interface{}) error {
+	return &wrapError{
+		msg: fmt.Sprintf(format, args...),
+		err: err,
+	}
+}
@@ -0,0 +1,146 @@
+package fastjson
+
+import (
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	f := func(s string, offset int, path, expected string) {
+		t.Helper()
+
+		check := func(err error) {
+			t.Helper()
+			if err == nil {
+				t.Fatalf("expecting non-nil error for %q", s)
+			}
+			pe, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("expecting *ParseError for %q; got %T", s, err)
+			}
+			if pe.Offset != offset {
+				t.Fatalf("unexpected offset for %q; got %d; want %d", s, pe.Offset, offset)
+			}
+			if pe.Path != path {
+				t.Fatalf("unexpected path for %q; got %q; want %q", s, pe.Path, path)
+			}
+			if pe.Expected != expected {
+				t.Fatalf("unexpected expected for %q; got %q; want %q", s, pe.Expected, expected)
+			}
+		}
+
+		var p Parser
+		_, err := p.Parse(s)
+		check(err)
+
+		err = Validate(s)
+		check(err)
+	}
+
+	// errors in the top-level value
+	f(``, 0, "", "value")
+	f(`  `, 2, "", "value")
+	f(`  foo`, 2, "", "value")
+	f(`"foo`, 0, "", "value")
+
+	// errors after the top-level value
+	f(`{} x`, 3, "", "end of JSON")
+	f(` [1,2]]`, 6, "", "end of JSON")
+
+	// errors in object keys
+	f(`{x}`, 1, "", "object key or '}'")
+	f(`{"a":1,}`, 7, "", "object key")
+	f(`{"a":1,"b`, 7, "", "object key")
+	f(`{"data":{"items":[{"a":1},{"b" 2}]}}`, 31, "data.items[1]", "':'")
+
+	// errors in values
+	f(`{"a":tru}`, 5, "a", "value")
+	f(`{"a":1 "b":2}`, 7, "", "',' or '}'")
+	f(`[1,2,x]`, 5, "[2]", "value")
+	f(`[1,2 3]`, 5, "", "',' or ']'")
+	f(`{"data":{"items":[1,2,{"x":[true,]}]}}`, 33, "data.items[2].x[1]", "value")
+	f(`{"fo\no":[{"a":"b\"c"},[]],"x":[y]}`, 32, "x[0]", "value or ']'")
+	f(`{"fo\no":[{"a":"b\"c"},[]],"x":[1,y]}`, 34, "x[1]", "value")
+	f(`{"fo\no":[1,y]}`, 12, "fo\no[1]", "value")
+	f(`[[[[`, 4, "[0][0][0][0]", "value or ']'")
+}
+
+func TestValidateError(t *testing.T) {
+	f := func(s string, offset int, path, expected string) {
+		t.Helper()
+
+		err := Validate(s)
+		pe, ok := err.(*ParseError)
+		if !ok {
+			t.Fatalf("expecting *ParseError for %q; got %T", s, err)
+		}
+		if pe.Offset != offset || pe.Path != path || pe.Expected != expected {
+			t.Fatalf("unexpected error details for %q; got (%d, %q, %q); want (%d, %q, %q)",
+				s, pe.Offset, pe.Path, pe.Expected, offset, path, expected)
+		}
+	}
+
+	// Errors, which are detected only by Validate
+	f(`{"foo":["bar","b\qz"]}`, 14, "foo[1]", "value")
+	f(`{"foo":{"b\qz":1}}`, 8, "foo", "object key or '}'")
+	f(`{"foo":[1, 01]}`, 11, "foo[1]", "value")
+}
+
+func TestParseErrorMessage(t *testing.T) {
+	var p Parser
+	_, err := p.Parse(`{"foo":bar}`)
+	if err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	msgExpected := `cannot parse JSON: cannot parse object: cannot parse object value: cannot parse number: unexpected char: "b"; unparsed tail: "bar}"`
+	if err.Error() != msgExpected {
+		t.Fatalf("unexpected error message\ngot\n%s\nwant\n%s", err, msgExpected)
+	}
+}
+
+func TestParseErrorLineColumn(t *testing.T) {
+	f := func(err error, line, column int) {
+		t.Helper()
+		pe, ok := err.(*ParseError)
+		if !ok {
+			t.Fatalf("expecting *ParseError; got %T", err)
+		}
+		if pe.Line != line || pe.Column != column {
+			t.Fatalf("unexpected position; got line %d, column %d; want line %d, column %d", pe.Line, pe.Column, line, column)
+		}
+	}
+
+	f(Validate(`x`), 1, 1)
+	f(Validate("{\n  \"a\": 1,\n  \"b\": x\n}"), 3, 8)
+	f(Validate("[1,\n2,\n"), 3, 1)
+
+	// The position must be relative to the whole stream.
+	f(ValidateStream("{}\n[1,\n2] [x]"), 3, 5)
+	f(ValidateStream("{} [x]"), 1, 5)
+
+	// The position must be relative to the whole buffer.
+	var p Parser
+	b := []byte("foo\nbar [1, x]")
+	_, err := p.ParseWithin(b, 8, len(b))
+	f(err, 2, 9)
+	b = []byte("foo\n[1,\n x]")
+	_, err = p.ParseWithin(b, 4, len(b))
+	f(err, 3, 2)
+}
+
+// isWrappedError returns true if err or any error in its Unwrap chain
+// equals to target.
+//
+// errors.Is cannot be used in tests, since it is missing in Go 1.12.
+func isWrappedError(err, target error) bool {
+	for err != nil {
+		if err == target {
+			return true
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return false
+		}
+		err = u.Unwrap()
+	}
+	return false
+}
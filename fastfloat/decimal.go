@@ -0,0 +1,121 @@
+package fastfloat
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrDecimalOverflow is returned by ParseDecimal if the number cannot be
+// represented as int64 mantissa and int32 scale without precision loss.
+//
+// Use math/big.Rat for parsing such numbers.
+var ErrDecimalOverflow = errors.New("decimal number doesn't fit int64 mantissa")
+
+// ParseDecimal parses decimal number s into mantissa and scale,
+// so the number equals to mantissa * 10^-scale.
+//
+// Unlike Parse, ParseDecimal doesn't lose precision, since it doesn't use
+// floating-point arithmetic. All the digits from s are preserved, including
+// trailing zeros in the fractional part. For example, 0.10 is parsed
+// into mantissa=10, scale=2.
+//
+// The returned scale is never negative. Numbers with positive exponents
+// are multiplied by the corresponding power of ten, so 12e3 is parsed
+// into mantissa=12000, scale=0.
+//
+// ErrDecimalOverflow is returned if the number doesn't fit int64 mantissa
+// and int32 scale.
+func ParseDecimal(s string) (int64, int32, error) {
+	if len(s) == 0 {
+		return 0, 0, syntaxErrorf(s, "cannot parse decimal number from empty string")
+	}
+	i := uint(0)
+	minus := s[0] == '-'
+	if minus {
+		i++
+	}
+	limit := uint64(math.MaxInt64)
+	if minus {
+		limit++
+	}
+
+	mant := uint64(0)
+	scale := int64(0)
+	digits := 0
+	for i < uint(len(s)) && s[i] >= '0' && s[i] <= '9' {
+		if !mulAdd10(&mant, s[i]-'0', limit) {
+			return 0, 0, ErrDecimalOverflow
+		}
+		digits++
+		i++
+	}
+	if i < uint(len(s)) && s[i] == '.' {
+		i++
+		for i < uint(len(s)) && s[i] >= '0' && s[i] <= '9' {
+			if !mulAdd10(&mant, s[i]-'0', limit) {
+				return 0, 0, ErrDecimalOverflow
+			}
+			scale++
+			digits++
+			i++
+		}
+	}
+	if digits == 0 {
+		return 0, 0, syntaxErrorf(s, "cannot parse decimal number from %q", s)
+	}
+	if i < uint(len(s)) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		expMinus := false
+		if i < uint(len(s)) && (s[i] == '-' || s[i] == '+') {
+			expMinus = s[i] == '-'
+			i++
+		}
+		j := i
+		exp := int64(0)
+		for i < uint(len(s)) && s[i] >= '0' && s[i] <= '9' {
+			if exp <= math.MaxInt32 {
+				exp = exp*10 + int64(s[i]-'0')
+			}
+			i++
+		}
+		if i <= j {
+			return 0, 0, syntaxErrorf(s, "cannot parse exponent in decimal number %q", s)
+		}
+		if expMinus {
+			exp = -exp
+		}
+		scale -= exp
+	}
+	if i < uint(len(s)) {
+		return 0, 0, syntaxErrorf(s, "unparsed tail left after parsing decimal number from %q: %q", s, s[i:])
+	}
+
+	if mant == 0 && (scale < 0 || scale > math.MaxInt32) {
+		// The scale doesn't matter for zero.
+		scale = 0
+	}
+	for scale < 0 {
+		if !mulAdd10(&mant, 0, limit) {
+			return 0, 0, ErrDecimalOverflow
+		}
+		scale++
+	}
+	if scale > math.MaxInt32 {
+		return 0, 0, ErrDecimalOverflow
+	}
+	if minus {
+		return int64(-mant), int32(scale), nil
+	}
+	return int64(mant), int32(scale), nil
+}
+
+// mulAdd10 sets *d to *d*10+digit if the result doesn't exceed limit.
+//
+// false is returned if the result exceeds limit.
+func mulAdd10(d *uint64, digit byte, limit uint64) bool {
+	if *d > (limit-uint64(digit))/10 {
+		return false
+	}
+	*d = *d*10 + uint64(digit)
+	return true
+}
@@ -0,0 +1,86 @@
+package fastfloat
+
+import (
+	"testing"
+)
+
+func TestParseDecimalSuccess(t *testing.T) {
+	f := func(s string, mantissaExpected int64, scaleExpected int32) {
+		t.Helper()
+
+		mantissa, scale, err := ParseDecimal(s)
+		if err != nil {
+			t.Fatalf("unexpected error in ParseDecimal(%q): %s", s, err)
+		}
+		if mantissa != mantissaExpected || scale != scaleExpected {
+			t.Fatalf("unexpected result for ParseDecimal(%q); got mantissa=%d, scale=%d; want mantissa=%d, scale=%d",
+				s, mantissa, scale, mantissaExpected, scaleExpected)
+		}
+	}
+
+	// Integers
+	f("0", 0, 0)
+	f("-0", 0, 0)
+	f("123", 123, 0)
+	f("-123", -123, 0)
+	f("9223372036854775807", 9223372036854775807, 0)
+	f("-9223372036854775808", -9223372036854775808, 0)
+
+	// Fractional part
+	f("0.10", 10, 2)
+	f("0.00", 0, 2)
+	f("-1.5", -15, 1)
+	f("12.", 12, 0)
+	f(".5", 5, 1)
+	f("0.000000000000000000001", 1, 21)
+	f("922337203685477580.7", 9223372036854775807, 1)
+
+	// Exponent part
+	f("8.54E-4", 854, 6)
+	f("-12.3450e2", -123450, 2)
+	f("1.5e1", 15, 0)
+	f("12e3", 12000, 0)
+	f("1e+18", 1000000000000000000, 0)
+	f("1e-5", 1, 5)
+	f("0e99999999999", 0, 0)
+	f("0e-99999999999", 0, 0)
+}
+
+func TestParseDecimalFailure(t *testing.T) {
+	f := func(s string, isOverflow bool) {
+		t.Helper()
+
+		mantissa, scale, err := ParseDecimal(s)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for ParseDecimal(%q)", s)
+		}
+		if (err == ErrDecimalOverflow) != isOverflow {
+			t.Fatalf("unexpected error for ParseDecimal(%q): %s", s, err)
+		}
+		if mantissa != 0 || scale != 0 {
+			t.Fatalf("unexpected result returned from ParseDecimal(%q); got mantissa=%d, scale=%d; want zeros", s, mantissa, scale)
+		}
+	}
+
+	// Overflow
+	f("79228162514264337593543950335", true)
+	f("9223372036854775808", true)
+	f("-9223372036854775809", true)
+	f("0.79228162514264337593543950335", true)
+	f("1e19", true)
+	f("1e99999999999", true)
+	f("1e-99999999999", true)
+
+	// Invalid numbers
+	f("", false)
+	f("-", false)
+	f(".", false)
+	f("-.e1", false)
+	f("+1", false)
+	f("1e", false)
+	f("1e+", false)
+	f("1.2.3", false)
+	f("12foo", false)
+	f("inf", false)
+	f("NaN", false)
+}
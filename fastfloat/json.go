@@ -0,0 +1,60 @@
+package fastfloat
+
+// ParseJSON parses floating-point number s according to JSON number grammar
+// from RFC 8259.
+//
+// Unlike Parse, it rejects numbers, which are invalid in JSON, such as
+// inf, nan, +1, 0123, 1., .5 or 1.e4. Numbers exceeding float64 range
+// such as 1e400 are returned as Inf without error, since they are valid JSON.
+func ParseJSON(s string) (float64, error) {
+	if err := validateJSON(s); err != nil {
+		return 0, err
+	}
+	return Parse(s)
+}
+
+// validateJSON verifies whether s matches JSON number grammar.
+func validateJSON(s string) error {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+
+	// Integer part
+	n := digitsPrefixLen(s[i:])
+	if n == 0 {
+		return syntaxErrorf(s, "missing integer part in JSON number %q", s)
+	}
+	if n > 1 && s[i] == '0' {
+		return syntaxErrorf(s, "leading zeros aren't allowed in JSON number %q", s)
+	}
+	i += n
+
+	// Fractional part
+	if i < len(s) && s[i] == '.' {
+		i++
+		n = digitsPrefixLen(s[i:])
+		if n == 0 {
+			return syntaxErrorf(s, "missing fractional part in JSON number %q", s)
+		}
+		i += n
+	}
+
+	// Exponent part
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		n = digitsPrefixLen(s[i:])
+		if n == 0 {
+			return syntaxErrorf(s, "missing exponent part in JSON number %q", s)
+		}
+		i += n
+	}
+
+	if i < len(s) {
+		return syntaxErrorf(s, "unparsed tail left after parsing JSON number %q: %q", s, s[i:])
+	}
+	return nil
+}
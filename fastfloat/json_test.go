@@ -0,0 +1,116 @@
+package fastfloat
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseJSONMatchesJSONValid(t *testing.T) {
+	// Number cases from fastjson validate tests plus numbers accepted by Parse,
+	// which are invalid in JSON.
+	for _, s := range []string{
+		"1",
+		"0",
+		"0e1",
+		"0e+0",
+		"-0e+0",
+		"-0",
+		"1e6",
+		"1e+6",
+		"-1e+6",
+		"-0e+6",
+		"-103e+1",
+		"-0.01e+006",
+		"-z",
+		"-",
+		"1e",
+		"1e+",
+		"03e+1",
+		"1e.1",
+		"00",
+		"1.e3",
+		"01e+6",
+		"-0.01e+0.6",
+		"123.",
+		"123.345",
+		"001",
+
+		"inf",
+		"-inf",
+		"+Inf",
+		"+iNf",
+		"infinity",
+		"nan",
+		"NaN",
+		"+1",
+		"+0.5",
+		".5",
+		"-.5",
+		"0123",
+		"-0123",
+		"1.e4",
+		"1.",
+		"1E-2",
+		"1e400",
+		"-1e-400",
+		"12345678901234567890",
+		"0.12345678901234567890",
+		"0x10",
+	} {
+		_, err := ParseJSON(s)
+		got := err == nil
+		want := json.Valid([]byte("[" + s + "]"))
+		if got != want {
+			t.Fatalf("unexpected result for ParseJSON(%q); got valid=%v (err=%v); want valid=%v", s, got, err, want)
+		}
+	}
+}
+
+func TestParseJSONFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		num, err := ParseJSON(s)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for ParseJSON(%q)", s)
+		}
+		if num != 0 {
+			t.Fatalf("unexpected number returned from ParseJSON(%q); got %v; want 0", s, num)
+		}
+	}
+
+	// JSON numbers cannot be empty or surrounded by whitespace.
+	f("")
+	f(" 1")
+	f("1 ")
+
+	f("-inf")
+	f("+1")
+	f("0123")
+	f("1.e4")
+}
+
+func TestParseJSONSuccess(t *testing.T) {
+	f := func(s string, expectedNum float64) {
+		t.Helper()
+
+		num, err := ParseJSON(s)
+		if err != nil {
+			t.Fatalf("unexpected error in ParseJSON(%q): %s", s, err)
+		}
+		if num != expectedNum {
+			t.Fatalf("unexpected number parsed from %q; got %v; want %v", s, num, expectedNum)
+		}
+	}
+
+	f("0", 0)
+	f("-0", 0)
+	f("123", 123)
+	f("-123.456", -123.456)
+	f("1e6", 1e6)
+	f("-0.01e+006", -0.01e+006)
+	f("12345678901234567890", 12345678901234567890)
+	f("1e400", math.Inf(1))
+	f("-1e400", math.Inf(-1))
+}
@@ -1,12 +1,88 @@
 package fastfloat
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
 	"strings"
 )
 
+// ErrSyntax is wrapped by errors returned for strings, which don't contain
+// a valid number.
+var ErrSyntax = errors.New("invalid syntax")
+
+// ErrRange is wrapped by errors returned for numbers, which are out of range
+// for the target type.
+var ErrRange = errors.New("value out of range")
+
+// NumError is the error returned from parse functions.
+//
+// It wraps either ErrSyntax or ErrRange like strconv.NumError does,
+// so errors.Is(err, ErrRange) may be used for distinguishing out of range
+// numbers from malformed numbers.
+type NumError struct {
+	// Num is the string, which cannot be parsed.
+	Num string
+
+	// Err is either ErrSyntax or ErrRange.
+	Err error
+
+	msg string
+}
+
+// Error returns human-readable description for e.
+func (e *NumError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns e.Err.
+func (e *NumError) Unwrap() error {
+	return e.Err
+}
+
+// syntaxErrorf returns NumError wrapping ErrSyntax for s with the formatted message.
+func syntaxErrorf(s, format string, args ...interface{}) error {
+	truncateErrorArgs(args)
+	return &NumError{
+		Num: s,
+		Err: ErrSyntax,
+		msg: fmt.Sprintf(format, args...),
+	}
+}
+
+// strconvErrorf returns NumError for s with the formatted message
+// for the given err returned from strconv.
+//
+// The returned error wraps ErrRange if err is strconv.ErrRange.
+// Otherwise it wraps ErrSyntax.
+func strconvErrorf(s string, err error, format string, args ...interface{}) error {
+	e := ErrSyntax
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+		e = ErrRange
+	}
+	truncateErrorArgs(args)
+	// Do not include err into the message, since it contains the whole s.
+	return &NumError{
+		Num: s,
+		Err: e,
+		msg: fmt.Sprintf(format, args...) + ": " + e.Error(),
+	}
+}
+
+// maxErrorStringLen is the maximum length of strings included into error messages.
+const maxErrorStringLen = 80
+
+// truncateErrorArgs truncates too long strings in args, so huge numbers
+// aren't copied into error messages.
+func truncateErrorArgs(args []interface{}) {
+	for i, arg := range args {
+		if s, ok := arg.(string); ok && len(s) > maxErrorStringLen {
+			args[i] = s[:maxErrorStringLen/2] + "..." + s[len(s)-maxErrorStringLen/2:]
+		}
+	}
+}
+
 // ParseUint64BestEffort parses uint64 number s.
 //
 // It is equivalent to strconv.ParseUint(s, 10, 64), but is faster.
@@ -54,7 +130,7 @@ func ParseUint64BestEffort(s string) uint64 {
 // See also ParseUint64BestEffort.
 func ParseUint64(s string) (uint64, error) {
 	if len(s) == 0 {
-		return 0, fmt.Errorf("cannot parse uint64 from empty string")
+		return 0, syntaxErrorf(s, "cannot parse uint64 from empty string")
 	}
 	i := uint(0)
 	d := uint64(0)
@@ -68,7 +144,7 @@ func ParseUint64(s string) (uint64, error) {
 				// Fall back to slow parsing.
 				dd, err := strconv.ParseUint(s, 10, 64)
 				if err != nil {
-					return 0, err
+					return 0, strconvErrorf(s, err, "cannot parse uint64 from %q", s)
 				}
 				return dd, nil
 			}
@@ -77,11 +153,11 @@ func ParseUint64(s string) (uint64, error) {
 		break
 	}
 	if i <= j {
-		return 0, fmt.Errorf("cannot parse uint64 from %q", s)
+		return 0, syntaxErrorf(s, "cannot parse uint64 from %q", s)
 	}
 	if i < uint(len(s)) {
 		// Unparsed tail left.
-		return 0, fmt.Errorf("unparsed tail left after parsing uint64 from %q: %q", s, s[i:])
+		return 0, syntaxErrorf(s, "unparsed tail left after parsing uint64 from %q: %q", s, s[i:])
 	}
 	return d, nil
 }
@@ -144,14 +220,14 @@ func ParseInt64BestEffort(s string) int64 {
 // See also ParseInt64BestEffort.
 func ParseInt64(s string) (int64, error) {
 	if len(s) == 0 {
-		return 0, fmt.Errorf("cannot parse int64 from empty string")
+		return 0, syntaxErrorf(s, "cannot parse int64 from empty string")
 	}
 	i := uint(0)
 	minus := s[0] == '-'
 	if minus {
 		i++
 		if i >= uint(len(s)) {
-			return 0, fmt.Errorf("cannot parse int64 from %q", s)
+			return 0, syntaxErrorf(s, "cannot parse int64 from %q", s)
 		}
 	}
 
@@ -166,7 +242,7 @@ func ParseInt64(s string) (int64, error) {
 				// Fall back to slow parsing.
 				dd, err := strconv.ParseInt(s, 10, 64)
 				if err != nil {
-					return 0, err
+					return 0, strconvErrorf(s, err, "cannot parse int64 from %q", s)
 				}
 				return dd, nil
 			}
@@ -175,11 +251,11 @@ func ParseInt64(s string) (int64, error) {
 		break
 	}
 	if i <= j {
-		return 0, fmt.Errorf("cannot parse int64 from %q", s)
+		return 0, syntaxErrorf(s, "cannot parse int64 from %q", s)
 	}
 	if i < uint(len(s)) {
 		// Unparsed tail left.
-		return 0, fmt.Errorf("unparsed tail left after parsing int64 form %q: %q", s, s[i:])
+		return 0, syntaxErrorf(s, "unparsed tail left after parsing int64 form %q: %q", s, s[i:])
 	}
 	if minus {
 		d = -d
@@ -187,6 +263,86 @@ func ParseInt64(s string) (int64, error) {
 	return d, nil
 }
 
+// ParseUint64Lenient parses uint64 number s.
+//
+// Unlike ParseUint64, it accepts optional leading '+' and surrounding
+// ASCII whitespace.
+func ParseUint64Lenient(s string) (uint64, error) {
+	ss, err := trimLenientInt(s)
+	if err != nil {
+		return 0, err
+	}
+	return ParseUint64(ss)
+}
+
+// ParseInt64Lenient parses int64 number s.
+//
+// Unlike ParseInt64, it accepts optional leading '+' and surrounding
+// ASCII whitespace.
+func ParseInt64Lenient(s string) (int64, error) {
+	ss, err := trimLenientInt(s)
+	if err != nil {
+		return 0, err
+	}
+	return ParseInt64(ss)
+}
+
+// ParseUint64BestEffortTrim parses uint64 number s surrounded
+// by optional ASCII whitespace.
+//
+// 0 is returned if the number cannot be parsed.
+// See ParseUint64BestEffort for details.
+func ParseUint64BestEffortTrim(s string) uint64 {
+	return ParseUint64BestEffort(strings.TrimFunc(s, isASCIISpace))
+}
+
+// ParseInt64BestEffortTrim parses int64 number s surrounded
+// by optional ASCII whitespace.
+//
+// 0 is returned if the number cannot be parsed.
+// See ParseInt64BestEffort for details.
+func ParseInt64BestEffortTrim(s string) int64 {
+	return ParseInt64BestEffort(strings.TrimFunc(s, isASCIISpace))
+}
+
+// ParseBestEffortTrim parses floating-point number s surrounded
+// by optional ASCII whitespace.
+//
+// 0 is returned if the number cannot be parsed.
+// See ParseBestEffort for details.
+func ParseBestEffortTrim(s string) float64 {
+	return ParseBestEffort(strings.TrimFunc(s, isASCIISpace))
+}
+
+// ParseTrim parses floating-point number s surrounded by optional
+// ASCII whitespace.
+//
+// Whitespace inside the number isn't allowed. See Parse for details.
+func ParseTrim(s string) (float64, error) {
+	return Parse(strings.TrimFunc(s, isASCIISpace))
+}
+
+// trimLenientInt trims surrounding whitespace and leading '+' from s.
+func trimLenientInt(s string) (string, error) {
+	ss := strings.TrimFunc(s, isASCIISpace)
+	if strings.HasPrefix(ss, "+") {
+		ss = ss[1:]
+		if len(ss) == 0 || ss[0] < '0' || ss[0] > '9' {
+			return "", syntaxErrorf(s, "missing digits after '+' in %q", s)
+		}
+	}
+	return ss, nil
+}
+
+func isASCIISpace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	default:
+		return false
+	}
+}
+
 // Exact powers of 10.
 //
 // This works faster than math.Pow10, since it avoids additional multiplication.
@@ -360,21 +516,21 @@ func ParseBestEffort(s string) float64 {
 // See also ParseBestEffort.
 func Parse(s string) (float64, error) {
 	if len(s) == 0 {
-		return 0, fmt.Errorf("cannot parse float64 from empty string")
+		return 0, syntaxErrorf(s, "cannot parse float64 from empty string")
 	}
 	i := uint(0)
 	minus := s[0] == '-'
 	if minus {
 		i++
 		if i >= uint(len(s)) {
-			return 0, fmt.Errorf("cannot parse float64 from %q", s)
+			return 0, syntaxErrorf(s, "cannot parse float64 from %q", s)
 		}
 	}
 
 	// the integer part might be elided to remain compliant
 	// with https://go.dev/ref/spec#Floating-point_literals
 	if s[i] == '.' && (i+1 >= uint(len(s)) || s[i+1] < '0' || s[i+1] > '9') {
-		return 0, fmt.Errorf("missing integer and fractional part in %q", s)
+		return 0, syntaxErrorf(s, "missing integer and fractional part in %q", s)
 	}
 
 	d := uint64(0)
@@ -388,7 +544,7 @@ func Parse(s string) (float64, error) {
 				// Fall back to slow parsing.
 				f, err := strconv.ParseFloat(s, 64)
 				if err != nil && !math.IsInf(f, 0) {
-					return 0, err
+					return 0, strconvErrorf(s, err, "cannot parse float64 from %q", s)
 				}
 				return f, nil
 			}
@@ -412,7 +568,7 @@ func Parse(s string) (float64, error) {
 		if strings.EqualFold(ss, "nan") {
 			return nan, nil
 		}
-		return 0, fmt.Errorf("unparsed tail left after parsing float64 from %q: %q", s, ss)
+		return 0, syntaxErrorf(s, "unparsed tail left after parsing float64 from %q: %q", s, ss)
 	}
 	f := float64(d)
 	if i >= uint(len(s)) {
@@ -440,7 +596,7 @@ func Parse(s string) (float64, error) {
 					// The mantissa is out of range. Fall back to standard parsing.
 					f, err := strconv.ParseFloat(s, 64)
 					if err != nil && !math.IsInf(f, 0) {
-						return 0, fmt.Errorf("cannot parse mantissa in %q: %s", s, err)
+						return 0, strconvErrorf(s, err, "cannot parse mantissa in %q", s)
 					}
 					return f, nil
 				}
@@ -449,7 +605,7 @@ func Parse(s string) (float64, error) {
 			break
 		}
 		if i < k {
-			return 0, fmt.Errorf("cannot find mantissa in %q", s)
+			return 0, syntaxErrorf(s, "cannot find mantissa in %q", s)
 		}
 		// Convert the entire mantissa to a float at once to avoid rounding errors.
 		f = float64(d) / float64pow10[i-k]
@@ -465,14 +621,14 @@ func Parse(s string) (float64, error) {
 		// Parse exponent part.
 		i++
 		if i >= uint(len(s)) {
-			return 0, fmt.Errorf("cannot parse exponent in %q", s)
+			return 0, syntaxErrorf(s, "cannot parse exponent in %q", s)
 		}
 		expMinus := false
 		if s[i] == '+' || s[i] == '-' {
 			expMinus = s[i] == '-'
 			i++
 			if i >= uint(len(s)) {
-				return 0, fmt.Errorf("cannot parse exponent in %q", s)
+				return 0, syntaxErrorf(s, "cannot parse exponent in %q", s)
 			}
 		}
 		exp := int16(0)
@@ -486,7 +642,7 @@ func Parse(s string) (float64, error) {
 					// Fall back to standard parsing.
 					f, err := strconv.ParseFloat(s, 64)
 					if err != nil && !math.IsInf(f, 0) {
-						return 0, fmt.Errorf("cannot parse exponent in %q: %s", s, err)
+						return 0, strconvErrorf(s, err, "cannot parse exponent in %q", s)
 					}
 					return f, nil
 				}
@@ -495,7 +651,7 @@ func Parse(s string) (float64, error) {
 			break
 		}
 		if i <= j {
-			return 0, fmt.Errorf("cannot parse exponent in %q", s)
+			return 0, syntaxErrorf(s, "cannot parse exponent in %q", s)
 		}
 		if expMinus {
 			exp = -exp
@@ -508,8 +664,76 @@ func Parse(s string) (float64, error) {
 			return f, nil
 		}
 	}
-	return 0, fmt.Errorf("cannot parse float64 from %q", s)
+	return 0, syntaxErrorf(s, "cannot parse float64 from %q", s)
 }
 
 var inf = math.Inf(1)
 var nan = math.NaN()
+
+// Options contains options for ParseExt.
+type Options struct {
+	// AllowHexFloat enables parsing hexadecimal floating-point numbers
+	// in Go syntax such as 0x1.8p3.
+	AllowHexFloat bool
+
+	// AllowUnderscores enables underscores between digits such as 1_000_000.
+	AllowUnderscores bool
+}
+
+// ParseExt parses floating-point number s with the given opts.
+//
+// ParseExt is equivalent to Parse for zero opts.
+func ParseExt(s string, opts Options) (float64, error) {
+	hex := opts.AllowHexFloat && isHexFloat(s)
+	if opts.AllowUnderscores && strings.IndexByte(s, '_') >= 0 {
+		ss, err := removeUnderscores(s, hex)
+		if err != nil {
+			return 0, err
+		}
+		s = ss
+	}
+	if hex {
+		if strings.IndexByte(s, '_') >= 0 {
+			// strconv accepts underscores in hexadecimal floats, so reject them explicitly.
+			return 0, syntaxErrorf(s, "underscores aren't allowed in %q", s)
+		}
+		// Hexadecimal floats are rare, so fall back to strconv.
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, strconvErrorf(s, err, "cannot parse hexadecimal float64 from %q", s)
+		}
+		return f, nil
+	}
+	return Parse(s)
+}
+
+func isHexFloat(s string) bool {
+	if strings.HasPrefix(s, "-") {
+		s = s[1:]
+	}
+	return len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X')
+}
+
+// removeUnderscores removes underscores between digits in s.
+//
+// Hexadecimal digits are taken into account if hex is set.
+func removeUnderscores(s string, hex bool) (string, error) {
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' {
+			b = append(b, s[i])
+			continue
+		}
+		if i == 0 || i+1 >= len(s) || !isDigit(s[i-1], hex) || !isDigit(s[i+1], hex) {
+			return "", syntaxErrorf(s, "underscore must be located between digits in %q", s)
+		}
+	}
+	return string(b), nil
+}
+
+func isDigit(ch byte, hex bool) bool {
+	if ch >= '0' && ch <= '9' {
+		return true
+	}
+	return hex && (ch >= 'a' && ch <= 'f' || ch >= 'A' && ch <= 'F')
+}
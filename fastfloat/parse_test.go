@@ -1,9 +1,11 @@
 package fastfloat
 
 import (
+	"fmt"
 	"math"
 	"math/rand"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -71,6 +73,94 @@ func TestParseUint64Failure(t *testing.T) {
 	f("18446744073709551616")
 }
 
+func TestParseErrors(t *testing.T) {
+	f := func(name string, err, errExpected error) {
+		t.Helper()
+		if err == nil {
+			t.Fatalf("%s: expecting non-nil error", name)
+		}
+		ne, ok := err.(*NumError)
+		if !ok {
+			t.Fatalf("%s: unexpected error type %T; want *NumError", name, err)
+		}
+		if ne.Err != errExpected {
+			t.Fatalf("%s: unexpected NumError.Err; got %q; want %q", name, ne.Err, errExpected)
+		}
+		if ne.Unwrap() != errExpected {
+			t.Fatalf("%s: unexpected wrapped error %q; want %q", name, ne.Unwrap(), errExpected)
+		}
+	}
+	fUint64 := func(s string, errExpected error) {
+		t.Helper()
+		_, err := ParseUint64(s)
+		f(fmt.Sprintf("ParseUint64(%q)", s), err, errExpected)
+	}
+	fInt64 := func(s string, errExpected error) {
+		t.Helper()
+		_, err := ParseInt64(s)
+		f(fmt.Sprintf("ParseInt64(%q)", s), err, errExpected)
+	}
+	fFloat64 := func(s string, errExpected error) {
+		t.Helper()
+		_, err := Parse(s)
+		f(fmt.Sprintf("Parse(%q)", s), err, errExpected)
+	}
+
+	fUint64("18446744073709551616", ErrRange)
+	fUint64("123456789012345678901234567890", ErrRange)
+	fUint64("", ErrSyntax)
+	fUint64("12x", ErrSyntax)
+	fUint64("-1", ErrSyntax)
+	fUint64("1234567890123456789x", ErrSyntax)
+
+	fInt64("9223372036854775808", ErrRange)
+	fInt64("-9223372036854775809", ErrRange)
+	fInt64("", ErrSyntax)
+	fInt64("12x", ErrSyntax)
+	fInt64("-", ErrSyntax)
+	fInt64("1234567890123456789x", ErrSyntax)
+
+	fFloat64("", ErrSyntax)
+	fFloat64("12x", ErrSyntax)
+	fFloat64("1e", ErrSyntax)
+	fFloat64("12345678901234567890x", ErrSyntax)
+	fFloat64("0.12345678901234567890x", ErrSyntax)
+
+	// Huge numbers mustn't be copied into error messages.
+	for _, s := range []string{strings.Repeat("1", 1<<20), "1" + strings.Repeat("x", 1<<20)} {
+		_, err := ParseUint64(s)
+		if n := len(err.Error()); n > 300 {
+			t.Fatalf("too long error message for ParseUint64 with %d bytes", n)
+		}
+		_, err = ParseInt64(s)
+		if n := len(err.Error()); n > 300 {
+			t.Fatalf("too long error message for ParseInt64 with %d bytes", n)
+		}
+	}
+	_, err := Parse("1" + strings.Repeat("x", 1<<20))
+	if n := len(err.Error()); n > 300 {
+		t.Fatalf("too long error message for Parse with %d bytes", n)
+	}
+
+	// Errors from the helper functions
+	_, err = ParseUint64Lenient("+")
+	f("ParseUint64Lenient", err, ErrSyntax)
+	_, err = ParseInt64Lenient(" 9223372036854775808 ")
+	f("ParseInt64Lenient", err, ErrRange)
+	_, _, err = ParseUint64Prefix("18446744073709551616ms")
+	f("ParseUint64Prefix", err, ErrRange)
+	_, _, err = ParseInt64Prefix("ms")
+	f("ParseInt64Prefix", err, ErrSyntax)
+	_, err = ParseJSON("0123")
+	f("ParseJSON", err, ErrSyntax)
+	_, err = ParseExt("0x1p", Options{AllowHexFloat: true})
+	f("ParseExt", err, ErrSyntax)
+	_, err = ParseExt("1__0", Options{AllowUnderscores: true})
+	f("ParseExt", err, ErrSyntax)
+	_, _, err = ParseDecimal("1.2.3")
+	f("ParseDecimal", err, ErrSyntax)
+}
+
 func TestParseUint64Success(t *testing.T) {
 	f := func(s string, expectedNum uint64) {
 		t.Helper()
@@ -184,6 +274,140 @@ func TestParseInt64Success(t *testing.T) {
 	f("-9223372036854775807", -9223372036854775807)
 }
 
+func TestParseUint64Lenient(t *testing.T) {
+	f := func(s string, expectedNum uint64) {
+		t.Helper()
+
+		num, err := ParseUint64Lenient(s)
+		if err != nil {
+			t.Fatalf("unexpected error in ParseUint64Lenient(%q): %s", s, err)
+		}
+		if num != expectedNum {
+			t.Fatalf("unexpected number parsed from %q; got %v; want %v", s, num, expectedNum)
+		}
+	}
+	f("0", 0)
+	f("+42", 42)
+	f(" 17 ", 17)
+	f("\t+123\r\n", 123)
+	f("18446744073709551615", 18446744073709551615)
+	f(" +18446744073709551615 ", 18446744073709551615)
+
+	ferr := func(s string) {
+		t.Helper()
+
+		num, err := ParseUint64Lenient(s)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for ParseUint64Lenient(%q)", s)
+		}
+		if num != 0 {
+			t.Fatalf("unexpected number returned from ParseUint64Lenient(%q); got %v; want %v", s, num, 0)
+		}
+	}
+	ferr("")
+	ferr("   ")
+	ferr("+")
+	ferr(" + 1")
+	ferr("++1")
+	ferr("+-1")
+	ferr("-1")
+	ferr("1 2")
+	ferr("12foo")
+	ferr("18446744073709551616")
+	ferr("+18446744073709551616")
+}
+
+func TestParseInt64Lenient(t *testing.T) {
+	f := func(s string, expectedNum int64) {
+		t.Helper()
+
+		num, err := ParseInt64Lenient(s)
+		if err != nil {
+			t.Fatalf("unexpected error in ParseInt64Lenient(%q): %s", s, err)
+		}
+		if num != expectedNum {
+			t.Fatalf("unexpected number parsed from %q; got %v; want %v", s, num, expectedNum)
+		}
+	}
+	f("0", 0)
+	f("+42", 42)
+	f(" 17 ", 17)
+	f(" -17\n", -17)
+	f("9223372036854775807", 9223372036854775807)
+	f(" +9223372036854775807 ", 9223372036854775807)
+	f("-9223372036854775808", -9223372036854775808)
+
+	ferr := func(s string) {
+		t.Helper()
+
+		num, err := ParseInt64Lenient(s)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for ParseInt64Lenient(%q)", s)
+		}
+		if num != 0 {
+			t.Fatalf("unexpected number returned from ParseInt64Lenient(%q); got %v; want %v", s, num, 0)
+		}
+	}
+	ferr("")
+	ferr("  ")
+	ferr("+")
+	ferr("+-1")
+	ferr("-+1")
+	ferr("- 1")
+	ferr("1 2")
+	ferr("12foo")
+	ferr("9223372036854775808")
+	ferr("+9223372036854775808")
+	ferr("-9223372036854775809")
+}
+
+func TestParseBestEffortTrim(t *testing.T) {
+	f := func(s string, expectedNum float64) {
+		t.Helper()
+
+		num := ParseBestEffortTrim(s)
+		if num != expectedNum {
+			t.Fatalf("unexpected number parsed from %q; got %v; want %v", s, num, expectedNum)
+		}
+		num, err := ParseTrim(s)
+		if expectedNum == 0 {
+			if err == nil && num != 0 {
+				t.Fatalf("unexpected number returned from ParseTrim(%q); got %v; want 0", s, num)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("unexpected error in ParseTrim(%q): %s", s, err)
+		}
+		if num != expectedNum {
+			t.Fatalf("unexpected number returned from ParseTrim(%q); got %v; want %v", s, num, expectedNum)
+		}
+	}
+	f("42 ", 42)
+	f(" 3.14", 3.14)
+	f("\t-1e3\r\n", -1000)
+	f("  0  ", 0)
+	f("", 0)
+	f("   ", 0)
+	f("1,000", 0)
+	f("1 000", 0)
+	f("4 2", 0)
+	f(" foo ", 0)
+
+	if n := ParseInt64BestEffortTrim(" -42\n"); n != -42 {
+		t.Fatalf("unexpected ParseInt64BestEffortTrim result; got %d; want %d", n, -42)
+	}
+	if n := ParseInt64BestEffortTrim("4 2"); n != 0 {
+		t.Fatalf("unexpected ParseInt64BestEffortTrim result; got %d; want %d", n, 0)
+	}
+	if n := ParseUint64BestEffortTrim("\t42 "); n != 42 {
+		t.Fatalf("unexpected ParseUint64BestEffortTrim result; got %d; want %d", n, 42)
+	}
+	if n := ParseUint64BestEffortTrim(" -1 "); n != 0 {
+		t.Fatalf("unexpected ParseUint64BestEffortTrim result; got %d; want %d", n, 0)
+	}
+}
+
 func TestParseBestEffort(t *testing.T) {
 	f := func(s string, expectedNum float64) {
 		t.Helper()
@@ -507,3 +731,78 @@ func TestParseFuzz(t *testing.T) {
 		}
 	}
 }
+
+func TestParseExtSuccess(t *testing.T) {
+	f := func(s string, opts Options, expectedNum float64) {
+		t.Helper()
+
+		num, err := ParseExt(s, opts)
+		if err != nil {
+			t.Fatalf("unexpected error in ParseExt(%q): %s", s, err)
+		}
+		if num != expectedNum {
+			t.Fatalf("unexpected number parsed from %q; got %v; want %v", s, num, expectedNum)
+		}
+	}
+
+	// Default options
+	f("0", Options{}, 0)
+	f("-12.5e2", Options{}, -1250)
+
+	// Hex floats
+	hexOpts := Options{AllowHexFloat: true}
+	f("0x1p-2", hexOpts, 0.25)
+	f("0X1.FP+3", hexOpts, 15.5)
+	f("0x1.8p3", hexOpts, 12)
+	f("-0x1p4", hexOpts, -16)
+	f("12.5", hexOpts, 12.5)
+
+	// Underscores
+	underscoreOpts := Options{AllowUnderscores: true}
+	f("1_000.5", underscoreOpts, 1000.5)
+	f("1_000_000", underscoreOpts, 1e6)
+	f("-1_0.2_5e1_0", underscoreOpts, -10.25e10)
+
+	// Hex floats with underscores
+	allOpts := Options{AllowHexFloat: true, AllowUnderscores: true}
+	f("0x1_0p0", allOpts, 16)
+	f("0xf_fp-4", allOpts, 15.9375)
+	f("1_000.5", allOpts, 1000.5)
+}
+
+func TestParseExtFailure(t *testing.T) {
+	f := func(s string, opts Options) {
+		t.Helper()
+
+		num, err := ParseExt(s, opts)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for ParseExt(%q)", s)
+		}
+		if num != 0 {
+			t.Fatalf("unexpected number returned from ParseExt(%q); got %v; want %v", s, num, 0)
+		}
+	}
+
+	// Extensions are disabled by default
+	f("0x1p-2", Options{})
+	f("1_000", Options{})
+
+	// Invalid hex floats
+	hexOpts := Options{AllowHexFloat: true}
+	f("0x", hexOpts)
+	f("0x1.8", hexOpts)
+	f("0xzp1", hexOpts)
+	f("0x1p", hexOpts)
+	f("0x1_0p0", hexOpts)
+
+	// Invalid underscores
+	underscoreOpts := Options{AllowUnderscores: true}
+	f("_1000", underscoreOpts)
+	f("1000_", underscoreOpts)
+	f("1__000", underscoreOpts)
+	f("-_1", underscoreOpts)
+	f("1_.5", underscoreOpts)
+	f("1._5", underscoreOpts)
+	f("1e_5", underscoreOpts)
+	f("0x1_0p0", underscoreOpts)
+}
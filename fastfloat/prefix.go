@@ -0,0 +1,132 @@
+package fastfloat
+
+import (
+	"strings"
+)
+
+// ParsePrefix parses the longest floating-point number prefix of s.
+//
+// It returns the parsed number and the number of bytes consumed from s,
+// so numbers embedded into bigger strings such as "123.4ms" may be parsed
+// without searching for the number end. Inf and NaN prefixes are supported
+// in the same way as in ParseBestEffort.
+//
+// An error is returned if s doesn't start with a valid number.
+func ParsePrefix(s string) (float64, int, error) {
+	n := floatPrefixLen(s)
+	if n == 0 {
+		return 0, 0, syntaxErrorf(s, "cannot find float64 number at the start of %q", s)
+	}
+	f, err := Parse(s[:n])
+	if err != nil {
+		return 0, 0, err
+	}
+	return f, n, nil
+}
+
+// ParseInt64Prefix parses the longest int64 number prefix of s.
+//
+// It returns the parsed number and the number of bytes consumed from s.
+//
+// An error is returned if s doesn't start with a valid number
+// or if the number doesn't fit int64.
+func ParseInt64Prefix(s string) (int64, int, error) {
+	n := 0
+	if len(s) > 0 && s[0] == '-' {
+		n++
+	}
+	n += digitsPrefixLen(s[n:])
+	if n == 0 || s[n-1] == '-' {
+		return 0, 0, syntaxErrorf(s, "cannot find int64 number at the start of %q", s)
+	}
+	d, err := ParseInt64(s[:n])
+	if err != nil {
+		return 0, 0, err
+	}
+	return d, n, nil
+}
+
+// ParseUint64Prefix parses the longest uint64 number prefix of s.
+//
+// It returns the parsed number and the number of bytes consumed from s.
+//
+// An error is returned if s doesn't start with a valid number
+// or if the number doesn't fit uint64.
+func ParseUint64Prefix(s string) (uint64, int, error) {
+	n := digitsPrefixLen(s)
+	if n == 0 {
+		return 0, 0, syntaxErrorf(s, "cannot find uint64 number at the start of %q", s)
+	}
+	d, err := ParseUint64(s[:n])
+	if err != nil {
+		return 0, 0, err
+	}
+	return d, n, nil
+}
+
+// floatPrefixLen returns the length of the longest number prefix of s,
+// which is accepted by Parse.
+//
+// 0 is returned if s doesn't start with a number.
+func floatPrefixLen(s string) int {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+
+	// Integer part
+	j := i
+	i += digitsPrefixLen(s[i:])
+	digits := i - j
+
+	// Fractional part
+	if i < len(s) && s[i] == '.' {
+		n := digitsPrefixLen(s[i+1:])
+		if digits > 0 || n > 0 {
+			i += 1 + n
+			digits += n
+		}
+	}
+	if digits == 0 {
+		return infNaNPrefixLen(s, j)
+	}
+
+	// Exponent part. It is consumed only if it contains digits.
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		k := i + 1
+		if k < len(s) && (s[k] == '+' || s[k] == '-') {
+			k++
+		}
+		if n := digitsPrefixLen(s[k:]); n > 0 {
+			i = k + n
+		}
+	}
+	return i
+}
+
+// infNaNPrefixLen returns the length of inf or nan prefix of s,
+// which starts at the given offset.
+//
+// 0 is returned if there is no inf or nan prefix.
+func infNaNPrefixLen(s string, offset int) int {
+	i := offset
+	if i < len(s) && s[i] == '+' {
+		i++
+	}
+	ss := s[i:]
+	// "infinity" must be checked before "inf", since the longest prefix is needed.
+	for _, prefix := range []string{"infinity", "inf", "nan"} {
+		if len(ss) >= len(prefix) && strings.EqualFold(ss[:len(prefix)], prefix) {
+			return i + len(prefix)
+		}
+	}
+	return 0
+}
+
+func digitsPrefixLen(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return n
+}
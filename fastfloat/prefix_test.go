@@ -0,0 +1,149 @@
+package fastfloat
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePrefixSuccess(t *testing.T) {
+	f := func(s string, fExpected float64, nExpected int) {
+		t.Helper()
+
+		f, n, err := ParsePrefix(s)
+		if err != nil {
+			t.Fatalf("unexpected error in ParsePrefix(%q): %s", s, err)
+		}
+		if n != nExpected {
+			t.Fatalf("unexpected number of consumed bytes in ParsePrefix(%q); got %d; want %d", s, n, nExpected)
+		}
+		if math.IsNaN(fExpected) {
+			if !math.IsNaN(f) {
+				t.Fatalf("unexpected result for ParsePrefix(%q); got %v; want NaN", s, f)
+			}
+			return
+		}
+		if f != fExpected {
+			t.Fatalf("unexpected result for ParsePrefix(%q); got %v; want %v", s, f, fExpected)
+		}
+	}
+
+	// Whole strings
+	f("0", 0, 1)
+	f("-123", -123, 4)
+	f("1.5e-3", 1.5e-3, 6)
+	f(".5", 0.5, 2)
+	f("12.", 12, 3)
+	f("12345678901234567890123", 12345678901234567890123, 23)
+
+	// Numbers with tails
+	f("123.4ms", 123.4, 5)
+	f("-1e3,rest", -1000, 4)
+	f("85%", 85, 2)
+	f("1.5.6", 1.5, 3)
+	f("12e", 12, 2)
+	f("12e+", 12, 2)
+	f("12E-x", 12, 2)
+	f("7e2e3", 700, 3)
+	f("0x10", 0, 1)
+	f("1_000", 1, 1)
+	f("3 apples", 3, 1)
+
+	// Inf and NaN
+	f("inf", math.Inf(1), 3)
+	f("-Inf,", math.Inf(-1), 4)
+	f("+infinityX", math.Inf(1), 9)
+	f("Infinit", math.Inf(1), 3)
+	f("NaN%", math.NaN(), 3)
+}
+
+func TestParsePrefixFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		f, n, err := ParsePrefix(s)
+		if err == nil {
+			t.Fatalf("expecting non-nil error in ParsePrefix(%q)", s)
+		}
+		if f != 0 || n != 0 {
+			t.Fatalf("unexpected result for ParsePrefix(%q); got (%v, %d); want (0, 0)", s, f, n)
+		}
+	}
+
+	f("")
+	f("e5")
+	f("-")
+	f(".")
+	f("-.e5")
+	f("+1")
+	f("abc")
+	f(" 1")
+	f("in")
+	f("na")
+}
+
+func TestParseInt64PrefixSuccess(t *testing.T) {
+	f := func(s string, dExpected int64, nExpected int) {
+		t.Helper()
+
+		d, n, err := ParseInt64Prefix(s)
+		if err != nil {
+			t.Fatalf("unexpected error in ParseInt64Prefix(%q): %s", s, err)
+		}
+		if d != dExpected || n != nExpected {
+			t.Fatalf("unexpected result for ParseInt64Prefix(%q); got (%d, %d); want (%d, %d)", s, d, n, dExpected, nExpected)
+		}
+	}
+
+	f("0", 0, 1)
+	f("-123ms", -123, 4)
+	f("85%", 85, 2)
+	f("1.5", 1, 1)
+	f("9223372036854775807,", 9223372036854775807, 19)
+	f("-9223372036854775808 ", -9223372036854775808, 20)
+}
+
+func TestParseInt64PrefixFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		d, n, err := ParseInt64Prefix(s)
+		if err == nil {
+			t.Fatalf("expecting non-nil error in ParseInt64Prefix(%q)", s)
+		}
+		if d != 0 || n != 0 {
+			t.Fatalf("unexpected result for ParseInt64Prefix(%q); got (%d, %d); want (0, 0)", s, d, n)
+		}
+	}
+
+	f("")
+	f("-")
+	f("-x")
+	f("e5")
+	f("+1")
+	f("9223372036854775808")
+	f("-9223372036854775809ms")
+}
+
+func TestParseUint64Prefix(t *testing.T) {
+	f := func(s string, dExpected uint64, nExpected int) {
+		t.Helper()
+
+		d, n, err := ParseUint64Prefix(s)
+		if err != nil {
+			t.Fatalf("unexpected error in ParseUint64Prefix(%q): %s", s, err)
+		}
+		if d != dExpected || n != nExpected {
+			t.Fatalf("unexpected result for ParseUint64Prefix(%q); got (%d, %d); want (%d, %d)", s, d, n, dExpected, nExpected)
+		}
+	}
+
+	f("0", 0, 1)
+	f("123ms", 123, 3)
+	f("18446744073709551615]", 18446744073709551615, 20)
+
+	for _, s := range []string{"", "-1", "x", "18446744073709551616"} {
+		if _, _, err := ParseUint64Prefix(s); err == nil {
+			t.Fatalf("expecting non-nil error in ParseUint64Prefix(%q)", s)
+		}
+	}
+}
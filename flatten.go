@@ -0,0 +1,336 @@
+package fastjson
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FlattenOpts contains options for Value.FlattenOpts.
+type FlattenOpts struct {
+	// KeepNull enables storing null values as empty strings.
+	//
+	// By default null values are omitted.
+	KeepNull bool
+}
+
+// Flatten stores scalar leaves of v into dst under paths joined with sep
+// and returns the resulting dst.
+//
+// For instance, {"items":[{"name":"foo"}]} is flattened into items.0.name=foo
+// if sep is ".". "." is used if sep is empty. sep cannot contain backslash.
+//
+// Strings are unescaped, numbers are stored in their original text
+// representation, while true and false are stored as "true" and "false".
+// Null values and empty objects and arrays are omitted. Scalar v is stored
+// under the empty key.
+//
+// Object keys containing sep or backslash are escaped with backslash.
+// Object keys consisting of digits are prefixed with backslash,
+// so they aren't confused with array indexes. Unflatten reverts the escaping.
+//
+// A new map is allocated if dst is nil. Keys and values in dst don't refer
+// to v, so they remain valid after the next Parse call.
+func (v *Value) Flatten(sep string, dst map[string]string) map[string]string {
+	return v.FlattenOpts(sep, dst, FlattenOpts{})
+}
+
+// FlattenOpts stores scalar leaves of v into dst according to opts
+// and returns the resulting dst.
+//
+// See Flatten for details.
+func (v *Value) FlattenOpts(sep string, dst map[string]string, opts FlattenOpts) map[string]string {
+	if len(sep) == 0 {
+		sep = "."
+	}
+	if strings.IndexByte(sep, '\\') >= 0 {
+		panic(fmt.Errorf("separator for Value.Flatten cannot contain backslash; got %q", sep))
+	}
+	if dst == nil {
+		dst = make(map[string]string)
+	}
+	if v == nil {
+		return dst
+	}
+	fl := flattener{
+		sep:  sep,
+		dst:  dst,
+		opts: opts,
+	}
+	fl.flatten(v, false)
+	return fl.dst
+}
+
+type flattener struct {
+	sep  string
+	dst  map[string]string
+	opts FlattenOpts
+
+	// path is the escaped path to the currently flattened value.
+	path []byte
+}
+
+func (fl *flattener) flatten(v *Value, hasParent bool) {
+	switch v.Type() {
+	case TypeObject:
+		v.o.unescapeKeys()
+		pathLen := len(fl.path)
+		for _, kv := range v.o.kvs {
+			if hasParent {
+				fl.path = append(fl.path, fl.sep...)
+			}
+			fl.path = appendFlattenKey(fl.path, kv.k, fl.sep)
+			fl.flatten(kv.v, true)
+			fl.path = fl.path[:pathLen]
+		}
+	case TypeArray:
+		pathLen := len(fl.path)
+		for i, vv := range v.a {
+			if hasParent {
+				fl.path = append(fl.path, fl.sep...)
+			}
+			fl.path = strconv.AppendInt(fl.path, int64(i), 10)
+			fl.flatten(vv, true)
+			fl.path = fl.path[:pathLen]
+		}
+	case TypeString, TypeNumber:
+		// Convert via []byte in order to make a copy of v.s.
+		fl.dst[string(fl.path)] = string(s2b(v.s))
+	case TypeTrue:
+		fl.dst[string(fl.path)] = "true"
+	case TypeFalse:
+		fl.dst[string(fl.path)] = "false"
+	case TypeNull:
+		if fl.opts.KeepNull {
+			fl.dst[string(fl.path)] = ""
+		}
+	default:
+		panic(fmt.Errorf("BUG: unexpected Value type: %d", v.t))
+	}
+}
+
+// appendFlattenKey appends escaped object key k to dst.
+func appendFlattenKey(dst []byte, k, sep string) []byte {
+	if isDigits(k) {
+		return append(append(dst, '\\'), k...)
+	}
+	for i := 0; i < len(k); i++ {
+		if k[i] == '\\' {
+			dst = append(dst, '\\', '\\')
+			continue
+		}
+		if strings.HasPrefix(k[i:], sep) {
+			dst = append(dst, '\\')
+			dst = append(dst, sep...)
+			i += len(sep) - 1
+			continue
+		}
+		dst = append(dst, k[i])
+	}
+	return dst
+}
+
+func isDigits(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// maxUnflattenArrayLen is the maximum length of arrays created by Unflatten.
+//
+// It protects from excessive memory usage for paths such as a.1000000000.
+const maxUnflattenArrayLen = 1 << 20
+
+// Unflatten builds a nested value from m produced by Value.Flatten with "." separator.
+//
+// See UnflattenSep for details.
+func Unflatten(m map[string]string, a *Arena) (*Value, error) {
+	return UnflattenSep(m, ".", a)
+}
+
+// UnflattenSep builds a nested value from m produced by Value.Flatten
+// with the given sep. "." is used if sep is empty.
+//
+// This is the inverse of Value.Flatten. Path elements consisting of
+// unescaped digits are treated as array indexes, while the rest of path
+// elements are treated as object keys. Missing array items are filled with nulls.
+// Values in m are stored as strings, since their original types are unknown.
+// Object keys are sorted. The returned value is always an object or an array.
+// Empty object is returned for empty m.
+//
+// An error is returned if paths in m conflict with each other,
+// such as a=x and a.b=y, or if they contain invalid escape sequences.
+//
+// The returned value is valid until Reset is called on a.
+func UnflattenSep(m map[string]string, sep string, a *Arena) (*Value, error) {
+	if len(sep) == 0 {
+		sep = "."
+	}
+	if strings.IndexByte(sep, '\\') >= 0 {
+		return nil, fmt.Errorf("separator cannot contain backslash; got %q", sep)
+	}
+	paths := make([]string, 0, len(m))
+	for path := range m {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var root *Value
+	var segs []flattenSegment
+	for _, path := range paths {
+		var err error
+		segs, err = appendFlattenSegments(segs[:0], path, sep)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse path %q: %s", path, err)
+		}
+		if root == nil {
+			root = newUnflattenContainer(a, segs[0])
+		}
+		if err := unflattenSet(a, root, segs, a.NewString(m[path])); err != nil {
+			return nil, fmt.Errorf("cannot set %q: %s", path, err)
+		}
+	}
+	if root == nil {
+		root = a.NewObject()
+	}
+	return root, nil
+}
+
+// flattenSegment is a path element for Unflatten.
+type flattenSegment struct {
+	key     string
+	idx     int
+	isIndex bool
+}
+
+// appendFlattenSegments appends unescaped path elements of the given path to dst.
+func appendFlattenSegments(dst []flattenSegment, path, sep string) ([]flattenSegment, error) {
+	for {
+		n := 0
+		var b []byte
+		isEscaped := false
+		for n < len(path) && !strings.HasPrefix(path[n:], sep) {
+			if path[n] != '\\' {
+				if isEscaped {
+					b = append(b, path[n])
+				}
+				n++
+				continue
+			}
+			if n+1 >= len(path) {
+				return dst, fmt.Errorf("trailing backslash")
+			}
+			if !isEscaped {
+				b = append(b, path[:n]...)
+				isEscaped = true
+			}
+			if strings.HasPrefix(path[n+1:], sep) {
+				b = append(b, sep...)
+				n += 1 + len(sep)
+				continue
+			}
+			b = append(b, path[n+1])
+			n += 2
+		}
+		seg := flattenSegment{
+			key: path[:n],
+		}
+		if isEscaped {
+			seg.key = string(b)
+		} else if isDigits(seg.key) {
+			if len(seg.key) > 1 && seg.key[0] == '0' {
+				return dst, fmt.Errorf("array index cannot start with 0; got %q", seg.key)
+			}
+			seg.isIndex = true
+			for i := 0; i < len(seg.key); i++ {
+				seg.idx = seg.idx*10 + int(seg.key[i]-'0')
+				if seg.idx >= maxUnflattenArrayLen {
+					return dst, fmt.Errorf("too big array index %s; it must be smaller than %d", seg.key, maxUnflattenArrayLen)
+				}
+			}
+		}
+		dst = append(dst, seg)
+		if n == len(path) {
+			return dst, nil
+		}
+		path = path[n+len(sep):]
+	}
+}
+
+func newUnflattenContainer(a *Arena, seg flattenSegment) *Value {
+	if seg.isIndex {
+		return a.NewArray()
+	}
+	return a.NewObject()
+}
+
+// unflattenSet sets value at the path in v.
+func unflattenSet(a *Arena, v *Value, segs []flattenSegment, value *Value) error {
+	for i, seg := range segs {
+		var child *Value
+		kvIdx := -1
+		switch {
+		case v.t == TypeObject && !seg.isIndex:
+			kvIdx = findUnflattenKey(v.o.kvs, seg.key)
+			if kvIdx >= 0 {
+				child = v.o.kvs[kvIdx].v
+			}
+		case v.t == TypeArray && seg.isIndex:
+			if seg.idx < len(v.a) {
+				child = v.a[seg.idx]
+			}
+		case v.t == TypeObject:
+			return fmt.Errorf("cannot use array index %d for object", seg.idx)
+		default:
+			return fmt.Errorf("cannot use object key %q for array", seg.key)
+		}
+
+		isLast := i == len(segs)-1
+		if child != nil && child.t != TypeNull {
+			if isLast || child.t != TypeObject && child.t != TypeArray {
+				return fmt.Errorf("conflicting values for %q", seg.key)
+			}
+			v = child
+			continue
+		}
+		child = value
+		if !isLast {
+			child = newUnflattenContainer(a, segs[i+1])
+		}
+		switch {
+		case seg.isIndex:
+			v.SetArrayItem(seg.idx, child)
+		case kvIdx >= 0:
+			v.o.kvs[kvIdx].v = child
+		default:
+			kv := v.o.getKV()
+			kv.k = seg.key
+			kv.v = child
+		}
+		v = child
+	}
+	return nil
+}
+
+// findUnflattenKey returns the index of the given key in kvs.
+//
+// -1 is returned if kvs doesn't contain the key.
+func findUnflattenKey(kvs []kv, key string) int {
+	// Paths are sorted, so the key is usually the last one.
+	if n := len(kvs); n > 0 && kvs[n-1].k == key {
+		return n - 1
+	}
+	for i := range kvs {
+		if kvs[i].k == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,179 @@
+package fastjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValueFlatten(t *testing.T) {
+	f := func(s, sep string, opts FlattenOpts, mExpected map[string]string) {
+		t.Helper()
+		v := MustParse(s)
+		m := v.FlattenOpts(sep, nil, opts)
+		if !reflect.DeepEqual(m, mExpected) {
+			t.Fatalf("unexpected result for %s;\ngot\n%q\nwant\n%q", s, m, mExpected)
+		}
+	}
+
+	f(`{}`, ".", FlattenOpts{}, map[string]string{})
+	f(`[]`, ".", FlattenOpts{}, map[string]string{})
+	f(`"foo"`, ".", FlattenOpts{}, map[string]string{
+		"": "foo",
+	})
+	f(`{"items":[{"name":"foo","n":1.50},{"name":"bar"}],"ok":true,"no":false,"nil":null,"e":{},"ea":[]}`, ".", FlattenOpts{}, map[string]string{
+		"items.0.name": "foo",
+		"items.0.n":    "1.50",
+		"items.1.name": "bar",
+		"ok":           "true",
+		"no":           "false",
+	})
+	f(`{"a":{"b":null},"c":[null,1]}`, ".", FlattenOpts{}, map[string]string{
+		"c.1": "1",
+	})
+	f(`{"a":{"b":null},"c":[null,1]}`, ".", FlattenOpts{KeepNull: true}, map[string]string{
+		"a.b": "",
+		"c.0": "",
+		"c.1": "1",
+	})
+	f(`[[1,[2]],{"x":3}]`, "", FlattenOpts{}, map[string]string{
+		"0.0":   "1",
+		"0.1.0": "2",
+		"1.x":   "3",
+	})
+	f(`{"a":{"b":[1]}}`, "::", FlattenOpts{}, map[string]string{
+		"a::b::0": "1",
+	})
+
+	// Keys containing the separator, backslash or digits must be escaped.
+	f(`{"a.b":{"c\\d":1,"0":2,"":3,"x0":4}}`, ".", FlattenOpts{}, map[string]string{
+		`a\.b.c\\d`: "1",
+		`a\.b.\0`:   "2",
+		`a\.b.`:     "3",
+		`a\.b.x0`:   "4",
+	})
+	f(`{"a::b:c":1}`, "::", FlattenOpts{}, map[string]string{
+		`a\::b:c`: "1",
+	})
+
+	// dst must be re-used.
+	dst := map[string]string{
+		"foo": "bar",
+	}
+	dst = MustParse(`{"x":1}`).Flatten(".", dst)
+	if !reflect.DeepEqual(dst, map[string]string{"foo": "bar", "x": "1"}) {
+		t.Fatalf("unexpected dst: %q", dst)
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+	f := func(m map[string]string, sep, resultExpected string) {
+		t.Helper()
+		var a Arena
+		v, err := UnflattenSep(m, sep, &a)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", m, err)
+		}
+		if result := v.String(); result != resultExpected {
+			t.Fatalf("unexpected result for %q;\ngot\n%s\nwant\n%s", m, result, resultExpected)
+		}
+
+		// Flatten must return the original map.
+		if mResult := v.Flatten(sep, nil); !reflect.DeepEqual(mResult, m) {
+			t.Fatalf("unexpected Flatten result for %s;\ngot\n%q\nwant\n%q", resultExpected, mResult, m)
+		}
+	}
+
+	f(map[string]string{}, ".", `{}`)
+	f(map[string]string{
+		"items.0.name": "foo",
+		"items.0.n":    "1.50",
+		"items.1.name": "bar",
+		"ok":           "true",
+	}, ".", `{"items":[{"n":"1.50","name":"foo"},{"name":"bar"}],"ok":"true"}`)
+	f(map[string]string{
+		"0.0":   "1",
+		"0.1.0": "2",
+		"1.x":   "3",
+	}, ".", `[["1",["2"]],{"x":"3"}]`)
+	f(map[string]string{
+		"a::b::0": "1",
+	}, "::", `{"a":{"b":["1"]}}`)
+
+	// Missing array items are filled with nulls.
+	f(map[string]string{
+		"a.2":   "x",
+		"a.0.b": "y",
+	}, ".", `{"a":[{"b":"y"},null,"x"]}`)
+	f(map[string]string{
+		"10": "x",
+		"9":  "y",
+	}, "", `[null,null,null,null,null,null,null,null,null,"y","x"]`)
+
+	// Escaped keys
+	f(map[string]string{
+		`a\.b.c\\d`: "1",
+		`a\.b.\0`:   "2",
+		`a\.b.`:     "3",
+		`a\.b.x0`:   "4",
+	}, ".", `{"a.b":{"":"3","0":"2","c\\d":"1","x0":"4"}}`)
+	f(map[string]string{
+		`a\::b:c`: `"`,
+	}, "::", `{"a::b:c":"\""}`)
+}
+
+func TestUnflattenError(t *testing.T) {
+	f := func(m map[string]string, sep, errExpected string) {
+		t.Helper()
+		var a Arena
+		v, err := UnflattenSep(m, sep, &a)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for %q; got %s", m, v)
+		}
+		if !strings.Contains(err.Error(), errExpected) {
+			t.Fatalf("unexpected error for %q; got %q; must contain %q", m, err, errExpected)
+		}
+	}
+
+	f(map[string]string{"a": "1"}, `\`, "separator cannot contain backslash")
+	f(map[string]string{"a": "1", "a.b": "2"}, ".", `cannot set "a.b": conflicting values for "a"`)
+	f(map[string]string{"a.b": "1", "a.b.c": "2"}, ".", `cannot set "a.b.c": conflicting values for "b"`)
+	f(map[string]string{"a.b.c": "1", `a.\b`: "2"}, ".", `cannot set "a.b.c": conflicting values for "b"`)
+	f(map[string]string{"a.0": "1", "a.x": "2"}, ".", `cannot set "a.x": cannot use object key "x" for array`)
+	f(map[string]string{"a.x": "1", "a.0": "2"}, ".", `cannot set "a.x": cannot use object key "x" for array`)
+	f(map[string]string{"a": "1", "0": "2"}, ".", `cannot set "a": cannot use object key "a" for array`)
+	f(map[string]string{"a.x.0": "1", "a.x.y": "2"}, ".", `cannot use object key "y" for array`)
+	f(map[string]string{"x.y": "1", "x.0": "2"}, ".", `cannot set "x.y": cannot use object key "y" for array`)
+	f(map[string]string{`a\`: "1"}, ".", `cannot parse path "a\\": trailing backslash`)
+	f(map[string]string{"a.01": "1"}, ".", "array index cannot start with 0")
+	f(map[string]string{"a.1048576": "1"}, ".", "too big array index 1048576")
+}
+
+func TestValueFlattenUnflattenFixtures(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		v := MustParse(s)
+		for _, opts := range []FlattenOpts{{}, {KeepNull: true}} {
+			m := v.FlattenOpts(".", nil, opts)
+			var a Arena
+			vu, err := Unflatten(m, &a)
+			if err != nil {
+				t.Fatalf("cannot unflatten %q: %s", m, err)
+			}
+			if mu := vu.FlattenOpts(".", nil, opts); !reflect.DeepEqual(mu, m) {
+				t.Fatalf("unexpected round-trip result;\ngot\n%q\nwant\n%q", mu, m)
+			}
+		}
+	}
+
+	f(smallFixture)
+	f(mediumFixture)
+	f(largeFixture)
+	f(twitterFixture)
+
+	v := MustParse(smallFixture)
+	m := v.Flatten(".", nil)
+	if m["sid"] != "486" || m["tz"] != "-6" || m["uuid"] != "de305d54-75b4-431b-adb2-eb6b9e546014" {
+		t.Fatalf("unexpected result for small fixture: %q", m)
+	}
+}
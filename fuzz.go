@@ -2,8 +2,14 @@
 
 package fastjson
 
+import "fmt"
+
 func Fuzz(data []byte) int {
 	err := ValidateBytes(data)
+	errs := ValidateAllBytes(data, 0)
+	if (err == nil) != (len(errs) == 0) {
+		panic(fmt.Errorf("ValidateAllBytes mismatch: ValidateBytes returned %v, while ValidateAllBytes returned %v", err, errs))
+	}
 	if err != nil {
 		return 0
 	}
@@ -0,0 +1,50 @@
+//go:build go1.18
+// +build go1.18
+
+package fastjson
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func FuzzParse(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var p Parser
+		v, err := p.ParseBytes(data)
+		if err != nil {
+			return
+		}
+		b := v.MarshalTo(nil)
+
+		var p2 Parser
+		v2, err := p2.ParseBytes(b)
+		if err != nil {
+			t.Fatalf("cannot parse marshaled value %q obtained from %q: %s", b, data, err)
+		}
+		if !v.Equal(v2) {
+			t.Fatalf("unexpected value after marshaling and parsing %q; got %s; want %s", data, v2, v)
+		}
+	})
+}
+
+func FuzzValidate(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		err := ValidateBytes(data)
+		ok := json.Valid(data)
+		if ok != (err == nil) {
+			t.Fatalf("unexpected validation result for %q; Validate error: %v; json.Valid: %v", data, err, ok)
+		}
+	})
+}
+
+func addFuzzSeeds(f *testing.F) {
+	for _, s := range validateTests {
+		f.Add([]byte(s))
+	}
+	for _, s := range []string{smallFixture, mediumFixture, largeFixture, canadaFixture, citmFixture, twitterFixture} {
+		f.Add([]byte(s))
+	}
+}
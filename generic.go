@@ -0,0 +1,60 @@
+//go:build go1.18
+// +build go1.18
+
+package fastjson
+
+// Get returns the value of type T by the given keys path in v.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// The zero value and false are returned for non-existing keys path,
+// for invalid value type and for numbers, which don't fit T.
+// Strings are unescaped and copied, so they remain valid after Parse
+// is called on the Parser returned v.
+//
+// Use GetOr for obtaining the value with the default.
+func Get[T int | int64 | uint | uint64 | float64 | string | bool](v *Value, keys ...string) (T, bool) {
+	var result T
+	v = v.Get(keys...)
+	if v == nil {
+		return result, false
+	}
+	var err error
+	switch p := any(&result).(type) {
+	case *int:
+		*p, err = v.Int()
+	case *int64:
+		*p, err = v.Int64()
+	case *uint:
+		*p, err = v.Uint()
+	case *uint64:
+		*p, err = v.Uint64()
+	case *float64:
+		*p, err = v.Float64()
+	case *string:
+		if v.Type() != TypeString {
+			return result, false
+		}
+		// Convert via []byte in order to make a copy of v.s.
+		*p = string(s2b(v.s))
+	case *bool:
+		*p, err = v.Bool()
+	}
+	if err != nil {
+		var zero T
+		return zero, false
+	}
+	return result, true
+}
+
+// GetOr returns the value of type T by the given keys path in v.
+//
+// defaultValue is returned if the value cannot be obtained.
+// See Get for details.
+func GetOr[T int | int64 | uint | uint64 | float64 | string | bool](v *Value, defaultValue T, keys ...string) T {
+	result, ok := Get[T](v, keys...)
+	if !ok {
+		return defaultValue
+	}
+	return result
+}
@@ -0,0 +1,111 @@
+//go:build go1.18
+// +build go1.18
+
+package fastjson
+
+import (
+	"testing"
+)
+
+func TestGetGeneric(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{
+		"int": -123,
+		"uint": 18446744073709551615,
+		"float": 1.5,
+		"str": "foo\nbar",
+		"bool": true,
+		"arr": [1, "x", false]
+	}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+
+	// int
+	testGet(t, v, []string{"int"}, -123, true)
+	testGet(t, v, []string{"arr", "0"}, 1, true)
+	testGet(t, v, []string{"float"}, 0, false)
+	testGet(t, v, []string{"str"}, 0, false)
+	testGet(t, v, []string{"missing"}, 0, false)
+
+	// int64
+	testGet(t, v, []string{"int"}, int64(-123), true)
+	testGet(t, v, []string{"uint"}, int64(0), false)
+
+	// uint
+	testGet(t, v, []string{"arr", "0"}, uint(1), true)
+	testGet(t, v, []string{"int"}, uint(0), false)
+
+	// uint64
+	testGet(t, v, []string{"uint"}, uint64(18446744073709551615), true)
+	testGet(t, v, []string{"int"}, uint64(0), false)
+
+	// float64
+	testGet(t, v, []string{"float"}, 1.5, true)
+	testGet(t, v, []string{"int"}, float64(-123), true)
+	testGet(t, v, []string{"bool"}, float64(0), false)
+
+	// string
+	testGet(t, v, []string{"str"}, "foo\nbar", true)
+	testGet(t, v, []string{"arr", "1"}, "x", true)
+	testGet(t, v, []string{"int"}, "", false)
+	testGet(t, v, []string{"arr"}, "", false)
+
+	// bool
+	testGet(t, v, []string{"bool"}, true, true)
+	testGet(t, v, []string{"arr", "2"}, false, true)
+	testGet(t, v, []string{"str"}, false, false)
+
+	// nil value
+	testGet(t, nil, []string{"int"}, 0, false)
+
+	// The returned string must remain valid after the next Parse call.
+	s, ok := Get[string](v, "str")
+	if !ok {
+		t.Fatalf("cannot obtain string")
+	}
+	if _, err := p.Parse(`{"str":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s != "foo\nbar" {
+		t.Fatalf("unexpected string; got %q; want %q", s, "foo\nbar")
+	}
+}
+
+func testGet[T int | int64 | uint | uint64 | float64 | string | bool](t *testing.T, v *Value, keys []string, resultExpected T, okExpected bool) {
+	t.Helper()
+
+	result, ok := Get[T](v, keys...)
+	if ok != okExpected {
+		t.Fatalf("unexpected ok for %q; got %v; want %v", keys, ok, okExpected)
+	}
+	if result != resultExpected {
+		t.Fatalf("unexpected result for %q; got %v; want %v", keys, result, resultExpected)
+	}
+
+	// GetOr must return the default value on failure.
+	var defaultValue T
+	switch p := any(&defaultValue).(type) {
+	case *int:
+		*p = 42
+	case *int64:
+		*p = 42
+	case *uint:
+		*p = 42
+	case *uint64:
+		*p = 42
+	case *float64:
+		*p = 42
+	case *string:
+		*p = "default"
+	case *bool:
+		*p = true
+	}
+	resultOr := GetOr(v, defaultValue, keys...)
+	if okExpected && resultOr != resultExpected {
+		t.Fatalf("unexpected GetOr result for %q; got %v; want %v", keys, resultOr, resultExpected)
+	}
+	if !okExpected && resultOr != defaultValue {
+		t.Fatalf("unexpected GetOr result for %q; got %v; want %v", keys, resultOr, defaultValue)
+	}
+}
@@ -1,6 +1,14 @@
 package fastjson
 
-var handyPool ParserPool
+import (
+	"fmt"
+	"strconv"
+)
+
+var (
+	handyPool      ParserPool
+	handyArenaPool ArenaPool
+)
 
 // GetString returns string value for the field identified by keys path
 // in JSON data.
@@ -50,6 +58,30 @@ func GetBytes(data []byte, keys ...string) []byte {
 	return b
 }
 
+// GetStringBuf appends string value for the field identified by keys path
+// in JSON data to dst and returns the result.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// dst is returned unchanged on error. Use Parser for proper error handling.
+//
+// GetStringBuf may be used for avoiding memory allocations by re-using dst.
+func GetStringBuf(dst, data []byte, keys ...string) []byte {
+	p := handyPool.Get()
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		handyPool.Put(p)
+		return dst
+	}
+	sb := v.GetStringBytes(keys...)
+
+	// Copy sb to dst, since sb belongs to p.
+	dst = append(dst, sb...)
+
+	handyPool.Put(p)
+	return dst
+}
+
 // GetInt returns int value for the field identified by keys path
 // in JSON data.
 //
@@ -70,6 +102,46 @@ func GetInt(data []byte, keys ...string) int {
 	return n
 }
 
+// GetInt64 returns int64 value for the field identified by keys path
+// in JSON data.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// 0 is returned on error. Use Parser for proper error handling.
+//
+// Parser is faster for obtaining multiple fields from JSON.
+func GetInt64(data []byte, keys ...string) int64 {
+	p := handyPool.Get()
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		handyPool.Put(p)
+		return 0
+	}
+	n := v.GetInt64(keys...)
+	handyPool.Put(p)
+	return n
+}
+
+// GetUint64 returns uint64 value for the field identified by keys path
+// in JSON data.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// 0 is returned on error. Use Parser for proper error handling.
+//
+// Parser is faster for obtaining multiple fields from JSON.
+func GetUint64(data []byte, keys ...string) uint64 {
+	p := handyPool.Get()
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		handyPool.Put(p)
+		return 0
+	}
+	n := v.GetUint64(keys...)
+	handyPool.Put(p)
+	return n
+}
+
 // GetFloat64 returns float64 value for the field identified by keys path
 // in JSON data.
 //
@@ -90,6 +162,42 @@ func GetFloat64(data []byte, keys ...string) float64 {
 	return f
 }
 
+// GetStringSlice returns strings from the array identified by keys path
+// in JSON data.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// nil is returned on error, including the case when the array contains
+// non-string items. An empty slice is returned for an empty array.
+// Use Parser for proper error handling.
+//
+// Parser is faster for obtaining multiple fields from JSON.
+func GetStringSlice(data []byte, keys ...string) []string {
+	p := handyPool.Get()
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		handyPool.Put(p)
+		return nil
+	}
+	v = v.Get(keys...)
+	if v == nil || v.Type() != TypeArray {
+		handyPool.Put(p)
+		return nil
+	}
+	ss := make([]string, len(v.a))
+	for i, vv := range v.a {
+		sb, err := vv.StringBytes()
+		if err != nil {
+			handyPool.Put(p)
+			return nil
+		}
+		// Make a copy of sb, since sb belongs to p.
+		ss[i] = string(sb)
+	}
+	handyPool.Put(p)
+	return ss
+}
+
 // GetBool returns boolean value for the field identified by keys path
 // in JSON data.
 //
@@ -110,6 +218,155 @@ func GetBool(data []byte, keys ...string) bool {
 	return b
 }
 
+// GetBoolLenient returns boolean value for the field identified by keys path
+// in JSON data.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// Unlike GetBool, GetBoolLenient accepts "true" and "false" strings
+// and 0 and 1 numbers. See Value.BoolLenient for details.
+//
+// False is returned on error. Use Parser for proper error handling.
+//
+// Parser is faster for obtaining multiple fields from JSON.
+func GetBoolLenient(data []byte, keys ...string) bool {
+	p := handyPool.Get()
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		handyPool.Put(p)
+		return false
+	}
+	b := v.GetBoolLenient(keys...)
+	handyPool.Put(p)
+	return b
+}
+
+// GetIntDefault returns int value for the field identified by keys path
+// in JSON data.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// defaultValue is returned on error, including missing field and invalid
+// value type. See Value.GetIntDefault for details.
+//
+// Parser is faster for obtaining multiple fields from JSON.
+func GetIntDefault(data []byte, defaultValue int, keys ...string) int {
+	p := handyPool.Get()
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		handyPool.Put(p)
+		return defaultValue
+	}
+	x := v.GetIntDefault(defaultValue, keys...)
+	handyPool.Put(p)
+	return x
+}
+
+// GetInt64Default returns int64 value for the field identified by keys path
+// in JSON data.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// defaultValue is returned on error, including missing field and invalid
+// value type. See Value.GetInt64Default for details.
+//
+// Parser is faster for obtaining multiple fields from JSON.
+func GetInt64Default(data []byte, defaultValue int64, keys ...string) int64 {
+	p := handyPool.Get()
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		handyPool.Put(p)
+		return defaultValue
+	}
+	x := v.GetInt64Default(defaultValue, keys...)
+	handyPool.Put(p)
+	return x
+}
+
+// GetUint64Default returns uint64 value for the field identified by keys path
+// in JSON data.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// defaultValue is returned on error, including missing field and invalid
+// value type. See Value.GetUint64Default for details.
+//
+// Parser is faster for obtaining multiple fields from JSON.
+func GetUint64Default(data []byte, defaultValue uint64, keys ...string) uint64 {
+	p := handyPool.Get()
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		handyPool.Put(p)
+		return defaultValue
+	}
+	x := v.GetUint64Default(defaultValue, keys...)
+	handyPool.Put(p)
+	return x
+}
+
+// GetFloat64Default returns float64 value for the field identified by keys path
+// in JSON data.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// defaultValue is returned on error, including missing field and invalid
+// value type. See Value.GetFloat64Default for details.
+//
+// Parser is faster for obtaining multiple fields from JSON.
+func GetFloat64Default(data []byte, defaultValue float64, keys ...string) float64 {
+	p := handyPool.Get()
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		handyPool.Put(p)
+		return defaultValue
+	}
+	x := v.GetFloat64Default(defaultValue, keys...)
+	handyPool.Put(p)
+	return x
+}
+
+// GetStringDefault returns string value for the field identified by keys path
+// in JSON data.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// defaultValue is returned on error, including missing field and invalid
+// value type. See Value.GetStringDefault for details.
+//
+// Parser is faster for obtaining multiple fields from JSON.
+func GetStringDefault(data []byte, defaultValue string, keys ...string) string {
+	p := handyPool.Get()
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		handyPool.Put(p)
+		return defaultValue
+	}
+	x := v.GetStringDefault(defaultValue, keys...)
+	handyPool.Put(p)
+	return x
+}
+
+// GetBoolDefault returns bool value for the field identified by keys path
+// in JSON data.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// defaultValue is returned on error, including missing field and invalid
+// value type. See Value.GetBoolDefault for details.
+//
+// Parser is faster for obtaining multiple fields from JSON.
+func GetBoolDefault(data []byte, defaultValue bool, keys ...string) bool {
+	p := handyPool.Get()
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		handyPool.Put(p)
+		return defaultValue
+	}
+	x := v.GetBoolDefault(defaultValue, keys...)
+	handyPool.Put(p)
+	return x
+}
+
 // Exists returns true if the field identified by keys path exists in JSON data.
 //
 // Array indexes may be represented as decimal numbers in keys.
@@ -129,6 +386,135 @@ func Exists(data []byte, keys ...string) bool {
 	return ok
 }
 
+// GetMany returns values for the given keys paths in JSON data.
+//
+// JSON data is parsed only once for all the paths, so GetMany is faster
+// than multiple Get* calls for the same data. The i-th returned value
+// corresponds to the i-th path. nil is returned for non-existing keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// The returned values don't refer to data, so they remain valid
+// after data modification.
+//
+// nil values are returned on error. Use Parser for proper error handling.
+func GetMany(data []byte, paths ...[]string) []*Value {
+	vs := make([]*Value, len(paths))
+	p := handyPool.Get()
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		handyPool.Put(p)
+		return vs
+	}
+
+	// Copy the found values, since they belong to p.
+	var a Arena
+	for i, path := range paths {
+		vv := v.Get(path...)
+		if vv != nil {
+			vs[i] = a.deepCopy(vv)
+		}
+	}
+
+	handyPool.Put(p)
+	return vs
+}
+
+// Set sets rawValue JSON at the given keys path in JSON data
+// and returns the modified JSON.
+//
+// Missing objects on the keys path are created. Array indexes
+// may be represented as decimal numbers in keys. Arrays are extended
+// with null items if the index exceeds the array length.
+//
+// The returned JSON is marshaled from scratch, so it doesn't contain
+// the original whitespace. It doesn't refer to data and rawValue.
+//
+// Use Parser and Value.Set for multiple modifications of the same JSON.
+func Set(data, rawValue []byte, keys ...string) ([]byte, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("missing keys path")
+	}
+	p := handyPool.Get()
+	a := handyArenaPool.Get()
+	result, err := set(p, a, data, rawValue, keys)
+	a.Reset()
+	handyArenaPool.Put(a)
+	handyPool.Put(p)
+	return result, err
+}
+
+func set(p *Parser, a *Arena, data, rawValue []byte, keys []string) ([]byte, error) {
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse data: %s", err)
+	}
+	value, err := a.ParseBytes(rawValue)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse rawValue: %s", err)
+	}
+
+	vv := v
+	for i, key := range keys[:len(keys)-1] {
+		child := vv.Get(key)
+		if child == nil {
+			child = a.NewObject()
+			if err := setChild(vv, key, child); err != nil {
+				return nil, fmt.Errorf("cannot set %q at %q: %s", key, keys[:i], err)
+			}
+		}
+		vv = child
+	}
+	key := keys[len(keys)-1]
+	if err := setChild(vv, key, value); err != nil {
+		return nil, fmt.Errorf("cannot set %q at %q: %s", key, keys[:len(keys)-1], err)
+	}
+	return v.MarshalTo(nil), nil
+}
+
+func setChild(v *Value, key string, child *Value) error {
+	switch v.t {
+	case TypeObject:
+		v.o.Set(key, child)
+		return nil
+	case TypeArray:
+		n, err := strconv.Atoi(key)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid array index %q", key)
+		}
+		v.SetArrayItem(n, child)
+		return nil
+	default:
+		return fmt.Errorf("cannot set key in %s", v.Type())
+	}
+}
+
+// Del deletes the value at the given keys path from JSON data
+// and returns the modified JSON.
+//
+// Array indexes may be represented as decimal numbers in keys.
+// JSON data is returned unchanged if the keys path doesn't exist.
+//
+// The returned JSON is marshaled from scratch, so it doesn't contain
+// the original whitespace. It doesn't refer to data.
+//
+// Use Parser and Value.Del for multiple modifications of the same JSON.
+func Del(data []byte, keys ...string) ([]byte, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("missing keys path")
+	}
+	p := handyPool.Get()
+	v, err := p.ParseBytes(data)
+	if err != nil {
+		handyPool.Put(p)
+		return nil, fmt.Errorf("cannot parse data: %s", err)
+	}
+	v.Get(keys[:len(keys)-1]...).Del(keys[len(keys)-1])
+	result := v.MarshalTo(nil)
+	handyPool.Put(p)
+	return result, nil
+}
+
 // Parse parses json string s.
 //
 // The function is slower than the Parser.Parse for re-used Parser.
@@ -2,6 +2,7 @@ package fastjson
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -118,6 +119,40 @@ func TestGetBytes(t *testing.T) {
 	}
 }
 
+func TestGetStringBuf(t *testing.T) {
+	data := []byte(`{"foo":"bar", "baz": 1234}`)
+
+	// normal path
+	b := GetStringBuf([]byte("prefix_"), data, "foo")
+	if string(b) != "prefix_bar" {
+		t.Fatalf("unexpected value obtained; got %q; want %q", b, "prefix_bar")
+	}
+
+	// The returned value mustn't be modified by subsequent calls.
+	GetStringBuf(nil, []byte(`{"foo":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}`), "foo")
+	if string(b) != "prefix_bar" {
+		t.Fatalf("unexpected value obtained; got %q; want %q", b, "prefix_bar")
+	}
+
+	// non-existing path
+	b = GetStringBuf(b[:0], data, "foo", "zzz")
+	if len(b) != 0 {
+		t.Fatalf("unexpected non-empty value obtained: %q", b)
+	}
+
+	// invalid type
+	b = GetStringBuf(b[:0], data, "baz")
+	if len(b) != 0 {
+		t.Fatalf("unexpected non-empty value obtained: %q", b)
+	}
+
+	// invalid json
+	b = GetStringBuf(b[:0], []byte("invalid json"), "foobar", "baz")
+	if len(b) != 0 {
+		t.Fatalf("unexpected non-empty value obtained: %q", b)
+	}
+}
+
 func TestGetInt(t *testing.T) {
 	data := []byte(`{"foo":"bar", "baz": 1234}`)
 
@@ -146,6 +181,115 @@ func TestGetInt(t *testing.T) {
 	}
 }
 
+func TestGetInt64(t *testing.T) {
+	data := []byte(`{"foo":"bar", "baz": 1234567890123456789, "neg": -9223372036854775808}`)
+
+	// normal path
+	n := GetInt64(data, "baz")
+	if n != 1234567890123456789 {
+		t.Fatalf("unexpected value obtained; got %d; want %d", n, int64(1234567890123456789))
+	}
+	n = GetInt64(data, "neg")
+	if n != -9223372036854775808 {
+		t.Fatalf("unexpected value obtained; got %d; want %d", n, int64(-9223372036854775808))
+	}
+
+	// non-existing path
+	n = GetInt64(data, "foo", "zzz")
+	if n != 0 {
+		t.Fatalf("unexpected non-zero value obtained: %d", n)
+	}
+
+	// invalid type
+	n = GetInt64(data, "foo")
+	if n != 0 {
+		t.Fatalf("unexpected non-zero value obtained: %d", n)
+	}
+
+	// invalid json
+	n = GetInt64([]byte("invalid json"), "foobar", "baz")
+	if n != 0 {
+		t.Fatalf("unexpected non-empty value obtained: %d", n)
+	}
+}
+
+func TestGetUint64(t *testing.T) {
+	data := []byte(`{"foo":"bar", "baz": 18446744073709551615, "neg": -1}`)
+
+	// normal path
+	n := GetUint64(data, "baz")
+	if n != 18446744073709551615 {
+		t.Fatalf("unexpected value obtained; got %d; want %d", n, uint64(18446744073709551615))
+	}
+
+	// non-existing path
+	n = GetUint64(data, "foo", "zzz")
+	if n != 0 {
+		t.Fatalf("unexpected non-zero value obtained: %d", n)
+	}
+
+	// invalid type
+	n = GetUint64(data, "foo")
+	if n != 0 {
+		t.Fatalf("unexpected non-zero value obtained: %d", n)
+	}
+	n = GetUint64(data, "neg")
+	if n != 0 {
+		t.Fatalf("unexpected non-zero value obtained: %d", n)
+	}
+
+	// invalid json
+	n = GetUint64([]byte("invalid json"), "foobar", "baz")
+	if n != 0 {
+		t.Fatalf("unexpected non-empty value obtained: %d", n)
+	}
+}
+
+func TestGetStringSlice(t *testing.T) {
+	data := []byte(`{"foo":"bar", "baz": ["a", "b\nc", ""], "empty": [], "mixed": ["a", 1]}`)
+
+	// normal path
+	ss := GetStringSlice(data, "baz")
+	if !reflect.DeepEqual(ss, []string{"a", "b\nc", ""}) {
+		t.Fatalf("unexpected value obtained; got %q; want %q", ss, []string{"a", "b\nc", ""})
+	}
+	ss = GetStringSlice(data, "empty")
+	if ss == nil || len(ss) != 0 {
+		t.Fatalf("unexpected value obtained; got %q; want empty slice", ss)
+	}
+
+	// non-existing path
+	ss = GetStringSlice(data, "foo", "zzz")
+	if ss != nil {
+		t.Fatalf("unexpected non-nil value obtained: %q", ss)
+	}
+
+	// invalid type
+	ss = GetStringSlice(data, "foo")
+	if ss != nil {
+		t.Fatalf("unexpected non-nil value obtained: %q", ss)
+	}
+	ss = GetStringSlice(data, "mixed")
+	if ss != nil {
+		t.Fatalf("unexpected non-nil value obtained: %q", ss)
+	}
+
+	// invalid json
+	ss = GetStringSlice([]byte("invalid json"), "foobar", "baz")
+	if ss != nil {
+		t.Fatalf("unexpected non-nil value obtained: %q", ss)
+	}
+
+	// The returned strings mustn't refer to the pooled parser.
+	ss = GetStringSlice(data, "baz")
+	for i := 0; i < 10; i++ {
+		GetString([]byte(`{"baz":["xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"]}`), "baz", "0")
+	}
+	if !reflect.DeepEqual(ss, []string{"a", "b\nc", ""}) {
+		t.Fatalf("unexpected value obtained; got %q; want %q", ss, []string{"a", "b\nc", ""})
+	}
+}
+
 func TestGetFloat64(t *testing.T) {
 	data := []byte(`{"foo":"bar", "baz": 12.34}`)
 
@@ -202,6 +346,38 @@ func TestGetBool(t *testing.T) {
 	}
 }
 
+func TestGetBoolLenient(t *testing.T) {
+	data := []byte(`{"foo":"bar", "baz": "TRUE", "x": [1, 0, false]}`)
+
+	// normal path
+	b := GetBoolLenient(data, "baz")
+	if !b {
+		t.Fatalf("unexpected value obtained; got %v; want %v", b, true)
+	}
+	b = GetBoolLenient(data, "x", "0")
+	if !b {
+		t.Fatalf("unexpected value obtained; got %v; want %v", b, true)
+	}
+
+	// non-existing path
+	b = GetBoolLenient(data, "foo", "zzz")
+	if b {
+		t.Fatalf("unexpected true value obtained")
+	}
+
+	// invalid value
+	b = GetBoolLenient(data, "foo")
+	if b {
+		t.Fatalf("unexpected true value obtained")
+	}
+
+	// invalid json
+	b = GetBoolLenient([]byte("invalid json"), "foobar", "baz")
+	if b {
+		t.Fatalf("unexpected true value obtained")
+	}
+}
+
 func TestExists(t *testing.T) {
 	data := []byte(`{"foo": [{"bar": 1234, "baz": 0}]}`)
 
@@ -233,6 +409,180 @@ func TestExists(t *testing.T) {
 	}
 }
 
+func TestGetMany(t *testing.T) {
+	data := []byte(`{"foo":{"bar":[1,"x\ny",{"baz":true}]},"qwe":"rty"}`)
+	vs := GetMany(data, []string{"foo", "bar"}, []string{"qwe"}, []string{"foo", "bar", "2"}, []string{"missing"}, nil)
+	if len(vs) != 5 {
+		t.Fatalf("unexpected number of values; got %d; want %d", len(vs), 5)
+	}
+
+	// The returned values mustn't refer to data or to the pooled parser.
+	for i := range data {
+		data[i] = 'x'
+	}
+	for i := 0; i < 10; i++ {
+		GetString([]byte(`{"foo":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}`), "foo")
+	}
+
+	f := func(v *Value, expected string) {
+		t.Helper()
+		if s := v.String(); s != expected {
+			t.Fatalf("unexpected value; got %q; want %q", s, expected)
+		}
+	}
+	f(vs[0], `[1,"x\ny",{"baz":true}]`)
+	f(vs[1], `"rty"`)
+	f(vs[2], `{"baz":true}`)
+	if vs[3] != nil {
+		t.Fatalf("expecting nil value for missing path; got %s", vs[3])
+	}
+	f(vs[4], `{"foo":{"bar":[1,"x\ny",{"baz":true}]},"qwe":"rty"}`)
+	if s := vs[0].GetStringBytes("1"); string(s) != "x\ny" {
+		t.Fatalf("unexpected string; got %q; want %q", s, "x\ny")
+	}
+
+	// Invalid JSON
+	vs = GetMany([]byte(`invalid JSON`), []string{"foo"}, []string{"bar"})
+	if len(vs) != 2 || vs[0] != nil || vs[1] != nil {
+		t.Fatalf("expecting two nil values on invalid JSON; got %v", vs)
+	}
+}
+
+func TestSet(t *testing.T) {
+	f := func(data, rawValue string, keys []string, resultExpected string) {
+		t.Helper()
+		result, err := Set([]byte(data), []byte(rawValue), keys...)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if string(result) != resultExpected {
+			t.Fatalf("unexpected result; got %s; want %s", result, resultExpected)
+		}
+	}
+
+	// Replace existing values
+	f(`{"foo":1,"bar":2}`, `"x"`, []string{"foo"}, `{"foo":"x","bar":2}`)
+	f(` { "foo" : { "bar" : 1 } } `, `[1, 2]`, []string{"foo", "bar"}, `{"foo":{"bar":[1,2]}}`)
+	f(`[1,2,3]`, `{"a":true}`, []string{"1"}, `[1,{"a":true},3]`)
+	f(`{"foo":[1,{"bar":2}]}`, `null`, []string{"foo", "1", "bar"}, `{"foo":[1,{"bar":null}]}`)
+
+	// Create missing values
+	f(`{}`, `123`, []string{"foo"}, `{"foo":123}`)
+	f(`{"x":1}`, `"y"`, []string{"foo", "bar", "baz"}, `{"x":1,"foo":{"bar":{"baz":"y"}}}`)
+	f(`[1]`, `2`, []string{"3"}, `[1,null,null,2]`)
+	f(`{"foo":[]}`, `true`, []string{"foo", "1", "bar"}, `{"foo":[null,{"bar":true}]}`)
+
+	// Escaped keys and strings
+	f(`{"f\u006fo":"a\nb"}`, `"\\"`, []string{"bar"}, `{"foo":"a\nb","bar":"\\"}`)
+}
+
+func TestSetError(t *testing.T) {
+	f := func(data, rawValue string, keys []string) {
+		t.Helper()
+		result, err := Set([]byte(data), []byte(rawValue), keys...)
+		if err == nil {
+			t.Fatalf("expecting non-nil error; got result %s", result)
+		}
+		if result != nil {
+			t.Fatalf("expecting nil result on error; got %s", result)
+		}
+	}
+
+	// Missing keys
+	f(`{}`, `1`, nil)
+
+	// Invalid JSON
+	f(`{"foo":`, `1`, []string{"foo"})
+	f(``, `1`, []string{"foo"})
+
+	// Invalid rawValue
+	f(`{}`, `{"foo":`, []string{"foo"})
+	f(`{}`, ``, []string{"foo"})
+	f(`{}`, `1 2`, []string{"foo"})
+
+	// Scalar parents
+	f(`{"foo":1}`, `2`, []string{"foo", "bar"})
+	f(`{"foo":"bar"}`, `2`, []string{"foo", "bar", "baz"})
+	f(`123`, `2`, []string{"foo"})
+
+	// Invalid array indexes
+	f(`[1,2]`, `2`, []string{"foo"})
+	f(`[1,2]`, `2`, []string{"-1"})
+	f(`{"foo":[]}`, `2`, []string{"foo", "bar", "baz"})
+}
+
+func TestSetConcurrent(t *testing.T) {
+	const concurrency = 4
+	data := []byte(largeFixture)
+
+	ch := make(chan error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func(n int) {
+			key := fmt.Sprintf("key_%d", n)
+			for j := 0; j < 10; j++ {
+				result, err := Set(data, []byte(fmt.Sprintf(`{"n":%d}`, j)), key, "value")
+				if err != nil {
+					ch <- fmt.Errorf("unexpected error: %s", err)
+					return
+				}
+				m := GetInt(result, key, "value", "n")
+				if m != j {
+					ch <- fmt.Errorf("unexpected value; got %d; want %d", m, j)
+					return
+				}
+				if !Exists(result, "users", "0", "id") {
+					ch <- fmt.Errorf("missing users.0.id in the result")
+					return
+				}
+			}
+			ch <- nil
+		}(i)
+	}
+
+	for i := 0; i < concurrency; i++ {
+		select {
+		case <-time.After(time.Second * 5):
+			t.Fatalf("timeout")
+		case err := <-ch:
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+	}
+}
+
+func TestDel(t *testing.T) {
+	f := func(data string, keys []string, resultExpected string) {
+		t.Helper()
+		result, err := Del([]byte(data), keys...)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if string(result) != resultExpected {
+			t.Fatalf("unexpected result; got %s; want %s", result, resultExpected)
+		}
+	}
+
+	f(`{"foo":1,"bar":2}`, []string{"foo"}, `{"bar":2}`)
+	f(`{"foo":{"bar":1,"baz":2}}`, []string{"foo", "bar"}, `{"foo":{"baz":2}}`)
+	f(`{"foo":[1,2,3]}`, []string{"foo", "1"}, `{"foo":[1,3]}`)
+	f(`{"f\u006fo":1,"bar":2}`, []string{"foo"}, `{"bar":2}`)
+
+	// Missing paths
+	f(` { "foo" : 1 } `, []string{"bar"}, `{"foo":1}`)
+	f(`{"foo":1}`, []string{"bar", "baz"}, `{"foo":1}`)
+	f(`{"foo":[1]}`, []string{"foo", "5"}, `{"foo":[1]}`)
+	f(`{"foo":1}`, []string{"foo", "bar"}, `{"foo":1}`)
+
+	// Errors
+	if _, err := Del([]byte(`{"foo":`), "foo"); err == nil {
+		t.Fatalf("expecting non-nil error for invalid JSON")
+	}
+	if _, err := Del([]byte(`{}`)); err == nil {
+		t.Fatalf("expecting non-nil error for missing keys")
+	}
+}
+
 func TestParse(t *testing.T) {
 	v, err := Parse(`{"foo": "bar"}`)
 	if err != nil {
@@ -286,3 +636,50 @@ func causesPanic(fn func()) (p bool) {
 	fn()
 	return
 }
+
+func TestGetDefault(t *testing.T) {
+	data := []byte(`{"zero":0,"n":-12,"f":1.5,"empty":"","s":"foo","false":false,"null":null}`)
+
+	f := func(name string, got, want interface{}) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("unexpected result for %s; got %v; want %v", name, got, want)
+		}
+	}
+
+	f("GetIntDefault(zero)", GetIntDefault(data, 7, "zero"), 0)
+	f("GetIntDefault(n)", GetIntDefault(data, 7, "n"), -12)
+	f("GetIntDefault(missing)", GetIntDefault(data, 7, "missing"), 7)
+	f("GetIntDefault(s)", GetIntDefault(data, 7, "s"), 7)
+
+	f("GetInt64Default(zero)", GetInt64Default(data, 7, "zero"), int64(0))
+	f("GetInt64Default(missing)", GetInt64Default(data, 7, "missing"), int64(7))
+	f("GetInt64Default(null)", GetInt64Default(data, 7, "null"), int64(7))
+
+	f("GetUint64Default(zero)", GetUint64Default(data, 7, "zero"), uint64(0))
+	f("GetUint64Default(missing)", GetUint64Default(data, 7, "missing"), uint64(7))
+	f("GetUint64Default(n)", GetUint64Default(data, 7, "n"), uint64(7))
+
+	f("GetFloat64Default(zero)", GetFloat64Default(data, 7.5, "zero"), float64(0))
+	f("GetFloat64Default(f)", GetFloat64Default(data, 7.5, "f"), 1.5)
+	f("GetFloat64Default(missing)", GetFloat64Default(data, 7.5, "missing"), 7.5)
+	f("GetFloat64Default(false)", GetFloat64Default(data, 7.5, "false"), 7.5)
+
+	f("GetStringDefault(empty)", GetStringDefault(data, "def", "empty"), "")
+	f("GetStringDefault(s)", GetStringDefault(data, "def", "s"), "foo")
+	f("GetStringDefault(missing)", GetStringDefault(data, "def", "missing"), "def")
+	f("GetStringDefault(zero)", GetStringDefault(data, "def", "zero"), "def")
+
+	f("GetBoolDefault(false)", GetBoolDefault(data, true, "false"), false)
+	f("GetBoolDefault(missing)", GetBoolDefault(data, true, "missing"), true)
+	f("GetBoolDefault(s)", GetBoolDefault(data, true, "s"), true)
+
+	// Invalid JSON
+	invalid := []byte(`{"zero":0`)
+	f("GetIntDefault(invalid)", GetIntDefault(invalid, 7, "zero"), 7)
+	f("GetInt64Default(invalid)", GetInt64Default(invalid, 7, "zero"), int64(7))
+	f("GetUint64Default(invalid)", GetUint64Default(invalid, 7, "zero"), uint64(7))
+	f("GetFloat64Default(invalid)", GetFloat64Default(invalid, 7.5, "zero"), 7.5)
+	f("GetStringDefault(invalid)", GetStringDefault(invalid, "def", "zero"), "def")
+	f("GetBoolDefault(invalid)", GetBoolDefault(invalid, true, "zero"), true)
+}
@@ -0,0 +1,42 @@
+package fastjson
+
+import (
+	"testing"
+)
+
+func BenchmarkGetMany(b *testing.B) {
+	data := []byte(twitterFixture)
+	paths := [][]string{
+		{"search_metadata", "max_id_str"},
+		{"search_metadata", "since_id_str"},
+		{"search_metadata", "query"},
+		{"search_metadata", "refresh_url"},
+		{"search_metadata", "next_results"},
+	}
+	b.Run("GetString", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				for _, path := range paths {
+					if GetString(data, path...) == "" {
+						panic("BUG: empty string")
+					}
+				}
+			}
+		})
+	})
+	b.Run("GetMany", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				for _, v := range GetMany(data, paths...) {
+					if len(v.GetStringBytes()) == 0 {
+						panic("BUG: empty string")
+					}
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,101 @@
+package fastjson
+
+import (
+	"math"
+)
+
+// Hash64 returns 64-bit structural hash for v mixed with the given seed.
+//
+// Values equal according to Equal have equal hashes. Additionally, objects
+// with the same entries in distinct order have equal hashes, and numbers
+// are hashed by their values, so 1e3 and 1000 have equal hashes.
+// Arrays with the same items in distinct order have distinct hashes
+// with high probability.
+//
+// Hash64 doesn't allocate memory, so it may be used for building dedup keys
+// without marshaling v. The hash isn't cryptographically secure.
+func (v *Value) Hash64(seed uint64) uint64 {
+	h := hashValue(hashOffset^seed, seed, v)
+	return hashMix(h)
+}
+
+const (
+	hashOffset = 14695981039346656037
+	hashPrime  = 1099511628211
+)
+
+func hashValue(h, seed uint64, v *Value) uint64 {
+	if v == nil {
+		return hashUint64(h, math.MaxUint64)
+	}
+	t := v.Type()
+	h = hashUint64(h, uint64(t))
+	switch t {
+	case TypeObject:
+		// Combine entry hashes via addition, so they don't depend on
+		// the order of entries.
+		v.o.unescapeKeys()
+		var sum uint64
+		for _, kv := range v.o.kvs {
+			eh := hashString(hashOffset^seed, kv.k)
+			eh = hashValue(eh, seed, kv.v)
+			sum += hashMix(eh)
+		}
+		h = hashUint64(h, uint64(len(v.o.kvs)))
+		return hashUint64(h, sum)
+	case TypeArray:
+		h = hashUint64(h, uint64(len(v.a)))
+		for _, vv := range v.a {
+			h = hashValue(h, seed, vv)
+		}
+		return h
+	case TypeString:
+		return hashString(h, v.s)
+	case TypeNumber:
+		f, err := v.Float64()
+		if err != nil {
+			// Hash invalid numbers by their textual representation.
+			return hashString(h, v.s)
+		}
+		if f == 0 {
+			// Normalize -0.
+			f = 0
+		}
+		return hashUint64(h, math.Float64bits(f))
+	default:
+		// null, true and false are distinguished by their types.
+		return h
+	}
+}
+
+// hashString mixes s into FNV-1a hash h.
+func hashString(h uint64, s string) uint64 {
+	h = hashUint64(h, uint64(len(s)))
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= hashPrime
+	}
+	return h
+}
+
+// hashUint64 mixes n into FNV-1a hash h.
+func hashUint64(h, n uint64) uint64 {
+	for i := 0; i < 8; i++ {
+		h ^= n & 0xff
+		h *= hashPrime
+		n >>= 8
+	}
+	return h
+}
+
+// hashMix returns well-mixed h.
+//
+// It is the finalizer from splitmix64.
+func hashMix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
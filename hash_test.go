@@ -0,0 +1,108 @@
+package fastjson
+
+import (
+	"testing"
+)
+
+func TestValueHash64(t *testing.T) {
+	hash := func(s string, seed uint64) uint64 {
+		t.Helper()
+		v, err := Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		return v.Hash64(seed)
+	}
+	fEqual := func(a, b string) {
+		t.Helper()
+		for _, seed := range []uint64{0, 1, 12345} {
+			if ha, hb := hash(a, seed), hash(b, seed); ha != hb {
+				t.Fatalf("expecting equal hashes for %s and %s with seed %d; got %d and %d", a, b, seed, ha, hb)
+			}
+		}
+	}
+	fNotEqual := func(a, b string) {
+		t.Helper()
+		for _, seed := range []uint64{0, 1, 12345} {
+			if ha, hb := hash(a, seed), hash(b, seed); ha == hb {
+				t.Fatalf("expecting distinct hashes for %s and %s with seed %d; got %d", a, b, seed, ha)
+			}
+		}
+	}
+
+	// Equal values
+	fEqual(`null`, ` null `)
+	fEqual(`"foo"`, `"foo"`)
+	fEqual(`{"foo":[1,{"bar":true}]}`, ` { "foo" : [ 1 , { "bar" : true } ] } `)
+
+	// Key order independence
+	fEqual(`{"a":1,"b":2}`, `{"b":2,"a":1}`)
+	fEqual(`{"x":{"a":[1,2],"b":"c"},"y":null}`, `{"y":null,"x":{"b":"c","a":[1,2]}}`)
+
+	// Numbers are hashed by their values
+	fEqual(`1000`, `1e3`)
+	fEqual(`[0.5]`, `[5e-1]`)
+	fEqual(`0`, `-0`)
+	fEqual(`{"a":1}`, `{"a":1.0}`)
+
+	// Array order dependence
+	fNotEqual(`[1,2]`, `[2,1]`)
+	fNotEqual(`[[1],[2]]`, `[[2],[1]]`)
+	fNotEqual(`[{"a":1},{"b":2}]`, `[{"b":2},{"a":1}]`)
+
+	// Distinct values
+	fNotEqual(`null`, `false`)
+	fNotEqual(`true`, `false`)
+	fNotEqual(`"1"`, `1`)
+	fNotEqual(`1`, `2`)
+	fNotEqual(`""`, `[]`)
+	fNotEqual(`[]`, `{}`)
+	fNotEqual(`[]`, `[null]`)
+	fNotEqual(`{"a":1}`, `{"a":2}`)
+	fNotEqual(`{"a":1}`, `{"b":1}`)
+	fNotEqual(`{"a":1,"b":2}`, `{"a":2,"b":1}`)
+	fNotEqual(`{"a":{"b":1}}`, `{"a":{},"b":1}`)
+	fNotEqual(`["ab","c"]`, `["a","bc"]`)
+	fNotEqual(`{"ab":"c"}`, `{"a":"bc"}`)
+
+	// Seeds
+	if hash(`{"foo":"bar"}`, 0) == hash(`{"foo":"bar"}`, 1) {
+		t.Fatalf("expecting distinct hashes for distinct seeds")
+	}
+
+	// Nil value
+	var v *Value
+	if v.Hash64(0) != v.Hash64(0) || v.Hash64(0) == hash(`null`, 0) {
+		t.Fatalf("unexpected hash for nil value")
+	}
+}
+
+func TestValueHash64Fixtures(t *testing.T) {
+	for _, s := range []string{smallFixture, mediumFixture, largeFixture, canadaFixture, citmFixture, twitterFixture} {
+		v := MustParse(s)
+		h := v.Hash64(0)
+
+		// Marshaled value must have the same hash.
+		vv := MustParse(v.String())
+		if hh := vv.Hash64(0); hh != h {
+			t.Fatalf("unexpected hash for marshaled value; got %d; want %d", hh, h)
+		}
+
+		// The hash must change after modifications.
+		vv.Set("new_key", MustParse(`1`))
+		if hh := vv.Hash64(0); hh == h {
+			t.Fatalf("expecting distinct hash after modification")
+		}
+	}
+}
+
+func TestValueHash64Allocs(t *testing.T) {
+	v := MustParse(mediumFixture)
+	v.Hash64(0)
+	n := testing.AllocsPerRun(100, func() {
+		v.Hash64(0)
+	})
+	if n != 0 {
+		t.Fatalf("unexpected number of allocations; got %v; want 0", n)
+	}
+}
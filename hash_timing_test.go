@@ -0,0 +1,33 @@
+package fastjson
+
+import (
+	"hash/fnv"
+	"sync/atomic"
+	"testing"
+)
+
+func BenchmarkValueHash64(b *testing.B) {
+	v := MustParse(mediumFixture)
+	b.Run("Hash64", func(b *testing.B) {
+		b.SetBytes(int64(len(mediumFixture)))
+		b.ReportAllocs()
+		var sink uint64
+		for i := 0; i < b.N; i++ {
+			sink += v.Hash64(0)
+		}
+		atomic.AddUint64(&Sink, sink)
+	})
+	b.Run("MarshalTo-fnv", func(b *testing.B) {
+		b.SetBytes(int64(len(mediumFixture)))
+		b.ReportAllocs()
+		var sink uint64
+		var buf []byte
+		for i := 0; i < b.N; i++ {
+			buf = v.MarshalTo(buf[:0])
+			h := fnv.New64a()
+			h.Write(buf)
+			sink += h.Sum64()
+		}
+		atomic.AddUint64(&Sink, sink)
+	})
+}
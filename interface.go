@@ -0,0 +1,75 @@
+package fastjson
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ToInterfaceOpts contains options for Value.ToInterfaceOpts.
+type ToInterfaceOpts struct {
+	// UseInt64 enables converting integer numbers fitting int64 to int64
+	// instead of float64.
+	UseInt64 bool
+
+	// UseJSONNumber enables converting numbers to json.Number containing
+	// the original number text. It takes precedence over UseInt64.
+	UseJSONNumber bool
+}
+
+// ToInterface converts v to interface{} tree in the same way as
+// json.Unmarshal does.
+//
+// Objects are converted to map[string]interface{}, arrays to []interface{},
+// strings to string, numbers to float64, true and false to bool and null
+// to nil. The last value wins for duplicate object keys.
+//
+// Strings and object keys are unescaped and copied, so the returned value
+// remains valid after the next Parse call.
+func (v *Value) ToInterface() interface{} {
+	return v.ToInterfaceOpts(ToInterfaceOpts{})
+}
+
+// ToInterfaceOpts converts v to interface{} tree according to opts.
+//
+// See ToInterface for details.
+func (v *Value) ToInterfaceOpts(opts ToInterfaceOpts) interface{} {
+	if v == nil {
+		return nil
+	}
+	switch v.Type() {
+	case TypeObject:
+		v.o.unescapeKeys()
+		m := make(map[string]interface{}, len(v.o.kvs))
+		for _, kv := range v.o.kvs {
+			m[string(s2b(kv.k))] = kv.v.ToInterfaceOpts(opts)
+		}
+		return m
+	case TypeArray:
+		a := make([]interface{}, len(v.a))
+		for i, vv := range v.a {
+			a[i] = vv.ToInterfaceOpts(opts)
+		}
+		return a
+	case TypeString:
+		// Convert via []byte in order to make a copy of v.s.
+		return string(s2b(v.s))
+	case TypeNumber:
+		if opts.UseJSONNumber {
+			return json.Number(string(s2b(v.s)))
+		}
+		if opts.UseInt64 && v.NumberKind() == NumberInt {
+			if n, err := v.Int64(); err == nil {
+				return n
+			}
+		}
+		return v.Float64BestEffort()
+	case TypeTrue:
+		return true
+	case TypeFalse:
+		return false
+	case TypeNull:
+		return nil
+	default:
+		panic(fmt.Errorf("BUG: unexpected Value type: %d", v.t))
+	}
+}
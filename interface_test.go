@@ -0,0 +1,96 @@
+package fastjson
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestValueToInterface(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		var expected interface{}
+		if err := json.Unmarshal([]byte(s), &expected); err != nil {
+			t.Fatalf("cannot unmarshal %q: %s", s, err)
+		}
+		v := MustParse(s)
+		if result := v.ToInterface(); !reflect.DeepEqual(result, expected) {
+			t.Fatalf("unexpected result for %q;\ngot\n%#v\nwant\n%#v", s, result, expected)
+		}
+	}
+
+	f(`null`)
+	f(`true`)
+	f(`false`)
+	f(`123`)
+	f(`-1.5e-10`)
+	f(`"foo"`)
+	f(`"a\nb\u0041\"c"`)
+	f(`[]`)
+	f(`{}`)
+	f(`[1,"x",null,{"a":[true,false]}]`)
+	f(`{"a\nb":{"c":[1,2]},"d":"e"}`)
+	f(`{"a":1,"a":2}`)
+	f(smallFixture)
+	f(mediumFixture)
+	f(largeFixture)
+	f(canadaFixture)
+	f(citmFixture)
+	f(twitterFixture)
+
+	var v *Value
+	if result := v.ToInterface(); result != nil {
+		t.Fatalf("expecting nil result for nil value; got %#v", result)
+	}
+}
+
+func TestValueToInterfaceOpts(t *testing.T) {
+	v := MustParse(`{"int":123,"neg":-45,"float":1.5,"exp":1e3,"big":18446744073709551615,"arr":[1,2.5]}`)
+
+	result := v.ToInterfaceOpts(ToInterfaceOpts{UseInt64: true})
+	expected := map[string]interface{}{
+		"int":   int64(123),
+		"neg":   int64(-45),
+		"float": 1.5,
+		"exp":   1e3,
+		"big":   1.8446744073709552e19,
+		"arr":   []interface{}{int64(1), 2.5},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("unexpected result for UseInt64;\ngot\n%#v\nwant\n%#v", result, expected)
+	}
+
+	result = v.ToInterfaceOpts(ToInterfaceOpts{UseInt64: true, UseJSONNumber: true})
+	expected = map[string]interface{}{
+		"int":   json.Number("123"),
+		"neg":   json.Number("-45"),
+		"float": json.Number("1.5"),
+		"exp":   json.Number("1e3"),
+		"big":   json.Number("18446744073709551615"),
+		"arr":   []interface{}{json.Number("1"), json.Number("2.5")},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("unexpected result for UseJSONNumber;\ngot\n%#v\nwant\n%#v", result, expected)
+	}
+}
+
+func TestValueToInterfaceNoAliasing(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"key":"value","esc\naped":["str\ting", 1.5]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	result := v.ToInterfaceOpts(ToInterfaceOpts{UseJSONNumber: true})
+
+	// Overwrite the parser buffer.
+	if _, err := p.Parse(`{"xxx":"xxxxx","xxxxxxxxxx":["xxxxxxxxx", 9.9]}`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]interface{}{
+		"key":       "value",
+		"esc\naped": []interface{}{"str\ting", json.Number("1.5")},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("the result mustn't refer to parser buffer;\ngot\n%#v\nwant\n%#v", result, expected)
+	}
+}
@@ -0,0 +1,128 @@
+package fastjson
+
+import (
+	"fmt"
+)
+
+// ParseLenient parses s containing JSON with JavaScript-style extensions.
+//
+// Unlike Parse, it accepts object keys without quotes if they are
+// ECMAScript identifiers such as foo, _bar or $baz, and strings in single
+// quotes with \' escape sequences. For example, {foo: 'bar', baz: 2}.
+//
+// s is normalized to standard JSON before parsing, so MarshalTo always
+// returns standard JSON with double-quoted strings and keys for the parsed
+// value. Raw returns the normalized JSON too if KeepRaw is enabled. Offsets in the returned
+// *ParseError refer to the normalized JSON if s contains malformed JSON
+// outside strings.
+//
+// The returned value is valid until the next call to Parse*.
+func (p *Parser) ParseLenient(s string) (*Value, error) {
+	b, tail, err := appendNormalizedJSON(p.b[:0], skipBOM(s))
+	p.b = b
+	if err != nil {
+		p.v = nil
+		return nil, newParseError(s, tail, fmt.Sprintf("cannot parse JSON: %s; unparsed tail: %q", err, startEndString(tail)))
+	}
+	ns := b2s(p.b)
+	p.resetCache(ns, 0)
+	return p.parse(ns, skipWS(ns))
+}
+
+// ParseBytesLenient parses b containing JSON with JavaScript-style extensions.
+//
+// See ParseLenient for details.
+func (p *Parser) ParseBytesLenient(b []byte) (*Value, error) {
+	return p.ParseLenient(b2s(b))
+}
+
+// appendNormalizedJSON appends s to dst after converting unquoted object
+// keys and single-quoted strings to double-quoted strings.
+//
+// The rest of s is appended as is, so it is validated by the parser.
+// The returned tail points to the malformed string in s on error.
+func appendNormalizedJSON(dst []byte, s string) ([]byte, string, error) {
+	for len(s) > 0 {
+		ch := s[0]
+		switch {
+		case ch == '"':
+			ss, tail, err := parseRawString(s[1:])
+			if err != nil {
+				return dst, s, fmt.Errorf("cannot parse string: %s", err)
+			}
+			dst = append(dst, '"')
+			dst = append(dst, ss...)
+			dst = append(dst, '"')
+			s = tail
+		case ch == '\'':
+			var err error
+			dst, s, err = appendSingleQuotedString(dst, s)
+			if err != nil {
+				return dst, s, err
+			}
+		case isIdentifierStart(ch):
+			n := 1
+			for n < len(s) && (isIdentifierStart(s[n]) || s[n] >= '0' && s[n] <= '9') {
+				n++
+			}
+			ident := s[:n]
+			s = s[n:]
+			if tail := skipWS(s); len(tail) > 0 && tail[0] == ':' {
+				// Object key.
+				dst = append(dst, '"')
+				dst = append(dst, ident...)
+				dst = append(dst, '"')
+			} else {
+				// true, false, null and other values are validated by the parser.
+				dst = append(dst, ident...)
+			}
+		default:
+			dst = append(dst, ch)
+			s = s[1:]
+		}
+	}
+	return dst, s, nil
+}
+
+// appendSingleQuotedString appends single-quoted string from the beginning of s
+// to dst as double-quoted string.
+//
+// It returns the tail of s after the string.
+func appendSingleQuotedString(dst []byte, s string) ([]byte, string, error) {
+	sOrig := s
+	dst = append(dst, '"')
+	s = s[1:]
+	for len(s) > 0 {
+		ch := s[0]
+		switch ch {
+		case '\'':
+			dst = append(dst, '"')
+			return dst, s[1:], nil
+		case '"':
+			dst = append(dst, '\\', '"')
+			s = s[1:]
+		case '\\':
+			if len(s) < 2 {
+				return dst, sOrig, fmt.Errorf(`cannot parse string: missing closing '\''`)
+			}
+			if s[1] == '\'' {
+				dst = append(dst, '\'')
+			} else {
+				// Other escape sequences are validated by the parser.
+				dst = append(dst, s[:2]...)
+			}
+			s = s[2:]
+		default:
+			dst = append(dst, ch)
+			s = s[1:]
+		}
+	}
+	return dst, sOrig, fmt.Errorf(`cannot parse string: missing closing '\''`)
+}
+
+// isIdentifierStart returns true if ch may start ECMAScript identifier.
+//
+// Non-ASCII chars are treated as identifier chars.
+func isIdentifierStart(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_' || ch == '$' || ch >= 0x80
+}
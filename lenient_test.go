@@ -0,0 +1,93 @@
+package fastjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParserParseLenient(t *testing.T) {
+	f := func(s, resultExpected string) {
+		t.Helper()
+		var p Parser
+		v, err := p.ParseLenient(s)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing %q: %s", s, err)
+		}
+		result := v.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q;\ngot\n%s\nwant\n%s", s, result, resultExpected)
+		}
+		if err := Validate(result); err != nil {
+			t.Fatalf("marshaled value for %q isn't valid JSON: %s\n%s", s, err, result)
+		}
+	}
+
+	// Standard JSON
+	f(`{"foo":"bar","baz":[1,2,{"x":null}]}`, `{"foo":"bar","baz":[1,2,{"x":null}]}`)
+	f(` [true, false] `, `[true,false]`)
+	f(`"a'b"`, `"a'b"`)
+
+	// Unquoted keys
+	f(`{foo: 'bar', baz: 2}`, `{"foo":"bar","baz":2}`)
+	f(`{$foo:1,_bar:2,$_:3,a1$:4}`, `{"$foo":1,"_bar":2,"$_":3,"a1$":4}`)
+	f(`{ foo : { bar : [ { baz : true } ] } }`, `{"foo":{"bar":[{"baz":true}]}}`)
+	f(`{true: 1, null: false}`, `{"true":1,"null":false}`)
+	f(`{e: 1e5}`, `{"e":1e5}`)
+
+	// Single-quoted strings
+	f(`'foo'`, `"foo"`)
+	f(`''`, `""`)
+	f(`'it\'s'`, `"it's"`)
+	f(`'say "hi"'`, `"say \"hi\""`)
+	f(`'a\nb\\'`, `"a\nb\\"`)
+	f(`{'foo': 'bar'}`, `{"foo":"bar"}`)
+
+	// Mixed quoting in a single document
+	f(`{a: 'x', "b": "y", 'c': "z'", d: ['e', "f", 'g"']}`, `{"a":"x","b":"y","c":"z'","d":["e","f","g\""]}`)
+
+	// Keys inside strings mustn't be modified
+	f(`{a: "b: c", 'd': 'e: f'}`, `{"a":"b: c","d":"e: f"}`)
+}
+
+func TestParserParseLenientError(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		var p Parser
+		v, err := p.ParseLenient(s)
+		if err == nil {
+			t.Fatalf("expecting non-nil error when parsing %q; got %s", s, v)
+		}
+		if _, ok := err.(*ParseError); !ok {
+			t.Fatalf("unexpected error type for %q: %T", s, err)
+		}
+	}
+
+	// Unterminated strings
+	f(`'foo`)
+	f(`'foo\'`)
+	f(`{a: 'b}`)
+	f(`"foo`)
+	f(`{"a: 1}`)
+
+	// Missing colons
+	f(`{foo 1}`)
+	f(`{foo}`)
+	f(`{'foo' 'bar'}`)
+
+	// Other malformed input
+	f(``)
+	f(`foo`)
+	f(`{foo: bar}`)
+	f(`[a: 1]`)
+	f(`{1: 2}`)
+	f(`{foo-bar: 1}`)
+	f(`{a: 1,}`)
+
+	// Error offset for unterminated single-quoted string
+	var p Parser
+	_, err := p.ParseLenient(`{a: 'b}`)
+	pe := err.(*ParseError)
+	if offsetExpected := strings.IndexByte(`{a: 'b}`, '\''); pe.Offset != offsetExpected {
+		t.Fatalf("unexpected error offset; got %d; want %d", pe.Offset, offsetExpected)
+	}
+}
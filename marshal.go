@@ -0,0 +1,271 @@
+package fastjson
+
+import (
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// MarshalOptions contains options for MarshalToOpts.
+//
+// The zero MarshalOptions results in the same output as MarshalTo.
+type MarshalOptions struct {
+	// EscapeHTML enables escaping of <, > and & chars in strings
+	// and object keys as \u003c, \u003e and \u0026, so the output
+	// may be safely embedded into HTML <script> tags.
+	//
+	// U+2028 and U+2029 chars are escaped too, since they cannot be
+	// embedded into JavaScript strings.
+	EscapeHTML bool
+
+	// ASCIIOnly enables escaping of all the non-ASCII chars in strings
+	// and object keys as \uXXXX, so the output contains only ASCII chars.
+	//
+	// Chars outside the Basic Multilingual Plane are escaped as UTF-16
+	// surrogate pairs.
+	ASCIIOnly bool
+}
+
+// MarshalToOpts appends marshaled v to dst according to opts
+// and returns the result.
+//
+// Strings and object keys are unescaped and then escaped again according
+// to opts, so escape sequences from the original JSON aren't preserved.
+// Invalid UTF-8 sequences in strings are replaced by \ufffd.
+//
+// MarshalToOpts is equivalent to MarshalTo if opts is zero.
+func (v *Value) MarshalToOpts(dst []byte, opts MarshalOptions) []byte {
+	if opts == (MarshalOptions{}) {
+		return v.MarshalTo(dst)
+	}
+	switch v.Type() {
+	case TypeObject:
+		return v.o.marshalToOpts(dst, &opts)
+	case TypeArray:
+		dst = append(dst, '[')
+		for i, vv := range v.a {
+			dst = vv.MarshalToOpts(dst, opts)
+			if i != len(v.a)-1 {
+				dst = append(dst, ',')
+			}
+		}
+		dst = append(dst, ']')
+		return dst
+	case TypeString:
+		return escapeStringOpts(dst, v.s, &opts)
+	case TypeNumber:
+		return append(dst, v.s...)
+	case TypeTrue:
+		return append(dst, "true"...)
+	case TypeFalse:
+		return append(dst, "false"...)
+	case TypeNull:
+		return append(dst, "null"...)
+	default:
+		panic(fmt.Errorf("BUG: unexpected Value type: %d", v.t))
+	}
+}
+
+// MarshalToOpts appends marshaled o to dst according to opts
+// and returns the result.
+//
+// See Value.MarshalToOpts for details.
+func (o *Object) MarshalToOpts(dst []byte, opts MarshalOptions) []byte {
+	if opts == (MarshalOptions{}) {
+		return o.MarshalTo(dst)
+	}
+	return o.marshalToOpts(dst, &opts)
+}
+
+func (o *Object) marshalToOpts(dst []byte, opts *MarshalOptions) []byte {
+	// Raw keys must be unescaped before escaping them according to opts.
+	o.unescapeKeys()
+	dst = append(dst, '{')
+	for i, kv := range o.kvs {
+		dst = escapeStringOpts(dst, kv.k, opts)
+		dst = append(dst, ':')
+		dst = kv.v.MarshalToOpts(dst, *opts)
+		if i != len(o.kvs)-1 {
+			dst = append(dst, ',')
+		}
+	}
+	dst = append(dst, '}')
+	return dst
+}
+
+// MarshalLen returns the number of bytes MarshalTo appends for v.
+//
+// It doesn't build the marshaled v, so it may be used for checking
+// whether v fits the given size limit before marshaling.
+func (v *Value) MarshalLen() int {
+	switch v.t {
+	case typeRawString:
+		return len(v.s) + 2
+	case TypeObject:
+		return v.o.MarshalLen()
+	case TypeArray:
+		n := 2
+		if len(v.a) > 1 {
+			// Commas between items.
+			n += len(v.a) - 1
+		}
+		for _, vv := range v.a {
+			n += vv.MarshalLen()
+		}
+		return n
+	case TypeString:
+		return escapedLen(v.s)
+	case TypeNumber:
+		return len(v.s)
+	case TypeTrue:
+		return len("true")
+	case TypeFalse:
+		return len("false")
+	case TypeNull:
+		return len("null")
+	default:
+		panic(fmt.Errorf("BUG: unexpected Value type: %d", v.t))
+	}
+}
+
+// MarshalLen returns the number of bytes MarshalTo appends for o.
+//
+// See Value.MarshalLen for details.
+func (o *Object) MarshalLen() int {
+	n := 2
+	if len(o.kvs) > 1 {
+		// Commas between entries.
+		n += len(o.kvs) - 1
+	}
+	for _, kv := range o.kvs {
+		if o.keysUnescaped {
+			n += escapedLen(kv.k)
+		} else {
+			n += len(kv.k) + 2
+		}
+		// Colon after the key.
+		n++
+		n += kv.v.MarshalLen()
+	}
+	return n
+}
+
+// escapeStringOpts appends JSON-quoted s to dst according to opts.
+func escapeStringOpts(dst []byte, s string, opts *MarshalOptions) []byte {
+	dst = append(dst, '"')
+	for i := 0; i < len(s); {
+		ch := s[i]
+		if ch < utf8.RuneSelf {
+			i++
+			if esc := asciiEscapes[ch]; esc != "" {
+				dst = append(dst, esc...)
+			} else if opts.EscapeHTML && (ch == '<' || ch == '>' || ch == '&') {
+				dst = appendUnicodeEscape(dst, rune(ch))
+			} else {
+				dst = append(dst, ch)
+			}
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			dst = appendUnicodeEscape(dst, utf8.RuneError)
+		case opts.ASCIIOnly && r > 0xffff:
+			r1, r2 := utf16.EncodeRune(r)
+			dst = appendUnicodeEscape(dst, r1)
+			dst = appendUnicodeEscape(dst, r2)
+		case opts.ASCIIOnly, opts.EscapeHTML && (r == '\u2028' || r == '\u2029'):
+			dst = appendUnicodeEscape(dst, r)
+		default:
+			dst = append(dst, s[i:i+size]...)
+		}
+		i += size
+	}
+	dst = append(dst, '"')
+	return dst
+}
+
+// asciiEscapes contains escape sequences for ASCII chars, which must be
+// escaped in JSON strings.
+//
+// The escape sequence is empty if the char mustn't be escaped.
+var asciiEscapes = func() (t [utf8.RuneSelf]string) {
+	for ch := 0; ch < 0x20; ch++ {
+		t[ch] = string(appendUnicodeEscape(nil, rune(ch)))
+	}
+	t['"'] = `\"`
+	t['\\'] = `\\`
+	t['\n'] = `\n`
+	t['\r'] = `\r`
+	t['\t'] = `\t`
+	t['\b'] = `\b`
+	t['\f'] = `\f`
+	return t
+}()
+
+// unicodeEscapeLen is the length of escape sequence appended
+// by appendUnicodeEscape.
+const unicodeEscapeLen = len(`\u0000`)
+
+// escapedLen returns the length of JSON-quoted s appended by escapeString.
+func escapedLen(s string) int {
+	if !needsEscaping(s) {
+		return len(s) + 2
+	}
+	n := 2
+	for i := 0; i < len(s); {
+		ch := s[i]
+		if ch < utf8.RuneSelf {
+			i++
+			if esc := asciiEscapes[ch]; esc != "" {
+				n += len(esc)
+			} else {
+				n++
+			}
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			n += unicodeEscapeLen
+		} else {
+			n += size
+		}
+		i += size
+	}
+	return n
+}
+
+// appendUnicodeEscape appends \uXXXX escape sequence for r to dst.
+//
+// r must fit 16 bits.
+func appendUnicodeEscape(dst []byte, r rune) []byte {
+	const hex = "0123456789abcdef"
+	return append(dst, '\\', 'u', hex[(r>>12)&0xf], hex[(r>>8)&0xf], hex[(r>>4)&0xf], hex[r&0xf])
+}
+
+// MarshalJSON implements json.Marshaler, so v may be embedded into structs
+// serialized with encoding/json.
+//
+// It is equivalent to MarshalTo. null is returned for nil v.
+func (v *Value) MarshalJSON() ([]byte, error) {
+	if v == nil {
+		return []byte("null"), nil
+	}
+	return v.MarshalTo(nil), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, so v may be embedded into structs
+// deserialized with encoding/json.
+//
+// data is copied, and the parsed value doesn't share memory with any Parser,
+// so v remains valid after data is modified.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	// Use a fresh Parser, since the parsed value must outlive it.
+	var p Parser
+	pv, err := p.Parse(string(data))
+	if err != nil {
+		return err
+	}
+	*v = *pv
+	return nil
+}
@@ -0,0 +1,385 @@
+package fastjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestMarshalToOpts(t *testing.T) {
+	f := func(s string, opts MarshalOptions, resultExpected string) {
+		t.Helper()
+
+		var p Parser
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		var expected interface{}
+		if err := json.Unmarshal([]byte(s), &expected); err != nil {
+			t.Fatalf("cannot unmarshal %q via encoding/json: %s", s, err)
+		}
+
+		result := v.MarshalToOpts(nil, opts)
+		if string(result) != resultExpected {
+			t.Fatalf("unexpected result for %q; got\n%s\nwant\n%s", s, result, resultExpected)
+		}
+		var got interface{}
+		if err := json.Unmarshal(result, &got); err != nil {
+			t.Fatalf("cannot unmarshal %q via encoding/json: %s", result, err)
+		}
+		if !jsonEqual(got, expected) {
+			t.Fatalf("unexpected value after round-trip; got %#v; want %#v", got, expected)
+		}
+
+		// Check for ASCII-only output.
+		if opts.ASCIIOnly {
+			for i := 0; i < len(result); i++ {
+				if result[i] >= 0x80 {
+					t.Fatalf("unexpected non-ASCII char at position %d in %q", i, result)
+				}
+			}
+		}
+	}
+
+	ascii := MarshalOptions{ASCIIOnly: true}
+	html := MarshalOptions{EscapeHTML: true}
+	all := MarshalOptions{ASCIIOnly: true, EscapeHTML: true}
+
+	// Zero options must result in MarshalTo output
+	f(`{"foo":["bar",1,true,null],"x\"y":"<é>"}`, MarshalOptions{}, `{"foo":["bar",1,true,null],"x\"y":"<é>"}`)
+
+	// Emoji
+	f(`"😀 héllo"`, ascii, `"\ud83d\ude00 h\u00e9llo"`)
+	f(`"😀 héllo"`, html, `"😀 héllo"`)
+	f(`"😀"`, ascii, `"\ud83d\ude00"`)
+	f(`"😀"`, html, `"😀"`)
+
+	// HTML-special chars
+	f(`"<script>alert('a&b')</script>"`, html, `"\u003cscript\u003ealert('a\u0026b')\u003c/script\u003e"`)
+	f(`"<script>"`, ascii, `"<script>"`)
+	f("\"a\u2028b\u2029c\"", html, `"a\u2028b\u2029c"`)
+	f(`"<ä>"`, all, `"\u003c\u00e4\u003e"`)
+
+	// Already escaped sequences
+	f(`"\"\\\/\b\f\n\r\t\u0001"`, all, `"\"\\/\b\f\n\r\t\u0001"`)
+	f(`"\u00e9\\u00e9"`, ascii, `"\u00e9\\u00e9"`)
+
+	// Object keys, including keys with escape sequences
+	f(`{"<kéy>":{"😀":"<v>"},"a\nb":[]}`, all, `{"\u003ck\u00e9y\u003e":{"\ud83d\ude00":"\u003cv\u003e"},"a\nb":[]}`)
+	f(`{"\"k\"":1}`, html, `{"\"k\"":1}`)
+	f(`{"\u00e9\u003c":1}`, all, `{"\u00e9\u003c":1}`)
+	f(`{"\u00e9<":1}`, html, `{"é\u003c":1}`)
+
+	// Numbers remain unchanged
+	f(`[1.5e3,-0]`, all, `[1.5e3,-0]`)
+}
+
+func TestMarshalToOptsInvalidUTF8(t *testing.T) {
+	var a Arena
+	o := a.NewObject()
+	o.Set("k\xff", a.NewString("a\xc0b"))
+	result := o.MarshalToOpts(nil, MarshalOptions{ASCIIOnly: true})
+	resultExpected := `{"k\ufffd":"a\ufffdb"}`
+	if string(result) != resultExpected {
+		t.Fatalf("unexpected result; got %s; want %s", result, resultExpected)
+	}
+}
+
+func jsonEqual(a, b interface{}) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(ja) == string(jb)
+}
+
+func TestValueMarshalUnmarshalJSON(t *testing.T) {
+	type record struct {
+		ID      int     `json:"id"`
+		Payload *Value  `json:"payload"`
+		Extra   Value   `json:"extra"`
+		Missing *Value  `json:"missing"`
+		Items   []Value `json:"items"`
+	}
+
+	data := []byte(`{"id":42,"payload":{"foo":[1,"bar",{"baz":true}],"x\"y":null},"extra":"é\n","missing":null,"items":[1,{"a":"b"}]}`)
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatalf("cannot unmarshal %s: %s", data, err)
+	}
+
+	// Modify the original data in order to verify the unmarshaled values don't refer to it.
+	for i := range data {
+		data[i] = 'x'
+	}
+
+	if r.ID != 42 {
+		t.Fatalf("unexpected id; got %d; want %d", r.ID, 42)
+	}
+	if r.Missing != nil {
+		t.Fatalf("expecting nil value for missing; got %s", r.Missing)
+	}
+	if n := r.Payload.GetInt("foo", "0"); n != 1 {
+		t.Fatalf("unexpected payload.foo[0]; got %d; want %d", n, 1)
+	}
+	if s := r.Payload.GetString("foo", "1"); s != "bar" {
+		t.Fatalf("unexpected payload.foo[1]; got %q; want %q", s, "bar")
+	}
+	if !r.Payload.GetBool("foo", "2", "baz") {
+		t.Fatalf("unexpected payload.foo[2].baz; got false; want true")
+	}
+	if s := r.Extra.GetString(); s != "é\n" {
+		t.Fatalf("unexpected extra; got %q; want %q", s, "é\n")
+	}
+	if len(r.Items) != 2 || r.Items[1].GetString("a") != "b" {
+		t.Fatalf("unexpected items: %v", r.Items)
+	}
+
+	result, err := json.Marshal(&r)
+	if err != nil {
+		t.Fatalf("cannot marshal %#v: %s", r, err)
+	}
+	resultExpected := `{"id":42,"payload":{"foo":[1,"bar",{"baz":true}],"x\"y":null},"extra":"é\n","missing":null,"items":[1,{"a":"b"}]}`
+	if string(result) != resultExpected {
+		t.Fatalf("unexpected result; got\n%s\nwant\n%s", result, resultExpected)
+	}
+}
+
+func TestValueUnmarshalJSONError(t *testing.T) {
+	var v Value
+	if err := v.UnmarshalJSON([]byte(`{"foo":`)); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+
+	var r struct {
+		Payload *Value `json:"payload"`
+	}
+	if err := json.Unmarshal([]byte(`{"payload":[1,2}`), &r); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
+
+func TestValueMarshalLen(t *testing.T) {
+	f := func(v *Value) {
+		t.Helper()
+		data := v.MarshalTo(nil)
+		if n := v.MarshalLen(); n != len(data) {
+			t.Fatalf("unexpected MarshalLen; got %d; want %d for %s", n, len(data), data)
+		}
+		if v.Type() == TypeObject {
+			o := v.GetObject()
+			data = o.MarshalTo(nil)
+			if n := o.MarshalLen(); n != len(data) {
+				t.Fatalf("unexpected Object.MarshalLen; got %d; want %d for %s", n, len(data), data)
+			}
+		}
+	}
+
+	for _, s := range []string{
+		`null`, `true`, `false`, `123`, `-1.5e10`, `""`, `"foo"`, `[]`, `{}`, `[1]`, `[1,2,3]`,
+		`{"a":1}`, `{"a":1,"b":[{},[]]}`, `"a\nb\"c\\d\u0000😀"`, `{"\n\"":"\t"}`,
+		smallFixture, mediumFixture, largeFixture, canadaFixture, citmFixture, twitterFixture,
+	} {
+		// Raw strings and keys
+		f(MustParse(s))
+
+		// Unescaped strings and keys
+		v := MustParse(s)
+		v.Normalize()
+		f(v)
+	}
+
+	// Heavy escaping
+	var a Arena
+	ss := []string{"", "foo", "a\"b\\c", "\x00\x01\x1f\x7f\n\r\t\b\f", "\xff\xfe", "\xed\xa0\x80", "<>&", "\xe2\x80\xa8", "\xf0\x9f\x98\x80"}
+	o := a.NewObject()
+	for _, s := range ss {
+		o.Set(s, a.NewString(s))
+		sv := MustParse(`""`)
+		sv.s = s
+		sv.t = TypeString
+		f(sv)
+	}
+	o.Set("strings", a.NewArrayFromStrings(ss))
+	f(o)
+}
+
+func TestValueMarshalLenRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var a Arena
+	randString := func() string {
+		b := make([]byte, r.Intn(10))
+		for i := range b {
+			switch r.Intn(4) {
+			case 0:
+				b[i] = byte(r.Intn(0x20))
+			case 1:
+				b[i] = `"\</`[r.Intn(4)]
+			case 2:
+				b[i] = byte(0x80 + r.Intn(0x80))
+			default:
+				b[i] = byte('a' + r.Intn(26))
+			}
+		}
+		return string(b)
+	}
+	var randValue func(depth int) *Value
+	randValue = func(depth int) *Value {
+		n := r.Intn(8)
+		if depth > 4 && n < 2 {
+			n += 2
+		}
+		switch n {
+		case 0:
+			v := a.NewObject()
+			for i := r.Intn(5); i > 0; i-- {
+				v.Set(randString(), randValue(depth+1))
+			}
+			return v
+		case 1:
+			v := a.NewArray()
+			for i := r.Intn(5); i > 0; i-- {
+				v.SetArrayItem(len(v.a), randValue(depth+1))
+			}
+			return v
+		case 2:
+			return a.NewString(randString())
+		case 3:
+			return a.NewNumberFloat64(r.NormFloat64() * 1e10)
+		case 4:
+			return a.NewNumberInt(r.Int())
+		case 5:
+			return a.NewTrue()
+		case 6:
+			return a.NewFalse()
+		default:
+			return a.NewNull()
+		}
+	}
+
+	for i := 0; i < 1000; i++ {
+		v := randValue(0)
+		data := v.MarshalTo(nil)
+		if n := v.MarshalLen(); n != len(data) {
+			t.Fatalf("unexpected MarshalLen; got %d; want %d for %s", n, len(data), data)
+		}
+
+		// Unescape strings and keys, so they are escaped again on marshaling.
+		vv := MustParse(string(data))
+		vv.Normalize()
+		data = vv.MarshalTo(nil)
+		if n := vv.MarshalLen(); n != len(data) {
+			t.Fatalf("unexpected MarshalLen for normalized value; got %d; want %d for %s", n, len(data), data)
+		}
+		a.Reset()
+	}
+}
+
+func TestValueMarshalLenAllocs(t *testing.T) {
+	v := MustParse(mediumFixture)
+	v.Normalize()
+	n := testing.AllocsPerRun(100, func() {
+		v.MarshalLen()
+	})
+	if n != 0 {
+		t.Fatalf("unexpected number of allocations; got %v; want 0", n)
+	}
+}
+
+func TestBufferPoolClass(t *testing.T) {
+	f := func(n, classExpected int) {
+		t.Helper()
+		class := bufferPoolClass(n)
+		if class != classExpected {
+			t.Fatalf("unexpected class for n=%d; got %d; want %d", n, class, classExpected)
+		}
+		if class < bufferPoolClasses && bufferPoolMinSize<<uint(class) < n {
+			t.Fatalf("too small class %d for n=%d", class, n)
+		}
+	}
+	f(0, 0)
+	f(1, 0)
+	f(1024, 0)
+	f(1025, 1)
+	f(2048, 1)
+	f(2049, 2)
+	f(1<<20, 10)
+	f(64<<20, 16)
+	f(64<<20+1, 17)
+}
+
+func TestValueMarshalToPooled(t *testing.T) {
+	var bp BufferPool
+	for _, s := range []string{`null`, `"foo\nbar"`, smallFixture, mediumFixture, largeFixture, canadaFixture, citmFixture, twitterFixture} {
+		v := MustParse(s)
+		expected := v.MarshalTo(nil)
+		for i := 0; i < 3; i++ {
+			buf, release := v.MarshalToPooled(&bp)
+			if string(buf) != string(expected) {
+				t.Fatalf("unexpected marshaled value\ngot\n%s\nwant\n%s", buf, expected)
+			}
+			if cap(buf) < bufferPoolMinSize {
+				t.Fatalf("too small buffer capacity: %d", cap(buf))
+			}
+			release()
+		}
+	}
+
+	// Buffers exceeding maxRetainedBytes must be dropped.
+	bp2 := NewBufferPool(4096)
+	v := MustParse(canadaFixture)
+	buf, release := v.MarshalToPooled(bp2)
+	release()
+	pb := bp2.get(len(buf))
+	if cap(pb.b) < len(buf) {
+		t.Fatalf("too small buffer; got cap=%d; want at least %d", cap(pb.b), len(buf))
+	}
+	for class := range bp2.pools {
+		if x := bp2.pools[class].Get(); x != nil && cap(x.(*pooledBuffer).b) > 4096 {
+			t.Fatalf("the pool mustn't retain buffers exceeding maxRetainedBytes; found buffer with cap=%d", cap(x.(*pooledBuffer).b))
+		}
+	}
+}
+
+func TestValueMarshalToPooledConcurrent(t *testing.T) {
+	var bp BufferPool
+	var vs []*Value
+	var expected [][]byte
+	for _, s := range []string{`[1,2,3]`, smallFixture, mediumFixture, largeFixture, twitterFixture} {
+		v := MustParse(s)
+		v.Normalize()
+		vs = append(vs, v)
+		expected = append(expected, v.MarshalTo(nil))
+	}
+
+	ch := make(chan error, 8)
+	for i := 0; i < cap(ch); i++ {
+		go func(seed int) {
+			for j := 0; j < 100; j++ {
+				k := (seed + j) % len(vs)
+				buf, release := vs[k].MarshalToPooled(&bp)
+				if string(buf) != string(expected[k]) {
+					ch <- fmt.Errorf("unexpected marshaled value #%d", k)
+					return
+				}
+				// Modify the buffer in order to detect buffers shared among goroutines.
+				for n := range buf {
+					buf[n] = 'x'
+				}
+				release()
+			}
+			ch <- nil
+		}(i)
+	}
+	for i := 0; i < cap(ch); i++ {
+		if err := <-ch; err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+}
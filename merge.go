@@ -0,0 +1,79 @@
+package fastjson
+
+import (
+	"fmt"
+)
+
+// MergePolicy controls the behavior of Value.Merge.
+//
+// The zero value MergePolicy replaces values in the receiver with the values
+// from other.
+type MergePolicy struct {
+	// PreferReceiver keeps the receiver values on conflicts.
+	// Otherwise values from other win.
+	PreferReceiver bool
+
+	// ConcatArrays appends items from other arrays to receiver arrays
+	// instead of resolving the conflict.
+	ConcatArrays bool
+
+	// Recursive merges nested objects recursively instead of resolving
+	// the conflict.
+	Recursive bool
+
+	// NullDeletes deletes keys with null values in other from the receiver.
+	NullDeletes bool
+}
+
+// Merge merges other object into v object according to the given policy.
+//
+// v is modified in place. Values from other are copied into v,
+// so other may be modified or become invalid after the call.
+//
+// An error is returned if v or other isn't an object or if the nesting depth
+// exceeds MaxDepth.
+func (v *Value) Merge(other *Value, policy MergePolicy) error {
+	if v == nil || v.t != TypeObject {
+		return fmt.Errorf("cannot merge into non-object value")
+	}
+	if other == nil || other.t != TypeObject {
+		return fmt.Errorf("cannot merge non-object value")
+	}
+	return mergeObjects(&v.o, &other.o, &policy, 1)
+}
+
+func mergeObjects(o, other *Object, policy *MergePolicy, depth int) error {
+	if depth > MaxDepth {
+		return fmt.Errorf("too big depth for the merged objects; it exceeds %d", MaxDepth)
+	}
+	other.unescapeKeys()
+	for _, kv := range other.kvs {
+		if policy.NullDeletes && kv.v.t == TypeNull {
+			o.Del(kv.k)
+			continue
+		}
+		v := o.Get(kv.k)
+		if v == nil {
+			// Copy the key, since it belongs to other.
+			k := string(s2b(kv.k))
+			o.Set(k, kv.v.Clone())
+			continue
+		}
+		if policy.Recursive && v.t == TypeObject && kv.v.t == TypeObject {
+			if err := mergeObjects(&v.o, &kv.v.o, policy, depth+1); err != nil {
+				return fmt.Errorf("cannot merge %q: %s", kv.k, err)
+			}
+			continue
+		}
+		if policy.ConcatArrays && v.t == TypeArray && kv.v.t == TypeArray {
+			for _, vv := range kv.v.a {
+				v.a = append(v.a, vv.Clone())
+			}
+			continue
+		}
+		if !policy.PreferReceiver {
+			o.Set(kv.k, kv.v.Clone())
+		}
+	}
+	return nil
+}
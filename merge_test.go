@@ -0,0 +1,106 @@
+package fastjson
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValueMerge(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		policy := MergePolicy{
+			PreferReceiver: i&1 != 0,
+			ConcatArrays:   i&2 != 0,
+			Recursive:      i&4 != 0,
+			NullDeletes:    i&8 != 0,
+		}
+		t.Run(fmt.Sprintf("%+v", policy), func(t *testing.T) {
+			testValueMerge(t, policy)
+		})
+	}
+}
+
+func testValueMerge(t *testing.T, policy MergePolicy) {
+	var p1, p2 Parser
+	v, err := p1.Parse(`{"s":1,"a":[1],"o":{"x":1,"y":1},"n":"keep","m":{"k":"v"}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	other, err := p2.Parse(`{"s":2,"a":[2],"o":{"y":2,"z":{"w":"x\ny"}},"n":null,"m":"scalar","new":[3]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := v.Merge(other, policy); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Overwrite the memory for other in order to detect aliasing.
+	if _, err := p2.Parse(`{"aaaaaaaaaaaaaaaaaaaaaaaa":["bbbbbbbbbbbbbbbbbbbbbbbbbbbbbb",1,2,3,4,5,6,7]}`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	pick := func(receiver, other string) string {
+		if policy.PreferReceiver {
+			return receiver
+		}
+		return other
+	}
+	var items []string
+	items = append(items, `"s":`+pick(`1`, `2`))
+	if policy.ConcatArrays {
+		items = append(items, `"a":[1,2]`)
+	} else {
+		items = append(items, `"a":`+pick(`[1]`, `[2]`))
+	}
+	if policy.Recursive {
+		items = append(items, `"o":{"x":1,"y":`+pick(`1`, `2`)+`,"z":{"w":"x\ny"}}`)
+	} else {
+		items = append(items, `"o":`+pick(`{"x":1,"y":1}`, `{"y":2,"z":{"w":"x\ny"}}`))
+	}
+	if !policy.NullDeletes {
+		items = append(items, `"n":`+pick(`"keep"`, `null`))
+	}
+	items = append(items, `"m":`+pick(`{"k":"v"}`, `"scalar"`))
+	items = append(items, `"new":[3]`)
+	sExpected := "{" + strings.Join(items, ",") + "}"
+
+	if s := v.String(); s != sExpected {
+		t.Fatalf("unexpected merge result\ngot\n%s\nwant\n%s", s, sExpected)
+	}
+}
+
+func TestValueMergeError(t *testing.T) {
+	f := func(s, sOther string) {
+		t.Helper()
+		v := MustParse(s)
+		if err := v.Merge(MustParse(sOther), MergePolicy{Recursive: true}); err == nil {
+			t.Fatalf("expecting non-nil error when merging %s into %s", sOther, s)
+		}
+	}
+	f(`[]`, `{}`)
+	f(`{}`, `[]`)
+	f(`{}`, `"foo"`)
+
+	var v *Value
+	if err := v.Merge(MustParse(`{}`), MergePolicy{}); err == nil {
+		t.Fatalf("expecting non-nil error when merging into nil value")
+	}
+	if err := MustParse(`{}`).Merge(nil, MergePolicy{}); err == nil {
+		t.Fatalf("expecting non-nil error when merging nil value")
+	}
+
+	// Too deep objects. Build them via Arena, since Parser limits the depth.
+	var a Arena
+	v1 := a.NewObject()
+	v2 := a.NewObject()
+	o1, o2 := v1, v2
+	for i := 0; i < MaxDepth+1; i++ {
+		n1, n2 := a.NewObject(), a.NewObject()
+		o1.Set("x", n1)
+		o2.Set("x", n2)
+		o1, o2 = n1, n2
+	}
+	if err := v1.Merge(v2, MergePolicy{Recursive: true}); err == nil {
+		t.Fatalf("expecting non-nil error for too deep objects")
+	}
+}
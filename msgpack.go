@@ -0,0 +1,148 @@
+package fastjson
+
+import (
+	"fmt"
+	"math"
+)
+
+// MarshalMsgpackTo appends v encoded in MessagePack format to dst
+// and returns the result.
+//
+// Objects are encoded as maps with string keys, strings are unescaped.
+// Integer numbers fitting int64 or uint64 are encoded as the smallest
+// MessagePack integers, while the rest of numbers are encoded as float64.
+// NaN and Inf numbers are encoded as float64 too.
+//
+// An error is returned if v contains a number, which cannot be parsed.
+func (v *Value) MarshalMsgpackTo(dst []byte) ([]byte, error) {
+	switch v.Type() {
+	case TypeObject:
+		v.o.unescapeKeys()
+		dst = appendMsgpackHeader(dst, len(v.o.kvs), 0x80, 0xde)
+		for _, kv := range v.o.kvs {
+			dst = appendMsgpackString(dst, kv.k)
+			var err error
+			dst, err = kv.v.MarshalMsgpackTo(dst)
+			if err != nil {
+				return dst, err
+			}
+		}
+		return dst, nil
+	case TypeArray:
+		dst = appendMsgpackHeader(dst, len(v.a), 0x90, 0xdc)
+		for _, vv := range v.a {
+			var err error
+			dst, err = vv.MarshalMsgpackTo(dst)
+			if err != nil {
+				return dst, err
+			}
+		}
+		return dst, nil
+	case TypeString:
+		return appendMsgpackString(dst, v.s), nil
+	case TypeNumber:
+		return appendMsgpackNumber(dst, v)
+	case TypeTrue:
+		return append(dst, 0xc3), nil
+	case TypeFalse:
+		return append(dst, 0xc2), nil
+	case TypeNull:
+		return append(dst, 0xc0), nil
+	default:
+		panic(fmt.Errorf("BUG: unexpected Value type: %d", v.t))
+	}
+}
+
+// appendMsgpackHeader appends map or array header for n items to dst.
+//
+// fixPrefix is the prefix for fixmap or fixarray, while prefix16
+// is the prefix for map16 or array16. The prefix for map32 or array32
+// is prefix16+1.
+func appendMsgpackHeader(dst []byte, n int, fixPrefix, prefix16 byte) []byte {
+	if n < 16 {
+		return append(dst, fixPrefix|byte(n))
+	}
+	if n <= math.MaxUint16 {
+		return appendUint16BE(append(dst, prefix16), uint16(n))
+	}
+	return appendUint32BE(append(dst, prefix16+1), uint32(n))
+}
+
+func appendMsgpackString(dst []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		dst = append(dst, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		dst = append(dst, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		dst = appendUint16BE(append(dst, 0xda), uint16(n))
+	default:
+		dst = appendUint32BE(append(dst, 0xdb), uint32(n))
+	}
+	return append(dst, s...)
+}
+
+func appendMsgpackNumber(dst []byte, v *Value) ([]byte, error) {
+	switch v.NumberKind() {
+	case NumberInt:
+		n, err := v.Int64()
+		if err != nil {
+			return dst, err
+		}
+		if n >= 0 {
+			return appendMsgpackUint(dst, uint64(n)), nil
+		}
+		switch {
+		case n >= -32:
+			return append(dst, byte(n)), nil
+		case n >= math.MinInt8:
+			return append(dst, 0xd0, byte(n)), nil
+		case n >= math.MinInt16:
+			return appendUint16BE(append(dst, 0xd1), uint16(n)), nil
+		case n >= math.MinInt32:
+			return appendUint32BE(append(dst, 0xd2), uint32(n)), nil
+		default:
+			return appendUint64BE(append(dst, 0xd3), uint64(n)), nil
+		}
+	case NumberUint:
+		n, err := v.Uint64()
+		if err != nil {
+			return dst, err
+		}
+		return appendMsgpackUint(dst, n), nil
+	default:
+		f, err := v.Float64()
+		if err != nil {
+			return dst, fmt.Errorf("cannot encode number %q: %s", v.s, err)
+		}
+		return appendUint64BE(append(dst, 0xcb), math.Float64bits(f)), nil
+	}
+}
+
+func appendMsgpackUint(dst []byte, n uint64) []byte {
+	switch {
+	case n <= 0x7f:
+		return append(dst, byte(n))
+	case n <= math.MaxUint8:
+		return append(dst, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16BE(append(dst, 0xcd), uint16(n))
+	case n <= math.MaxUint32:
+		return appendUint32BE(append(dst, 0xce), uint32(n))
+	default:
+		return appendUint64BE(append(dst, 0xcf), n)
+	}
+}
+
+func appendUint16BE(dst []byte, n uint16) []byte {
+	return append(dst, byte(n>>8), byte(n))
+}
+
+func appendUint32BE(dst []byte, n uint32) []byte {
+	return append(dst, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func appendUint64BE(dst []byte, n uint64) []byte {
+	return append(dst, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32), byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
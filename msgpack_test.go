@@ -0,0 +1,255 @@
+package fastjson
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshalMsgpackTo(t *testing.T) {
+	f := func(s, resultExpected string) {
+		t.Helper()
+		v := MustParse(s)
+		result, err := v.MarshalMsgpackTo([]byte("foo"))
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %s", s, err)
+		}
+		if string(result[:3]) != "foo" {
+			t.Fatalf("unexpected prefix for %s; got %q; want %q", s, result[:3], "foo")
+		}
+		if h := hex.EncodeToString(result[3:]); h != resultExpected {
+			t.Fatalf("unexpected result for %s; got %s; want %s", s, h, resultExpected)
+		}
+	}
+
+	// Literals
+	f(`null`, "c0")
+	f(`true`, "c3")
+	f(`false`, "c2")
+
+	// Integers
+	f(`0`, "00")
+	f(`127`, "7f")
+	f(`128`, "cc80")
+	f(`255`, "ccff")
+	f(`256`, "cd0100")
+	f(`65536`, "ce00010000")
+	f(`4294967296`, "cf0000000100000000")
+	f(`18446744073709551615`, "cfffffffffffffffff")
+	f(`-1`, "ff")
+	f(`-32`, "e0")
+	f(`-33`, "d0df")
+	f(`-128`, "d080")
+	f(`-129`, "d1ff7f")
+	f(`-32769`, "d2ffff7fff")
+	f(`-2147483649`, "d3ffffffff7fffffff")
+	f(`-9223372036854775808`, "d38000000000000000")
+
+	// Floats
+	f(`1.5`, "cb3ff8000000000000")
+	f(`1e2`, "cb4059000000000000")
+	f(`18446744073709551616`, "cb43f0000000000000")
+	f(`-0.0`, "cb8000000000000000")
+
+	// Strings
+	f(`""`, "a0")
+	f(`"a\nb"`, "a3610a62")
+	f(`"`+strings.Repeat("x", 31)+`"`, "bf"+strings.Repeat("78", 31))
+	f(`"`+strings.Repeat("x", 32)+`"`, "d920"+strings.Repeat("78", 32))
+	f(`"`+strings.Repeat("x", 256)+`"`, "da0100"+strings.Repeat("78", 256))
+
+	// Arrays and objects
+	f(`[]`, "90")
+	f(`{}`, "80")
+	f(`[1,[true],{"a":null}]`, "9301"+"91c3"+"81a161c0")
+	f(`[`+strings.Repeat("1,", 15)+`1]`, "dc0010"+strings.Repeat("01", 16))
+	f(`{"x\u0079":1}`, "81a2787901")
+}
+
+func TestMarshalMsgpackToNaNInf(t *testing.T) {
+	var a Arena
+	f := func(s string, fExpected float64) {
+		t.Helper()
+		result, err := a.NewNumberString(s).MarshalMsgpackTo(nil)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %s", s, err)
+		}
+		if len(result) != 9 || result[0] != 0xcb {
+			t.Fatalf("unexpected result for %s: %x", s, result)
+		}
+		f := math.Float64frombits(binary.BigEndian.Uint64(result[1:]))
+		if math.IsNaN(fExpected) {
+			if !math.IsNaN(f) {
+				t.Fatalf("unexpected result for %s; got %v; want NaN", s, f)
+			}
+			return
+		}
+		if f != fExpected {
+			t.Fatalf("unexpected result for %s; got %v; want %v", s, f, fExpected)
+		}
+	}
+	f("NaN", math.NaN())
+	f("inf", math.Inf(1))
+	f("-Inf", math.Inf(-1))
+
+	// Invalid number
+	if _, err := a.NewNumberString("foobar").MarshalMsgpackTo(nil); err == nil {
+		t.Fatalf("expecting non-nil error for invalid number")
+	}
+}
+
+func TestMarshalMsgpackToFixtures(t *testing.T) {
+	f := func(name, s string) {
+		t.Helper()
+		v := MustParse(s)
+		result, err := v.MarshalMsgpackTo(nil)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %s", name, err)
+		}
+		got, tail, err := decodeMsgpack(result)
+		if err != nil {
+			t.Fatalf("cannot decode msgpack for %s: %s", name, err)
+		}
+		if len(tail) > 0 {
+			t.Fatalf("unexpected tail after decoding msgpack for %s: %x", name, tail)
+		}
+		var expected interface{}
+		if err := json.Unmarshal([]byte(s), &expected); err != nil {
+			t.Fatalf("cannot unmarshal %s via encoding/json: %s", name, err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("unexpected decoded value for %s", name)
+		}
+	}
+	f("small", smallFixture)
+	f("medium", mediumFixture)
+	f("large", largeFixture)
+	f("canada", canadaFixture)
+	f("citm", citmFixture)
+	f("twitter", twitterFixture)
+}
+
+// decodeMsgpack decodes msgpack value from b into the representation
+// used by encoding/json, so the results may be compared.
+//
+// It supports only the formats generated by MarshalMsgpackTo.
+func decodeMsgpack(b []byte) (interface{}, []byte, error) {
+	if len(b) == 0 {
+		return nil, b, fmt.Errorf("unexpected end of data")
+	}
+	c := b[0]
+	b = b[1:]
+	need := func(n int) error {
+		if len(b) < n {
+			return fmt.Errorf("unexpected end of data; need %d bytes; got %d bytes", n, len(b))
+		}
+		return nil
+	}
+	readUint := func(n int) (uint64, error) {
+		if err := need(n); err != nil {
+			return 0, err
+		}
+		var x uint64
+		for _, ch := range b[:n] {
+			x = x<<8 | uint64(ch)
+		}
+		b = b[n:]
+		return x, nil
+	}
+	decodeString := func(n uint64) (interface{}, []byte, error) {
+		if err := need(int(n)); err != nil {
+			return nil, b, err
+		}
+		return string(b[:n]), b[n:], nil
+	}
+	decodeArray := func(n uint64) (interface{}, []byte, error) {
+		a := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			v, tail, err := decodeMsgpack(b)
+			if err != nil {
+				return nil, tail, err
+			}
+			a = append(a, v)
+			b = tail
+		}
+		return a, b, nil
+	}
+	decodeMap := func(n uint64) (interface{}, []byte, error) {
+		m := make(map[string]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			k, tail, err := decodeMsgpack(b)
+			if err != nil {
+				return nil, tail, err
+			}
+			ks, ok := k.(string)
+			if !ok {
+				return nil, tail, fmt.Errorf("unexpected map key type %T", k)
+			}
+			v, tail, err := decodeMsgpack(tail)
+			if err != nil {
+				return nil, tail, err
+			}
+			m[ks] = v
+			b = tail
+		}
+		return m, b, nil
+	}
+
+	switch {
+	case c <= 0x7f:
+		return float64(c), b, nil
+	case c >= 0xe0:
+		return float64(int8(c)), b, nil
+	case c&0xe0 == 0xa0:
+		return decodeString(uint64(c & 0x1f))
+	case c&0xf0 == 0x90:
+		return decodeArray(uint64(c & 0x0f))
+	case c&0xf0 == 0x80:
+		return decodeMap(uint64(c & 0x0f))
+	}
+	switch c {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2:
+		return false, b, nil
+	case 0xc3:
+		return true, b, nil
+	case 0xcb:
+		x, err := readUint(8)
+		return math.Float64frombits(x), b, err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		x, err := readUint(1 << (c - 0xcc))
+		return float64(x), b, err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		n := 1 << (c - 0xd0)
+		x, err := readUint(n)
+		// Sign-extend x.
+		shift := uint(64 - 8*n)
+		return float64(int64(x<<shift) >> shift), b, err
+	case 0xd9, 0xda, 0xdb:
+		n, err := readUint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, b, err
+		}
+		return decodeString(n)
+	case 0xdc, 0xdd:
+		n, err := readUint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, b, err
+		}
+		return decodeArray(n)
+	case 0xde, 0xdf:
+		n, err := readUint(2 << (c - 0xde))
+		if err != nil {
+			return nil, b, err
+		}
+		return decodeMap(n)
+	default:
+		return nil, b, fmt.Errorf("unsupported msgpack format 0x%02x", c)
+	}
+}
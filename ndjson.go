@@ -0,0 +1,191 @@
+package fastjson
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LineScanner scans line-delimited JSON ( http://ndjson.org/ ).
+//
+// Every line is parsed independently, so a malformed line doesn't stop
+// scanning of the subsequent lines. Blank lines are skipped.
+//
+// LineScanner may be re-used for subsequent parsing.
+//
+// LineScanner cannot be used from concurrent goroutines.
+type LineScanner struct {
+	// b contains a working copy of the data passed to Init.
+	b []byte
+
+	// s points to the next line to parse.
+	s string
+
+	// line is 1-based line number for the current value.
+	line int
+
+	// nextLine is 1-based line number for s.
+	nextLine int
+
+	// err contains the error for the current line.
+	err error
+
+	// v contains the value for the current line.
+	v *Value
+
+	// c is used for caching JSON values.
+	c cache
+}
+
+// LineError is returned from LineScanner.Error for malformed lines.
+type LineError struct {
+	// Line is 1-based line number for the malformed line.
+	Line int
+
+	// Err is the parse error for the line.
+	Err error
+}
+
+// Error returns string representation for e.
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying parse error.
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Init initializes ls with the given s.
+//
+// s must contain JSON values delimited by newlines.
+// A single UTF-8 byte order mark at the beginning of s is skipped.
+func (ls *LineScanner) Init(s string) {
+	ls.b = append(ls.b[:0], skipBOM(s)...)
+	ls.s = b2s(ls.b)
+	ls.line = 0
+	ls.nextLine = 1
+	ls.err = nil
+	ls.v = nil
+}
+
+// InitBytes initializes ls with the given b.
+//
+// b must contain JSON values delimited by newlines.
+func (ls *LineScanner) InitBytes(b []byte) {
+	ls.Init(b2s(b))
+}
+
+// Next parses the next non-blank line from s passed to Init.
+//
+// Returns true if the line is found. The parsed value is available
+// via Value call. If the line cannot be parsed, then Value returns nil
+// and Error returns *LineError for the line.
+//
+// Returns false on the end of s.
+func (ls *LineScanner) Next() bool {
+	ls.err = nil
+	ls.v = nil
+	for len(ls.s) > 0 {
+		line := ls.s
+		n := strings.IndexByte(line, '\n')
+		if n >= 0 {
+			line = line[:n]
+			ls.s = ls.s[n+1:]
+		} else {
+			ls.s = ""
+		}
+		ls.line = ls.nextLine
+		ls.nextLine++
+
+		lineOrig := line
+		line = skipWS(line)
+		if len(line) == 0 {
+			// Skip blank line.
+			continue
+		}
+
+		ls.c.reset()
+		v, tail, err := parseValue(line, &ls.c, 0)
+		if err != nil {
+			err = newParseError(lineOrig, tail, fmt.Sprintf("cannot parse JSON: %s; unparsed tail: %q", err, startEndString(tail)))
+			ls.err = &LineError{
+				Line: ls.line,
+				Err:  err,
+			}
+			return true
+		}
+		tail = skipWS(tail)
+		if len(tail) > 0 {
+			err = newParseError(lineOrig, tail, fmt.Sprintf("unexpected tail: %q", startEndString(tail)))
+			ls.err = &LineError{
+				Line: ls.line,
+				Err:  err,
+			}
+			return true
+		}
+		ls.v = v
+		return true
+	}
+	return false
+}
+
+// Error returns the error for the current line.
+//
+// nil is returned if the current line has been successfully parsed.
+// The returned error is *LineError.
+func (ls *LineScanner) Error() error {
+	return ls.err
+}
+
+// Value returns the value for the current line.
+//
+// nil is returned if the current line cannot be parsed.
+//
+// The value is valid until the Next call.
+func (ls *LineScanner) Value() *Value {
+	return ls.v
+}
+
+// Line returns 1-based line number for the current line.
+func (ls *LineScanner) Line() int {
+	return ls.line
+}
+
+// AppendNDJSON appends values to dst in line-delimited JSON format
+// ( http://ndjson.org/ ) and returns the result.
+//
+// Every value is marshaled in compact form via MarshalTo and is followed
+// by a single '\n', so the marshaled values never contain newlines.
+// The result may be parsed with LineScanner or Scanner.
+func AppendNDJSON(dst []byte, values ...*Value) []byte {
+	for _, v := range values {
+		dst = v.MarshalTo(dst)
+		dst = append(dst, '\n')
+	}
+	return dst
+}
+
+// WriteNDJSON writes values to w in line-delimited JSON format
+// ( http://ndjson.org/ ).
+//
+// It returns the number of bytes written to w. See AppendNDJSON
+// for the format details.
+//
+// Unlike AppendNDJSON, WriteNDJSON doesn't build the whole output in memory.
+// The output is written to w in small chunks instead.
+func WriteNDJSON(w io.Writer, values ...*Value) (int64, error) {
+	vw := getValueWriter(w)
+	for _, v := range values {
+		vw.writeValue(v)
+		if vw.err != nil {
+			break
+		}
+		vw.buf = append(vw.buf, '\n')
+		vw.flushIfNeeded()
+	}
+	vw.flush()
+	n, err := vw.n, vw.err
+	putValueWriter(vw)
+	return n, err
+}
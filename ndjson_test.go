@@ -0,0 +1,175 @@
+package fastjson
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLineScanner(t *testing.T) {
+	var ls LineScanner
+
+	ls.Init(`{"a":1}
+{"a":2
+[1,2,3]
+
+  "foo"  ` + "\r" + `
+[1] [2]
+	
+null`)
+	var results []string
+	var errs []error
+	for ls.Next() {
+		if err := ls.Error(); err != nil {
+			if ls.Value() != nil {
+				t.Fatalf("expecting nil value on error")
+			}
+			errs = append(errs, err)
+			continue
+		}
+		results = append(results, fmt.Sprintf("%d:%s", ls.Line(), ls.Value()))
+	}
+
+	s := strings.Join(results, ",")
+	sExpected := `1:{"a":1},3:[1,2,3],5:"foo",8:null`
+	if s != sExpected {
+		t.Fatalf("unexpected values; got %q; want %q", s, sExpected)
+	}
+
+	if len(errs) != 2 {
+		t.Fatalf("unexpected number of errors; got %d; want %d", len(errs), 2)
+	}
+	for i, lineExpected := range []int{2, 6} {
+		le, ok := errs[i].(*LineError)
+		if !ok {
+			t.Fatalf("expecting *LineError; got %T", errs[i])
+		}
+		if le.Line != lineExpected {
+			t.Fatalf("unexpected line number for error #%d; got %d; want %d", i, le.Line, lineExpected)
+		}
+		if _, ok := le.Err.(*ParseError); !ok {
+			t.Fatalf("expecting *ParseError inside *LineError; got %T", le.Err)
+		}
+	}
+	if !strings.HasPrefix(errs[1].Error(), `line 6: unexpected tail: "[2]"`) {
+		t.Fatalf("unexpected error message: %s", errs[1])
+	}
+
+	// Re-use the scanner
+	ls.InitBytes([]byte("\n\n"))
+	if ls.Next() {
+		t.Fatalf("Next must return false for blank lines")
+	}
+	ls.InitBytes([]byte("1\n2"))
+	n := 0
+	for ls.Next() {
+		n += ls.Value().GetInt()
+	}
+	if n != 3 {
+		t.Fatalf("unexpected sum of values; got %d; want %d", n, 3)
+	}
+}
+
+func TestAppendNDJSON(t *testing.T) {
+	var values []*Value
+	for _, s := range []string{
+		`{"foo":"bar\nbaz","x":[1,2,{"y":null}]}`,
+		"[\n  1,\n  2\n]",
+		`"line1\nline2"`,
+		`123`,
+		`true`,
+		`{}`,
+	} {
+		values = append(values, MustParse(s))
+	}
+	values = append(values, MustParse(mediumFixture), MustParse(twitterFixture))
+
+	data := AppendNDJSON([]byte("prefix\n"), values...)
+	if !strings.HasPrefix(string(data), "prefix\n") {
+		t.Fatalf("dst prefix must be preserved; got %q", data[:10])
+	}
+	data = data[len("prefix\n"):]
+	if n := strings.Count(string(data), "\n"); n != len(values) {
+		t.Fatalf("unexpected number of lines; got %d; want %d", n, len(values))
+	}
+
+	checkValues := func(vs []*Value) {
+		t.Helper()
+		if len(vs) != len(values) {
+			t.Fatalf("unexpected number of values; got %d; want %d", len(vs), len(values))
+		}
+		for i, v := range vs {
+			if !v.Equal(values[i]) {
+				t.Fatalf("unexpected value #%d;\ngot\n%s\nwant\n%s", i, v, values[i])
+			}
+		}
+	}
+
+	// Round-trip via Scanner
+	var sc Scanner
+	sc.InitBytes(data)
+	var vs []*Value
+	for sc.Next() {
+		vs = append(vs, MustParse(sc.Value().String()))
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	checkValues(vs)
+
+	// Round-trip via LineScanner
+	var ls LineScanner
+	ls.InitBytes(data)
+	vs = vs[:0]
+	for ls.Next() {
+		if err := ls.Error(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		vs = append(vs, MustParse(ls.Value().String()))
+	}
+	checkValues(vs)
+
+	// WriteNDJSON must produce the same output as AppendNDJSON.
+	// Re-create the expected output, since Equal calls above unescape
+	// strings in values, so they are marshaled differently.
+	data = AppendNDJSON(nil, values...)
+	var bb bytes.Buffer
+	n, err := WriteNDJSON(&bb, values...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n != int64(len(data)) {
+		t.Fatalf("unexpected number of bytes written; got %d; want %d", n, len(data))
+	}
+	if bb.String() != string(data) {
+		t.Fatalf("unexpected data written\ngot\n%s\nwant\n%s", bb.String(), data)
+	}
+
+	// No values
+	if data := AppendNDJSON(nil); len(data) != 0 {
+		t.Fatalf("expecting empty result for no values; got %q", data)
+	}
+	if n, err := WriteNDJSON(&bb); n != 0 || err != nil {
+		t.Fatalf("unexpected result for no values; n=%d, err=%v", n, err)
+	}
+}
+
+func TestWriteNDJSONError(t *testing.T) {
+	v := MustParse(canadaFixture)
+	for _, limit := range []int{0, 1, 100, 5000, 100000} {
+		t.Run(fmt.Sprintf("limit_%d", limit), func(t *testing.T) {
+			w := &limitedWriter{limit: limit}
+			n, err := WriteNDJSON(w, v, v, v)
+			if err != errLimitReached {
+				t.Fatalf("unexpected error; got %v; want %v", err, errLimitReached)
+			}
+			if n != int64(limit) {
+				t.Fatalf("unexpected number of bytes written; got %d; want %d", n, limit)
+			}
+			if w.calls > 1 {
+				t.Fatalf("writing must stop after the first error; got %d calls after the error", w.calls-1)
+			}
+		})
+	}
+}
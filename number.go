@@ -0,0 +1,340 @@
+package fastjson
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fastjson/fastfloat"
+)
+
+// NumberKind represents the kind of JSON number.
+//
+// See Value.NumberKind for details.
+type NumberKind int
+
+const (
+	// NumberInvalid is returned for non-number values and for numbers,
+	// which cannot be parsed.
+	NumberInvalid NumberKind = 0
+
+	// NumberInt is an integer number fitting int64.
+	//
+	// Int64 succeeds for such numbers.
+	NumberInt NumberKind = 1
+
+	// NumberUint is an integer number fitting uint64, but not int64.
+	//
+	// Uint64 succeeds for such numbers.
+	NumberUint NumberKind = 2
+
+	// NumberFloat is a number, which doesn't fit int64 and uint64.
+	//
+	// Only Float64 succeeds for such numbers.
+	NumberFloat NumberKind = 3
+)
+
+// String returns string representation of nk.
+func (nk NumberKind) String() string {
+	switch nk {
+	case NumberInvalid:
+		return "invalid"
+	case NumberInt:
+		return "int"
+	case NumberUint:
+		return "uint"
+	case NumberFloat:
+		return "float"
+	default:
+		panic(fmt.Errorf("BUG: unknown NumberKind: %d", nk))
+	}
+}
+
+// NumberKind returns the kind of the number in v.
+//
+// The kind is determined by the original number text without parsing
+// the number, so numbers with a fractional part or an exponent
+// are always NumberFloat, even if they are integral. For example,
+// NumberKind returns NumberFloat for 1e5 and 1.0, since Int64 and Uint64
+// fail for such numbers.
+//
+// Integers, which don't fit int64 and uint64, are NumberFloat.
+//
+// NumberInvalid is returned if v doesn't contain a number.
+//
+// The result is cached in v, so subsequent calls are cheap.
+func (v *Value) NumberKind() NumberKind {
+	if v.t != TypeNumber {
+		return NumberInvalid
+	}
+	if NumberKind(v.nk) == NumberInvalid {
+		// Do not store NumberInvalid, so NumberKind never modifies
+		// normalized values. See Value.Normalize.
+		nk := getNumberKind(v.s)
+		if nk == NumberInvalid {
+			return nk
+		}
+		v.nk = uint8(nk)
+	}
+	return NumberKind(v.nk)
+}
+
+// IsInt returns true if v contains an integer number fitting int64 or uint64.
+//
+// See NumberKind for details.
+func (v *Value) IsInt() bool {
+	nk := v.NumberKind()
+	return nk == NumberInt || nk == NumberUint
+}
+
+// IsFloat returns true if v contains a number, which doesn't fit
+// int64 and uint64.
+//
+// See NumberKind for details.
+func (v *Value) IsFloat() bool {
+	return v.NumberKind() == NumberFloat
+}
+
+// IsNaN returns true if v contains NaN number.
+func (v *Value) IsNaN() bool {
+	return v.Type() == TypeNumber && math.IsNaN(fastfloat.ParseBestEffort(v.s))
+}
+
+// IsInf returns true if v contains positive or negative Inf number.
+//
+// Numbers exceeding float64 range such as 1e400 are treated as Inf.
+func (v *Value) IsInf() bool {
+	return v.Type() == TypeNumber && math.IsInf(fastfloat.ParseBestEffort(v.s), 0)
+}
+
+func getNumberKind(s string) NumberKind {
+	i := 0
+	minus := len(s) > 0 && s[0] == '-'
+	if minus {
+		i++
+	}
+	if i >= len(s) {
+		return NumberInvalid
+	}
+	d := uint64(0)
+	overflow := false
+	for ; i < len(s); i++ {
+		ch := s[i]
+		if ch < '0' || ch > '9' {
+			// The number isn't integer.
+			if _, err := fastfloat.Parse(s); err != nil {
+				return NumberInvalid
+			}
+			return NumberFloat
+		}
+		n := uint64(ch - '0')
+		if d > math.MaxUint64/10 || d == math.MaxUint64/10 && n > math.MaxUint64%10 {
+			overflow = true
+		}
+		d = d*10 + n
+	}
+	switch {
+	case overflow:
+		return NumberFloat
+	case minus && d > 1<<63:
+		return NumberFloat
+	case minus || d <= math.MaxInt64:
+		return NumberInt
+	default:
+		return NumberUint
+	}
+}
+
+// Decimal returns the underlying JSON number for the v as mantissa and scale,
+// so the number equals to mantissa * 10^-scale.
+//
+// Unlike Float64, Decimal doesn't lose precision. fastfloat.ErrDecimalOverflow
+// is returned if the number doesn't fit int64 mantissa. Use math/big.Rat
+// for parsing such numbers. See fastfloat.ParseDecimal for details.
+func (v *Value) Decimal() (int64, int32, error) {
+	if v.Type() != TypeNumber {
+		return 0, 0, fmt.Errorf("value doesn't contain number; it contains %s", v.Type())
+	}
+	return fastfloat.ParseDecimal(v.s)
+}
+
+// RawNumber returns the original text of the number in v.
+//
+// The text isn't normalized, so it may be passed to arbitrary-precision
+// libraries without precision loss. MarshalTo writes the same text.
+//
+// The returned string refers to the Parser memory, so it is valid
+// until Parse is called on the Parser returned v.
+func (v *Value) RawNumber() (string, error) {
+	if v.Type() != TypeNumber {
+		return "", fmt.Errorf("value doesn't contain number; it contains %s", v.Type())
+	}
+	return v.s, nil
+}
+
+// maxBigIntExponent is the maximum exponent accepted by Value.BigInt.
+//
+// It protects from excessive memory usage for numbers such as 1e1000000000.
+const maxBigIntExponent = 10000
+
+// BigInt returns the underlying JSON integer for the v without
+// precision loss.
+//
+// Numbers with a fractional part or an exponent such as 1.5e3 are accepted
+// only if they are integral. Exponents exceeding 10000 aren't supported.
+func (v *Value) BigInt() (*big.Int, error) {
+	if v.Type() != TypeNumber {
+		return nil, fmt.Errorf("value doesn't contain number; it contains %s", v.Type())
+	}
+	s := v.s
+	if strings.IndexAny(s, ".eE") < 0 {
+		bi, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("cannot parse integer number %q", s)
+		}
+		return bi, nil
+	}
+	if n := strings.IndexAny(s, "eE"); n >= 0 {
+		exp, err := strconv.Atoi(s[n+1:])
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse exponent in number %q: %s", s, err)
+		}
+		if exp < -maxBigIntExponent || exp > maxBigIntExponent {
+			return nil, fmt.Errorf("too big exponent in number %q; it must be in the range [%d..%d]", s, -maxBigIntExponent, maxBigIntExponent)
+		}
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("cannot parse number %q", s)
+	}
+	if !r.IsInt() {
+		return nil, fmt.Errorf("number %q isn't integer", s)
+	}
+	return new(big.Int).Set(r.Num()), nil
+}
+
+// BigFloat returns the underlying JSON number for the v as big.Float
+// with the given precision in bits.
+//
+// Zero prec means 64. The number is rounded to the nearest even
+// if it cannot be represented with the given precision.
+// Inf numbers are supported, while NaN numbers result in error.
+func (v *Value) BigFloat(prec uint) (*big.Float, error) {
+	if v.Type() != TypeNumber {
+		return nil, fmt.Errorf("value doesn't contain number; it contains %s", v.Type())
+	}
+	if prec == 0 {
+		prec = 64
+	}
+	s := strings.TrimLeft(v.s, "+-")
+	if len(s) > 0 && (s[0] == 'i' || s[0] == 'I') {
+		// big.ParseFloat doesn't support all the Inf forms accepted by Float64
+		// such as -INF, so handle Inf numbers here.
+		if !math.IsInf(fastfloat.ParseBestEffort(v.s), 0) {
+			return nil, fmt.Errorf("cannot parse number %q", v.s)
+		}
+		f := new(big.Float).SetPrec(prec)
+		return f.SetInf(v.s[0] == '-'), nil
+	}
+	f, _, err := big.ParseFloat(v.s, 10, prec, big.ToNearestEven)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse number %q: %s", v.s, err)
+	}
+	return f, nil
+}
+
+// numberCache is the kind of the parsed number cached in Value.
+type numberCache uint8
+
+const (
+	numberCacheNone    numberCache = 0
+	numberCacheFloat64 numberCache = 1
+	numberCacheInt64   numberCache = 2
+)
+
+// cachedFloat64 returns float64 for the number stored in v.
+//
+// v must contain a number.
+//
+// Only the first successfully parsed number is cached, so hot loops calling
+// the same accessor on the same values don't re-parse them. The cached int64
+// is used for float64 too, since it is converted to float64 with the same
+// rounding as fastfloat.Parse uses. Values already holding the cached number
+// aren't modified, so cachedFloat64 and cachedInt64 are safe to call
+// concurrently on normalized values. See Value.Normalize.
+func (v *Value) cachedFloat64() (float64, error) {
+	switch v.nc {
+	case numberCacheFloat64:
+		return math.Float64frombits(v.n), nil
+	case numberCacheInt64:
+		n := int64(v.n)
+		if n == 0 && v.s[0] == '-' {
+			// -0 is parsed as negative zero float64.
+			return math.Copysign(0, -1), nil
+		}
+		return float64(n), nil
+	}
+	f, err := fastfloat.Parse(v.s)
+	if err != nil {
+		return 0, err
+	}
+	if v.nc == numberCacheNone {
+		v.n = math.Float64bits(f)
+		v.nc = numberCacheFloat64
+	}
+	return f, nil
+}
+
+// cachedInt64 returns int64 for the number stored in v.
+//
+// v must contain a number. See cachedFloat64 for details.
+func (v *Value) cachedInt64() (int64, error) {
+	if v.nc == numberCacheInt64 {
+		return int64(v.n), nil
+	}
+	if err := checkIntNumberLen(v.s, true); err != nil {
+		return 0, err
+	}
+	n, err := fastfloat.ParseInt64(v.s)
+	if err != nil {
+		return 0, err
+	}
+	if v.nc == numberCacheNone {
+		v.n = uint64(n)
+		v.nc = numberCacheInt64
+	}
+	return n, nil
+}
+
+// maxIntNumberLen is the maximum length of int64 and uint64 numbers
+// without leading zeros.
+const maxIntNumberLen = len("18446744073709551615")
+
+// checkIntNumberLen returns an error if number s is too long for int64
+// if signed is set, or for uint64 otherwise.
+//
+// This allows failing fast on huge numbers without passing them to fastfloat,
+// since fastfloat falls back to strconv for long numbers, which copies
+// the whole number into the returned error on every call.
+func checkIntNumberLen(s string, signed bool) error {
+	if len(s) <= maxIntNumberLen {
+		return nil
+	}
+	digits := s
+	if signed && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	digits = strings.TrimLeft(digits, "0")
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return wrapErrorf(fastfloat.ErrSyntax, "cannot parse integer from number %q", startEndString(s))
+		}
+	}
+	if len(digits) <= maxIntNumberLen {
+		// The number has leading zeros, so it may fit 64 bits.
+		return nil
+	}
+	return wrapErrorf(fastfloat.ErrRange, "number %q doesn't fit 64-bit integer", startEndString(s))
+}
@@ -0,0 +1,497 @@
+package fastjson
+
+import (
+	"math"
+	"testing"
+
+	"github.com/valyala/fastjson/fastfloat"
+)
+
+func TestValueNumberKind(t *testing.T) {
+	f := func(s string, nkExpected NumberKind) {
+		t.Helper()
+
+		var p Parser
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		for i := 0; i < 2; i++ {
+			// The second call returns the cached result.
+			nk := v.NumberKind()
+			if nk != nkExpected {
+				t.Fatalf("unexpected NumberKind for %q; got %s; want %s", s, nk, nkExpected)
+			}
+		}
+		if v.IsInt() != (nkExpected == NumberInt || nkExpected == NumberUint) {
+			t.Fatalf("unexpected IsInt result for %q", s)
+		}
+		if v.IsFloat() != (nkExpected == NumberFloat) {
+			t.Fatalf("unexpected IsFloat result for %q", s)
+		}
+
+		// NumberKind must agree with Int64, Uint64 and Float64.
+		_, errInt64 := v.Int64()
+		_, errUint64 := v.Uint64()
+		_, errFloat64 := v.Float64()
+		switch nkExpected {
+		case NumberInt:
+			if errInt64 != nil {
+				t.Fatalf("unexpected Int64 error for %q: %s", s, errInt64)
+			}
+		case NumberUint:
+			if errInt64 == nil {
+				t.Fatalf("expecting non-nil Int64 error for %q", s)
+			}
+			if errUint64 != nil {
+				t.Fatalf("unexpected Uint64 error for %q: %s", s, errUint64)
+			}
+		case NumberFloat:
+			if errInt64 == nil || errUint64 == nil {
+				t.Fatalf("expecting non-nil Int64 and Uint64 errors for %q", s)
+			}
+			if errFloat64 != nil {
+				t.Fatalf("unexpected Float64 error for %q: %s", s, errFloat64)
+			}
+		case NumberInvalid:
+			if v.Type() == TypeNumber && errFloat64 == nil {
+				t.Fatalf("expecting non-nil Float64 error for %q", s)
+			}
+		}
+	}
+
+	// int
+	f("0", NumberInt)
+	f("-0", NumberInt)
+	f("123", NumberInt)
+	f("-123", NumberInt)
+	f("9223372036854775807", NumberInt)
+	f("-9223372036854775808", NumberInt)
+
+	// uint
+	f("9223372036854775808", NumberUint)
+	f("18446744073709551615", NumberUint)
+
+	// float
+	f("18446744073709551616", NumberFloat)
+	f("99999999999999999999", NumberFloat)
+	f("-9223372036854775809", NumberFloat)
+	f("1e5", NumberFloat)
+	f("1E5", NumberFloat)
+	f("1.0", NumberFloat)
+	f("-0.5e-3", NumberFloat)
+
+	// non-numbers
+	f(`"123"`, NumberInvalid)
+	f(`null`, NumberInvalid)
+	f(`[1]`, NumberInvalid)
+	f(`{}`, NumberInvalid)
+	f(`true`, NumberInvalid)
+}
+
+func TestValueNumberKindArena(t *testing.T) {
+	var a Arena
+	f := func(v *Value, nkExpected NumberKind) {
+		t.Helper()
+		if nk := v.NumberKind(); nk != nkExpected {
+			t.Fatalf("unexpected NumberKind for %s; got %s; want %s", v, nk, nkExpected)
+		}
+	}
+	f(a.NewNumberInt(-42), NumberInt)
+	f(a.NewNumberFloat64(1.5), NumberFloat)
+	f(a.NewNumberString("18446744073709551615"), NumberUint)
+	f(a.NewNumberString("foo"), NumberInvalid)
+	f(a.NewNumberString("-"), NumberInvalid)
+	f(a.NewNumberString(""), NumberInvalid)
+
+	// Cached NumberKind must be reset on Arena re-use.
+	a.Reset()
+	f(a.NewNumberString("1.5"), NumberFloat)
+}
+
+func TestValueNumberCache(t *testing.T) {
+	var p Parser
+	s := `[1.50, -0, 1e2, 12345678901234567890, 7, "x", -9]`
+	v, err := p.Parse(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	a := v.GetArray()
+
+	// Repeated calls must return the same results.
+	for i := 0; i < 3; i++ {
+		if f, err := a[0].Float64(); err != nil || f != 1.5 {
+			t.Fatalf("unexpected Float64 result; got %v, %v; want 1.5, nil", f, err)
+		}
+		if f, err := a[1].Float64(); err != nil || f != 0 || !math.Signbit(f) {
+			t.Fatalf("unexpected Float64 result for -0; got %v, %v", f, err)
+		}
+		if f := a[2].GetFloat64(); f != 100 {
+			t.Fatalf("unexpected GetFloat64 result; got %v; want 100", f)
+		}
+		if f := a[3].Float64BestEffort(); f != 12345678901234567890 {
+			t.Fatalf("unexpected Float64BestEffort result; got %v", f)
+		}
+		if n, err := a[4].Int64(); err != nil || n != 7 {
+			t.Fatalf("unexpected Int64 result; got %v, %v; want 7, nil", n, err)
+		}
+		if n := a[6].GetInt(); n != -9 {
+			t.Fatalf("unexpected GetInt result; got %v; want -9", n)
+		}
+	}
+
+	// Int64 must fail for non-integer numbers with the cached float64.
+	if _, err := a[0].Int64(); err == nil {
+		t.Fatalf("expecting non-nil error for Int64 on 1.50")
+	}
+	// The cached float64 mustn't be overwritten by Int64 and vice versa.
+	if n, err := a[2].Int64(); err == nil {
+		t.Fatalf("expecting non-nil error for Int64 on 1e2; got %d", n)
+	}
+	if f, err := a[4].Float64(); err != nil || f != 7 {
+		t.Fatalf("unexpected Float64 result for the cached int64; got %v, %v; want 7, nil", f, err)
+	}
+	if n, err := a[4].Int64(); err != nil || n != 7 {
+		t.Fatalf("unexpected Int64 result after Float64; got %v, %v; want 7, nil", n, err)
+	}
+	if _, err := a[5].Float64(); err == nil {
+		t.Fatalf("expecting non-nil error for string value")
+	}
+
+	// The original number representation must be preserved.
+	if got := v.String(); got != `[1.50,-0,1e2,12345678901234567890,7,"x",-9]` {
+		t.Fatalf("unexpected marshaled value: %s", got)
+	}
+
+	// The cache must be reset on Parser re-use.
+	v, err = p.Parse(`[2.25, 8]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if f := v.GetFloat64("0"); f != 2.25 {
+		t.Fatalf("unexpected number after Parser re-use; got %v; want 2.25", f)
+	}
+	if n := v.GetInt64("1"); n != 8 {
+		t.Fatalf("unexpected number after Parser re-use; got %v; want 8", n)
+	}
+
+	// The cache must be reset on Arena re-use.
+	var ar Arena
+	if f, err := ar.NewNumberFloat64(3.5).Float64(); err != nil || f != 3.5 {
+		t.Fatalf("unexpected Float64 result; got %v, %v; want 3.5, nil", f, err)
+	}
+	ar.Reset()
+	if f, err := ar.NewNumberInt(4).Float64(); err != nil || f != 4 {
+		t.Fatalf("unexpected Float64 result after Arena re-use; got %v, %v; want 4, nil", f, err)
+	}
+}
+
+func TestValueNumberCacheNormalizedInt(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		fExpected, err := fastfloat.Parse(s)
+		if err != nil {
+			t.Fatalf("unexpected error in fastfloat.Parse(%q): %s", s, err)
+		}
+		nExpected, err := fastfloat.ParseInt64(s)
+		if err != nil {
+			t.Fatalf("unexpected error in fastfloat.ParseInt64(%q): %s", s, err)
+		}
+
+		v := MustParse(s)
+		v.Normalize()
+		if v.nc != numberCacheInt64 {
+			t.Fatalf("expecting the cached int64 for %q after Normalize; got cache kind %d", s, v.nc)
+		}
+		n, err := v.Int64()
+		if err != nil || n != nExpected {
+			t.Fatalf("unexpected Int64 result for %q; got %d, %v; want %d, nil", s, n, err, nExpected)
+		}
+		// float64 obtained from the cached int64 must match fastfloat.Parse.
+		fv, err := v.Float64()
+		if err != nil || math.Float64bits(fv) != math.Float64bits(fExpected) {
+			t.Fatalf("unexpected Float64 result for %q; got %v, %v; want %v, nil", s, fv, err, fExpected)
+		}
+	}
+	f("0")
+	f("-0")
+	f("7")
+	f("-9")
+	f("9007199254740993")
+	f("-9007199254740995")
+	f("1234567890123456789")
+	f("9223372036854775807")
+	f("-9223372036854775808")
+}
+
+func TestValueNumberCacheConcurrent(t *testing.T) {
+	v := MustParse(`[1, 2.5, -3, 4e1]`)
+	v.Normalize()
+
+	// Accessors mustn't modify normalized values, so they may be called
+	// concurrently. Run the test with -race in order to verify this.
+	ch := make(chan float64, 4)
+	for i := 0; i < cap(ch); i++ {
+		go func() {
+			sum := 0.0
+			for _, vv := range v.GetArray() {
+				sum += vv.GetFloat64()
+				sum += float64(vv.GetInt64())
+			}
+			ch <- sum
+		}()
+	}
+	for i := 0; i < cap(ch); i++ {
+		if sum := <-ch; sum != 38.5 {
+			t.Fatalf("unexpected sum; got %v; want 38.5", sum)
+		}
+	}
+}
+
+func TestValueDecimal(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"price":0.10,"x":-12.3450e2,"y":8.54E-4,"big":79228162514264337593543950335,"s":"1.5"}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	f := func(key string, mantissaExpected int64, scaleExpected int32) {
+		t.Helper()
+		mantissa, scale, err := v.Get(key).Decimal()
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", key, err)
+		}
+		if mantissa != mantissaExpected || scale != scaleExpected {
+			t.Fatalf("unexpected result for %q; got mantissa=%d, scale=%d; want mantissa=%d, scale=%d",
+				key, mantissa, scale, mantissaExpected, scaleExpected)
+		}
+	}
+	f("price", 10, 2)
+	f("x", -123450, 2)
+	f("y", 854, 6)
+
+	if _, _, err := v.Get("big").Decimal(); err != fastfloat.ErrDecimalOverflow {
+		t.Fatalf("unexpected error; got %v; want %v", err, fastfloat.ErrDecimalOverflow)
+	}
+	if _, _, err := v.Get("s").Decimal(); err == nil {
+		t.Fatalf("expecting non-nil error for string value")
+	}
+}
+
+func TestValueBigInt(t *testing.T) {
+	digits100 := "1234567890123456789012345678901234567890123456789012345678901234567890123456789012345678901234567890"
+	f := func(s, expected string) {
+		t.Helper()
+		v, err := Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		bi, err := v.BigInt()
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		if result := bi.String(); result != expected {
+			t.Fatalf("unexpected BigInt for %q; got %s; want %s", s, result, expected)
+		}
+	}
+	f("0", "0")
+	f("-0", "0")
+	f("123", "123")
+	f("-9223372036854775809", "-9223372036854775809")
+	f("18446744073709551616", "18446744073709551616")
+	f(digits100, digits100)
+	f("-"+digits100, "-"+digits100)
+	f("1.5e3", "1500")
+	f("1e20", "100000000000000000000")
+	f("12300e-2", "123")
+	f("1.000", "1")
+	f("-2.0E+2", "-200")
+
+	ferr := func(s string) {
+		t.Helper()
+		v, err := Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		if bi, err := v.BigInt(); err == nil {
+			t.Fatalf("expecting non-nil error for %q; got %s", s, bi)
+		}
+	}
+	ferr("1.5")
+	ferr("1e-3")
+	ferr("1e100000")
+	ferr("1e-100000")
+	ferr("inf")
+	ferr("-Inf")
+	ferr("NaN")
+	ferr(`"123"`)
+	ferr("null")
+}
+
+func TestValueBigFloat(t *testing.T) {
+	f := func(s string, prec uint, expected string) {
+		t.Helper()
+		v, err := Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		bf, err := v.BigFloat(prec)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		if result := bf.Text('g', -1); result != expected {
+			t.Fatalf("unexpected BigFloat for %q; got %s; want %s", s, result, expected)
+		}
+		if precExpected := prec; precExpected > 0 && bf.Prec() != precExpected {
+			t.Fatalf("unexpected precision for %q; got %d; want %d", s, bf.Prec(), precExpected)
+		}
+	}
+	f("0", 0, "0")
+	f("1.5", 0, "1.5")
+	f("-12.5e2", 53, "-1250")
+	f("1e400", 0, "1e+400")
+	f("18446744073709551617", 64, "1.8446744073709551616e+19")
+	f("18446744073709551617", 128, "1.8446744073709551617e+19")
+	f("inf", 0, "+Inf")
+	f("-INF", 0, "-Inf")
+	f("+iNf", 0, "+Inf")
+
+	for _, s := range []string{"nan", "NaN", `"1"`, "[]"} {
+		v, err := Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		if _, err := v.BigFloat(0); err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+	}
+}
+
+func TestValueRawNumber(t *testing.T) {
+	// Numbers must be preserved exactly, including digits exceeding
+	// the float64 precision.
+	digits100 := "-1234567890123456789012345678901234567890123456789012345678901234567890123456789012345678901234567890"
+	s := `{"a":` + digits100 + `,"b":[1.50,1E+2,-0,0.000000000000000000000000000001]}`
+	v, err := Parse(s)
+	if err != nil {
+		t.Fatalf("cannot parse %q: %s", s, err)
+	}
+
+	// Access numbers via lossy methods before marshaling.
+	v.Get("a").Float64()
+	for _, vv := range v.GetArray("b") {
+		vv.Float64()
+		vv.NumberKind()
+	}
+	v.Normalize()
+
+	if result := v.String(); result != s {
+		t.Fatalf("unexpected marshaled JSON;\ngot\n%s\nwant\n%s", result, s)
+	}
+	ns, err := v.Get("a").RawNumber()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ns != digits100 {
+		t.Fatalf("unexpected raw number; got %s; want %s", ns, digits100)
+	}
+	ns, err = v.Get("b", "0").RawNumber()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ns != "1.50" {
+		t.Fatalf("unexpected raw number; got %s; want %s", ns, "1.50")
+	}
+	if _, err := v.Get("b").RawNumber(); err == nil {
+		t.Fatalf("expecting non-nil error for array")
+	}
+}
+
+func TestValueNaNInf(t *testing.T) {
+	f := func(s string, fExpected float64, isNaN, isInf bool) {
+		t.Helper()
+		v, err := Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", s, err)
+		}
+		if v.Type() != TypeNumber {
+			t.Fatalf("unexpected type for %s; got %s; want %s", s, v.Type(), TypeNumber)
+		}
+		if nk := v.NumberKind(); nk != NumberFloat {
+			t.Fatalf("unexpected NumberKind for %s; got %s; want %s", s, nk, NumberFloat)
+		}
+		equal := func(f float64) bool {
+			if math.IsNaN(fExpected) {
+				return math.IsNaN(f)
+			}
+			return f == fExpected
+		}
+
+		f, err := v.Float64()
+		if err != nil {
+			t.Fatalf("unexpected error in Float64 for %s: %s", s, err)
+		}
+		if !equal(f) {
+			t.Fatalf("unexpected Float64 for %s; got %v; want %v", s, f, fExpected)
+		}
+		if f := v.GetFloat64(); !equal(f) {
+			t.Fatalf("unexpected GetFloat64 for %s; got %v; want %v", s, f, fExpected)
+		}
+		if f := v.Float64BestEffort(); !equal(f) {
+			t.Fatalf("unexpected Float64BestEffort for %s; got %v; want %v", s, f, fExpected)
+		}
+		if f := GetFloat64([]byte(s)); !equal(f) {
+			t.Fatalf("unexpected GetFloat64 helper result for %s; got %v; want %v", s, f, fExpected)
+		}
+		if v.IsNaN() != isNaN {
+			t.Fatalf("unexpected IsNaN for %s; got %v; want %v", s, v.IsNaN(), isNaN)
+		}
+		if v.IsInf() != isInf {
+			t.Fatalf("unexpected IsInf for %s; got %v; want %v", s, v.IsInf(), isInf)
+		}
+
+		// Integer accessors must fail.
+		if _, err := v.Int64(); err == nil {
+			t.Fatalf("expecting non-nil error in Int64 for %s", s)
+		}
+		if _, err := v.Int(); err == nil {
+			t.Fatalf("expecting non-nil error in Int for %s", s)
+		}
+		if n := v.GetInt(); n != 0 {
+			t.Fatalf("unexpected GetInt for %s; got %d; want 0", s, n)
+		}
+
+		// The original number must be marshaled as is.
+		if result := v.String(); result != s {
+			t.Fatalf("unexpected marshaled value; got %s; want %s", result, s)
+		}
+	}
+	f("NaN", math.NaN(), true, false)
+	f("nan", math.NaN(), true, false)
+	f("-nan", math.NaN(), true, false)
+	f("inf", math.Inf(1), false, true)
+	f("+Inf", math.Inf(1), false, true)
+	f("-inf", math.Inf(-1), false, true)
+	f("1e400", math.Inf(1), false, true)
+	f("-1e400", math.Inf(-1), false, true)
+	f("1.5", 1.5, false, false)
+	f("1e-400", 0, false, false)
+
+	// Non-numbers
+	v := MustParse(`"NaN"`)
+	if v.IsNaN() || v.IsInf() || v.Float64BestEffort() != 0 {
+		t.Fatalf("unexpected results for string value")
+	}
+	if _, err := v.Float64(); err == nil {
+		t.Fatalf("expecting non-nil error in Float64 for string value")
+	}
+
+	// Invalid numbers
+	var a Arena
+	v = a.NewNumberString("foobar")
+	if v.IsNaN() || v.IsInf() || v.Float64BestEffort() != 0 {
+		t.Fatalf("unexpected results for invalid number")
+	}
+	if _, err := v.Float64(); err == nil {
+		t.Fatalf("expecting non-nil error in Float64 for invalid number")
+	}
+}
@@ -0,0 +1,165 @@
+package fastjson
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/valyala/fastjson/fastfloat"
+)
+
+func BenchmarkValueNumberKind(b *testing.B) {
+	s := `[1, -23, 4.5, 6e7, 18446744073709551615, 0.125, 1234567890, -3.14159]`
+	f := func(b *testing.B, isInt func(v *Value) bool) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(s)))
+		b.RunParallel(func(pb *testing.PB) {
+			var p Parser
+			var n uint64
+			for pb.Next() {
+				v, err := p.Parse(s)
+				if err != nil {
+					panic(fmt.Errorf("unexpected error: %s", err))
+				}
+				for _, vv := range v.GetArray() {
+					if isInt(vv) {
+						n++
+					}
+				}
+			}
+			atomic.AddUint64(&Sink, n)
+		})
+	}
+	b.Run("IsInt", func(b *testing.B) {
+		f(b, func(v *Value) bool {
+			return v.IsInt()
+		})
+	})
+	b.Run("Int64-error", func(b *testing.B) {
+		f(b, func(v *Value) bool {
+			if _, err := v.Int64(); err == nil {
+				return true
+			}
+			_, err := v.Uint64()
+			return err == nil
+		})
+	})
+}
+
+func BenchmarkValueFloat64RepeatedPasses(b *testing.B) {
+	const itemsCount = 1000000
+	var bb []byte
+	bb = append(bb, '[')
+	for i := 0; i < itemsCount; i++ {
+		if i > 0 {
+			bb = append(bb, ',')
+		}
+		bb = append(bb, fmt.Sprintf("%d.%d", i, i%1000)...)
+	}
+	bb = append(bb, ']')
+
+	var p Parser
+	v, err := p.ParseBytes(bb)
+	if err != nil {
+		b.Fatalf("unexpected error: %s", err)
+	}
+	a := v.GetArray()
+	sum := func(f func(v *Value) float64) float64 {
+		x := 0.0
+		for _, vv := range a {
+			x += f(vv)
+		}
+		return x
+	}
+
+	b.Run("fastfloat.Parse", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(bb)))
+		x := 0.0
+		for i := 0; i < b.N; i++ {
+			x += sum(func(v *Value) float64 {
+				// This is how Float64 parsed numbers before caching.
+				f, _ := fastfloat.Parse(v.s)
+				return f
+			})
+		}
+		atomic.AddUint64(&Sink, uint64(x))
+	})
+	b.Run("Float64", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(bb)))
+		x := 0.0
+		for i := 0; i < b.N; i++ {
+			x += sum(func(v *Value) float64 {
+				f, _ := v.Float64()
+				return f
+			})
+		}
+		atomic.AddUint64(&Sink, uint64(x))
+	})
+}
+
+func BenchmarkValueInt64RepeatedPasses(b *testing.B) {
+	const itemsCount = 1000000
+	var bb []byte
+	bb = append(bb, '[')
+	for i := 0; i < itemsCount; i++ {
+		if i > 0 {
+			bb = append(bb, ',')
+		}
+		bb = append(bb, fmt.Sprintf("%d", i*1000+i%1000)...)
+	}
+	bb = append(bb, ']')
+
+	var p Parser
+	v, err := p.ParseBytes(bb)
+	if err != nil {
+		b.Fatalf("unexpected error: %s", err)
+	}
+	v.Normalize()
+	a := v.GetArray()
+	sum := func(f func(v *Value) int64) int64 {
+		x := int64(0)
+		for _, vv := range a {
+			x += f(vv)
+		}
+		return x
+	}
+
+	b.Run("fastfloat.ParseInt64", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(bb)))
+		x := int64(0)
+		for i := 0; i < b.N; i++ {
+			x += sum(func(v *Value) int64 {
+				n, _ := fastfloat.ParseInt64(v.s)
+				return n
+			})
+		}
+		atomic.AddUint64(&Sink, uint64(x))
+	})
+	b.Run("Int64", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(bb)))
+		x := int64(0)
+		for i := 0; i < b.N; i++ {
+			x += sum(func(v *Value) int64 {
+				n, _ := v.Int64()
+				return n
+			})
+		}
+		atomic.AddUint64(&Sink, uint64(x))
+	})
+	b.Run("Float64", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(bb)))
+		x := int64(0)
+		for i := 0; i < b.N; i++ {
+			x += sum(func(v *Value) int64 {
+				f, _ := v.Float64()
+				return int64(f)
+			})
+		}
+		atomic.AddUint64(&Sink, uint64(x))
+	})
+}
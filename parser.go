@@ -1,11 +1,15 @@
 package fastjson
 
 import (
+	"errors"
 	"fmt"
 	"github.com/valyala/fastjson/fastfloat"
+	"math/bits"
 	"strconv"
 	"strings"
 	"unicode/utf16"
+	"unicode/utf8"
+	"unsafe"
 )
 
 // Parser parses JSON.
@@ -20,26 +24,162 @@ type Parser struct {
 
 	// c is a cache for json values.
 	c cache
+
+	// v is the value returned from the last successful Parse* call.
+	v *Value
+
+	// raw contains the original JSON passed to Parse* if KeepRaw is enabled.
+	//
+	// b cannot be used for this, since strings are unescaped in place.
+	raw []byte
+
+	// keepRaw is set via KeepRaw.
+	keepRaw bool
 }
 
 // Parse parses s containing JSON.
 //
+// A single UTF-8 byte order mark at the beginning of s is skipped.
+// UTF-16 encoded JSON isn't supported.
+//
 // The returned value is valid until the next call to Parse*.
+// The returned error is *ParseError.
 //
 // Use Scanner if a stream of JSON values must be parsed.
 func (p *Parser) Parse(s string) (*Value, error) {
-	s = skipWS(s)
+	sOrig := s
+	s = skipWS(skipBOM(s))
+	p.b = append(p.b[:0], s...)
+	p.resetCache(sOrig, 0)
+	return p.parse(sOrig, b2s(p.b))
+}
+
+// ParseOwning parses b containing JSON.
+//
+// Unlike ParseBytes, ParseOwning doesn't copy b. p takes ownership of b
+// instead and may modify it, so the caller mustn't access b after the call.
+// The previous internal buffer of p is dropped. Use SwapBuffer for obtaining
+// it before the call if it must be re-used.
+//
+// Raw returns nil for the values returned by ParseOwning,
+// since b may be modified.
+//
+// The returned value is valid until the next call to Parse* or SwapBuffer.
+// The returned error is *ParseError.
+func (p *Parser) ParseOwning(b []byte) (*Value, error) {
+	p.b = b
+	p.c.reset()
+	s := b2s(p.b)
+	return p.parse(s, skipWS(skipBOM(s)))
+}
+
+// SwapBuffer replaces the internal buffer of p with b[:0]
+// and returns the previous internal buffer.
+//
+// The returned buffer may contain the JSON passed to ParseOwning.
+// The ownership for the returned buffer is transferred to the caller,
+// while p takes ownership of b. This allows cycling buffers
+// between p and a buffer pool without memory allocations.
+//
+// Values returned by p become invalid after the call.
+func (p *Parser) SwapBuffer(b []byte) []byte {
+	bPrev := p.b
+	p.b = b[:0]
+	p.v = nil
+	return bPrev
+}
+
+// ForEachArrayElement parses s containing JSON array and calls f
+// for each array element.
+//
+// Unlike Parse, ForEachArrayElement doesn't build the whole array in memory.
+// Array elements are parsed one-by-one instead, so the memory usage
+// is proportional to the size of the largest array element.
+// This is useful for parsing big arrays with many elements.
+//
+// i is the index of the element in the array. f cannot hold v after returning,
+// since the memory occupied by v is re-used for the next element.
+//
+// The error returned from f stops the iteration and is returned
+// from ForEachArrayElement. Other returned errors are *ParseError.
+func (p *Parser) ForEachArrayElement(s string, f func(i int, v *Value) error) error {
+	sOrig := s
+	s = skipWS(skipBOM(s))
 	p.b = append(p.b[:0], s...)
+	p.resetCache(sOrig, 0)
+	p.v = nil
+
+	parseError := func(tail string, err error) error {
+		return newParseError(sOrig, tail, fmt.Sprintf("cannot parse JSON: %s; unparsed tail: %q", err, startEndString(tail)))
+	}
+
+	s = b2s(p.b)
+	if len(s) == 0 || s[0] != '[' {
+		return parseError(s, fmt.Errorf("missing '[' at the beginning of array"))
+	}
+	s = skipWS(s[1:])
+	if len(s) > 0 && s[0] == ']' {
+		s = s[1:]
+	} else {
+		for i := 0; ; i++ {
+			s = skipWS(s)
+			v, tail, err := parseValue(s, &p.c, 1)
+			if err != nil {
+				return parseError(tail, fmt.Errorf("cannot parse array: cannot parse array value: %s", err))
+			}
+			if err := f(i, v); err != nil {
+				return err
+			}
+			// Re-use the memory occupied by v for the next element.
+			// Do not call p.resetCache(), since it resets p.c.raw.
+			p.c.vs = p.c.vs[:0]
+
+			s = skipWS(tail)
+			if len(s) == 0 {
+				return parseError(s, fmt.Errorf("cannot parse array: unexpected end of array"))
+			}
+			if s[0] == ',' {
+				s = s[1:]
+				continue
+			}
+			if s[0] == ']' {
+				s = s[1:]
+				break
+			}
+			return parseError(s, fmt.Errorf("cannot parse array: missing ',' after array value"))
+		}
+	}
+	s = skipWS(s)
+	if len(s) > 0 {
+		return newParseError(sOrig, s, fmt.Sprintf("unexpected tail: %q", startEndString(s)))
+	}
+	return nil
+}
+
+// resetCache resets p.c before parsing sOrig located at the given offset
+// in the buffer passed to Parse*.
+func (p *Parser) resetCache(sOrig string, offset int) {
 	p.c.reset()
+	p.c.srcOffset = offset
+	p.c.srcLen = len(sOrig)
+	if p.keepRaw {
+		// Copy sOrig, since the caller may re-use it after Parse* returns.
+		p.raw = append(p.raw[:0], sOrig...)
+		p.c.raw = b2s(p.raw)
+	}
+}
 
-	v, tail, err := parseValue(b2s(p.b), &p.c, 0)
+func (p *Parser) parse(sOrig, s string) (*Value, error) {
+	p.v = nil
+	v, tail, err := parseValue(s, &p.c, 0)
 	if err != nil {
-		return nil, fmt.Errorf("cannot parse JSON: %s; unparsed tail: %q", err, startEndString(tail))
+		return nil, newParseError(sOrig, tail, fmt.Sprintf("cannot parse JSON: %s; unparsed tail: %q", err, startEndString(tail)))
 	}
 	tail = skipWS(tail)
 	if len(tail) > 0 {
-		return nil, fmt.Errorf("unexpected tail: %q", startEndString(tail))
+		return nil, newParseError(sOrig, tail, fmt.Sprintf("unexpected tail: %q", startEndString(tail)))
 	}
+	p.v = v
 	return v, nil
 }
 
@@ -52,24 +192,502 @@ func (p *Parser) ParseBytes(b []byte) (*Value, error) {
 	return p.Parse(b2s(b))
 }
 
+// ParseWithin parses JSON value located at b[start:end].
+//
+// Only b[start:end] is copied to p, so this is useful for parsing a small
+// part of a big buffer such as memory-mapped file. The value may be
+// surrounded by whitespace inside b[start:end].
+//
+// Offsets returned from ValueOffset, Object.KeyOffset and ParseError
+// are relative to b, so they may be correlated with the original buffer.
+//
+// The returned value is valid until the next call to Parse*.
+func (p *Parser) ParseWithin(b []byte, start, end int) (*Value, error) {
+	if start < 0 || end > len(b) || start > end {
+		p.v = nil
+		return nil, fmt.Errorf("invalid window [%d:%d) for the buffer with length %d", start, end, len(b))
+	}
+	sOrig := b2s(b[start:end])
+	s := skipWS(skipBOM(sOrig))
+	p.b = append(p.b[:0], s...)
+	p.resetCache(sOrig, start)
+	v, err := p.parse(sOrig, b2s(p.b))
+	if err != nil {
+		if pe, ok := err.(*ParseError); ok {
+			pe.shift(b2s(b[:start]))
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// ValueOffset returns the location of v in the original JSON passed
+// to Parse*.
+//
+// The original v is located at start:start+length. See ParseWithin for
+// obtaining the location in a bigger buffer.
+//
+// ok is false if the location of v is unknown, e.g. if KeepRaw isn't enabled,
+// if v hasn't been returned by the last Parse* call on p or if v is true,
+// false or null, since these values are shared among all the parsed JSONs.
+// See Raw for details.
+func (p *Parser) ValueOffset(v *Value) (int, int, bool) {
+	if v == nil {
+		return 0, 0, false
+	}
+	loc, ok := p.c.rawLocation(v)
+	if !ok {
+		return 0, 0, false
+	}
+	return p.c.srcOffset + loc.offset, loc.length, true
+}
+
+// Raw returns the original JSON for v as it was passed to p.Parse*.
+//
+// The original JSON is preserved exactly, including whitespace, number
+// formatting, escape sequences and key order. It doesn't reflect
+// modifications made to v via Set* and Del calls.
+//
+// The original JSON is available only if KeepRaw is enabled.
+// nil is returned for values, which haven't been returned by the last
+// Parse* call on p, such as values created via Arena, cloned values
+// and values returned by ParseOwning. The original JSON for true, false
+// and null values is always available.
+//
+// The returned bytes refer to the copy of the original JSON held by p,
+// so they are valid until the next Parse* call on p. The returned bytes
+// cannot be modified.
+func (p *Parser) Raw(v *Value) []byte {
+	if v == nil {
+		return nil
+	}
+	switch v.t {
+	case TypeTrue:
+		return s2b("true")
+	case TypeFalse:
+		return s2b("false")
+	case TypeNull:
+		return s2b("null")
+	}
+	return p.c.rawBytes(v)
+}
+
+// ExtractRaw returns the original JSON for the value at the given keys path
+// in the value returned from the last successful Parse* call on p.
+//
+// The original bytes are returned without re-serialization, so strings
+// keep their surrounding quotes and escape sequences, while numbers keep
+// their original text. This is faster than Get followed by MarshalTo
+// for forwarding sub-documents. The value is marshaled via MarshalTo
+// if its original JSON is unavailable, e.g. if KeepRaw is disabled
+// or after ParseOwning.
+// See Raw for details.
+//
+// The returned bytes are valid until the next Parse* call on p and
+// until the original JSON is modified. They cannot be modified.
+func (p *Parser) ExtractRaw(keys ...string) ([]byte, error) {
+	if p.v == nil {
+		return nil, fmt.Errorf("cannot extract value from Parser without successfully parsed JSON")
+	}
+	v := p.v.Get(keys...)
+	if v == nil {
+		return nil, fmt.Errorf("cannot find value at path %q", keys)
+	}
+	if raw := p.Raw(v); raw != nil {
+		return raw, nil
+	}
+	return v.MarshalTo(nil), nil
+}
+
+// MemoryFootprint returns the approximate number of bytes retained by p.
+//
+// It includes the capacities of the internal buffer, the value cache
+// and the buffers used by KeepRaw.
+//
+// The returned value may be used for metrics. It is also used by ParserPool
+// created via NewParserPool for dropping oversized parsers.
+func (p *Parser) MemoryFootprint() int {
+	return cap(p.b) + cap(p.raw) + p.c.memoryFootprint()
+}
+
+// BufferCap returns the capacity in bytes of the internal buffer,
+// which holds a copy of the parsed JSON.
+//
+// The returned value may be exported as a gauge metric.
+func (p *Parser) BufferCap() int {
+	return cap(p.b)
+}
+
+// ValueCacheCap returns the number of Values p may hold
+// without additional memory allocations.
+//
+// The returned value may be exported as a gauge metric.
+func (p *Parser) ValueCacheCap() int {
+	return cap(p.c.vs)
+}
+
+// InternKeys enables or disables interning of object keys in p.
+//
+// Interned keys are shared among all the objects parsed by p, including
+// objects from subsequent Parse* calls. This reduces memory usage when
+// copying values with identical keys via Value.Clone.
+//
+// Key interning is disabled by default.
+func (p *Parser) InternKeys(intern bool) {
+	if !intern {
+		p.c.keys = nil
+		return
+	}
+	if p.c.keys == nil {
+		p.c.keys = make(map[string]string)
+	}
+}
+
+// DuplicateKeyMode determines how duplicate object keys are resolved
+// during parsing. See Parser.DuplicateKeyMode.
+type DuplicateKeyMode int
+
+const (
+	// DupFirst keeps all the entries with duplicate keys in the parsed
+	// objects, so Object.Get returns the first value, while Object.Visit
+	// and MarshalTo see all the entries.
+	//
+	// This is the default mode.
+	DupFirst DuplicateKeyMode = 0
+
+	// DupLast keeps only the first entry for duplicate keys, while its value
+	// is replaced by the last value for the key. This matches JSON.parse
+	// in JavaScript, while the entry keeps the position of the first key.
+	DupLast DuplicateKeyMode = 1
+
+	// DupError makes Parse* return an error for objects with duplicate keys.
+	DupError DuplicateKeyMode = 2
+)
+
+// DuplicateKeyMode sets the mode for resolving duplicate object keys
+// in the subsequently parsed JSONs.
+//
+// Keys are compared after unescaping in DupLast and DupError modes,
+// so {"a":1,"\u0061":2} contains duplicate keys. This requires unescaping
+// all the object keys during parsing, which slows down parsing of objects
+// a bit. Objects with many keys additionally require a memory allocation
+// for the index of keys.
+//
+// The mode is DupFirst by default. It is preserved across Parse* calls.
+func (p *Parser) DuplicateKeyMode(mode DuplicateKeyMode) {
+	p.c.dupKeyMode = mode
+}
+
+// MaxStringLen limits the length of string values in the subsequently
+// parsed JSONs to maxLen bytes.
+//
+// The length is measured in bytes between the quotes before unescaping.
+// Parse* returns an error pointing to the beginning of the too long string,
+// so untrusted input with huge strings is rejected before unescaping them.
+// Note that Parse, ParseBytes and ParseWithin still copy the whole input
+// into the internal buffer. Use ValidateWithLimits for rejecting the input
+// without copying it.
+//
+// Zero or negative maxLen means no limit, which is the default.
+// The limit is preserved across Parse* calls.
+func (p *Parser) MaxStringLen(maxLen int) {
+	p.c.maxStringLen = maxLen
+}
+
+// MaxKeyLen limits the length of object keys in the subsequently parsed
+// JSONs to maxLen bytes.
+//
+// See MaxStringLen for details.
+func (p *Parser) MaxKeyLen(maxLen int) {
+	p.c.maxKeyLen = maxLen
+}
+
+// MaxNumberLen limits the length of numbers in the subsequently parsed
+// JSONs to maxLen bytes.
+//
+// Huge numbers are valid JSON, but they are expensive to process, since
+// number accessors such as Float64 scan the whole number. Parse* returns
+// an error pointing to the beginning of the too long number.
+// Use ValidateWithOptions for rejecting the input without copying it.
+//
+// Zero or negative maxLen means no limit, which is the default.
+// The limit is preserved across Parse* calls.
+func (p *Parser) MaxNumberLen(maxLen int) {
+	p.c.maxNumberLen = maxLen
+}
+
+// KeepRaw enables or disables keeping the original JSON for the values
+// parsed by the subsequent Parse* calls. See Raw and ValueOffset.
+//
+// Parse* makes an additional copy of the JSON and records the location
+// of every parsed value when KeepRaw is enabled, so parsing becomes slower.
+//
+// KeepRaw is disabled by default. The setting is preserved across
+// Parse* calls.
+func (p *Parser) KeepRaw(keep bool) {
+	p.keepRaw = keep
+	if !keep {
+		p.raw = nil
+	}
+}
+
+// Clone returns new Parser with the internal buffer and the value cache
+// pre-allocated to the same capacities as in p.
+//
+// The contents of p aren't copied and the returned parser doesn't share
+// memory with p, so it may be used from another goroutine. This allows
+// starting new workers with warm parsers. Key interning and KeepRaw settings
+// are copied to the returned parser, while interned keys aren't copied.
+func (p *Parser) Clone() *Parser {
+	var pc Parser
+	pc.Preallocate(cap(p.b), cap(p.c.vs))
+	if p.c.keys != nil {
+		pc.c.keys = make(map[string]string, len(p.c.keys))
+	}
+	pc.c.dupKeyMode = p.c.dupKeyMode
+	pc.c.maxStringLen = p.c.maxStringLen
+	pc.c.maxKeyLen = p.c.maxKeyLen
+	pc.c.maxNumberLen = p.c.maxNumberLen
+	pc.keepRaw = p.keepRaw
+	pc.c.cc.checkInterval = p.c.cc.checkInterval
+	return &pc
+}
+
+// Preallocate pre-allocates the internal buffer of p for parsing JSON
+// with up to inputBytes length and the value cache for up to values values.
+//
+// This reduces memory allocations during the first Parse* calls.
+// Already allocated memory isn't shrunk.
+//
+// Values returned by p become invalid after the call.
+func (p *Parser) Preallocate(inputBytes, values int) {
+	p.v = nil
+	p.c.reset()
+	if cap(p.b) < inputBytes {
+		p.b = make([]byte, 0, inputBytes)
+	} else {
+		p.b = p.b[:0]
+	}
+	if cap(p.c.vs) < values {
+		p.c.vs = make([]Value, 0, values)
+	}
+}
+
 type cache struct {
 	vs []Value
+
+	// vsPrev contains the previous backing arrays for vs, which have been
+	// replaced since the last reset because of vs growth.
+	//
+	// The values allocated from these arrays are still in use,
+	// so vsPrev is used for locating them. See cache.valueIndex.
+	// vsPrev is filled only if raw isn't empty.
+	vsPrev [][]Value
+
+	// locs contains the locations of the original JSON for vs items
+	// with the same indexes. It is filled only if raw isn't empty.
+	locs []rawLocation
+
+	// keys contains interned object keys if key interning is enabled.
+	keys map[string]string
+
+	// raw contains the original JSON passed to Parser.Parse*
+	// if Parser.KeepRaw is enabled.
+	//
+	// It is used for obtaining original JSON for the parsed values.
+	raw string
+
+	// srcOffset is the offset of the original JSON in the buffer
+	// passed to Parser.ParseWithin.
+	srcOffset int
+
+	// srcLen is the length of the original JSON passed to Parser.Parse*.
+	//
+	// It is used for obtaining offsets of object keys.
+	srcLen int
+
+	// dupKeyMode is set via Parser.DuplicateKeyMode.
+	dupKeyMode DuplicateKeyMode
+
+	// maxStringLen is set via Parser.MaxStringLen.
+	maxStringLen int
+
+	// maxKeyLen is set via Parser.MaxKeyLen.
+	maxKeyLen int
+
+	// maxNumberLen is set via Parser.MaxNumberLen.
+	maxNumberLen int
+
+	// cc checks ctx passed to Parser.ParseCtx.
+	cc contextChecker
+}
+
+// rawLocation is the location of the original JSON for the parsed value.
+type rawLocation struct {
+	offset int
+
+	// length is zero if the location is unknown.
+	length int
+}
+
+// setRaw stores the location of the original JSON for v located
+// at the given s and tail of the parsed JSON.
+func (c *cache) setRaw(v *Value, s, tail string) {
+	if len(c.raw) == 0 {
+		return
+	}
+	i, ok := c.valueIndex(v)
+	if !ok {
+		return
+	}
+	c.locs[i] = rawLocation{
+		offset: len(c.raw) - len(s),
+		length: len(s) - len(tail),
+	}
+}
+
+// rawBytes returns the original JSON for v.
+//
+// nil is returned if v hasn't been allocated by c since the last reset
+// or if the original JSON for v is unknown.
+func (c *cache) rawBytes(v *Value) []byte {
+	loc, ok := c.rawLocation(v)
+	if !ok {
+		return nil
+	}
+	return s2b(c.raw[loc.offset : loc.offset+loc.length])
+}
+
+// rawLocation returns the location of the original JSON for v.
+func (c *cache) rawLocation(v *Value) (rawLocation, bool) {
+	if len(c.raw) == 0 {
+		return rawLocation{}, false
+	}
+	i, ok := c.valueIndex(v)
+	if !ok {
+		return rawLocation{}, false
+	}
+	loc := c.locs[i]
+	return loc, loc.length > 0
+}
+
+// valueIndex returns the index of v in the values allocated by c
+// since the last reset.
+//
+// false is returned if v hasn't been allocated by c.
+func (c *cache) valueIndex(v *Value) (int, bool) {
+	if i, ok := indexOfValue(c.vs, v); ok {
+		return i, true
+	}
+	for _, vs := range c.vsPrev {
+		if i, ok := indexOfValue(vs, v); ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// indexOfValue returns the index of the vs item v points to.
+func indexOfValue(vs []Value, v *Value) (int, bool) {
+	if len(vs) == 0 {
+		return 0, false
+	}
+	start := uintptr(unsafe.Pointer(&vs[0]))
+	ptr := uintptr(unsafe.Pointer(v))
+	size := unsafe.Sizeof(Value{})
+	if ptr < start || ptr >= start+uintptr(len(vs))*size {
+		return 0, false
+	}
+	return int((ptr - start) / size), true
+}
+
+const (
+	// maxInternedKeys is the maximum number of interned keys per cache.
+	maxInternedKeys = 1024
+
+	// maxInternedKeyLen is the maximum length of the interned key.
+	maxInternedKeyLen = 64
+)
+
+// internKey returns interned k.
+//
+// Interned keys don't refer to the parsed JSON, so they remain valid
+// after the cache reset.
+//
+// Returns k and false if k cannot be interned.
+func (c *cache) internKey(k string) (string, bool) {
+	if ik, ok := c.keys[k]; ok {
+		return ik, true
+	}
+	// Keys with escape sequences cannot be interned, since they are unescaped in place.
+	if len(c.keys) >= maxInternedKeys || len(k) > maxInternedKeyLen || strings.IndexByte(k, '\\') >= 0 {
+		return k, false
+	}
+	// Convert via []byte in order to make a copy of k.
+	ik := string(s2b(k))
+	c.keys[ik] = ik
+	return ik, true
+}
+
+func (c *cache) memoryFootprint() int {
+	return cap(c.vs)*int(unsafe.Sizeof(Value{})) + cap(c.locs)*int(unsafe.Sizeof(rawLocation{}))
 }
 
 func (c *cache) reset() {
 	c.vs = c.vs[:0]
+	for i := range c.vsPrev {
+		c.vsPrev[i] = nil
+	}
+	c.vsPrev = c.vsPrev[:0]
+	c.locs = c.locs[:0]
+	c.raw = ""
+	c.srcOffset = 0
+	c.srcLen = 0
 }
 
 func (c *cache) getValue() *Value {
 	if cap(c.vs) > len(c.vs) {
 		c.vs = c.vs[:len(c.vs)+1]
 	} else {
+		if len(c.raw) > 0 {
+			c.vsPrev = append(c.vsPrev, c.vs)
+		}
 		c.vs = append(c.vs, Value{})
 	}
+	if len(c.raw) > 0 {
+		// Keep locs in sync with vs, which may be truncated by Parser.ForEachArrayElement.
+		c.locs = append(c.locs[:len(c.vs)-1], rawLocation{})
+	}
 	// Do not reset the value, since the caller must properly init it.
-	return &c.vs[len(c.vs)-1]
+	// Reset only nk, nc, escErr and userTag, since the majority of callers don't set them.
+	v := &c.vs[len(c.vs)-1]
+	v.nk = uint8(NumberInvalid)
+	v.nc = numberCacheNone
+	v.escErr = escapeErrorNone
+	v.userTag = 0
+	return v
+}
+
+// bomUTF8 is UTF-8 encoded byte order mark.
+const bomUTF8 = "\xef\xbb\xbf"
+
+// skipBOM skips UTF-8 byte order mark at the beginning of s.
+//
+// Windows tools frequently put it at the beginning of JSON files.
+func skipBOM(s string) string {
+	if len(s) >= len(bomUTF8) && s[:len(bomUTF8)] == bomUTF8 {
+		return s[len(bomUTF8):]
+	}
+	return s
+}
+
+// hasUTF16BOM returns true if s starts with UTF-16 byte order mark.
+func hasUTF16BOM(s string) bool {
+	return len(s) >= 2 && (s[0] == 0xfe && s[1] == 0xff || s[0] == 0xff && s[1] == 0xfe)
 }
 
+var errUTF16 = errors.New("UTF-16 encoded JSON is not supported; convert it to UTF-8")
+
 func skipWS(s string) string {
 	if len(s) == 0 || s[0] > 0x20 {
 		// Fast path.
@@ -82,7 +700,23 @@ func skipWSSlow(s string) string {
 	if len(s) == 0 || s[0] != 0x20 && s[0] != 0x0A && s[0] != 0x09 && s[0] != 0x0D {
 		return s
 	}
-	for i := 1; i < len(s); i++ {
+	// Short whitespace runs are the most common, so check the first bytes
+	// one by one before switching to checking 8 bytes at once.
+	i := 1
+	for ; i < len(s) && i < 8; i++ {
+		if s[i] != 0x20 && s[i] != 0x0A && s[i] != 0x09 && s[i] != 0x0D {
+			return s[i:]
+		}
+	}
+	for i+8 <= len(s) {
+		m := wsMask(loadUint64(s[i:]))
+		if m != swarHighBits {
+			// Non-whitespace byte found.
+			return s[i+bits.TrailingZeros64(^m&swarHighBits)/8:]
+		}
+		i += 8
+	}
+	for ; i < len(s); i++ {
 		if s[i] != 0x20 && s[i] != 0x0A && s[i] != 0x09 && s[i] != 0x0D {
 			return s[i:]
 		}
@@ -93,6 +727,41 @@ func skipWSSlow(s string) string {
 type kv struct {
 	k string
 	v *Value
+
+	// ko contains the offset of the key in the original JSON in the upper
+	// 48 bits and the length of the key including quotes in the lower 16 bits.
+	// See setKeyOffset.
+	//
+	// ko is zero if the key offset is unknown. This grows kv by 8 bytes.
+	ko uint64
+}
+
+// maxKeyOffsetLen is the maximum length of the key including quotes,
+// which offset may be stored in kv.
+const maxKeyOffsetLen = 1<<16 - 1
+
+// maxKeyOffset is the maximum key offset, which may be stored in kv.
+const maxKeyOffset = 1<<48 - 1
+
+// setKeyOffset stores the offset and the length of the key in the original JSON.
+//
+// The offset isn't stored if it doesn't fit kv.ko.
+func (kv *kv) setKeyOffset(offset, length int) {
+	if length > maxKeyOffsetLen || uint64(offset) > maxKeyOffset {
+		kv.ko = 0
+		return
+	}
+	kv.ko = uint64(offset)<<16 | uint64(length)
+}
+
+// keyOffset returns the offset and the length of the key in the original JSON.
+//
+// ok is false if the offset is unknown.
+func (kv *kv) keyOffset() (int, int, bool) {
+	if kv.ko == 0 {
+		return 0, 0, false
+	}
+	return int(kv.ko >> 16), int(kv.ko & maxKeyOffsetLen), true
 }
 
 // MaxDepth is the maximum depth for nested JSON.
@@ -106,12 +775,18 @@ func parseValue(s string, c *cache, depth int) (*Value, string, error) {
 	if depth > MaxDepth {
 		return nil, s, fmt.Errorf("too big depth for the nested JSON; it exceeds %d", MaxDepth)
 	}
+	if c.cc.ctx != nil {
+		if err := c.cc.check(); err != nil {
+			return nil, s, err
+		}
+	}
 
 	if s[0] == '{' {
 		v, tail, err := parseObject(s[1:], c, depth)
 		if err != nil {
 			return nil, tail, fmt.Errorf("cannot parse object: %s", err)
 		}
+		c.setRaw(v, s, tail)
 		return v, tail, nil
 	}
 	if s[0] == '[' {
@@ -119,27 +794,33 @@ func parseValue(s string, c *cache, depth int) (*Value, string, error) {
 		if err != nil {
 			return nil, tail, fmt.Errorf("cannot parse array: %s", err)
 		}
+		c.setRaw(v, s, tail)
 		return v, tail, nil
 	}
 	if s[0] == '"' {
 		ss, tail, err := parseRawString(s[1:])
 		if err != nil {
-			return nil, tail, fmt.Errorf("cannot parse string: %s", err)
+			// Point to the beginning of the invalid string.
+			return nil, s, fmt.Errorf("cannot parse string: %s", err)
+		}
+		if c.maxStringLen > 0 && len(ss) > c.maxStringLen {
+			return nil, s, fmt.Errorf("too long string with %d bytes; it exceeds the limit of %d bytes", len(ss), c.maxStringLen)
 		}
 		v := c.getValue()
 		v.t = typeRawString
 		v.s = ss
+		c.setRaw(v, s, tail)
 		return v, tail, nil
 	}
 	if s[0] == 't' {
 		if len(s) < len("true") || s[:len("true")] != "true" {
-			return nil, s, fmt.Errorf("unexpected value found: %q", s)
+			return nil, s, fmt.Errorf("unexpected value found: %q", startEndString(s))
 		}
 		return valueTrue, s[len("true"):], nil
 	}
 	if s[0] == 'f' {
 		if len(s) < len("false") || s[:len("false")] != "false" {
-			return nil, s, fmt.Errorf("unexpected value found: %q", s)
+			return nil, s, fmt.Errorf("unexpected value found: %q", startEndString(s))
 		}
 		return valueFalse, s[len("false"):], nil
 	}
@@ -150,20 +831,28 @@ func parseValue(s string, c *cache, depth int) (*Value, string, error) {
 				v := c.getValue()
 				v.t = TypeNumber
 				v.s = s[:3]
+				c.setRaw(v, s, s[3:])
 				return v, s[3:], nil
 			}
-			return nil, s, fmt.Errorf("unexpected value found: %q", s)
+			return nil, s, fmt.Errorf("unexpected value found: %q", startEndString(s))
 		}
 		return valueNull, s[len("null"):], nil
 	}
 
 	ns, tail, err := parseRawNumber(s)
 	if err != nil {
+		if depth == 1 && hasUTF16BOM(s) {
+			return nil, s, errUTF16
+		}
 		return nil, tail, fmt.Errorf("cannot parse number: %s", err)
 	}
+	if c.maxNumberLen > 0 && len(ns) > c.maxNumberLen {
+		return nil, s, fmt.Errorf("too long number with %d bytes; it exceeds the limit of %d bytes", len(ns), c.maxNumberLen)
+	}
 	v := c.getValue()
 	v.t = TypeNumber
 	v.s = ns
+	c.setRaw(v, s, tail)
 	return v, tail, nil
 }
 
@@ -226,8 +915,10 @@ func parseObject(s string, c *cache, depth int) (*Value, string, error) {
 	o := c.getValue()
 	o.t = TypeObject
 	o.o.reset()
+	o.o.keysInterned = c.keys != nil
+	dupKeyMode := c.dupKeyMode
+	var keysIndex map[string]int
 	for {
-		var err error
 		kv := o.o.getKV()
 
 		// Parse key.
@@ -235,10 +926,42 @@ func parseObject(s string, c *cache, depth int) (*Value, string, error) {
 		if len(s) == 0 || s[0] != '"' {
 			return nil, s, fmt.Errorf(`cannot find opening '"" for object key`)
 		}
-		kv.k, s, err = parseRawKey(s[1:])
+		k, tail, err := parseRawKey(s[1:])
 		if err != nil {
+			// Point to the beginning of the invalid key.
 			return nil, s, fmt.Errorf("cannot parse object key: %s", err)
 		}
+		if c.maxKeyLen > 0 && len(k) > c.maxKeyLen {
+			return nil, s, fmt.Errorf("too long object key with %d bytes; it exceeds the limit of %d bytes", len(k), c.maxKeyLen)
+		}
+		kv.k = k
+		if c.srcLen > 0 {
+			kv.setKeyOffset(c.srcOffset+c.srcLen-len(s), len(s)-len(tail))
+		}
+		if dupKeyMode != DupFirst {
+			// Duplicate keys must be compared by their unescaped form.
+			var ee escapeError
+			kv.k, ee = unescapeStringCheck(k)
+			if o.o.keysEscapeError == escapeErrorNone {
+				o.o.keysEscapeError = ee
+			}
+		}
+		if c.keys != nil {
+			var ok bool
+			kv.k, ok = c.internKey(kv.k)
+			if !ok {
+				o.o.keysInterned = false
+			}
+		}
+		dupIdx := -1
+		if dupKeyMode != DupFirst {
+			dupIdx, keysIndex = findDuplicateKey(o.o.kvs, keysIndex)
+			if dupIdx >= 0 && dupKeyMode == DupError {
+				// Point to the beginning of the duplicate key.
+				return nil, s, fmt.Errorf("duplicate object key %q", kv.k)
+			}
+		}
+		s = tail
 		s = skipWS(s)
 		if len(s) == 0 || s[0] != ':' {
 			return nil, s, fmt.Errorf("missing ':' after object key")
@@ -251,6 +974,12 @@ func parseObject(s string, c *cache, depth int) (*Value, string, error) {
 		if err != nil {
 			return nil, s, fmt.Errorf("cannot parse object value: %s", err)
 		}
+		if dupIdx >= 0 {
+			// DupLast mode - replace the value for the first key
+			// and drop the duplicate entry.
+			o.o.kvs[dupIdx].v = kv.v
+			o.o.kvs = o.o.kvs[:len(o.o.kvs)-1]
+		}
 		s = skipWS(s)
 		if len(s) == 0 {
 			return nil, s, fmt.Errorf("unexpected end of object")
@@ -260,14 +989,21 @@ func parseObject(s string, c *cache, depth int) (*Value, string, error) {
 			continue
 		}
 		if s[0] == '}' {
+			if dupKeyMode != DupFirst {
+				o.o.keysUnescaped = true
+			}
 			return o, s[1:], nil
 		}
 		return nil, s, fmt.Errorf("missing ',' after object value")
 	}
 }
 
+// escapeString appends JSON-quoted s to dst.
+//
+// Invalid UTF-8 sequences in s, including UTF-16 surrogates, are replaced
+// by \ufffd, so the result is always valid JSON.
 func escapeString(dst []byte, s string) []byte {
-	if !hasSpecialChars(s) {
+	if !needsEscaping(s) {
 		// Fast path - nothing to escape.
 		dst = append(dst, '"')
 		dst = append(dst, s...)
@@ -276,15 +1012,56 @@ func escapeString(dst []byte, s string) []byte {
 	}
 
 	// Slow path.
-	return strconv.AppendQuote(dst, s)
+	return escapeStringOpts(dst, s, &MarshalOptions{})
+}
+
+// needsEscaping returns true if s cannot be put into JSON string as is.
+//
+// UTF-8 is validated only if s contains non-ASCII chars, since the majority
+// of strings in JSON contain only ASCII chars.
+func needsEscaping(s string) bool {
+	i := 0
+	// Check 8 bytes at once.
+	for i+8 <= len(s) {
+		x := loadUint64(s[i:])
+		if specialCharsMask(x) != 0 {
+			return true
+		}
+		if x&swarHighBits != 0 {
+			return needsEscapingNonASCII(s[i:])
+		}
+		i += 8
+	}
+	for ; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' || s[i] < 0x20 {
+			return true
+		}
+		if s[i] >= 0x80 {
+			return needsEscapingNonASCII(s[i:])
+		}
+	}
+	return false
+}
+
+// needsEscapingNonASCII returns true if s containing non-ASCII chars
+// cannot be put into JSON string as is.
+//
+// s must start at the beginning of UTF-8 sequence.
+func needsEscapingNonASCII(s string) bool {
+	return hasSpecialChars(s) || !utf8.ValidString(s)
 }
 
 func hasSpecialChars(s string) bool {
-	if strings.IndexByte(s, '"') >= 0 || strings.IndexByte(s, '\\') >= 0 {
-		return true
+	i := 0
+	// Check 8 bytes at once.
+	for i+8 <= len(s) {
+		if specialCharsMask(loadUint64(s[i:])) != 0 {
+			return true
+		}
+		i += 8
 	}
-	for i := 0; i < len(s); i++ {
-		if s[i] < 0x20 {
+	for ; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' || s[i] < 0x20 {
 			return true
 		}
 	}
@@ -292,17 +1069,68 @@ func hasSpecialChars(s string) bool {
 }
 
 func unescapeStringBestEffort(s string) string {
+	us, _ := unescapeStringCheck(s)
+	return us
+}
+
+// escapeError describes the first invalid escape sequence found
+// by unescapeStringCheck.
+type escapeError uint8
+
+const (
+	escapeErrorNone escapeError = iota
+	escapeErrorUnknown
+	escapeErrorTruncated
+	escapeErrorInvalidUnicode
+	escapeErrorSurrogate
+)
+
+func (e escapeError) String() string {
+	switch e {
+	case escapeErrorNone:
+		return "no error"
+	case escapeErrorUnknown:
+		return "unknown escape sequence"
+	case escapeErrorTruncated:
+		return "truncated escape sequence at the end of string"
+	case escapeErrorInvalidUnicode:
+		return `invalid \u escape sequence; it must contain 4 hex digits`
+	case escapeErrorSurrogate:
+		return `unpaired surrogate in \u escape sequence`
+	default:
+		return fmt.Sprintf("BUG: unknown escapeError: %d", uint8(e))
+	}
+}
+
+// unescapeStringCheck unescapes s in place in the same way
+// as unescapeStringBestEffort and returns the first invalid escape sequence
+// found in s.
+//
+// Invalid escape sequences are kept in the returned string in the same way
+// as in unescapeStringBestEffort, so the returned string doesn't depend
+// on whether the error is checked.
+func unescapeStringCheck(s string) (string, escapeError) {
 	n := strings.IndexByte(s, '\\')
 	if n < 0 {
 		// Fast path - nothing to unescape.
-		return s
+		return s, escapeErrorNone
 	}
 
 	// Slow path - unescape string.
 	b := s2b(s) // It is safe to do, since s points to a byte slice in Parser.b.
 	b = b[:n]
 	s = s[n+1:]
-	for len(s) > 0 {
+	ee := escapeErrorNone
+	setErr := func(e escapeError) {
+		if ee == escapeErrorNone {
+			ee = e
+		}
+	}
+	for {
+		if len(s) == 0 {
+			setErr(escapeErrorTruncated)
+			break
+		}
 		ch := s[0]
 		s = s[1:]
 		switch ch {
@@ -325,6 +1153,7 @@ func unescapeStringBestEffort(s string) string {
 		case 'u':
 			if len(s) < 4 {
 				// Too short escape sequence. Just store it unchanged.
+				setErr(escapeErrorInvalidUnicode)
 				b = append(b, "\\u"...)
 				break
 			}
@@ -332,6 +1161,7 @@ func unescapeStringBestEffort(s string) string {
 			x, err := strconv.ParseUint(xs, 16, 16)
 			if err != nil {
 				// Invalid escape sequence. Just store it unchanged.
+				setErr(escapeErrorInvalidUnicode)
 				b = append(b, "\\u"...)
 				break
 			}
@@ -344,21 +1174,28 @@ func unescapeStringBestEffort(s string) string {
 			// Surrogate.
 			// See https://en.wikipedia.org/wiki/Universal_Character_Set_characters#Surrogates
 			if len(s) < 6 || s[0] != '\\' || s[1] != 'u' {
+				setErr(escapeErrorSurrogate)
 				b = append(b, "\\u"...)
 				b = append(b, xs...)
 				break
 			}
 			x1, err := strconv.ParseUint(s[2:6], 16, 16)
 			if err != nil {
+				setErr(escapeErrorSurrogate)
 				b = append(b, "\\u"...)
 				b = append(b, xs...)
 				break
 			}
 			r := utf16.DecodeRune(rune(x), rune(x1))
+			if r == utf8.RuneError {
+				// The surrogate pair is invalid, e.g. it starts with low surrogate.
+				setErr(escapeErrorSurrogate)
+			}
 			b = append(b, string(r)...)
 			s = s[6:]
 		default:
 			// Unknown escape sequence. Just store it unchanged.
+			setErr(escapeErrorUnknown)
 			b = append(b, '\\', ch)
 		}
 		n = strings.IndexByte(s, '\\')
@@ -369,7 +1206,7 @@ func unescapeStringBestEffort(s string) string {
 		b = append(b, s[:n]...)
 		s = s[n+1:]
 	}
-	return b2s(b)
+	return b2s(b), ee
 }
 
 // parseRawKey is similar to parseRawString, but is optimized
@@ -450,13 +1287,33 @@ func parseRawNumber(s string) (string, string, error) {
 // Object cannot be used from concurrent goroutines.
 // Use per-goroutine parsers or ParserPool instead.
 type Object struct {
-	kvs           []kv
+	kvs []kv
+
+	// keysUnescaped is set if keys in kvs are unescaped.
+	//
+	// Otherwise keys in kvs contain raw escaped keys from the parsed JSON,
+	// so they are written to the marshaled JSON as is. That's why all
+	// the code adding new keys to kvs must call unescapeKeys beforehand,
+	// since the added keys are unescaped.
+	//
+	// Only the parser may create objects with raw keys. Objects created
+	// by Arena always have keysUnescaped set, so keys stored directly
+	// into their kvs are escaped during marshaling.
 	keysUnescaped bool
+
+	// keysInterned is set if all the keys are interned via cache.internKey.
+	keysInterned bool
+
+	// keysEscapeError is the first invalid escape sequence found
+	// in keys by unescapeKeys.
+	keysEscapeError escapeError
 }
 
 func (o *Object) reset() {
 	o.kvs = o.kvs[:0]
 	o.keysUnescaped = false
+	o.keysInterned = false
+	o.keysEscapeError = escapeErrorNone
 }
 
 // MarshalTo appends marshaled o to dst and returns the result.
@@ -480,10 +1337,19 @@ func (o *Object) MarshalTo(dst []byte) []byte {
 	return dst
 }
 
+// AppendString appends string representation of the o to dst
+// and returns the result.
+//
+// It is equivalent to MarshalTo. Use it instead of String in hot paths
+// such as logging, since it allows re-using dst.
+func (o *Object) AppendString(dst []byte) []byte {
+	return o.MarshalTo(dst)
+}
+
 // String returns string representation for the o.
 //
-// This function is for debugging purposes only. It isn't optimized for speed.
-// See MarshalTo instead.
+// This function is for debugging purposes only. See AppendString
+// and MarshalTo for the faster alternatives.
 func (o *Object) String() string {
 	b := o.MarshalTo(nil)
 	// It is safe converting b to string without allocation, since b is no longer
@@ -491,13 +1357,50 @@ func (o *Object) String() string {
 	return b2s(b)
 }
 
+// maxLinearDuplicateKeySearch is the maximum number of object entries,
+// which are searched linearly by findDuplicateKey.
+const maxLinearDuplicateKeySearch = 32
+
+// findDuplicateKey returns the index of the entry with the same key
+// as the last entry in kvs. -1 is returned if there is no such entry.
+//
+// kvs[:len(kvs)-1] mustn't contain duplicate keys. Big objects are indexed
+// in keysIndex in order to avoid quadratic complexity. The keysIndex
+// must be passed to subsequent calls for the same object.
+func findDuplicateKey(kvs []kv, keysIndex map[string]int) (int, map[string]int) {
+	n := len(kvs) - 1
+	key := kvs[n].k
+	if n < maxLinearDuplicateKeySearch {
+		for i := range kvs[:n] {
+			if kvs[i].k == key {
+				return i, keysIndex
+			}
+		}
+		return -1, keysIndex
+	}
+	if keysIndex == nil {
+		keysIndex = make(map[string]int, 2*n)
+		for i := range kvs[:n] {
+			keysIndex[kvs[i].k] = i
+		}
+	}
+	if i, ok := keysIndex[key]; ok {
+		return i, keysIndex
+	}
+	keysIndex[key] = n
+	return -1, keysIndex
+}
+
 func (o *Object) getKV() *kv {
 	if cap(o.kvs) > len(o.kvs) {
 		o.kvs = o.kvs[:len(o.kvs)+1]
 	} else {
 		o.kvs = append(o.kvs, kv{})
 	}
-	return &o.kvs[len(o.kvs)-1]
+	kv := &o.kvs[len(o.kvs)-1]
+	// Reset only the key offset, since the caller must set k and v.
+	kv.ko = 0
+	return kv
 }
 
 func (o *Object) unescapeKeys() {
@@ -507,7 +1410,11 @@ func (o *Object) unescapeKeys() {
 	kvs := o.kvs
 	for i := range kvs {
 		kv := &kvs[i]
-		kv.k = unescapeStringBestEffort(kv.k)
+		var ee escapeError
+		kv.k, ee = unescapeStringCheck(kv.k)
+		if o.keysEscapeError == escapeErrorNone {
+			o.keysEscapeError = ee
+		}
 	}
 	o.keysUnescaped = true
 }
@@ -543,9 +1450,157 @@ func (o *Object) Get(key string) *Value {
 	return nil
 }
 
+// GetStrict is like Get, but returns an error if object keys in o contain
+// invalid escape sequences, since such keys cannot be reliably matched.
+//
+// nil value without error is returned for the missing key.
+// Use Value.StringBytesStrict for verifying escape sequences in string values.
+func (o *Object) GetStrict(key string) (*Value, error) {
+	o.unescapeKeys()
+	if o.keysEscapeError != escapeErrorNone {
+		return nil, fmt.Errorf("cannot unescape object keys: %s", o.keysEscapeError)
+	}
+	for _, kv := range o.kvs {
+		if kv.k == key {
+			return kv.v, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetWithExists returns the value for the given key in the o
+// and reports whether the key exists.
+//
+// (null value, true) is returned for the key with explicit null value,
+// while (nil, false) is returned for missing key.
+//
+// The returned value is valid until Parse is called on the Parser returned o.
+func (o *Object) GetWithExists(key string) (*Value, bool) {
+	if o == nil {
+		return nil, false
+	}
+	v := o.Get(key)
+	return v, v != nil
+}
+
+// Has returns true if the o contains the given key.
+func (o *Object) Has(key string) bool {
+	return o != nil && o.Get(key) != nil
+}
+
+// GetAll appends all the values for the given key in the o to dst
+// and returns the result.
+//
+// Unlike Get, which returns the value only for the first occurrence
+// of the key, GetAll returns values for all the duplicate keys
+// in the original order of the parsed JSON.
+//
+// The returned values are valid until Parse is called on the Parser returned o.
+func (o *Object) GetAll(dst []*Value, key string) []*Value {
+	if o == nil {
+		return dst
+	}
+	o.unescapeKeys()
+
+	for _, kv := range o.kvs {
+		if kv.k == key {
+			dst = append(dst, kv.v)
+		}
+	}
+	return dst
+}
+
+// CountKey returns the number of occurrences of the given key in the o.
+func (o *Object) CountKey(key string) int {
+	if o == nil {
+		return 0
+	}
+	o.unescapeKeys()
+
+	n := 0
+	for _, kv := range o.kvs {
+		if kv.k == key {
+			n++
+		}
+	}
+	return n
+}
+
+// GetSeveral appends values for the given keys in the o to dst
+// and returns the result.
+//
+// The i-th appended value corresponds to keys[i]. The value for the first
+// occurrence of the key is appended, while nil is appended for missing keys.
+//
+// Unlike calling Get for every key, GetSeveral walks o only once, so it is
+// faster when obtaining multiple values from big objects. The number
+// of keys should be small, since every object key is compared against
+// all of them. GetSeveral doesn't allocate if dst has enough capacity.
+//
+// The returned values are valid until Parse is called on the Parser returned o.
+func (o *Object) GetSeveral(dst []*Value, keys ...string) []*Value {
+	dstLen := len(dst)
+	for range keys {
+		dst = append(dst, nil)
+	}
+	if o == nil {
+		return dst
+	}
+	vs := dst[dstLen:]
+
+	if !o.keysUnescaped {
+		// Fast path - try searching for the keys without object keys unescaping.
+		hasEscapedKeys := false
+		for _, key := range keys {
+			if strings.IndexByte(key, '\\') >= 0 {
+				hasEscapedKeys = true
+				break
+			}
+		}
+		if !hasEscapedKeys && o.getSeveral(vs, keys) == len(keys) {
+			return dst
+		}
+	}
+
+	// Slow path - unescape object keys.
+	o.unescapeKeys()
+
+	o.getSeveral(vs, keys)
+	return dst
+}
+
+// getSeveral fills nil vs items with values for the corresponding keys
+// and returns the number of non-nil vs items.
+func (o *Object) getSeveral(vs []*Value, keys []string) int {
+	found := 0
+	for _, v := range vs {
+		if v != nil {
+			found++
+		}
+	}
+	for _, kv := range o.kvs {
+		if found == len(keys) {
+			break
+		}
+		// Compare the last chars before comparing the whole keys,
+		// since keys frequently share common prefixes.
+		k := kv.k
+		n := len(k) - 1
+		for i, key := range keys {
+			if vs[i] == nil && len(key) == len(k) && (n < 0 || key[n] == k[n]) && key == k {
+				vs[i] = kv.v
+				found++
+			}
+		}
+	}
+	return found
+}
+
 // Visit calls f for each item in the o in the original order
 // of the parsed JSON.
 //
+// Items with duplicate keys are visited too.
+//
 // f cannot hold key and/or v after returning.
 func (o *Object) Visit(f func(key []byte, v *Value)) {
 	if o == nil {
@@ -559,6 +1614,100 @@ func (o *Object) Visit(f func(key []byte, v *Value)) {
 	}
 }
 
+// VisitPrefix calls f for each item in the o with the key starting
+// with the given prefix.
+//
+// Keys are matched byte-wise after unescaping. Items are visited
+// in the original order of the parsed JSON, including items with
+// duplicate keys.
+//
+// f cannot hold key and/or v after returning.
+func (o *Object) VisitPrefix(prefix string, f func(key []byte, v *Value)) {
+	if o == nil {
+		return
+	}
+
+	o.unescapeKeys()
+
+	for _, kv := range o.kvs {
+		if strings.HasPrefix(kv.k, prefix) {
+			f(s2b(kv.k), kv.v)
+		}
+	}
+}
+
+// KeysWithPrefix appends keys starting with the given prefix from o
+// to dst and returns the result.
+//
+// Keys are matched byte-wise after unescaping. The appended keys refer
+// to o memory, so they are valid until Parse is called on the Parser
+// returned o. The appended keys mustn't be modified.
+func (o *Object) KeysWithPrefix(dst [][]byte, prefix string) [][]byte {
+	if o == nil {
+		return dst
+	}
+
+	o.unescapeKeys()
+
+	for _, kv := range o.kvs {
+		if strings.HasPrefix(kv.k, prefix) {
+			dst = append(dst, s2b(kv.k))
+		}
+	}
+	return dst
+}
+
+// KeyOffset returns the location of the given key in the original JSON
+// passed to Parser.Parse*.
+//
+// The returned start is the offset of the opening quote of the key,
+// while length is the length of the key including quotes, so the original
+// key is located at start:start+length. The length may differ
+// from the unescaped key length if the key contains escape sequences.
+//
+// ok is false if the key is missing or if its location is unknown,
+// e.g. if the key has been added via Set or if the object hasn't been
+// parsed by Parser. See Parser.Raw for details.
+func (o *Object) KeyOffset(key string) (int, int, bool) {
+	if o == nil {
+		return 0, 0, false
+	}
+
+	o.unescapeKeys()
+
+	for i := range o.kvs {
+		kv := &o.kvs[i]
+		if kv.k == key {
+			return kv.keyOffset()
+		}
+	}
+	return 0, 0, false
+}
+
+// VisitWithOffsets calls f for each item in the o in the original order
+// of the parsed JSON.
+//
+// Unlike Visit, f also receives the offset of the key in the original JSON.
+// The offset is -1 if it is unknown. See KeyOffset for details.
+//
+// f cannot hold key and/or v after returning.
+func (o *Object) VisitWithOffsets(f func(key []byte, offset int, v *Value)) {
+	if o == nil {
+		return
+	}
+
+	o.unescapeKeys()
+
+	for i := range o.kvs {
+		kv := &o.kvs[i]
+		offset, _, ok := kv.keyOffset()
+		if !ok {
+			offset = -1
+		}
+		f(s2b(kv.k), offset, kv.v)
+	}
+}
+
 // Value represents any JSON value.
 //
 // Call Type in order to determine the actual type of the JSON value.
@@ -570,9 +1719,34 @@ type Value struct {
 	a []*Value
 	s string
 	t Type
+
+	// n contains the cached parsed number. Its kind is stored in nc.
+	// See Value.cachedFloat64 for details.
+	//
+	// These fields grow Value by 8 bytes.
+	n  uint64
+	nc numberCache
+
+	// escErr is the first invalid escape sequence found in the string
+	// when it is unescaped. See StringBytesStrict.
+	escErr escapeError
+
+	// nk contains cached NumberKind for numbers.
+	//
+	// It is stored as uint8, so it fits the padding after nc and escErr.
+	nk uint8
+
+	// userTag is set via SetUserTag.
+	//
+	// It occupies the padding after nc, escErr and nk, so it doesn't grow Value.
+	userTag uint32
 }
 
 // MarshalTo appends marshaled v to dst and returns the result.
+//
+// Numbers are written exactly as they were parsed, so MarshalTo never
+// loses digits for numbers exceeding int64, uint64 or float64 precision.
+// See Value.RawNumber.
 func (v *Value) MarshalTo(dst []byte) []byte {
 	switch v.t {
 	case typeRawString:
@@ -590,27 +1764,84 @@ func (v *Value) MarshalTo(dst []byte) []byte {
 				dst = append(dst, ',')
 			}
 		}
-		dst = append(dst, ']')
-		return dst
-	case TypeString:
-		return escapeString(dst, v.s)
+		dst = append(dst, ']')
+		return dst
+	case TypeString:
+		return escapeString(dst, v.s)
+	case TypeNumber:
+		return append(dst, v.s...)
+	case TypeTrue:
+		return append(dst, "true"...)
+	case TypeFalse:
+		return append(dst, "false"...)
+	case TypeNull:
+		return append(dst, "null"...)
+	default:
+		panic(fmt.Errorf("BUG: unexpected Value type: %d", v.t))
+	}
+}
+
+// Clone returns a deep copy of v.
+//
+// The returned copy doesn't refer to the memory owned by the Parser
+// or the Arena v belongs to, so it remains valid after v becomes invalid.
+//
+// nil is returned for nil v.
+func (v *Value) Clone() *Value {
+	if v == nil {
+		return nil
+	}
+	var a Arena
+	return a.deepCopy(v)
+}
+
+// Normalize eagerly performs all the lazy work on v and its children,
+// such as unescaping strings and object keys.
+//
+// Read-only methods such as Get*, Type, Visit, StringBytes, NumberKind
+// and MarshalTo may modify lazily unescaped values, so they cannot be called
+// concurrently on a freshly parsed value. They can be safely called
+// from concurrent goroutines after Normalize returns, until v is modified
+// via Set*, Del or Normalize calls.
+func (v *Value) Normalize() {
+	if v == nil {
+		return
+	}
+	switch v.Type() {
+	case TypeObject:
+		o := &v.o
+		o.unescapeKeys()
+		for _, kv := range o.kvs {
+			kv.v.Normalize()
+		}
+	case TypeArray:
+		for _, vv := range v.a {
+			vv.Normalize()
+		}
 	case TypeNumber:
-		return append(dst, v.s...)
-	case TypeTrue:
-		return append(dst, "true"...)
-	case TypeFalse:
-		return append(dst, "false"...)
-	case TypeNull:
-		return append(dst, "null"...)
-	default:
-		panic(fmt.Errorf("BUG: unexpected Value type: %d", v.t))
+		// Cache int64 for integers, since it is used for float64 too.
+		// See Value.cachedFloat64.
+		if v.NumberKind() == NumberInt {
+			v.cachedInt64()
+		} else {
+			v.cachedFloat64()
+		}
 	}
 }
 
+// AppendString appends string representation of the v to dst
+// and returns the result.
+//
+// It is equivalent to MarshalTo. Use it instead of String in hot paths
+// such as logging, since it allows re-using dst.
+func (v *Value) AppendString(dst []byte) []byte {
+	return v.MarshalTo(dst)
+}
+
 // String returns string representation of the v.
 //
-// The function is for debugging purposes only. It isn't optimized for speed.
-// See MarshalTo instead.
+// The function is for debugging purposes only. See AppendString
+// and MarshalTo for the faster alternatives.
 //
 // Don't confuse this function with StringBytes, which must be called
 // for obtaining the underlying JSON string for the v.
@@ -677,12 +1908,55 @@ func (t Type) String() string {
 // Type returns the type of the v.
 func (v *Value) Type() Type {
 	if v.t == typeRawString {
-		v.s = unescapeStringBestEffort(v.s)
+		v.s, v.escErr = unescapeStringCheck(v.s)
 		v.t = TypeString
 	}
 	return v.t
 }
 
+// IsNull returns true if v is JSON null.
+//
+// false is returned for nil v, so missing values aren't confused
+// with explicit nulls. See GetWithExists.
+func (v *Value) IsNull() bool {
+	return v != nil && v.t == TypeNull
+}
+
+// IsObject returns true if v is JSON object.
+//
+// false is returned for nil v.
+func (v *Value) IsObject() bool {
+	return v != nil && v.t == TypeObject
+}
+
+// IsArray returns true if v is JSON array.
+//
+// false is returned for nil v.
+func (v *Value) IsArray() bool {
+	return v != nil && v.t == TypeArray
+}
+
+// IsString returns true if v is JSON string.
+//
+// false is returned for nil v.
+func (v *Value) IsString() bool {
+	return v != nil && (v.t == TypeString || v.t == typeRawString)
+}
+
+// IsNumber returns true if v is JSON number.
+//
+// false is returned for nil v.
+func (v *Value) IsNumber() bool {
+	return v != nil && v.t == TypeNumber
+}
+
+// IsBool returns true if v is JSON true or false.
+//
+// false is returned for nil v.
+func (v *Value) IsBool() bool {
+	return v != nil && (v.t == TypeTrue || v.t == TypeFalse)
+}
+
 // Exists returns true if the field exists for the given keys path.
 //
 // Array indexes may be represented as decimal numbers in keys.
@@ -721,6 +1995,112 @@ func (v *Value) Get(keys ...string) *Value {
 	return v
 }
 
+// GetWithExists returns value by the given keys path and reports
+// whether the path exists.
+//
+// Unlike Get, it makes obvious at call sites that explicit null values
+// are distinguished from missing ones: (null value, true) is returned
+// for explicit null, while (nil, false) is returned for missing path.
+//
+// The returned value is valid until Parse is called on the Parser returned v.
+func (v *Value) GetWithExists(keys ...string) (*Value, bool) {
+	v = v.Get(keys...)
+	return v, v != nil
+}
+
+// GetKey returns the value for the given object key.
+//
+// Unlike Get, GetKey never treats key as an array index.
+// nil is returned if v isn't an object or if the key is missing.
+//
+// The returned value is valid until Parse is called on the Parser returned v.
+func (v *Value) GetKey(key string) *Value {
+	if v == nil || v.t != TypeObject {
+		return nil
+	}
+	return v.o.Get(key)
+}
+
+// GetIndex returns the array item with the given index.
+//
+// Negative index refers to the item from the end of the array,
+// so -1 refers to the last item.
+// nil is returned if v isn't an array or if the index is out of range.
+//
+// The returned value is valid until Parse is called on the Parser returned v.
+func (v *Value) GetIndex(i int) *Value {
+	if v == nil || v.t != TypeArray {
+		return nil
+	}
+	if i < 0 {
+		i += len(v.a)
+	}
+	if i < 0 || i >= len(v.a) {
+		return nil
+	}
+	return v.a[i]
+}
+
+// GetPath returns value by the given path.
+//
+// Path elements must be object keys (string) and array indexes (int).
+// Unlike Get, string elements are always treated as object keys,
+// while int elements are always treated as array indexes.
+// See GetKey and GetIndex for details. Paths returned from Find
+// may be passed to GetPath.
+//
+// nil is returned for non-existing path or for invalid path elements.
+//
+// The returned value is valid until Parse is called on the Parser returned v.
+func (v *Value) GetPath(path ...interface{}) *Value {
+	for _, elem := range path {
+		switch elem := elem.(type) {
+		case string:
+			v = v.GetKey(elem)
+		case int:
+			v = v.GetIndex(elem)
+		default:
+			return nil
+		}
+		if v == nil {
+			return nil
+		}
+	}
+	return v
+}
+
+// GetMany returns values for the given keys paths.
+//
+// The i-th returned value corresponds to the i-th path.
+// nil is returned for non-existing keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// The returned values are valid until Parse is called on the Parser returned v.
+func (v *Value) GetMany(paths ...[]string) []*Value {
+	vs := make([]*Value, len(paths))
+	for i, path := range paths {
+		vs[i] = v.Get(path...)
+	}
+	return vs
+}
+
+// GetSeveral appends values for the given keys in the object located
+// by the given prefix keys path to dst and returns the result.
+//
+// nil values are appended for all the keys if the object is missing.
+// See Object.GetSeveral for details.
+//
+// The returned values are valid until Parse is called on the Parser returned v.
+func (v *Value) GetSeveral(dst []*Value, prefix []string, keys ...string) []*Value {
+	var o *Object
+	v = v.Get(prefix...)
+	if v != nil && v.t == TypeObject {
+		o = &v.o
+	}
+	return o.GetSeveral(dst, keys...)
+}
+
 // GetObject returns object value by the given keys path.
 //
 // Array indexes may be represented as decimal numbers in keys.
@@ -756,12 +2136,17 @@ func (v *Value) GetArray(keys ...string) []*Value {
 // Array indexes may be represented as decimal numbers in keys.
 //
 // 0 is returned for non-existing keys path or for invalid value type.
+// NaN and Inf numbers are returned in the same way as Float64 does.
 func (v *Value) GetFloat64(keys ...string) float64 {
 	v = v.Get(keys...)
 	if v == nil || v.Type() != TypeNumber {
 		return 0
 	}
-	return fastfloat.ParseBestEffort(v.s)
+	f, err := v.cachedFloat64()
+	if err != nil {
+		return 0
+	}
+	return f
 }
 
 // GetInt returns int value by the given keys path.
@@ -774,7 +2159,10 @@ func (v *Value) GetInt(keys ...string) int {
 	if v == nil || v.Type() != TypeNumber {
 		return 0
 	}
-	n := fastfloat.ParseInt64BestEffort(v.s)
+	n, err := v.cachedInt64()
+	if err != nil {
+		return 0
+	}
 	nn := int(n)
 	if int64(nn) != n {
 		return 0
@@ -792,6 +2180,9 @@ func (v *Value) GetUint(keys ...string) uint {
 	if v == nil || v.Type() != TypeNumber {
 		return 0
 	}
+	if checkIntNumberLen(v.s, false) != nil {
+		return 0
+	}
 	n := fastfloat.ParseUint64BestEffort(v.s)
 	nn := uint(n)
 	if uint64(nn) != n {
@@ -810,7 +2201,11 @@ func (v *Value) GetInt64(keys ...string) int64 {
 	if v == nil || v.Type() != TypeNumber {
 		return 0
 	}
-	return fastfloat.ParseInt64BestEffort(v.s)
+	n, err := v.cachedInt64()
+	if err != nil {
+		return 0
+	}
+	return n
 }
 
 // GetUint64 returns uint64 value by the given keys path.
@@ -823,6 +2218,9 @@ func (v *Value) GetUint64(keys ...string) uint64 {
 	if v == nil || v.Type() != TypeNumber {
 		return 0
 	}
+	if checkIntNumberLen(v.s, false) != nil {
+		return 0
+	}
 	return fastfloat.ParseUint64BestEffort(v.s)
 }
 
@@ -841,6 +2239,23 @@ func (v *Value) GetStringBytes(keys ...string) []byte {
 	return s2b(v.s)
 }
 
+// GetString returns string value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// An empty string is returned for non-existing keys path or for invalid value type.
+//
+// Unlike GetStringBytes, the returned string is a copy, so it remains valid
+// after Parse is called on the Parser returned v.
+func (v *Value) GetString(keys ...string) string {
+	v = v.Get(keys...)
+	if v == nil || v.Type() != TypeString {
+		return ""
+	}
+	// Convert via []byte in order to make a copy of v.s.
+	return string(s2b(v.s))
+}
+
 // GetBool returns bool value by the given keys path.
 //
 // Array indexes may be represented as decimal numbers in keys.
@@ -854,6 +2269,146 @@ func (v *Value) GetBool(keys ...string) bool {
 	return false
 }
 
+// GetBoolLenient returns bool value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// Unlike GetBool, GetBoolLenient accepts "true" and "false" strings
+// and 0 and 1 numbers. See BoolLenient for details.
+//
+// false is returned for non-existing keys path or for invalid value.
+func (v *Value) GetBoolLenient(keys ...string) bool {
+	v = v.Get(keys...)
+	if v == nil {
+		return false
+	}
+	b, err := v.BoolLenient()
+	if err != nil {
+		return false
+	}
+	return b
+}
+
+// GetIntDefault returns int value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// Unlike GetInt, defaultValue is returned for non-existing keys path
+// or for invalid value type, so missing values may be distinguished
+// from zero values. defaultValue is also returned if the number
+// doesn't fit int.
+func (v *Value) GetIntDefault(defaultValue int, keys ...string) int {
+	v = v.Get(keys...)
+	if v == nil {
+		return defaultValue
+	}
+	n, err := v.Int()
+	if err != nil {
+		return defaultValue
+	}
+	return n
+}
+
+// GetInt64Default returns int64 value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// Unlike GetInt64, defaultValue is returned for non-existing keys path
+// or for invalid value type, so missing values may be distinguished
+// from zero values. defaultValue is also returned if the number
+// doesn't fit int64.
+func (v *Value) GetInt64Default(defaultValue int64, keys ...string) int64 {
+	v = v.Get(keys...)
+	if v == nil {
+		return defaultValue
+	}
+	n, err := v.Int64()
+	if err != nil {
+		return defaultValue
+	}
+	return n
+}
+
+// GetUint64Default returns uint64 value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// Unlike GetUint64, defaultValue is returned for non-existing keys path
+// or for invalid value type, so missing values may be distinguished
+// from zero values. defaultValue is also returned if the number
+// doesn't fit uint64.
+func (v *Value) GetUint64Default(defaultValue uint64, keys ...string) uint64 {
+	v = v.Get(keys...)
+	if v == nil {
+		return defaultValue
+	}
+	n, err := v.Uint64()
+	if err != nil {
+		return defaultValue
+	}
+	return n
+}
+
+// GetFloat64Default returns float64 value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// Unlike GetFloat64, defaultValue is returned for non-existing keys path
+// or for invalid value type, so missing values may be distinguished
+// from zero values.
+func (v *Value) GetFloat64Default(defaultValue float64, keys ...string) float64 {
+	v = v.Get(keys...)
+	if v == nil {
+		return defaultValue
+	}
+	f, err := v.Float64()
+	if err != nil {
+		return defaultValue
+	}
+	return f
+}
+
+// GetStringDefault returns string value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// Unlike GetString, defaultValue is returned for non-existing keys path
+// or for invalid value type, so missing values may be distinguished
+// from empty strings.
+//
+// The returned string is a copy, so it remains valid after Parse is called
+// on the Parser returned v.
+func (v *Value) GetStringDefault(defaultValue string, keys ...string) string {
+	v = v.Get(keys...)
+	if v == nil || v.Type() != TypeString {
+		return defaultValue
+	}
+	// Convert via []byte in order to make a copy of v.s.
+	return string(s2b(v.s))
+}
+
+// GetBoolDefault returns bool value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// Unlike GetBool, defaultValue is returned for non-existing keys path
+// or for invalid value type, so missing values may be distinguished
+// from false values.
+func (v *Value) GetBoolDefault(defaultValue bool, keys ...string) bool {
+	v = v.Get(keys...)
+	if v == nil {
+		return defaultValue
+	}
+	switch v.t {
+	case TypeTrue:
+		return true
+	case TypeFalse:
+		return false
+	default:
+		return defaultValue
+	}
+}
+
 // Object returns the underlying JSON object for the v.
 //
 // The returned object is valid until Parse is called on the Parser returned v.
@@ -890,69 +2445,188 @@ func (v *Value) StringBytes() ([]byte, error) {
 	return s2b(v.s), nil
 }
 
+// StringBytesCopy appends the underlying JSON string for the v to dst
+// and returns the result.
+//
+// Unlike StringBytes, the returned string doesn't refer to the Parser memory,
+// so it remains valid after Parse is called on the Parser returned v.
+func (v *Value) StringBytesCopy(dst []byte) ([]byte, error) {
+	if v.Type() != TypeString {
+		return dst, fmt.Errorf("value doesn't contain string; it contains %s", v.Type())
+	}
+	return append(dst, v.s...), nil
+}
+
+// StringBytesStrict is like StringBytes, but returns an error if the JSON
+// string contains invalid escape sequences such as unknown escapes,
+// truncated \u escapes or unpaired surrogates.
+//
+// StringBytes keeps invalid escape sequences in the returned string as is.
+//
+// This function doesn't unescape the string twice, so it may be freely
+// mixed with StringBytes and other methods reading strings.
+func (v *Value) StringBytesStrict() ([]byte, error) {
+	if v.Type() != TypeString {
+		return nil, fmt.Errorf("value doesn't contain string; it contains %s", v.Type())
+	}
+	if v.escErr != escapeErrorNone {
+		return nil, fmt.Errorf("cannot unescape string %q: %s", startEndString(v.s), v.escErr)
+	}
+	return s2b(v.s), nil
+}
+
 // Float64 returns the underlying JSON number for the v.
 //
-// Use GetFloat64 if you don't need error handling.
+// NaN and Inf numbers such as NaN, nan, -inf and +Inf are returned
+// without error. Numbers exceeding float64 range such as 1e400
+// are returned as Inf without error too.
+//
+// The parsed number is cached in v, so subsequent calls are cheap.
+//
+// Use GetFloat64 or Float64BestEffort if you don't need error handling.
 func (v *Value) Float64() (float64, error) {
 	if v.Type() != TypeNumber {
 		return 0, fmt.Errorf("value doesn't contain number; it contains %s", v.Type())
 	}
-	return fastfloat.Parse(v.s)
+	return v.cachedFloat64()
+}
+
+// Float64Strict returns the underlying JSON number for the v.
+//
+// Unlike Float64, it returns an error for numbers, which are invalid
+// according to RFC 8259, such as NaN, -inf, +1 or 0123. The parser accepts
+// such numbers, so Float64Strict may be used for rejecting them after parsing.
+func (v *Value) Float64Strict() (float64, error) {
+	if v.Type() != TypeNumber {
+		return 0, fmt.Errorf("value doesn't contain number; it contains %s", v.Type())
+	}
+	return fastfloat.ParseJSON(v.s)
+}
+
+// Float64FromString returns the number encoded in JSON string v.
+//
+// This covers the common case of numbers encoded as strings such as "42".
+// The unescaped string contents must match the number grammar accepted
+// by Float64, so surrounding whitespace and thousands separators result
+// in error. Use Float64FromStringTrim for ignoring surrounding whitespace.
+//
+// JSON numbers are returned as is, so the function may be used for values,
+// which may contain either numbers or strings with numbers.
+func (v *Value) Float64FromString() (float64, error) {
+	return v.float64FromString(fastfloat.Parse)
+}
+
+// Float64FromStringTrim is like Float64FromString, but ignores ASCII
+// whitespace around the number in JSON string v such as " 42 ".
+func (v *Value) Float64FromStringTrim() (float64, error) {
+	return v.float64FromString(fastfloat.ParseTrim)
+}
+
+func (v *Value) float64FromString(parse func(s string) (float64, error)) (float64, error) {
+	switch v.Type() {
+	case TypeNumber:
+		return v.cachedFloat64()
+	case TypeString:
+		f, err := parse(v.s)
+		if err != nil {
+			return 0, wrapErrorf(err, "cannot parse number from string %q: %s", v.s, err)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("value doesn't contain string or number; it contains %s", v.Type())
+	}
+}
+
+// Float64BestEffort returns the underlying JSON number for the v.
+//
+// 0 is returned if v doesn't contain a number or if the number
+// cannot be parsed. Otherwise the result is the same as for Float64.
+func (v *Value) Float64BestEffort() float64 {
+	if v.Type() != TypeNumber {
+		return 0
+	}
+	f, err := v.cachedFloat64()
+	if err != nil {
+		return 0
+	}
+	return f
 }
 
 // Int returns the underlying JSON int for the v.
 //
+// errors.Is(err, fastfloat.ErrRange) returns true if the number doesn't fit int,
+// while errors.Is(err, fastfloat.ErrSyntax) returns true for other parse errors.
+//
 // Use GetInt if you don't need error handling.
 func (v *Value) Int() (int, error) {
 	if v.Type() != TypeNumber {
 		return 0, fmt.Errorf("value doesn't contain number; it contains %s", v.Type())
 	}
-	n, err := fastfloat.ParseInt64(v.s)
+	n, err := v.cachedInt64()
 	if err != nil {
 		return 0, err
 	}
 	nn := int(n)
 	if int64(nn) != n {
-		return 0, fmt.Errorf("number %q doesn't fit int", v.s)
+		return 0, wrapErrorf(fastfloat.ErrRange, "number %q doesn't fit int", v.s)
 	}
 	return nn, nil
 }
 
 // Uint returns the underlying JSON uint for the v.
 //
+// errors.Is(err, fastfloat.ErrRange) returns true if the number doesn't fit uint,
+// while errors.Is(err, fastfloat.ErrSyntax) returns true for other parse errors.
+//
 // Use GetInt if you don't need error handling.
 func (v *Value) Uint() (uint, error) {
 	if v.Type() != TypeNumber {
 		return 0, fmt.Errorf("value doesn't contain number; it contains %s", v.Type())
 	}
-	n, err := fastfloat.ParseUint64(v.s)
+	n, err := v.parseUint64()
 	if err != nil {
 		return 0, err
 	}
 	nn := uint(n)
 	if uint64(nn) != n {
-		return 0, fmt.Errorf("number %q doesn't fit uint", v.s)
+		return 0, wrapErrorf(fastfloat.ErrRange, "number %q doesn't fit uint", v.s)
 	}
 	return nn, nil
 }
 
 // Int64 returns the underlying JSON int64 for the v.
 //
+// The parsed number is cached in v, so subsequent calls are cheap.
+//
+// errors.Is(err, fastfloat.ErrRange) returns true if the number doesn't fit int64,
+// while errors.Is(err, fastfloat.ErrSyntax) returns true for other parse errors.
+//
 // Use GetInt64 if you don't need error handling.
 func (v *Value) Int64() (int64, error) {
 	if v.Type() != TypeNumber {
 		return 0, fmt.Errorf("value doesn't contain number; it contains %s", v.Type())
 	}
-	return fastfloat.ParseInt64(v.s)
+	return v.cachedInt64()
 }
 
 // Uint64 returns the underlying JSON uint64 for the v.
 //
+// errors.Is(err, fastfloat.ErrRange) returns true if the number doesn't fit uint64,
+// while errors.Is(err, fastfloat.ErrSyntax) returns true for other parse errors.
+//
 // Use GetInt64 if you don't need error handling.
 func (v *Value) Uint64() (uint64, error) {
 	if v.Type() != TypeNumber {
 		return 0, fmt.Errorf("value doesn't contain number; it contains %s", v.Type())
 	}
+	return v.parseUint64()
+}
+
+// parseUint64 returns uint64 for the number stored in v.
+func (v *Value) parseUint64() (uint64, error) {
+	if err := checkIntNumberLen(v.s, false); err != nil {
+		return 0, err
+	}
 	return fastfloat.ParseUint64(v.s)
 }
 
@@ -969,6 +2643,46 @@ func (v *Value) Bool() (bool, error) {
 	return false, fmt.Errorf("value doesn't contain bool; it contains %s", v.Type())
 }
 
+// BoolLenient returns bool for the v.
+//
+// Unlike Bool, BoolLenient accepts the following values in addition
+// to JSON true and false:
+//
+//   - "true" and "false" strings in any case
+//   - 1 and 0 numbers
+//
+// Use GetBoolLenient if you don't need error handling.
+func (v *Value) BoolLenient() (bool, error) {
+	switch v.Type() {
+	case TypeTrue:
+		return true, nil
+	case TypeFalse:
+		return false, nil
+	case TypeString:
+		if strings.EqualFold(v.s, "true") {
+			return true, nil
+		}
+		if strings.EqualFold(v.s, "false") {
+			return false, nil
+		}
+		return false, fmt.Errorf("cannot convert string %q to bool; it must be \"true\" or \"false\"", v.s)
+	case TypeNumber:
+		f, err := fastfloat.Parse(v.s)
+		if err != nil {
+			return false, fmt.Errorf("cannot convert number %q to bool: %s", v.s, err)
+		}
+		if f == 1 {
+			return true, nil
+		}
+		if f == 0 {
+			return false, nil
+		}
+		return false, fmt.Errorf("cannot convert number %q to bool; it must be 0 or 1", v.s)
+	default:
+		return false, fmt.Errorf("value doesn't contain bool; it contains %s", v.Type())
+	}
+}
+
 var (
 	valueTrue  = &Value{t: TypeTrue}
 	valueFalse = &Value{t: TypeFalse}
@@ -3,9 +3,14 @@ package fastjson
 import (
 	"fmt"
 	"math"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
+	"unsafe"
+
+	"github.com/valyala/fastjson/fastfloat"
 )
 
 func TestParseRawNumber(t *testing.T) {
@@ -101,6 +106,169 @@ func testUnescapeStringBestEffort(t *testing.T, s, expectedS string) {
 	}
 }
 
+func TestUnescapeStringCheck(t *testing.T) {
+	f := func(s, expectedS string, expectedErr escapeError) {
+		t.Helper()
+
+		b := append([]byte{}, s...)
+		us, ee := unescapeStringCheck(b2s(b))
+		if us != expectedS {
+			t.Fatalf("unexpected unescaped string for %q; got %q; want %q", s, us, expectedS)
+		}
+		if ee != expectedErr {
+			t.Fatalf("unexpected error for %q; got %q; want %q", s, ee, expectedErr)
+		}
+	}
+
+	// Valid escape sequences.
+	f(``, ``, escapeErrorNone)
+	f(`\"`, `"`, escapeErrorNone)
+	f(`\\\"абв`, `\"абв`, escapeErrorNone)
+	f(`йцук\n\"\\Y\/\b\f\r\t`, "йцук\n\"\\Y/\b\f\r\t", escapeErrorNone)
+	f(`q\u1234we`, "q\u1234we", escapeErrorNone)
+	f(`п\ud83e\udd2dи`, "п🤭и", escapeErrorNone)
+
+	// The cases from TestUnescapeStringBestEffort error block.
+	// The unescaped string must match unescapeStringBestEffort results.
+	f(`\`, ``, escapeErrorTruncated)
+	f(`foo\qwe`, `foo\qwe`, escapeErrorUnknown)
+	f(`\"x\uyz\"`, `"x\uyz"`, escapeErrorInvalidUnicode)
+	f(`\u12\"пролw`, `\u12"пролw`, escapeErrorInvalidUnicode)
+	f(`п\ud83eи`, "п\\ud83eи", escapeErrorSurrogate)
+
+	// Additional malformed escape sequences.
+	f(`foo\`, `foo`, escapeErrorTruncated)
+	f(`\u12`, `\u12`, escapeErrorInvalidUnicode)
+	f(`\ud83e\u12`, `\ud83e\u12`, escapeErrorSurrogate)
+	f(`\ud83e\uzzzz`, `\ud83e\uzzzz`, escapeErrorSurrogate)
+	f(`\udd2d\ud83e`, "\ufffd", escapeErrorSurrogate)
+	f(`\ud83e\u0041`, "\ufffd", escapeErrorSurrogate)
+
+	// The first error is returned.
+	f(`\x\u12`, `\x\u12`, escapeErrorUnknown)
+}
+
+func TestValueStringBytesStrict(t *testing.T) {
+	var p Parser
+	f := func(s, expectedS string) {
+		t.Helper()
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		sb, err := v.StringBytesStrict()
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		if string(sb) != expectedS {
+			t.Fatalf("unexpected string for %q; got %q; want %q", s, sb, expectedS)
+		}
+	}
+	f(`""`, "")
+	f(`"foo"`, "foo")
+	f(`"п\ud83e\udd2dи\n"`, "п🤭и\n")
+
+	ferr := func(s, expectedBestEffort string) {
+		t.Helper()
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		if _, err := v.StringBytesStrict(); err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+
+		// Best-effort methods must continue working after the strict check.
+		sb, err := v.StringBytes()
+		if err != nil {
+			t.Fatalf("unexpected error in StringBytes for %q: %s", s, err)
+		}
+		if string(sb) != expectedBestEffort {
+			t.Fatalf("unexpected StringBytes for %q; got %q; want %q", s, sb, expectedBestEffort)
+		}
+
+		// The strict check must fail after best-effort unescaping too.
+		if _, err := v.StringBytesStrict(); err == nil {
+			t.Fatalf("expecting non-nil error for %q on the second call", s)
+		}
+	}
+	ferr(`"foo\qwe"`, `foo\qwe`)
+	ferr(`"\"x\uyz\""`, `"x\uyz"`)
+	ferr(`"\u12\"пролw"`, `\u12"пролw`)
+	ferr(`"п\ud83eи"`, "п\\ud83eи")
+	ferr(`"\udd2d\ud83e"`, "\ufffd")
+
+	// The error must be detected if the string is unescaped before the strict check.
+	v, err := p.Parse(`["fo\u", 1]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := v.GetStringBytes("0"); string(s) != `fo\u` {
+		t.Fatalf("unexpected string; got %q; want %q", s, `fo\u`)
+	}
+	if _, err := v.Get("0").StringBytesStrict(); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	if _, err := v.Get("1").StringBytesStrict(); err == nil {
+		t.Fatalf("expecting non-nil error for number")
+	}
+
+	// Parsed values must be reset after the error.
+	v, err = p.Parse(`["foo"]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := v.Get("0").StringBytesStrict(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestObjectGetStrict(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"foo":1,"b\u0061r":"x\q"}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	o := v.GetObject()
+	vv, err := o.GetStrict("bar")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := vv.StringBytesStrict(); err == nil {
+		t.Fatalf("expecting non-nil error for the value with invalid escape sequence")
+	}
+	vv, err = o.GetStrict("foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := vv.GetInt(); n != 1 {
+		t.Fatalf("unexpected value; got %d; want %d", n, 1)
+	}
+	vv, err = o.GetStrict("missing")
+	if err != nil {
+		t.Fatalf("unexpected error for missing key: %s", err)
+	}
+	if vv != nil {
+		t.Fatalf("expecting nil value for missing key; got %s", vv)
+	}
+
+	for _, s := range []string{`{"a":1,"x\y":2}`, `{"\ud83e":1}`, `{"foo\u12":1}`} {
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		o := v.GetObject()
+		if _, err := o.GetStrict("a"); err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+
+		// Get must continue working in best-effort mode.
+		if vv := o.Get("a"); s == `{"a":1,"x\y":2}` && vv == nil {
+			t.Fatalf("expecting non-nil value for %q", s)
+		}
+	}
+}
+
 func TestParseRawString(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		f := func(s, expectedRS, expectedTail string) {
@@ -188,430 +356,1570 @@ func TestParserPool(t *testing.T) {
 	}
 }
 
-func TestValueInvalidTypeConversion(t *testing.T) {
-	var p Parser
-
-	v, err := p.Parse(`[{},[],"",123.45,true,null]`)
-	if err != nil {
+func TestParserPoolPutDropsParsedJSON(t *testing.T) {
+	var pp ParserPool
+	p := pp.Get()
+	p.KeepRaw(true)
+	if _, err := p.Parse(`{"foo":"bar"}`); err != nil {
 		t.Fatalf("unexpected error: %s", err)
 	}
-	a := v.GetArray()
-
-	// object
-	_, err = a[0].Object()
-	if err != nil {
-		t.Fatalf("unexpected error when obtaining object: %s", err)
-	}
-	_, err = a[0].Array()
-	if err == nil {
-		t.Fatalf("expecting non-nil error when trying to obtain array from object")
+	pp.Put(p)
+	if p.v != nil || p.c.raw != "" || len(p.c.vs) != 0 {
+		t.Fatalf("the parser put into the pool must not refer to the parsed JSON")
 	}
+}
 
-	// array
-	_, err = a[1].Array()
+func TestValueGetMany(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"foo":[1,{"bar":"baz"}],"x":null}`)
 	if err != nil {
-		t.Fatalf("unexpected error when obtaining array: %s", err)
+		t.Fatalf("unexpected error: %s", err)
 	}
-	_, err = a[1].Object()
-	if err == nil {
-		t.Fatalf("expecting non-nil error when trying to obtain object from array")
+	vs := v.GetMany([]string{"foo", "1", "bar"}, []string{"x"}, []string{"foo", "2"}, nil)
+	if len(vs) != 4 {
+		t.Fatalf("unexpected number of values; got %d; want %d", len(vs), 4)
 	}
-
-	// string
-	_, err = a[2].StringBytes()
-	if err != nil {
-		t.Fatalf("unexpected error when obtaining string: %s", err)
+	if s := vs[0].GetStringBytes(); string(s) != "baz" {
+		t.Fatalf("unexpected value; got %q; want %q", s, "baz")
 	}
-	_, err = a[2].Int()
-	if err == nil {
-		t.Fatalf("expecting non-nil error when trying to obtain int from string")
+	if vs[1].Type() != TypeNull {
+		t.Fatalf("unexpected value type; got %s; want %s", vs[1].Type(), TypeNull)
 	}
-	_, err = a[2].Int64()
-	if err == nil {
-		t.Fatalf("expecting non-nil error when trying to obtain int64 from string")
+	if vs[2] != nil {
+		t.Fatalf("expecting nil value for missing path; got %s", vs[2])
 	}
-	_, err = a[2].Uint()
-	if err == nil {
-		t.Fatalf("expecting non-nil error when trying to obtain uint from string")
+	if vs[3] != v {
+		t.Fatalf("empty path must return the value itself")
 	}
-	_, err = a[2].Uint64()
-	if err == nil {
-		t.Fatalf("expecting non-nil error when trying to obtain uint64 from string")
+
+	// nil value
+	v = nil
+	vs = v.GetMany([]string{"foo"})
+	if len(vs) != 1 || vs[0] != nil {
+		t.Fatalf("expecting nil value for nil v; got %v", vs)
 	}
-	_, err = a[2].Float64()
-	if err == nil {
-		t.Fatalf("expecting non-nil error when trying to obtain float64 from string")
+}
+
+func TestValueClone(t *testing.T) {
+	var v *Value
+	if v.Clone() != nil {
+		t.Fatalf("expecting nil clone for nil value")
 	}
 
-	// number
-	_, err = a[3].Float64()
+	var p Parser
+	s := `{"fo\no":[1,"x\"y",{"bar":null,"baz":true}],"qwe":false}`
+	v, err := p.Parse(s)
 	if err != nil {
-		t.Fatalf("unexpected error when obtaining float64: %s", err)
+		t.Fatalf("unexpected error: %s", err)
 	}
-	_, err = a[3].StringBytes()
-	if err == nil {
-		t.Fatalf("expecting non-nil error when trying to obtain string from number")
+	expected := v.String()
+	vc := v.Clone()
+	vcSub := v.Get("fo\no", "2").Clone()
+
+	// Re-use the parser on another input.
+	if _, err := p.Parse(`["aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",{"x":1}]`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
 	}
 
-	// true
-	_, err = a[4].Bool()
-	if err != nil {
-		t.Fatalf("unexpected error when obtaining bool: %s", err)
+	if str := vc.String(); str != expected {
+		t.Fatalf("unexpected clone; got %q; want %q", str, expected)
 	}
-	_, err = a[4].StringBytes()
-	if err == nil {
-		t.Fatalf("expecting non-nil error when trying to obtain string from bool")
+	if str := vcSub.String(); str != `{"bar":null,"baz":true}` {
+		t.Fatalf("unexpected clone; got %q; want %q", str, `{"bar":null,"baz":true}`)
 	}
+	if sb := vc.GetStringBytes("fo\no", "1"); string(sb) != `x"y` {
+		t.Fatalf("unexpected string in the clone; got %q; want %q", sb, `x"y`)
+	}
+}
 
-	// null
-	_, err = a[5].Bool()
-	if err == nil {
-		t.Fatalf("expecting non-nil error when trying to obtain bool from null")
+func TestValueSize(t *testing.T) {
+	if unsafe.Sizeof(uintptr(0)) != 8 {
+		t.Skip("the test is valid only on 64-bit platforms")
+	}
+	// Value and kv are allocated for every parsed value and object entry,
+	// so their size must remain small.
+	if n := unsafe.Sizeof(Value{}); n != 96 {
+		t.Fatalf("unexpected Value size; got %d bytes; want 96 bytes", n)
+	}
+	if n := unsafe.Sizeof(kv{}); n != 32 {
+		t.Fatalf("unexpected kv size; got %d bytes; want 32 bytes", n)
 	}
 }
 
-func TestValueGetTyped(t *testing.T) {
+func TestParserRaw(t *testing.T) {
 	var p Parser
+	p.KeepRaw(true)
+	if raw := p.Raw(nil); raw != nil {
+		t.Fatalf("expecting nil raw for nil value; got %q", raw)
+	}
 
-	v, err := p.Parse(`{"foo": 123, "bar": "433", "baz": true, "obj":{}, "arr":[1,2,3],
-		"zero_float1": 0.00,
-		"zero_float2": -0e123,
-		"inf_float": Inf,
-		"minus_inf_float": -Inf,
-		"nan": nan
-	}`)
+	s := " {\n\t\"fo\\no\" : [ 1.50E+3 , \"x\\\"y\", {\"bar\":null, \"baz\" :true} ],\"qwe\":false\t} \n"
+	v, err := p.Parse(s)
 	if err != nil {
 		t.Fatalf("unexpected error: %s", err)
 	}
-
-	if !v.Exists("foo") {
-		t.Fatalf("foo must exist in the v")
-	}
-	if v.Exists("foo", "bar") {
-		t.Fatalf("foo.bar mustn't exist in the v")
+	f := func(v *Value, expected string) {
+		t.Helper()
+		if raw := p.Raw(v); string(raw) != expected {
+			t.Fatalf("unexpected raw JSON; got %q; want %q", raw, expected)
+		}
 	}
-	if v.Exists("foobar") {
-		t.Fatalf("foobar mustn't exist in the v")
+	f(v, s[1:len(s)-2])
+	f(v.Get("fo\no"), "[ 1.50E+3 , \"x\\\"y\", {\"bar\":null, \"baz\" :true} ]")
+	f(v.Get("fo\no", "0"), "1.50E+3")
+	f(v.Get("fo\no", "1"), `"x\"y"`)
+	f(v.Get("fo\no", "2"), `{"bar":null, "baz" :true}`)
+	f(v.Get("fo\no", "2", "bar"), "null")
+	f(v.Get("fo\no", "2", "baz"), "true")
+	f(v.Get("qwe"), "false")
+
+	// Raw JSON must remain unchanged after unescaping the string.
+	if sb := v.GetStringBytes("fo\no", "1"); string(sb) != `x"y` {
+		t.Fatalf("unexpected string; got %q; want %q", sb, `x"y`)
 	}
+	f(v.Get("fo\no", "1"), `"x\"y"`)
 
-	o := v.GetObject("obj")
-	os := o.String()
-	if os != "{}" {
-		t.Fatalf("unexpected object; got %s; want %s", os, "{}")
+	// Modifications aren't reflected in raw JSON.
+	v.Get("fo\no", "2").Del("bar")
+	f(v.Get("fo\no", "2"), `{"bar":null, "baz" :true}`)
+
+	// Values without the original JSON.
+	if raw := p.Raw(v.Clone()); raw != nil {
+		t.Fatalf("expecting nil raw for cloned value; got %q", raw)
 	}
-	o = v.GetObject("arr")
-	if o != nil {
-		t.Fatalf("unexpected non-nil object: %s", o)
+	var a Arena
+	if raw := p.Raw(a.NewString("foo")); raw != nil {
+		t.Fatalf("expecting nil raw for arena value; got %q", raw)
 	}
-	o = v.GetObject("foo", "bar")
-	if o != nil {
-		t.Fatalf("unexpected non-nil object: %s", o)
+	vOther := MustParse(`"foo"`)
+	if raw := p.Raw(vOther); raw != nil {
+		t.Fatalf("expecting nil raw for the value from another parser; got %q", raw)
 	}
-	a := v.GetArray("arr")
-	if len(a) != 3 {
-		t.Fatalf("unexpected array len; got %d; want %d", len(a), 3)
+	vCopy := *v.Get("fo\no", "1")
+	if raw := p.Raw(&vCopy); raw != nil {
+		t.Fatalf("expecting nil raw for the copied value; got %q", raw)
 	}
-	a = v.GetArray("obj")
-	if a != nil {
-		t.Fatalf("unexpected non-nil array: %s", a)
+
+	// Raw JSON for the fixture must match the corresponding part of the input.
+	data := getFromFile("testdata/twitter.json")
+	v, err = p.Parse(data)
+	if err != nil {
+		t.Fatalf("cannot parse twitter.json: %s", err)
+	}
+	f(v, strings.TrimSpace(data))
+	statuses := v.GetArray("statuses")
+	for _, st := range statuses {
+		raw := p.Raw(st)
+		var pp Parser
+		vv, err := pp.ParseBytes(raw)
+		if err != nil {
+			t.Fatalf("cannot parse raw JSON %q: %s", raw, err)
+		}
+		if !vv.Equal(st) {
+			t.Fatalf("unexpected value parsed from raw JSON %q", raw)
+		}
 	}
-	a = v.GetArray("foo", "bar")
-	if a != nil {
-		t.Fatalf("unexpected non-nil array: %s", a)
+}
+
+func TestValueBoolLenient(t *testing.T) {
+	f := func(s string, resultExpected bool) {
+		t.Helper()
+
+		var p Parser
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		b, err := v.BoolLenient()
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		if b != resultExpected {
+			t.Fatalf("unexpected result for %q; got %v; want %v", s, b, resultExpected)
+		}
+		if b := v.GetBoolLenient(); b != resultExpected {
+			t.Fatalf("unexpected GetBoolLenient result for %q; got %v; want %v", s, b, resultExpected)
+		}
 	}
-	n := v.GetInt("foo")
-	if n != 123 {
-		t.Fatalf("unexpected value; got %d; want %d", n, 123)
+	f(`true`, true)
+	f(`false`, false)
+	f(`"true"`, true)
+	f(`"TRUE"`, true)
+	f(`"False"`, false)
+	f(`"f\u0061lse"`, false)
+	f(`1`, true)
+	f(`0`, false)
+	f(`1.0`, true)
+	f(`-0`, false)
+
+	ferr := func(s string) {
+		t.Helper()
+
+		var p Parser
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		b, err := v.BoolLenient()
+		if err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+		if b {
+			t.Fatalf("unexpected true value for %q", s)
+		}
+		if v.GetBoolLenient() {
+			t.Fatalf("unexpected true value from GetBoolLenient for %q", s)
+		}
 	}
-	n64 := v.GetInt64("foo")
-	if n != 123 {
-		t.Fatalf("unexpected value; got %d; want %d", n64, 123)
+	ferr(`2`)
+	ferr(`-1`)
+	ferr(`"yes"`)
+	ferr(`"1"`)
+	ferr(`""`)
+	ferr(`null`)
+	ferr(`[]`)
+	ferr(`{}`)
+
+	// The strict GetBool must remain unchanged.
+	var p Parser
+	v, err := p.Parse(`{"a":"true","b":1}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
 	}
-	un := v.GetUint("foo")
-	if un != 123 {
-		t.Fatalf("unexpected value; got %d; want %d", un, 123)
+	if v.GetBool("a") || v.GetBool("b") {
+		t.Fatalf("GetBool must return false for non-bool values")
 	}
-	un64 := v.GetUint64("foo")
-	if un != 123 {
-		t.Fatalf("unexpected value; got %d; want %d", un64, 123)
+	if !v.GetBoolLenient("a") || !v.GetBoolLenient("b") {
+		t.Fatalf("GetBoolLenient must return true")
 	}
-	n = v.GetInt("bar")
-	if n != 0 {
-		t.Fatalf("unexpected non-zero value; got %d", n)
+	if v.GetBoolLenient("missing") {
+		t.Fatalf("GetBoolLenient must return false for missing key")
 	}
-	n64 = v.GetInt64("bar")
-	if n != 0 {
-		t.Fatalf("unexpected non-zero value; got %d", n64)
+}
+
+func TestParserKeepRaw(t *testing.T) {
+	var p Parser
+	b := []byte(`{"foo":["b\u0061r",123]}`)
+	v, err := p.ParseBytes(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
 	}
-	un = v.GetUint("bar")
-	if n != 0 {
-		t.Fatalf("unexpected non-zero value; got %d", un)
+	if raw := p.Raw(v.Get("foo")); raw != nil {
+		t.Fatalf("unexpected non-nil raw JSON with disabled KeepRaw: %q", raw)
 	}
-	un64 = v.GetUint64("bar")
-	if n != 0 {
-		t.Fatalf("unexpected non-zero value; got %d", n64)
+	if _, _, ok := p.ValueOffset(v.Get("foo")); ok {
+		t.Fatalf("expecting no location with disabled KeepRaw")
 	}
-	f := v.GetFloat64("foo")
-	if f != 123.0 {
-		t.Fatalf("unexpected value; got %f; want %f", f, 123.0)
+	if _, _, ok := v.GetObject().KeyOffset("foo"); !ok {
+		t.Fatalf("key location must be available with disabled KeepRaw")
 	}
-	f = v.GetFloat64("bar")
-	if f != 0 {
-		t.Fatalf("unexpected value; got %f; want %f", f, 0.0)
+
+	p.KeepRaw(true)
+	v, err = p.ParseBytes(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
 	}
-	f = v.GetFloat64("foooo", "bar")
-	if f != 0 {
-		t.Fatalf("unexpected value; got %f; want %f", f, 0.0)
+	// Raw JSON must remain unchanged after the caller re-uses b
+	// and after the string is unescaped in place.
+	for i := range b {
+		b[i] = ' '
 	}
-	f = v.GetFloat64()
-	if f != 0 {
-		t.Fatalf("unexpected value; got %f; want %f", f, 0.0)
+	if sb := v.GetStringBytes("foo", "0"); string(sb) != "bar" {
+		t.Fatalf("unexpected string; got %q; want %q", sb, "bar")
 	}
-	sb := v.GetStringBytes("bar")
-	if string(sb) != "433" {
-		t.Fatalf("unexpected value; got %q; want %q", sb, "443")
-	}
-	sb = v.GetStringBytes("foo")
-	if sb != nil {
-		t.Fatalf("unexpected value; got %q; want %q", sb, []byte(nil))
-	}
-	bv := v.GetBool("baz")
-	if !bv {
-		t.Fatalf("unexpected value; got %v; want %v", bv, true)
-	}
-	bv = v.GetBool("bar")
-	if bv {
-		t.Fatalf("unexpected value; got %v; want %v", bv, false)
+	if raw := p.Raw(v.Get("foo")); string(raw) != `["b\u0061r",123]` {
+		t.Fatalf("unexpected raw JSON; got %q; want %q", raw, `["b\u0061r",123]`)
 	}
 
-	zv := v.Get("zero_float1")
-	zf, err := zv.Float64()
+	p.KeepRaw(false)
+	v, err = p.Parse(`[1]`)
 	if err != nil {
 		t.Fatalf("unexpected error: %s", err)
 	}
-	if zf != 0 {
-		t.Fatalf("unexpected zero_float1 value: %f. Expecting 0", zf)
+	if raw := p.Raw(v); raw != nil {
+		t.Fatalf("unexpected non-nil raw JSON after disabling KeepRaw: %q", raw)
 	}
+}
 
-	zv = v.Get("zero_float2")
-	zf, err = zv.Float64()
-	if err != nil {
-		t.Fatalf("unexpected error: %s", err)
+func TestParserParseOwning(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		// Use exactly sized buffer.
+		b := append(make([]byte, 0, len(s)), s...)
+		var p Parser
+		v, err := p.ParseOwning(b)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing %q: %s", s, err)
+		}
+		if raw := p.Raw(v); raw != nil {
+			t.Fatalf("unexpected non-nil raw JSON: %q", raw)
+		}
+		o := v.GetObject()
+		if o == nil {
+			t.Fatalf("expecting object; got %s", v.Type())
+		}
+		if o.Len() != 2 {
+			t.Fatalf("unexpected number of items; got %d; want %d", o.Len(), 2)
+		}
+		if sb := v.GetStringBytes("a\nb"); string(sb) != "xAy\"" {
+			t.Fatalf("unexpected string; got %q; want %q", sb, "xAy\"")
+		}
+		if sb := v.GetStringBytes("c", "0"); string(sb) != "é\t" {
+			t.Fatalf("unexpected string; got %q; want %q", sb, "é\t")
+		}
+
+		// The buffer must be returned to the caller via SwapBuffer.
+		bPrev := p.SwapBuffer(nil)
+		if len(bPrev) == 0 || &bPrev[0] != &b[0] {
+			t.Fatalf("SwapBuffer must return the buffer passed to ParseOwning")
+		}
 	}
-	if zf != 0 {
-		t.Fatalf("unexpected zero_float1 value: %f. Expecting 0", zf)
+	f(`{"a\nb":"xAy\"","c":["é\t"]}`)
+	f(" \n{\"a\\nb\":\"x\\u0041y\\\"\",\"c\":[\"\\u00e9\\t\"]}\t")
+
+	// Invalid JSON
+	var p Parser
+	_, err := p.ParseOwning([]byte(`{"foo": [1,2,]}`))
+	if err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expecting *ParseError; got %T", err)
+	}
+	if pe.Offset != 13 || pe.Path != "foo[2]" {
+		t.Fatalf("unexpected error location; got offset=%d, path=%q; want offset=13, path=%q", pe.Offset, pe.Path, "foo[2]")
 	}
 
-	infv := v.Get("inf_float")
-	inff, err := infv.Float64()
+	// Parse must use the buffer passed to SwapBuffer.
+	buf := make([]byte, 0, 64)
+	p.SwapBuffer(buf)
+	v, err := p.Parse(`{"x":"y"}`)
 	if err != nil {
 		t.Fatalf("unexpected error: %s", err)
 	}
-	if !math.IsInf(inff, 1) {
-		t.Fatalf("unexpected inf_float value: %f. Expecting %f", inff, math.Inf(1))
+	if sb := v.GetStringBytes("x"); string(sb) != "y" {
+		t.Fatalf("unexpected string; got %q; want %q", sb, "y")
 	}
+	bPrev := p.SwapBuffer(nil)
+	if cap(bPrev) != cap(buf) || string(bPrev) != `{"x":"y"}` {
+		t.Fatalf("unexpected buffer returned from SwapBuffer: %q", bPrev)
+	}
+}
 
-	ninfv := v.Get("minus_inf_float")
-	ninff, err := ninfv.Float64()
-	if err != nil {
-		t.Fatalf("unexpected error: %s", err)
+func TestValueAppendString(t *testing.T) {
+	f := func(v *Value) {
+		t.Helper()
+		expected := string(v.MarshalTo(nil))
+		if str := v.String(); str != expected {
+			t.Fatalf("unexpected String result; got %q; want %q", str, expected)
+		}
+		if str := fmt.Sprintf("%s", v); str != expected {
+			t.Fatalf("unexpected fmt result; got %q; want %q", str, expected)
+		}
+		b := v.AppendString([]byte("prefix"))
+		if string(b) != "prefix"+expected {
+			t.Fatalf("unexpected AppendString result; got %q; want %q", b, "prefix"+expected)
+		}
+		if o, err := v.Object(); err == nil {
+			if str := o.String(); str != expected {
+				t.Fatalf("unexpected Object.String result; got %q; want %q", str, expected)
+			}
+			b := o.AppendString([]byte("prefix"))
+			if string(b) != "prefix"+expected {
+				t.Fatalf("unexpected Object.AppendString result; got %q; want %q", b, "prefix"+expected)
+			}
+		}
 	}
-	if !math.IsInf(ninff, -1) {
-		t.Fatalf("unexpected inf_float value: %f. Expecting %f", ninff, math.Inf(-11))
+
+	var p Parser
+	v, err := p.Parse(mediumFixture)
+	if err != nil {
+		t.Fatalf("cannot parse medium.json: %s", err)
 	}
+	f(v)
+	f(v.Get("person", "name"))
 
-	nanv := v.Get("nan")
-	nanf, err := nanv.Float64()
+	// String must work for values exceeding the original JSON length.
+	v, err = p.Parse(` {"a" : [ 1 ] } `)
 	if err != nil {
 		t.Fatalf("unexpected error: %s", err)
 	}
-	if !math.IsNaN(nanf) {
-		t.Fatalf("unexpected nan value: %f. Expecting %f", nanf, math.NaN())
-	}
+	f(v)
+	var a Arena
+	v.Set("b", a.NewString(strings.Repeat("x", 100)))
+	v.Get("a").SetArrayItem(1, a.NewStringBytes([]byte("foo\nbar")))
+	f(v)
+	f(v.Get("a"))
+	f(a.NewNumberInt(123))
 }
 
-func TestVisitNil(t *testing.T) {
+func TestObjectKeyOffset(t *testing.T) {
 	var p Parser
-	v, err := p.Parse(`{}`)
+	s := " \n{\"foo\": 1, \"b\\u0061r\" : {\"x\\ny\":[{\"\":null}]}, \"\\\"q\\\"\":2}"
+	v, err := p.Parse(s)
 	if err != nil {
 		t.Fatalf("unexpected error: %s", err)
 	}
-	o := v.GetObject("non-existing-key")
-	if o != nil {
-		t.Fatalf("obtained an object for non-existing key: %#v", o)
+	f := func(o *Object, key, rawKeyExpected string) {
+		t.Helper()
+		start, length, ok := o.KeyOffset(key)
+		if !ok {
+			t.Fatalf("cannot find offset for key %q", key)
+		}
+		if rawKey := s[start : start+length]; rawKey != rawKeyExpected {
+			t.Fatalf("unexpected raw key for %q; got %q; want %q", key, rawKey, rawKeyExpected)
+		}
+	}
+	o := v.GetObject()
+	f(o, "foo", `"foo"`)
+	f(o, "bar", `"b\u0061r"`)
+	f(o, `"q"`, `"\"q\""`)
+	f(v.GetObject("bar"), "x\ny", `"x\ny"`)
+	f(v.GetObject("bar", "x\ny", "0"), "", `""`)
+	if _, _, ok := o.KeyOffset("missing"); ok {
+		t.Fatalf("unexpected offset for missing key")
 	}
-	o.Visit(func(k []byte, v *Value) {
-		t.Fatalf("unexpected visit call; k=%q; v=%s", k, v)
-	})
-}
 
-func TestValueGet(t *testing.T) {
-	var pp ParserPool
+	// Keys added via Set have no offsets.
+	var a Arena
+	o.Set("new", a.NewNull())
+	if _, _, ok := o.KeyOffset("new"); ok {
+		t.Fatalf("unexpected offset for the key added via Set")
+	}
+	f(o, "foo", `"foo"`)
 
-	p := pp.Get()
-	v, err := p.ParseBytes([]byte(`{"xx":33.33,"foo":[123,{"bar":["baz"],"x":"y"}], "": "empty-key", "empty-value": ""}`))
+	// Too long keys have no offsets.
+	longKey := strings.Repeat("x", maxKeyOffsetLen)
+	vl, err := p.Parse(`{"a":1,"` + longKey + `":2}`)
 	if err != nil {
 		t.Fatalf("unexpected error: %s", err)
 	}
+	if _, _, ok := vl.GetObject().KeyOffset(longKey); ok {
+		t.Fatalf("unexpected offset for too long key")
+	}
+	if start, length, ok := vl.GetObject().KeyOffset("a"); !ok || start != 1 || length != 3 {
+		t.Fatalf("unexpected offset for the key %q; got start=%d, length=%d, ok=%v; want 1, 3, true", "a", start, length, ok)
+	}
 
-	t.Run("positive", func(t *testing.T) {
-		sb := v.GetStringBytes("")
-		if string(sb) != "empty-key" {
-			t.Fatalf("unexpected value for empty key; got %q; want %q", sb, "empty-key")
+	// Renamed keys have no offsets.
+	vr, err := p.Parse(`{"alpha":1,"b":2}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	or := vr.GetObject()
+	or.Rename("alpha", "zz")
+	if _, _, ok := or.KeyOffset("zz"); ok {
+		t.Fatalf("unexpected offset for the renamed key")
+	}
+	or.VisitWithOffsets(func(key []byte, offset int, _ *Value) {
+		if string(key) == "zz" && offset != -1 {
+			t.Fatalf("unexpected offset for the renamed key; got %d; want -1", offset)
 		}
-		sb = v.GetStringBytes("empty-value")
-		if string(sb) != "" {
-			t.Fatalf("unexpected non-empty value: %q", sb)
+		if string(key) == "b" && offset != 11 {
+			t.Fatalf("unexpected offset for the key %q; got %d; want 11", key, offset)
 		}
+	})
+	v, err = p.Parse(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	o = v.GetObject()
 
-		vv := v.Get("foo", "1")
-		if vv == nil {
-			t.Fatalf("cannot find the required value")
+	// Constructed and cloned objects have no offsets.
+	if _, _, ok := v.Clone().GetObject().KeyOffset("foo"); ok {
+		t.Fatalf("unexpected offset for the cloned object")
+	}
+	oc := a.NewObject()
+	oc.Set("foo", a.NewTrue())
+	if _, _, ok := oc.GetObject().KeyOffset("foo"); ok {
+		t.Fatalf("unexpected offset for the constructed object")
+	}
+	oc.GetObject().VisitWithOffsets(func(key []byte, offset int, v *Value) {
+		if offset != -1 {
+			t.Fatalf("unexpected offset for the constructed object; got %d; want -1", offset)
 		}
+	})
+
+	// Verify offsets for all the keys in the fixture.
+	s = twitterFixture
+	v, err = p.Parse(s)
+	if err != nil {
+		t.Fatalf("cannot parse twitter.json: %s", err)
+	}
+	keys := 0
+	v.Walk(func(path []interface{}, vv *Value) bool {
 		o, err := vv.Object()
 		if err != nil {
-			t.Fatalf("cannot obtain object: %s", err)
+			return true
 		}
-
-		n := 0
-		o.Visit(func(k []byte, v *Value) {
-			n++
-			switch string(k) {
-			case "bar":
-				if v.Type() != TypeArray {
-					t.Fatalf("unexpected value type; got %d; want %d", v.Type(), TypeArray)
-				}
-				s := v.String()
-				if s != `["baz"]` {
-					t.Fatalf("unexpected array; got %q; want %q", s, `["baz"]`)
-				}
-			case "x":
-				sb, err := v.StringBytes()
-				if err != nil {
-					t.Fatalf("cannot obtain string: %s", err)
-				}
-				if string(sb) != "y" {
-					t.Fatalf("unexpected string; got %q; want %q", sb, "y")
-				}
-			default:
-				t.Fatalf("unknown key: %s", k)
+		o.VisitWithOffsets(func(key []byte, offset int, _ *Value) {
+			keys++
+			if offset < 0 || offset >= len(s) || s[offset] != '"' {
+				t.Fatalf("unexpected offset %d for key %q at %v", offset, key, path)
+			}
+			_, length, ok := o.KeyOffset(string(key))
+			if !ok {
+				t.Fatalf("cannot find offset for key %q at %v", key, path)
+			}
+			var pp Parser
+			kv, err := pp.Parse(s[offset : offset+length])
+			if err != nil {
+				t.Fatalf("cannot parse raw key at offset %d: %s", offset, err)
+			}
+			if k := kv.GetStringBytes(); string(k) != string(key) {
+				t.Fatalf("unexpected key at offset %d; got %q; want %q", offset, k, key)
 			}
 		})
-		if n != 2 {
-			t.Fatalf("unexpected number of items visited in the array; got %d; want %d", n, 2)
-		}
+		return true
 	})
+	if keys == 0 {
+		t.Fatalf("no keys found in twitter.json")
+	}
+}
 
-	t.Run("negative", func(t *testing.T) {
-		vv := v.Get("nonexisting", "path")
-		if vv != nil {
-			t.Fatalf("expecting nil value for nonexisting path. Got %#v", vv)
-		}
-		vv = v.Get("foo", "bar", "baz")
-		if vv != nil {
-			t.Fatalf("expecting nil value for nonexisting path. Got %#v", vv)
-		}
-		vv = v.Get("foo", "-123")
-		if vv != nil {
-			t.Fatalf("expecting nil value for nonexisting path. Got %#v", vv)
-		}
-		vv = v.Get("foo", "234")
-		if vv != nil {
-			t.Fatalf("expecting nil value for nonexisting path. Got %#v", vv)
-		}
-		vv = v.Get("xx", "yy")
-		if vv != nil {
-			t.Fatalf("expecting nil value for nonexisting path. Got %#v", vv)
+func TestParserForEachArrayElement(t *testing.T) {
+	// Build a big array.
+	const n = 100000
+	var sb strings.Builder
+	sb.WriteString(" [")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteString(",\n")
 		}
-	})
-
-	pp.Put(p)
-}
+		fmt.Fprintf(&sb, `{"id":%d,"name":"item\n%d","tags":["a","b",{"x":[%d]}]}`, i, i, i)
+	}
+	sb.WriteString("] ")
+	s := sb.String()
 
-func TestParserParse(t *testing.T) {
 	var p Parser
-
-	t.Run("complex-string", func(t *testing.T) {
-		v, err := p.Parse(`{"тест":1, "\\\"фыва\"":2, "\\\"\u1234x":"\\fЗУ\\\\"}`)
-		if err != nil {
-			t.Fatalf("unexpected error: %s", err)
+	p.KeepRaw(true)
+	iExpected := 0
+	maxCacheLen := 0
+	err := p.ForEachArrayElement(s, func(i int, v *Value) error {
+		if i != iExpected {
+			return fmt.Errorf("unexpected index; got %d; want %d", i, iExpected)
 		}
-		n := v.GetInt("тест")
-		if n != 1 {
-			t.Fatalf("unexpected int; got %d; want %d", n, 1)
+		iExpected++
+		if id := v.GetInt("id"); id != i {
+			return fmt.Errorf("unexpected id; got %d; want %d", id, i)
 		}
-		n = v.GetInt(`\"фыва"`)
-		if n != 2 {
-			t.Fatalf("unexpected int; got %d; want %d", n, 2)
+		name := fmt.Sprintf("item\n%d", i)
+		if sb := v.GetStringBytes("name"); string(sb) != name {
+			return fmt.Errorf("unexpected name; got %q; want %q", sb, name)
 		}
-		sb := v.GetStringBytes("\\\"\u1234x")
-		if string(sb) != `\fЗУ\\` {
-			t.Fatalf("unexpected string; got %q; want %q", sb, `\fЗУ\\`)
+		raw := fmt.Sprintf(`{"id":%d,"name":"item\n%d","tags":["a","b",{"x":[%d]}]}`, i, i, i)
+		if string(p.Raw(v)) != raw {
+			return fmt.Errorf("unexpected raw JSON; got %q; want %q", p.Raw(v), raw)
+		}
+		if len(p.c.vs) > maxCacheLen {
+			maxCacheLen = len(p.c.vs)
 		}
+		return nil
 	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if iExpected != n {
+		t.Fatalf("unexpected number of elements; got %d; want %d", iExpected, n)
+	}
+	if maxCacheLen > 10 {
+		t.Fatalf("too big cache length: %d", maxCacheLen)
+	}
 
-	t.Run("invalid-string-escape", func(t *testing.T) {
-		v, err := p.Parse(`"fo\u"`)
-		if err != nil {
-			t.Fatalf("unexpected error when parsing string")
-		}
-		// Make sure only valid string part remains
-		sb, err := v.StringBytes()
-		if err != nil {
-			t.Fatalf("cannot obtain string: %s", err)
-		}
-		if string(sb) != "fo\\u" {
-			t.Fatalf("unexpected string; got %q; want %q", sb, "fo\\u")
+	// Early abort.
+	errAbort := fmt.Errorf("abort")
+	calls := 0
+	err = p.ForEachArrayElement(s, func(i int, v *Value) error {
+		calls++
+		if i == 10 {
+			return errAbort
 		}
+		return nil
+	})
+	if err != errAbort {
+		t.Fatalf("unexpected error; got %v; want %v", err, errAbort)
+	}
+	if calls != 11 {
+		t.Fatalf("unexpected number of calls; got %d; want %d", calls, 11)
+	}
 
-		v, err = p.Parse(`"foo\ubarz2134"`)
-		if err != nil {
-			t.Fatalf("unexpected error when parsing string")
-		}
-		sb, err = v.StringBytes()
+	f := func(s string, valuesExpected string) {
+		t.Helper()
+		var values []string
+		err := p.ForEachArrayElement(s, func(i int, v *Value) error {
+			values = append(values, v.String())
+			return nil
+		})
 		if err != nil {
-			t.Fatalf("cannot obtain string: %s", err)
+			t.Fatalf("unexpected error for %q: %s", s, err)
 		}
-		if string(sb) != "foo\\ubarz2134" {
-			t.Fatalf("unexpected string; got %q; want %q", sb, "foo")
+		if str := strings.Join(values, ","); str != valuesExpected {
+			t.Fatalf("unexpected values for %q; got %q; want %q", s, str, valuesExpected)
 		}
+	}
+	f(`[]`, ``)
+	f(" [ ] ", ``)
+	f(`[1]`, `1`)
+	f(`[1, "a", null, [true], {}]`, `1,"a",null,[true],{}`)
 
-		v, err = p.Parse(`"fo` + "\x19" + `\u"`)
-		if err != nil {
-			t.Fatalf("unexpected error when parsing string")
-		}
-		sb, err = v.StringBytes()
-		if err != nil {
-			t.Fatalf("cannot obtain string: %s", err)
+	ferr := func(s string, calls int) {
+		t.Helper()
+		n := 0
+		err := p.ForEachArrayElement(s, func(i int, v *Value) error {
+			n++
+			return nil
+		})
+		if err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
 		}
-		if string(sb) != "fo\x19\\u" {
-			t.Fatalf("unexpected string; got %q; want %q", sb, "fo\x19\\u")
+		if _, ok := err.(*ParseError); !ok {
+			t.Fatalf("expecting *ParseError for %q; got %T", s, err)
 		}
-	})
-
-	t.Run("invalid-number", func(t *testing.T) {
-		v, err := p.Parse("123+456")
-		if err != nil {
-			t.Fatalf("unexpected error when parsing int")
+		if n != calls {
+			t.Fatalf("unexpected number of calls for %q; got %d; want %d", s, n, calls)
 		}
+	}
+	ferr(``, 0)
+	ferr(`{}`, 0)
+	ferr(`123`, 0)
+	ferr(`[`, 0)
+	ferr(`[1`, 1)
+	ferr(`[1,`, 1)
+	ferr(`[1 2]`, 1)
+	ferr(`[1,]`, 1)
+	ferr(`[1, 2] foo`, 2)
+	ferr(`[1, 2]]`, 2)
+}
 
-		// Make sure invalid int isn't parsed.
-		n, err := v.Int()
-		if err == nil {
-			t.Fatalf("expecting non-nil error")
-		}
-		if n != 0 {
-			t.Fatalf("unexpected int; got %d; want %d", n, 0)
-		}
+func TestValueGetKeyIndex(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"0":"zero","1":[10,20,{"0":[30]}],"a":[["x","y"]]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f := func(v *Value, expected string) {
+		t.Helper()
+		if v == nil {
+			if expected != "" {
+				t.Fatalf("unexpected nil value; want %s", expected)
+			}
+			return
+		}
+		if str := v.String(); str != expected {
+			t.Fatalf("unexpected value; got %s; want %s", str, expected)
+		}
+	}
+
+	// Numeric object keys
+	f(v.GetKey("0"), `"zero"`)
+	f(v.GetKey("1").GetIndex(0), `10`)
+	f(v.GetIndex(0), ``)
+	f(v.GetKey("missing"), ``)
+
+	// Array indexes
+	arr := v.GetKey("1")
+	f(arr.GetIndex(1), `20`)
+	f(arr.GetIndex(-1), `{"0":[30]}`)
+	f(arr.GetIndex(-3), `10`)
+	f(arr.GetIndex(3), ``)
+	f(arr.GetIndex(-4), ``)
+	f(arr.GetKey("0"), ``)
+
+	// Chaining
+	f(v.GetKey("1").GetIndex(-1).GetKey("0").GetIndex(0), `30`)
+	f(v.GetKey("a").GetIndex(0).GetIndex(-1), `"y"`)
+	f(v.GetKey("missing").GetIndex(0).GetKey("x"), ``)
+
+	// GetPath
+	f(v.GetPath(), v.String())
+	f(v.GetPath("0"), `"zero"`)
+	f(v.GetPath("1", -1, "0", 0), `30`)
+	f(v.GetPath("1", "0"), ``)
+	f(v.GetPath(1), ``)
+	f(v.GetPath("1", 1.5), ``)
+
+	// Get keeps treating numeric keys as array indexes for arrays.
+	f(v.Get("1", "0"), `10`)
+
+	// Scalars
+	f(v.GetKey("0").GetKey("0"), ``)
+	f(v.GetKey("0").GetIndex(0), ``)
+
+	// Paths from Find
+	path, vv := v.Find(func(v *Value) bool {
+		return v.Type() == TypeString && string(v.GetStringBytes()) == "y"
 	})
+	if vv == nil {
+		t.Fatalf("cannot find value")
+	}
+	if v.GetPath(path...) != vv {
+		t.Fatalf("GetPath must return the value found by Find for path %v", path)
+	}
+}
 
-	t.Run("empty-json", func(t *testing.T) {
-		_, err := p.Parse("")
-		if err == nil {
-			t.Fatalf("expecting non-nil error when parsing empty json")
-		}
-		_, err = p.Parse("\n\t    \n")
-		if err == nil {
+func TestValueGetStringCopy(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"foo":"b\nar","baz":[1,"x"]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	s := v.GetString("foo")
+	sx := v.GetString("baz", "1")
+	if str := v.GetString("baz", "0"); str != "" {
+		t.Fatalf("unexpected non-empty string for number: %q", str)
+	}
+	if str := v.GetString("missing"); str != "" {
+		t.Fatalf("unexpected non-empty string for missing key: %q", str)
+	}
+	b, err := v.Get("foo").StringBytesCopy([]byte("prefix_"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	bb, err := v.Get("baz").StringBytesCopy(b)
+	if err == nil {
+		t.Fatalf("expecting non-nil error for array")
+	}
+	if string(bb) != string(b) {
+		t.Fatalf("dst must be returned unchanged on error; got %q; want %q", bb, b)
+	}
+
+	// Overwrite the original buffer.
+	if _, err := p.Parse(`{"foo":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if s != "b\nar" {
+		t.Fatalf("unexpected string; got %q; want %q", s, "b\nar")
+	}
+	if sx != "x" {
+		t.Fatalf("unexpected string; got %q; want %q", sx, "x")
+	}
+	if string(b) != "prefix_b\nar" {
+		t.Fatalf("unexpected string; got %q; want %q", b, "prefix_b\nar")
+	}
+}
+
+func TestParserInternKeys(t *testing.T) {
+	var p Parser
+	p.InternKeys(true)
+
+	s := `[{"foo":1,"bar":"x"},{"foo":2,"bar":"y","b\"az":3},{}]`
+	v, err := p.Parse(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if str := v.String(); str != `[{"foo":1,"bar":"x"},{"foo":2,"bar":"y","b\"az":3},{}]` {
+		t.Fatalf("unexpected value; got %s", str)
+	}
+	if n := v.GetInt("1", "b\"az"); n != 3 {
+		t.Fatalf("unexpected value for escaped key; got %d; want %d", n, 3)
+	}
+
+	keyData := func(v *Value, n int) uintptr {
+		k := v.GetObject().kvs[n].k
+		return (*reflect.StringHeader)(unsafe.Pointer(&k)).Data
+	}
+	a := v.GetArray()
+	if keyData(a[0], 0) != keyData(a[1], 0) {
+		t.Fatalf("interned keys must share memory")
+	}
+	if !a[0].GetObject().keysInterned || a[1].GetObject().keysInterned {
+		t.Fatalf("unexpected keysInterned flags")
+	}
+
+	// Clone must share interned keys
+	vc := a[0].Clone()
+	if keyData(vc, 0) != keyData(a[0], 0) {
+		t.Fatalf("cloned interned keys must share memory")
+	}
+
+	// Interned keys must remain valid after the next Parse call
+	v, err = p.Parse(`{"aaaaaaaaaaaaaaaaaaa":"bbbbbbbbbbbbbbbbbbbbbbbbbb"}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if str := vc.String(); str != `{"foo":1,"bar":"x"}` {
+		t.Fatalf("unexpected cloned value; got %s", str)
+	}
+
+	// Adding new keys resets keysInterned
+	vc.Set("new", v)
+	if vc.GetObject().keysInterned {
+		t.Fatalf("keysInterned must be reset after adding new key")
+	}
+
+	p.InternKeys(false)
+	v, err = p.Parse(`{"foo":1}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v.GetObject().keysInterned {
+		t.Fatalf("keys mustn't be interned after disabling interning")
+	}
+}
+
+func TestParserPoolMaxRetainedBytes(t *testing.T) {
+	pp := NewParserPool(64 * 1024)
+
+	p := pp.Get()
+	if _, err := p.Parse(canadaFixture); err != nil {
+		t.Fatalf("cannot parse canada fixture: %s", err)
+	}
+	if n := p.MemoryFootprint(); n <= 64*1024 {
+		t.Fatalf("too small memory footprint for parsed canada fixture: %d bytes", n)
+	}
+	pp.Put(p)
+	for i := 0; i < 10; i++ {
+		if pp.Get() == p {
+			t.Fatalf("the oversized parser mustn't be retained by the pool")
+		}
+	}
+
+	p = pp.Get()
+	if _, err := p.Parse(`{"foo":"bar"}`); err != nil {
+		t.Fatalf("cannot parse small JSON: %s", err)
+	}
+	if n := p.MemoryFootprint(); n > 64*1024 {
+		t.Fatalf("too big memory footprint for small JSON: %d bytes", n)
+	}
+	pp.Put(p)
+
+	// The copy of the JSON kept for Raw must be included in the memory footprint.
+	p = pp.Get()
+	p.KeepRaw(true)
+	if _, err := p.Parse(`"` + strings.Repeat("x", 64*1024) + `"`); err != nil {
+		t.Fatalf("cannot parse long string: %s", err)
+	}
+	if n, nMin := p.MemoryFootprint(), 2*64*1024; n < nMin {
+		t.Fatalf("too small memory footprint with KeepRaw; got %d bytes; want at least %d bytes", n, nMin)
+	}
+	pp.Put(p)
+}
+
+func TestParserPoolStats(t *testing.T) {
+	// Stats are disabled for the zero value pool.
+	var ppZero ParserPool
+	ppZero.Put(ppZero.Get())
+	if ps := ppZero.Stats(); ps != (PoolStats{}) {
+		t.Fatalf("unexpected stats for the zero value pool; got %+v; want zero stats", ps)
+	}
+
+	pp := NewParserPoolWithStats(64 * 1024)
+
+	// Get parsers before putting them back, so all of them must be new.
+	ps := []*Parser{pp.Get(), pp.Get(), pp.Get()}
+	for _, p := range ps {
+		if _, err := p.Parse(`{"foo":[1,2,3]}`); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if _, err := ps[2].Parse(canadaFixture); err != nil {
+		t.Fatalf("cannot parse canada fixture: %s", err)
+	}
+	for _, p := range ps {
+		pp.Put(p)
+	}
+	stats := pp.Stats()
+	expectedStats := PoolStats{
+		Gets:     3,
+		Puts:     3,
+		News:     3,
+		Discards: 1,
+	}
+	if stats != expectedStats {
+		t.Fatalf("unexpected stats; got %+v; want %+v", stats, expectedStats)
+	}
+
+	// sync.Pool may drop the pooled parsers at any time,
+	// so only the upper bound for News is known.
+	for i := 0; i < 5; i++ {
+		pp.Put(pp.Get())
+	}
+	stats = pp.Stats()
+	if stats.Gets != 8 || stats.Puts != 8 || stats.Discards != 1 {
+		t.Fatalf("unexpected stats; got %+v; want Gets=8, Puts=8, Discards=1", stats)
+	}
+	if stats.News < 3 || stats.News > 8 {
+		t.Fatalf("unexpected News; got %d; want in the range [3..8]", stats.News)
+	}
+}
+
+func TestParserCap(t *testing.T) {
+	var p Parser
+	if n := p.BufferCap(); n != 0 {
+		t.Fatalf("unexpected BufferCap for new parser; got %d; want 0", n)
+	}
+	if n := p.ValueCacheCap(); n != 0 {
+		t.Fatalf("unexpected ValueCacheCap for new parser; got %d; want 0", n)
+	}
+	s := `{"foo":[1,2,3],"bar":"baz"}`
+	if _, err := p.Parse(s); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := p.BufferCap(); n < len(s) {
+		t.Fatalf("too small BufferCap; got %d; want at least %d", n, len(s))
+	}
+	// The object, the array with 3 items and the string.
+	if n := p.ValueCacheCap(); n < 6 {
+		t.Fatalf("too small ValueCacheCap; got %d; want at least %d", n, 6)
+	}
+	if n, nExpected := p.MemoryFootprint(), p.BufferCap()+p.ValueCacheCap()*int(unsafe.Sizeof(Value{})); n != nExpected {
+		t.Fatalf("unexpected MemoryFootprint; got %d; want %d", n, nExpected)
+	}
+}
+
+func TestValueNumberErrors(t *testing.T) {
+	f := func(name string, err, errExpected error) {
+		t.Helper()
+		if err == nil {
+			t.Fatalf("%s: expecting non-nil error", name)
+		}
+		if !isWrappedError(err, errExpected) {
+			t.Fatalf("%s: unexpected error %q; want wrapped %q", name, err, errExpected)
+		}
+	}
+
+	v := MustParse(`18446744073709551616`)
+	_, err := v.Int()
+	f("Int", err, fastfloat.ErrRange)
+	_, err = v.Uint()
+	f("Uint", err, fastfloat.ErrRange)
+	_, err = v.Int64()
+	f("Int64", err, fastfloat.ErrRange)
+	_, err = v.Uint64()
+	f("Uint64", err, fastfloat.ErrRange)
+
+	v = MustParse(`-1`)
+	_, err = v.Uint()
+	f("Uint", err, fastfloat.ErrSyntax)
+	_, err = v.Uint64()
+	f("Uint64", err, fastfloat.ErrSyntax)
+
+	var a Arena
+	v = a.NewNumberString("12x")
+	_, err = v.Int()
+	f("Int", err, fastfloat.ErrSyntax)
+	_, err = v.Uint()
+	f("Uint", err, fastfloat.ErrSyntax)
+	_, err = v.Int64()
+	f("Int64", err, fastfloat.ErrSyntax)
+	_, err = v.Uint64()
+	f("Uint64", err, fastfloat.ErrSyntax)
+	_, err = v.Float64()
+	f("Float64", err, fastfloat.ErrSyntax)
+
+	v = a.NewString("12x")
+	_, err = v.Float64FromString()
+	f("Float64FromString", err, fastfloat.ErrSyntax)
+
+	v = MustParse(`[1,18446744073709551616]`)
+	_, err = v.Int64Array(nil)
+	f("Int64Array", err, fastfloat.ErrRange)
+}
+
+func TestValueInvalidTypeConversion(t *testing.T) {
+	var p Parser
+
+	v, err := p.Parse(`[{},[],"",123.45,true,null]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	a := v.GetArray()
+
+	// object
+	_, err = a[0].Object()
+	if err != nil {
+		t.Fatalf("unexpected error when obtaining object: %s", err)
+	}
+	_, err = a[0].Array()
+	if err == nil {
+		t.Fatalf("expecting non-nil error when trying to obtain array from object")
+	}
+
+	// array
+	_, err = a[1].Array()
+	if err != nil {
+		t.Fatalf("unexpected error when obtaining array: %s", err)
+	}
+	_, err = a[1].Object()
+	if err == nil {
+		t.Fatalf("expecting non-nil error when trying to obtain object from array")
+	}
+
+	// string
+	_, err = a[2].StringBytes()
+	if err != nil {
+		t.Fatalf("unexpected error when obtaining string: %s", err)
+	}
+	_, err = a[2].Int()
+	if err == nil {
+		t.Fatalf("expecting non-nil error when trying to obtain int from string")
+	}
+	_, err = a[2].Int64()
+	if err == nil {
+		t.Fatalf("expecting non-nil error when trying to obtain int64 from string")
+	}
+	_, err = a[2].Uint()
+	if err == nil {
+		t.Fatalf("expecting non-nil error when trying to obtain uint from string")
+	}
+	_, err = a[2].Uint64()
+	if err == nil {
+		t.Fatalf("expecting non-nil error when trying to obtain uint64 from string")
+	}
+	_, err = a[2].Float64()
+	if err == nil {
+		t.Fatalf("expecting non-nil error when trying to obtain float64 from string")
+	}
+
+	// number
+	_, err = a[3].Float64()
+	if err != nil {
+		t.Fatalf("unexpected error when obtaining float64: %s", err)
+	}
+	_, err = a[3].StringBytes()
+	if err == nil {
+		t.Fatalf("expecting non-nil error when trying to obtain string from number")
+	}
+
+	// true
+	_, err = a[4].Bool()
+	if err != nil {
+		t.Fatalf("unexpected error when obtaining bool: %s", err)
+	}
+	_, err = a[4].StringBytes()
+	if err == nil {
+		t.Fatalf("expecting non-nil error when trying to obtain string from bool")
+	}
+
+	// null
+	_, err = a[5].Bool()
+	if err == nil {
+		t.Fatalf("expecting non-nil error when trying to obtain bool from null")
+	}
+}
+
+func TestValueGetTyped(t *testing.T) {
+	var p Parser
+
+	v, err := p.Parse(`{"foo": 123, "bar": "433", "baz": true, "obj":{}, "arr":[1,2,3],
+		"zero_float1": 0.00,
+		"zero_float2": -0e123,
+		"inf_float": Inf,
+		"minus_inf_float": -Inf,
+		"nan": nan
+	}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !v.Exists("foo") {
+		t.Fatalf("foo must exist in the v")
+	}
+	if v.Exists("foo", "bar") {
+		t.Fatalf("foo.bar mustn't exist in the v")
+	}
+	if v.Exists("foobar") {
+		t.Fatalf("foobar mustn't exist in the v")
+	}
+
+	o := v.GetObject("obj")
+	os := o.String()
+	if os != "{}" {
+		t.Fatalf("unexpected object; got %s; want %s", os, "{}")
+	}
+	o = v.GetObject("arr")
+	if o != nil {
+		t.Fatalf("unexpected non-nil object: %s", o)
+	}
+	o = v.GetObject("foo", "bar")
+	if o != nil {
+		t.Fatalf("unexpected non-nil object: %s", o)
+	}
+	a := v.GetArray("arr")
+	if len(a) != 3 {
+		t.Fatalf("unexpected array len; got %d; want %d", len(a), 3)
+	}
+	a = v.GetArray("obj")
+	if a != nil {
+		t.Fatalf("unexpected non-nil array: %s", a)
+	}
+	a = v.GetArray("foo", "bar")
+	if a != nil {
+		t.Fatalf("unexpected non-nil array: %s", a)
+	}
+	n := v.GetInt("foo")
+	if n != 123 {
+		t.Fatalf("unexpected value; got %d; want %d", n, 123)
+	}
+	n64 := v.GetInt64("foo")
+	if n != 123 {
+		t.Fatalf("unexpected value; got %d; want %d", n64, 123)
+	}
+	un := v.GetUint("foo")
+	if un != 123 {
+		t.Fatalf("unexpected value; got %d; want %d", un, 123)
+	}
+	un64 := v.GetUint64("foo")
+	if un != 123 {
+		t.Fatalf("unexpected value; got %d; want %d", un64, 123)
+	}
+	n = v.GetInt("bar")
+	if n != 0 {
+		t.Fatalf("unexpected non-zero value; got %d", n)
+	}
+	n64 = v.GetInt64("bar")
+	if n != 0 {
+		t.Fatalf("unexpected non-zero value; got %d", n64)
+	}
+	un = v.GetUint("bar")
+	if n != 0 {
+		t.Fatalf("unexpected non-zero value; got %d", un)
+	}
+	un64 = v.GetUint64("bar")
+	if n != 0 {
+		t.Fatalf("unexpected non-zero value; got %d", n64)
+	}
+	f := v.GetFloat64("foo")
+	if f != 123.0 {
+		t.Fatalf("unexpected value; got %f; want %f", f, 123.0)
+	}
+	f = v.GetFloat64("bar")
+	if f != 0 {
+		t.Fatalf("unexpected value; got %f; want %f", f, 0.0)
+	}
+	f = v.GetFloat64("foooo", "bar")
+	if f != 0 {
+		t.Fatalf("unexpected value; got %f; want %f", f, 0.0)
+	}
+	f = v.GetFloat64()
+	if f != 0 {
+		t.Fatalf("unexpected value; got %f; want %f", f, 0.0)
+	}
+	sb := v.GetStringBytes("bar")
+	if string(sb) != "433" {
+		t.Fatalf("unexpected value; got %q; want %q", sb, "443")
+	}
+	sb = v.GetStringBytes("foo")
+	if sb != nil {
+		t.Fatalf("unexpected value; got %q; want %q", sb, []byte(nil))
+	}
+	bv := v.GetBool("baz")
+	if !bv {
+		t.Fatalf("unexpected value; got %v; want %v", bv, true)
+	}
+	bv = v.GetBool("bar")
+	if bv {
+		t.Fatalf("unexpected value; got %v; want %v", bv, false)
+	}
+
+	zv := v.Get("zero_float1")
+	zf, err := zv.Float64()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if zf != 0 {
+		t.Fatalf("unexpected zero_float1 value: %f. Expecting 0", zf)
+	}
+
+	zv = v.Get("zero_float2")
+	zf, err = zv.Float64()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if zf != 0 {
+		t.Fatalf("unexpected zero_float1 value: %f. Expecting 0", zf)
+	}
+
+	infv := v.Get("inf_float")
+	inff, err := infv.Float64()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !math.IsInf(inff, 1) {
+		t.Fatalf("unexpected inf_float value: %f. Expecting %f", inff, math.Inf(1))
+	}
+
+	ninfv := v.Get("minus_inf_float")
+	ninff, err := ninfv.Float64()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !math.IsInf(ninff, -1) {
+		t.Fatalf("unexpected inf_float value: %f. Expecting %f", ninff, math.Inf(-11))
+	}
+
+	nanv := v.Get("nan")
+	nanf, err := nanv.Float64()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !math.IsNaN(nanf) {
+		t.Fatalf("unexpected nan value: %f. Expecting %f", nanf, math.NaN())
+	}
+}
+
+func TestValueFloat64Strict(t *testing.T) {
+	var p Parser
+	f := func(s string, expectedNum float64) {
+		t.Helper()
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		n, err := v.Float64Strict()
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		if n != expectedNum {
+			t.Fatalf("unexpected number for %q; got %v; want %v", s, n, expectedNum)
+		}
+	}
+	f("0", 0)
+	f("-12.5e2", -1250)
+	f("1e400", math.Inf(1))
+
+	// The parser accepts the following numbers, while they are invalid JSON.
+	for _, s := range []string{"0123", "1.", "1.e4", ".5", "-inf", "+Inf", "NaN"} {
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		if _, err := v.Float64(); err != nil {
+			t.Fatalf("unexpected error in Float64 for %q: %s", s, err)
+		}
+		if _, err := v.Float64Strict(); err == nil {
+			t.Fatalf("expecting non-nil error in Float64Strict for %q", s)
+		}
+	}
+
+	v, err := p.Parse(`"123"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := v.Float64Strict(); err == nil {
+		t.Fatalf("expecting non-nil error for string value")
+	}
+}
+
+func TestValueFloat64FromString(t *testing.T) {
+	var p Parser
+	f := func(s string, expectedNum float64, expectedNumTrim float64) {
+		t.Helper()
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		n, err := v.Float64FromString()
+		if err != nil {
+			t.Fatalf("unexpected error in Float64FromString for %q: %s", s, err)
+		}
+		if n != expectedNum {
+			t.Fatalf("unexpected number for %q; got %v; want %v", s, n, expectedNum)
+		}
+		n, err = v.Float64FromStringTrim()
+		if err != nil {
+			t.Fatalf("unexpected error in Float64FromStringTrim for %q: %s", s, err)
+		}
+		if n != expectedNumTrim {
+			t.Fatalf("unexpected trimmed number for %q; got %v; want %v", s, n, expectedNumTrim)
+		}
+	}
+	f(`"42"`, 42, 42)
+	f(`"-1.5e3"`, -1500, -1500)
+	f(`"\u0031\u0032"`, 12, 12)
+	f(`123.5`, 123.5, 123.5)
+
+	fTrim := func(s string, expectedNum float64) {
+		t.Helper()
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		if _, err := v.Float64FromString(); err == nil {
+			t.Fatalf("expecting non-nil error in Float64FromString for %q", s)
+		}
+		n, err := v.Float64FromStringTrim()
+		if err != nil {
+			t.Fatalf("unexpected error in Float64FromStringTrim for %q: %s", s, err)
+		}
+		if n != expectedNum {
+			t.Fatalf("unexpected trimmed number for %q; got %v; want %v", s, n, expectedNum)
+		}
+	}
+	fTrim(`"42 "`, 42)
+	fTrim(`" 3.14"`, 3.14)
+	fTrim(`"\t-7\n"`, -7)
+
+	ferr := func(s string) {
+		t.Helper()
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		if _, err := v.Float64FromString(); err == nil {
+			t.Fatalf("expecting non-nil error in Float64FromString for %q", s)
+		}
+		if _, err := v.Float64FromStringTrim(); err == nil {
+			t.Fatalf("expecting non-nil error in Float64FromStringTrim for %q", s)
+		}
+	}
+	ferr(`"1,000"`)
+	ferr(`"1 000"`)
+	ferr(`" 4 2 "`)
+	ferr(`""`)
+	ferr(`"  "`)
+	ferr(`"foo"`)
+	ferr(`null`)
+	ferr(`true`)
+	ferr(`[1]`)
+	ferr(`{"a":1}`)
+}
+
+func TestObjectVisitPrefix(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"attr.x":1,"id":2,"\u0061ttr.y":3,"meta.attr.z":4,"attr.\u0078":5,"ATTR.w":6,"attr":7}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	o := v.GetObject()
+
+	var keys []string
+	var sum int
+	o.VisitPrefix("attr.", func(key []byte, v *Value) {
+		keys = append(keys, string(key))
+		sum += v.GetInt()
+	})
+	keysExpected := []string{"attr.x", "attr.y", "attr.x"}
+	if !reflect.DeepEqual(keys, keysExpected) {
+		t.Fatalf("unexpected keys visited; got %q; want %q", keys, keysExpected)
+	}
+	if sum != 9 {
+		t.Fatalf("unexpected sum of visited values; got %d; want %d", sum, 9)
+	}
+
+	bKeys := o.KeysWithPrefix(nil, "attr.")
+	keys = keys[:0]
+	for _, key := range bKeys {
+		keys = append(keys, string(key))
+	}
+	if !reflect.DeepEqual(keys, keysExpected) {
+		t.Fatalf("unexpected keys with prefix; got %q; want %q", keys, keysExpected)
+	}
+
+	// KeysWithPrefix must append to dst.
+	bKeys = o.KeysWithPrefix(bKeys[:1], "meta.")
+	if len(bKeys) != 2 || string(bKeys[0]) != "attr.x" || string(bKeys[1]) != "meta.attr.z" {
+		t.Fatalf("unexpected keys; got %q; want %q", bKeys, []string{"attr.x", "meta.attr.z"})
+	}
+
+	// Empty prefix matches all the keys.
+	if bKeys := o.KeysWithPrefix(nil, ""); len(bKeys) != o.Len() {
+		t.Fatalf("unexpected number of keys for empty prefix; got %d; want %d", len(bKeys), o.Len())
+	}
+
+	// Missing prefix.
+	o.VisitPrefix("foo", func(key []byte, v *Value) {
+		t.Fatalf("unexpected key visited: %q", key)
+	})
+	if bKeys := o.KeysWithPrefix(nil, "foo"); len(bKeys) != 0 {
+		t.Fatalf("unexpected keys for missing prefix: %q", bKeys)
+	}
+
+	// nil object.
+	var oNil *Object
+	oNil.VisitPrefix("", func(key []byte, v *Value) {
+		t.Fatalf("unexpected key visited: %q", key)
+	})
+	if bKeys := oNil.KeysWithPrefix(nil, ""); len(bKeys) != 0 {
+		t.Fatalf("unexpected keys for nil object: %q", bKeys)
+	}
+}
+
+func TestVisitNil(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	o := v.GetObject("non-existing-key")
+	if o != nil {
+		t.Fatalf("obtained an object for non-existing key: %#v", o)
+	}
+	o.Visit(func(k []byte, v *Value) {
+		t.Fatalf("unexpected visit call; k=%q; v=%s", k, v)
+	})
+}
+
+func TestValueGet(t *testing.T) {
+	var pp ParserPool
+
+	p := pp.Get()
+	v, err := p.ParseBytes([]byte(`{"xx":33.33,"foo":[123,{"bar":["baz"],"x":"y"}], "": "empty-key", "empty-value": ""}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	t.Run("positive", func(t *testing.T) {
+		sb := v.GetStringBytes("")
+		if string(sb) != "empty-key" {
+			t.Fatalf("unexpected value for empty key; got %q; want %q", sb, "empty-key")
+		}
+		sb = v.GetStringBytes("empty-value")
+		if string(sb) != "" {
+			t.Fatalf("unexpected non-empty value: %q", sb)
+		}
+
+		vv := v.Get("foo", "1")
+		if vv == nil {
+			t.Fatalf("cannot find the required value")
+		}
+		o, err := vv.Object()
+		if err != nil {
+			t.Fatalf("cannot obtain object: %s", err)
+		}
+
+		n := 0
+		o.Visit(func(k []byte, v *Value) {
+			n++
+			switch string(k) {
+			case "bar":
+				if v.Type() != TypeArray {
+					t.Fatalf("unexpected value type; got %d; want %d", v.Type(), TypeArray)
+				}
+				s := v.String()
+				if s != `["baz"]` {
+					t.Fatalf("unexpected array; got %q; want %q", s, `["baz"]`)
+				}
+			case "x":
+				sb, err := v.StringBytes()
+				if err != nil {
+					t.Fatalf("cannot obtain string: %s", err)
+				}
+				if string(sb) != "y" {
+					t.Fatalf("unexpected string; got %q; want %q", sb, "y")
+				}
+			default:
+				t.Fatalf("unknown key: %s", k)
+			}
+		})
+		if n != 2 {
+			t.Fatalf("unexpected number of items visited in the array; got %d; want %d", n, 2)
+		}
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		vv := v.Get("nonexisting", "path")
+		if vv != nil {
+			t.Fatalf("expecting nil value for nonexisting path. Got %#v", vv)
+		}
+		vv = v.Get("foo", "bar", "baz")
+		if vv != nil {
+			t.Fatalf("expecting nil value for nonexisting path. Got %#v", vv)
+		}
+		vv = v.Get("foo", "-123")
+		if vv != nil {
+			t.Fatalf("expecting nil value for nonexisting path. Got %#v", vv)
+		}
+		vv = v.Get("foo", "234")
+		if vv != nil {
+			t.Fatalf("expecting nil value for nonexisting path. Got %#v", vv)
+		}
+		vv = v.Get("xx", "yy")
+		if vv != nil {
+			t.Fatalf("expecting nil value for nonexisting path. Got %#v", vv)
+		}
+	})
+
+	pp.Put(p)
+}
+
+func TestParserParse(t *testing.T) {
+	var p Parser
+
+	t.Run("complex-string", func(t *testing.T) {
+		v, err := p.Parse(`{"тест":1, "\\\"фыва\"":2, "\\\"\u1234x":"\\fЗУ\\\\"}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		n := v.GetInt("тест")
+		if n != 1 {
+			t.Fatalf("unexpected int; got %d; want %d", n, 1)
+		}
+		n = v.GetInt(`\"фыва"`)
+		if n != 2 {
+			t.Fatalf("unexpected int; got %d; want %d", n, 2)
+		}
+		sb := v.GetStringBytes("\\\"\u1234x")
+		if string(sb) != `\fЗУ\\` {
+			t.Fatalf("unexpected string; got %q; want %q", sb, `\fЗУ\\`)
+		}
+	})
+
+	t.Run("invalid-string-escape", func(t *testing.T) {
+		v, err := p.Parse(`"fo\u"`)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing string")
+		}
+		// Make sure only valid string part remains
+		sb, err := v.StringBytes()
+		if err != nil {
+			t.Fatalf("cannot obtain string: %s", err)
+		}
+		if string(sb) != "fo\\u" {
+			t.Fatalf("unexpected string; got %q; want %q", sb, "fo\\u")
+		}
+
+		v, err = p.Parse(`"foo\ubarz2134"`)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing string")
+		}
+		sb, err = v.StringBytes()
+		if err != nil {
+			t.Fatalf("cannot obtain string: %s", err)
+		}
+		if string(sb) != "foo\\ubarz2134" {
+			t.Fatalf("unexpected string; got %q; want %q", sb, "foo")
+		}
+
+		v, err = p.Parse(`"fo` + "\x19" + `\u"`)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing string")
+		}
+		sb, err = v.StringBytes()
+		if err != nil {
+			t.Fatalf("cannot obtain string: %s", err)
+		}
+		if string(sb) != "fo\x19\\u" {
+			t.Fatalf("unexpected string; got %q; want %q", sb, "fo\x19\\u")
+		}
+	})
+
+	t.Run("invalid-number", func(t *testing.T) {
+		v, err := p.Parse("123+456")
+		if err != nil {
+			t.Fatalf("unexpected error when parsing int")
+		}
+
+		// Make sure invalid int isn't parsed.
+		n, err := v.Int()
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if n != 0 {
+			t.Fatalf("unexpected int; got %d; want %d", n, 0)
+		}
+	})
+
+	t.Run("empty-json", func(t *testing.T) {
+		_, err := p.Parse("")
+		if err == nil {
+			t.Fatalf("expecting non-nil error when parsing empty json")
+		}
+		_, err = p.Parse("\n\t    \n")
+		if err == nil {
 			t.Fatalf("expecting non-nil error when parsing empty json")
 		}
 	})
@@ -840,438 +2148,1637 @@ func TestParserParse(t *testing.T) {
 	t.Run("integer", func(t *testing.T) {
 		v, err := p.Parse("12345")
 		if err != nil {
-			t.Fatalf("cannot parse integer: %s", err)
+			t.Fatalf("cannot parse integer: %s", err)
+		}
+		tp := v.Type()
+		if tp != TypeNumber || tp.String() != "number" {
+			t.Fatalf("unexpected type obtained for integer: %#v", v)
+		}
+		n, err := v.Int()
+		if err != nil {
+			t.Fatalf("cannot obtain int: %s", err)
+		}
+		if n != 12345 {
+			t.Fatalf("unexpected value obtained for integer; got %d; want %d", n, 12345)
+		}
+		s := v.String()
+		if s != "12345" {
+			t.Fatalf("unexpected string representation of integer; got %q; want %q", s, "12345")
+		}
+	})
+
+	t.Run("int64", func(t *testing.T) {
+		v, err := p.Parse("-8838840643388017390")
+		if err != nil {
+			t.Fatalf("cannot parse int64: %s", err)
+		}
+		tp := v.Type()
+		if tp != TypeNumber || tp.String() != "number" {
+			t.Fatalf("unexpected type obtained for int64: %#v", v)
+		}
+		n, err := v.Int64()
+		if err != nil {
+			t.Fatalf("cannot obtain int64: %s", err)
+		}
+		if n != int64(-8838840643388017390) {
+			t.Fatalf("unexpected value obtained for int64; got %d; want %d", n, int64(-8838840643388017390))
+		}
+		s := v.String()
+		if s != "-8838840643388017390" {
+			t.Fatalf("unexpected string representation of int64; got %q; want %q", s, "-8838840643388017390")
+		}
+	})
+
+	t.Run("uint", func(t *testing.T) {
+		v, err := p.Parse("18446744073709551615")
+		if err != nil {
+			t.Fatalf("cannot parse uint: %s", err)
+		}
+		tp := v.Type()
+		if tp != TypeNumber || tp.String() != "number" {
+			t.Fatalf("unexpected type obtained for uint: %#v", v)
+		}
+		n, err := v.Uint64()
+		if err != nil {
+			t.Fatalf("cannot obtain uint64: %s", err)
+		}
+		if n != uint64(18446744073709551615) {
+			t.Fatalf("unexpected value obtained for uint; got %d; want %d", n, uint64(18446744073709551615))
+		}
+		s := v.String()
+		if s != "18446744073709551615" {
+			t.Fatalf("unexpected string representation of uint; got %q; want %q", s, "18446744073709551615")
+		}
+	})
+
+	t.Run("uint64", func(t *testing.T) {
+		v, err := p.Parse("18446744073709551615")
+		if err != nil {
+			t.Fatalf("cannot parse uint64: %s", err)
+		}
+		tp := v.Type()
+		if tp != TypeNumber || tp.String() != "number" {
+			t.Fatalf("unexpected type obtained for uint64: %#v", v)
+		}
+		n, err := v.Uint64()
+		if err != nil {
+			t.Fatalf("cannot obtain uint64: %s", err)
+		}
+		if n != 18446744073709551615 {
+			t.Fatalf("unexpected value obtained for uint64; got %d; want %d", n, uint64(18446744073709551615))
+		}
+		s := v.String()
+		if s != "18446744073709551615" {
+			t.Fatalf("unexpected string representation of uint64; got %q; want %q", s, "18446744073709551615")
+		}
+	})
+
+	t.Run("float", func(t *testing.T) {
+		v, err := p.Parse("-12.345")
+		if err != nil {
+			t.Fatalf("cannot parse integer: %s", err)
+		}
+		n, err := v.Float64()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		tp := v.Type()
+		if tp != TypeNumber || tp.String() != "number" {
+			t.Fatalf("unexpected type obtained for integer: %#v", v)
+		}
+		if n != -12.345 {
+			t.Fatalf("unexpected value obtained for integer; got %f; want %f", n, -12.345)
+		}
+		s := v.String()
+		if s != "-12.345" {
+			t.Fatalf("unexpected string representation of integer; got %q; want %q", s, "-12.345")
+		}
+	})
+
+	t.Run("string", func(t *testing.T) {
+		v, err := p.Parse(`"foo bar"`)
+		if err != nil {
+			t.Fatalf("cannot parse string: %s", err)
+		}
+		tp := v.Type()
+		if tp != TypeString || tp.String() != "string" {
+			t.Fatalf("unexpected type obtained for string: %#v", v)
+		}
+		sb, err := v.StringBytes()
+		if err != nil {
+			t.Fatalf("cannot obtain string: %s", err)
+		}
+		if string(sb) != "foo bar" {
+			t.Fatalf("unexpected value obtained for string; got %q; want %q", sb, "foo bar")
+		}
+		ss := v.String()
+		if ss != `"foo bar"` {
+			t.Fatalf("unexpected string representation of string; got %q; want %q", ss, `"foo bar"`)
+		}
+	})
+
+	t.Run("string-escaped", func(t *testing.T) {
+		v, err := p.Parse(`"\n\t\\foo\"bar\u3423x\/\b\f\r\\"`)
+		if err != nil {
+			t.Fatalf("cannot parse string: %s", err)
+		}
+		tp := v.Type()
+		if tp != TypeString {
+			t.Fatalf("unexpected type obtained for string: %#v", v)
+		}
+		sb, err := v.StringBytes()
+		if err != nil {
+			t.Fatalf("cannot obtain string: %s", err)
+		}
+		if string(sb) != "\n\t\\foo\"bar\u3423x/\b\f\r\\" {
+			t.Fatalf("unexpected value obtained for string; got %q; want %q", sb, "\n\t\\foo\"bar\u3423x/\b\f\r\\")
+		}
+		ss := v.String()
+		if ss != `"\n\t\\foo\"bar㐣x/\b\f\r\\"` {
+			t.Fatalf("unexpected string representation of string; got %q; want %q", ss, `"\n\t\\foo\"bar㐣x/\b\f\r\\"`)
+		}
+	})
+
+	t.Run("object-one-element", func(t *testing.T) {
+		v, err := p.Parse(`  {
+	"foo"   : "bar"  }	 `)
+		if err != nil {
+			t.Fatalf("cannot parse object: %s", err)
+		}
+		tp := v.Type()
+		if tp != TypeObject {
+			t.Fatalf("unexpected type obtained for object: %#v", v)
+		}
+		o, err := v.Object()
+		if err != nil {
+			t.Fatalf("cannot obtain object: %s", err)
+		}
+		vv := o.Get("foo")
+		if vv.Type() != TypeString {
+			t.Fatalf("unexpected type for foo item: got %d; want %d", vv.Type(), TypeString)
+		}
+		vv = o.Get("non-existing key")
+		if vv != nil {
+			t.Fatalf("unexpected value obtained for non-existing key: %#v", vv)
+		}
+
+		s := v.String()
+		if s != `{"foo":"bar"}` {
+			t.Fatalf("unexpected string representation for object; got %q; want %q", s, `{"foo":"bar"}`)
+		}
+	})
+
+	t.Run("object-multi-elements", func(t *testing.T) {
+		v, err := p.Parse(`{"foo": [1,2,3  ]  ,"bar":{},"baz":123.456}`)
+		if err != nil {
+			t.Fatalf("cannot parse object: %s", err)
+		}
+		tp := v.Type()
+		if tp != TypeObject {
+			t.Fatalf("unexpected type obtained for object: %#v", v)
+		}
+		o, err := v.Object()
+		if err != nil {
+			t.Fatalf("cannot obtain object: %s", err)
+		}
+		vv := o.Get("foo")
+		if vv.Type() != TypeArray {
+			t.Fatalf("unexpected type for foo item; got %d; want %d", vv.Type(), TypeArray)
+		}
+		vv = o.Get("bar")
+		if vv.Type() != TypeObject {
+			t.Fatalf("unexpected type for bar item; got %d; want %d", vv.Type(), TypeObject)
+		}
+		vv = o.Get("baz")
+		if vv.Type() != TypeNumber {
+			t.Fatalf("unexpected type for baz item; got %d; want %d", vv.Type(), TypeNumber)
+		}
+		vv = o.Get("non-existing-key")
+		if vv != nil {
+			t.Fatalf("unexpected value obtained for non-existing key: %#v", vv)
+		}
+
+		s := v.String()
+		if s != `{"foo":[1,2,3],"bar":{},"baz":123.456}` {
+			t.Fatalf("unexpected string representation for object; got %q; want %q", s, `{"foo":[1,2,3],"bar":{},"baz":123.456}`)
+		}
+	})
+
+	t.Run("array-one-element", func(t *testing.T) {
+		v, err := p.Parse(`   [{"bar":[  [],[[]]   ]} ]  `)
+		if err != nil {
+			t.Fatalf("cannot parse array: %s", err)
 		}
 		tp := v.Type()
-		if tp != TypeNumber || tp.String() != "number" {
-			t.Fatalf("unexpected type obtained for integer: %#v", v)
+		if tp != TypeArray {
+			t.Fatalf("unexpected type obtained for array: %#v", v)
 		}
-		n, err := v.Int()
+		a, err := v.Array()
 		if err != nil {
-			t.Fatalf("cannot obtain int: %s", err)
+			t.Fatalf("unexpected error: %s", err)
 		}
-		if n != 12345 {
-			t.Fatalf("unexpected value obtained for integer; got %d; want %d", n, 12345)
+		if len(a) != 1 {
+			t.Fatalf("unexpected array len; got %d; want %d", len(a), 1)
 		}
+		if a[0].Type() != TypeObject {
+			t.Fatalf("unexpected type for a[0]; got %d; want %d", a[0].Type(), TypeObject)
+		}
+
 		s := v.String()
-		if s != "12345" {
-			t.Fatalf("unexpected string representation of integer; got %q; want %q", s, "12345")
+		if s != `[{"bar":[[],[[]]]}]` {
+			t.Fatalf("unexpected string representation for array; got %q; want %q", s, `[{"bar":[[],[[]]]}]`)
 		}
 	})
 
-	t.Run("int64", func(t *testing.T) {
-		v, err := p.Parse("-8838840643388017390")
+	t.Run("array-multi-elements", func(t *testing.T) {
+		v, err := p.Parse(`   [1,"foo",{"bar":[     ],"baz":""}    ,[  "x" ,	"y"   ]     ]   `)
 		if err != nil {
-			t.Fatalf("cannot parse int64: %s", err)
+			t.Fatalf("cannot parse array: %s", err)
 		}
 		tp := v.Type()
-		if tp != TypeNumber || tp.String() != "number" {
-			t.Fatalf("unexpected type obtained for int64: %#v", v)
+		if tp != TypeArray {
+			t.Fatalf("unexpected type obtained for array: %#v", v)
 		}
-		n, err := v.Int64()
+		a, err := v.Array()
 		if err != nil {
-			t.Fatalf("cannot obtain int64: %s", err)
+			t.Fatalf("unexpected error: %s", err)
 		}
-		if n != int64(-8838840643388017390) {
-			t.Fatalf("unexpected value obtained for int64; got %d; want %d", n, int64(-8838840643388017390))
+		if len(a) != 4 {
+			t.Fatalf("unexpected array len; got %d; want %d", len(a), 4)
+		}
+		if a[0].Type() != TypeNumber {
+			t.Fatalf("unexpected type for a[0]; got %d; want %d", a[0].Type(), TypeNumber)
+		}
+		if a[1].Type() != TypeString {
+			t.Fatalf("unexpected type for a[1]; got %d; want %d", a[1].Type(), TypeString)
 		}
+		if a[2].Type() != TypeObject {
+			t.Fatalf("unexpected type for a[2]; got %d; want %d", a[2].Type(), TypeObject)
+		}
+		if a[3].Type() != TypeArray {
+			t.Fatalf("unexpected type for a[3]; got %d; want %d", a[3].Type(), TypeArray)
+		}
+
 		s := v.String()
-		if s != "-8838840643388017390" {
-			t.Fatalf("unexpected string representation of int64; got %q; want %q", s, "-8838840643388017390")
+		if s != `[1,"foo",{"bar":[],"baz":""},["x","y"]]` {
+			t.Fatalf("unexpected string representation for array; got %q; want %q", s, `[1,"foo",{"bar":[],"baz":""},["x","y"]]`)
 		}
 	})
 
-	t.Run("uint", func(t *testing.T) {
-		v, err := p.Parse("18446744073709551615")
+	t.Run("complex-object", func(t *testing.T) {
+		s := `{"foo":[-1.345678,[[[[[]]]],{}],"bar"],"baz":{"bbb":123}}`
+		v, err := p.Parse(s)
 		if err != nil {
-			t.Fatalf("cannot parse uint: %s", err)
+			t.Fatalf("cannot parse complex object: %s", err)
 		}
-		tp := v.Type()
-		if tp != TypeNumber || tp.String() != "number" {
-			t.Fatalf("unexpected type obtained for uint: %#v", v)
+		if v.Type() != TypeObject {
+			t.Fatalf("unexpected type obtained for object: %#v", v)
 		}
-		n, err := v.Uint64()
-		if err != nil {
-			t.Fatalf("cannot obtain uint64: %s", err)
+
+		ss := v.String()
+		if ss != s {
+			t.Fatalf("unexpected string representation for object; got %q; want %q", ss, s)
 		}
-		if n != uint64(18446744073709551615) {
-			t.Fatalf("unexpected value obtained for uint; got %d; want %d", n, uint64(18446744073709551615))
+
+		s = strings.TrimSpace(largeFixture)
+		v, err = p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse largeFixture: %s", err)
 		}
-		s := v.String()
-		if s != "18446744073709551615" {
-			t.Fatalf("unexpected string representation of uint; got %q; want %q", s, "18446744073709551615")
+		ss = v.String()
+		if ss != s {
+			t.Fatalf("unexpected string representation for object; got\n%q; want\n%q", ss, s)
 		}
 	})
 
-	t.Run("uint64", func(t *testing.T) {
-		v, err := p.Parse("18446744073709551615")
-		if err != nil {
-			t.Fatalf("cannot parse uint64: %s", err)
+	t.Run("complex-object-visit-all", func(t *testing.T) {
+		n := 0
+		var f func(k []byte, v *Value)
+		f = func(k []byte, v *Value) {
+			switch v.Type() {
+			case TypeObject:
+				o, err := v.Object()
+				if err != nil {
+					t.Fatalf("cannot obtain object: %s", err)
+				}
+				o.Visit(f)
+			case TypeArray:
+				a, err := v.Array()
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				for _, vv := range a {
+					f(nil, vv)
+				}
+			case TypeString:
+				sb, err := v.StringBytes()
+				if err != nil {
+					t.Fatalf("cannot obtain string: %s", err)
+				}
+				n += len(sb)
+			case TypeNumber:
+				nn, err := v.Int()
+				if err != nil {
+					t.Fatalf("cannot obtain int: %s", err)
+				}
+				n += nn
+			}
 		}
-		tp := v.Type()
-		if tp != TypeNumber || tp.String() != "number" {
-			t.Fatalf("unexpected type obtained for uint64: %#v", v)
+
+		s := strings.TrimSpace(largeFixture)
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse largeFixture: %s", err)
 		}
-		n, err := v.Uint64()
+		o, err := v.Object()
 		if err != nil {
-			t.Fatalf("cannot obtain uint64: %s", err)
+			t.Fatalf("cannot obtain object: %s", err)
 		}
-		if n != 18446744073709551615 {
-			t.Fatalf("unexpected value obtained for uint64; got %d; want %d", n, uint64(18446744073709551615))
+		o.Visit(f)
+
+		if n != 21473 {
+			t.Fatalf("unexpected n; got %d; want %d", n, 21473)
 		}
-		s := v.String()
-		if s != "18446744073709551615" {
-			t.Fatalf("unexpected string representation of uint64; got %q; want %q", s, "18446744073709551615")
+
+		// Make sure the json remains valid after visiting all the items.
+		ss := v.String()
+		if ss != s {
+			t.Fatalf("unexpected string representation for object; got\n%q; want\n%q", ss, s)
 		}
+
 	})
+}
 
-	t.Run("float", func(t *testing.T) {
-		v, err := p.Parse("-12.345")
-		if err != nil {
-			t.Fatalf("cannot parse integer: %s", err)
+func TestParseBigObject(t *testing.T) {
+	const itemsCount = 10000
+
+	// build big json object
+	var ss []string
+	for i := 0; i < itemsCount; i++ {
+		s := fmt.Sprintf(`"key_%d": "value_%d"`, i, i)
+		ss = append(ss, s)
+	}
+	s := "{" + strings.Join(ss, ",") + "}"
+
+	// parse it
+	var p Parser
+	v, err := p.Parse(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Look up object items
+	for i := 0; i < itemsCount; i++ {
+		k := fmt.Sprintf("key_%d", i)
+		expectedV := fmt.Sprintf("value_%d", i)
+		sb := v.GetStringBytes(k)
+		if string(sb) != expectedV {
+			t.Fatalf("unexpected value obtained; got %q; want %q", sb, expectedV)
 		}
-		n, err := v.Float64()
+	}
+
+	// verify non-existing key returns nil
+	sb := v.GetStringBytes("non-existing-key")
+	if sb != nil {
+		t.Fatalf("unexpected non-nil value for non-existing-key: %q", sb)
+	}
+}
+
+func TestParseGetConcurrent(t *testing.T) {
+	concurrency := 10
+	ch := make(chan error, concurrency)
+	s := `{"foo": "bar", "empty_obj": {}}`
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			ch <- testParseGetSerial(s)
+		}()
+	}
+	for i := 0; i < concurrency; i++ {
+		select {
+		case err := <-ch:
+			if err != nil {
+				t.Fatalf("unexpected error during concurrent test: %s", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout")
+		}
+	}
+}
+
+func testParseGetSerial(s string) error {
+	var p Parser
+	for i := 0; i < 100; i++ {
+		v, err := p.Parse(s)
 		if err != nil {
-			t.Fatalf("unexpected error: %s", err)
+			return fmt.Errorf("cannot parse %q: %s", s, err)
 		}
-		tp := v.Type()
-		if tp != TypeNumber || tp.String() != "number" {
-			t.Fatalf("unexpected type obtained for integer: %#v", v)
+		sb := v.GetStringBytes("foo")
+		if string(sb) != "bar" {
+			return fmt.Errorf("unexpected value for key=%q; got %q; want %q", "foo", sb, "bar")
 		}
-		if n != -12.345 {
-			t.Fatalf("unexpected value obtained for integer; got %f; want %f", n, -12.345)
+		vv := v.Get("empty_obj", "non-existing-key")
+		if vv != nil {
+			return fmt.Errorf("unexpected non-nil value got: %s", vv)
 		}
-		s := v.String()
-		if s != "-12.345" {
-			t.Fatalf("unexpected string representation of integer; got %q; want %q", s, "-12.345")
+	}
+	return nil
+}
+
+func TestValueNormalize(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"foo":["bar",{"x\ny":"z\\"}],"n":1.5e3}`)
+	if err != nil {
+		t.Fatalf("cannot parse JSON: %s", err)
+	}
+	v.Normalize()
+
+	var check func(v *Value)
+	check = func(v *Value) {
+		t.Helper()
+		switch v.t {
+		case typeRawString:
+			t.Fatalf("unexpected raw string %q after Normalize", v.s)
+		case TypeObject:
+			if !v.o.keysUnescaped {
+				t.Fatalf("unexpected escaped keys in %s after Normalize", v)
+			}
+			for _, kv := range v.o.kvs {
+				check(kv.v)
+			}
+		case TypeArray:
+			for _, vv := range v.a {
+				check(vv)
+			}
+		case TypeNumber:
+			if NumberKind(v.nk) == NumberInvalid {
+				t.Fatalf("unexpected unknown number kind for %s after Normalize", v)
+			}
+		}
+	}
+	check(v)
+
+	if s := v.GetString("foo", "0"); s != "bar" {
+		t.Fatalf("unexpected string; got %q; want %q", s, "bar")
+	}
+	if s := v.GetString("foo", "1", "x\ny"); s != `z\` {
+		t.Fatalf("unexpected string; got %q; want %q", s, `z\`)
+	}
+
+	// Normalize must be nil-safe.
+	var vNil *Value
+	vNil.Normalize()
+}
+
+func TestValueNormalizeConcurrentAccess(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(twitterFixture)
+	if err != nil {
+		t.Fatalf("cannot parse twitter fixture: %s", err)
+	}
+	v.Normalize()
+	resultExpected := string(v.MarshalTo(nil))
+
+	const workers = 8
+	errCh := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		go func(n int) {
+			errCh <- func() error {
+				for j := 0; j < 10; j++ {
+					switch (n + j) % 3 {
+					case 0:
+						for _, st := range v.GetArray("statuses") {
+							if len(st.GetStringBytes("text")) == 0 {
+								return fmt.Errorf("unexpected empty text for %s", st.Get("id_str"))
+							}
+							if st.Get("user", "screen_name").Type() != TypeString {
+								return fmt.Errorf("unexpected screen_name type for %s", st.Get("id_str"))
+							}
+							st.Get("id").NumberKind()
+						}
+					case 1:
+						var visit func(v *Value)
+						visit = func(v *Value) {
+							switch v.Type() {
+							case TypeObject:
+								v.GetObject().Visit(func(key []byte, v *Value) {
+									visit(v)
+								})
+							case TypeArray:
+								for _, vv := range v.GetArray() {
+									visit(vv)
+								}
+							case TypeString:
+								v.StringBytes()
+							}
+						}
+						visit(v)
+					case 2:
+						result := string(v.MarshalTo(nil))
+						if result != resultExpected {
+							return fmt.Errorf("unexpected MarshalTo result")
+						}
+					}
+				}
+				return nil
+			}()
+		}(i)
+	}
+	for i := 0; i < workers; i++ {
+		select {
+		case err := <-errCh:
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timeout")
 		}
-	})
+	}
+}
 
-	t.Run("string", func(t *testing.T) {
-		v, err := p.Parse(`"foo bar"`)
-		if err != nil {
-			t.Fatalf("cannot parse string: %s", err)
-		}
-		tp := v.Type()
-		if tp != TypeString || tp.String() != "string" {
-			t.Fatalf("unexpected type obtained for string: %#v", v)
-		}
-		sb, err := v.StringBytes()
+func TestValueTypePredicates(t *testing.T) {
+	f := func(s string, isNull, isObject, isArray, isString, isNumber, isBool bool) {
+		t.Helper()
+		v, err := Parse(s)
 		if err != nil {
-			t.Fatalf("cannot obtain string: %s", err)
+			t.Fatalf("cannot parse %s: %s", s, err)
 		}
-		if string(sb) != "foo bar" {
-			t.Fatalf("unexpected value obtained for string; got %q; want %q", sb, "foo bar")
-		}
-		ss := v.String()
-		if ss != `"foo bar"` {
-			t.Fatalf("unexpected string representation of string; got %q; want %q", ss, `"foo bar"`)
+		if v.IsNull() != isNull {
+			t.Fatalf("unexpected IsNull() for %s; got %v; want %v", s, v.IsNull(), isNull)
 		}
-	})
-
-	t.Run("string-escaped", func(t *testing.T) {
-		v, err := p.Parse(`"\n\t\\foo\"bar\u3423x\/\b\f\r\\"`)
-		if err != nil {
-			t.Fatalf("cannot parse string: %s", err)
+		if v.IsObject() != isObject {
+			t.Fatalf("unexpected IsObject() for %s; got %v; want %v", s, v.IsObject(), isObject)
 		}
-		tp := v.Type()
-		if tp != TypeString {
-			t.Fatalf("unexpected type obtained for string: %#v", v)
+		if v.IsArray() != isArray {
+			t.Fatalf("unexpected IsArray() for %s; got %v; want %v", s, v.IsArray(), isArray)
 		}
-		sb, err := v.StringBytes()
-		if err != nil {
-			t.Fatalf("cannot obtain string: %s", err)
+		if v.IsString() != isString {
+			t.Fatalf("unexpected IsString() for %s; got %v; want %v", s, v.IsString(), isString)
 		}
-		if string(sb) != "\n\t\\foo\"bar\u3423x/\b\f\r\\" {
-			t.Fatalf("unexpected value obtained for string; got %q; want %q", sb, "\n\t\\foo\"bar\u3423x/\b\f\r\\")
+		if v.IsNumber() != isNumber {
+			t.Fatalf("unexpected IsNumber() for %s; got %v; want %v", s, v.IsNumber(), isNumber)
 		}
-		ss := v.String()
-		if ss != `"\n\t\\foo\"bar㐣x/\b\f\r\\"` {
-			t.Fatalf("unexpected string representation of string; got %q; want %q", ss, `"\n\t\\foo\"bar㐣x/\b\f\r\\"`)
+		if v.IsBool() != isBool {
+			t.Fatalf("unexpected IsBool() for %s; got %v; want %v", s, v.IsBool(), isBool)
 		}
-	})
+	}
+	f(`null`, true, false, false, false, false, false)
+	f(`{}`, false, true, false, false, false, false)
+	f(`[]`, false, false, true, false, false, false)
+	f(`"foo"`, false, false, false, true, false, false)
+	f(`"f\u006fo"`, false, false, false, true, false, false)
+	f(`-1.5`, false, false, false, false, true, false)
+	f(`true`, false, false, false, false, false, true)
+	f(`false`, false, false, false, false, false, true)
 
-	t.Run("object-one-element", func(t *testing.T) {
-		v, err := p.Parse(`  {
-	"foo"   : "bar"  }	 `)
+	// All the predicates must return false for nil value.
+	var v *Value
+	if v.IsNull() || v.IsObject() || v.IsArray() || v.IsString() || v.IsNumber() || v.IsBool() {
+		t.Fatalf("unexpected true predicate for nil value")
+	}
+}
+
+func TestValueGetWithExists(t *testing.T) {
+	f := func(s string, existsExpected, isNullExpected bool) {
+		t.Helper()
+		v, err := Parse(s)
 		if err != nil {
-			t.Fatalf("cannot parse object: %s", err)
-		}
-		tp := v.Type()
-		if tp != TypeObject {
-			t.Fatalf("unexpected type obtained for object: %#v", v)
+			t.Fatalf("cannot parse %s: %s", s, err)
 		}
-		o, err := v.Object()
-		if err != nil {
-			t.Fatalf("cannot obtain object: %s", err)
+
+		vv, exists := v.GetWithExists("a")
+		if exists != existsExpected {
+			t.Fatalf("unexpected exists for Value.GetWithExists on %s; got %v; want %v", s, exists, existsExpected)
 		}
-		vv := o.Get("foo")
-		if vv.Type() != TypeString {
-			t.Fatalf("unexpected type for foo item: got %d; want %d", vv.Type(), TypeString)
+		if (vv != nil) != exists {
+			t.Fatalf("unexpected value for Value.GetWithExists on %s: %v", s, vv)
 		}
-		vv = o.Get("non-existing key")
-		if vv != nil {
-			t.Fatalf("unexpected value obtained for non-existing key: %#v", vv)
+		if vv.IsNull() != isNullExpected {
+			t.Fatalf("unexpected IsNull() for Value.GetWithExists on %s; got %v; want %v", s, vv.IsNull(), isNullExpected)
 		}
 
-		s := v.String()
-		if s != `{"foo":"bar"}` {
-			t.Fatalf("unexpected string representation for object; got %q; want %q", s, `{"foo":"bar"}`)
+		vv, exists = v.GetObject().GetWithExists("a")
+		if exists != existsExpected {
+			t.Fatalf("unexpected exists for Object.GetWithExists on %s; got %v; want %v", s, exists, existsExpected)
 		}
-	})
-
-	t.Run("object-multi-elements", func(t *testing.T) {
-		v, err := p.Parse(`{"foo": [1,2,3  ]  ,"bar":{},"baz":123.456}`)
-		if err != nil {
-			t.Fatalf("cannot parse object: %s", err)
+		if (vv != nil) != exists {
+			t.Fatalf("unexpected value for Object.GetWithExists on %s: %v", s, vv)
 		}
-		tp := v.Type()
-		if tp != TypeObject {
-			t.Fatalf("unexpected type obtained for object: %#v", v)
+		if vv.IsNull() != isNullExpected {
+			t.Fatalf("unexpected IsNull() for Object.GetWithExists on %s; got %v; want %v", s, vv.IsNull(), isNullExpected)
 		}
-		o, err := v.Object()
+	}
+	f(`{"a":null}`, true, true)
+	f(`{}`, false, false)
+	f(`{"a":1}`, true, false)
+	f(`{"b":null}`, false, false)
+
+	// Nested paths
+	v := MustParse(`{"a":{"b":null},"c":[null]}`)
+	if vv, ok := v.GetWithExists("a", "b"); !ok || !vv.IsNull() {
+		t.Fatalf("expecting existing null value for a.b; got %v, %v", vv, ok)
+	}
+	if vv, ok := v.GetWithExists("c", "0"); !ok || !vv.IsNull() {
+		t.Fatalf("expecting existing null value for c[0]; got %v, %v", vv, ok)
+	}
+	if vv, ok := v.GetWithExists("a", "b", "c"); ok || vv != nil {
+		t.Fatalf("expecting missing value for a.b.c; got %v, %v", vv, ok)
+	}
+
+	// nil receivers
+	var vNil *Value
+	if vv, ok := vNil.GetWithExists("a"); ok || vv != nil {
+		t.Fatalf("expecting missing value for nil Value; got %v, %v", vv, ok)
+	}
+	var oNil *Object
+	if vv, ok := oNil.GetWithExists("a"); ok || vv != nil {
+		t.Fatalf("expecting missing value for nil Object; got %v, %v", vv, ok)
+	}
+}
+
+func TestObjectGetSeveral(t *testing.T) {
+	f := func(s string, keys []string, resultExpected string) {
+		t.Helper()
+		v, err := Parse(s)
 		if err != nil {
-			t.Fatalf("cannot obtain object: %s", err)
-		}
-		vv := o.Get("foo")
-		if vv.Type() != TypeArray {
-			t.Fatalf("unexpected type for foo item; got %d; want %d", vv.Type(), TypeArray)
+			t.Fatalf("cannot parse %s: %s", s, err)
 		}
-		vv = o.Get("bar")
-		if vv.Type() != TypeObject {
-			t.Fatalf("unexpected type for bar item; got %d; want %d", vv.Type(), TypeObject)
+		vs := v.GetObject().GetSeveral([]*Value{nil}, keys...)
+		if len(vs) != len(keys)+1 || vs[0] != nil {
+			t.Fatalf("unexpected values for %s: %v", s, vs)
 		}
-		vv = o.Get("baz")
-		if vv.Type() != TypeNumber {
-			t.Fatalf("unexpected type for baz item; got %d; want %d", vv.Type(), TypeNumber)
+		var result []string
+		for i, vv := range vs[1:] {
+			vvExpected := v.GetObject().Get(keys[i])
+			if vv != vvExpected {
+				t.Fatalf("unexpected value for key %q in %s; got %v; want %v", keys[i], s, vv, vvExpected)
+			}
+			if vv == nil {
+				result = append(result, "<nil>")
+			} else {
+				result = append(result, vv.String())
+			}
 		}
-		vv = o.Get("non-existing-key")
-		if vv != nil {
-			t.Fatalf("unexpected value obtained for non-existing key: %#v", vv)
+		if r := strings.Join(result, ","); r != resultExpected {
+			t.Fatalf("unexpected result for %s; got %s; want %s", s, r, resultExpected)
 		}
+	}
+	f(`{}`, nil, ``)
+	f(`{}`, []string{"a", "b"}, `<nil>,<nil>`)
+	f(`{"a":1,"b":[2],"c":{"d":3}}`, []string{"c", "missing", "a"}, `{"d":3},<nil>,1`)
+	f(`{"a":1,"b":2,"a":3}`, []string{"a", "b", "a"}, `1,2,1`)
+	f(`{"a":1,"b":2}`, []string{"b"}, `2`)
+	f(`{"":1,"a":2}`, []string{"a", ""}, `2,1`)
 
-		s := v.String()
-		if s != `{"foo":[1,2,3],"bar":{},"baz":123.456}` {
-			t.Fatalf("unexpected string representation for object; got %q; want %q", s, `{"foo":[1,2,3],"bar":{},"baz":123.456}`)
-		}
+	// Escaped keys
+	f(`{"x\ny":1,"z\"":2}`, []string{"x\ny", "z\""}, `1,2`)
+	f(`{"\u0061b":1,"c":2}`, []string{"ab", "c"}, `1,2`)
+	f(`{"\u0061b":1,"c":2}`, []string{"c", "ab", "\\"}, `2,1,<nil>`)
+
+	// nil object
+	var o *Object
+	if vs := o.GetSeveral(nil, "a", "b"); len(vs) != 2 || vs[0] != nil || vs[1] != nil {
+		t.Fatalf("unexpected values for nil object: %v", vs)
+	}
+
+	// GetSeveral mustn't allocate if dst has enough capacity.
+	v := MustParse(`{"a":1,"b":"x","c\u0041":null}`)
+	dst := make([]*Value, 0, 3)
+	n := testing.AllocsPerRun(100, func() {
+		dst = v.GetObject().GetSeveral(dst[:0], "b", "cA", "a")
 	})
+	if n > 0 {
+		t.Fatalf("unexpected allocations: %v", n)
+	}
+	if dst[0].GetString() != "x" || !dst[1].IsNull() || dst[2].GetInt() != 1 {
+		t.Fatalf("unexpected values: %v", dst)
+	}
+}
 
-	t.Run("array-one-element", func(t *testing.T) {
-		v, err := p.Parse(`   [{"bar":[  [],[[]]   ]} ]  `)
-		if err != nil {
-			t.Fatalf("cannot parse array: %s", err)
+func TestValueGetSeveral(t *testing.T) {
+	v := MustParse(`{"foo":{"bar":{"a":1,"b":2}},"arr":[{"c":3}]}`)
+	f := func(prefix []string, keys []string, resultExpected string) {
+		t.Helper()
+		vs := v.GetSeveral(nil, prefix, keys...)
+		if len(vs) != len(keys) {
+			t.Fatalf("unexpected number of values; got %d; want %d", len(vs), len(keys))
+		}
+		var result []string
+		for _, vv := range vs {
+			if vv == nil {
+				result = append(result, "<nil>")
+			} else {
+				result = append(result, vv.String())
+			}
 		}
-		tp := v.Type()
-		if tp != TypeArray {
-			t.Fatalf("unexpected type obtained for array: %#v", v)
+		if r := strings.Join(result, ","); r != resultExpected {
+			t.Fatalf("unexpected result for prefix %q; got %s; want %s", prefix, r, resultExpected)
 		}
-		a, err := v.Array()
-		if err != nil {
-			t.Fatalf("unexpected error: %s", err)
+	}
+	f(nil, []string{"foo", "arr", "x"}, `{"bar":{"a":1,"b":2}},[{"c":3}],<nil>`)
+	f([]string{"foo", "bar"}, []string{"b", "a"}, `2,1`)
+	f([]string{"arr", "0"}, []string{"c"}, `3`)
+	f([]string{"arr"}, []string{"c"}, `<nil>`)
+	f([]string{"missing"}, []string{"a", "b"}, `<nil>,<nil>`)
+}
+
+func TestObjectDuplicateKeys(t *testing.T) {
+	v := MustParse(`{"a":1,"b":2,"a":3,"c":{},"a\u0000":4,"\u0061":5}`)
+	o := v.GetObject()
+
+	if n := o.Get("a").GetInt(); n != 1 {
+		t.Fatalf("unexpected Get result; got %d; want %d", n, 1)
+	}
+
+	vs := o.GetAll([]*Value{nil}, "a")
+	if len(vs) != 4 || vs[0] != nil {
+		t.Fatalf("unexpected GetAll result: %v", vs)
+	}
+	for i, nExpected := range []int{1, 3, 5} {
+		if n := vs[i+1].GetInt(); n != nExpected {
+			t.Fatalf("unexpected GetAll value #%d; got %d; want %d", i, n, nExpected)
 		}
-		if len(a) != 1 {
-			t.Fatalf("unexpected array len; got %d; want %d", len(a), 1)
+	}
+	if vs := o.GetAll(nil, "missing"); vs != nil {
+		t.Fatalf("unexpected GetAll result for missing key: %v", vs)
+	}
+
+	f := func(key string, countExpected int) {
+		t.Helper()
+		if n := o.CountKey(key); n != countExpected {
+			t.Fatalf("unexpected CountKey(%q); got %d; want %d", key, n, countExpected)
 		}
-		if a[0].Type() != TypeObject {
-			t.Fatalf("unexpected type for a[0]; got %d; want %d", a[0].Type(), TypeObject)
+		if o.Has(key) != (countExpected > 0) {
+			t.Fatalf("unexpected Has(%q); got %v; want %v", key, o.Has(key), countExpected > 0)
 		}
+	}
+	f("a", 3)
+	f("b", 1)
+	f("c", 1)
+	f("a\x00", 1)
+	f("missing", 0)
 
-		s := v.String()
-		if s != `[{"bar":[[],[[]]]}]` {
-			t.Fatalf("unexpected string representation for array; got %q; want %q", s, `[{"bar":[[],[[]]]}]`)
-		}
+	// Duplicate keys must be visited in the original order.
+	var keys []string
+	var values []string
+	o.Visit(func(key []byte, v *Value) {
+		keys = append(keys, string(key))
+		values = append(values, v.String())
 	})
+	keysExpected := []string{"a", "b", "a", "c", "a\x00", "a"}
+	valuesExpected := []string{"1", "2", "3", "{}", "4", "5"}
+	if !reflect.DeepEqual(keys, keysExpected) {
+		t.Fatalf("unexpected keys visited; got %q; want %q", keys, keysExpected)
+	}
+	if !reflect.DeepEqual(values, valuesExpected) {
+		t.Fatalf("unexpected values visited; got %q; want %q", values, valuesExpected)
+	}
 
-	t.Run("array-multi-elements", func(t *testing.T) {
-		v, err := p.Parse(`   [1,"foo",{"bar":[     ],"baz":""}    ,[  "x" ,	"y"   ]     ]   `)
-		if err != nil {
-			t.Fatalf("cannot parse array: %s", err)
+	// nil object
+	var oNil *Object
+	if oNil.Has("a") || oNil.CountKey("a") != 0 || oNil.GetAll(nil, "a") != nil {
+		t.Fatalf("unexpected results for nil object")
+	}
+}
+
+func TestParseBOM(t *testing.T) {
+	const bom = "\xef\xbb\xbf"
+	const utf16LE = "\xff\xfe{\x00\"\x00a\x00\"\x00:\x001\x00}\x00"
+	const utf16BE = "\xfe\xff\x00{\x00\"\x00a\x00\"\x00:\x001\x00}"
+
+	parsers := map[string]func(s string) (*Value, error){
+		"Parser": func(s string) (*Value, error) {
+			var p Parser
+			return p.Parse(s)
+		},
+		"ParseOwning": func(s string) (*Value, error) {
+			var p Parser
+			return p.ParseOwning([]byte(s))
+		},
+		"Arena": func(s string) (*Value, error) {
+			var a Arena
+			return a.Parse(s)
+		},
+	}
+	for name, parse := range parsers {
+		t.Run(name, func(t *testing.T) {
+			f := func(s, resultExpected string) {
+				t.Helper()
+				v, err := parse(s)
+				if err != nil {
+					t.Fatalf("unexpected error when parsing %q: %s", s, err)
+				}
+				if result := v.String(); result != resultExpected {
+					t.Fatalf("unexpected result for %q; got %s; want %s", s, result, resultExpected)
+				}
+			}
+			f(bom+`{"a":1}`, `{"a":1}`)
+			f(bom+` [1, 2] `, `[1,2]`)
+			f(bom+`"foo"`, `"foo"`)
+
+			fError := func(s, errExpected string) {
+				t.Helper()
+				_, err := parse(s)
+				if err == nil {
+					t.Fatalf("expecting non-nil error when parsing %q", s)
+				}
+				if !strings.Contains(err.Error(), errExpected) {
+					t.Fatalf("unexpected error when parsing %q; got %q; must contain %q", s, err, errExpected)
+				}
+			}
+			fError(bom, "cannot parse empty string")
+			fError(bom+bom+`{}`, "cannot parse number")
+			fError(` `+bom+`{}`, "cannot parse number")
+			fError(`[`+bom+`1]`, "cannot parse number")
+			fError(`{}`+bom, "unexpected tail")
+			fError(utf16LE, "UTF-16 encoded JSON is not supported")
+			fError(utf16BE, "UTF-16 encoded JSON is not supported")
+			fError(`[`+utf16LE+`]`, "cannot parse number")
+		})
+	}
+
+	// ForEachArrayElement
+	var p Parser
+	var items []string
+	err := p.ForEachArrayElement(bom+`[1,"x"]`, func(i int, v *Value) error {
+		items = append(items, v.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := strings.Join(items, ","); s != `1,"x"` {
+		t.Fatalf("unexpected items; got %s; want %s", s, `1,"x"`)
+	}
+}
+
+func TestParserClone(t *testing.T) {
+	var p Parser
+	p.InternKeys(true)
+	if _, err := p.Parse(largeFixture); err != nil {
+		t.Fatalf("cannot parse large fixture: %s", err)
+	}
+	pc := p.Clone()
+	if cap(pc.b) != cap(p.b) {
+		t.Fatalf("unexpected buffer capacity; got %d; want %d", cap(pc.b), cap(p.b))
+	}
+	if cap(pc.c.vs) != cap(p.c.vs) {
+		t.Fatalf("unexpected cache capacity; got %d; want %d", cap(pc.c.vs), cap(p.c.vs))
+	}
+	if len(pc.b) != 0 || len(pc.c.vs) != 0 || pc.v != nil {
+		t.Fatalf("the contents of the parser mustn't be copied")
+	}
+	if pc.c.keys == nil || len(pc.c.keys) != 0 {
+		t.Fatalf("key interning must be enabled without copying interned keys")
+	}
+	if &pc.b[:1][0] == &p.b[:1][0] || &pc.c.vs[:1][0] == &p.c.vs[:1][0] {
+		t.Fatalf("the cloned parser mustn't share memory with the original parser")
+	}
+
+	// Parse distinct documents concurrently in the original and the cloned parsers.
+	ch := make(chan error, 2)
+	f := func(p *Parser, s string) {
+		for i := 0; i < 10; i++ {
+			v, err := p.Parse(s)
+			if err != nil {
+				ch <- err
+				return
+			}
+			if result := v.String(); result != MustParse(s).String() {
+				ch <- fmt.Errorf("unexpected result; got %q", startEndString(result))
+				return
+			}
 		}
-		tp := v.Type()
-		if tp != TypeArray {
-			t.Fatalf("unexpected type obtained for array: %#v", v)
+		ch <- nil
+	}
+	go f(&p, largeFixture)
+	go f(pc, mediumFixture)
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-ch:
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout")
 		}
-		a, err := v.Array()
+	}
+}
+
+func TestParserPreallocate(t *testing.T) {
+	var p Parser
+	p.Preallocate(1000, 100)
+	if cap(p.b) != 1000 || cap(p.c.vs) != 100 {
+		t.Fatalf("unexpected capacities; got %d and %d; want 1000 and 100", cap(p.b), cap(p.c.vs))
+	}
+
+	// Already allocated memory mustn't be shrunk.
+	p.Preallocate(10, 1)
+	if cap(p.b) != 1000 || cap(p.c.vs) != 100 {
+		t.Fatalf("unexpected capacities after shrinking; got %d and %d; want 1000 and 100", cap(p.b), cap(p.c.vs))
+	}
+
+	// Pre-allocated memory must be used by Parse.
+	b := p.b[:1]
+	vs := p.c.vs[:1]
+	v, err := p.Parse(`{"foo":[1,2,"bar"]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if &p.b[0] != &b[0] || &p.c.vs[0] != &vs[0] {
+		t.Fatalf("Parse must re-use pre-allocated memory")
+	}
+	if s := v.String(); s != `{"foo":[1,2,"bar"]}` {
+		t.Fatalf("unexpected value: %s", s)
+	}
+
+	// Preallocate invalidates the parsed value.
+	p.Preallocate(10000, 1000)
+	if cap(p.b) != 10000 || cap(p.c.vs) != 1000 || len(p.c.vs) != 0 {
+		t.Fatalf("unexpected state after growing; cap(b)=%d, cap(vs)=%d, len(vs)=%d", cap(p.b), cap(p.c.vs), len(p.c.vs))
+	}
+	if ps := p.Stats(); ps != (ParseStats{}) {
+		t.Fatalf("expecting zero stats after Preallocate; got %+v", ps)
+	}
+}
+
+func TestParserDuplicateKeyMode(t *testing.T) {
+	const s = `{"a":1,"\u0061":2}`
+	f := func(p *Parser, s, getExpected, visitExpected, marshalExpected string) {
+		t.Helper()
+		v, err := p.Parse(s)
 		if err != nil {
-			t.Fatalf("unexpected error: %s", err)
+			t.Fatalf("cannot parse %s: %s", s, err)
 		}
-		if len(a) != 4 {
-			t.Fatalf("unexpected array len; got %d; want %d", len(a), 4)
+		o := v.GetObject()
+		if result := o.Get("a").String(); result != getExpected {
+			t.Fatalf("unexpected Get result for %s; got %s; want %s", s, result, getExpected)
 		}
-		if a[0].Type() != TypeNumber {
-			t.Fatalf("unexpected type for a[0]; got %d; want %d", a[0].Type(), TypeNumber)
+		var visited []string
+		o.Visit(func(key []byte, v *Value) {
+			visited = append(visited, string(key)+"="+v.String())
+		})
+		if result := strings.Join(visited, ","); result != visitExpected {
+			t.Fatalf("unexpected visited items for %s; got %s; want %s", s, result, visitExpected)
 		}
-		if a[1].Type() != TypeString {
-			t.Fatalf("unexpected type for a[1]; got %d; want %d", a[1].Type(), TypeString)
+		if result := v.String(); result != marshalExpected {
+			t.Fatalf("unexpected marshaled JSON for %s; got %s; want %s", s, result, marshalExpected)
 		}
-		if a[2].Type() != TypeObject {
-			t.Fatalf("unexpected type for a[2]; got %d; want %d", a[2].Type(), TypeObject)
+	}
+
+	// DupFirst is the default mode.
+	var p Parser
+	f(&p, s, "1", "a=1,a=2", `{"a":1,"a":2}`)
+	p.DuplicateKeyMode(DupFirst)
+	f(&p, s, "1", "a=1,a=2", `{"a":1,"a":2}`)
+
+	p.DuplicateKeyMode(DupLast)
+	f(&p, s, "2", "a=2", `{"a":2}`)
+
+	// The entry keeps the position of the first key.
+	f(&p, `{"a":1,"b":{"x":1,"x":[2]},"c":3,"a":4,"b":5,"b":6}`, "4", `a=4,b=6,c=3`, `{"a":4,"b":6,"c":3}`)
+	f(&p, `{"a":{"x":1,"x":[2]},"\u0062":"\u0063"}`, `{"x":[2]}`, `a={"x":[2]},b="\u0063"`, `{"a":{"x":[2]},"b":"\u0063"}`)
+
+	p.DuplicateKeyMode(DupError)
+	if _, err := p.Parse(s); err == nil {
+		t.Fatalf("expecting non-nil error for %s", s)
+	} else if pe, ok := err.(*ParseError); !ok || pe.Offset != 7 || !strings.Contains(err.Error(), `duplicate object key "a"`) {
+		t.Fatalf("unexpected error; got %v; want *ParseError with offset 7 mentioning the duplicate key", err)
+	}
+	if _, err := p.Parse(`[{"a":1},{"a":{"b":2,"c":3,"b":4}}]`); err == nil {
+		t.Fatalf("expecting non-nil error for nested duplicate key")
+	}
+	f(&p, `{"a":1,"b":{"a":2},"\u0063":[{"a":3}]}`, "1", `a=1,b={"a":2},c=[{"a":3}]`, `{"a":1,"b":{"a":2},"c":[{"a":3}]}`)
+
+	// The mode must be preserved by Clone.
+	pc := p.Clone()
+	if _, err := pc.Parse(s); err == nil {
+		t.Fatalf("expecting non-nil error for cloned parser")
+	}
+
+	// Key interning must work with unescaped keys.
+	p.DuplicateKeyMode(DupLast)
+	p.InternKeys(true)
+	f(&p, s, "2", "a=2", `{"a":2}`)
+	p.InternKeys(false)
+
+	// Invalid escape sequences in keys must be detected.
+	v, err := p.Parse(`{"a\x":1}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := v.GetObject().GetStrict("a\\x"); err == nil {
+		t.Fatalf("expecting non-nil error for invalid escape sequence in key")
+	}
+}
+
+func TestParserDuplicateKeyModeBigObject(t *testing.T) {
+	var ss []string
+	for i := 0; i < 100; i++ {
+		ss = append(ss, fmt.Sprintf(`"k%d":%d`, i%70, i))
+	}
+	s := "{" + strings.Join(ss, ",") + "}"
+
+	var p Parser
+	p.DuplicateKeyMode(DupLast)
+	v, err := p.Parse(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	o := v.GetObject()
+	if n := o.Len(); n != 70 {
+		t.Fatalf("unexpected number of entries; got %d; want %d", n, 70)
+	}
+	for i := 0; i < 70; i++ {
+		nExpected := i
+		if i < 30 {
+			nExpected = i + 70
 		}
-		if a[3].Type() != TypeArray {
-			t.Fatalf("unexpected type for a[3]; got %d; want %d", a[3].Type(), TypeArray)
+		key := fmt.Sprintf("k%d", i)
+		if n := o.Get(key).GetInt(); n != nExpected {
+			t.Fatalf("unexpected value for %q; got %d; want %d", key, n, nExpected)
+		}
+		if k := string(o.kvs[i].k); k != key {
+			t.Fatalf("unexpected key at position %d; got %q; want %q", i, k, key)
 		}
+	}
 
-		s := v.String()
-		if s != `[1,"foo",{"bar":[],"baz":""},["x","y"]]` {
-			t.Fatalf("unexpected string representation for array; got %q; want %q", s, `[1,"foo",{"bar":[],"baz":""},["x","y"]]`)
-		}
-	})
+	p.DuplicateKeyMode(DupError)
+	if _, err := p.Parse(s); err == nil || !strings.Contains(err.Error(), `duplicate object key "k0"`) {
+		t.Fatalf("unexpected error; got %v; want error mentioning the duplicate key", err)
+	}
+	if _, err := p.Parse("{" + strings.Join(ss[:70], ",") + "}"); err != nil {
+		t.Fatalf("unexpected error for object without duplicate keys: %s", err)
+	}
+}
 
-	t.Run("complex-object", func(t *testing.T) {
-		s := `{"foo":[-1.345678,[[[[[]]]],{}],"bar"],"baz":{"bbb":123}}`
-		v, err := p.Parse(s)
+func TestParserExtractRaw(t *testing.T) {
+	var p Parser
+	p.KeepRaw(true)
+	if _, err := p.ExtractRaw("foo"); err == nil {
+		t.Fatalf("expecting non-nil error for Parser without parsed JSON")
+	}
+
+	f := func(fixture string, keys ...string) {
+		t.Helper()
+		v, err := p.Parse(fixture)
 		if err != nil {
-			t.Fatalf("cannot parse complex object: %s", err)
+			t.Fatalf("cannot parse fixture: %s", err)
 		}
-		if v.Type() != TypeObject {
-			t.Fatalf("unexpected type obtained for object: %#v", v)
+		raw, err := p.ExtractRaw(keys...)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", keys, err)
 		}
-
-		ss := v.String()
-		if ss != s {
-			t.Fatalf("unexpected string representation for object; got %q; want %q", ss, s)
+		start, length, ok := p.ValueOffset(v.Get(keys...))
+		if !ok {
+			t.Fatalf("cannot obtain offset for %q", keys)
+		}
+		if s := fixture[start : start+length]; string(raw) != s {
+			t.Fatalf("unexpected raw value for %q;\ngot\n%s\nwant\n%s", keys, raw, s)
 		}
 
-		s = strings.TrimSpace(largeFixture)
-		v, err = p.Parse(s)
+		// The raw value must be parsed to the equal value.
+		var p2 Parser
+		v2, err := p2.ParseBytes(raw)
 		if err != nil {
-			t.Fatalf("cannot parse largeFixture: %s", err)
-		}
-		ss = v.String()
-		if ss != s {
-			t.Fatalf("unexpected string representation for object; got\n%q; want\n%q", ss, s)
+			t.Fatalf("cannot parse raw value for %q: %s", keys, err)
+		}
+		if !v2.Equal(v.Get(keys...)) {
+			t.Fatalf("unexpected value parsed for %q;\ngot\n%s\nwant\n%s", keys, v2, v.Get(keys...))
+		}
+	}
+	f(mediumFixture, "person", "geo")
+	f(mediumFixture, "person", "name", "fullName")
+	f(mediumFixture, "person", "geo", "lat")
+	f(mediumFixture, "person")
+	f(twitterFixture, "statuses", "0", "text")
+	f(twitterFixture, "statuses", "1", "user")
+	f(twitterFixture, "search_metadata")
+	f(`{"a":{"b":"x\u0020y","c":1.50E+2}}`, "a")
+	f(`{"a":{"b":"x\u0020y","c":1.50E+2}}`, "a", "b")
+	f(`{"a":{"b":"x\u0020y","c":1.50E+2}}`, "a", "c")
+	f(`[1, [2, 3]]`, "1")
+
+	// Strings must keep the quotes and escape sequences.
+	if _, err := p.Parse(`{"s" : "x\ny" }`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	raw, err := p.ExtractRaw("s")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(raw) != `"x\ny"` {
+		t.Fatalf("unexpected raw string; got %s; want %s", raw, `"x\ny"`)
+	}
+
+	// The root value is returned for empty path.
+	raw, err = p.ExtractRaw()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(raw) != `{"s" : "x\ny" }` {
+		t.Fatalf("unexpected raw root; got %s; want %s", raw, `{"s" : "x\ny" }`)
+	}
+
+	// Missing path.
+	if _, err := p.ExtractRaw("missing"); err == nil {
+		t.Fatalf("expecting non-nil error for missing path")
+	}
+
+	// The value is marshaled if the original JSON is unavailable.
+	if _, err := p.ParseOwning([]byte(`{"a": [1, "x\ny"]}`)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	raw, err = p.ExtractRaw("a")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(raw) != `[1,"x\ny"]` {
+		t.Fatalf("unexpected marshaled value; got %s; want %s", raw, `[1,"x\ny"]`)
+	}
+
+	// Failed Parse call must reset the parsed value.
+	if _, err := p.Parse(`{"a":`); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	if _, err := p.ExtractRaw("a"); err == nil {
+		t.Fatalf("expecting non-nil error after failed Parse call")
+	}
+}
+
+func TestParserParseWithin(t *testing.T) {
+	b := []byte(largeFixture)
+
+	var pFull Parser
+	pFull.KeepRaw(true)
+	vFull, err := pFull.ParseBytes(b)
+	if err != nil {
+		t.Fatalf("cannot parse largeFixture: %s", err)
+	}
+	start, length, ok := pFull.ValueOffset(vFull)
+	if n := len(strings.TrimSpace(largeFixture)); !ok || start != 0 || length != n {
+		t.Fatalf("unexpected root location; got start=%d, length=%d, ok=%v; want 0, %d, true", start, length, ok, n)
+	}
+
+	checkRaw := func(p *Parser, v *Value, start, length int) {
+		t.Helper()
+		if s := string(b[start : start+length]); s != string(p.Raw(v)) {
+			t.Fatalf("unexpected JSON at [%d:%d); got %q; want %q", start, start+length, s, p.Raw(v))
 		}
-	})
+	}
 
-	t.Run("complex-object-visit-all", func(t *testing.T) {
-		n := 0
-		var f func(k []byte, v *Value)
-		f = func(k []byte, v *Value) {
-			switch v.Type() {
-			case TypeObject:
-				o, err := v.Object()
-				if err != nil {
-					t.Fatalf("cannot obtain object: %s", err)
-				}
-				o.Visit(f)
-			case TypeArray:
-				a, err := v.Array()
-				if err != nil {
-					t.Fatalf("unexpected error: %s", err)
+	var p Parser
+	p.KeepRaw(true)
+	n := 0
+	for _, keys := range [][]string{{"users"}, {"topics", "topics"}} {
+		for _, item := range vFull.GetArray(keys...) {
+			start, length, ok := pFull.ValueOffset(item)
+			if !ok {
+				t.Fatalf("cannot obtain location for %s", item)
+			}
+			checkRaw(&pFull, item, start, length)
+
+			v, err := p.ParseWithin(b, start, start+length)
+			if err != nil {
+				t.Fatalf("cannot parse window [%d:%d): %s", start, start+length, err)
+			}
+			if !v.Equal(item) {
+				t.Fatalf("unexpected value parsed at [%d:%d); got %s; want %s", start, start+length, v, item)
+			}
+			vStart, vLength, ok := p.ValueOffset(v)
+			if !ok || vStart != start || vLength != length {
+				t.Fatalf("unexpected value location; got start=%d, length=%d, ok=%v; want %d, %d, true", vStart, vLength, ok, start, length)
+			}
+
+			// Locations of nested values and keys must match the original buffer.
+			o := v.GetObject()
+			o.Visit(func(key []byte, vv *Value) {
+				kStart, kLength, ok := o.KeyOffset(string(key))
+				if !ok {
+					t.Fatalf("cannot obtain location for key %q", key)
 				}
-				for _, vv := range a {
-					f(nil, vv)
+				if kStart <= start || kStart+kLength >= start+length {
+					t.Fatalf("key %q location [%d:%d) is outside the window [%d:%d)", key, kStart, kStart+kLength, start, start+length)
 				}
-			case TypeString:
-				sb, err := v.StringBytes()
-				if err != nil {
-					t.Fatalf("cannot obtain string: %s", err)
+				if s := string(b[kStart : kStart+kLength]); s != `"`+string(key)+`"` {
+					t.Fatalf("unexpected key at [%d:%d); got %s; want %q", kStart, kStart+kLength, s, key)
 				}
-				n += len(sb)
-			case TypeNumber:
-				nn, err := v.Int()
-				if err != nil {
-					t.Fatalf("cannot obtain int: %s", err)
+				if vStart, vLength, ok := p.ValueOffset(vv); ok {
+					checkRaw(&p, vv, vStart, vLength)
+					n++
 				}
-				n += nn
-			}
+			})
 		}
+	}
+	if n == 0 {
+		t.Fatalf("expecting non-zero number of located nested values")
+	}
 
-		s := strings.TrimSpace(largeFixture)
-		v, err := p.Parse(s)
-		if err != nil {
-			t.Fatalf("cannot parse largeFixture: %s", err)
+	// Values from other parsers and shared values have no location.
+	if _, _, ok := p.ValueOffset(vFull); ok {
+		t.Fatalf("expecting no location for the value from another parser")
+	}
+	if _, _, ok := p.ValueOffset(valueNull); ok {
+		t.Fatalf("expecting no location for null")
+	}
+	if _, _, ok := p.ValueOffset(nil); ok {
+		t.Fatalf("expecting no location for nil value")
+	}
+
+	// Whitespace around the value is allowed.
+	data := []byte(`[1, {"foo": "bar"} ]`)
+	v, err := p.ParseWithin(data, 3, len(data)-1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	start, length, ok = p.ValueOffset(v)
+	if !ok || start != 4 || length != 14 {
+		t.Fatalf("unexpected location; got start=%d, length=%d, ok=%v; want 4, 14, true", start, length, ok)
+	}
+	start, length, ok = v.GetObject().KeyOffset("foo")
+	if !ok || start != 5 || length != 5 {
+		t.Fatalf("unexpected key location; got start=%d, length=%d, ok=%v; want 5, 5, true", start, length, ok)
+	}
+
+	// Parse resets the window offset.
+	v, err = p.Parse(`{"foo":1}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if start, _, _ := v.GetObject().KeyOffset("foo"); start != 1 {
+		t.Fatalf("unexpected key offset after Parse; got %d; want 1", start)
+	}
+
+	// Errors
+	for _, w := range [][2]int{{-1, 3}, {3, 2}, {0, len(data) + 1}} {
+		if _, err := p.ParseWithin(data, w[0], w[1]); err == nil {
+			t.Fatalf("expecting non-nil error for window [%d:%d)", w[0], w[1])
 		}
-		o, err := v.Object()
-		if err != nil {
-			t.Fatalf("cannot obtain object: %s", err)
+	}
+	_, err = p.ParseWithin(data, 4, len(data))
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expecting *ParseError for the trailing data; got %v", err)
+	}
+	if pe.Offset != len(data)-1 {
+		t.Fatalf("unexpected error offset; got %d; want %d", pe.Offset, len(data)-1)
+	}
+	_, err = p.ParseWithin(data, 4, 15)
+	pe, ok = err.(*ParseError)
+	if !ok {
+		t.Fatalf("expecting *ParseError for the truncated value; got %v", err)
+	}
+	if pe.Offset < 4 || pe.Offset > 15 {
+		t.Fatalf("error offset %d is outside the window [4:15)", pe.Offset)
+	}
+}
+
+func TestValueGetDefault(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"zero":0,"n":-12,"u":18446744073709551615,"f":1.5,"empty":"","s":"foo","false":false,"true":true,"null":null,"a":[3,{"x":"y"}]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f := func(name string, got, want interface{}) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("unexpected result for %s; got %v; want %v", name, got, want)
 		}
-		o.Visit(f)
+	}
 
-		if n != 21473 {
-			t.Fatalf("unexpected n; got %d; want %d", n, 21473)
+	// int
+	f("GetIntDefault(zero)", v.GetIntDefault(7, "zero"), 0)
+	f("GetIntDefault(n)", v.GetIntDefault(7, "n"), -12)
+	f("GetIntDefault(a[0])", v.GetIntDefault(7, "a", "0"), 3)
+	f("GetIntDefault(missing)", v.GetIntDefault(7, "missing"), 7)
+	f("GetIntDefault(s)", v.GetIntDefault(7, "s"), 7)
+	f("GetIntDefault(null)", v.GetIntDefault(7, "null"), 7)
+	f("GetIntDefault(f)", v.GetIntDefault(7, "f"), 7)
+
+	// int64
+	f("GetInt64Default(zero)", v.GetInt64Default(7, "zero"), int64(0))
+	f("GetInt64Default(n)", v.GetInt64Default(7, "n"), int64(-12))
+	f("GetInt64Default(missing)", v.GetInt64Default(7, "missing"), int64(7))
+	f("GetInt64Default(s)", v.GetInt64Default(7, "s"), int64(7))
+	f("GetInt64Default(u)", v.GetInt64Default(7, "u"), int64(7))
+
+	// uint64
+	f("GetUint64Default(zero)", v.GetUint64Default(7, "zero"), uint64(0))
+	f("GetUint64Default(u)", v.GetUint64Default(7, "u"), uint64(18446744073709551615))
+	f("GetUint64Default(missing)", v.GetUint64Default(7, "missing"), uint64(7))
+	f("GetUint64Default(true)", v.GetUint64Default(7, "true"), uint64(7))
+	f("GetUint64Default(n)", v.GetUint64Default(7, "n"), uint64(7))
+
+	// float64
+	f("GetFloat64Default(zero)", v.GetFloat64Default(7.5, "zero"), float64(0))
+	f("GetFloat64Default(f)", v.GetFloat64Default(7.5, "f"), 1.5)
+	f("GetFloat64Default(missing)", v.GetFloat64Default(7.5, "missing"), 7.5)
+	f("GetFloat64Default(s)", v.GetFloat64Default(7.5, "s"), 7.5)
+	f("GetFloat64Default(a)", v.GetFloat64Default(7.5, "a"), 7.5)
+
+	// string
+	f("GetStringDefault(empty)", v.GetStringDefault("def", "empty"), "")
+	f("GetStringDefault(s)", v.GetStringDefault("def", "s"), "foo")
+	f("GetStringDefault(a[1].x)", v.GetStringDefault("def", "a", "1", "x"), "y")
+	f("GetStringDefault(missing)", v.GetStringDefault("def", "missing"), "def")
+	f("GetStringDefault(zero)", v.GetStringDefault("def", "zero"), "def")
+	f("GetStringDefault(null)", v.GetStringDefault("def", "null"), "def")
+
+	// bool
+	f("GetBoolDefault(false)", v.GetBoolDefault(true, "false"), false)
+	f("GetBoolDefault(true)", v.GetBoolDefault(false, "true"), true)
+	f("GetBoolDefault(missing)", v.GetBoolDefault(true, "missing"), true)
+	f("GetBoolDefault(zero)", v.GetBoolDefault(true, "zero"), true)
+	f("GetBoolDefault(null)", v.GetBoolDefault(true, "null"), true)
+
+	// The returned string must remain valid after the next Parse call.
+	s := v.GetStringDefault("def", "s")
+	if _, err := p.Parse(`{"s":"bar"}`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f("GetStringDefault after Parse", s, "foo")
+}
+
+func TestParserMaxStringLen(t *testing.T) {
+	f := func(p *Parser, s string, offset int, path, errExpected string) {
+		t.Helper()
+		check := func(err error) {
+			t.Helper()
+			if err == nil {
+				t.Fatalf("expecting non-nil error for %s", s)
+			}
+			pe, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("expecting *ParseError for %s; got %T", s, err)
+			}
+			if pe.Offset != offset || pe.Path != path {
+				t.Fatalf("unexpected error location for %s; got (%d, %q); want (%d, %q)", s, pe.Offset, pe.Path, offset, path)
+			}
+			if !strings.Contains(err.Error(), errExpected) {
+				t.Fatalf("unexpected error for %s; got %q; must contain %q", s, err, errExpected)
+			}
+		}
+		_, err := p.Parse(s)
+		check(err)
+		err = ValidateWithLimits(s, p.c.maxStringLen, p.c.maxKeyLen)
+		check(err)
+		err = ValidateBytesWithLimits([]byte(s), p.c.maxStringLen, p.c.maxKeyLen)
+		check(err)
+	}
+	fSuccess := func(p *Parser, s string) {
+		t.Helper()
+		if _, err := p.Parse(s); err != nil {
+			t.Fatalf("unexpected error for %s: %s", s, err)
 		}
+		if err := ValidateWithLimits(s, p.c.maxStringLen, p.c.maxKeyLen); err != nil {
+			t.Fatalf("unexpected error from ValidateWithLimits for %s: %s", s, err)
+		}
+	}
 
-		// Make sure the json remains valid after visiting all the items.
-		ss := v.String()
-		if ss != s {
-			t.Fatalf("unexpected string representation for object; got\n%q; want\n%q", ss, s)
+	var p Parser
+	p.MaxStringLen(4)
+	p.MaxKeyLen(3)
+
+	// Strings at the limit
+	fSuccess(&p, `"abcd"`)
+	fSuccess(&p, `{"abc":"abcd","x":["","1234"]}`)
+
+	// The limit applies to the escaped string.
+	fSuccess(&p, `"\n\t"`)
+	f(&p, `"\u0061"`, 0, "", "too long string with 6 bytes; it exceeds the limit of 4 bytes")
+
+	// Strings one byte over the limit
+	f(&p, `"abcde"`, 0, "", "too long string with 5 bytes; it exceeds the limit of 4 bytes")
+	f(&p, `{"a":{"b":[1, "12345"]}}`, 14, "a.b[1]", "too long string with 5 bytes; it exceeds the limit of 4 bytes")
+
+	// Too long keys
+	f(&p, `{"abcd":1}`, 1, "", "too long object key with 4 bytes; it exceeds the limit of 3 bytes")
+	f(&p, `{"a":[{"x":1,"verylongkey":2}]}`, 13, "a[0]", "too long object key with 11 bytes; it exceeds the limit of 3 bytes")
+	f(&p, `{"\u0061":1}`, 1, "", "too long object key with 6 bytes; it exceeds the limit of 3 bytes")
+
+	// Keys aren't limited by MaxStringLen
+	p.MaxKeyLen(0)
+	fSuccess(&p, `{"verylongkey":"abcd"}`)
+	f(&p, `{"verylongkey":"abcde"}`, 15, "verylongkey", "too long string with 5 bytes")
+
+	// The limits must be copied by Clone.
+	pc := p.Clone()
+	f(pc, `["abcde"]`, 1, "[0]", "too long string with 5 bytes")
+
+	// Zero limits mean no limits.
+	p.MaxStringLen(0)
+	fSuccess(&p, `{"verylongkey":"verylongvalue"}`)
+}
+
+func TestParserMaxNumberLen(t *testing.T) {
+	f := func(p *Parser, s string, offset int, path, errExpected string) {
+		t.Helper()
+		check := func(err error) {
+			t.Helper()
+			if err == nil {
+				t.Fatalf("expecting non-nil error for %s", s)
+			}
+			pe, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("expecting *ParseError for %s; got %T", s, err)
+			}
+			if pe.Offset != offset || pe.Path != path {
+				t.Fatalf("unexpected error location for %s; got (%d, %q); want (%d, %q)", s, pe.Offset, pe.Path, offset, path)
+			}
+			if !strings.Contains(err.Error(), errExpected) {
+				t.Fatalf("unexpected error for %s; got %q; must contain %q", s, err, errExpected)
+			}
+		}
+		_, err := p.Parse(s)
+		check(err)
+		opts := ValidateOptions{
+			MaxNumberLen: p.c.maxNumberLen,
+		}
+		err = ValidateWithOptions(s, opts)
+		check(err)
+		err = ValidateBytesWithOptions([]byte(s), opts)
+		check(err)
+	}
+	fSuccess := func(p *Parser, s string) {
+		t.Helper()
+		if _, err := p.Parse(s); err != nil {
+			t.Fatalf("unexpected error for %s: %s", s, err)
+		}
+		opts := ValidateOptions{
+			MaxNumberLen: p.c.maxNumberLen,
+		}
+		if err := ValidateWithOptions(s, opts); err != nil {
+			t.Fatalf("unexpected error from ValidateWithOptions for %s: %s", s, err)
 		}
+	}
 
-	})
+	var p Parser
+	p.MaxNumberLen(4)
+
+	// Numbers at the limit
+	fSuccess(&p, `1234`)
+	fSuccess(&p, `{"a":[-1.5,1e10,"12345"],"12345":true}`)
+
+	// Numbers one byte over the limit
+	f(&p, `12345`, 0, "", "too long number with 5 bytes; it exceeds the limit of 4 bytes")
+	f(&p, `{"a":{"b":[1, -1.25]}}`, 14, "a.b[1]", "too long number with 5 bytes; it exceeds the limit of 4 bytes")
+
+	// The limit must be copied by Clone.
+	pc := p.Clone()
+	f(pc, `[1e100]`, 1, "[0]", "too long number with 5 bytes")
+
+	// Zero limit means no limit.
+	p.MaxNumberLen(0)
+	fSuccess(&p, `[12345678901234567890]`)
 }
 
-func TestParseBigObject(t *testing.T) {
-	const itemsCount = 10000
+func TestParserHugeNumber(t *testing.T) {
+	const maxErrorLen = 1000
+	const maxAllocBytes = 1 << 20
+	s := "1" + strings.Repeat("2", 10<<20)
 
-	// build big json object
-	var ss []string
-	for i := 0; i < itemsCount; i++ {
-		s := fmt.Sprintf(`"key_%d": "value_%d"`, i, i)
-		ss = append(ss, s)
+	// The number must be rejected by the limit without copying it into the error.
+	var p Parser
+	p.MaxNumberLen(1024)
+	_, err := p.Parse(s)
+	if err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	if !strings.Contains(err.Error(), "too long number with 10485761 bytes; it exceeds the limit of 1024 bytes") {
+		t.Fatalf("unexpected error: %s", startEndString(err.Error()))
+	}
+	if n := len(err.Error()); n > maxErrorLen {
+		t.Fatalf("too long error message with %d bytes", n)
+	}
+	err = ValidateWithOptions(s, ValidateOptions{
+		MaxNumberLen: 1024,
+	})
+	if err == nil {
+		t.Fatalf("expecting non-nil error from ValidateWithOptions")
+	}
+	if !strings.Contains(err.Error(), "too long number with 10485761 bytes; it exceeds the limit of 1024 bytes") {
+		t.Fatalf("unexpected error from ValidateWithOptions: %s", startEndString(err.Error()))
+	}
+	if n := len(err.Error()); n > maxErrorLen {
+		t.Fatalf("too long error message from ValidateWithOptions with %d bytes", n)
+	}
+	if err := ValidateWithOptions(s, ValidateOptions{}); err != nil {
+		t.Fatalf("unexpected error from ValidateWithOptions without limits: %s", startEndString(err.Error()))
 	}
-	s := "{" + strings.Join(ss, ",") + "}"
 
-	// parse it
-	var p Parser
+	// The number must be parsed without the limit.
+	p.MaxNumberLen(0)
 	v, err := p.Parse(s)
 	if err != nil {
-		t.Fatalf("unexpected error: %s", err)
+		t.Fatalf("unexpected error: %s", startEndString(err.Error()))
 	}
 
-	// Look up object items
-	for i := 0; i < itemsCount; i++ {
-		k := fmt.Sprintf("key_%d", i)
-		expectedV := fmt.Sprintf("value_%d", i)
-		sb := v.GetStringBytes(k)
-		if string(sb) != expectedV {
-			t.Fatalf("unexpected value obtained; got %q; want %q", sb, expectedV)
+	// Accessors must fail without copying the number.
+	checkAllocs := func(name string, f func() error, errExpected error) {
+		t.Helper()
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		allocBytes := ms.TotalAlloc
+		var err error
+		for i := 0; i < 10; i++ {
+			err = f()
+		}
+		runtime.ReadMemStats(&ms)
+		if n := ms.TotalAlloc - allocBytes; n > maxAllocBytes {
+			t.Fatalf("%s: too many bytes allocated: %d", name, n)
 		}
+		if errExpected == nil {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %s", name, err)
+			}
+			return
+		}
+		if !isWrappedError(err, errExpected) {
+			t.Fatalf("%s: unexpected error %q; want wrapped %q", name, startEndString(err.Error()), errExpected)
+		}
+		if n := len(err.Error()); n > maxErrorLen {
+			t.Fatalf("%s: too long error message with %d bytes", name, n)
+		}
+	}
+	checkAllocs("Int64", func() error {
+		_, err := v.Int64()
+		return err
+	}, fastfloat.ErrRange)
+	checkAllocs("Uint64", func() error {
+		_, err := v.Uint64()
+		return err
+	}, fastfloat.ErrRange)
+	checkAllocs("Int", func() error {
+		_, err := v.Int()
+		return err
+	}, fastfloat.ErrRange)
+	checkAllocs("Uint", func() error {
+		_, err := v.Uint()
+		return err
+	}, fastfloat.ErrRange)
+	checkAllocs("GetInt64", func() error {
+		if n := v.GetInt64(); n != 0 {
+			return fmt.Errorf("unexpected number: %d", n)
+		}
+		return nil
+	}, nil)
+	checkAllocs("GetUint64", func() error {
+		if n := v.GetUint64(); n != 0 {
+			return fmt.Errorf("unexpected number: %d", n)
+		}
+		return nil
+	}, nil)
+	// The number exceeds float64 range, so Float64 returns +Inf. The result
+	// is cached, so only the first call scans the number.
+	if _, err := v.Float64(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
 	}
+	checkAllocs("Float64", func() error {
+		f, err := v.Float64()
+		if err == nil && !math.IsInf(f, 1) {
+			return fmt.Errorf("unexpected number: %v", f)
+		}
+		return err
+	}, nil)
 
-	// verify non-existing key returns nil
-	sb := v.GetStringBytes("non-existing-key")
-	if sb != nil {
-		t.Fatalf("unexpected non-nil value for non-existing-key: %q", sb)
+	// Invalid huge numbers must fail without copying them.
+	v, err = p.Parse("1" + strings.Repeat("-", 10<<20))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", startEndString(err.Error()))
 	}
-}
+	checkAllocs("Int64 for invalid number", func() error {
+		_, err := v.Int64()
+		return err
+	}, fastfloat.ErrSyntax)
+	checkAllocs("Float64 for invalid number", func() error {
+		_, err := v.Float64()
+		return err
+	}, fastfloat.ErrSyntax)
 
-func TestParseGetConcurrent(t *testing.T) {
-	concurrency := 10
-	ch := make(chan error, concurrency)
-	s := `{"foo": "bar", "empty_obj": {}}`
-	for i := 0; i < concurrency; i++ {
-		go func() {
-			ch <- testParseGetSerial(s)
-		}()
+	// Numbers with leading zeros are parsed as before.
+	v = MustParse(strings.Repeat("0", 100) + "123")
+	if n, err := v.Uint64(); err != nil || n != 123 {
+		t.Fatalf("unexpected result for number with leading zeros; got %d, %v; want 123", n, err)
 	}
-	for i := 0; i < concurrency; i++ {
-		select {
-		case err := <-ch:
-			if err != nil {
-				t.Fatalf("unexpected error during concurrent test: %s", err)
-			}
-		case <-time.After(time.Second):
-			t.Fatalf("timeout")
-		}
+	if n, err := v.Int64(); err != nil || n != 123 {
+		t.Fatalf("unexpected result for number with leading zeros; got %d, %v; want 123", n, err)
 	}
-}
 
-func testParseGetSerial(s string) error {
-	var p Parser
-	for i := 0; i < 100; i++ {
-		v, err := p.Parse(s)
-		if err != nil {
-			return fmt.Errorf("cannot parse %q: %s", s, err)
+	// Errors for huge invalid values must be truncated.
+	for _, s := range []string{"tru", "fals", "nul", "123"} {
+		_, err := p.Parse(s + strings.Repeat(" x", 1<<20))
+		if err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
 		}
-		sb := v.GetStringBytes("foo")
-		if string(sb) != "bar" {
-			return fmt.Errorf("unexpected value for key=%q; got %q; want %q", "foo", sb, "bar")
+		if n := len(err.Error()); n > maxErrorLen {
+			t.Fatalf("too long error message with %d bytes for %q", n, s)
 		}
-		vv := v.Get("empty_obj", "non-existing-key")
-		if vv != nil {
-			return fmt.Errorf("unexpected non-nil value got: %s", vv)
+		err = Validate(s + strings.Repeat(" x", 1<<20))
+		if err == nil {
+			t.Fatalf("expecting non-nil error from Validate for %q", s)
+		}
+		if n := len(err.Error()); n > maxErrorLen {
+			t.Fatalf("too long error message from Validate with %d bytes for %q", n, s)
 		}
 	}
-	return nil
 }
@@ -1,10 +1,12 @@
 package fastjson
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"strings"
+	"sync/atomic"
 	"testing"
 )
 
@@ -108,6 +110,45 @@ func benchmarkObjectGet(b *testing.B, itemsCount, lookupsCount int) {
 	})
 }
 
+func BenchmarkObjectGetSeveral(b *testing.B) {
+	var ss []string
+	for i := 0; i < 50; i++ {
+		ss = append(ss, fmt.Sprintf(`"key_%d": "value_%d"`, i, i))
+	}
+	v := MustParse("{" + strings.Join(ss, ",") + "}")
+	o := v.GetObject()
+	var keys []string
+	for i := 0; i < 8; i++ {
+		keys = append(keys, fmt.Sprintf("key_%d", 5+i*6))
+	}
+	b.Run("Get", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			var sink int
+			for pb.Next() {
+				for _, key := range keys {
+					sink += len(o.Get(key).GetStringBytes())
+				}
+			}
+			atomic.AddUint64(&Sink, uint64(sink))
+		})
+	})
+	b.Run("GetSeveral", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			var sink int
+			var vs []*Value
+			for pb.Next() {
+				vs = o.GetSeveral(vs[:0], keys...)
+				for _, v := range vs {
+					sink += len(v.GetStringBytes())
+				}
+			}
+			atomic.AddUint64(&Sink, uint64(sink))
+		})
+	})
+}
+
 func BenchmarkMarshalTo(b *testing.B) {
 	b.Run("small", func(b *testing.B) {
 		benchmarkMarshalTo(b, smallFixture)
@@ -149,6 +190,137 @@ func benchmarkMarshalTo(b *testing.B, s string) {
 	benchPool.Put(p)
 }
 
+func BenchmarkMarshalToPooled(b *testing.B) {
+	// Mixed-size workload from 0.2KB to 2MB.
+	var vs []*Value
+	n := 0
+	for _, s := range []string{smallFixture, mediumFixture, largeFixture, canadaFixture, citmFixture, twitterFixture} {
+		v := MustParse(s)
+		v.Normalize()
+		vs = append(vs, v)
+		n += len(s)
+	}
+	b.Run("MarshalTo-nil", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(n))
+		b.RunParallel(func(pb *testing.PB) {
+			var sink int
+			for pb.Next() {
+				for _, v := range vs {
+					buf := v.MarshalTo(nil)
+					sink += len(buf)
+				}
+			}
+			atomic.AddUint64(&Sink, uint64(sink))
+		})
+	})
+	b.Run("MarshalToPooled", func(b *testing.B) {
+		var bp BufferPool
+		b.ReportAllocs()
+		b.SetBytes(int64(n))
+		b.RunParallel(func(pb *testing.PB) {
+			var sink int
+			for pb.Next() {
+				for _, v := range vs {
+					buf, release := v.MarshalToPooled(&bp)
+					sink += len(buf)
+					release()
+				}
+			}
+			atomic.AddUint64(&Sink, uint64(sink))
+		})
+	})
+}
+
+func BenchmarkValueString(b *testing.B) {
+	p := benchPool.Get()
+	v, err := p.Parse(mediumFixture)
+	if err != nil {
+		panic(fmt.Errorf("unexpected error: %s", err))
+	}
+	f := func(b *testing.B, appendString func(dst []byte) []byte) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(mediumFixture)))
+		b.RunParallel(func(pb *testing.PB) {
+			var b []byte
+			var n int
+			for pb.Next() {
+				b = appendString(b[:0])
+				n += len(b)
+			}
+			atomic.AddUint64(&Sink, uint64(n))
+		})
+	}
+	b.Run("MarshalTo-nil", func(b *testing.B) {
+		// This is how String worked without pre-allocation.
+		f(b, func(dst []byte) []byte {
+			return v.MarshalTo(nil)
+		})
+	})
+	b.Run("String", func(b *testing.B) {
+		f(b, func(dst []byte) []byte {
+			return append(dst, v.String()...)
+		})
+	})
+	b.Run("AppendString", func(b *testing.B) {
+		f(b, func(dst []byte) []byte {
+			return v.AppendString(dst)
+		})
+	})
+	benchPool.Put(p)
+}
+
+func BenchmarkValueClone(b *testing.B) {
+	v := MustParse(mediumFixture)
+
+	// Unescape strings in advance, so Clone doesn't modify v
+	// from concurrent goroutines.
+	v.Clone()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(mediumFixture)))
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			vc := v.Clone()
+			if vc.Type() != TypeObject {
+				panic(fmt.Errorf("unexpected value type; got %s; want %s", vc.Type(), TypeObject))
+			}
+		}
+	})
+}
+
+func BenchmarkValueCloneInternKeys(b *testing.B) {
+	var ss []string
+	for i := 0; i < 100000; i++ {
+		ss = append(ss, fmt.Sprintf(`{"id":%d,"name":"item","price":1.5,"available":true,"category":"x"}`, i))
+	}
+	s := "[" + strings.Join(ss, ",") + "]"
+
+	f := func(b *testing.B, intern bool) {
+		var p Parser
+		p.InternKeys(intern)
+		v, err := p.Parse(s)
+		if err != nil {
+			panic(fmt.Errorf("unexpected error: %s", err))
+		}
+		b.ReportAllocs()
+		b.SetBytes(int64(len(s)))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			vc := v.Clone()
+			if vc.Type() != TypeArray {
+				panic(fmt.Errorf("unexpected value type; got %s; want %s", vc.Type(), TypeArray))
+			}
+		}
+	}
+	b.Run("no-intern", func(b *testing.B) {
+		f(b, false)
+	})
+	b.Run("intern", func(b *testing.B) {
+		f(b, true)
+	})
+}
+
 func BenchmarkParse(b *testing.B) {
 	b.Run("small", func(b *testing.B) {
 		benchmarkParse(b, smallFixture)
@@ -156,6 +328,9 @@ func BenchmarkParse(b *testing.B) {
 	b.Run("medium", func(b *testing.B) {
 		benchmarkParse(b, mediumFixture)
 	})
+	b.Run("medium-pretty", func(b *testing.B) {
+		benchmarkParse(b, mediumPrettyFixture)
+	})
 	b.Run("large", func(b *testing.B) {
 		benchmarkParse(b, largeFixture)
 	})
@@ -180,8 +355,55 @@ var (
 	canadaFixture  = getFromFile("testdata/canada.json")
 	citmFixture    = getFromFile("testdata/citm_catalog.json")
 	twitterFixture = getFromFile("testdata/twitter.json")
+
+	// mediumPrettyFixture contains lots of whitespace between tokens.
+	mediumPrettyFixture = indentJSON(mediumFixture)
 )
 
+func indentJSON(s string) string {
+	var bb bytes.Buffer
+	if err := json.Indent(&bb, []byte(s), "", "    "); err != nil {
+		panic(fmt.Errorf("cannot indent JSON: %s", err))
+	}
+	return bb.String()
+}
+
+func BenchmarkSkipWS(b *testing.B) {
+	for _, n := range []int{1, 4, 16, 64} {
+		s := strings.Repeat(" \n\t\r", n/4+1)[:n] + "x"
+		b.Run(fmt.Sprintf("len_%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(s)))
+			b.RunParallel(func(pb *testing.PB) {
+				var sink int
+				for pb.Next() {
+					sink += len(skipWS(s))
+				}
+				atomic.AddUint64(&Sink, uint64(sink))
+			})
+		})
+	}
+}
+
+func BenchmarkHasSpecialChars(b *testing.B) {
+	for _, n := range []int{4, 16, 64, 256} {
+		s := strings.Repeat("abcdefgh", n/8+1)[:n]
+		b.Run(fmt.Sprintf("len_%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(s)))
+			b.RunParallel(func(pb *testing.PB) {
+				var sink int
+				for pb.Next() {
+					if !hasSpecialChars(s) {
+						sink++
+					}
+				}
+				atomic.AddUint64(&Sink, uint64(sink))
+			})
+		})
+	}
+}
+
 func getFromFile(filename string) string {
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -190,6 +412,62 @@ func getFromFile(filename string) string {
 	return string(data)
 }
 
+func BenchmarkParseOwning(b *testing.B) {
+	b.Run("large", func(b *testing.B) {
+		benchmarkParseOwning(b, largeFixture)
+	})
+	b.Run("canada", func(b *testing.B) {
+		benchmarkParseOwning(b, canadaFixture)
+	})
+	b.Run("twitter", func(b *testing.B) {
+		benchmarkParseOwning(b, twitterFixture)
+	})
+}
+
+func benchmarkParseOwning(b *testing.B, s string) {
+	// Both benchmarks copy s into a pooled buffer in order to simulate
+	// reading incoming message.
+	b.Run("ParseBytes", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(s)))
+		b.RunParallel(func(pb *testing.PB) {
+			p := benchPool.Get()
+			var buf []byte
+			for pb.Next() {
+				buf = append(buf[:0], s...)
+				v, err := p.ParseBytes(buf)
+				if err != nil {
+					panic(fmt.Errorf("unexpected error: %s", err))
+				}
+				if v.Type() != TypeObject {
+					panic(fmt.Errorf("unexpected value type; got %s; want %s", v.Type(), TypeObject))
+				}
+			}
+			benchPool.Put(p)
+		})
+	})
+	b.Run("ParseOwning", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(s)))
+		b.RunParallel(func(pb *testing.PB) {
+			p := benchPool.Get()
+			var buf []byte
+			for pb.Next() {
+				buf = append(buf[:0], s...)
+				v, err := p.ParseOwning(buf)
+				if err != nil {
+					panic(fmt.Errorf("unexpected error: %s", err))
+				}
+				if v.Type() != TypeObject {
+					panic(fmt.Errorf("unexpected value type; got %s; want %s", v.Type(), TypeObject))
+				}
+				buf = p.SwapBuffer(nil)
+			}
+			benchPool.Put(p)
+		})
+	})
+}
+
 func benchmarkParse(b *testing.B, s string) {
 	b.Run("stdjson-map", func(b *testing.B) {
 		benchmarkStdJSONParseMap(b, s)
@@ -266,6 +544,27 @@ func benchmarkFastJSONParseGet(b *testing.B, s string) {
 
 var benchPool ParserPool
 
+func BenchmarkParserPoolStats(b *testing.B) {
+	f := func(b *testing.B, pp *ParserPool) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			var sink int
+			for pb.Next() {
+				p := pp.Get()
+				sink += p.BufferCap()
+				pp.Put(p)
+			}
+			atomic.AddUint64(&Sink, uint64(sink))
+		})
+	}
+	b.Run("without-stats", func(b *testing.B) {
+		f(b, NewParserPool(0))
+	})
+	b.Run("with-stats", func(b *testing.B) {
+		f(b, NewParserPoolWithStats(0))
+	})
+}
+
 func benchmarkStdJSONParseMap(b *testing.B, s string) {
 	b.ReportAllocs()
 	b.SetBytes(int64(len(s)))
@@ -316,3 +615,57 @@ func benchmarkStdJSONParseEmptyStruct(b *testing.B, s string) {
 		}
 	})
 }
+
+func BenchmarkWriteTo(b *testing.B) {
+	v := MustParse(canadaFixture)
+	b.Run("MarshalTo", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(canadaFixture)))
+		for i := 0; i < b.N; i++ {
+			// Allocate a new buffer on every iteration in order to measure
+			// the peak memory usage for marshaling the whole value at once.
+			buf := v.MarshalTo(nil)
+			if len(buf) == 0 {
+				panic("BUG: empty marshaled value")
+			}
+		}
+	})
+	b.Run("WriteTo", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(canadaFixture)))
+		for i := 0; i < b.N; i++ {
+			n, err := v.WriteTo(ioutil.Discard)
+			if err != nil {
+				panic(fmt.Errorf("unexpected error: %s", err))
+			}
+			if n == 0 {
+				panic("BUG: empty written value")
+			}
+		}
+	})
+}
+
+func BenchmarkParserFirstParse(b *testing.B) {
+	var pWarm Parser
+	if _, err := pWarm.Parse(largeFixture); err != nil {
+		b.Fatalf("cannot parse large fixture: %s", err)
+	}
+	f := func(b *testing.B, newParser func() *Parser) {
+		b.SetBytes(int64(len(largeFixture)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p := newParser()
+			if _, err := p.Parse(largeFixture); err != nil {
+				b.Fatalf("unexpected error: %s", err)
+			}
+		}
+	}
+	b.Run("cold", func(b *testing.B) {
+		f(b, func() *Parser {
+			return &Parser{}
+		})
+	})
+	b.Run("clone", func(b *testing.B) {
+		f(b, pWarm.Clone)
+	})
+}
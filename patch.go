@@ -0,0 +1,394 @@
+package fastjson
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ApplyPatch applies JSON Patch (RFC 6902) to doc.
+//
+// patch must be an array of operation objects. add, remove, replace,
+// move, copy and test operations are supported. Paths are JSON Pointers
+// (RFC 6901). The "-" array index refers to the position after
+// the last array item for add, move and copy operations.
+//
+// The patch is applied atomically: doc remains unchanged if any operation
+// fails. On success the contents of doc are replaced by the patched copy
+// allocated from a, so doc is valid until Reset is called on a.
+// doc cannot be true, false or null returned from Parse*, since these
+// values are shared.
+// patch isn't referenced by doc after the call.
+func ApplyPatch(doc, patch *Value, a *Arena) error {
+	if doc == nil {
+		return fmt.Errorf("cannot apply patch to nil value")
+	}
+	if isSharedValue(doc) {
+		// true, false and null are shared among all the parsed values,
+		// so they cannot be overwritten with the patched value.
+		return fmt.Errorf("cannot apply patch to %s value; apply it to the containing object or array instead", doc.Type())
+	}
+	if patch == nil || patch.t != TypeArray {
+		return fmt.Errorf("patch must be an array")
+	}
+	pa := patcher{
+		a:    a,
+		root: a.deepCopy(doc),
+	}
+	for i, op := range patch.a {
+		if err := pa.apply(op); err != nil {
+			return fmt.Errorf("cannot apply patch operation #%d: %s", i, err)
+		}
+	}
+	*doc = *pa.root
+	return nil
+}
+
+type patcher struct {
+	a    *Arena
+	root *Value
+}
+
+func (pa *patcher) apply(op *Value) error {
+	if op.Type() != TypeObject {
+		return fmt.Errorf("operation must be an object; got %s", op.Type())
+	}
+	name, err := getPatchString(op, "op")
+	if err != nil {
+		return err
+	}
+	path, err := getPatchPointer(op, "path")
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "add":
+		value, err := getPatchValue(op)
+		if err != nil {
+			return err
+		}
+		return pa.add(path, pa.a.deepCopy(value))
+	case "remove":
+		_, err := pa.remove(path)
+		return err
+	case "replace":
+		value, err := getPatchValue(op)
+		if err != nil {
+			return err
+		}
+		return pa.replace(path, pa.a.deepCopy(value))
+	case "move":
+		from, err := getPatchPointer(op, "from")
+		if err != nil {
+			return err
+		}
+		if isPointerPrefix(from, path) && len(from) < len(path) {
+			return fmt.Errorf("cannot move %q into its child %q", formatPointer(from), formatPointer(path))
+		}
+		v, err := pa.remove(from)
+		if err != nil {
+			return err
+		}
+		return pa.add(path, v)
+	case "copy":
+		from, err := getPatchPointer(op, "from")
+		if err != nil {
+			return err
+		}
+		v, err := pa.get(from)
+		if err != nil {
+			return err
+		}
+		return pa.add(path, pa.a.deepCopy(v))
+	case "test":
+		value, err := getPatchValue(op)
+		if err != nil {
+			return err
+		}
+		v, err := pa.get(path)
+		if err != nil {
+			return err
+		}
+		if !patchValuesEqual(v, value) {
+			return fmt.Errorf("test failed for %q: got %s; want %s", formatPointer(path), v, value)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported op %q", name)
+	}
+}
+
+// get returns the value located at the given path.
+func (pa *patcher) get(path []string) (*Value, error) {
+	v := pa.root
+	for i, token := range path {
+		switch v.Type() {
+		case TypeObject:
+			vv := v.o.Get(token)
+			if vv == nil {
+				return nil, fmt.Errorf("missing key %q at %q", token, formatPointer(path[:i]))
+			}
+			v = vv
+		case TypeArray:
+			n, err := parseArrayIndex(token, len(v.a)-1)
+			if err != nil {
+				return nil, fmt.Errorf("%s at %q", err, formatPointer(path[:i]))
+			}
+			v = v.a[n]
+		default:
+			return nil, fmt.Errorf("cannot get %q from %s at %q", token, v.Type(), formatPointer(path[:i]))
+		}
+	}
+	return v, nil
+}
+
+// add adds v at the given path.
+func (pa *patcher) add(path []string, v *Value) error {
+	if len(path) == 0 {
+		pa.root = v
+		return nil
+	}
+	parent, err := pa.get(path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	token := path[len(path)-1]
+	switch parent.t {
+	case TypeObject:
+		parent.o.Set(pa.a.copyString(token), v)
+		return nil
+	case TypeArray:
+		n := len(parent.a)
+		if token != "-" {
+			n, err = parseArrayIndex(token, len(parent.a))
+			if err != nil {
+				return fmt.Errorf("%s at %q", err, formatPointer(path[:len(path)-1]))
+			}
+		}
+		parent.a = append(parent.a, nil)
+		copy(parent.a[n+1:], parent.a[n:])
+		parent.a[n] = v
+		return nil
+	default:
+		return fmt.Errorf("cannot add %q to %s at %q", token, parent.t, formatPointer(path[:len(path)-1]))
+	}
+}
+
+// remove removes the value at the given path and returns it.
+func (pa *patcher) remove(path []string) (*Value, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot remove the root value")
+	}
+	v, err := pa.get(path)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := pa.get(path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	if parent.t == TypeObject {
+		parent.o.Del(token)
+		return v, nil
+	}
+	n, err := parseArrayIndex(token, len(parent.a)-1)
+	if err != nil {
+		return nil, err
+	}
+	parent.a = append(parent.a[:n], parent.a[n+1:]...)
+	return v, nil
+}
+
+// replace replaces the value at the given path with v.
+func (pa *patcher) replace(path []string, v *Value) error {
+	if len(path) == 0 {
+		pa.root = v
+		return nil
+	}
+	if _, err := pa.get(path); err != nil {
+		return err
+	}
+	parent, err := pa.get(path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	token := path[len(path)-1]
+	if parent.t == TypeObject {
+		parent.o.Set(pa.a.copyString(token), v)
+		return nil
+	}
+	n, err := parseArrayIndex(token, len(parent.a)-1)
+	if err != nil {
+		return err
+	}
+	parent.a[n] = v
+	return nil
+}
+
+func getPatchString(op *Value, key string) (string, error) {
+	v := op.Get(key)
+	if v == nil {
+		return "", fmt.Errorf("missing %q member", key)
+	}
+	sb, err := v.StringBytes()
+	if err != nil {
+		return "", fmt.Errorf("cannot obtain %q member: %s", key, err)
+	}
+	return b2s(sb), nil
+}
+
+func getPatchPointer(op *Value, key string) ([]string, error) {
+	s, err := getPatchString(op, key)
+	if err != nil {
+		return nil, err
+	}
+	path, err := parsePointer(s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %q member: %s", key, err)
+	}
+	return path, nil
+}
+
+func getPatchValue(op *Value) (*Value, error) {
+	v := op.Get("value")
+	if v == nil {
+		return nil, fmt.Errorf(`missing "value" member`)
+	}
+	return v, nil
+}
+
+// parsePointer splits JSON Pointer s into unescaped reference tokens.
+func parsePointer(s string) ([]string, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("JSON Pointer must start with '/'; got %q", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, token := range tokens {
+		if strings.IndexByte(token, '~') < 0 {
+			continue
+		}
+		b := make([]byte, 0, len(token))
+		for j := 0; j < len(token); j++ {
+			if token[j] != '~' {
+				b = append(b, token[j])
+				continue
+			}
+			if j+1 < len(token) && token[j+1] == '0' {
+				b = append(b, '~')
+			} else if j+1 < len(token) && token[j+1] == '1' {
+				b = append(b, '/')
+			} else {
+				return nil, fmt.Errorf("invalid escape sequence in JSON Pointer %q", s)
+			}
+			j++
+		}
+		tokens[i] = b2s(b)
+	}
+	return tokens, nil
+}
+
+// formatPointer returns JSON Pointer for the given reference tokens.
+func formatPointer(path []string) string {
+	var b []byte
+	for _, token := range path {
+		b = append(b, '/')
+		for i := 0; i < len(token); i++ {
+			switch token[i] {
+			case '~':
+				b = append(b, '~', '0')
+			case '/':
+				b = append(b, '~', '1')
+			default:
+				b = append(b, token[i])
+			}
+		}
+	}
+	return string(b)
+}
+
+// isPointerPrefix returns true if prefix is a prefix of path.
+func isPointerPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i, token := range prefix {
+		if path[i] != token {
+			return false
+		}
+	}
+	return true
+}
+
+// parseArrayIndex parses JSON Pointer array index s in the range [0..maxIndex].
+func parseArrayIndex(s string, maxIndex int) (int, error) {
+	if len(s) == 0 || len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("invalid array index %q", s)
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, fmt.Errorf("invalid array index %q", s)
+		}
+		n = n*10 + int(s[i]-'0')
+		if n > maxIndex {
+			return 0, fmt.Errorf("array index %q is out of range [0..%d]", s, maxIndex)
+		}
+	}
+	return n, nil
+}
+
+// patchValuesEqual returns true if v and w are equal according to RFC 6902
+// test operation rules.
+//
+// Unlike Value.Equal, object entries are compared regardless of their order,
+// and numbers are compared by their values.
+func patchValuesEqual(v, w *Value) bool {
+	t := v.Type()
+	if t != w.Type() {
+		return false
+	}
+	switch t {
+	case TypeObject:
+		if v.o.Len() != w.o.Len() {
+			return false
+		}
+		w.o.unescapeKeys()
+		for _, kv := range w.o.kvs {
+			vv := v.o.Get(kv.k)
+			if vv == nil || !patchValuesEqual(vv, kv.v) {
+				return false
+			}
+		}
+		return true
+	case TypeArray:
+		if len(v.a) != len(w.a) {
+			return false
+		}
+		for i, vv := range v.a {
+			if !patchValuesEqual(vv, w.a[i]) {
+				return false
+			}
+		}
+		return true
+	case TypeString:
+		return v.s == w.s
+	case TypeNumber:
+		return numbersEqual(v, w)
+	default:
+		// null, true and false
+		return true
+	}
+}
+
+// numbersEqual returns true if numbers v and w have equal values.
+func numbersEqual(v, w *Value) bool {
+	if v.s == w.s {
+		return true
+	}
+	f1, err1 := v.Float64()
+	f2, err2 := w.Float64()
+	return err1 == nil && err2 == nil && f1 == f2
+}
@@ -0,0 +1,160 @@
+package fastjson
+
+import (
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	var a Arena
+	f := func(doc, patch, resultExpected string) {
+		t.Helper()
+		v := MustParse(doc)
+		p := MustParse(patch)
+		if err := ApplyPatch(v, p, &a); err != nil {
+			t.Fatalf("unexpected error when applying %s to %s: %s", patch, doc, err)
+		}
+		vExpected := MustParse(resultExpected)
+		if !patchValuesEqual(v, vExpected) {
+			t.Fatalf("unexpected result when applying %s to %s\ngot\n%s\nwant\n%s", patch, doc, v, vExpected)
+		}
+	}
+
+	// Examples from RFC 6902 appendix A.
+	// A.1. Adding an Object Member
+	f(`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`)
+	// A.2. Adding an Array Element
+	f(`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`)
+	// A.3. Removing an Object Member
+	f(`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`)
+	// A.4. Removing an Array Element
+	f(`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`)
+	// A.5. Replacing a Value
+	f(`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`)
+	// A.6. Moving a Value
+	f(`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+		`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`)
+	// A.7. Moving an Array Element
+	f(`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`)
+	// A.8. Testing a Value: Success
+	f(`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`)
+	// A.10. Adding a Nested Member Object
+	f(`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`)
+	// A.11. Ignoring Unrecognized Elements
+	f(`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"foo":"bar","baz":"qux"}`)
+	// A.14. ~ Escape Ordering
+	f(`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`)
+	// A.16. Adding an Array Value
+	f(`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`)
+
+	// Copy
+	f(`{"a":{"b":[1,2]}}`, `[{"op":"copy","from":"/a/b","path":"/c"},{"op":"add","path":"/c/-","value":3}]`, `{"a":{"b":[1,2]},"c":[1,2,3]}`)
+	// The whole document
+	f(`{"a":1}`, `[{"op":"replace","path":"","value":[1]},{"op":"add","path":"/0","value":0}]`, `[0,1]`)
+	f(`{"a":1}`, `[{"op":"test","path":"","value":{"a":1.0}}]`, `{"a":1}`)
+	// Moving to the same location
+	f(`{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a"}]`, `{"a":{"b":1}}`)
+	// Keys with escape sequences
+	f(`{"a/b":{"c~d":1}}`, `[{"op":"replace","path":"/a~1b/c~0d","value":"x\ny"}]`, `{"a/b":{"c~d":"x\ny"}}`)
+	// Test op compares objects regardless of the order of entries
+	f(`{"a":{"x":1,"y":[true,null]}}`, `[{"op":"test","path":"/a","value":{"y":[true,null],"x":1}}]`, `{"a":{"x":1,"y":[true,null]}}`)
+	// Empty patch
+	f(`[1,2]`, `[]`, `[1,2]`)
+}
+
+func TestApplyPatchError(t *testing.T) {
+	var a Arena
+	f := func(doc, patch string) {
+		t.Helper()
+		v := MustParse(doc)
+		p := MustParse(patch)
+		if err := ApplyPatch(v, p, &a); err == nil {
+			t.Fatalf("expecting non-nil error when applying %s to %s", patch, doc)
+		}
+		// The doc must remain unchanged on error.
+		if s := v.String(); s != doc {
+			t.Fatalf("unexpected doc after failed patch %s; got %s; want %s", patch, s, doc)
+		}
+	}
+
+	// A.9. Testing a Value: Error
+	f(`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`)
+	// A.12. Adding to a Nonexistent Target
+	f(`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`)
+	// A.13. Invalid JSON Patch Document
+	f(`{"foo":"bar"}`, `[{"op":"ad","path":"/baz","value":"qux"}]`)
+	// A.15. Comparing Strings and Numbers
+	f(`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":"10"}]`)
+
+	// Out of range indexes
+	f(`{"foo":[1,2]}`, `[{"op":"add","path":"/foo/3","value":1}]`)
+	f(`{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/2"}]`)
+	f(`{"foo":[1,2]}`, `[{"op":"replace","path":"/foo/-","value":1}]`)
+	f(`{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/01"}]`)
+	f(`{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/-1"}]`)
+	f(`{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/99999999999999999999999"}]`)
+
+	// Atomicity: the first operation succeeds, while the second one fails.
+	f(`{"foo":[1,2]}`, `[{"op":"add","path":"/bar","value":1},{"op":"test","path":"/foo/0","value":2}]`)
+	f(`{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/0"},{"op":"remove","path":"/missing"}]`)
+
+	// Invalid operations
+	f(`{}`, `{}`)
+	f(`{}`, `[1]`)
+	f(`{}`, `[{"path":"/a","value":1}]`)
+	f(`{}`, `[{"op":"add","value":1}]`)
+	f(`{}`, `[{"op":"add","path":"a","value":1}]`)
+	f(`{}`, `[{"op":"add","path":"/a~2","value":1}]`)
+	f(`{}`, `[{"op":"add","path":"/a"}]`)
+	f(`{"a":1}`, `[{"op":"move","path":"/b"}]`)
+	f(`{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`)
+	f(`{"a":1}`, `[{"op":"copy","from":"/b","path":"/c"}]`)
+	f(`{"a":1}`, `[{"op":"remove","path":""}]`)
+	f(`{"a":1}`, `[{"op":"add","path":"/a/b","value":1}]`)
+}
+
+func TestApplyPatchSharedValue(t *testing.T) {
+	var a Arena
+	v := MustParse(`{"x":null,"t":true,"f":false}`)
+	for _, key := range []string{"x", "t", "f"} {
+		err := ApplyPatch(v.Get(key), MustParse(`[{"op":"replace","path":"","value":{"a":1}}]`), &a)
+		if err == nil {
+			t.Fatalf("expecting non-nil error when patching the shared value at %q", key)
+		}
+	}
+	if s := v.String(); s != `{"x":null,"t":true,"f":false}` {
+		t.Fatalf("unexpected doc after failed patches; got %s", s)
+	}
+
+	// Values parsed by other parsers must remain unchanged.
+	var p Parser
+	vv, err := p.Parse(`[null,true,false]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := vv.String(); s != `[null,true,false]` {
+		t.Fatalf("unexpected value; got %s; want %s", s, `[null,true,false]`)
+	}
+}
+
+func TestApplyPatchValuesLifetime(t *testing.T) {
+	var a Arena
+	var p Parser
+	v := MustParse(`{"a":[1]}`)
+	patch, err := p.Parse(`[{"op":"add","path":"/b","value":{"c":"d"}},{"op":"add","path":"/a/-","value":"x"}]`)
+	if err != nil {
+		t.Fatalf("cannot parse patch: %s", err)
+	}
+	if err := ApplyPatch(v, patch, &a); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The patched doc mustn't refer to the patch.
+	if _, err := p.Parse(`{"foo":"barbazbarbazbarbazbarbazbarbazbarbazbarbazbarbazbarbaz"}`); err != nil {
+		t.Fatalf("cannot parse JSON: %s", err)
+	}
+	s := v.String()
+	sExpected := `{"a":[1,"x"],"b":{"c":"d"}}`
+	if s != sExpected {
+		t.Fatalf("unexpected doc; got %s; want %s", s, sExpected)
+	}
+}
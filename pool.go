@@ -1,12 +1,44 @@
 package fastjson
 
 import (
+	"math/bits"
 	"sync"
+	"sync/atomic"
 )
 
 // ParserPool may be used for pooling Parsers for similarly typed JSONs.
+//
+// The zero value ParserPool retains Parsers of any size.
+// Use NewParserPool for limiting the memory retained by the pool.
 type ParserPool struct {
 	pool sync.Pool
+
+	maxRetainedBytes int
+
+	// stats is non-nil only for pools created via NewParserPoolWithStats.
+	stats *poolStats
+}
+
+// NewParserPool returns new ParserPool, which doesn't retain Parsers
+// with MemoryFootprint exceeding maxRetainedBytes.
+//
+// This prevents from pinning big amounts of memory in the pool after parsing
+// occasional big JSONs.
+//
+// Zero maxRetainedBytes means unlimited.
+func NewParserPool(maxRetainedBytes int) *ParserPool {
+	return &ParserPool{
+		maxRetainedBytes: maxRetainedBytes,
+	}
+}
+
+// NewParserPoolWithStats is like NewParserPool, but the returned pool
+// also maintains usage counters, which may be obtained via Stats.
+func NewParserPoolWithStats(maxRetainedBytes int) *ParserPool {
+	return &ParserPool{
+		maxRetainedBytes: maxRetainedBytes,
+		stats:            &poolStats{},
+	}
 }
 
 // Get returns a Parser from pp.
@@ -14,6 +46,7 @@ type ParserPool struct {
 // The Parser must be Put to pp after use.
 func (pp *ParserPool) Get() *Parser {
 	v := pp.pool.Get()
+	pp.stats.get(v == nil)
 	if v == nil {
 		return &Parser{}
 	}
@@ -25,12 +58,55 @@ func (pp *ParserPool) Get() *Parser {
 // p and objects recursively returned from p cannot be used after p
 // is put into pp.
 func (pp *ParserPool) Put(p *Parser) {
+	if pp.maxRetainedBytes > 0 && p.MemoryFootprint() > pp.maxRetainedBytes {
+		// Drop the oversized parser, so its memory may be reclaimed by GC.
+		pp.stats.put(true)
+		return
+	}
+	// Drop references to the parsed JSON, so they aren't retained in pp.
+	p.v = nil
+	p.c.reset()
+	pp.stats.put(false)
 	pp.pool.Put(p)
 }
 
+// Stats returns usage counters for pp.
+//
+// Zero counters are returned if pp isn't created via NewParserPoolWithStats.
+func (pp *ParserPool) Stats() PoolStats {
+	return pp.stats.load()
+}
+
 // ArenaPool may be used for pooling Arenas for similarly typed JSONs.
+//
+// The zero value ArenaPool retains Arenas of any size.
+// Use NewArenaPool for limiting the memory retained by the pool.
 type ArenaPool struct {
 	pool sync.Pool
+
+	maxRetainedBytes int
+
+	// stats is non-nil only for pools created via NewArenaPoolWithStats.
+	stats *poolStats
+}
+
+// NewArenaPool returns new ArenaPool, which doesn't retain Arenas
+// with MemoryFootprint exceeding maxRetainedBytes.
+//
+// Zero maxRetainedBytes means unlimited.
+func NewArenaPool(maxRetainedBytes int) *ArenaPool {
+	return &ArenaPool{
+		maxRetainedBytes: maxRetainedBytes,
+	}
+}
+
+// NewArenaPoolWithStats is like NewArenaPool, but the returned pool
+// also maintains usage counters, which may be obtained via Stats.
+func NewArenaPoolWithStats(maxRetainedBytes int) *ArenaPool {
+	return &ArenaPool{
+		maxRetainedBytes: maxRetainedBytes,
+		stats:            &poolStats{},
+	}
 }
 
 // Get returns an Arena from ap.
@@ -38,6 +114,7 @@ type ArenaPool struct {
 // The Arena must be Put to ap after use.
 func (ap *ArenaPool) Get() *Arena {
 	v := ap.pool.Get()
+	ap.stats.get(v == nil)
 	if v == nil {
 		return &Arena{}
 	}
@@ -48,5 +125,223 @@ func (ap *ArenaPool) Get() *Arena {
 //
 // a and objects created by a cannot be used after a is put into ap.
 func (ap *ArenaPool) Put(a *Arena) {
+	if ap.maxRetainedBytes > 0 && a.MemoryFootprint() > ap.maxRetainedBytes {
+		// Drop the oversized arena, so its memory may be reclaimed by GC.
+		ap.stats.put(true)
+		return
+	}
+	ap.stats.put(false)
 	ap.pool.Put(a)
 }
+
+// Stats returns usage counters for ap.
+//
+// Zero counters are returned if ap isn't created via NewArenaPoolWithStats.
+func (ap *ArenaPool) Stats() PoolStats {
+	return ap.stats.load()
+}
+
+// PoolStats contains usage counters for ParserPool and ArenaPool.
+//
+// The counters may be exported as metrics. See NewParserPoolWithStats
+// and NewArenaPoolWithStats.
+type PoolStats struct {
+	// Gets is the number of Get calls.
+	Gets uint64
+
+	// Puts is the number of Put calls including discarded items.
+	Puts uint64
+
+	// News is the number of items created by Get, since the pool was empty.
+	//
+	// Gets-News is the number of pool hits.
+	News uint64
+
+	// Discards is the number of items dropped by Put, since their
+	// MemoryFootprint exceeded the maxRetainedBytes limit.
+	Discards uint64
+}
+
+// poolStats holds PoolStats counters, which are updated atomically.
+//
+// It is always allocated separately, so the counters are properly aligned
+// for atomic access on 32-bit platforms.
+type poolStats struct {
+	gets     uint64
+	puts     uint64
+	news     uint64
+	discards uint64
+}
+
+func (ps *poolStats) get(isNew bool) {
+	if ps == nil {
+		return
+	}
+	atomic.AddUint64(&ps.gets, 1)
+	if isNew {
+		atomic.AddUint64(&ps.news, 1)
+	}
+}
+
+func (ps *poolStats) put(isDiscard bool) {
+	if ps == nil {
+		return
+	}
+	atomic.AddUint64(&ps.puts, 1)
+	if isDiscard {
+		atomic.AddUint64(&ps.discards, 1)
+	}
+}
+
+func (ps *poolStats) load() PoolStats {
+	if ps == nil {
+		return PoolStats{}
+	}
+	return PoolStats{
+		Gets:     atomic.LoadUint64(&ps.gets),
+		Puts:     atomic.LoadUint64(&ps.puts),
+		News:     atomic.LoadUint64(&ps.news),
+		Discards: atomic.LoadUint64(&ps.discards),
+	}
+}
+
+// ScannerPool may be used for pooling Scanners for similarly typed JSONs.
+type ScannerPool struct {
+	pool sync.Pool
+}
+
+// Get returns a Scanner from sp.
+//
+// The Scanner must be Put to sp after use.
+func (sp *ScannerPool) Get() *Scanner {
+	v := sp.pool.Get()
+	if v == nil {
+		return &Scanner{}
+	}
+	return v.(*Scanner)
+}
+
+// Put returns sc to sp.
+//
+// sc and values obtained from sc cannot be used after sc is put into sp.
+func (sp *ScannerPool) Put(sc *Scanner) {
+	// Reset the settings, so Get always returns Scanner with default settings.
+	sc.keepValues = false
+	sc.typeFilter = 0
+	sp.pool.Put(sc)
+}
+
+// BufferPool may be used for pooling buffers for marshaled JSONs
+// of widely varying sizes.
+//
+// Buffers are pooled in size classes, so occasional big buffers
+// aren't returned for small JSONs and small buffers don't need
+// to grow for big JSONs.
+//
+// The zero value BufferPool retains buffers up to 64MB.
+// Use NewBufferPool for limiting the memory retained by the pool.
+//
+// See Value.MarshalToPooled.
+type BufferPool struct {
+	pools [bufferPoolClasses]sync.Pool
+
+	maxRetainedBytes int
+}
+
+const (
+	// bufferPoolMinSize is the size of buffers in the smallest class.
+	bufferPoolMinSize = 1024
+
+	// bufferPoolClasses is the number of size classes in BufferPool.
+	//
+	// The size of buffers in every class is twice bigger than the size
+	// in the previous class.
+	bufferPoolClasses = 17
+)
+
+// NewBufferPool returns new BufferPool, which doesn't retain buffers
+// with capacity exceeding maxRetainedBytes.
+//
+// Zero maxRetainedBytes means 64MB, which is the size of the biggest
+// buffers retained by BufferPool.
+func NewBufferPool(maxRetainedBytes int) *BufferPool {
+	return &BufferPool{
+		maxRetainedBytes: maxRetainedBytes,
+	}
+}
+
+// pooledBuffer is a buffer obtained from BufferPool.
+type pooledBuffer struct {
+	b  []byte
+	bp *BufferPool
+
+	// release returns the buffer to bp.
+	//
+	// It is created only once per pooledBuffer in order to avoid
+	// memory allocations on every MarshalToPooled call.
+	release func()
+}
+
+func (bp *BufferPool) get(n int) *pooledBuffer {
+	class := bufferPoolClass(n)
+	if class >= bufferPoolClasses {
+		// Too big buffer. Do not pool it.
+		pb := &pooledBuffer{
+			b: make([]byte, 0, n),
+		}
+		pb.release = func() {}
+		return pb
+	}
+	if v := bp.pools[class].Get(); v != nil {
+		return v.(*pooledBuffer)
+	}
+	pb := &pooledBuffer{
+		b:  make([]byte, 0, bufferPoolMinSize<<uint(class)),
+		bp: bp,
+	}
+	pb.release = func() {
+		pb.bp.put(pb)
+	}
+	return pb
+}
+
+func (bp *BufferPool) put(pb *pooledBuffer) {
+	n := cap(pb.b)
+	if bp.maxRetainedBytes > 0 && n > bp.maxRetainedBytes {
+		// Drop the oversized buffer, so its memory may be reclaimed by GC.
+		return
+	}
+	if n < bufferPoolMinSize {
+		return
+	}
+	// Put the buffer to the biggest class, which guarantees the requested
+	// capacity for get.
+	class := bits.Len(uint(n)) - bits.Len(bufferPoolMinSize)
+	if class >= bufferPoolClasses {
+		return
+	}
+	pb.b = pb.b[:0]
+	bp.pools[class].Put(pb)
+}
+
+// bufferPoolClass returns the smallest size class for buffers with n bytes.
+func bufferPoolClass(n int) int {
+	if n <= bufferPoolMinSize {
+		return 0
+	}
+	return bits.Len(uint(n-1)) - bits.Len(bufferPoolMinSize-1)
+}
+
+// MarshalToPooled marshals v into a buffer obtained from bp.
+//
+// The buffer size class is selected via MarshalLen, so the buffer
+// doesn't grow during marshaling.
+//
+// release must be called when buf is no longer needed in order to return
+// buf to bp. buf cannot be used after release call. release mustn't be
+// called multiple times.
+func (v *Value) MarshalToPooled(bp *BufferPool) (buf []byte, release func()) {
+	pb := bp.get(v.MarshalLen())
+	pb.b = v.MarshalTo(pb.b[:0])
+	return pb.b, pb.release
+}
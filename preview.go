@@ -0,0 +1,235 @@
+package fastjson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Preview returns marshaled v truncated to at most maxLen bytes.
+//
+// "...(truncated, N bytes total)" suffix is appended to the truncated
+// result, where N is the MarshalLen of v. The result without the suffix
+// is a byte prefix of MarshalTo output, which never ends in the middle
+// of an escape sequence or a multi-byte rune. v is returned without
+// the suffix if its marshaled length doesn't exceed maxLen.
+//
+// Only the first maxLen bytes of v are marshaled, while the total length
+// is calculated via MarshalLen, so Preview is suitable for logging big values.
+func (v *Value) Preview(maxLen int) string {
+	n := v.MarshalLen()
+	if n <= maxLen {
+		return v.String()
+	}
+	pw := newPreviewer(maxLen)
+	pw.appendValue(v)
+	return pw.finish(n)
+}
+
+// Preview returns marshaled o truncated to at most maxLen bytes.
+//
+// See Value.Preview for details.
+func (o *Object) Preview(maxLen int) string {
+	n := o.MarshalLen()
+	if n <= maxLen {
+		return o.String()
+	}
+	pw := newPreviewer(maxLen)
+	pw.appendObject(o)
+	return pw.finish(n)
+}
+
+// previewer marshals values until the marshaled length exceeds maxLen.
+type previewer struct {
+	dst    []byte
+	maxLen int
+}
+
+func newPreviewer(maxLen int) *previewer {
+	if maxLen < 0 {
+		maxLen = 0
+	}
+	return &previewer{
+		dst:    make([]byte, 0, maxLen+64),
+		maxLen: maxLen,
+	}
+}
+
+// full returns true if pw.dst exceeds pw.maxLen, so there is no need
+// in marshaling the rest of the value.
+func (pw *previewer) full() bool {
+	return len(pw.dst) > pw.maxLen
+}
+
+// remaining returns the number of bytes, which must be appended to pw.dst
+// in order to exceed pw.maxLen.
+func (pw *previewer) remaining() int {
+	return pw.maxLen + 1 - len(pw.dst)
+}
+
+// finish truncates the marshaled value at the safe position
+// and appends the suffix with the total length n.
+func (pw *previewer) finish(n int) string {
+	dst := pw.dst[:previewCutLen(pw.dst, pw.maxLen)]
+	dst = append(dst, "...(truncated, "...)
+	dst = strconv.AppendInt(dst, int64(n), 10)
+	dst = append(dst, " bytes total)"...)
+	return b2s(dst)
+}
+
+func (pw *previewer) appendBytes(s string) {
+	if n := pw.remaining(); n < len(s) {
+		s = s[:n]
+	}
+	pw.dst = append(pw.dst, s...)
+}
+
+func (pw *previewer) appendValue(v *Value) {
+	if pw.full() {
+		return
+	}
+	switch v.t {
+	case typeRawString:
+		pw.appendRawString(v.s)
+	case TypeObject:
+		pw.appendObject(&v.o)
+	case TypeArray:
+		pw.dst = append(pw.dst, '[')
+		for i, vv := range v.a {
+			if i > 0 {
+				pw.dst = append(pw.dst, ',')
+			}
+			pw.appendValue(vv)
+			if pw.full() {
+				return
+			}
+		}
+		pw.dst = append(pw.dst, ']')
+	case TypeString:
+		pw.appendString(v.s)
+	case TypeNumber:
+		pw.appendBytes(v.s)
+	case TypeTrue:
+		pw.appendBytes("true")
+	case TypeFalse:
+		pw.appendBytes("false")
+	case TypeNull:
+		pw.appendBytes("null")
+	default:
+		panic(fmt.Errorf("BUG: unexpected Value type: %d", v.t))
+	}
+}
+
+func (pw *previewer) appendObject(o *Object) {
+	if pw.full() {
+		return
+	}
+	pw.dst = append(pw.dst, '{')
+	for i, kv := range o.kvs {
+		if i > 0 {
+			pw.dst = append(pw.dst, ',')
+		}
+		if o.keysUnescaped {
+			pw.appendString(kv.k)
+		} else {
+			pw.appendRawString(kv.k)
+		}
+		if pw.full() {
+			return
+		}
+		pw.dst = append(pw.dst, ':')
+		pw.appendValue(kv.v)
+		if pw.full() {
+			return
+		}
+	}
+	pw.dst = append(pw.dst, '}')
+}
+
+// appendRawString appends already escaped s in quotes.
+func (pw *previewer) appendRawString(s string) {
+	if pw.full() {
+		return
+	}
+	pw.dst = append(pw.dst, '"')
+	pw.appendBytes(s)
+	if !pw.full() {
+		pw.dst = append(pw.dst, '"')
+	}
+}
+
+// appendString appends escaped s in quotes like escapeString does.
+func (pw *previewer) appendString(s string) {
+	if pw.full() {
+		return
+	}
+	n := pw.remaining() + utf8.UTFMax
+	if n >= len(s) {
+		pw.dst = escapeString(pw.dst, s)
+		return
+	}
+
+	// Escape only the prefix of s, which is enough for exceeding maxLen.
+	// Every byte in s results in at least a single byte in the escaped string.
+	// Do not cut valid multi-byte runes, since the escaped prefix
+	// must match the beginning of the escaped s.
+	for i := 1; i < utf8.UTFMax && !utf8.RuneStart(s[n]); i++ {
+		n--
+	}
+	pw.dst = escapeString(pw.dst, s[:n])
+	// Drop the closing quote.
+	pw.dst = pw.dst[:len(pw.dst)-1]
+}
+
+// previewCutLen returns the maximum length up to maxLen for the marshaled b,
+// which doesn't split escape sequences and multi-byte runes in strings.
+func previewCutLen(b []byte, maxLen int) int {
+	inString := false
+	i := 0
+	for i < len(b) {
+		n := 1
+		if inString {
+			switch b[i] {
+			case '"':
+				inString = false
+			case '\\':
+				n = escapeSequenceLen(b[i:])
+			default:
+				if !utf8.FullRune(b[i:]) {
+					// The rune is cut at the end of b.
+					n = utf8.UTFMax
+				} else {
+					_, n = utf8.DecodeRune(b[i:])
+				}
+			}
+		} else if b[i] == '"' {
+			inString = true
+		}
+		if i+n > maxLen {
+			break
+		}
+		i += n
+	}
+	return i
+}
+
+// escapeSequenceLen returns the length of the escape sequence at the beginning of b.
+//
+// UTF-16 surrogate pairs are treated as a single escape sequence.
+func escapeSequenceLen(b []byte) int {
+	if len(b) < 2 || b[1] != 'u' {
+		return 2
+	}
+	if len(b) >= 6 && isHighSurrogateEscape(b) && (len(b) < 12 || b[6] == '\\' && b[7] == 'u') {
+		// Conservatively treat the high surrogate at the end of b as a pair,
+		// since the low surrogate may be cut.
+		return 12
+	}
+	return 6
+}
+
+// isHighSurrogateEscape returns true if b starts with \uD800-\uDBFF escape sequence.
+func isHighSurrogateEscape(b []byte) bool {
+	return (b[2] == 'd' || b[2] == 'D') && strings.IndexByte("89abAB", b[3]) >= 0
+}
@@ -0,0 +1,135 @@
+package fastjson
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// previewBrokenTailRe matches the preview ending in the middle of escape sequence.
+var previewBrokenTailRe = regexp.MustCompile(`(^|[^\\])(\\\\)*(\\|\\u[0-9a-fA-F]{0,3}|\\u[dD][89abAB][0-9a-fA-F]{2}(\\|\\u[0-9a-fA-F]{0,3})?)$`)
+
+func testPreview(t *testing.T, v *Value, maxLen int) {
+	t.Helper()
+	testPreviewData(t, v, v.MarshalTo(nil), maxLen)
+}
+
+func testPreviewData(t *testing.T, v *Value, data []byte, maxLen int) {
+	t.Helper()
+	result := v.Preview(maxLen)
+	if maxLen < 0 {
+		maxLen = 0
+	}
+	if len(data) <= maxLen {
+		if result != string(data) {
+			t.Fatalf("unexpected preview for maxLen=%d; got\n%s\nwant\n%s", maxLen, result, data)
+		}
+		return
+	}
+	suffix := fmt.Sprintf("...(truncated, %d bytes total)", len(data))
+	if !strings.HasSuffix(result, suffix) {
+		t.Fatalf("missing suffix %q in the preview for maxLen=%d: %q", suffix, maxLen, result)
+	}
+	prefix := result[:len(result)-len(suffix)]
+	if len(prefix) > maxLen {
+		t.Fatalf("too long preview for maxLen=%d; got %d bytes", maxLen, len(prefix))
+	}
+	if len(prefix) < maxLen-11 {
+		t.Fatalf("too short preview for maxLen=%d; got %d bytes", maxLen, len(prefix))
+	}
+	if !strings.HasPrefix(string(data), prefix) {
+		t.Fatalf("preview for maxLen=%d isn't a prefix of the marshaled value; got\n%q\nwant prefix of\n%q", maxLen, prefix, data)
+	}
+	if utf8.Valid(data) && !utf8.ValidString(prefix) {
+		t.Fatalf("preview for maxLen=%d splits multi-byte rune: %q", maxLen, prefix)
+	}
+	if previewBrokenTailRe.MatchString(prefix) {
+		t.Fatalf("preview for maxLen=%d splits escape sequence: %q", maxLen, prefix)
+	}
+	if o, err := v.Object(); err == nil {
+		if s := o.Preview(maxLen); s != result {
+			t.Fatalf("unexpected Object.Preview for maxLen=%d; got\n%q\nwant\n%q", maxLen, s, result)
+		}
+	}
+}
+
+func TestValuePreview(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		v, err := Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		n := v.MarshalLen()
+		for maxLen := -1; maxLen <= n+1; maxLen++ {
+			testPreview(t, v, maxLen)
+		}
+
+		// Unescaped strings and keys must be handled in the same way.
+		v.Normalize()
+		for maxLen := -1; maxLen <= n+1; maxLen++ {
+			testPreview(t, v, maxLen)
+		}
+	}
+
+	f(`123`)
+	f(`"foo"`)
+	f(`[]`)
+	f(`{}`)
+	f(`[null,true,false,-1.5e10,"x"]`)
+	f(`{"foo":"bar","baz":[1,2,{"x":"y"}]}`)
+	f(`"привет 😀 \t\n\"\\"`)
+	f(`{"ключ\n":"значение\u0000","emoji😀":["😀😀","ééé"]}`)
+	f("\"привет \U0001F600\"")
+	f(`"😀😀😀"`)
+	f(`["\"\\\"\\\\\"\\\\\\"]`)
+
+	// Values created via Arena contain unescaped strings with invalid UTF-8
+	// and control chars.
+	var a Arena
+	v := a.NewObject()
+	v.Set("ctrl\x01\x02\x03", a.NewString("\x00\x1f\x7f\"\\"))
+	v.Set("invalid", a.NewString("\xff\xed\xa0\x80\xe2\x82"))
+	v.Set("runes", a.NewString(strings.Repeat("ж€\U0001F600", 10)))
+	v.Set("arr", a.NewArrayFromStrings([]string{"a", "\xf0\x9f", " "}))
+	n := v.MarshalLen()
+	for maxLen := -1; maxLen <= n+1; maxLen++ {
+		testPreview(t, v, maxLen)
+	}
+
+	// Exact limit.
+	v, err := Parse(`{"foo":"bar"}`)
+	if err != nil {
+		t.Fatalf("cannot parse JSON: %s", err)
+	}
+	if s := v.Preview(13); s != `{"foo":"bar"}` {
+		t.Fatalf("unexpected preview at the limit; got %q; want %q", s, `{"foo":"bar"}`)
+	}
+	if s := v.Preview(12); s != `{"foo":"bar"...(truncated, 13 bytes total)` {
+		t.Fatalf("unexpected preview below the limit; got %q", s)
+	}
+	if s := v.Preview(9); s != `{"foo":"b...(truncated, 13 bytes total)` {
+		t.Fatalf("unexpected preview; got %q", s)
+	}
+	if s := v.Preview(0); s != `...(truncated, 13 bytes total)` {
+		t.Fatalf("unexpected preview for zero maxLen; got %q", s)
+	}
+}
+
+func TestValuePreviewFixtures(t *testing.T) {
+	for _, s := range []string{largeFixture, canadaFixture, citmFixture, twitterFixture} {
+		v, err := Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse fixture: %s", err)
+		}
+		data := v.MarshalTo(nil)
+		for _, maxLen := range []int{0, 1, 10, 100, 200, 1000, 12345} {
+			testPreviewData(t, v, data, maxLen)
+		}
+		for maxLen := 1; maxLen < 3000; maxLen += 37 {
+			testPreviewData(t, v, data, maxLen)
+		}
+	}
+}
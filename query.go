@@ -0,0 +1,200 @@
+package fastjson
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Wildcard is a special key in GetAll and ExistsAny patterns, which matches
+// every item of an array and every value of an object.
+const Wildcard = "*"
+
+// GetAll returns all the values matching the given keys pattern.
+//
+// The pattern is a keys path like in Get, where Wildcard key matches
+// every item of an array and every value of an object. For example,
+// the following call returns tags for all the items in data array:
+//
+//	v.GetAll("data", "*", "tags", "*")
+//
+// Wildcard cannot be used for matching object keys equal to "*",
+// since it matches all the object values.
+//
+// Values are returned in the order they appear in the document.
+// nil is returned if there are no matching values.
+//
+// The returned values are valid until Parse is called on the Parser returned v.
+func (v *Value) GetAll(pattern ...string) []*Value {
+	return v.GetAllAppend(nil, pattern...)
+}
+
+// GetAllAppend appends all the values matching the given keys pattern to dst
+// and returns the result.
+//
+// See GetAll for details.
+func (v *Value) GetAllAppend(dst []*Value, pattern ...string) []*Value {
+	if v == nil {
+		return dst
+	}
+	for i, key := range pattern {
+		if key == Wildcard {
+			switch v.t {
+			case TypeObject:
+				for _, kv := range v.o.kvs {
+					dst = kv.v.GetAllAppend(dst, pattern[i+1:]...)
+				}
+			case TypeArray:
+				for _, vv := range v.a {
+					dst = vv.GetAllAppend(dst, pattern[i+1:]...)
+				}
+			}
+			return dst
+		}
+		v = v.getKey(key)
+		if v == nil {
+			return dst
+		}
+	}
+	return append(dst, v)
+}
+
+// ExistsAny returns true if at least a single value matches
+// the given keys pattern.
+//
+// See GetAll for details on the pattern.
+func (v *Value) ExistsAny(pattern ...string) bool {
+	if v == nil {
+		return false
+	}
+	for i, key := range pattern {
+		if key == Wildcard {
+			switch v.t {
+			case TypeObject:
+				for _, kv := range v.o.kvs {
+					if kv.v.ExistsAny(pattern[i+1:]...) {
+						return true
+					}
+				}
+			case TypeArray:
+				for _, vv := range v.a {
+					if vv.ExistsAny(pattern[i+1:]...) {
+						return true
+					}
+				}
+			}
+			return false
+		}
+		v = v.getKey(key)
+		if v == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// getKey returns the value for the given key in v like Get does.
+func (v *Value) getKey(key string) *Value {
+	switch v.t {
+	case TypeObject:
+		return v.o.Get(key)
+	case TypeArray:
+		n, err := strconv.Atoi(key)
+		if err != nil || n < 0 || n >= len(v.a) {
+			return nil
+		}
+		return v.a[n]
+	default:
+		return nil
+	}
+}
+
+// GetDotted returns value by the given dotted path such as "foo.bar.0.baz".
+//
+// Path segments are separated by dots. A literal dot in the key must be
+// escaped as `\.`, while a literal backslash must be escaped as `\\`,
+// so "net\.host\.name" refers to the "net.host.name" key.
+// Numeric segments are treated as array indexes only if the current value
+// is an array, so they may refer to numeric object keys too.
+// Empty segments refer to empty keys.
+//
+// nil is returned for non-existing path or for invalid escape sequence.
+//
+// The returned value is valid until Parse is called on the Parser returned v.
+func (v *Value) GetDotted(path string) *Value {
+	var buf []byte
+	for v != nil {
+		n := dottedSegmentLen(path)
+		key := path[:n]
+		if strings.IndexByte(key, '\\') >= 0 {
+			var ok bool
+			buf, ok = appendUnescapedDottedKey(buf[:0], key)
+			if !ok {
+				return nil
+			}
+			key = b2s(buf)
+		}
+		v = v.getKey(key)
+		if n == len(path) {
+			return v
+		}
+		path = path[n+1:]
+	}
+	return nil
+}
+
+// ExistsDotted returns true if the field exists for the given dotted path.
+//
+// See GetDotted for details on the path.
+func (v *Value) ExistsDotted(path string) bool {
+	return v.GetDotted(path) != nil
+}
+
+// GetDottedInt returns int value by the given dotted path.
+//
+// See GetDotted for details on the path and GetInt for details
+// on the returned value.
+func (v *Value) GetDottedInt(path string) int {
+	return v.GetDotted(path).GetInt()
+}
+
+// GetDottedStringBytes returns string value by the given dotted path.
+//
+// See GetDotted for details on the path and GetStringBytes for details
+// on the returned value.
+//
+// The returned string is valid until Parse is called on the Parser returned v.
+func (v *Value) GetDottedStringBytes(path string) []byte {
+	return v.GetDotted(path).GetStringBytes()
+}
+
+// dottedSegmentLen returns the length of the first segment in dotted path.
+func dottedSegmentLen(path string) int {
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			// Skip the escaped char.
+			i++
+		case '.':
+			return i
+		}
+	}
+	return len(path)
+}
+
+// appendUnescapedDottedKey appends unescaped dotted path segment key to dst.
+//
+// false is returned if key contains invalid escape sequence.
+func appendUnescapedDottedKey(dst []byte, key string) ([]byte, bool) {
+	for i := 0; i < len(key); i++ {
+		ch := key[i]
+		if ch == '\\' {
+			i++
+			if i >= len(key) || key[i] != '.' && key[i] != '\\' {
+				return dst, false
+			}
+			ch = key[i]
+		}
+		dst = append(dst, ch)
+	}
+	return dst, true
+}
@@ -0,0 +1,235 @@
+package fastjson
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValueGetAll(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{
+		"data": [
+			{"id": 1, "status": "ok", "tags": ["a", "b"]},
+			{"id": 2, "status": "failed", "tags": []},
+			{"id": 3, "status": "ok", "tags": ["c"], "extra": {"x": 1, "y": [2]}}
+		],
+		"meta": {"a": {"n": 1}, "b": {"n": 2}, "*": {"n": 3}}
+	}`)
+	if err != nil {
+		t.Fatalf("cannot parse json: %s", err)
+	}
+	f := func(pattern []string, resultExpected string) {
+		t.Helper()
+
+		vs := v.GetAll(pattern...)
+		result := "["
+		for i, vv := range vs {
+			if i > 0 {
+				result += ","
+			}
+			result += vv.String()
+		}
+		result += "]"
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q; got %s; want %s", pattern, result, resultExpected)
+		}
+		if len(vs) == 0 && vs != nil {
+			t.Fatalf("expecting nil result for %q", pattern)
+		}
+		if v.ExistsAny(pattern...) != (len(vs) > 0) {
+			t.Fatalf("unexpected ExistsAny result for %q", pattern)
+		}
+
+		// GetAllAppend must append to dst.
+		dst := []*Value{valueNull}
+		dst = v.GetAllAppend(dst, pattern...)
+		if len(dst) != len(vs)+1 || dst[0] != valueNull {
+			t.Fatalf("unexpected GetAllAppend result for %q", pattern)
+		}
+	}
+
+	// No wildcards
+	f(nil, `[`+v.String()+`]`)
+	f([]string{"data", "1", "id"}, `[2]`)
+	f([]string{"data", "5", "id"}, `[]`)
+
+	// Wildcards over arrays
+	f([]string{"data", "*", "id"}, `[1,2,3]`)
+	f([]string{"data", "*", "status"}, `["ok","failed","ok"]`)
+	f([]string{"data", "*", "tags", "*"}, `["a","b","c"]`)
+	f([]string{"data", "*", "tags", "0"}, `["a","c"]`)
+	f([]string{"data", "*", "extra", "y", "*"}, `[2]`)
+	f([]string{"data", "*", "missing"}, `[]`)
+	f([]string{"data", "*", "tags", "*", "*"}, `[]`)
+
+	// Wildcards over objects
+	f([]string{"meta", "*", "n"}, `[1,2,3]`)
+	f([]string{"data", "2", "extra", "*"}, `[1,[2]]`)
+	f([]string{"*", "*", "id"}, `[1,2,3]`)
+
+	// Wildcards over scalars
+	f([]string{"data", "0", "id", "*"}, `[]`)
+}
+
+func TestValueGetAllTwitter(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(twitterFixture)
+	if err != nil {
+		t.Fatalf("cannot parse twitter.json: %s", err)
+	}
+	var m struct {
+		Statuses []struct {
+			ID       int64 `json:"id"`
+			Entities struct {
+				Hashtags []struct {
+					Text string `json:"text"`
+				} `json:"hashtags"`
+			} `json:"entities"`
+		} `json:"statuses"`
+	}
+	if err := json.Unmarshal([]byte(twitterFixture), &m); err != nil {
+		t.Fatalf("cannot unmarshal twitter.json: %s", err)
+	}
+
+	ids := v.GetAll("statuses", "*", "id")
+	if len(ids) != len(m.Statuses) {
+		t.Fatalf("unexpected number of ids; got %d; want %d", len(ids), len(m.Statuses))
+	}
+	for i, id := range ids {
+		if n := id.GetInt64(); n != m.Statuses[i].ID {
+			t.Fatalf("unexpected id #%d; got %d; want %d", i, n, m.Statuses[i].ID)
+		}
+	}
+
+	var hashtagsExpected []string
+	for _, st := range m.Statuses {
+		for _, h := range st.Entities.Hashtags {
+			hashtagsExpected = append(hashtagsExpected, h.Text)
+		}
+	}
+	hashtags := v.GetAll("statuses", "*", "entities", "hashtags", "*", "text")
+	if len(hashtags) != len(hashtagsExpected) {
+		t.Fatalf("unexpected number of hashtags; got %d; want %d", len(hashtags), len(hashtagsExpected))
+	}
+	for i, h := range hashtags {
+		if s := string(h.GetStringBytes()); s != hashtagsExpected[i] {
+			t.Fatalf("unexpected hashtag #%d; got %q; want %q", i, s, hashtagsExpected[i])
+		}
+	}
+
+	if !v.ExistsAny("statuses", "*", "user", "lang") {
+		t.Fatalf("expecting existing user lang")
+	}
+	if v.ExistsAny("statuses", "*", "user", "missing") {
+		t.Fatalf("unexpected missing key found")
+	}
+
+	// No allocations for the re-used results slice.
+	dst := make([]*Value, 0, len(ids))
+	n := testing.AllocsPerRun(100, func() {
+		dst = v.GetAllAppend(dst[:0], "statuses", "*", "id")
+		dst = v.GetAllAppend(dst[:0], "statuses", "*", "missing")
+		if v.ExistsAny("statuses", "*", "missing") {
+			panic("unexpected existing key")
+		}
+	})
+	if n != 0 {
+		t.Fatalf("unexpected allocations: %v", n)
+	}
+}
+
+func TestValueGetDotted(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{
+		"net.host.name": "example.com",
+		"net": {"host": {"name": "nested"}},
+		"back\\slash": 1,
+		"a.b\\c": 2,
+		"": {"": 3},
+		"0": "numeric key",
+		"obj": {"1": {"x": 4}, "2.5": 5},
+		"arr": [10, {"k.v": "dotted in array", "0": "zero key"}, [20, 30]]
+	}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f := func(path, expected string) {
+		t.Helper()
+		vv := v.GetDotted(path)
+		if vv == nil {
+			t.Fatalf("cannot find value for path %q", path)
+		}
+		if s := vv.String(); s != expected {
+			t.Fatalf("unexpected value for path %q; got %s; want %s", path, s, expected)
+		}
+		if !v.ExistsDotted(path) {
+			t.Fatalf("ExistsDotted must return true for path %q", path)
+		}
+	}
+
+	// Keys with dots
+	f(`net.host.name`, `"nested"`)
+	f(`net\.host\.name`, `"example.com"`)
+	f(`net.host`, `{"name":"nested"}`)
+	f(`a\.b\\c`, `2`)
+	f(`back\\slash`, `1`)
+
+	// Empty keys
+	f(`.`, `3`)
+	f(``, `{"":3}`)
+
+	// Numeric object keys
+	f(`0`, `"numeric key"`)
+	f(`obj.1.x`, `4`)
+	f(`obj.2\.5`, `5`)
+
+	// Mixed arrays and objects
+	f(`arr.0`, `10`)
+	f(`arr.1.k\.v`, `"dotted in array"`)
+	f(`arr.1.0`, `"zero key"`)
+	f(`arr.2.1`, `30`)
+
+	// Missing paths
+	fMissing := func(path string) {
+		t.Helper()
+		if vv := v.GetDotted(path); vv != nil {
+			t.Fatalf("expecting nil value for path %q; got %s", path, vv)
+		}
+		if v.ExistsDotted(path) {
+			t.Fatalf("ExistsDotted must return false for path %q", path)
+		}
+	}
+	fMissing(`net.host.name.x`)
+	fMissing(`net\.host`)
+	fMissing(`arr.3`)
+	fMissing(`arr.-1`)
+	fMissing(`arr.0.x`)
+	fMissing(`obj.2.5`)
+	fMissing(`missing`)
+	fMissing(`net.`)
+
+	// Invalid escape sequences
+	fMissing(`net\host`)
+	fMissing(`back\`)
+
+	// Typed variants
+	if n := v.GetDottedInt(`arr.2.0`); n != 20 {
+		t.Fatalf("unexpected GetDottedInt result; got %d; want 20", n)
+	}
+	if n := v.GetDottedInt(`net\.host\.name`); n != 0 {
+		t.Fatalf("unexpected GetDottedInt result for string; got %d; want 0", n)
+	}
+	if s := v.GetDottedStringBytes(`net\.host\.name`); string(s) != "example.com" {
+		t.Fatalf("unexpected GetDottedStringBytes result; got %q; want %q", s, "example.com")
+	}
+	if s := v.GetDottedStringBytes(`arr.5`); s != nil {
+		t.Fatalf("unexpected GetDottedStringBytes result for missing path; got %q; want nil", s)
+	}
+
+	// nil value
+	var vNil *Value
+	if vNil.GetDotted("foo") != nil {
+		t.Fatalf("expecting nil value for nil receiver")
+	}
+}
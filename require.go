@@ -0,0 +1,146 @@
+package fastjson
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RequireType is the type expected by Value.Require and Value.RequirePaths.
+type RequireType int
+
+const (
+	// RequireNull matches JSON null.
+	RequireNull = RequireType(TypeNull)
+
+	// RequireObject matches JSON objects.
+	RequireObject = RequireType(TypeObject)
+
+	// RequireArray matches JSON arrays.
+	RequireArray = RequireType(TypeArray)
+
+	// RequireString matches JSON strings.
+	RequireString = RequireType(TypeString)
+
+	// RequireNumber matches JSON numbers.
+	RequireNumber = RequireType(TypeNumber)
+
+	// RequireTrue matches JSON true.
+	RequireTrue = RequireType(TypeTrue)
+
+	// RequireFalse matches JSON false.
+	RequireFalse = RequireType(TypeFalse)
+
+	// RequireInt64 matches JSON numbers convertible to int64.
+	RequireInt64 RequireType = 100
+
+	// RequireNonEmptyString matches non-empty JSON strings.
+	RequireNonEmptyString RequireType = 101
+
+	// RequireBool matches JSON true and false.
+	RequireBool RequireType = 102
+)
+
+// String returns string representation of t.
+func (t RequireType) String() string {
+	switch t {
+	case RequireInt64:
+		return "int64 number"
+	case RequireNonEmptyString:
+		return "non-empty string"
+	case RequireBool:
+		return "bool"
+	default:
+		return Type(t).String()
+	}
+}
+
+// Require verifies whether v is an object containing all the keys
+// from shape with the given types.
+//
+// All the violations are returned in a single error sorted by keys, e.g.:
+//
+//	missing key "id"; key "tags": expected array, got string
+//
+// Require doesn't allocate memory if v matches shape.
+func (v *Value) Require(shape map[string]RequireType) error {
+	if v == nil || v.Type() != TypeObject {
+		return fmt.Errorf("expected object, got %s", typeOrNil(v))
+	}
+	o := &v.o
+	return requireShape(shape, "key", func(key string) *Value {
+		return o.Get(key)
+	})
+}
+
+// RequirePaths verifies whether v contains values with the given types
+// at all the paths from shape.
+//
+// Paths are dotted paths such as "user.tags.0". See GetDotted for details.
+// See Require for details on types and the returned error.
+//
+// RequirePaths doesn't allocate memory if v matches shape.
+func (v *Value) RequirePaths(shape map[string]RequireType) error {
+	return requireShape(shape, "path", v.GetDotted)
+}
+
+func requireShape(shape map[string]RequireType, kind string, get func(key string) *Value) error {
+	var failed []string
+	for key, t := range shape {
+		if !matchesType(get(key), t) {
+			failed = append(failed, key)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	sort.Strings(failed)
+	var sb strings.Builder
+	for i, key := range failed {
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		v := get(key)
+		if v == nil {
+			fmt.Fprintf(&sb, "missing %s %q", kind, key)
+			continue
+		}
+		t := shape[key]
+		got := v.Type().String()
+		if t == RequireNonEmptyString && v.Type() == TypeString {
+			got = "empty string"
+		}
+		fmt.Fprintf(&sb, "%s %q: expected %s, got %s", kind, key, t, got)
+	}
+	return errors.New(sb.String())
+}
+
+// matchesType returns true if v matches t.
+func matchesType(v *Value, t RequireType) bool {
+	if v == nil {
+		return false
+	}
+	switch t {
+	case RequireInt64:
+		if v.Type() != TypeNumber {
+			return false
+		}
+		_, err := v.Int64()
+		return err == nil
+	case RequireNonEmptyString:
+		return v.Type() == TypeString && len(v.s) > 0
+	case RequireBool:
+		return v.t == TypeTrue || v.t == TypeFalse
+	default:
+		return RequireType(v.Type()) == t
+	}
+}
+
+func typeOrNil(v *Value) string {
+	if v == nil {
+		return "nil"
+	}
+	return v.Type().String()
+}
@@ -0,0 +1,120 @@
+package fastjson
+
+import (
+	"testing"
+)
+
+func TestValueRequire(t *testing.T) {
+	v := MustParse(smallFixture)
+	shape := map[string]RequireType{
+		"st":   RequireInt64,
+		"sid":  RequireNumber,
+		"tt":   RequireNonEmptyString,
+		"uuid": RequireString,
+		"tz":   RequireInt64,
+	}
+	if err := v.Require(shape); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := v.RequirePaths(shape); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := v.Require(nil); err != nil {
+		t.Fatalf("unexpected error for nil shape: %s", err)
+	}
+
+	f := func(s string, shape map[string]RequireType, errExpected string) {
+		t.Helper()
+		v := MustParse(s)
+		err := v.Require(shape)
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if err.Error() != errExpected {
+			t.Fatalf("unexpected error\ngot\n%s\nwant\n%s", err, errExpected)
+		}
+	}
+
+	// Multiple violations are aggregated and sorted by keys.
+	f(`{"tags":"foo","name":"","n":1.5,"b":null,"o":{}}`, map[string]RequireType{
+		"id":   RequireInt64,
+		"tags": RequireArray,
+		"name": RequireNonEmptyString,
+		"n":    RequireInt64,
+		"b":    RequireBool,
+		"o":    RequireObject,
+	}, `key "b": expected bool, got null; missing key "id"; key "n": expected int64 number, got number; key "name": expected non-empty string, got empty string; key "tags": expected array, got string`)
+
+	// Escaped keys
+	f(`{"a\nb":1}`, map[string]RequireType{
+		"a\nb": RequireString,
+	}, `key "a\nb": expected string, got number`)
+
+	// Non-object values
+	f(`[1]`, map[string]RequireType{"a": RequireNull}, `expected object, got array`)
+	var vNil *Value
+	if err := vNil.Require(nil); err == nil {
+		t.Fatalf("expecting non-nil error for nil value")
+	}
+
+	// Bool pseudo-type matches both true and false.
+	v = MustParse(`{"t":true,"f":false,"big":18446744073709551615}`)
+	if err := v.Require(map[string]RequireType{"t": RequireBool, "f": RequireBool, "big": RequireNumber}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := v.Require(map[string]RequireType{"big": RequireInt64}); err == nil {
+		t.Fatalf("expecting non-nil error for number exceeding int64")
+	}
+}
+
+func TestValueRequirePaths(t *testing.T) {
+	v := MustParse(`{"user":{"id":123,"name":"foo","tags":["a","b"],"a.b":{"c":null}},"items":[{"x":1},{"x":"2"}]}`)
+	if err := v.RequirePaths(map[string]RequireType{
+		"user":        RequireObject,
+		"user.id":     RequireInt64,
+		"user.name":   RequireNonEmptyString,
+		"user.tags":   RequireArray,
+		"user.tags.1": RequireString,
+		`user.a\.b.c`: RequireNull,
+		"items.0.x":   RequireNumber,
+		"items.1.x":   RequireString,
+		"items":       RequireArray,
+	}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err := v.RequirePaths(map[string]RequireType{
+		"user.id":     RequireString,
+		"user.tags.2": RequireString,
+		"items.1.x":   RequireInt64,
+		"user.a.b.c":  RequireNull,
+	})
+	if err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	errExpected := `path "items.1.x": expected int64 number, got string; missing path "user.a.b.c"; path "user.id": expected string, got number; missing path "user.tags.2"`
+	if err.Error() != errExpected {
+		t.Fatalf("unexpected error\ngot\n%s\nwant\n%s", err, errExpected)
+	}
+}
+
+func TestValueRequireAllocs(t *testing.T) {
+	v := MustParse(smallFixture)
+	shape := map[string]RequireType{
+		"st":   RequireInt64,
+		"sid":  RequireNumber,
+		"tt":   RequireNonEmptyString,
+		"uuid": RequireString,
+	}
+	n := testing.AllocsPerRun(100, func() {
+		if err := v.Require(shape); err != nil {
+			panic(err)
+		}
+		if err := v.RequirePaths(shape); err != nil {
+			panic(err)
+		}
+	})
+	if n != 0 {
+		t.Fatalf("unexpected number of memory allocations; got %v; want 0", n)
+	}
+}
@@ -2,14 +2,32 @@ package fastjson
 
 import (
 	"errors"
+	"fmt"
+	"io"
 )
 
 // Scanner scans a series of JSON values. Values may be delimited by whitespace.
 //
+// Values may be also concatenated without delimiters, such as {"a":1}{"b":2}
+// or "foo"123true, as long as the JSON grammar allows finding the end
+// of every value. The stream is split into values in the same way
+// as encoding/json.Decoder does:
+//
+//   - Objects, arrays and strings end at the closing bracket or quote.
+//   - true, false and null end after the last char of the literal,
+//     so truefalse contains two values.
+//   - Numbers end at the first char, which cannot continue the number
+//     according to the JSON grammar. So 12-3 contains 12 and -3, while
+//     012 contains 0 and 12. Adjacent numbers such as 12 and 13 must be
+//     delimited by whitespace, since 1213 is a single number.
+//
 // Scanner may parse JSON lines ( http://jsonlines.org/ ).
 //
 // Scanner may be re-used for subsequent parsing.
 //
+// By default the value returned from Scanner is valid until the next Next* call.
+// Call KeepValues(true) for keeping all the values valid until the next Init* call.
+//
 // Scanner cannot be used from concurrent goroutines.
 //
 // Use Parser for parsing only a single JSON value.
@@ -20,6 +38,9 @@ type Scanner struct {
 	// s points to the next JSON value to parse.
 	s string
 
+	// offset is the offset of b in the original data passed to Init*.
+	offset int
+
 	// err contains the last error.
 	err error
 
@@ -28,16 +49,76 @@ type Scanner struct {
 
 	// c is used for caching JSON values.
 	c cache
+
+	// keepValues is set via KeepValues.
+	keepValues bool
+
+	// typeFilter contains a bit per each Type set via SetTypeFilter.
+	//
+	// Zero typeFilter means that all the values are returned from Next.
+	typeFilter uint16
+
+	// skipped is the number of values filtered out by typeFilter since Init*.
+	skipped int
+}
+
+// KeepValues enables or disables keeping parsed values valid
+// until the next Init* call.
+//
+// By default every Next* call invalidates the previously parsed value.
+// If keep is true, then all the values parsed since the last Init* call
+// remain valid until the next Init* call. This simplifies collecting values
+// from the stream at the cost of higher memory usage.
+//
+// The setting is preserved across Init* calls.
+func (sc *Scanner) KeepValues(keep bool) {
+	sc.keepValues = keep
+}
+
+// SetTypeFilter makes Next return only top-level values of the given types.
+//
+// Values of other types are skipped by Next. Pass both TypeTrue
+// and TypeFalse for obtaining boolean values.
+//
+// Skipped values are parsed in the same way as returned values,
+// so parse errors in skipped values aren't masked.
+// The number of skipped values is available via Skipped call.
+//
+// SetTypeFilter without args disables filtering.
+// The setting is preserved across Init* calls.
+func (sc *Scanner) SetTypeFilter(types ...Type) {
+	sc.typeFilter = 0
+	for _, t := range types {
+		switch t {
+		case TypeNull, TypeObject, TypeArray, TypeString, TypeNumber, TypeTrue, TypeFalse:
+			sc.typeFilter |= 1 << uint(t)
+		default:
+			panic(fmt.Errorf("unsupported type for Scanner.SetTypeFilter: %d", t))
+		}
+	}
+}
+
+func (sc *Scanner) matchesTypeFilter(v *Value) bool {
+	t := v.t
+	if t == typeRawString {
+		t = TypeString
+	}
+	return sc.typeFilter&(1<<uint(t)) != 0
+}
+
+// Skipped returns the number of values skipped by Next since the last Init*
+// call because of the filter set via SetTypeFilter.
+func (sc *Scanner) Skipped() int {
+	return sc.skipped
 }
 
 // Init initializes sc with the given s.
 //
 // s may contain multiple JSON values, which may be delimited by whitespace.
+// A single UTF-8 byte order mark at the beginning of s is skipped.
 func (sc *Scanner) Init(s string) {
-	sc.b = append(sc.b[:0], s...)
-	sc.s = b2s(sc.b)
-	sc.err = nil
-	sc.v = nil
+	sBOM := skipBOM(s)
+	sc.init(sBOM, len(s)-len(sBOM))
 }
 
 // InitBytes initializes sc with the given b.
@@ -47,33 +128,241 @@ func (sc *Scanner) InitBytes(b []byte) {
 	sc.Init(b2s(b))
 }
 
+// InitAt initializes sc with the given s and starts scanning
+// at the given offset in s.
+//
+// This allows resuming the scan at the offset obtained via Offset call
+// after the previously scanned value. Offsets returned from Offset
+// after InitAt are relative to the start of s.
+//
+// The offset must be located at JSON value boundary, i.e. the next
+// non-whitespace char after the offset must start a value. Otherwise
+// the subsequent Next* calls fail and Error returns the error.
+// Note that offsets inside strings and nested values cannot be always
+// detected, so the scanning result is undefined for such offsets.
+func (sc *Scanner) InitAt(s string, offset int) {
+	if offset == 0 {
+		sc.Init(s)
+		return
+	}
+	if offset < 0 || offset > len(s) {
+		sc.init("", 0)
+		sc.err = fmt.Errorf("offset %d is out of range [0..%d]", offset, len(s))
+		return
+	}
+	sc.init(s[offset:], offset)
+	if err := checkValueBoundary(s, offset); err != nil {
+		sc.err = err
+	}
+}
+
+// InitBytesAt initializes sc with the given b and starts scanning
+// at the given offset in b.
+//
+// See InitAt for details.
+func (sc *Scanner) InitBytesAt(b []byte, offset int) {
+	sc.InitAt(b2s(b), offset)
+}
+
+func (sc *Scanner) init(s string, offset int) {
+	sc.b = append(sc.b[:0], s...)
+	sc.s = b2s(sc.b)
+	sc.offset = offset
+	sc.err = nil
+	sc.v = nil
+	sc.skipped = 0
+	sc.c.reset()
+}
+
+// checkValueBoundary verifies whether the given offset in s is located
+// at JSON value boundary.
+func checkValueBoundary(s string, offset int) error {
+	tail := skipWS(s[offset:])
+	if len(tail) == 0 {
+		return nil
+	}
+	switch tail[0] {
+	case ',', ':', ']', '}':
+		return fmt.Errorf("offset %d isn't located at JSON value boundary; unexpected char %q at offset %d", offset, tail[0], len(s)-len(tail))
+	}
+	if len(tail) == len(s)-offset && !isValueDelimiter(s[offset-1]) && !isValueDelimiter(s[offset]) && !isAdjacentValueBoundary(s, offset) {
+		return fmt.Errorf("offset %d isn't located at JSON value boundary; it points to the middle of %q", offset, startEndString(s[offset-1:]))
+	}
+	return nil
+}
+
+// isAdjacentValueBoundary returns true if the given offset in s is located
+// between adjacent values without delimiters such as truefalse, 12-3 or 12null.
+//
+// The token containing the offset is split in the same way as Next does.
+func isAdjacentValueBoundary(s string, offset int) bool {
+	start := offset
+	for start > 0 && !isValueDelimiter(s[start-1]) {
+		start--
+	}
+	end := offset
+	for end < len(s) && !isValueDelimiter(s[end]) {
+		end++
+	}
+	for start < offset {
+		n := adjacentValueLen(s[start:end])
+		if n == end-start {
+			return false
+		}
+		start += n
+	}
+	return start == offset
+}
+
+// Offset returns the offset of the unparsed data in s passed to Init*.
+//
+// The offset points to the end of the last parsed or skipped value,
+// so it may be persisted and then passed to InitAt for resuming the scan
+// after the value.
+func (sc *Scanner) Offset() int {
+	return sc.offset + len(sc.b) - len(sc.s)
+}
+
 // Next parses the next JSON value from s passed to Init.
 //
 // Returns true on success. The parsed value is available via Value call.
 //
 // Returns false either on error or on the end of s.
 // Call Error in order to determine the cause of the returned false.
+//
+// Values not matching the filter set via SetTypeFilter are skipped.
 func (sc *Scanner) Next() bool {
 	if sc.err != nil {
 		return false
 	}
 
+	for {
+		sc.s = skipWS(sc.s)
+		if len(sc.s) == 0 {
+			sc.err = errEOF
+			return false
+		}
+
+		if !sc.keepValues {
+			sc.c.reset()
+		}
+		vsLen := len(sc.c.vs)
+		v, tail, err := parseAdjacentValue(sc.s, &sc.c)
+		if err != nil {
+			sc.err = err
+			return false
+		}
+
+		sc.s = tail
+		if sc.typeFilter != 0 && !sc.matchesTypeFilter(v) {
+			// Drop the skipped value from the cache, so it doesn't occupy
+			// memory when KeepValues is enabled.
+			sc.c.vs = sc.c.vs[:vsLen]
+			sc.skipped++
+			continue
+		}
+		sc.v = v
+		return true
+	}
+}
+
+// SkipNext skips the next JSON value from s passed to Init without parsing it.
+//
+// SkipNext is much faster than Next, since it only finds the end
+// of the value by tracking brackets and skipping strings. The skipped value
+// isn't validated, so it may contain invalid JSON. An error is returned
+// only if the end of the value cannot be found.
+//
+// Returns true on success. Value returns nil after SkipNext.
+//
+// Returns false either on error or on the end of s.
+// Call Error in order to determine the cause of the returned false.
+func (sc *Scanner) SkipNext() bool {
+	if sc.err != nil {
+		return false
+	}
+
 	sc.s = skipWS(sc.s)
 	if len(sc.s) == 0 {
 		sc.err = errEOF
 		return false
 	}
 
-	sc.c.reset()
-	v, tail, err := parseValue(sc.s, &sc.c, 0)
+	n, err := scanJSONValue(sc.s)
 	if err != nil {
-		sc.err = err
+		sc.err = fmt.Errorf("cannot skip JSON value: %s; value starts with %q", err, startEndString(sc.s))
 		return false
 	}
+	if n < 0 {
+		if sc.s[0] == '{' || sc.s[0] == '[' || sc.s[0] == '"' {
+			sc.err = fmt.Errorf("cannot skip JSON value: unexpected end of value; value starts with %q", startEndString(sc.s))
+			return false
+		}
+		// The last number or literal is terminated by the end of s.
+		n = len(sc.s)
+	}
+
+	sc.s = sc.s[n:]
+	sc.v = nil
+	return true
+}
+
+// PeekByte returns the first byte of the next JSON value from s passed to Init
+// without consuming it.
+//
+// This allows deciding whether to call Next or SkipNext for the next value.
+//
+// false is returned on error or on the end of s.
+func (sc *Scanner) PeekByte() (byte, bool) {
+	if sc.err != nil {
+		return 0, false
+	}
+	sc.s = skipWS(sc.s)
+	if len(sc.s) == 0 {
+		return 0, false
+	}
+	return sc.s[0], true
+}
+
+// NextValue parses exactly one JSON value from s passed to Init.
+//
+// Unlike Next, NextValue doesn't treat non-JSON data after the parsed value
+// as a stream error. The unparsed data may be obtained via Tail call.
+// The tail is left untouched if the value cannot be parsed, so it may be
+// processed by the caller and then passed to Init for further scanning.
+//
+// io.EOF is returned if s contains only whitespace.
+//
+// The returned value is valid until the next Next* call
+// if KeepValues isn't enabled. Otherwise it is valid until the next Init* call.
+func (sc *Scanner) NextValue() (*Value, error) {
+	if sc.err != nil && sc.err != errEOF {
+		return nil, sc.err
+	}
+	s := skipWS(sc.s)
+	if len(s) == 0 {
+		sc.s = s
+		return nil, io.EOF
+	}
+
+	if !sc.keepValues {
+		sc.c.reset()
+	}
+	v, tail, err := parseAdjacentValue(s, &sc.c)
+	if err != nil {
+		return nil, err
+	}
 
 	sc.s = tail
 	sc.v = v
-	return true
+	return v, nil
+}
+
+// Tail returns the unparsed remainder of s passed to Init.
+//
+// The returned string is valid until the next Init* call.
+func (sc *Scanner) Tail() string {
+	return sc.s
 }
 
 // Error returns the last error.
@@ -86,9 +375,205 @@ func (sc *Scanner) Error() error {
 
 // Value returns the last parsed value.
 //
-// The value is valid until the Next call.
+// The value is valid until the Next* call if KeepValues isn't enabled.
+// Otherwise it is valid until the next Init* call.
 func (sc *Scanner) Value() *Value {
 	return sc.v
 }
 
 var errEOF = errors.New("end of s")
+
+// ScanJSONValue is a split function for bufio.Scanner, which splits
+// the input into top-level JSON values delimited by optional whitespace.
+//
+// ScanJSONValue only finds value boundaries without validating values,
+// so the returned tokens may contain invalid JSON. Use Parser or Validate
+// for parsing or validating the returned tokens. The returned tokens
+// don't contain the surrounding whitespace.
+//
+// Make sure the bufio.Scanner buffer is big enough for holding
+// the largest JSON value. See bufio.Scanner.Buffer for details.
+func ScanJSONValue(data []byte, atEOF bool) (int, []byte, error) {
+	s := b2s(data)
+	tail := skipWS(s)
+	start := len(s) - len(tail)
+	if len(tail) == 0 {
+		// Skip the whitespace.
+		return len(data), nil, nil
+	}
+	n, err := scanJSONValue(tail)
+	if err != nil {
+		return 0, nil, fmt.Errorf("cannot find the end of JSON value at offset %d: %s; value starts with %q", start, err, startEndString(tail))
+	}
+	if n < 0 {
+		if !atEOF {
+			// Request more data.
+			return start, nil, nil
+		}
+		if tail[0] == '{' || tail[0] == '[' || tail[0] == '"' {
+			return 0, nil, fmt.Errorf("unexpected end of JSON value at offset %d; value starts with %q", start, startEndString(tail))
+		}
+		// The last number or literal is terminated by EOF.
+		n = len(tail)
+	}
+	end := start + n
+	return end, data[start:end:end], nil
+}
+
+// parseAdjacentValue parses the top-level value at the start of s,
+// which may be immediately followed by the next value without delimiters.
+func parseAdjacentValue(s string, c *cache) (*Value, string, error) {
+	v, tail, err := parseValue(s, c, 0)
+	if err != nil || v.t != TypeNumber {
+		// Objects, arrays, strings and literals are already terminated
+		// at the end of the value by parseValue.
+		return v, tail, err
+	}
+	if n := adjacentValueLen(v.s); n < len(v.s) {
+		// parseRawNumber consumes chars of the adjacent number.
+		tail = s[n:]
+		v.s = v.s[:n]
+		c.setRaw(v, s, tail)
+	}
+	return v, tail, nil
+}
+
+// adjacentValueLen returns the length of the first value in token
+// containing number or literal without delimiters.
+//
+// len(token) is returned if token doesn't start with a value, which
+// is immediately followed by the next value, such as truefalse, 12-3 or 12null.
+func adjacentValueLen(token string) int {
+	for _, literal := range []string{"true", "false", "null"} {
+		if len(token) > len(literal) && token[:len(literal)] == literal {
+			return len(literal)
+		}
+	}
+	n := numberPrefixLen(token)
+	if n == 0 || n == len(token) {
+		return len(token)
+	}
+	switch ch := token[n]; {
+	case ch == '-' || ch >= '0' && ch <= '9':
+		// The number is followed by the next number.
+		return n
+	case ch == 't' || ch == 'f' || ch == 'n':
+		// The number is followed by a literal.
+		return n
+	}
+	// Leave invalid numbers such as 1.2.3 as is, so they are reported
+	// in the same way as before.
+	return len(token)
+}
+
+// numberPrefixLen returns the length of the longest number prefix of s
+// according to the JSON grammar.
+//
+// 0 is returned if s doesn't start with a number.
+func numberPrefixLen(s string) int {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	if i >= len(s) || s[i] < '0' || s[i] > '9' {
+		return 0
+	}
+	if s[i] == '0' {
+		// Leading zeros aren't allowed, so the integer part ends here.
+		i++
+	} else {
+		i += digitsLen(s[i:])
+	}
+	if i+1 < len(s) && s[i] == '.' {
+		if n := digitsLen(s[i+1:]); n > 0 {
+			i += 1 + n
+		}
+	}
+	if i+1 < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if s[j] == '-' || s[j] == '+' {
+			j++
+		}
+		if n := digitsLen(s[j:]); n > 0 {
+			i = j + n
+		}
+	}
+	return i
+}
+
+func digitsLen(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return n
+}
+
+// structuralChars contains chars, which are tracked by scanJSONValue
+// inside objects and arrays.
+var structuralChars = func() (t [256]bool) {
+	for _, ch := range []byte(`"{}[]`) {
+		t[ch] = true
+	}
+	return t
+}()
+
+// scanJSONValue returns the length of the JSON value at the start of s.
+//
+// -1 is returned if s contains incomplete JSON value.
+func scanJSONValue(s string) (int, error) {
+	switch s[0] {
+	case '{', '[':
+		depth := 0
+		i := 0
+		for {
+			// Fast path - skip chars, which cannot change the depth.
+			for i < len(s) && !structuralChars[s[i]] {
+				i++
+			}
+			if i >= len(s) {
+				return -1, nil
+			}
+			switch s[i] {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1, nil
+				}
+			case '"':
+				_, tail, err := parseRawString(s[i+1:])
+				if err != nil {
+					return -1, nil
+				}
+				i = len(s) - len(tail)
+				continue
+			}
+			i++
+		}
+	case '"':
+		_, tail, err := parseRawString(s[1:])
+		if err != nil {
+			return -1, nil
+		}
+		return len(s) - len(tail), nil
+	default:
+		n := 0
+		for n < len(s) && !isValueDelimiter(s[n]) {
+			n++
+		}
+		if n == 0 {
+			return 0, fmt.Errorf("unexpected char %q", s[0])
+		}
+		if m := adjacentValueLen(s[:n]); m < n {
+			// The value is immediately followed by the next value.
+			return m, nil
+		}
+		if n == len(s) {
+			// The value may continue in the next data chunk.
+			return -1, nil
+		}
+		return n, nil
+	}
+}
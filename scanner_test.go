@@ -1,8 +1,13 @@
 package fastjson
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -37,3 +42,704 @@ func TestScanner(t *testing.T) {
 		}
 	})
 }
+
+func TestScannerNextValue(t *testing.T) {
+	var sc Scanner
+
+	sc.Init(`{"a":1}XYZ[2]`)
+	v, err := sc.NextValue()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := v.String(); s != `{"a":1}` {
+		t.Fatalf("unexpected value; got %q; want %q", s, `{"a":1}`)
+	}
+	if tail := sc.Tail(); tail != "XYZ[2]" {
+		t.Fatalf("unexpected tail; got %q; want %q", tail, "XYZ[2]")
+	}
+
+	// Non-JSON tail must be left untouched on error.
+	if _, err := sc.NextValue(); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	if tail := sc.Tail(); tail != "XYZ[2]" {
+		t.Fatalf("unexpected tail; got %q; want %q", tail, "XYZ[2]")
+	}
+
+	// Skip the non-JSON framing and continue scanning.
+	sc.Init(sc.Tail()[len("XYZ"):])
+	v, err = sc.NextValue()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := v.String(); s != `[2]` {
+		t.Fatalf("unexpected value; got %q; want %q", s, `[2]`)
+	}
+	if sc.Value() != v {
+		t.Fatalf("Value must return the value obtained via NextValue")
+	}
+	if _, err := sc.NextValue(); err != io.EOF {
+		t.Fatalf("unexpected error; got %v; want %v", err, io.EOF)
+	}
+	if tail := sc.Tail(); tail != "" {
+		t.Fatalf("unexpected tail; got %q; want %q", tail, "")
+	}
+}
+
+func TestScannerSkipNext(t *testing.T) {
+	var sc Scanner
+
+	// Skip values starting with '[' and parse the rest.
+	sc.Init(` {"a":[1,{"b":"]}"}]} [1,[2,"\"["],{}] "x[" 123 true null [{"c":"d"}]{"e":2}`)
+	var bb bytes.Buffer
+	for {
+		ch, ok := sc.PeekByte()
+		if !ok {
+			break
+		}
+		if ch == '[' {
+			if !sc.SkipNext() {
+				break
+			}
+			if v := sc.Value(); v != nil {
+				t.Fatalf("unexpected non-nil value after SkipNext: %s", v)
+			}
+			bb.WriteString("<skipped>")
+			continue
+		}
+		if !sc.Next() {
+			break
+		}
+		fmt.Fprintf(&bb, "%s;", sc.Value())
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	s := bb.String()
+	sExpected := `{"a":[1,{"b":"]}"}]};<skipped>"x[";123;true;null;<skipped>{"e":2};`
+	if s != sExpected {
+		t.Fatalf("unexpected result; got %q; want %q", s, sExpected)
+	}
+	if _, ok := sc.PeekByte(); ok {
+		t.Fatalf("PeekByte must return false at the end of s")
+	}
+
+	// Skip all the values.
+	sc.Init(`{"foo":"bar"} 123 "baz" -1.5e3 [1,2]`)
+	n := 0
+	for sc.SkipNext() {
+		n++
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n != 5 {
+		t.Fatalf("unexpected number of skipped values; got %d; want %d", n, 5)
+	}
+
+	// Errors in skipped values.
+	f := func(s string) {
+		t.Helper()
+		sc.Init(s)
+		for sc.SkipNext() {
+		}
+		if err := sc.Error(); err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+		if sc.SkipNext() || sc.Next() {
+			t.Fatalf("SkipNext and Next must return false after error")
+		}
+		if _, ok := sc.PeekByte(); ok {
+			t.Fatalf("PeekByte must return false after error")
+		}
+	}
+	f(`[1,2`)
+	f(`{} {"foo":"bar}`)
+	f(`"foo`)
+	f(`123 }`)
+	f(`:`)
+}
+
+func TestScannerAdjacentValues(t *testing.T) {
+	// stdSplit splits s into values in the same way as encoding/json.Decoder does.
+	stdSplit := func(s string) ([]string, bool) {
+		d := json.NewDecoder(strings.NewReader(s))
+		var values []string
+		for d.More() {
+			var m json.RawMessage
+			if err := d.Decode(&m); err != nil {
+				return nil, false
+			}
+			values = append(values, string(m))
+		}
+		return values, true
+	}
+
+	f := func(s string) {
+		t.Helper()
+		expected, ok := stdSplit(s)
+		if !ok {
+			// Skip invalid streams such as 1.5e31E+2, since fastjson parses
+			// invalid numbers lazily.
+			return
+		}
+
+		// Next
+		var sc Scanner
+		sc.Init(s)
+		var values []string
+		for sc.Next() {
+			values = append(values, sc.Value().String())
+		}
+		if err := sc.Error(); err != nil {
+			t.Fatalf("unexpected error in Next for %q: %s", s, err)
+		}
+		if !reflect.DeepEqual(values, expected) {
+			t.Fatalf("unexpected values from Next for %q; got %q; want %q", s, values, expected)
+		}
+
+		// NextValue
+		sc.Init(s)
+		values = values[:0]
+		for {
+			v, err := sc.NextValue()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("unexpected error in NextValue for %q: %s", s, err)
+			}
+			values = append(values, v.String())
+		}
+		if !reflect.DeepEqual(values, expected) {
+			t.Fatalf("unexpected values from NextValue for %q; got %q; want %q", s, values, expected)
+		}
+
+		// SkipNext
+		sc.Init(s)
+		values = values[:0]
+		offset := 0
+		for sc.SkipNext() {
+			values = append(values, strings.TrimSpace(s[offset:sc.Offset()]))
+			offset = sc.Offset()
+		}
+		if err := sc.Error(); err != nil {
+			t.Fatalf("unexpected error in SkipNext for %q: %s", s, err)
+		}
+		if !reflect.DeepEqual(values, expected) {
+			t.Fatalf("unexpected values from SkipNext for %q; got %q; want %q", s, values, expected)
+		}
+
+		// InitAt must accept every offset returned from Offset.
+		sc.Init(s)
+		var offsets []int
+		for sc.Next() {
+			offsets = append(offsets, sc.Offset())
+		}
+		for i, offset := range offsets {
+			sc.InitAt(s, offset)
+			values = values[:0]
+			for sc.Next() {
+				values = append(values, sc.Value().String())
+			}
+			if err := sc.Error(); err != nil {
+				t.Fatalf("unexpected error after resuming %q at %d: %s", s, offset, err)
+			}
+			if !reflect.DeepEqual(values, expected[i+1:]) {
+				t.Fatalf("unexpected values after resuming %q at %d; got %q; want %q", s, offset, values, expected[i+1:])
+			}
+		}
+
+		// ScanJSONValue
+		for _, chunkLen := range []int{1, 2, 1000} {
+			bs := bufio.NewScanner(&chunkedReader{
+				data:      s,
+				chunkLens: []int{chunkLen},
+			})
+			bs.Split(ScanJSONValue)
+			values = values[:0]
+			for bs.Scan() {
+				values = append(values, bs.Text())
+			}
+			if err := bs.Err(); err != nil {
+				t.Fatalf("unexpected error in ScanJSONValue for %q: %s", s, err)
+			}
+			if !reflect.DeepEqual(values, expected) {
+				t.Fatalf("unexpected values from ScanJSONValue for %q with chunkLen=%d; got %q; want %q", s, chunkLen, values, expected)
+			}
+		}
+	}
+
+	values := []string{
+		// objects
+		`{"a":1}`,
+		`{}`,
+		// arrays
+		`[1,"x"]`,
+		`[]`,
+		// strings
+		`"x"`,
+		`""`,
+		`"\"\\"`,
+		// numbers
+		`12`,
+		`0`,
+		`-0`,
+		`-1.5e3`,
+		`1E+2`,
+		`0.25`,
+		// literals
+		`true`,
+		`false`,
+		`null`,
+	}
+	for _, a := range values {
+		for _, b := range values {
+			f(a + b)
+			for _, c := range values {
+				f(a + b + c)
+			}
+		}
+	}
+
+	// Ambiguous cases are split in the same way as encoding/json does.
+	f(`1213`)
+	f(`12 13`)
+	f(`012`)
+	f(`00`)
+	f(`-0-0`)
+	f(`1.5-2.5`)
+	f(`1e5-3`)
+}
+
+func TestScannerKeepValues(t *testing.T) {
+	var ss []string
+	for i := 0; i < 100; i++ {
+		ss = append(ss, fmt.Sprintf(`{"id":%d,"name":"item_%d","tags":["a\nb",%d]}`, i, i, i))
+	}
+	s := strings.Join(ss, "\n")
+
+	f := func(keep bool) []string {
+		var sc Scanner
+		sc.KeepValues(keep)
+		sc.Init(s)
+		var vs []*Value
+		for sc.Next() {
+			vs = append(vs, sc.Value())
+		}
+		if err := sc.Error(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var result []string
+		for _, v := range vs {
+			result = append(result, v.String())
+		}
+		return result
+	}
+
+	result := f(true)
+	if len(result) != len(ss) {
+		t.Fatalf("unexpected number of values; got %d; want %d", len(result), len(ss))
+	}
+	for i := range ss {
+		if result[i] != ss[i] {
+			t.Fatalf("unexpected value #%d; got %q; want %q", i, result[i], ss[i])
+		}
+	}
+
+	// Values are overwritten by subsequent Next calls by default.
+	result = f(false)
+	if len(result) != len(ss) {
+		t.Fatalf("unexpected number of values; got %d; want %d", len(result), len(ss))
+	}
+	if result[0] == ss[0] {
+		t.Fatalf("the first value must be overwritten by subsequent Next calls")
+	}
+}
+
+func TestScannerSetTypeFilter(t *testing.T) {
+	s := `{"a":1} 123 "foo" [1,2] {"b":"x\ny"} null true false nan {} "bar"`
+	f := func(keep bool, types []Type, expected []string, skippedExpected int) {
+		t.Helper()
+		var sc Scanner
+		sc.KeepValues(keep)
+		sc.SetTypeFilter(types...)
+		sc.Init(s)
+		var vs []*Value
+		for sc.Next() {
+			vs = append(vs, sc.Value())
+		}
+		if err := sc.Error(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var result []string
+		for _, v := range vs {
+			result = append(result, v.String())
+		}
+		if keep && !reflect.DeepEqual(result, expected) {
+			t.Fatalf("unexpected values for %s; got %q; want %q", types, result, expected)
+		}
+		if len(result) != len(expected) {
+			t.Fatalf("unexpected number of values for %s; got %d; want %d", types, len(result), len(expected))
+		}
+		if n := sc.Skipped(); n != skippedExpected {
+			t.Fatalf("unexpected number of skipped values for %s; got %d; want %d", types, n, skippedExpected)
+		}
+	}
+	for _, keep := range []bool{false, true} {
+		f(keep, []Type{TypeObject}, []string{`{"a":1}`, `{"b":"x\ny"}`, `{}`}, 8)
+		f(keep, []Type{TypeString, TypeArray}, []string{`"foo"`, `[1,2]`, `"bar"`}, 8)
+		f(keep, []Type{TypeNumber, TypeNull}, []string{`123`, `null`, `nan`}, 8)
+		f(keep, []Type{TypeTrue, TypeFalse}, []string{`true`, `false`}, 9)
+		f(keep, []Type{TypeFalse}, []string{`false`}, 10)
+		f(keep, nil, strings.Fields(s), 0)
+	}
+
+	// The filter is preserved across Init calls, while Skipped is reset.
+	var sc Scanner
+	sc.SetTypeFilter(TypeObject)
+	sc.Init(`1 2 {}`)
+	for sc.Next() {
+	}
+	if n := sc.Skipped(); n != 2 {
+		t.Fatalf("unexpected number of skipped values; got %d; want %d", n, 2)
+	}
+	sc.Init(`[] {"x":1} 3`)
+	if !sc.Next() {
+		t.Fatalf("cannot find object: %v", sc.Error())
+	}
+	if s := sc.Value().String(); s != `{"x":1}` {
+		t.Fatalf("unexpected value; got %s; want %s", s, `{"x":1}`)
+	}
+	if n := sc.Skipped(); n != 1 {
+		t.Fatalf("unexpected number of skipped values; got %d; want %d", n, 1)
+	}
+	if sc.Next() {
+		t.Fatalf("unexpected value found: %s", sc.Value())
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Parse errors in skipped values must be reported.
+	for _, s := range []string{`{} [1,2`, `{} "foo`, `{} [1,]`, `{} tru {}`, `{} [} {}`} {
+		sc.Init(s)
+		if !sc.Next() {
+			t.Fatalf("cannot find the first object in %q: %v", s, sc.Error())
+		}
+		if sc.Next() {
+			t.Fatalf("unexpected value found in %q: %s", s, sc.Value())
+		}
+		if sc.Error() == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+	}
+
+	// Unsupported type.
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expecting panic for unsupported type")
+			}
+		}()
+		sc.SetTypeFilter(typeRawString)
+	}()
+}
+
+func TestScannerPool(t *testing.T) {
+	var sp ScannerPool
+	for i := 0; i < 10; i++ {
+		sc := sp.Get()
+		if sc.keepValues {
+			t.Fatalf("Scanner obtained from the pool must have default settings")
+		}
+		sc.KeepValues(true)
+		sc.Init(`[1] {"foo":"bar"}`)
+		n := 0
+		for sc.Next() {
+			n++
+		}
+		if err := sc.Error(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if n != 2 {
+			t.Fatalf("unexpected number of values; got %d; want %d", n, 2)
+		}
+		sp.Put(sc)
+	}
+}
+
+// chunkedReader returns data in chunks with lengths from chunkLens.
+type chunkedReader struct {
+	data      string
+	chunkLens []int
+	n         int
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := r.chunkLens[r.n%len(r.chunkLens)]
+	r.n++
+	if n > len(p) {
+		n = len(p)
+	}
+	if n > len(r.data) {
+		n = len(r.data)
+	}
+	copy(p, r.data[:n])
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestScanJSONValue(t *testing.T) {
+	f := func(values []string, delimiters []string, chunkLens []int) {
+		t.Helper()
+
+		var sb strings.Builder
+		for i, v := range values {
+			sb.WriteString(delimiters[i%len(delimiters)])
+			sb.WriteString(v)
+		}
+		sb.WriteString(delimiters[0])
+		r := &chunkedReader{
+			data:      sb.String(),
+			chunkLens: chunkLens,
+		}
+		bs := bufio.NewScanner(r)
+		bs.Buffer(nil, 4*1024*1024)
+		bs.Split(ScanJSONValue)
+		var tokens []string
+		for bs.Scan() {
+			tokens = append(tokens, bs.Text())
+		}
+		if err := bs.Err(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(tokens) != len(values) {
+			t.Fatalf("unexpected number of tokens; got %d; want %d", len(tokens), len(values))
+		}
+		for i, token := range tokens {
+			if token != values[i] {
+				t.Fatalf("unexpected token #%d; got\n%s\nwant\n%s", i, startEndString(token), startEndString(values[i]))
+			}
+		}
+	}
+
+	// Small values with escape sequences split at every byte.
+	values := []string{
+		`{"a\"b}":["\\",{"c":"]\\\""}],"d":[]}`,
+		`"foo\"bar\\"`,
+		`""`,
+		`"\\\\"`,
+		`[1,[2,[3,{"}":"{"}]]]`,
+		`123`,
+		`-1.5e+3`,
+		`true`,
+		`false`,
+		`null`,
+		`{}`,
+		`[]`,
+	}
+	for _, chunkLen := range []int{1, 2, 3, 5, 7, 1000} {
+		f(values, []string{" ", "\n", "\t\r\n "}, []int{chunkLen})
+		f(values[:5], []string{""}, []int{chunkLen})
+	}
+
+	// Large fixtures split at awkward boundaries.
+	values = []string{
+		strings.TrimSpace(largeFixture),
+		strings.TrimSpace(twitterFixture),
+		strings.TrimSpace(mediumFixture),
+		strings.TrimSpace(smallFixture),
+		strings.TrimSpace(citmFixture),
+		"42",
+	}
+	f(values, []string{"\n"}, []int{1, 3, 2, 17, 4096, 1, 1, 255, 7, 65536})
+	f(values, []string{" ", ""}, []int{1 << 20})
+}
+
+func TestScanJSONValueError(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		bs := bufio.NewScanner(strings.NewReader(s))
+		bs.Split(ScanJSONValue)
+		for bs.Scan() {
+		}
+		if err := bs.Err(); err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+	}
+	f(`{"foo":1`)
+	f(`[1, 2] [`)
+	f(`"foo`)
+	f(`"foo\"`)
+	f(`}`)
+	f(`1 ,2`)
+}
+
+func TestScannerBOM(t *testing.T) {
+	const bom = "\xef\xbb\xbf"
+	var sc Scanner
+	sc.Init(bom + `{"a":1} [2]`)
+	var results []string
+	for sc.Next() {
+		results = append(results, sc.Value().String())
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := strings.Join(results, ","); s != `{"a":1},[2]` {
+		t.Fatalf("unexpected results; got %s; want %s", s, `{"a":1},[2]`)
+	}
+
+	// BOM-only input
+	sc.Init(bom)
+	if sc.Next() {
+		t.Fatalf("expecting no values in BOM-only input; got %s", sc.Value())
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error for BOM-only input: %s", err)
+	}
+
+	// BOM in the middle of the stream
+	sc.Init(`1 ` + bom + `2`)
+	if !sc.Next() {
+		t.Fatalf("expecting the first value; got error %v", sc.Error())
+	}
+	if sc.Next() {
+		t.Fatalf("expecting error for BOM in the middle of the stream; got %s", sc.Value())
+	}
+	if sc.Error() == nil {
+		t.Fatalf("expecting non-nil error for BOM in the middle of the stream")
+	}
+
+	// UTF-16 encoded input
+	sc.Init("\xff\xfe{\x00}\x00")
+	if sc.Next() {
+		t.Fatalf("expecting error for UTF-16 encoded input")
+	}
+	if err := sc.Error(); err == nil || !strings.Contains(err.Error(), "UTF-16 encoded JSON is not supported") {
+		t.Fatalf("unexpected error for UTF-16 encoded input: %v", err)
+	}
+
+	// LineScanner
+	var ls LineScanner
+	ls.Init(bom + "1\n2")
+	results = results[:0]
+	for ls.Next() {
+		if err := ls.Error(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		results = append(results, ls.Value().String())
+	}
+	if s := strings.Join(results, ","); s != `1,2` {
+		t.Fatalf("unexpected results; got %s; want %s", s, `1,2`)
+	}
+}
+
+func TestScannerInitAt(t *testing.T) {
+	s := "\xef\xbb\xbf" + `{"a":1} [2, 3]
+"foo\"bar"  -12.5e3 true
+	null {"b":{"c":[]}}123 false`
+
+	// Full scan
+	var sc Scanner
+	sc.Init(s)
+	var values []string
+	var offsets []int
+	for sc.Next() {
+		values = append(values, sc.Value().String())
+		offsets = append(offsets, sc.Offset())
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if sc.Offset() != len(s) {
+		t.Fatalf("unexpected offset at the end of s; got %d; want %d", sc.Offset(), len(s))
+	}
+	if len(values) != 9 {
+		t.Fatalf("unexpected number of values; got %d; want 9", len(values))
+	}
+
+	// Resume scan after every value with a fresh scanner.
+	for i, offset := range offsets {
+		var scResumed Scanner
+		scResumed.InitAt(s, offset)
+		var tail []string
+		for scResumed.Next() {
+			tail = append(tail, scResumed.Value().String())
+			if n := len(tail); scResumed.Offset() != offsets[i+n] {
+				t.Fatalf("unexpected offset after resuming at %d; got %d; want %d", offset, scResumed.Offset(), offsets[i+n])
+			}
+		}
+		if err := scResumed.Error(); err != nil {
+			t.Fatalf("unexpected error after resuming at %d: %s", offset, err)
+		}
+		got := strings.Join(tail, ",")
+		want := strings.Join(values[i+1:], ",")
+		if got != want {
+			t.Fatalf("unexpected values after resuming at %d\ngot\n%s\nwant\n%s", offset, got, want)
+		}
+	}
+
+	// Offsets must remain absolute after SkipNext and NextValue.
+	sc.InitBytesAt([]byte(s), offsets[1])
+	if !sc.SkipNext() {
+		t.Fatalf("unexpected error: %v", sc.Error())
+	}
+	if sc.Offset() != offsets[2] {
+		t.Fatalf("unexpected offset after SkipNext; got %d; want %d", sc.Offset(), offsets[2])
+	}
+	if _, err := sc.NextValue(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if sc.Offset() != offsets[3] {
+		t.Fatalf("unexpected offset after NextValue; got %d; want %d", sc.Offset(), offsets[3])
+	}
+
+	// Whitespace before the value and at the end of s is allowed.
+	sc.InitAt(s, offsets[1]+1)
+	if !sc.Next() || sc.Value().String() != values[2] {
+		t.Fatalf("cannot resume scan at whitespace before the value; error: %v", sc.Error())
+	}
+	sc.InitAt(s, len(s))
+	if sc.Next() {
+		t.Fatalf("expecting no values at the end of s; got %s", sc.Value())
+	}
+	if err := sc.Error(); err != nil {
+		t.Fatalf("unexpected error at the end of s: %s", err)
+	}
+
+	// Invalid offsets
+	f := func(s string, offset int) {
+		t.Helper()
+		var sc Scanner
+		sc.InitAt(s, offset)
+		if sc.Next() {
+			t.Fatalf("expecting error for offset %d in %q; got %s", offset, s, sc.Value())
+		}
+		if sc.Error() == nil {
+			t.Fatalf("expecting non-nil error for offset %d in %q", offset, s)
+		}
+		if _, err := sc.NextValue(); err == nil {
+			t.Fatalf("expecting non-nil error from NextValue for offset %d in %q", offset, s)
+		}
+	}
+	f(`[1,2]`, -1)
+	f(`[1,2]`, 6)
+	f(`[1,2]`, 2)
+	f(`[1,2]`, 4)
+	f(`{"a":1}`, 4)
+	f(`123 456`, 1)
+	f(`true false`, 8)
+	f(`truefalse`, 2)
+	f(`truefalse`, 6)
+	f(`12-3`, 1)
+	f(`-1.5e3true`, 2)
+	f(`-1.5e3true`, 5)
+	f(`12true`, 4)
+	f(`1.2.3`, 3)
+}
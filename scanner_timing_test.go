@@ -0,0 +1,46 @@
+package fastjson
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func BenchmarkScanner(b *testing.B) {
+	b.Run("large", func(b *testing.B) {
+		benchmarkScannerStream(b, largeFixture)
+	})
+	b.Run("canada", func(b *testing.B) {
+		benchmarkScannerStream(b, canadaFixture)
+	})
+}
+
+func benchmarkScannerStream(b *testing.B, fixture string) {
+	// Build a stream of fixtures delimited by newlines.
+	s := strings.Repeat(strings.TrimSpace(fixture)+"\n", 10)
+	b.Run("Next", func(b *testing.B) {
+		benchmarkScanner(b, s, (*Scanner).Next)
+	})
+	b.Run("SkipNext", func(b *testing.B) {
+		benchmarkScanner(b, s, (*Scanner).SkipNext)
+	})
+}
+
+func benchmarkScanner(b *testing.B, s string, next func(sc *Scanner) bool) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(s)))
+	b.RunParallel(func(pb *testing.PB) {
+		var sc Scanner
+		var n int
+		for pb.Next() {
+			sc.Init(s)
+			for next(&sc) {
+				n++
+			}
+			if err := sc.Error(); err != nil {
+				panic(err)
+			}
+		}
+		atomic.AddUint64(&Sink, uint64(n))
+	})
+}
@@ -0,0 +1,510 @@
+package fastjson
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SetPArena sets value at the given path in v.
+//
+// Path elements must be object keys (string) and array indexes (int),
+// like in GetPath. Missing and null intermediate values are replaced
+// by values allocated from a: objects are created for string elements,
+// while arrays are created for int elements. Arrays are extended with nulls
+// up to the given index like in SetArrayItem.
+//
+// An error is returned if the path is empty, if it contains invalid
+// elements or if an existing intermediate value has another type
+// than required by the path element. The error refers to the failed
+// path element, e.g. "path[1]: expected object key (string), got int on object"
+// or "path[0]: cannot set into value of type number".
+//
+// The created values follow Arena lifetime rules, i.e. v becomes invalid
+// after Reset is called on a.
+func (v *Value) SetPArena(a *Arena, path []interface{}, value *Value) error {
+	if v == nil {
+		return fmt.Errorf("cannot set into nil Value")
+	}
+	if len(path) == 0 {
+		return fmt.Errorf("path cannot be empty")
+	}
+	if value == nil {
+		value = valueNull
+	}
+	for i, elem := range path {
+		isLast := i == len(path)-1
+		switch elem := elem.(type) {
+		case string:
+			if v.Type() != TypeObject {
+				return setPathTypeError(i, v, "array index (int)", "string")
+			}
+			if isLast {
+				if !v.o.Replace(elem, value) {
+					v.o.Set(elem, value)
+				}
+				return nil
+			}
+			child := v.o.Get(elem)
+			if child == nil || child.t == TypeNull {
+				child = newSetPathContainer(a, path[i+1])
+				v.o.Set(elem, child)
+			}
+			v = child
+		case int:
+			if v.Type() != TypeArray {
+				return setPathTypeError(i, v, "object key (string)", "int")
+			}
+			if elem < 0 {
+				return fmt.Errorf("path[%d]: array index cannot be negative; got %d", i, elem)
+			}
+			if isLast {
+				v.SetArrayItem(elem, value)
+				return nil
+			}
+			if elem >= len(v.a) || v.a[elem] == nil || v.a[elem].t == TypeNull {
+				v.SetArrayItem(elem, newSetPathContainer(a, path[i+1]))
+			}
+			v = v.a[elem]
+		default:
+			return fmt.Errorf("path[%d]: unsupported path element %v of type %T; it must be string or int", i, elem, elem)
+		}
+	}
+	return nil
+}
+
+// SetPErr sets value at the given path in v.
+//
+// It works like SetPArena, but the created intermediate values are
+// allocated on the heap, so they don't depend on Arena lifetime.
+// An error explaining why value cannot be set is returned instead
+// of silently ignoring the call.
+func (v *Value) SetPErr(path []interface{}, value *Value) error {
+	var a Arena
+	return v.SetPArena(&a, path, value)
+}
+
+// setPathTypeError returns an error for the path element at index i,
+// which cannot be applied to v.
+//
+// expected is the path element required by v if v is a container.
+func setPathTypeError(i int, v *Value, expected, got string) error {
+	t := v.Type()
+	if t != TypeObject && t != TypeArray {
+		return fmt.Errorf("path[%d]: cannot set into value of type %s", i, t)
+	}
+	return fmt.Errorf("path[%d]: expected %s, got %s on %s", i, expected, got, t)
+}
+
+// SetAnyErr converts anyVal to Value via Arena.NewFromInterface
+// and sets it at the given path in v.
+//
+// It works like SetAnyArena, but the created values are allocated
+// on the heap, so they don't depend on Arena lifetime. See SetPErr
+// for details.
+func (v *Value) SetAnyErr(path []interface{}, anyVal interface{}) error {
+	var a Arena
+	return v.SetAnyArena(&a, path, anyVal)
+}
+
+// SetAnyArena converts anyVal to Value via Arena.NewFromInterface
+// and sets it at the given path in v.
+//
+// See SetPArena for details.
+func (v *Value) SetAnyArena(a *Arena, path []interface{}, anyVal interface{}) error {
+	value, err := a.NewFromInterface(anyVal)
+	if err != nil {
+		return fmt.Errorf("cannot set value at %s: %s", formatSetPath(path), err)
+	}
+	return v.SetPArena(a, path, value)
+}
+
+// newSetPathContainer returns new container for the given next path element.
+//
+// Invalid path elements are reported by SetPArena on the next iteration.
+func newSetPathContainer(a *Arena, nextElem interface{}) *Value {
+	if _, ok := nextElem.(int); ok {
+		return a.NewArray()
+	}
+	return a.NewObject()
+}
+
+// formatSetPath returns human-readable representation of the path prefix.
+func formatSetPath(path []interface{}) string {
+	if len(path) == 0 {
+		return "the root"
+	}
+	return fmt.Sprintf("%v", path)
+}
+
+// NewFromInterface returns new value for x.
+//
+// This is the inverse of Value.ToInterface. The following types
+// are supported:
+//
+//   - nil, bool and string
+//   - signed and unsigned integers, float32 and float64
+//   - json.Number, which must contain a valid JSON number
+//   - []interface{}, []string, []int64 and []float64
+//   - map[string]interface{}; its entries are sorted by keys
+//   - *Value, which is used as is
+//   - time.Time, which is converted to RFC 3339 string
+//   - json.RawMessage and json.Marshaler; their JSON is parsed
+//   - encoding.TextMarshaler, which is converted to string
+//
+// nil slices and maps are converted to null. Other types are converted
+// via reflection like encoding/json does:
+// pointers are dereferenced, arrays and slices are converted to arrays,
+// []byte is converted to base64 string, maps with string and integer
+// keys are converted to objects with keys sorted, and structs are converted
+// to objects. Struct fields are named according to their json tags,
+// which may contain "-", omitempty and string options. Fields of embedded
+// structs are added after the fields of the outer struct unless the outer
+// struct already has fields with the same names.
+//
+// An error is returned for unsupported types such as func, chan
+// and complex numbers, and for values with nesting depth exceeding MaxDepth.
+//
+// The returned value is valid until Reset is called on a.
+func (a *Arena) NewFromInterface(x interface{}) (*Value, error) {
+	return a.newFromInterface(x, 0)
+}
+
+func (a *Arena) newFromInterface(x interface{}, depth int) (*Value, error) {
+	depth++
+	if depth > MaxDepth {
+		return nil, fmt.Errorf("too big depth for the nested value; it exceeds %d", MaxDepth)
+	}
+	switch x := x.(type) {
+	case nil:
+		return valueNull, nil
+	case *Value:
+		if x == nil {
+			return valueNull, nil
+		}
+		return x, nil
+	case bool:
+		if x {
+			return valueTrue, nil
+		}
+		return valueFalse, nil
+	case string:
+		return a.NewString(x), nil
+	case int:
+		return a.NewNumberInt(x), nil
+	case int8:
+		return a.NewNumberInt64(int64(x)), nil
+	case int16:
+		return a.NewNumberInt64(int64(x)), nil
+	case int32:
+		return a.NewNumberInt64(int64(x)), nil
+	case int64:
+		return a.NewNumberInt64(x), nil
+	case uint:
+		return a.NewNumberUint64(uint64(x)), nil
+	case uint8:
+		return a.NewNumberUint64(uint64(x)), nil
+	case uint16:
+		return a.NewNumberUint64(uint64(x)), nil
+	case uint32:
+		return a.NewNumberUint64(uint64(x)), nil
+	case uint64:
+		return a.NewNumberUint64(x), nil
+	case float32:
+		return a.NewNumberFloat64(float64(x)), nil
+	case float64:
+		return a.NewNumberFloat64(x), nil
+	case json.Number:
+		return a.NewNumberStringErr(string(x))
+	case []string:
+		if x == nil {
+			return valueNull, nil
+		}
+		return a.NewArrayFromStrings(x), nil
+	case []int64:
+		if x == nil {
+			return valueNull, nil
+		}
+		return a.NewArrayFromInts(x), nil
+	case []float64:
+		if x == nil {
+			return valueNull, nil
+		}
+		return a.NewArrayFromFloats(x), nil
+	case []interface{}:
+		if x == nil {
+			return valueNull, nil
+		}
+		v := a.NewArrayCapacity(len(x))
+		for _, item := range x {
+			vv, err := a.newFromInterface(item, depth)
+			if err != nil {
+				return nil, err
+			}
+			v.a = append(v.a, vv)
+		}
+		return v, nil
+	case map[string]interface{}:
+		if x == nil {
+			return valueNull, nil
+		}
+		v := a.NewObjectCapacity(len(x))
+		for k, item := range x {
+			vv, err := a.newFromInterface(item, depth)
+			if err != nil {
+				return nil, err
+			}
+			v.o.kvs = append(v.o.kvs, kv{
+				k: k,
+				v: vv,
+			})
+		}
+		sort.Sort(kvsByKey(v.o.kvs))
+		return v, nil
+	case time.Time:
+		return a.NewString(x.Format(time.RFC3339Nano)), nil
+	case json.RawMessage:
+		if x == nil {
+			return valueNull, nil
+		}
+		v, err := a.ParseBytes(x)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse json.RawMessage: %s", err)
+		}
+		return v, nil
+	case json.Marshaler:
+		if isNilPointer(x) {
+			return valueNull, nil
+		}
+		data, err := x.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal %T to JSON: %s", x, err)
+		}
+		v, err := a.ParseBytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse JSON returned from %T.MarshalJSON: %s", x, err)
+		}
+		return v, nil
+	case encoding.TextMarshaler:
+		if isNilPointer(x) {
+			return valueNull, nil
+		}
+		data, err := x.MarshalText()
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal %T to text: %s", x, err)
+		}
+		return a.NewStringBytes(data), nil
+	default:
+		return a.newFromReflect(reflect.ValueOf(x), depth)
+	}
+}
+
+// isNilPointer returns true if x is nil pointer.
+func isNilPointer(x interface{}) bool {
+	rv := reflect.ValueOf(x)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// newFromReflect returns new value for rv, which cannot be converted
+// without reflection.
+func (a *Arena) newFromReflect(rv reflect.Value, depth int) (*Value, error) {
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			return valueTrue, nil
+		}
+		return valueFalse, nil
+	case reflect.String:
+		return a.NewString(rv.String()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.NewNumberInt64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.NewNumberUint64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return a.NewNumberFloat64(rv.Float()), nil
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return valueNull, nil
+		}
+		return a.newFromReflectValue(rv.Elem(), depth)
+	case reflect.Slice:
+		if rv.IsNil() {
+			return valueNull, nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return a.NewString(base64.StdEncoding.EncodeToString(rv.Bytes())), nil
+		}
+		return a.newArrayFromReflect(rv, depth)
+	case reflect.Array:
+		return a.newArrayFromReflect(rv, depth)
+	case reflect.Map:
+		if rv.IsNil() {
+			return valueNull, nil
+		}
+		return a.newObjectFromReflectMap(rv, depth)
+	case reflect.Struct:
+		v := a.NewObject()
+		if err := a.addReflectStructFields(v, rv, depth); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case reflect.Invalid:
+		return valueNull, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s; %s values cannot be represented in JSON", rv.Type(), rv.Kind())
+	}
+}
+
+// newFromReflectValue returns new value for rv.
+//
+// Values obtained via unexported embedded structs cannot be converted
+// to interface{}, so they are converted via reflection only.
+func (a *Arena) newFromReflectValue(rv reflect.Value, depth int) (*Value, error) {
+	if rv.CanInterface() {
+		return a.newFromInterface(rv.Interface(), depth)
+	}
+	depth++
+	if depth > MaxDepth {
+		return nil, fmt.Errorf("too big depth for the nested value; it exceeds %d", MaxDepth)
+	}
+	return a.newFromReflect(rv, depth)
+}
+
+func (a *Arena) newArrayFromReflect(rv reflect.Value, depth int) (*Value, error) {
+	n := rv.Len()
+	v := a.NewArrayCapacity(n)
+	for i := 0; i < n; i++ {
+		vv, err := a.newFromReflectValue(rv.Index(i), depth)
+		if err != nil {
+			return nil, err
+		}
+		v.a = append(v.a, vv)
+	}
+	return v, nil
+}
+
+func (a *Arena) newObjectFromReflectMap(rv reflect.Value, depth int) (*Value, error) {
+	v := a.NewObjectCapacity(rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		var k string
+		switch key := iter.Key(); key.Kind() {
+		case reflect.String:
+			k = key.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			k = strconv.FormatInt(key.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			k = strconv.FormatUint(key.Uint(), 10)
+		default:
+			return nil, fmt.Errorf("unsupported map key type %s in %s; it must be string or integer", key.Type(), rv.Type())
+		}
+		vv, err := a.newFromReflectValue(iter.Value(), depth)
+		if err != nil {
+			return nil, err
+		}
+		v.o.kvs = append(v.o.kvs, kv{
+			k: k,
+			v: vv,
+		})
+	}
+	sort.Sort(kvsByKey(v.o.kvs))
+	return v, nil
+}
+
+// addReflectStructFields adds exported fields of the struct rv to v.
+//
+// Fields already present in v aren't overwritten, so outer struct fields
+// take precedence over fields of embedded structs.
+func (a *Arena) addReflectStructFields(v *Value, rv reflect.Value, depth int) error {
+	rt := rv.Type()
+	var embedded []reflect.Value
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if n := strings.IndexByte(tag, ','); n >= 0 {
+			name, opts = tag[:n], tag[n:]
+		}
+		fv := rv.Field(i)
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				// Fields of the embedded struct are added after the fields of rv.
+				if fv.Kind() == reflect.Ptr {
+					if fv.IsNil() || sf.PkgPath != "" {
+						// encoding/json ignores embedded pointers to unexported structs.
+						continue
+					}
+					fv = fv.Elem()
+				}
+				embedded = append(embedded, fv)
+				continue
+			}
+		}
+		if sf.PkgPath != "" {
+			// Skip unexported field.
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if strings.Contains(opts, ",omitempty") && isEmptyReflectValue(fv) {
+			continue
+		}
+		if v.o.Get(name) != nil {
+			continue
+		}
+		vv, err := a.newFromReflectValue(fv, depth)
+		if err != nil {
+			return fmt.Errorf("cannot convert field %s.%s: %s", rt, sf.Name, err)
+		}
+		if strings.Contains(opts, ",string") {
+			switch vv.Type() {
+			case TypeString, TypeNumber, TypeTrue, TypeFalse:
+				vv = a.NewStringBytes(vv.MarshalTo(nil))
+			}
+		}
+		v.o.kvs = append(v.o.kvs, kv{
+			k: name,
+			v: vv,
+		})
+	}
+	for _, fv := range embedded {
+		if err := a.addReflectStructFields(v, fv, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isEmptyReflectValue returns true if rv is empty according to omitempty
+// json tag option.
+func isEmptyReflectValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,404 @@
+package fastjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValueSetPArena(t *testing.T) {
+	var a Arena
+	v := a.NewObject()
+	f := func(path []interface{}, value *Value, resultExpected string) {
+		t.Helper()
+		if err := v.SetPArena(&a, path, value); err != nil {
+			t.Fatalf("unexpected error for %v: %s", path, err)
+		}
+		if result := v.String(); result != resultExpected {
+			t.Fatalf("unexpected result after setting %v;\ngot\n%s\nwant\n%s", path, result, resultExpected)
+		}
+	}
+	f([]interface{}{"a"}, a.NewNumberInt(1), `{"a":1}`)
+	f([]interface{}{"b", "c", "d"}, a.NewString("x"), `{"a":1,"b":{"c":{"d":"x"}}}`)
+	f([]interface{}{"b", "c", "e"}, a.NewTrue(), `{"a":1,"b":{"c":{"d":"x","e":true}}}`)
+	f([]interface{}{"a"}, a.NewNumberInt(2), `{"a":2,"b":{"c":{"d":"x","e":true}}}`)
+	f([]interface{}{"arr", 2, "k"}, nil, `{"a":2,"b":{"c":{"d":"x","e":true}},"arr":[null,null,{"k":null}]}`)
+	f([]interface{}{"arr", 0, 1}, a.NewFalse(), `{"a":2,"b":{"c":{"d":"x","e":true}},"arr":[[null,false],null,{"k":null}]}`)
+	f([]interface{}{"arr", 2}, a.NewNumberInt(3), `{"a":2,"b":{"c":{"d":"x","e":true}},"arr":[[null,false],null,3]}`)
+	f([]interface{}{"b"}, a.NewNull(), `{"a":2,"b":null,"arr":[[null,false],null,3]}`)
+
+	// Null intermediate values are replaced.
+	f([]interface{}{"b", "x"}, a.NewNumberInt(4), `{"a":2,"b":{"x":4},"arr":[[null,false],null,3]}`)
+	f([]interface{}{"arr", 1, "y"}, a.NewNumberInt(5), `{"a":2,"b":{"x":4},"arr":[[null,false],{"y":5},3]}`)
+
+	ferr := func(path []interface{}) {
+		t.Helper()
+		s := v.String()
+		if err := v.SetPArena(&a, path, a.NewNull()); err == nil {
+			t.Fatalf("expecting non-nil error for %v", path)
+		}
+		if result := v.String(); result != s {
+			t.Fatalf("unexpected modification after error for %v;\ngot\n%s\nwant\n%s", path, result, s)
+		}
+	}
+	ferr(nil)
+	ferr([]interface{}{0})
+	ferr([]interface{}{"a", "b"})
+	ferr([]interface{}{"arr", "b"})
+	ferr([]interface{}{"arr", -1})
+	ferr([]interface{}{"arr", 2, 0})
+	ferr([]interface{}{1.5})
+	ferr([]interface{}{"b", "x", true})
+
+	var vNil *Value
+	if err := vNil.SetPArena(&a, []interface{}{"a"}, nil); err == nil {
+		t.Fatalf("expecting non-nil error for nil Value")
+	}
+
+	// The parsed values may be modified too.
+	vp := MustParse(`{"a":{"b c":[1]}}`)
+	if err := vp.SetPArena(&a, []interface{}{"a", "b c", 0}, a.NewString("y")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result := vp.String(); result != `{"a":{"b c":["y"]}}` {
+		t.Fatalf("unexpected result; got %s; want %s", result, `{"a":{"b c":["y"]}}`)
+	}
+}
+
+func TestValueSetPErr(t *testing.T) {
+	v := MustParse(`{"a":null,"arr":[1,null,{"x":2}],"n":3}`)
+	f := func(path []interface{}, value *Value, resultExpected string) {
+		t.Helper()
+		if err := v.SetPErr(path, value); err != nil {
+			t.Fatalf("unexpected error for %v: %s", path, err)
+		}
+		if result := v.String(); result != resultExpected {
+			t.Fatalf("unexpected result after setting %v;\ngot\n%s\nwant\n%s", path, result, resultExpected)
+		}
+	}
+
+	// Replace null intermediate values.
+	f([]interface{}{"a", "b"}, MustParse(`1`), `{"a":{"b":1},"arr":[1,null,{"x":2}],"n":3}`)
+	f([]interface{}{"arr", 1, 0}, MustParse(`true`), `{"a":{"b":1},"arr":[1,[true],{"x":2}],"n":3}`)
+
+	// Replace array elements.
+	f([]interface{}{"arr", 0}, MustParse(`"foo"`), `{"a":{"b":1},"arr":["foo",[true],{"x":2}],"n":3}`)
+	f([]interface{}{"arr", 2, "x"}, nil, `{"a":{"b":1},"arr":["foo",[true],{"x":null}],"n":3}`)
+
+	// Create intermediate containers.
+	f([]interface{}{"c", 1, "d"}, MustParse(`[]`), `{"a":{"b":1},"arr":["foo",[true],{"x":null}],"n":3,"c":[null,{"d":[]}]}`)
+	f([]interface{}{"arr", 4}, MustParse(`5`), `{"a":{"b":1},"arr":["foo",[true],{"x":null},null,5],"n":3,"c":[null,{"d":[]}]}`)
+
+	// Replace scalar values.
+	f([]interface{}{"n"}, MustParse(`{}`), `{"a":{"b":1},"arr":["foo",[true],{"x":null},null,5],"n":{},"c":[null,{"d":[]}]}`)
+
+	ferr := func(v *Value, path []interface{}, errExpected string) {
+		t.Helper()
+		s := v.String()
+		err := v.SetPErr(path, MustParse(`1`))
+		if err == nil {
+			t.Fatalf("expecting non-nil error for %v", path)
+		}
+		if err.Error() != errExpected {
+			t.Fatalf("unexpected error for %v;\ngot\n%s\nwant\n%s", path, err, errExpected)
+		}
+		if result := v.String(); result != s {
+			t.Fatalf("unexpected modification after error for %v;\ngot\n%s\nwant\n%s", path, result, s)
+		}
+	}
+	v = MustParse(`{"a":[1,{"b":2}],"n":3}`)
+	ferr(v, []interface{}{"a", "b"}, "path[1]: expected array index (int), got string on array")
+	ferr(v, []interface{}{0}, "path[0]: expected object key (string), got int on object")
+	ferr(v, []interface{}{"a", 1, 0}, "path[2]: expected object key (string), got int on object")
+	ferr(v, []interface{}{"n", "x"}, "path[1]: cannot set into value of type number")
+	ferr(v, []interface{}{"a", 0, 1}, "path[2]: cannot set into value of type number")
+	ferr(MustParse(`123`), []interface{}{"x"}, "path[0]: cannot set into value of type number")
+	ferr(MustParse(`null`), []interface{}{"x"}, "path[0]: cannot set into value of type null")
+	ferr(v, []interface{}{"a", -1}, "path[1]: array index cannot be negative; got -1")
+	ferr(v, []interface{}{"a", 1.5}, "path[1]: unsupported path element 1.5 of type float64; it must be string or int")
+	ferr(v, nil, "path cannot be empty")
+
+	var vNil *Value
+	if err := vNil.SetPErr([]interface{}{"a"}, nil); err == nil || err.Error() != "cannot set into nil Value" {
+		t.Fatalf("unexpected error for nil Value: %v", err)
+	}
+}
+
+func TestValueSetAnyArena(t *testing.T) {
+	var a Arena
+	v := a.NewObject()
+	f := func(path []interface{}, anyVal interface{}, resultExpected string) {
+		t.Helper()
+		if err := v.SetAnyArena(&a, path, anyVal); err != nil {
+			t.Fatalf("unexpected error for %v: %s", path, err)
+		}
+		if result := v.String(); result != resultExpected {
+			t.Fatalf("unexpected result after setting %v;\ngot\n%s\nwant\n%s", path, result, resultExpected)
+		}
+	}
+	f([]interface{}{"a"}, nil, `{"a":null}`)
+	f([]interface{}{"a"}, "x\"y", `{"a":"x\"y"}`)
+	f([]interface{}{"a"}, true, `{"a":true}`)
+	f([]interface{}{"a"}, int8(-8), `{"a":-8}`)
+	f([]interface{}{"a"}, uint64(math.MaxUint64), `{"a":18446744073709551615}`)
+	f([]interface{}{"a"}, float32(1.5), `{"a":1.5}`)
+	f([]interface{}{"a"}, json.Number("1.50e3"), `{"a":1.50e3}`)
+	f([]interface{}{"a"}, []string{"x", "y"}, `{"a":["x","y"]}`)
+	f([]interface{}{"a"}, []int64{1, -2}, `{"a":[1,-2]}`)
+	f([]interface{}{"a"}, []float64{0.5}, `{"a":[0.5]}`)
+	f([]interface{}{"a"}, MustParse(`{"z":[]}`), `{"a":{"z":[]}}`)
+	f([]interface{}{"b", 1}, map[string]interface{}{
+		"y": []interface{}{1, "2", nil, false},
+		"x": map[string]interface{}{},
+	}, `{"a":{"z":[]},"b":[null,{"x":{},"y":[1,"2",null,false]}]}`)
+
+	// ToInterface results must be converted back to the equal value.
+	vp := MustParse(`{"foo":[1,2.5,{"bar":"baz","x":null}],"t":true}`)
+	vc, err := a.NewFromInterface(vp.ToInterface())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !vc.Equal(vp) {
+		t.Fatalf("unexpected value; got %s; want %s", vc, vp)
+	}
+
+	ferr := func(anyVal interface{}) {
+		t.Helper()
+		s := v.String()
+		if err := v.SetAnyArena(&a, []interface{}{"c"}, anyVal); err == nil {
+			t.Fatalf("expecting non-nil error for %#v", anyVal)
+		}
+		if result := v.String(); result != s {
+			t.Fatalf("unexpected modification after error;\ngot\n%s\nwant\n%s", result, s)
+		}
+	}
+	ferr(json.Number("1x"))
+	ferr(func() {})
+	ferr([]interface{}{1, make(chan int)})
+	ferr(map[string]interface{}{"x": complex(1, 2)})
+
+	// Cyclic values must be rejected.
+	m := map[string]interface{}{}
+	m["m"] = m
+	ferr(m)
+}
+
+type testJSONMarshaler struct {
+	data string
+	err  error
+}
+
+func (m testJSONMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(m.data), m.err
+}
+
+type testTextMarshaler int
+
+func (m testTextMarshaler) MarshalText() ([]byte, error) {
+	if m < 0 {
+		return nil, fmt.Errorf("negative value")
+	}
+	return []byte(fmt.Sprintf("tm-%d", int(m))), nil
+}
+
+type testString string
+
+type testEmbedded struct {
+	E    int `json:"e"`
+	Name string
+}
+
+type testInner struct {
+	X int
+}
+
+type testStruct struct {
+	testEmbedded
+	testInner
+	Name    string      `json:"name"`
+	Skip    int         `json:"-"`
+	Empty   string      `json:",omitempty"`
+	Count   int64       `json:"count,string"`
+	Flag    bool        `json:",string"`
+	Quoted  testString  `json:"quoted,string"`
+	Ptr     *int        `json:"ptr"`
+	Nested  *testStruct `json:"nested,omitempty"`
+	Items   [2]uint8    `json:"items"`
+	private int
+}
+
+func TestArenaNewFromInterfaceTypes(t *testing.T) {
+	var a Arena
+	f := func(x interface{}, resultExpected string) {
+		t.Helper()
+		v, err := a.NewFromInterface(x)
+		if err != nil {
+			t.Fatalf("unexpected error for %#v: %s", x, err)
+		}
+		if result := v.String(); result != resultExpected {
+			t.Fatalf("unexpected result for %#v;\ngot\n%s\nwant\n%s", x, result, resultExpected)
+		}
+	}
+
+	// time.Time
+	tm := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	f(tm, `"2020-01-02T03:04:05.000000006Z"`)
+	f(&tm, `"2020-01-02T03:04:05.000000006Z"`)
+	f(time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("", 3600)), `"2020-01-02T03:04:05+01:00"`)
+
+	// json.RawMessage and json.Marshaler
+	f(json.RawMessage(` {"x": [1, 2]} `), `{"x":[1,2]}`)
+	f(json.RawMessage(nil), `null`)
+	f(testJSONMarshaler{data: `[1, "a"]`}, `[1,"a"]`)
+	f((*testJSONMarshaler)(nil), `null`)
+	f(MustParse(`{"v":true}`), `{"v":true}`)
+
+	// encoding.TextMarshaler
+	f(testTextMarshaler(12), `"tm-12"`)
+	f([]testTextMarshaler{1, 2}, `["tm-1","tm-2"]`)
+
+	// Maps with integer keys
+	f(map[int]string{2: "b", 10: "a", -1: "c"}, `{"-1":"c","10":"a","2":"b"}`)
+	f(map[uint8]bool{1: true}, `{"1":true}`)
+	f(map[testString]int{"x": 1}, `{"x":1}`)
+	f(map[string]int(nil), `null`)
+
+	// Arrays and slices
+	f([3]int{1, 2, 3}, `[1,2,3]`)
+	f([2][]string{{"a"}, nil}, `[["a"],null]`)
+	f([0]int{}, `[]`)
+	f([]uint16{1, 2}, `[1,2]`)
+	f([]byte("foo"), `"Zm9v"`)
+	f([]map[string]float32{{"x": 0.5}}, `[{"x":0.5}]`)
+
+	// Named types and pointers
+	n := 42
+	pn := &n
+	f(testString("foo"), `"foo"`)
+	f(&pn, `42`)
+	f((*int)(nil), `null`)
+
+	// Structs
+	f(testStruct{
+		testEmbedded: testEmbedded{
+			E:    7,
+			Name: "inner",
+		},
+		testInner: testInner{
+			X: 8,
+		},
+		Name:    "n",
+		Skip:    1,
+		Count:   -42,
+		Flag:    true,
+		Quoted:  "q",
+		Nested:  &testStruct{},
+		Items:   [2]uint8{1, 2},
+		private: 1,
+	}, `{"name":"n","count":"-42","Flag":"true","quoted":"\"q\"","ptr":null,`+
+		`"nested":{"name":"","count":"0","Flag":"false","quoted":"\"\"","ptr":null,"items":[0,0],"e":0,"Name":"","X":0},`+
+		`"items":[1,2],"e":7,"Name":"inner","X":8}`)
+	f(struct{}{}, `{}`)
+	f(struct {
+		A int `json:"a"`
+		B struct {
+			C []interface{} `json:"c"`
+		}
+	}{}, `{"a":0,"B":{"c":null}}`)
+
+	ferr := func(x interface{}, errExpected string) {
+		t.Helper()
+		v, err := a.NewFromInterface(x)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for %#v; got %s", x, v)
+		}
+		if !strings.Contains(err.Error(), errExpected) {
+			t.Fatalf("unexpected error for %#v; got %q; must contain %q", x, err, errExpected)
+		}
+	}
+	ferr(func() {}, "unsupported type func(); func values cannot be represented in JSON")
+	ferr(make(chan int), "unsupported type chan int; chan values cannot be represented in JSON")
+	ferr(complex64(1), "unsupported type complex64; complex64 values cannot be represented in JSON")
+	ferr(map[string]interface{}{"f": func() {}}, "func values cannot be represented in JSON")
+	ferr(struct{ C chan int }{}, "cannot convert field struct { C chan int }.C: unsupported type chan int")
+	ferr(map[float64]int{1: 2}, "unsupported map key type float64 in map[float64]int; it must be string or integer")
+	ferr(json.RawMessage(`{"x":`), "cannot parse json.RawMessage")
+	ferr(testJSONMarshaler{data: `[1,`}, "cannot parse JSON returned from fastjson.testJSONMarshaler.MarshalJSON")
+	ferr(testJSONMarshaler{err: fmt.Errorf("foo")}, "cannot marshal fastjson.testJSONMarshaler to JSON: foo")
+	ferr(testTextMarshaler(-1), "cannot marshal fastjson.testTextMarshaler to text: negative value")
+
+	// Cyclic pointers must be rejected.
+	type cyclic struct {
+		P *cyclic
+	}
+	c := &cyclic{}
+	c.P = c
+	ferr(c, "too big depth")
+}
+
+func TestValueSetAnyErr(t *testing.T) {
+	v := MustParse(`{"a":null}`)
+	if err := v.SetAnyErr([]interface{}{"a", "t"}, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := v.SetAnyErr([]interface{}{"b"}, map[int][2]int{1: {2, 3}}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resultExpected := `{"a":{"t":"2020-01-02T03:04:05Z"},"b":{"1":[2,3]}}`
+	if result := v.String(); result != resultExpected {
+		t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+	}
+
+	err := v.SetAnyErr([]interface{}{"c"}, func() {})
+	if err == nil {
+		t.Fatalf("expecting non-nil error for func")
+	}
+	errExpected := "cannot set value at [c]: unsupported type func(); func values cannot be represented in JSON"
+	if err.Error() != errExpected {
+		t.Fatalf("unexpected error;\ngot\n%s\nwant\n%s", err, errExpected)
+	}
+	if err := v.SetAnyErr([]interface{}{"a", 0}, 1); err == nil {
+		t.Fatalf("expecting non-nil error for invalid path")
+	}
+	if result := v.String(); result != resultExpected {
+		t.Fatalf("unexpected modification after error;\ngot\n%s\nwant\n%s", result, resultExpected)
+	}
+}
+
+func TestValueSetAnyArenaReset(t *testing.T) {
+	var a Arena
+
+	// Warm up the arena, so its memory isn't re-allocated below.
+	for i := 0; i < 10; i++ {
+		a.NewObject()
+	}
+	a.Reset()
+
+	v := a.NewObject()
+	if err := v.SetAnyArena(&a, []interface{}{"a", "b"}, "foo"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := v.String(); s != `{"a":{"b":"foo"}}` {
+		t.Fatalf("unexpected result; got %s; want %s", s, `{"a":{"b":"foo"}}`)
+	}
+
+	// Values created via SetAnyArena are invalidated by Reset,
+	// so their memory is re-used for subsequently created values.
+	a.Reset()
+	v2 := a.NewArray()
+	if v2 != v {
+		t.Fatalf("the memory for values must be re-used after Reset")
+	}
+	if err := v2.SetAnyArena(&a, []interface{}{1}, "bar"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s := v2.String(); s != `[null,"bar"]` {
+		t.Fatalf("unexpected result; got %s; want %s", s, `[null,"bar"]`)
+	}
+	if s := v.String(); s != `[null,"bar"]` {
+		t.Fatalf("the value created before Reset must be overwritten; got %s", s)
+	}
+}
@@ -0,0 +1,50 @@
+package fastjson
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func BenchmarkValueSetAnyArena(b *testing.B) {
+	// Build a document with 1000 leaves: 100 objects with 10 fields each.
+	var paths [][]interface{}
+	var values []interface{}
+	for i := 0; i < 100; i++ {
+		for j := 0; j < 10; j++ {
+			paths = append(paths, []interface{}{"items", i, fmt.Sprintf("field_%d", j)})
+			// Convert values to interface{} beforehand, so the conversion
+			// doesn't allocate memory in the benchmark loop.
+			values = append(values, i*10+j)
+		}
+	}
+	f := func(b *testing.B, getArena func(a *Arena) *Arena) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			var aa Arena
+			var sink int
+			for pb.Next() {
+				a := getArena(&aa)
+				v := a.NewObject()
+				for i, path := range paths {
+					if err := v.SetAnyArena(a, path, values[i]); err != nil {
+						panic(fmt.Errorf("unexpected error: %s", err))
+					}
+				}
+				sink += v.GetObject().Len()
+				a.Reset()
+			}
+			atomic.AddUint64(&Sink, uint64(sink))
+		})
+	}
+	b.Run("reused-arena", func(b *testing.B) {
+		f(b, func(a *Arena) *Arena {
+			return a
+		})
+	})
+	b.Run("new-arena", func(b *testing.B) {
+		f(b, func(a *Arena) *Arena {
+			return &Arena{}
+		})
+	})
+}
@@ -0,0 +1,88 @@
+package fastjson
+
+// ParseStats contains structural stats for the parsed JSON.
+//
+// See Value.Stats and Parser.Stats.
+type ParseStats struct {
+	// MaxDepth is the maximum nesting depth of the JSON.
+	//
+	// It is counted in the same way as MaxDepth constant, i.e. scalar
+	// values and empty objects or arrays have depth 1,
+	// while {"foo":[1]} has depth 3.
+	MaxDepth int
+
+	// NumValues is the total number of values including the root value.
+	NumValues int
+
+	// NumObjects is the number of objects.
+	NumObjects int
+
+	// NumArrays is the number of arrays.
+	NumArrays int
+
+	// NumStrings is the number of string values. Object keys aren't counted.
+	NumStrings int
+
+	// NumNumbers is the number of numbers.
+	NumNumbers int
+
+	// StringBytes is the total length of unescaped string values.
+	// Object keys aren't counted.
+	StringBytes int
+}
+
+// Stats returns structural stats for v.
+//
+// Stats visits all the values in v, so its cost is comparable
+// to the cost of MarshalTo. Strings are unescaped during the call.
+func (v *Value) Stats() ParseStats {
+	var ps ParseStats
+	if v != nil {
+		ps.add(v, 1)
+	}
+	return ps
+}
+
+func (ps *ParseStats) add(v *Value, depth int) {
+	ps.NumValues++
+	if depth > ps.MaxDepth {
+		ps.MaxDepth = depth
+	}
+	switch v.Type() {
+	case TypeObject:
+		ps.NumObjects++
+		for _, kv := range v.o.kvs {
+			ps.add(kv.v, depth+1)
+		}
+	case TypeArray:
+		ps.NumArrays++
+		for _, vv := range v.a {
+			ps.add(vv, depth+1)
+		}
+	case TypeString:
+		ps.NumStrings++
+		ps.StringBytes += len(v.s)
+	case TypeNumber:
+		ps.NumNumbers++
+	}
+}
+
+// Stats returns structural stats for the value returned from the last
+// successful Parse* call.
+//
+// Zero stats are returned if the last Parse* call failed or if the parsed
+// value has been invalidated by SwapBuffer or ForEachArrayElement.
+// The stats reflect modifications made to the parsed value after
+// the Parse* call. See Value.Stats for details.
+func (p *Parser) Stats() ParseStats {
+	return p.v.Stats()
+}
+
+// CacheLen returns the number of Values allocated from p by the last
+// Parse* call.
+//
+// It may be used for deciding whether p must be returned to a pool.
+// See also MemoryFootprint.
+func (p *Parser) CacheLen() int {
+	return len(p.c.vs)
+}
@@ -0,0 +1,113 @@
+package fastjson
+
+import (
+	"testing"
+)
+
+func TestValueStats(t *testing.T) {
+	f := func(s string, psExpected ParseStats) {
+		t.Helper()
+		var p Parser
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		ps := v.Stats()
+		if ps != psExpected {
+			t.Fatalf("unexpected stats for %q;\ngot\n%+v\nwant\n%+v", s, ps, psExpected)
+		}
+		if ps := p.Stats(); ps != psExpected {
+			t.Fatalf("unexpected parser stats for %q;\ngot\n%+v\nwant\n%+v", s, ps, psExpected)
+		}
+		// null, true and false values aren't allocated from the cache.
+		if n := p.CacheLen(); n > psExpected.NumValues {
+			t.Fatalf("too big cache len for %q; got %d; mustn't exceed %d", s, n, psExpected.NumValues)
+		}
+	}
+
+	f(`null`, ParseStats{MaxDepth: 1, NumValues: 1})
+	f(`123`, ParseStats{MaxDepth: 1, NumValues: 1, NumNumbers: 1})
+	f(`"foo"`, ParseStats{MaxDepth: 1, NumValues: 1, NumStrings: 1, StringBytes: 3})
+	f(`{}`, ParseStats{MaxDepth: 1, NumValues: 1, NumObjects: 1})
+	f(`[]`, ParseStats{MaxDepth: 1, NumValues: 1, NumArrays: 1})
+	f(`{"foo":[1]}`, ParseStats{MaxDepth: 3, NumValues: 3, NumObjects: 1, NumArrays: 1, NumNumbers: 1})
+	f(`[1, "a\nb", {"x": [true, false, null, [[]]]}, "", -1.5e3]`, ParseStats{
+		MaxDepth:    5,
+		NumValues:   12,
+		NumObjects:  1,
+		NumArrays:   4,
+		NumStrings:  2,
+		NumNumbers:  2,
+		StringBytes: 3,
+	})
+
+	// Object keys aren't counted as strings.
+	f(`{"foo":"bar","baz":{"x":"y"}}`, ParseStats{MaxDepth: 3, NumValues: 4, NumObjects: 2, NumStrings: 2, StringBytes: 4})
+
+	// The nil value
+	var v *Value
+	if ps := v.Stats(); ps != (ParseStats{}) {
+		t.Fatalf("expecting zero stats for nil value; got %+v", ps)
+	}
+}
+
+func TestParserStatsInvalidated(t *testing.T) {
+	var p Parser
+	if ps := p.Stats(); ps != (ParseStats{}) {
+		t.Fatalf("expecting zero stats for unused parser; got %+v", ps)
+	}
+	if _, err := p.Parse(`[1,2,3]`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ps := p.Stats(); ps.NumNumbers != 3 {
+		t.Fatalf("unexpected number of numbers; got %d; want 3", ps.NumNumbers)
+	}
+
+	// Failed parse
+	if _, err := p.Parse(`[1,2`); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	if ps := p.Stats(); ps != (ParseStats{}) {
+		t.Fatalf("expecting zero stats after failed parse; got %+v", ps)
+	}
+
+	// SwapBuffer
+	if _, err := p.Parse(`[1,2,3]`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	p.SwapBuffer(nil)
+	if ps := p.Stats(); ps != (ParseStats{}) {
+		t.Fatalf("expecting zero stats after SwapBuffer; got %+v", ps)
+	}
+}
+
+func TestParserStatsLargeFixture(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(largeFixture)
+	if err != nil {
+		t.Fatalf("cannot parse large fixture: %s", err)
+	}
+	ps := p.Stats()
+	if ps.MaxDepth < 3 || ps.MaxDepth > 10 {
+		t.Fatalf("unexpected MaxDepth: %d", ps.MaxDepth)
+	}
+	if n := p.CacheLen(); n < ps.NumObjects+ps.NumArrays+ps.NumStrings+ps.NumNumbers || n > ps.NumValues {
+		t.Fatalf("unexpected CacheLen %d for %+v", n, ps)
+	}
+	if ps.NumObjects < 100 || ps.NumStrings < 300 || ps.NumNumbers < 300 {
+		t.Fatalf("too small counts: %+v", ps)
+	}
+	if n := ps.NumObjects + ps.NumArrays + ps.NumStrings + ps.NumNumbers; n > ps.NumValues {
+		t.Fatalf("the sum of value counts %d exceeds NumValues %d", n, ps.NumValues)
+	}
+	if ps.StringBytes < ps.NumStrings || ps.StringBytes > len(largeFixture) {
+		t.Fatalf("unexpected StringBytes: %d", ps.StringBytes)
+	}
+
+	// Modifications must be reflected in the stats.
+	v.Set("new_key", MustParse(`{"a":"b"}`))
+	psNew := p.Stats()
+	if psNew.NumValues != ps.NumValues+2 || psNew.NumObjects != ps.NumObjects+1 || psNew.StringBytes != ps.StringBytes+1 {
+		t.Fatalf("unexpected stats after modification;\ngot\n%+v\nprev\n%+v", psNew, ps)
+	}
+}
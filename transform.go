@@ -0,0 +1,98 @@
+package fastjson
+
+// TransformStringsOpts contains options for Value.TransformStringsOpts.
+type TransformStringsOpts struct {
+	// Keys enables transforming object keys in addition to string values.
+	Keys bool
+}
+
+// TransformStrings replaces every string value in v with the result of f.
+//
+// f is called with the path to the string and its unescaped contents.
+// Path elements are object keys (string) and array indexes (int) like
+// in Walk. f may modify s in place and return it. The returned bytes
+// are copied, so f may re-use them after returning. f cannot hold path
+// and s after returning, since they are re-used for subsequent calls.
+//
+// The replaced strings are escaped during marshaling, so MarshalTo
+// returns valid JSON after the transformation. Values of other types
+// remain untouched. Raw keeps returning the original JSON.
+func (v *Value) TransformStrings(f func(path []interface{}, s []byte) []byte) {
+	v.TransformStringsOpts(f, TransformStringsOpts{})
+}
+
+// TransformStringsOpts replaces strings in v with the result of f according to opts.
+//
+// f is called for object keys if opts.Keys is set. The path for the key
+// points to the object containing the key, so keys may be distinguished
+// from string values by the path. Children of the entry are visited with
+// the transformed key in the path.
+//
+// See TransformStrings for details.
+func (v *Value) TransformStringsOpts(f func(path []interface{}, s []byte) []byte, opts TransformStringsOpts) {
+	if v == nil {
+		return
+	}
+	tr := transformer{
+		f:    f,
+		opts: opts,
+	}
+	tr.transform(v)
+}
+
+type transformer struct {
+	f    func(path []interface{}, s []byte) []byte
+	opts TransformStringsOpts
+
+	// path is the path to the currently transformed value.
+	path []interface{}
+
+	// buf is a buffer for strings passed to f.
+	buf []byte
+}
+
+func (tr *transformer) transform(v *Value) {
+	switch v.Type() {
+	case TypeObject:
+		o := &v.o
+		o.unescapeKeys()
+		for i := range o.kvs {
+			kv := &o.kvs[i]
+			if tr.opts.Keys {
+				if k, ok := tr.transformString(kv.k); ok {
+					kv.k = k
+					// The key offset refers to the original key in the JSON.
+					kv.ko = 0
+					// The new key isn't interned, so the object cannot be treated as having interned keys anymore.
+					o.keysInterned = false
+				}
+			}
+			tr.path = append(tr.path, kv.k)
+			tr.transform(kv.v)
+			tr.path = tr.path[:len(tr.path)-1]
+		}
+	case TypeArray:
+		for i, vv := range v.a {
+			tr.path = append(tr.path, i)
+			tr.transform(vv)
+			tr.path = tr.path[:len(tr.path)-1]
+		}
+	case TypeString:
+		if s, ok := tr.transformString(v.s); ok {
+			v.s = s
+			v.escErr = escapeErrorNone
+		}
+	}
+}
+
+// transformString returns the result of tr.f for s.
+//
+// false is returned if the result equals to s, so s needn't be replaced.
+func (tr *transformer) transformString(s string) (string, bool) {
+	tr.buf = append(tr.buf[:0], s...)
+	b := tr.f(tr.path, tr.buf)
+	if string(b) == s {
+		return s, false
+	}
+	return string(b), true
+}
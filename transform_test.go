@@ -0,0 +1,185 @@
+package fastjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// nonStringValues returns string representation for all the non-string values in v.
+func nonStringValues(v *Value) []string {
+	var a []string
+	v.Walk(func(path []interface{}, v *Value) bool {
+		switch v.Type() {
+		case TypeObject, TypeArray, TypeString:
+		default:
+			a = append(a, fmt.Sprintf("%v=%s", path, v))
+		}
+		return true
+	})
+	return a
+}
+
+func TestValueTransformStringsFixtures(t *testing.T) {
+	f := func(s, key string) {
+		t.Helper()
+		var p Parser
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse fixture: %s", err)
+		}
+		nonStrings := nonStringValues(v)
+		dataOrig := v.MarshalTo(nil)
+
+		n := 0
+		v.TransformStrings(func(path []interface{}, s []byte) []byte {
+			if k, ok := path[len(path)-1].(string); ok && k == key {
+				n++
+				return append(s[:0], "<redacted>"...)
+			}
+			return s
+		})
+		if n == 0 {
+			t.Fatalf("cannot find strings under the key %q", key)
+		}
+
+		data := v.MarshalTo(nil)
+		if !json.Valid(data) {
+			t.Fatalf("invalid JSON after the transformation:\n%s", data)
+		}
+		if len(data) == len(dataOrig) {
+			t.Fatalf("the length of marshaled value must change after the transformation")
+		}
+		if bytes.Contains(data, []byte(`"`+key+`":"`)) && !bytes.Contains(data, []byte(`"`+key+`":"<redacted>"`)) {
+			t.Fatalf("missing redacted value for the key %q", key)
+		}
+		vv, err := ParseBytes(data)
+		if err != nil {
+			t.Fatalf("cannot parse transformed value: %s", err)
+		}
+		m := 0
+		vv.Walk(func(path []interface{}, v *Value) bool {
+			if len(path) > 0 && path[len(path)-1] == key && v.Type() == TypeString {
+				m++
+				if s := string(v.GetStringBytes()); s != "<redacted>" {
+					t.Fatalf("unexpected value at %v; got %q; want %q", path, s, "<redacted>")
+				}
+			}
+			return true
+		})
+		if m != n {
+			t.Fatalf("unexpected number of redacted values; got %d; want %d", m, n)
+		}
+		if a := nonStringValues(vv); fmt.Sprint(a) != fmt.Sprint(nonStrings) {
+			t.Fatalf("non-string values mustn't change after the transformation")
+		}
+	}
+
+	f(mediumFixture, "email")
+	f(twitterFixture, "screen_name")
+	f(citmFixture, "name")
+}
+
+func TestValueTransformStrings(t *testing.T) {
+	const s = `{"ab":"x\"y","n":[1,"A\n",true,null,{"k":"v"}],"e":""}`
+
+	var p Parser
+	v, err := p.Parse(s)
+	if err != nil {
+		t.Fatalf("cannot parse JSON: %s", err)
+	}
+
+	// Transform raw strings without accessing them beforehand.
+	var calls []string
+	v.TransformStrings(func(path []interface{}, s []byte) []byte {
+		calls = append(calls, fmt.Sprintf("%v=%q", path, s))
+		return append(s, "\"\\\n\x01"...)
+	})
+	callsExpected := `[[ab]="x\"y" [n 1]="A\n" [n 4 k]="v" [e]=""]`
+	if fmt.Sprint(calls) != callsExpected {
+		t.Fatalf("unexpected calls; got\n%s\nwant\n%s", calls, callsExpected)
+	}
+	data := v.MarshalTo(nil)
+	if !json.Valid(data) {
+		t.Fatalf("invalid JSON after the transformation:\n%s", data)
+	}
+	resultExpected := `{"ab":"x\"y\"\\\n\u0001","n":[1,"A\n\"\\\n\u0001",true,null,{"k":"v\"\\\n\u0001"}],"e":"\"\\\n\u0001"}`
+	if string(data) != resultExpected {
+		t.Fatalf("unexpected result; got\n%s\nwant\n%s", data, resultExpected)
+	}
+	if sb := v.GetStringBytes("n", "1"); string(sb) != "A\n\"\\\n\x01" {
+		t.Fatalf("unexpected string; got %q", sb)
+	}
+
+	// Transform keys.
+	v, err = p.Parse(s)
+	if err != nil {
+		t.Fatalf("cannot parse JSON: %s", err)
+	}
+	calls = calls[:0]
+	v.TransformStringsOpts(func(path []interface{}, s []byte) []byte {
+		calls = append(calls, fmt.Sprintf("%v=%q", path, s))
+		return bytes.ToUpper(s)
+	}, TransformStringsOpts{
+		Keys: true,
+	})
+	callsExpected = `[[]="ab" [AB]="x\"y" []="n" [N 1]="A\n" [N 4]="k" [N 4 K]="v" []="e" [E]=""]`
+	if fmt.Sprint(calls) != callsExpected {
+		t.Fatalf("unexpected calls; got\n%s\nwant\n%s", calls, callsExpected)
+	}
+	resultExpected = `{"AB":"X\"Y","N":[1,"A\n",true,null,{"K":"V"}],"E":""}`
+	if data := v.MarshalTo(nil); string(data) != resultExpected {
+		t.Fatalf("unexpected result; got\n%s\nwant\n%s", data, resultExpected)
+	}
+	if sb := v.GetStringBytes("N", "4", "K"); string(sb) != "V" {
+		t.Fatalf("unexpected value for the transformed key; got %q; want %q", sb, "V")
+	}
+	for _, key := range []string{"AB", "N", "E"} {
+		if _, _, ok := v.GetObject().KeyOffset(key); ok {
+			t.Fatalf("unexpected offset for the transformed key %q", key)
+		}
+	}
+	v.GetObject().VisitWithOffsets(func(key []byte, offset int, _ *Value) {
+		if offset != -1 {
+			t.Fatalf("unexpected offset for the transformed key %q; got %d; want -1", key, offset)
+		}
+	})
+
+	// Interned keys must be transformed too.
+	p.InternKeys(true)
+	v, err = p.Parse(`{"foo":"bar"}`)
+	if err != nil {
+		t.Fatalf("cannot parse JSON: %s", err)
+	}
+	v.TransformStringsOpts(func(path []interface{}, s []byte) []byte {
+		return bytes.ToUpper(s)
+	}, TransformStringsOpts{
+		Keys: true,
+	})
+	if data := v.MarshalTo(nil); string(data) != `{"FOO":"BAR"}` {
+		t.Fatalf("unexpected result; got %s; want %s", data, `{"FOO":"BAR"}`)
+	}
+
+	// Scalar values.
+	v = MustParse(`"foo"`)
+	v.TransformStrings(func(path []interface{}, s []byte) []byte {
+		if len(path) != 0 {
+			t.Fatalf("unexpected non-empty path: %v", path)
+		}
+		return []byte("bar")
+	})
+	if vs := v.String(); vs != `"bar"` {
+		t.Fatalf("unexpected result; got %s; want %s", vs, `"bar"`)
+	}
+	v = MustParse(`123`)
+	v.TransformStrings(func(path []interface{}, s []byte) []byte {
+		t.Fatalf("unexpected call for number")
+		return s
+	})
+	var vNil *Value
+	vNil.TransformStrings(func(path []interface{}, s []byte) []byte {
+		t.Fatalf("unexpected call for nil value")
+		return s
+	})
+}
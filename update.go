@@ -1,6 +1,8 @@
 package fastjson
 
 import (
+	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -31,6 +33,30 @@ func (o *Object) Del(key string) {
 	}
 }
 
+// DelPrefix deletes all the entries with keys starting with the given
+// prefix from o.
+//
+// Keys are matched byte-wise after unescaping. The order of the remaining
+// entries is preserved.
+//
+// Returns the number of deleted entries.
+func (o *Object) DelPrefix(prefix string) int {
+	if o == nil {
+		return 0
+	}
+	o.unescapeKeys()
+
+	kvs := o.kvs[:0]
+	for _, kv := range o.kvs {
+		if !strings.HasPrefix(kv.k, prefix) {
+			kvs = append(kvs, kv)
+		}
+	}
+	n := len(o.kvs) - len(kvs)
+	o.kvs = kvs
+	return n
+}
+
 // Del deletes the entry with the given key from array or object v.
 func (v *Value) Del(key string) {
 	if v == nil {
@@ -51,6 +77,13 @@ func (v *Value) Del(key string) {
 
 // Set sets (key, value) entry in the o.
 //
+// The existing entry keeps its position in o, while the new entry is added
+// to the end of o. So Del followed by Set moves the entry to the end.
+// Use SetAt for inserting the entry at the given position.
+//
+// Duplicate entries with the given key are removed from o,
+// so only a single entry with the given key remains after the call.
+//
 // The value must be unchanged during o lifetime.
 func (o *Object) Set(key string, value *Value) {
 	if o == nil {
@@ -66,16 +99,224 @@ func (o *Object) Set(key string, value *Value) {
 		kv := &o.kvs[i]
 		if kv.k == key {
 			kv.v = value
+			o.delDuplicates(i)
 			return
 		}
 	}
 
 	// Add new entry.
+	// The key isn't interned, so the object cannot be treated as having interned keys anymore.
+	o.keysInterned = false
 	kv := o.getKV()
 	kv.k = key
 	kv.v = value
 }
 
+// delDuplicates removes entries after the i-th entry with the same key
+// as the i-th entry.
+//
+// The caller must unescape o keys before calling delDuplicates.
+func (o *Object) delDuplicates(i int) {
+	key := o.kvs[i].k
+	kvs := o.kvs[:i+1]
+	for _, kv := range o.kvs[i+1:] {
+		if kv.k != key {
+			kvs = append(kvs, kv)
+		}
+	}
+	o.kvs = kvs
+}
+
+// Rename renames the entry with oldKey to newKey in o.
+//
+// The entry keeps its position in o. Other entries with newKey are removed
+// from o, so only a single entry with newKey remains after the call.
+// Only the first entry is renamed if o contains multiple entries with oldKey.
+//
+// Returns false if o doesn't contain an entry with oldKey.
+func (o *Object) Rename(oldKey, newKey string) bool {
+	if o == nil {
+		return false
+	}
+	o.unescapeKeys()
+
+	for i := range o.kvs {
+		kv := &o.kvs[i]
+		if kv.k != oldKey {
+			continue
+		}
+		if oldKey == newKey {
+			return true
+		}
+		// The new key isn't interned, so the object cannot be treated as having interned keys anymore.
+		o.keysInterned = false
+		kv.k = newKey
+		// The key offset refers to oldKey in the original JSON.
+		kv.ko = 0
+		kvs := o.kvs[:0]
+		for j, kv := range o.kvs {
+			if j == i || kv.k != newKey {
+				kvs = append(kvs, kv)
+			}
+		}
+		o.kvs = kvs
+		return true
+	}
+	return false
+}
+
+// Upsert updates or inserts the entry with the given key in o.
+//
+// f is called with the existing value for the key or with nil if o
+// doesn't contain the key. The value returned from f replaces the existing
+// value or is added to the end of o if the key is missing. nil returned
+// from f is treated as null, like in Set.
+//
+// Upsert finds the key only once, so it is faster than Get followed by Set.
+// Duplicate entries with the given key are removed from o, like in Set.
+//
+// f mustn't modify o. The value returned from f must be unchanged
+// during o lifetime.
+func (o *Object) Upsert(key string, f func(existing *Value) *Value) {
+	if o == nil {
+		return
+	}
+	o.unescapeKeys()
+
+	for i := range o.kvs {
+		kv := &o.kvs[i]
+		if kv.k == key {
+			kv.v = f(kv.v)
+			if kv.v == nil {
+				kv.v = valueNull
+			}
+			o.delDuplicates(i)
+			return
+		}
+	}
+
+	value := f(nil)
+	if value == nil {
+		value = valueNull
+	}
+	o.keysInterned = false
+	kv := o.getKV()
+	kv.k = key
+	kv.v = value
+}
+
+// IndexOf returns the position of the entry with the given key in o.
+//
+// The position of the first entry is returned if o contains multiple
+// entries with the given key. -1 is returned if o doesn't contain the key.
+func (o *Object) IndexOf(key string) int {
+	if o == nil {
+		return -1
+	}
+	o.unescapeKeys()
+
+	for i, kv := range o.kvs {
+		if kv.k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetAt inserts (key, value) entry at the given position in o.
+//
+// Existing entries with the given key are removed from o before
+// the insertion, so IndexOf(key) returns index after the call. index must be
+// in the range [0..Len()] for o without the removed entries. o remains
+// unchanged if index is out of range. This allows
+// restoring the entry at the original position after Del:
+//
+//	i := o.IndexOf(key)
+//	o.Del(key)
+//	...
+//	o.SetAt(i, key, value)
+//
+// nil value is treated as null. The value must be unchanged during o lifetime.
+func (o *Object) SetAt(index int, key string, value *Value) error {
+	if o == nil {
+		return fmt.Errorf("cannot set %q in nil object", key)
+	}
+	if value == nil {
+		value = valueNull
+	}
+	o.unescapeKeys()
+
+	n := len(o.kvs) - o.CountKey(key)
+	if index < 0 || index > n {
+		return fmt.Errorf("index %d is out of range [0..%d]", index, n)
+	}
+	if n < len(o.kvs) {
+		kvs := o.kvs[:0]
+		for _, kv := range o.kvs {
+			if kv.k != key {
+				kvs = append(kvs, kv)
+			}
+		}
+		o.kvs = kvs
+	}
+
+	// The key isn't interned, so the object cannot be treated as having interned keys anymore.
+	o.keysInterned = false
+	o.getKV()
+	copy(o.kvs[index+1:], o.kvs[index:])
+	kv := &o.kvs[index]
+	kv.k = key
+	kv.v = value
+	kv.ko = 0
+	return nil
+}
+
+// Replace replaces the value for the given key in o.
+//
+// Unlike Set, Replace doesn't add the entry if o doesn't contain the key
+// and returns false in this case. The entry keeps its position in o.
+// Duplicate entries with the given key are removed from o, like in Set.
+//
+// nil value is treated as null. The value must be unchanged during o lifetime.
+func (o *Object) Replace(key string, value *Value) bool {
+	if o == nil {
+		return false
+	}
+	if value == nil {
+		value = valueNull
+	}
+	o.unescapeKeys()
+
+	for i := range o.kvs {
+		kv := &o.kvs[i]
+		if kv.k == key {
+			kv.v = value
+			o.delDuplicates(i)
+			return true
+		}
+	}
+	return false
+}
+
+// Dedup removes entries with duplicate keys from o.
+//
+// The first entry for each key is kept, so Get returns the same values
+// before and after the call.
+//
+// Returns the number of removed entries.
+func (o *Object) Dedup() int {
+	if o == nil {
+		return 0
+	}
+	o.unescapeKeys()
+
+	n := len(o.kvs)
+	for i := 0; i < len(o.kvs); i++ {
+		o.delDuplicates(i)
+	}
+	return n - len(o.kvs)
+}
+
 // Set sets (key, value) entry in the array or object v.
 //
 // The value must be unchanged during v lifetime.
@@ -103,8 +344,83 @@ func (v *Value) SetArrayItem(idx int, value *Value) {
 	if v == nil || v.t != TypeArray {
 		return
 	}
-	for idx >= len(v.a) {
-		v.a = append(v.a, valueNull)
+	if idx >= len(v.a) {
+		// Grow the array at once instead of growing it item by item.
+		if n := idx + 1 - len(v.a); n > cap(v.a)-len(v.a) {
+			v.a = growValues(v.a, n)
+		}
+		for idx >= len(v.a) {
+			v.a = append(v.a, valueNull)
+		}
 	}
 	v.a[idx] = value
 }
+
+// GrowArray reserves the capacity for n additional items in array v.
+//
+// Subsequent SetArrayItem calls for up to n new items don't re-allocate
+// the underlying slice. GrowArray does nothing if v isn't an array.
+func (v *Value) GrowArray(n int) {
+	if v == nil || v.t != TypeArray || n <= cap(v.a)-len(v.a) {
+		return
+	}
+	a := make([]*Value, len(v.a), len(v.a)+n)
+	copy(a, v.a)
+	v.a = a
+}
+
+// growValues returns a with the capacity for at least n additional items.
+//
+// The capacity is grown exponentially, so repeated calls have amortized
+// constant cost per item.
+func growValues(a []*Value, n int) []*Value {
+	capNew := 2 * cap(a)
+	if capNew < len(a)+n {
+		capNew = len(a) + n
+	}
+	aNew := make([]*Value, len(a), capNew)
+	copy(aNew, a)
+	return aNew
+}
+
+// MoveToFront moves the entry with the given key to the front of o.
+//
+// Returns false if o doesn't contain the given key.
+func (o *Object) MoveToFront(key string) bool {
+	if o == nil {
+		return false
+	}
+	o.unescapeKeys()
+
+	for i, kv := range o.kvs {
+		if kv.k == key {
+			copy(o.kvs[1:i+1], o.kvs[:i])
+			o.kvs[0] = kv
+			return true
+		}
+	}
+	return false
+}
+
+// Swap swaps the i-th and the j-th entries in o.
+//
+// Swap is no-op if i or j is out of range.
+func (o *Object) Swap(i, j int) {
+	if o == nil || i < 0 || j < 0 || i >= len(o.kvs) || j >= len(o.kvs) {
+		return
+	}
+	o.kvs[i], o.kvs[j] = o.kvs[j], o.kvs[i]
+}
+
+// SortArray stably sorts items in the array v using the given less func.
+//
+// SortArray is no-op if v isn't an array.
+func (v *Value) SortArray(less func(a, b *Value) bool) {
+	if v == nil || v.t != TypeArray {
+		return
+	}
+	a := v.a
+	sort.SliceStable(a, func(i, j int) bool {
+		return less(a[i], a[j])
+	})
+}
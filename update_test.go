@@ -1,6 +1,7 @@
 package fastjson
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -101,3 +102,470 @@ func TestValueDelSet(t *testing.T) {
 	v.Set("x", MustParse(`[]`))
 	v.SetArrayItem(1, MustParse(`[]`))
 }
+
+func TestObjectSetDuplicateKeys(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"a":1,"b":2,"a":3,"c":4,"a":5}`)
+	if err != nil {
+		t.Fatalf("unexpected error during parse: %s", err)
+	}
+	o := v.GetObject()
+
+	// Get must return the first entry
+	if n := o.Get("a").GetInt(); n != 1 {
+		t.Fatalf("unexpected value for the first key; got %d; want %d", n, 1)
+	}
+
+	o.Set("a", MustParse(`"x"`))
+	str := o.String()
+	strExpected := `{"a":"x","b":2,"c":4}`
+	if str != strExpected {
+		t.Fatalf("unexpected string representation for o: got %q; want %q", str, strExpected)
+	}
+	if n := strings.Count(str, `"a"`); n != 1 {
+		t.Fatalf("unexpected number of occurrences for the key; got %d; want %d", n, 1)
+	}
+}
+
+func TestObjectDedup(t *testing.T) {
+	var o *Object
+	if n := o.Dedup(); n != 0 {
+		t.Fatalf("unexpected number of removed entries for nil object; got %d; want %d", n, 0)
+	}
+
+	var p Parser
+	v, err := p.Parse(`{"a":1,"b":2,"a":3,"b":4,"c":5,"a":6}`)
+	if err != nil {
+		t.Fatalf("unexpected error during parse: %s", err)
+	}
+	o = v.GetObject()
+	if n := o.Dedup(); n != 3 {
+		t.Fatalf("unexpected number of removed entries; got %d; want %d", n, 3)
+	}
+	str := o.String()
+	strExpected := `{"a":1,"b":2,"c":5}`
+	if str != strExpected {
+		t.Fatalf("unexpected string representation for o: got %q; want %q", str, strExpected)
+	}
+
+	// Repeated call is no-op
+	if n := o.Dedup(); n != 0 {
+		t.Fatalf("unexpected number of removed entries; got %d; want %d", n, 0)
+	}
+}
+
+func TestObjectMoveToFrontSwap(t *testing.T) {
+	var o *Object
+	if o.MoveToFront("x") {
+		t.Fatalf("MoveToFront must return false on nil object")
+	}
+	o.Swap(0, 1)
+
+	var p Parser
+	v, err := p.Parse(`{"a":1,"b\"":2,"c\nd":3}`)
+	if err != nil {
+		t.Fatalf("unexpected error during parse: %s", err)
+	}
+	o = v.GetObject()
+
+	if !o.MoveToFront("c\nd") {
+		t.Fatalf("cannot move existing key to front")
+	}
+	str := o.String()
+	strExpected := `{"c\nd":3,"a":1,"b\"":2}`
+	if str != strExpected {
+		t.Fatalf("unexpected string representation for o: got %q; want %q", str, strExpected)
+	}
+	if o.MoveToFront("missing") {
+		t.Fatalf("MoveToFront must return false for missing key")
+	}
+
+	o.Swap(1, 2)
+	str = o.String()
+	strExpected = `{"c\nd":3,"b\"":2,"a":1}`
+	if str != strExpected {
+		t.Fatalf("unexpected string representation for o: got %q; want %q", str, strExpected)
+	}
+
+	// Invalid indexes
+	o.Swap(-1, 0)
+	o.Swap(0, 3)
+	if s := o.String(); s != str {
+		t.Fatalf("unexpected string representation for o: got %q; want %q", s, str)
+	}
+	if n := o.Get("b\"").GetInt(); n != 2 {
+		t.Fatalf("unexpected value for escaped key; got %d; want %d", n, 2)
+	}
+}
+
+func TestValueSortArray(t *testing.T) {
+	var v *Value
+	v.SortArray(func(a, b *Value) bool { return false })
+
+	var p Parser
+	v, err := p.Parse(`[{"n":3,"id":"a"},{"n":1,"id":"b"},{"n":2,"id":"c"},{"n":1,"id":"d"}]`)
+	if err != nil {
+		t.Fatalf("unexpected error during parse: %s", err)
+	}
+	v.SortArray(func(a, b *Value) bool {
+		return a.GetInt("n") < b.GetInt("n")
+	})
+	str := v.String()
+	strExpected := `[{"n":1,"id":"b"},{"n":1,"id":"d"},{"n":2,"id":"c"},{"n":3,"id":"a"}]`
+	if str != strExpected {
+		t.Fatalf("unexpected string representation for v: got %q; want %q", str, strExpected)
+	}
+
+	// Non-array values are left unchanged
+	o := v.Get("0")
+	o.SortArray(func(a, b *Value) bool { return true })
+	if s := o.String(); s != `{"n":1,"id":"b"}` {
+		t.Fatalf("unexpected string representation for o: got %q; want %q", s, `{"n":1,"id":"b"}`)
+	}
+}
+
+func TestObjectSetEscapedKeys(t *testing.T) {
+	keys := []string{"weird\"key\n", "back\\slash", "tab\tkey", "\x00", "A", "plain"}
+	f := func(s string) {
+		t.Helper()
+		for _, key := range keys {
+			// Set the key without any prior Get, so object keys remain raw
+			// until the Set call.
+			var p Parser
+			v, err := p.Parse(s)
+			if err != nil {
+				t.Fatalf("cannot parse %q: %s", s, err)
+			}
+			o := v.GetObject()
+			o.Set(key, MustParse(`"value"`))
+
+			data := o.MarshalTo(nil)
+			if err := ValidateBytes(data); err != nil {
+				t.Fatalf("invalid JSON after setting %q in %q: %s\n%s", key, s, err, data)
+			}
+			vv, err := ParseBytes(data)
+			if err != nil {
+				t.Fatalf("cannot parse %s: %s", data, err)
+			}
+			if sb := vv.GetStringBytes(key); string(sb) != "value" {
+				t.Fatalf("unexpected value for key %q in %s; got %q; want %q", key, data, sb, "value")
+			}
+
+			// Original keys must be preserved.
+			for _, kv := range MustParse(s).GetObject().kvs {
+				k := unescapeStringBestEffort(string(append([]byte{}, kv.k...)))
+				if k != key && !vv.Exists(k) {
+					t.Fatalf("missing original key %q in %s", k, data)
+				}
+			}
+
+			// Delete the key without any prior Get.
+			v, err = p.Parse(string(data))
+			if err != nil {
+				t.Fatalf("cannot parse %s: %s", data, err)
+			}
+			v.Del(key)
+			data = v.MarshalTo(nil)
+			if err := ValidateBytes(data); err != nil {
+				t.Fatalf("invalid JSON after deleting %q: %s\n%s", key, err, data)
+			}
+			if MustParse(string(data)).Exists(key) {
+				t.Fatalf("key %q must be deleted from %s", key, data)
+			}
+		}
+	}
+	f(`{"a":1}`)
+	f(`{}`)
+	f(`{"a\"b":1,"c\nd":2}`)
+	f(`{"\u0041":1,"x\\y":2}`)
+}
+
+func TestObjectRename(t *testing.T) {
+	f := func(s, oldKey, newKey string, okExpected bool, resultExpected string) {
+		t.Helper()
+		o := MustParse(s).GetObject()
+		ok := o.Rename(oldKey, newKey)
+		if ok != okExpected {
+			t.Fatalf("unexpected result for renaming %q to %q in %s; got %v; want %v", oldKey, newKey, s, ok, okExpected)
+		}
+		result := o.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected object after renaming %q to %q in %s;\ngot\n%s\nwant\n%s", oldKey, newKey, s, result, resultExpected)
+		}
+		if err := Validate(result); err != nil {
+			t.Fatalf("invalid JSON after renaming: %s", err)
+		}
+	}
+
+	// The renamed entry must keep its position.
+	f(`{"a":1,"b":2,"c":3}`, "a", "x", true, `{"x":1,"b":2,"c":3}`)
+	f(`{"a":1,"b":2,"c":3}`, "b", "x", true, `{"a":1,"x":2,"c":3}`)
+	f(`{"a":1,"b":2,"c":3}`, "c", "x", true, `{"a":1,"b":2,"x":3}`)
+
+	// Missing key
+	f(`{"a":1}`, "b", "x", false, `{"a":1}`)
+	f(`{}`, "a", "x", false, `{}`)
+
+	// Same key
+	f(`{"a":1,"b":2}`, "a", "a", true, `{"a":1,"b":2}`)
+
+	// Other entries with the new key must be removed.
+	f(`{"a":1,"b":2,"c":3}`, "c", "a", true, `{"b":2,"a":3}`)
+	f(`{"a":1,"b":2,"a":3}`, "b", "a", true, `{"a":2}`)
+
+	// Only the first entry with the old key is renamed.
+	f(`{"a":1,"a":2}`, "a", "b", true, `{"b":1,"a":2}`)
+
+	// Escaped keys
+	f(`{"f\u006fo":1,"bar":2}`, "foo", "x\"y", true, `{"x\"y":1,"bar":2}`)
+	f(`{"a\nb":1}`, "a\nb", "c", true, `{"c":1}`)
+
+	// nil object
+	var o *Object
+	if o.Rename("a", "b") {
+		t.Fatalf("expecting false for nil object")
+	}
+}
+
+func TestObjectUpsert(t *testing.T) {
+	f := func(s, key string, valueNew *Value, existingExpected, resultExpected string) {
+		t.Helper()
+		o := MustParse(s).GetObject()
+		calls := 0
+		o.Upsert(key, func(existing *Value) *Value {
+			calls++
+			existingStr := "<nil>"
+			if existing != nil {
+				existingStr = existing.String()
+			}
+			if existingStr != existingExpected {
+				t.Fatalf("unexpected existing value for %q in %s; got %s; want %s", key, s, existingStr, existingExpected)
+			}
+			return valueNew
+		})
+		if calls != 1 {
+			t.Fatalf("unexpected number of callback calls; got %d; want 1", calls)
+		}
+		result := o.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected object after upserting %q in %s;\ngot\n%s\nwant\n%s", key, s, result, resultExpected)
+		}
+	}
+
+	v := MustParse(`[1]`)
+
+	// Update keeps the position.
+	f(`{"a":1,"b":2,"c":3}`, "b", v, "2", `{"a":1,"b":[1],"c":3}`)
+	f(`{"a":1,"b":2,"c":3}`, "a", v, "1", `{"a":[1],"b":2,"c":3}`)
+
+	// Insert adds the entry to the end.
+	f(`{"a":1}`, "x", v, "<nil>", `{"a":1,"x":[1]}`)
+	f(`{}`, "x", v, "<nil>", `{"x":[1]}`)
+
+	// nil is treated as null.
+	f(`{"a":1}`, "a", nil, "1", `{"a":null}`)
+	f(`{"a":1}`, "b", nil, "<nil>", `{"a":1,"b":null}`)
+
+	// Duplicates are removed.
+	f(`{"a":1,"b":2,"a":3}`, "a", v, "1", `{"a":[1],"b":2}`)
+
+	// Escaped keys
+	f(`{"f\u006fo":1}`, "foo", v, "1", `{"foo":[1]}`)
+	f(`{"a":1}`, "x\ny", v, "<nil>", `{"a":1,"x\ny":[1]}`)
+
+	// Increment counter
+	var a Arena
+	o := MustParse(`{"n":1}`).GetObject()
+	for i := 0; i < 3; i++ {
+		o.Upsert("n", func(existing *Value) *Value {
+			return a.NewNumberInt(existing.GetInt() + 1)
+		})
+	}
+	if s := o.String(); s != `{"n":4}` {
+		t.Fatalf("unexpected object; got %s; want %s", s, `{"n":4}`)
+	}
+
+	// nil object
+	var oNil *Object
+	oNil.Upsert("a", func(existing *Value) *Value {
+		t.Fatalf("callback mustn't be called for nil object")
+		return nil
+	})
+}
+
+func TestObjectIndexOf(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"a":1,"b\nc":2,"d":3,"a":4}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	o := v.GetObject()
+	f := func(key string, nExpected int) {
+		t.Helper()
+		if n := o.IndexOf(key); n != nExpected {
+			t.Fatalf("unexpected index for %q; got %d; want %d", key, n, nExpected)
+		}
+	}
+	f("a", 0)
+	f("b\nc", 1)
+	f("d", 2)
+	f("missing", -1)
+	f(`b\nc`, -1)
+
+	var oNil *Object
+	if n := oNil.IndexOf("a"); n != -1 {
+		t.Fatalf("unexpected index for nil object; got %d; want -1", n)
+	}
+}
+
+func TestObjectSetAt(t *testing.T) {
+	var p Parser
+	s := `{"a":1,"b\nc":[2],"d":{"x":"y"},"e":null}`
+
+	// Del+SetAt round trip must preserve the original order.
+	for _, key := range []string{"a", "b\nc", "d", "e"} {
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		o := v.GetObject()
+		i := o.IndexOf(key)
+		value := o.Get(key)
+		o.Del(key)
+		if err := o.SetAt(i, key, value); err != nil {
+			t.Fatalf("unexpected error for %q: %s", key, err)
+		}
+		if got := string(v.MarshalTo(nil)); got != s {
+			t.Fatalf("unexpected object after Del+SetAt for %q\ngot\n%s\nwant\n%s", key, got, s)
+		}
+	}
+
+	f := func(index int, key, value, resultExpected string) {
+		t.Helper()
+		v, err := p.Parse(`{"a":1,"b":2,"a":3}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		o := v.GetObject()
+		if err := o.SetAt(index, key, MustParse(value)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got := o.String(); got != resultExpected {
+			t.Fatalf("unexpected result for SetAt(%d, %q, %s); got %s; want %s", index, key, value, got, resultExpected)
+		}
+		if n := o.IndexOf(key); n != index {
+			t.Fatalf("unexpected IndexOf(%q) after SetAt; got %d; want %d", key, n, index)
+		}
+	}
+	f(0, "x", `"new"`, `{"x":"new","a":1,"b":2,"a":3}`)
+	f(2, "x", `"new"`, `{"a":1,"b":2,"x":"new","a":3}`)
+	f(3, "x", `"new"`, `{"a":1,"b":2,"a":3,"x":"new"}`)
+	f(1, "a", `"new"`, `{"b":2,"a":"new"}`)
+	f(0, "b", `"new"`, `{"b":"new","a":1,"a":3}`)
+	f(0, "q\"w", `"new"`, `{"q\"w":"new","a":1,"b":2,"a":3}`)
+
+	// nil value is treated as null.
+	o := MustParse(`{"a":1}`).GetObject()
+	if err := o.SetAt(0, "b", nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := o.String(); got != `{"b":null,"a":1}` {
+		t.Fatalf("unexpected result; got %s; want %s", got, `{"b":null,"a":1}`)
+	}
+
+	// Out of range index mustn't modify the object.
+	for _, index := range []int{-1, 2, 10} {
+		o := MustParse(`{"a":1,"b":2,"a":3}`).GetObject()
+		if err := o.SetAt(index, "a", MustParse(`4`)); err == nil {
+			t.Fatalf("expecting non-nil error for index %d", index)
+		}
+		if got := o.String(); got != `{"a":1,"b":2,"a":3}` {
+			t.Fatalf("the object mustn't be modified on error; got %s", got)
+		}
+	}
+	var oNil *Object
+	if err := oNil.SetAt(0, "a", nil); err == nil {
+		t.Fatalf("expecting non-nil error for nil object")
+	}
+}
+
+func TestObjectReplace(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"a":1,"b\nc":2,"d":3,"a":4}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	o := v.GetObject()
+
+	if !o.Replace("b\nc", MustParse(`"x"`)) {
+		t.Fatalf("expecting true for existing key")
+	}
+	if got := o.String(); got != `{"a":1,"b\nc":"x","d":3,"a":4}` {
+		t.Fatalf("unexpected object after Replace; got %s", got)
+	}
+
+	// Duplicates are removed.
+	if !o.Replace("a", nil) {
+		t.Fatalf("expecting true for existing key")
+	}
+	if got := o.String(); got != `{"a":null,"b\nc":"x","d":3}` {
+		t.Fatalf("unexpected object after Replace; got %s", got)
+	}
+
+	// Missing keys aren't added.
+	if o.Replace("missing", MustParse(`1`)) {
+		t.Fatalf("expecting false for missing key")
+	}
+	if o.Replace(`b\nc`, MustParse(`1`)) {
+		t.Fatalf("expecting false for escaped form of the key")
+	}
+	if got := o.String(); got != `{"a":null,"b\nc":"x","d":3}` {
+		t.Fatalf("the object mustn't be modified for missing key; got %s", got)
+	}
+
+	var oNil *Object
+	if oNil.Replace("a", nil) {
+		t.Fatalf("expecting false for nil object")
+	}
+}
+
+func TestObjectDelPrefix(t *testing.T) {
+	var o *Object
+	if n := o.DelPrefix("attr."); n != 0 {
+		t.Fatalf("unexpected number of deleted entries for nil object; got %d; want 0", n)
+	}
+
+	f := func(s, prefix string, nExpected int, resultExpected string) {
+		t.Helper()
+		v, err := Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %s", s, err)
+		}
+		o := v.GetObject()
+		n := o.DelPrefix(prefix)
+		if n != nExpected {
+			t.Fatalf("unexpected number of deleted entries for prefix %q; got %d; want %d", prefix, n, nExpected)
+		}
+		result := v.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected result for prefix %q;\ngot\n%s\nwant\n%s", prefix, result, resultExpected)
+		}
+	}
+	f(`{}`, "attr.", 0, `{}`)
+	f(`{"a":1,"b":2}`, "attr.", 0, `{"a":1,"b":2}`)
+	f(`{"a":1,"b":2}`, "", 2, `{}`)
+	f(`{"attr.x":1,"id":2,"\u0061ttr.y":3,"meta.attr.z":4,"attr.\u0078":5,"ATTR.w":6}`, "attr.", 3, `{"id":2,"meta.attr.z":4,"ATTR.w":6}`)
+	f(`{"attr.x":1,"id":2,"\u0061ttr.y":3,"meta.z":4}`, "meta.", 1, `{"attr.x":1,"id":2,"attr.y":3}`)
+	f(`{"attr":1,"attr.":2,"attr.x":3}`, "attr.", 2, `{"attr":1}`)
+	f(`{"x\ny":1,"x\nz":2,"xy":3}`, "x\n", 2, `{"xy":3}`)
+
+	// Set must work after DelPrefix.
+	v := MustParse(`{"attr.x":1,"id":2,"attr.y":3}`)
+	o = v.GetObject()
+	o.DelPrefix("attr.")
+	o.Set("attr.z", MustParse(`4`))
+	o.Set("id", MustParse(`5`))
+	if s := v.String(); s != `{"id":5,"attr.z":4}` {
+		t.Fatalf("unexpected result; got %s; want %s", s, `{"id":5,"attr.z":4}`)
+	}
+}
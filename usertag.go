@@ -0,0 +1,40 @@
+package fastjson
+
+// SetUserTag attaches the given user-defined tag to v.
+//
+// Tags allow marking values in one pass over the parsed JSON and acting
+// on the marks in subsequent passes without maintaining maps keyed
+// by *Value. Tags carry no JSON semantics: they aren't marshaled,
+// aren't compared by Equal and aren't copied by Clone.
+//
+// Values have zero tag by default. Tags are cleared when the memory
+// occupied by v is re-used, i.e. on the next Parse* call on the Parser
+// returned v or on Reset call on the Arena v belongs to.
+//
+// true, false and null values returned by Parser and Arena are shared
+// between all the parsed documents, so tags cannot be set on them.
+// SetUserTag is no-op for such values and UserTag always returns 0 for them.
+//
+// The tag fits the padding in Value struct, so it doesn't increase
+// memory usage.
+func (v *Value) SetUserTag(tag uint32) {
+	if v == nil || isSharedValue(v) {
+		return
+	}
+	v.userTag = tag
+}
+
+// UserTag returns the tag set on v via SetUserTag.
+//
+// 0 is returned if the tag isn't set.
+func (v *Value) UserTag() uint32 {
+	if v == nil {
+		return 0
+	}
+	return v.userTag
+}
+
+// isSharedValue returns true if v is shared between all the parsed documents.
+func isSharedValue(v *Value) bool {
+	return v == valueTrue || v == valueFalse || v == valueNull
+}
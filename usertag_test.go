@@ -0,0 +1,97 @@
+package fastjson
+
+import (
+	"testing"
+)
+
+func TestValueUserTag(t *testing.T) {
+	const s = `{"user":{"name":"foo","password":"secret"},"items":[1,"x",{"token":"abc"}],"ok":true}`
+
+	var p Parser
+	v, err := p.Parse(s)
+	if err != nil {
+		t.Fatalf("cannot parse JSON: %s", err)
+	}
+	if tag := v.UserTag(); tag != 0 {
+		t.Fatalf("unexpected default tag; got %d; want 0", tag)
+	}
+
+	// Mark values in the first pass.
+	v.Get("user", "password").SetUserTag(1)
+	v.Get("items", "2").SetUserTag(2)
+	v.Get("items", "1").SetUserTag(3)
+
+	// Tags must be preserved through navigation within the same parse.
+	if tag := v.Get("user", "password").UserTag(); tag != 1 {
+		t.Fatalf("unexpected tag for password; got %d; want 1", tag)
+	}
+	if tag := v.GetArray("items")[2].UserTag(); tag != 2 {
+		t.Fatalf("unexpected tag for items[2]; got %d; want 2", tag)
+	}
+	tags := make(map[string]uint32)
+	v.GetObject("user").Visit(func(key []byte, vv *Value) {
+		tags[string(key)] = vv.UserTag()
+	})
+	if tags["name"] != 0 || tags["password"] != 1 {
+		t.Fatalf("unexpected tags obtained via Visit: %v", tags)
+	}
+	if tag := v.Get("items", "1").UserTag(); tag != 3 {
+		t.Fatalf("unexpected tag for items[1]; got %d; want 3", tag)
+	}
+
+	// Tags mustn't be marshaled.
+	if vs := v.String(); vs != s {
+		t.Fatalf("unexpected marshaled value; got\n%s\nwant\n%s", vs, s)
+	}
+
+	// Shared values cannot be tagged.
+	vTrue := v.Get("ok")
+	vTrue.SetUserTag(4)
+	if tag := vTrue.UserTag(); tag != 0 {
+		t.Fatalf("unexpected tag for shared true value; got %d; want 0", tag)
+	}
+	vv, err := Parse(`true`)
+	if err != nil {
+		t.Fatalf("cannot parse JSON: %s", err)
+	}
+	if tag := vv.UserTag(); tag != 0 {
+		t.Fatalf("unexpected tag for true value from another parse; got %d; want 0", tag)
+	}
+
+	// nil values are supported.
+	var vNil *Value
+	vNil.SetUserTag(5)
+	if tag := vNil.UserTag(); tag != 0 {
+		t.Fatalf("unexpected tag for nil value; got %d; want 0", tag)
+	}
+
+	// Tags must be cleared on the next Parse call.
+	v, err = p.Parse(s)
+	if err != nil {
+		t.Fatalf("cannot parse JSON: %s", err)
+	}
+	for _, keys := range [][]string{{"user", "password"}, {"items", "2"}, {"items", "1"}} {
+		if tag := v.Get(keys...).UserTag(); tag != 0 {
+			t.Fatalf("tag for %q must be cleared after Parse; got %d", keys, tag)
+		}
+	}
+}
+
+func TestArenaUserTag(t *testing.T) {
+	var a Arena
+	for i := 0; i < 3; i++ {
+		o := a.NewObject()
+		o.Set("foo", a.NewString("bar"))
+		o.Set("n", a.NewNumberInt(123))
+		for _, vv := range []*Value{o, o.Get("foo"), o.Get("n")} {
+			if tag := vv.UserTag(); tag != 0 {
+				t.Fatalf("tag must be cleared after Reset; got %d", tag)
+			}
+			vv.SetUserTag(uint32(i + 1))
+		}
+		if tag := o.Get("foo").UserTag(); tag != uint32(i+1) {
+			t.Fatalf("unexpected tag; got %d; want %d", tag, i+1)
+		}
+		a.Reset()
+	}
+}
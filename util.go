@@ -1,6 +1,7 @@
 package fastjson
 
 import (
+	"encoding/binary"
 	"reflect"
 	"unsafe"
 )
@@ -9,6 +10,11 @@ func b2s(b []byte) string {
 	return *(*string)(unsafe.Pointer(&b))
 }
 
+// stringDataPtr returns the address of the first byte of s.
+func stringDataPtr(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
 func s2b(s string) (b []byte) {
 	strh := (*reflect.StringHeader)(unsafe.Pointer(&s))
 	sh := (*reflect.SliceHeader)(unsafe.Pointer(&b))
@@ -28,3 +34,73 @@ func startEndString(s string) string {
 	end := s[len(s)-40:]
 	return start + "..." + end
 }
+
+// appendUint appends decimal digits for n to dst and returns the result.
+//
+// It is equivalent to strconv.AppendUint(dst, n, 10).
+func appendUint(dst []byte, n uint64) []byte {
+	var buf [20]byte
+	i := len(buf)
+	for n >= 10 {
+		i--
+		q := n / 10
+		buf[i] = byte('0' + n - q*10)
+		n = q
+	}
+	i--
+	buf[i] = byte('0' + n)
+	return append(dst, buf[i:]...)
+}
+
+// appendInt appends decimal digits for n to dst and returns the result.
+//
+// It is equivalent to strconv.AppendInt(dst, n, 10).
+func appendInt(dst []byte, n int64) []byte {
+	if n >= 0 {
+		return appendUint(dst, uint64(n))
+	}
+	dst = append(dst, '-')
+	// The conversion works for math.MinInt64 too.
+	return appendUint(dst, uint64(-n))
+}
+
+// The following functions process 8 bytes at once
+// (SWAR - SIMD within a register).
+
+const (
+	swarLowBits  = 0x0101010101010101
+	swarHighBits = 0x8080808080808080
+)
+
+// loadUint64 returns the first 8 bytes of s as little-endian uint64.
+//
+// The caller must ensure len(s) >= 8.
+func loadUint64(s string) uint64 {
+	return binary.LittleEndian.Uint64(s2b(s[:8]))
+}
+
+// eqMask returns a mask with the high bit set in every byte of x,
+// which equals to ch.
+func eqMask(x uint64, ch byte) uint64 {
+	return zeroMask(x ^ (swarLowBits * uint64(ch)))
+}
+
+// zeroMask returns a mask with the high bit set in every zero byte of x.
+//
+// Unlike the well-known haszero trick, the mask is exact, since it doesn't
+// propagate borrows between bytes.
+func zeroMask(x uint64) uint64 {
+	const lowBits7 = swarLowBits * 0x7f
+	return ^(((x & lowBits7) + lowBits7) | x) & swarHighBits
+}
+
+// wsMask returns a mask with the high bit set in every JSON whitespace byte of x.
+func wsMask(x uint64) uint64 {
+	return eqMask(x, 0x20) | eqMask(x, 0x0A) | eqMask(x, 0x09) | eqMask(x, 0x0D)
+}
+
+// specialCharsMask returns a mask with the high bit set in every byte of x,
+// which must be escaped in JSON string: '"', '\\' and control chars.
+func specialCharsMask(x uint64) uint64 {
+	return eqMask(x, '"') | eqMask(x, '\\') | zeroMask(x&(swarLowBits*0xe0))
+}
@@ -1,7 +1,10 @@
 package fastjson
 
 import (
+	"math"
+	"strconv"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestStartEndString(t *testing.T) {
@@ -28,3 +31,130 @@ func TestStartEndString(t *testing.T) {
 	f(getString(maxStartEndStringLen+1), "abcdefghijklmnopqrstuvwxyzabcdefghijklmn...pqrstuvwxyzabcdefghijklmnopqrstuvwxyzabc")
 	f(getString(100*maxStartEndStringLen), "abcdefghijklmnopqrstuvwxyzabcdefghijklmn...efghijklmnopqrstuvwxyzabcdefghijklmnopqr")
 }
+
+func TestSkipWSSWAR(t *testing.T) {
+	isWS := func(c byte) bool {
+		return c == 0x20 || c == 0x0A || c == 0x09 || c == 0x0D
+	}
+	skipWSRef := func(s string) string {
+		for i := 0; i < len(s); i++ {
+			if !isWS(s[i]) {
+				return s[i:]
+			}
+		}
+		return ""
+	}
+	f := func(s string) {
+		t.Helper()
+		result := skipWSSlow(s)
+		resultExpected := skipWSRef(s)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for skipWSSlow(%q); got %q; want %q", s, result, resultExpected)
+		}
+	}
+
+	// Put every byte value at every position after whitespace prefix.
+	const ws = " \n\t\r \n\t\r \n\t\r \n\t\r \n\t\r \n\t\r"
+	for n := 0; n <= len(ws); n++ {
+		f(ws[:n])
+		for c := 0; c < 256; c++ {
+			s := ws[:n] + string([]byte{byte(c)}) + "x"
+			f(s)
+			f(s[:n+1])
+		}
+	}
+}
+
+func TestHasSpecialCharsSWAR(t *testing.T) {
+	hasSpecialCharsRef := func(s string) bool {
+		for i := 0; i < len(s); i++ {
+			if s[i] == '"' || s[i] == '\\' || s[i] < 0x20 {
+				return true
+			}
+		}
+		return false
+	}
+	f := func(s string) {
+		t.Helper()
+		result := hasSpecialChars(s)
+		resultExpected := hasSpecialCharsRef(s)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for hasSpecialChars(%q); got %v; want %v", s, result, resultExpected)
+		}
+	}
+
+	// Put every byte value at every position in strings with various lengths.
+	const prefix = "abcdefghijklmnop"
+	for n := 0; n <= len(prefix); n++ {
+		f(prefix[:n])
+		for c := 0; c < 256; c++ {
+			s := prefix[:n] + string([]byte{byte(c)}) + "\xff\x7f z"
+			f(s)
+			f(s[:n+1])
+		}
+	}
+}
+
+func TestNeedsEscapingSWAR(t *testing.T) {
+	needsEscapingRef := func(s string) bool {
+		for i := 0; i < len(s); i++ {
+			if s[i] == '"' || s[i] == '\\' || s[i] < 0x20 {
+				return true
+			}
+		}
+		return !utf8.ValidString(s)
+	}
+	f := func(s string) {
+		t.Helper()
+		result := needsEscaping(s)
+		resultExpected := needsEscapingRef(s)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for needsEscaping(%q); got %v; want %v", s, result, resultExpected)
+		}
+	}
+
+	// Put every byte value and multi-byte runes at every position
+	// in strings with various lengths.
+	const prefix = "abcdefghijklmnop"
+	for n := 0; n <= len(prefix); n++ {
+		f(prefix[:n])
+		for c := 0; c < 256; c++ {
+			s := prefix[:n] + string([]byte{byte(c)}) + "\xff\x7f z"
+			f(s)
+			f(s[:n+1])
+		}
+		for _, tail := range []string{"ж", "жx\"", "€abcdefgh", "\U0001F600", "ж\xe2\x82", "abcdefghж\n"} {
+			f(prefix[:n] + tail)
+		}
+	}
+}
+
+func TestAppendInt(t *testing.T) {
+	fUint := func(n uint64) {
+		t.Helper()
+		result := string(appendUint([]byte("foo"), n))
+		resultExpected := string(strconv.AppendUint([]byte("foo"), n, 10))
+		if result != resultExpected {
+			t.Fatalf("unexpected result for appendUint(%d); got %q; want %q", n, result, resultExpected)
+		}
+	}
+	fInt := func(n int64) {
+		t.Helper()
+		result := string(appendInt(nil, n))
+		resultExpected := strconv.FormatInt(n, 10)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for appendInt(%d); got %q; want %q", n, result, resultExpected)
+		}
+	}
+	for n := uint64(1); n != 0 && n <= math.MaxUint64/3; n *= 3 {
+		fUint(n - 1)
+		fUint(n)
+		fUint(n + 1)
+		fInt(int64(n))
+		fInt(-int64(n))
+	}
+	fUint(math.MaxUint64)
+	fInt(0)
+	fInt(math.MaxInt64)
+	fInt(math.MinInt64)
+}
@@ -7,39 +7,530 @@ import (
 )
 
 // Validate validates JSON s.
+//
+// A single UTF-8 byte order mark at the beginning of s is skipped.
+//
+// JSON with nesting depth exceeding MaxDepth is rejected, like Parser does.
+// Use ValidateWithDepth for validating deeper JSON.
+//
+// The returned error is *ParseError.
 func Validate(s string) error {
-	s = skipWS(s)
+	return ValidateWithDepth(s, MaxDepth)
+}
+
+// ValidateBytes validates JSON b.
+//
+// The returned error is *ParseError.
+func ValidateBytes(b []byte) error {
+	return Validate(b2s(b))
+}
 
-	tail, err := validateValue(s)
+// ValidateWithDepth validates JSON s with nesting depth up to maxDepth.
+//
+// MaxDepth is used if maxDepth <= 0.
+//
+// The returned error is *ParseError.
+func ValidateWithDepth(s string, maxDepth int) error {
+	if maxDepth <= 0 {
+		maxDepth = MaxDepth
+	}
+	sOrig := s
+	s = skipWS(skipBOM(s))
+
+	tail, err := validateValue(s, 0, &validateLimits{
+		maxDepth: maxDepth,
+	})
 	if err != nil {
-		return fmt.Errorf("cannot parse JSON: %s; unparsed tail: %q", err, startEndString(tail))
+		return newParseError(sOrig, tail, fmt.Sprintf("cannot parse JSON: %s; unparsed tail: %q", err, startEndString(tail)))
 	}
 	tail = skipWS(tail)
 	if len(tail) > 0 {
-		return fmt.Errorf("unexpected tail: %q", startEndString(tail))
+		return newParseError(sOrig, tail, fmt.Sprintf("unexpected tail: %q", startEndString(tail)))
 	}
 	return nil
 }
 
-// ValidateBytes validates JSON b.
-func ValidateBytes(b []byte) error {
-	return Validate(b2s(b))
+// ValidateBytesWithDepth validates JSON b with nesting depth up to maxDepth.
+//
+// See ValidateWithDepth for details.
+func ValidateBytesWithDepth(b []byte, maxDepth int) error {
+	return ValidateWithDepth(b2s(b), maxDepth)
+}
+
+// ValidateWithLimits validates JSON s with limits on string lengths.
+//
+// Strings longer than maxStringLen bytes and object keys longer than
+// maxKeyLen bytes are rejected. The lengths are measured in bytes between
+// the quotes before unescaping. Zero or negative limit means no limit.
+//
+// The returned error is *ParseError pointing to the beginning of the too
+// long string or key. See ValidateWithOptions for limiting number lengths.
+func ValidateWithLimits(s string, maxStringLen, maxKeyLen int) error {
+	return ValidateWithOptions(s, ValidateOptions{
+		MaxStringLen: maxStringLen,
+		MaxKeyLen:    maxKeyLen,
+	})
+}
+
+// ValidateBytesWithLimits validates JSON b with limits on string lengths.
+//
+// See ValidateWithLimits for details.
+func ValidateBytesWithLimits(b []byte, maxStringLen, maxKeyLen int) error {
+	return ValidateWithLimits(b2s(b), maxStringLen, maxKeyLen)
+}
+
+// ValidateOptions contains limits for ValidateWithOptions.
+//
+// Zero or negative limit means no limit, so the zero ValidateOptions
+// results in the same validation as Validate.
+type ValidateOptions struct {
+	// MaxStringLen is the maximum length of string values in bytes
+	// between the quotes before unescaping.
+	MaxStringLen int
+
+	// MaxKeyLen is the maximum length of object keys in bytes
+	// between the quotes before unescaping.
+	MaxKeyLen int
+
+	// MaxNumberLen is the maximum length of numbers in bytes.
+	//
+	// Huge numbers are valid JSON, but they are expensive to process.
+	// See Parser.MaxNumberLen.
+	MaxNumberLen int
+}
+
+// ValidateWithOptions validates JSON s with the limits from opts.
+//
+// The returned error is *ParseError pointing to the beginning of the too
+// long string, key or number.
+func ValidateWithOptions(s string, opts ValidateOptions) error {
+	sOrig := s
+	s = skipWS(skipBOM(s))
+
+	tail, err := validateValue(s, 0, &validateLimits{
+		maxDepth:     MaxDepth,
+		maxStringLen: opts.MaxStringLen,
+		maxKeyLen:    opts.MaxKeyLen,
+		maxNumberLen: opts.MaxNumberLen,
+	})
+	if err != nil {
+		return newParseError(sOrig, tail, fmt.Sprintf("cannot parse JSON: %s; unparsed tail: %q", err, startEndString(tail)))
+	}
+	tail = skipWS(tail)
+	if len(tail) > 0 {
+		return newParseError(sOrig, tail, fmt.Sprintf("unexpected tail: %q", startEndString(tail)))
+	}
+	return nil
+}
+
+// ValidateBytesWithOptions validates JSON b with the limits from opts.
+//
+// See ValidateWithOptions for details.
+func ValidateBytesWithOptions(b []byte, opts ValidateOptions) error {
+	return ValidateWithOptions(b2s(b), opts)
+}
+
+// ValidatePrefix validates a single JSON value at the beginning of s.
+//
+// Leading whitespace is skipped. The data after the value isn't validated.
+// The returned tailOffset points to the first byte after the value in s.
+//
+// The returned error is *ParseError.
+func ValidatePrefix(s string) (int, error) {
+	sOrig := s
+	s = skipWS(skipBOM(s))
+
+	tail, err := validateValue(s, 0, &defaultValidateLimits)
+	if err != nil {
+		return 0, newParseError(sOrig, tail, fmt.Sprintf("cannot parse JSON: %s; unparsed tail: %q", err, startEndString(tail)))
+	}
+	return len(sOrig) - len(tail), nil
+}
+
+// ValidateBytesPrefix validates a single JSON value at the beginning of b.
+//
+// See ValidatePrefix for details.
+func ValidateBytesPrefix(b []byte) (int, error) {
+	return ValidatePrefix(b2s(b))
+}
+
+// ValidateStream validates a stream of zero or more JSON values in s.
+//
+// Values may be delimited by whitespace, like in Scanner. Empty s
+// is valid, since it contains zero values. Unlike Scanner, ValidateStream
+// doesn't build parsed values, so it doesn't allocate memory. Values are
+// validated as strictly as Validate does, while Scanner accepts some
+// invalid JSON such as control chars inside strings.
+//
+// The returned error is *ParseError with the offset in s. Its message
+// contains zero-based index of the first invalid value.
+func ValidateStream(s string) error {
+	sOrig := s
+	s = skipWS(skipBOM(s))
+	for i := 0; len(s) > 0; i++ {
+		tail, err := validateValue(s, 0, &defaultValidateLimits)
+		if err != nil {
+			pe := newParseError(s, tail, fmt.Sprintf("cannot parse JSON value #%d: %s; unparsed tail: %q", i, err, startEndString(tail)))
+			// Make the position relative to sOrig.
+			pe.shift(sOrig[:len(sOrig)-len(s)])
+			return pe
+		}
+		s = skipWS(tail)
+	}
+	return nil
+}
+
+// ValidateStreamBytes validates a stream of zero or more JSON values in b.
+//
+// See ValidateStream for details.
+func ValidateStreamBytes(b []byte) error {
+	return ValidateStream(b2s(b))
+}
+
+// ValidateAll validates JSON s and returns up to maxErrors errors found in s.
+//
+// Unlike Validate, ValidateAll doesn't stop on the first error. It skips
+// the invalid part of s until the next ',', '}' or ']' at the current
+// nesting level and continues validation from there, so independent errors
+// may be fixed at once. Errors found after the first one may be caused by
+// imperfect recovery. Validation stops if recovery is impossible,
+// e.g. on unterminated string at the end of s.
+//
+// All the found errors are returned if maxErrors <= 0.
+// nil is returned for valid JSON.
+//
+// The returned errors are *ParseError in the order of their offsets.
+func ValidateAll(s string, maxErrors int) []error {
+	ev := &errorsValidator{
+		s:         s,
+		maxErrors: maxErrors,
+	}
+	tail, ok := ev.validateValue(skipWS(skipBOM(s)))
+	if !ok {
+		tail, ok = ev.resync(tail)
+		if !ok {
+			return ev.errs
+		}
+	}
+	tail = skipWS(tail)
+	if len(tail) > 0 {
+		ev.addError(tail, "end of JSON", false, fmt.Sprintf("unexpected tail: %q", startEndString(tail)))
+	}
+	return ev.errs
+}
+
+// ValidateAllBytes validates JSON b and returns up to maxErrors errors found in b.
+//
+// See ValidateAll for details.
+func ValidateAllBytes(b []byte, maxErrors int) []error {
+	return ValidateAll(b2s(b), maxErrors)
+}
+
+// errorsValidator validates JSON with recovery after errors.
+type errorsValidator struct {
+	// s is the original JSON.
+	s string
+
+	maxErrors int
+	errs      []error
+
+	// stopped is set when validation cannot be continued.
+	stopped bool
+
+	// stack contains the containers for the currently validated value.
+	stack []errorsFrame
+}
+
+type errorsFrame struct {
+	// key is the raw key for the current object value.
+	key string
+
+	// idx is the index of the current array value.
+	idx int
+
+	isObject bool
+}
+
+// addError registers the error at the given tail of ev.s.
+//
+// The error path includes the current container item if isItem is set.
+func (ev *errorsValidator) addError(tail, expected string, isItem bool, msg string) {
+	pe := newParseError(ev.s, tail, "")
+	pe.Path = ev.path(isItem)
+	pe.Expected = expected
+	pe.msg = fmt.Sprintf("cannot parse JSON at line %d, column %d: %s; unparsed tail: %q", pe.Line, pe.Column, msg, startEndString(tail))
+	ev.errs = append(ev.errs, pe)
+	if len(tail) == 0 || ev.maxErrors > 0 && len(ev.errs) >= ev.maxErrors {
+		ev.stopped = true
+	}
+}
+
+func (ev *errorsValidator) path(isItem bool) string {
+	var b []byte
+	for i := range ev.stack {
+		f := &ev.stack[i]
+		if i == len(ev.stack)-1 && !isItem {
+			break
+		}
+		if !f.isObject {
+			b = append(b, '[')
+			b = strconv.AppendInt(b, int64(f.idx), 10)
+			b = append(b, ']')
+			continue
+		}
+		if len(b) > 0 {
+			b = append(b, '.')
+		}
+		// Copy the key before unescaping, since unescapeStringBestEffort modifies
+		// the string in place, while ev.s may point to read-only memory.
+		b = append(b, unescapeStringBestEffort(string(append([]byte(nil), f.key...)))...)
+	}
+	return string(b)
 }
 
-func validateValue(s string) (string, error) {
+// resync skips s until the next ',', '}' or ']' at the current nesting level.
+//
+// false is returned if the end of ev.s is reached or if the validation
+// has been stopped.
+func (ev *errorsValidator) resync(s string) (string, bool) {
+	if ev.stopped {
+		return s, false
+	}
+	depth := 0
+	for len(s) > 0 {
+		switch s[0] {
+		case '"':
+			n := strings.IndexByte(s[1:], '"')
+			for n >= 0 && isEscapedQuote(s, n+1) {
+				m := strings.IndexByte(s[n+2:], '"')
+				if m < 0 {
+					n = -1
+					break
+				}
+				n += m + 1
+			}
+			if n < 0 {
+				ev.stopped = true
+				return "", false
+			}
+			s = s[n+2:]
+			continue
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return s, true
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				return s, true
+			}
+		}
+		s = s[1:]
+	}
+	ev.stopped = true
+	return s, false
+}
+
+// isEscapedQuote returns true if the quote at s[n] is escaped with backslash.
+func isEscapedQuote(s string, n int) bool {
+	i := n - 1
+	for i >= 0 && s[i] == '\\' {
+		i--
+	}
+	return (n-i)%2 == 0
+}
+
+// validateValue validates the value at the beginning of s.
+//
+// false is returned if the value is invalid. The error is registered
+// then and the returned tail points to the error location.
+func (ev *errorsValidator) validateValue(s string) (string, bool) {
+	if len(s) > 0 && (s[0] == '{' || s[0] == '[') {
+		if len(ev.stack) >= MaxDepth {
+			ev.addError(s, "value", true, fmt.Sprintf("too big depth for the nested JSON; it exceeds %d", MaxDepth))
+			return s, false
+		}
+		ev.stack = append(ev.stack, errorsFrame{
+			isObject: s[0] == '{',
+		})
+		var tail string
+		var ok bool
+		if s[0] == '{' {
+			tail, ok = ev.validateObject(s[1:])
+		} else {
+			tail, ok = ev.validateArray(s[1:])
+		}
+		ev.stack = ev.stack[:len(ev.stack)-1]
+		return tail, ok
+	}
+
+	tail, err := validateValue(s, 0, &defaultValidateLimits)
+	if err != nil {
+		expected := "value"
+		if f := ev.lastFrame(); f != nil && !f.isObject && f.idx == 0 {
+			expected = "value or ']'"
+		}
+		ev.addError(tail, expected, true, err.Error())
+		return tail, false
+	}
+	return tail, true
+}
+
+func (ev *errorsValidator) lastFrame() *errorsFrame {
+	if len(ev.stack) == 0 {
+		return nil
+	}
+	return &ev.stack[len(ev.stack)-1]
+}
+
+// validateArray validates array items in s and returns the tail after the array.
+//
+// Errors in the array items are registered and skipped. false is returned
+// only if the validation cannot be continued.
+func (ev *errorsValidator) validateArray(s string) (string, bool) {
+	s = skipWS(s)
+	if len(s) > 0 && s[0] == ']' {
+		return s[1:], true
+	}
+	for {
+		s = skipWS(s)
+		tail, ok := ev.validateValue(s)
+		if ok {
+			s = skipWS(tail)
+			switch {
+			case len(s) == 0:
+				ev.addError(s, "',' or ']'", false, "unexpected end of array")
+				return s, false
+			case s[0] == ',' || s[0] == ']':
+			default:
+				ev.addError(s, "',' or ']'", false, "missing ',' after array value")
+				s, ok = ev.resync(s)
+			}
+		} else {
+			s, ok = ev.resync(tail)
+		}
+		if !ok {
+			return s, false
+		}
+		if s[0] != ',' {
+			// Either ']' or mismatched '}' ends the array.
+			return s[1:], true
+		}
+		s = s[1:]
+		// ev.stack may be re-allocated by nested values, so obtain the frame again.
+		ev.lastFrame().idx++
+	}
+}
+
+// validateObject validates object items in s and returns the tail after the object.
+//
+// Errors in the object items are registered and skipped. false is returned
+// only if the validation cannot be continued.
+func (ev *errorsValidator) validateObject(s string) (string, bool) {
+	s = skipWS(s)
+	if len(s) > 0 && s[0] == '}' {
+		return s[1:], true
+	}
+	expectedKey := "object key or '}'"
+	for {
+		var ok bool
+		s = skipWS(s)
+		s, ok = ev.validateObjectItem(s, expectedKey)
+		if ok {
+			s = skipWS(s)
+			switch {
+			case len(s) == 0:
+				ev.addError(s, "',' or '}'", false, "unexpected end of object")
+				return s, false
+			case s[0] == ',' || s[0] == '}':
+			default:
+				ev.addError(s, "',' or '}'", false, "missing ',' after object value")
+				s, ok = ev.resync(s)
+			}
+		} else {
+			s, ok = ev.resync(s)
+		}
+		if !ok {
+			return s, false
+		}
+		if s[0] != ',' {
+			// Either '}' or mismatched ']' ends the object.
+			return s[1:], true
+		}
+		s = s[1:]
+		expectedKey = "object key"
+	}
+}
+
+// validateObjectItem validates "key": value at the beginning of s.
+//
+// false is returned if the item is invalid. The error is registered then
+// and the returned tail points to the error location.
+func (ev *errorsValidator) validateObjectItem(s, expectedKey string) (string, bool) {
+	if len(s) == 0 || s[0] != '"' {
+		ev.addError(s, expectedKey, false, `cannot find opening '"' for object key`)
+		return s, false
+	}
+	key, tail, err := validateKey(s[1:])
+	if err != nil {
+		ev.addError(s, expectedKey, false, fmt.Sprintf("cannot parse object key: %s", err))
+		return s, false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 {
+			ev.addError(s, expectedKey, false, fmt.Sprintf("object key cannot contain control char 0x%02X", key[i]))
+			return s, false
+		}
+	}
+	ev.lastFrame().key = key
+	s = skipWS(tail)
+	if len(s) == 0 || s[0] != ':' {
+		ev.addError(s, "':'", false, "missing ':' after object key")
+		return s, false
+	}
+	return ev.validateValue(skipWS(s[1:]))
+}
+
+// validateLimits contains limits for validateValue.
+type validateLimits struct {
+	maxDepth     int
+	maxStringLen int
+	maxKeyLen    int
+	maxNumberLen int
+
+	// cc checks ctx passed to ValidateCtx.
+	cc contextChecker
+}
+
+var defaultValidateLimits = validateLimits{
+	maxDepth: MaxDepth,
+}
+
+func validateValue(s string, depth int, vl *validateLimits) (string, error) {
 	if len(s) == 0 {
 		return s, fmt.Errorf("cannot parse empty string")
 	}
+	depth++
+	if depth > vl.maxDepth {
+		return s, fmt.Errorf("too big depth for the nested JSON; it exceeds %d", vl.maxDepth)
+	}
+	if vl.cc.ctx != nil {
+		if err := vl.cc.check(); err != nil {
+			return s, err
+		}
+	}
 
 	if s[0] == '{' {
-		tail, err := validateObject(s[1:])
+		tail, err := validateObject(s[1:], depth, vl)
 		if err != nil {
 			return tail, fmt.Errorf("cannot parse object: %s", err)
 		}
 		return tail, nil
 	}
 	if s[0] == '[' {
-		tail, err := validateArray(s[1:])
+		tail, err := validateArray(s[1:], depth, vl)
 		if err != nil {
 			return tail, fmt.Errorf("cannot parse array: %s", err)
 		}
@@ -48,43 +539,56 @@ func validateValue(s string) (string, error) {
 	if s[0] == '"' {
 		sv, tail, err := validateString(s[1:])
 		if err != nil {
-			return tail, fmt.Errorf("cannot parse string: %s", err)
+			// Point to the beginning of the invalid string.
+			return s, fmt.Errorf("cannot parse string: %s", err)
+		}
+		if vl.maxStringLen > 0 {
+			// Do not use len(sv), since validateString may return only the tail of the string.
+			if n := len(s) - len(tail) - 2; n > vl.maxStringLen {
+				return s, fmt.Errorf("too long string with %d bytes; it exceeds the limit of %d bytes", n, vl.maxStringLen)
+			}
 		}
 		// Scan the string for control chars.
 		for i := 0; i < len(sv); i++ {
 			if sv[i] < 0x20 {
-				return tail, fmt.Errorf("string cannot contain control char 0x%02X", sv[i])
+				return s, fmt.Errorf("string cannot contain control char 0x%02X", sv[i])
 			}
 		}
 		return tail, nil
 	}
 	if s[0] == 't' {
 		if len(s) < len("true") || s[:len("true")] != "true" {
-			return s, fmt.Errorf("unexpected value found: %q", s)
+			return s, fmt.Errorf("unexpected value found: %q", startEndString(s))
 		}
 		return s[len("true"):], nil
 	}
 	if s[0] == 'f' {
 		if len(s) < len("false") || s[:len("false")] != "false" {
-			return s, fmt.Errorf("unexpected value found: %q", s)
+			return s, fmt.Errorf("unexpected value found: %q", startEndString(s))
 		}
 		return s[len("false"):], nil
 	}
 	if s[0] == 'n' {
 		if len(s) < len("null") || s[:len("null")] != "null" {
-			return s, fmt.Errorf("unexpected value found: %q", s)
+			return s, fmt.Errorf("unexpected value found: %q", startEndString(s))
 		}
 		return s[len("null"):], nil
 	}
 
 	tail, err := validateNumber(s)
 	if err != nil {
+		if depth == 1 && hasUTF16BOM(s) {
+			return s, errUTF16
+		}
 		return tail, fmt.Errorf("cannot parse number: %s", err)
 	}
+	if n := len(s) - len(tail); vl.maxNumberLen > 0 && n > vl.maxNumberLen {
+		return s, fmt.Errorf("too long number with %d bytes; it exceeds the limit of %d bytes", n, vl.maxNumberLen)
+	}
 	return tail, nil
 }
 
-func validateArray(s string) (string, error) {
+func validateArray(s string, depth int, vl *validateLimits) (string, error) {
 	s = skipWS(s)
 	if len(s) == 0 {
 		return s, fmt.Errorf("missing ']'")
@@ -97,7 +601,7 @@ func validateArray(s string) (string, error) {
 		var err error
 
 		s = skipWS(s)
-		s, err = validateValue(s)
+		s, err = validateValue(s, depth, vl)
 		if err != nil {
 			return s, fmt.Errorf("cannot parse array value: %s", err)
 		}
@@ -118,7 +622,7 @@ func validateArray(s string) (string, error) {
 	}
 }
 
-func validateObject(s string) (string, error) {
+func validateObject(s string, depth int, vl *validateLimits) (string, error) {
 	s = skipWS(s)
 	if len(s) == 0 {
 		return s, fmt.Errorf("missing '}'")
@@ -136,17 +640,24 @@ func validateObject(s string) (string, error) {
 			return s, fmt.Errorf(`cannot find opening '"" for object key`)
 		}
 
-		var key string
-		key, s, err = validateKey(s[1:])
+		// Point to the beginning of the invalid key on errors.
+		key, tail, err := validateKey(s[1:])
 		if err != nil {
 			return s, fmt.Errorf("cannot parse object key: %s", err)
 		}
+		if vl.maxKeyLen > 0 {
+			// Do not use len(key), since validateKey may return only the tail of the key.
+			if n := len(s) - len(tail) - 2; n > vl.maxKeyLen {
+				return s, fmt.Errorf("too long object key with %d bytes; it exceeds the limit of %d bytes", n, vl.maxKeyLen)
+			}
+		}
 		// Scan the key for control chars.
 		for i := 0; i < len(key); i++ {
 			if key[i] < 0x20 {
 				return s, fmt.Errorf("object key cannot contain control char 0x%02X", key[i])
 			}
 		}
+		s = tail
 		s = skipWS(s)
 		if len(s) == 0 || s[0] != ':' {
 			return s, fmt.Errorf("missing ':' after object key")
@@ -155,7 +666,7 @@ func validateObject(s string) (string, error) {
 
 		// Parse value
 		s = skipWS(s)
-		s, err = validateValue(s)
+		s, err = validateValue(s, depth, vl)
 		if err != nil {
 			return s, fmt.Errorf("cannot parse object value: %s", err)
 		}
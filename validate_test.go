@@ -37,6 +37,148 @@ func TestValidateSimple(t *testing.T) {
 	}
 }
 
+func TestValidatePrefix(t *testing.T) {
+	f := func(s string, offsetExpected int) {
+		t.Helper()
+		offset, err := ValidatePrefix(s)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		if offset != offsetExpected {
+			t.Fatalf("unexpected offset for %q; got %d; want %d", s, offset, offsetExpected)
+		}
+		offset, err = ValidateBytesPrefix([]byte(s))
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		if offset != offsetExpected {
+			t.Fatalf("unexpected offset for %q; got %d; want %d", s, offset, offsetExpected)
+		}
+	}
+
+	// values followed by garbage
+	f(`{"foo":[1,2]}`+"\x00\x01binary", 13)
+	f(`  "bar"xyz`, 7)
+	f(`123abc`, 3)
+	f(`true}`, 4)
+
+	// values followed by whitespace
+	f(`[1, 2]  `, 6)
+	f(" null\n", 5)
+
+	// values followed by other values
+	f(`{}{"foo":"bar"}`, 2)
+	f(`1 2`, 1)
+	f(` "a" "b"`, 4)
+
+	// invalid values
+	ferr := func(s string) {
+		t.Helper()
+		if _, err := ValidatePrefix(s); err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+	}
+	ferr(``)
+	ferr(`   `)
+	ferr(`01`)
+	ferr(`{"foo":bar}`)
+	ferr(`"foo\qwe" tail`)
+	ferr("\"foo\x00\" tail")
+	ferr(`[1,2`)
+}
+
+func TestValidateMaxDepth(t *testing.T) {
+	f := func(s string, maxDepth int, valid bool) {
+		t.Helper()
+
+		err := ValidateWithDepth(s, maxDepth)
+		if valid && err != nil {
+			t.Fatalf("unexpected error for maxDepth=%d: %s", maxDepth, err)
+		}
+		if !valid && err == nil {
+			t.Fatalf("expecting non-nil error for maxDepth=%d", maxDepth)
+		}
+		if err := ValidateBytesWithDepth([]byte(s), maxDepth); (err == nil) != valid {
+			t.Fatalf("unexpected ValidateBytesWithDepth result for maxDepth=%d: %v", maxDepth, err)
+		}
+		if maxDepth == MaxDepth {
+			// Validate and Parse must be consistent.
+			errValidate := Validate(s)
+			var p Parser
+			_, errParse := p.Parse(s)
+			if (errValidate == nil) != valid || (errParse == nil) != valid {
+				t.Fatalf("unexpected result; Validate error: %v; Parse error: %v; want valid=%v", errValidate, errParse, valid)
+			}
+		}
+	}
+	arrays := func(depth int) string {
+		return strings.Repeat("[", depth) + strings.Repeat("]", depth)
+	}
+	objects := func(depth int) string {
+		return strings.Repeat(`{"a":`, depth-1) + "1" + strings.Repeat("}", depth-1)
+	}
+
+	// Exactly at the limit
+	f(arrays(MaxDepth), MaxDepth, true)
+	f(objects(MaxDepth), MaxDepth, true)
+	f(arrays(10), 10, true)
+	f(objects(10), 10, true)
+
+	// One past the limit
+	f(arrays(MaxDepth+1), MaxDepth, false)
+	f(objects(MaxDepth+1), MaxDepth, false)
+	f(arrays(11), 10, false)
+	f(objects(11), 10, false)
+
+	// Raised limit
+	f(arrays(10000), 10000, true)
+	f(objects(10000), 10000, true)
+
+	// Default limit is used for non-positive maxDepth
+	f(arrays(MaxDepth), 0, true)
+	f(arrays(MaxDepth+1), -1, false)
+
+	// Pathological input must fail fast without stack overflow.
+	s := strings.Repeat("[", 1000000)
+	f(s, MaxDepth, false)
+	f(s, 0, false)
+	err := Validate(s)
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expecting *ParseError; got %T", err)
+	}
+	if pe.Offset != MaxDepth {
+		t.Fatalf("unexpected error offset; got %d; want %d", pe.Offset, MaxDepth)
+	}
+	if !strings.Contains(err.Error(), "too big depth") {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestValidateTruncatedEscape(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		if err := Validate(s); err == nil {
+			t.Fatalf("expecting non-nil error from Validate(%q)", s)
+		}
+		if _, err := ValidatePrefix(s); err == nil {
+			t.Fatalf("expecting non-nil error from ValidatePrefix(%q)", s)
+		}
+		var p Parser
+		if _, err := p.Parse(s); err == nil {
+			t.Fatalf("expecting non-nil error from Parse(%q)", s)
+		}
+	}
+	f(`"\`)
+	f(`"foo\`)
+	f(`"\u`)
+	f(`"\u12`)
+	f(`["\u12`)
+	f(`{"\u12`)
+	f(`{"a":"\`)
+}
+
 func TestValidateNumberZeroLen(t *testing.T) {
 	tail, err := validateNumber("")
 	if err == nil {
@@ -47,109 +189,111 @@ func TestValidateNumberZeroLen(t *testing.T) {
 	}
 }
 
+// validateTests contains tricky JSON strings for validation tests.
+var validateTests = []string{
+	"",
+	"   ",
+	" z",
+	" 1  1",
+	" 1  {}",
+	" 1  []",
+	" 1  true",
+	" 1  null",
+	" 1  \"n\"",
+
+	// string
+	`"foo"`,
+	"\"\xe2\x80\xa8\xe2\x80\xa9\"", // line-sep and paragraph-sep
+	` "\uaaaa" `,
+	`"\uz"`,
+	` "\`,
+	` "\z`,
+	" \"f\x00o\"",  // control char
+	"\"foo\nbar\"", // control char
+	`"foo\qw"`,     // unknown escape sequence
+	` "foo`,
+	` "\uazaa" `,
+	`"\"\\\/\b\f\n\r\t"`,
+
+	// number
+	"1",
+	"  0 ",
+	" 0e1 ",
+	" 0e+0 ",
+	" -0e+0 ",
+	"-0",
+	"1e6",
+	"1e+6",
+	"-1e+6",
+	"-0e+6",
+	" -103e+1 ",
+	"-0.01e+006",
+	"-z",
+	"-",
+	"1e",
+	"1e+",
+	" 03e+1 ",
+	" 1e.1 ",
+	" 00 ",
+	"1.e3",
+	"01e+6",
+	"-0.01e+0.6",
+	"123.",
+	"123.345",
+	"001 ",
+	"001",
+
+	// object
+	"{}",
+	`{"foo": 3}`,
+	"{\"f\x00oo\": 3}",
+	`{"foo\WW": 4}`, // unknown escape sequence
+	`{"foo": 3 "bar"}`,
+	` {}    `,
+	strings.Repeat(`{"f":`, MaxDepth-1) + "{}" + strings.Repeat("}", MaxDepth-1),
+	`{"foo": [{"":3, "4": "3"}, 4, {}], "t_wo": 1}`,
+	` {"foo": 2,"fudge}`,
+	`{{"foo": }}`,
+	`{{"foo": [{"":3, 4: "3"}, 4, "5": {4}]}, "t_wo": 1}`,
+	"{",
+	`{"foo"`,
+	`{"foo",f}`,
+	`{"foo",`,
+	`{"foo"f`,
+	"{}}",
+	`{"foo": 234`,
+	`{"foo\"bar": 123}`,
+	"{\n\t\"foo\"  \n\b\f: \t123}",
+
+	// array
+	`[]`,
+	`[ 1, {}]`,
+	strings.Repeat("[", MaxDepth) + strings.Repeat("]", MaxDepth),
+	`[1, 2, 3, 4, {}]`,
+	`[`,
+	`[1,`,
+	`[1a`,
+	`[]]`,
+	`[1  `,
+
+	// boolean
+	"true",
+	"   true ",
+	"tree",
+	"false",
+	"  true f",
+	"fals",
+	"falsee",
+
+	// null
+	"null ",
+	" null ",
+	" nulll ",
+	"no",
+}
+
 func TestValidate(t *testing.T) {
-	var tests = []string{
-		"",
-		"   ",
-		" z",
-		" 1  1",
-		" 1  {}",
-		" 1  []",
-		" 1  true",
-		" 1  null",
-		" 1  \"n\"",
-
-		// string
-		`"foo"`,
-		"\"\xe2\x80\xa8\xe2\x80\xa9\"", // line-sep and paragraph-sep
-		` "\uaaaa" `,
-		`"\uz"`,
-		` "\`,
-		` "\z`,
-		" \"f\x00o\"",  // control char
-		"\"foo\nbar\"", // control char
-		`"foo\qw"`,     // unknown escape sequence
-		` "foo`,
-		` "\uazaa" `,
-		`"\"\\\/\b\f\n\r\t"`,
-
-		// number
-		"1",
-		"  0 ",
-		" 0e1 ",
-		" 0e+0 ",
-		" -0e+0 ",
-		"-0",
-		"1e6",
-		"1e+6",
-		"-1e+6",
-		"-0e+6",
-		" -103e+1 ",
-		"-0.01e+006",
-		"-z",
-		"-",
-		"1e",
-		"1e+",
-		" 03e+1 ",
-		" 1e.1 ",
-		" 00 ",
-		"1.e3",
-		"01e+6",
-		"-0.01e+0.6",
-		"123.",
-		"123.345",
-		"001 ",
-		"001",
-
-		// object
-		"{}",
-		`{"foo": 3}`,
-		"{\"f\x00oo\": 3}",
-		`{"foo\WW": 4}`, // unknown escape sequence
-		`{"foo": 3 "bar"}`,
-		` {}    `,
-		strings.Repeat(`{"f":`, 1000) + "{}" + strings.Repeat("}", 1000),
-		`{"foo": [{"":3, "4": "3"}, 4, {}], "t_wo": 1}`,
-		` {"foo": 2,"fudge}`,
-		`{{"foo": }}`,
-		`{{"foo": [{"":3, 4: "3"}, 4, "5": {4}]}, "t_wo": 1}`,
-		"{",
-		`{"foo"`,
-		`{"foo",f}`,
-		`{"foo",`,
-		`{"foo"f`,
-		"{}}",
-		`{"foo": 234`,
-		`{"foo\"bar": 123}`,
-		"{\n\t\"foo\"  \n\b\f: \t123}",
-
-		// array
-		`[]`,
-		`[ 1, {}]`,
-		strings.Repeat("[", 1000) + strings.Repeat("]", 1000),
-		`[1, 2, 3, 4, {}]`,
-		`[`,
-		`[1,`,
-		`[1a`,
-		`[]]`,
-		`[1  `,
-
-		// boolean
-		"true",
-		"   true ",
-		"tree",
-		"false",
-		"  true f",
-		"fals",
-		"falsee",
-
-		// null
-		"null ",
-		" null ",
-		" nulll ",
-		"no",
-	}
-	for i, test := range tests {
+	for i, test := range validateTests {
 		in := []byte(test)
 		got := ValidateBytes(in) == nil
 		exp := json.Valid(in)
@@ -159,3 +303,195 @@ func TestValidate(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateBOM(t *testing.T) {
+	const bom = "\xef\xbb\xbf"
+	for _, s := range []string{bom + `{"a":1}`, bom + ` [] `, bom + `123`} {
+		if err := Validate(s); err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		if _, err := ValidatePrefix(s); err != nil {
+			t.Fatalf("unexpected error in ValidatePrefix for %q: %s", s, err)
+		}
+	}
+	for _, s := range []string{bom, bom + bom + `{}`, ` ` + bom + `{}`, `[` + bom + `]`, `{}` + bom} {
+		if err := Validate(s); err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+	}
+	err := Validate("\xff\xfe{\x00}\x00")
+	if err == nil || !strings.Contains(err.Error(), "UTF-16 encoded JSON is not supported") {
+		t.Fatalf("unexpected error for UTF-16 encoded JSON: %v", err)
+	}
+}
+
+func TestValidateStream(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		if err := ValidateStream(s); err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		if err := ValidateStreamBytes([]byte(s)); err != nil {
+			t.Fatalf("unexpected error in ValidateStreamBytes for %q: %s", s, err)
+		}
+	}
+	f(``)
+	f(`   `)
+	f("\n\n")
+	f(`1`)
+	f(`1 2 3`)
+	f(`{"a":1}{"b":2}`)
+	f("{\"a\":1}\n[1,2]\n\"foo\"\nnull\ntrue\n")
+	f("\xef\xbb\xbf{}\n{}")
+	f(smallFixture + mediumFixture + "\n" + largeFixture)
+
+	fError := func(s string, offsetExpected int, errExpected string) {
+		t.Helper()
+		err := ValidateStream(s)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+		pe, ok := err.(*ParseError)
+		if !ok {
+			t.Fatalf("unexpected error type for %q: %T", s, err)
+		}
+		if pe.Offset != offsetExpected {
+			t.Fatalf("unexpected error offset for %q; got %d; want %d", s, pe.Offset, offsetExpected)
+		}
+		if !strings.Contains(err.Error(), errExpected) {
+			t.Fatalf("unexpected error for %q; got %q; must contain %q", s, err, errExpected)
+		}
+	}
+
+	// The 3rd of 5 values is malformed.
+	s := "{\"a\":1}\n[1,2]\n{\"b\":[1,}\n\"foo\"\nnull\n"
+	fError(s, strings.Index(s, "}\n\"foo"), "cannot parse JSON value #2")
+	fError(`1 2 x 3 4`, 4, "cannot parse JSON value #2")
+	fError(`{} [] "foo`, 6, "cannot parse JSON value #2")
+
+	fError(`x`, 0, "cannot parse JSON value #0")
+	fError(`{"a":1} ]`, 8, "cannot parse JSON value #1")
+	fError(`[1] [1`, 6, "cannot parse JSON value #1")
+}
+
+func TestValidateAll(t *testing.T) {
+	type errInfo struct {
+		line, column int
+		path         string
+	}
+	f := func(s string, maxErrors int, errsExpected []errInfo) {
+		t.Helper()
+		errs := ValidateAll(s, maxErrors)
+		if len(errs) != len(errsExpected) {
+			t.Fatalf("unexpected number of errors for %q; got %d; want %d; errors: %v", s, len(errs), len(errsExpected), errs)
+		}
+		for i, err := range errs {
+			pe, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("unexpected error type for %q: %T", s, err)
+			}
+			e := errsExpected[i]
+			if pe.Line != e.line || pe.Column != e.column || pe.Path != e.path {
+				t.Fatalf("unexpected error #%d for %q; got (%d, %d, %q); want (%d, %d, %q); error: %s",
+					i, s, pe.Line, pe.Column, pe.Path, e.line, e.column, e.path, err)
+			}
+		}
+		errsBytes := ValidateAllBytes([]byte(s), maxErrors)
+		if len(errsBytes) != len(errs) {
+			t.Fatalf("unexpected number of errors from ValidateAllBytes for %q; got %d; want %d", s, len(errsBytes), len(errs))
+		}
+	}
+
+	// Valid JSON
+	f(`{}`, 0, nil)
+	f(smallFixture, 0, nil)
+	f(mediumFixture, 1, nil)
+
+	// Three independent errors in a config.
+	s := `{
+  "name": "foo",
+  "port": 80x,
+  "hosts": ["a", tru, "c"],
+  "limits": {"cpu" 2, "mem": 100}
+}`
+	f(s, 0, []errInfo{
+		{3, 13, ""},
+		{4, 18, "hosts[1]"},
+		{5, 20, "limits"},
+	})
+
+	// maxErrors limits the number of errors.
+	f(s, 2, []errInfo{
+		{3, 13, ""},
+		{4, 18, "hosts[1]"},
+	})
+	f(s, 1, []errInfo{
+		{3, 13, ""},
+	})
+
+	// Errors in nested arrays and after recovery in the middle of containers.
+	f(`[1,,[2 3],{"a":[x]},]`, 0, []errInfo{
+		{1, 4, "[1]"},
+		{1, 8, "[2]"},
+		{1, 17, "[3].a[0]"},
+		{1, 21, "[4]"},
+	})
+	f("[1]\n]", 0, []errInfo{
+		{2, 1, ""},
+	})
+
+	// Unrecoverable errors
+	f(`{"a": "abc`, 0, []errInfo{
+		{1, 7, "a"},
+	})
+	f(`["abc, 1, x, 2]`, 0, []errInfo{
+		{1, 2, "[0]"},
+	})
+	f(`{"a":1`, 0, []errInfo{
+		{1, 7, ""},
+	})
+	f(`{"a":x, "b":1`, 0, []errInfo{
+		{1, 6, "a"},
+		{1, 14, ""},
+	})
+	f(`{"a":x, "b":[1`, 0, []errInfo{
+		{1, 6, "a"},
+		{1, 15, "b"},
+	})
+	f(`[x`, 0, []errInfo{
+		{1, 2, "[0]"},
+	})
+	f(``, 0, []errInfo{
+		{1, 1, ""},
+	})
+
+	// Escaped quotes are skipped during recovery.
+	f(`[x "a\"]", 1]`, 0, []errInfo{
+		{1, 2, "[0]"},
+	})
+
+	// The first error must be located at the error returned by Validate.
+	for i := 0; i < len(mediumFixture); i++ {
+		s := mediumFixture[:i] + "x" + mediumFixture[i+1:]
+		err := Validate(s)
+		errs := ValidateAll(s, 0)
+		if err == nil {
+			if len(errs) > 0 {
+				t.Fatalf("unexpected errors for valid JSON %q: %v", s, errs)
+			}
+			continue
+		}
+		if len(errs) == 0 {
+			t.Fatalf("expecting errors for %q", s)
+		}
+		offset := err.(*ParseError).Offset
+		if offset0 := errs[0].(*ParseError).Offset; offset0 != offset {
+			t.Fatalf("unexpected first error offset for %q; got %d; want %d", s, offset0, offset)
+		}
+	}
+
+	// Deep nesting
+	f(strings.Repeat("[", MaxDepth+1)+strings.Repeat("]", MaxDepth+1), 0, []errInfo{
+		{1, MaxDepth + 1, strings.Repeat("[0]", MaxDepth)},
+	})
+}
@@ -60,3 +60,41 @@ func benchmarkValidateFastJSON(b *testing.B, s string) {
 		}
 	})
 }
+
+func BenchmarkValidateStream(b *testing.B) {
+	var bb []byte
+	for i := 0; i < 10; i++ {
+		for _, s := range []string{smallFixture, mediumFixture, largeFixture} {
+			bb = MustParse(s).MarshalTo(bb)
+			bb = append(bb, '\n')
+		}
+	}
+	s := string(bb)
+
+	b.Run("ValidateStream", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(s)))
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if err := ValidateStream(s); err != nil {
+					panic(fmt.Errorf("unexpected error: %s", err))
+				}
+			}
+		})
+	})
+	b.Run("Scanner", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(s)))
+		b.RunParallel(func(pb *testing.PB) {
+			var sc Scanner
+			for pb.Next() {
+				sc.Init(s)
+				for sc.Next() {
+				}
+				if err := sc.Error(); err != nil {
+					panic(fmt.Errorf("unexpected error: %s", err))
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,62 @@
+package fastjson
+
+// Walk performs depth-first traversal over v and calls f for every value
+// including v itself.
+//
+// path contains the path to the value from v. Path elements are object keys
+// (string) and array indexes (int). path is empty for v.
+// Children of the value aren't visited if f returns false.
+//
+// f cannot hold path after returning, since it is re-used for subsequent calls.
+func (v *Value) Walk(f func(path []interface{}, v *Value) bool) {
+	if v == nil {
+		return
+	}
+	var path []interface{}
+	walk(v, path, f)
+}
+
+func walk(v *Value, path []interface{}, f func(path []interface{}, v *Value) bool) []interface{} {
+	if !f(path, v) {
+		return path
+	}
+	switch v.t {
+	case TypeObject:
+		v.o.unescapeKeys()
+		for _, kv := range v.o.kvs {
+			path = append(path, kv.k)
+			path = walk(kv.v, path, f)
+			path = path[:len(path)-1]
+		}
+	case TypeArray:
+		for i, vv := range v.a {
+			path = append(path, i)
+			path = walk(vv, path, f)
+			path = path[:len(path)-1]
+		}
+	}
+	return path
+}
+
+// Find returns the first value in v matching pred in the document order.
+//
+// The returned path contains object keys (string) and array indexes (int)
+// for the found value. See Walk for details.
+//
+// nil path and nil value are returned if no matching value is found.
+func (v *Value) Find(pred func(v *Value) bool) ([]interface{}, *Value) {
+	var pathFound []interface{}
+	var vFound *Value
+	v.Walk(func(path []interface{}, v *Value) bool {
+		if vFound != nil {
+			return false
+		}
+		if pred(v) {
+			pathFound = append([]interface{}{}, path...)
+			vFound = v
+			return false
+		}
+		return true
+	})
+	return pathFound, vFound
+}
@@ -0,0 +1,65 @@
+package fastjson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValueWalk(t *testing.T) {
+	var v *Value
+	v.Walk(func(path []interface{}, v *Value) bool {
+		t.Fatalf("f mustn't be called for nil value")
+		return true
+	})
+
+	v = MustParse(`{"a":[1,{"b\nc":true}],"d":{"e":null,"f":[]},"g":"x"}`)
+	var visited []string
+	v.Walk(func(path []interface{}, v *Value) bool {
+		visited = append(visited, fmt.Sprintf("%v=%s", path, v))
+		// Skip children of "d"
+		return len(path) != 1 || path[0] != "d"
+	})
+	s := strings.Join(visited, "; ")
+	sExpected := `[]={"a":[1,{"b\nc":true}],"d":{"e":null,"f":[]},"g":"x"}; [a]=[1,{"b\nc":true}]; [a 0]=1; [a 1]={"b\nc":true}; [a 1 b` + "\n" + `c]=true; [d]={"e":null,"f":[]}; [g]="x"`
+	if s != sExpected {
+		t.Fatalf("unexpected visited values\ngot\n%s\nwant\n%s", s, sExpected)
+	}
+}
+
+func TestValueFind(t *testing.T) {
+	v := MustParse(twitterFixture)
+	path, vFound := v.Find(func(v *Value) bool {
+		return string(v.GetStringBytes("screen_name")) == "aym0566x"
+	})
+	if vFound == nil {
+		t.Fatalf("cannot find the value")
+	}
+	keys := make([]string, len(path))
+	for i, p := range path {
+		switch x := p.(type) {
+		case string:
+			keys[i] = x
+		case int:
+			keys[i] = strconv.Itoa(x)
+		default:
+			t.Fatalf("unexpected path element type %T", p)
+		}
+	}
+	if v.Get(keys...) != vFound {
+		t.Fatalf("the returned path %v doesn't resolve to the found value", path)
+	}
+	keysExpected := "statuses,0,entities,user_mentions,0"
+	if strings.Join(keys, ",") != keysExpected {
+		t.Fatalf("unexpected path; got %q; want %q", strings.Join(keys, ","), keysExpected)
+	}
+
+	// Missing value
+	path, vFound = v.Find(func(v *Value) bool {
+		return v.Type() == TypeString && string(v.GetStringBytes()) == "missing-value-in-the-fixture"
+	})
+	if path != nil || vFound != nil {
+		t.Fatalf("expecting nil results for missing value; got %v, %v", path, vFound)
+	}
+}
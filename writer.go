@@ -0,0 +1,401 @@
+package fastjson
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// WriteTo writes marshaled v to w.
+//
+// Unlike MarshalTo, WriteTo doesn't build the whole marshaled v in memory.
+// The marshaled v is written to w in small chunks instead.
+//
+// WriteTo implements io.WriterTo.
+func (v *Value) WriteTo(w io.Writer) (int64, error) {
+	vw := getValueWriter(w)
+	vw.writeValue(v)
+	vw.flush()
+	n, err := vw.n, vw.err
+	putValueWriter(vw)
+	return n, err
+}
+
+// WriteTo writes marshaled o to w.
+//
+// Unlike MarshalTo, WriteTo doesn't build the whole marshaled o in memory.
+// The marshaled o is written to w in small chunks instead.
+//
+// WriteTo implements io.WriterTo.
+func (o *Object) WriteTo(w io.Writer) (int64, error) {
+	vw := getValueWriter(w)
+	vw.writeObject(o)
+	vw.flush()
+	n, err := vw.n, vw.err
+	putValueWriter(vw)
+	return n, err
+}
+
+// maxValueWriterBufLen is the buffer size after which valueWriter
+// flushes the buffered data to the underlying writer.
+const maxValueWriterBufLen = 4 * 1024
+
+type valueWriter struct {
+	w   io.Writer
+	buf []byte
+	n   int64
+	err error
+}
+
+func (vw *valueWriter) writeValue(v *Value) {
+	switch v.t {
+	case TypeObject:
+		vw.writeObject(&v.o)
+	case TypeArray:
+		vw.buf = append(vw.buf, '[')
+		for i, vv := range v.a {
+			if i > 0 {
+				vw.buf = append(vw.buf, ',')
+			}
+			vw.writeValue(vv)
+			if vw.err != nil {
+				return
+			}
+		}
+		vw.buf = append(vw.buf, ']')
+	default:
+		vw.buf = v.MarshalTo(vw.buf)
+	}
+	vw.flushIfNeeded()
+}
+
+func (vw *valueWriter) writeObject(o *Object) {
+	vw.buf = append(vw.buf, '{')
+	for i, kv := range o.kvs {
+		if i > 0 {
+			vw.buf = append(vw.buf, ',')
+		}
+		if o.keysUnescaped {
+			vw.buf = escapeString(vw.buf, kv.k)
+		} else {
+			vw.buf = append(vw.buf, '"')
+			vw.buf = append(vw.buf, kv.k...)
+			vw.buf = append(vw.buf, '"')
+		}
+		vw.buf = append(vw.buf, ':')
+		vw.writeValue(kv.v)
+		if vw.err != nil {
+			return
+		}
+	}
+	vw.buf = append(vw.buf, '}')
+	vw.flushIfNeeded()
+}
+
+func (vw *valueWriter) flushIfNeeded() {
+	if len(vw.buf) >= maxValueWriterBufLen {
+		vw.flush()
+	}
+}
+
+func (vw *valueWriter) flush() {
+	if vw.err != nil || len(vw.buf) == 0 {
+		return
+	}
+	n, err := vw.w.Write(vw.buf)
+	vw.n += int64(n)
+	if err == nil && n < len(vw.buf) {
+		err = io.ErrShortWrite
+	}
+	vw.buf = vw.buf[:0]
+	vw.err = err
+}
+
+func getValueWriter(w io.Writer) *valueWriter {
+	v := valueWriterPool.Get()
+	if v == nil {
+		v = &valueWriter{}
+	}
+	vw := v.(*valueWriter)
+	vw.w = w
+	return vw
+}
+
+func putValueWriter(vw *valueWriter) {
+	vw.w = nil
+	if cap(vw.buf) > 16*maxValueWriterBufLen {
+		// Do not hold big buffers obtained when writing long strings.
+		vw.buf = nil
+	}
+	vw.buf = vw.buf[:0]
+	vw.n = 0
+	vw.err = nil
+	valueWriterPool.Put(vw)
+}
+
+var valueWriterPool sync.Pool
+
+// Writer builds JSON by appending it directly to an internal buffer.
+//
+// Unlike building Value tree via Arena and marshaling it, Writer doesn't
+// allocate values, so it is faster for write-heavy paths.
+//
+// Keys and strings are escaped. Structural errors such as Key call outside
+// object or unbalanced End calls make Writer ignore the subsequent calls.
+// The first error is returned from Err and Bytes.
+//
+// Writer cannot be used from concurrently running goroutines.
+type Writer struct {
+	b []byte
+
+	// stack contains '{' and '[' for the currently open objects and arrays.
+	stack []byte
+
+	// needComma is set if the next item must be preceded by comma.
+	needComma bool
+
+	// hasKey is set if Key has been called for the next object value.
+	hasKey bool
+
+	// done is set after the top-level value is written.
+	done bool
+
+	err error
+}
+
+// Reset resets w, so it may be re-used for building new JSON.
+func (w *Writer) Reset() {
+	w.b = w.b[:0]
+	w.stack = w.stack[:0]
+	w.needComma = false
+	w.hasKey = false
+	w.done = false
+	w.err = nil
+}
+
+// Err returns the first error occurred in w.
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// Bytes returns the built JSON.
+//
+// An error is returned if w contains an error, if there are unclosed
+// objects or arrays or if nothing has been written.
+//
+// The returned JSON is valid until the next call to Reset.
+func (w *Writer) Bytes() ([]byte, error) {
+	if w.err != nil {
+		return nil, w.err
+	}
+	if len(w.stack) > 0 {
+		return nil, fmt.Errorf("%d unclosed objects or arrays", len(w.stack))
+	}
+	if !w.done {
+		return nil, fmt.Errorf("nothing has been written")
+	}
+	return w.b, nil
+}
+
+// ObjectStart starts JSON object.
+//
+// Object entries must be written via Key call followed by a value.
+func (w *Writer) ObjectStart() {
+	if !w.beforeValue() {
+		return
+	}
+	w.b = append(w.b, '{')
+	w.stack = append(w.stack, '{')
+	w.needComma = false
+}
+
+// ObjectEnd ends the object started with ObjectStart.
+func (w *Writer) ObjectEnd() {
+	if w.err != nil {
+		return
+	}
+	if !w.inObject() {
+		w.err = fmt.Errorf("unexpected ObjectEnd call without the matching ObjectStart")
+		return
+	}
+	if w.hasKey {
+		w.err = fmt.Errorf("missing value for the last key before ObjectEnd")
+		return
+	}
+	w.b = append(w.b, '}')
+	w.stack = w.stack[:len(w.stack)-1]
+	w.afterValue()
+}
+
+// ArrayStart starts JSON array.
+func (w *Writer) ArrayStart() {
+	if !w.beforeValue() {
+		return
+	}
+	w.b = append(w.b, '[')
+	w.stack = append(w.stack, '[')
+	w.needComma = false
+}
+
+// ArrayEnd ends the array started with ArrayStart.
+func (w *Writer) ArrayEnd() {
+	if w.err != nil {
+		return
+	}
+	if len(w.stack) == 0 || w.stack[len(w.stack)-1] != '[' {
+		w.err = fmt.Errorf("unexpected ArrayEnd call without the matching ArrayStart")
+		return
+	}
+	w.b = append(w.b, ']')
+	w.stack = w.stack[:len(w.stack)-1]
+	w.afterValue()
+}
+
+// Key writes object key k.
+//
+// Key must be called only inside object and must be followed by a value.
+func (w *Writer) Key(k string) {
+	if w.err != nil {
+		return
+	}
+	if !w.inObject() {
+		w.err = fmt.Errorf("cannot write key %q outside object", k)
+		return
+	}
+	if w.hasKey {
+		w.err = fmt.Errorf("missing value for the key preceding %q", k)
+		return
+	}
+	if w.needComma {
+		w.b = append(w.b, ',')
+	}
+	w.b = escapeString(w.b, k)
+	w.b = append(w.b, ':')
+	w.hasKey = true
+}
+
+// String writes JSON string s.
+func (w *Writer) String(s string) {
+	if !w.beforeValue() {
+		return
+	}
+	w.b = escapeString(w.b, s)
+	w.afterValue()
+}
+
+// Int64 writes JSON number n.
+func (w *Writer) Int64(n int64) {
+	if !w.beforeValue() {
+		return
+	}
+	w.b = appendInt(w.b, n)
+	w.afterValue()
+}
+
+// Uint64 writes JSON number n.
+func (w *Writer) Uint64(n uint64) {
+	if !w.beforeValue() {
+		return
+	}
+	w.b = strconv.AppendUint(w.b, n, 10)
+	w.afterValue()
+}
+
+// Float64 writes JSON number f.
+//
+// NaN and Inf cannot be represented in JSON, so they result in error.
+func (w *Writer) Float64(f float64) {
+	if w.err != nil {
+		return
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		w.err = fmt.Errorf("cannot write %v as JSON number", f)
+		return
+	}
+	if !w.beforeValue() {
+		return
+	}
+	w.b = strconv.AppendFloat(w.b, f, 'g', -1, 64)
+	w.afterValue()
+}
+
+// Bool writes JSON true or false.
+func (w *Writer) Bool(b bool) {
+	if !w.beforeValue() {
+		return
+	}
+	if b {
+		w.b = append(w.b, "true"...)
+	} else {
+		w.b = append(w.b, "false"...)
+	}
+	w.afterValue()
+}
+
+// Null writes JSON null.
+func (w *Writer) Null() {
+	if !w.beforeValue() {
+		return
+	}
+	w.b = append(w.b, "null"...)
+	w.afterValue()
+}
+
+// Raw writes JSON value b as is.
+//
+// b isn't validated, so it must contain a single valid JSON value.
+func (w *Writer) Raw(b []byte) {
+	if w.err != nil {
+		return
+	}
+	if len(b) == 0 {
+		w.err = fmt.Errorf("cannot write empty raw value")
+		return
+	}
+	if !w.beforeValue() {
+		return
+	}
+	w.b = append(w.b, b...)
+	w.afterValue()
+}
+
+func (w *Writer) inObject() bool {
+	return len(w.stack) > 0 && w.stack[len(w.stack)-1] == '{'
+}
+
+// beforeValue prepares w for writing the next value.
+//
+// false is returned if the value cannot be written.
+func (w *Writer) beforeValue() bool {
+	if w.err != nil {
+		return false
+	}
+	if len(w.stack) == 0 {
+		if w.done {
+			w.err = fmt.Errorf("cannot write more than one top-level value")
+			return false
+		}
+		return true
+	}
+	if w.inObject() {
+		if !w.hasKey {
+			w.err = fmt.Errorf("missing Key call before object value")
+			return false
+		}
+		w.hasKey = false
+		return true
+	}
+	if w.needComma {
+		w.b = append(w.b, ',')
+	}
+	return true
+}
+
+func (w *Writer) afterValue() {
+	w.needComma = true
+	if len(w.stack) == 0 {
+		w.done = true
+	}
+}
@@ -0,0 +1,288 @@
+package fastjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestValueWriteTo(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		v := MustParse(s)
+		expected := v.MarshalTo(nil)
+
+		var bb bytes.Buffer
+		n, err := v.WriteTo(&bb)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if n != int64(len(expected)) {
+			t.Fatalf("unexpected number of bytes written; got %d; want %d", n, len(expected))
+		}
+		if bb.String() != string(expected) {
+			t.Fatalf("unexpected data written\ngot\n%s\nwant\n%s", bb.String(), expected)
+		}
+	}
+
+	f(`null`)
+	f(`"foo\nbar"`)
+	f(`[]`)
+	f(`{}`)
+	f(`[1,"x",true,false,null,{"a":[]}]`)
+	f(`{"fo\no":"bar","x":[1,2,{"y":"z"}],"b":true}`)
+	f(`[` + strings.Repeat(`{"foo":"barbazqwertyuiop","x":[1,2,3]},`, 1000) + `null]`)
+	f(twitterFixture)
+	f(canadaFixture)
+}
+
+func TestObjectWriteTo(t *testing.T) {
+	o := MustParse(`{"fo\no":"bar","x":[1,2,3]}`).GetObject()
+	o.Set("new", MustParse(`"x\"y"`))
+	expected := o.MarshalTo(nil)
+
+	var bb bytes.Buffer
+	n, err := o.WriteTo(&bb)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n != int64(len(expected)) {
+		t.Fatalf("unexpected number of bytes written; got %d; want %d", n, len(expected))
+	}
+	if bb.String() != string(expected) {
+		t.Fatalf("unexpected data written\ngot\n%s\nwant\n%s", bb.String(), expected)
+	}
+}
+
+func TestValueWriteToError(t *testing.T) {
+	v := MustParse(canadaFixture)
+	for _, limit := range []int{0, 1, 100, 5000, 100000} {
+		t.Run(fmt.Sprintf("limit_%d", limit), func(t *testing.T) {
+			w := &limitedWriter{limit: limit}
+			n, err := v.WriteTo(w)
+			if err != errLimitReached {
+				t.Fatalf("unexpected error; got %v; want %v", err, errLimitReached)
+			}
+			if n != int64(limit) {
+				t.Fatalf("unexpected number of bytes written; got %d; want %d", n, limit)
+			}
+			if w.calls > 1 {
+				t.Fatalf("writing must stop after the first error; got %d calls after the error", w.calls-1)
+			}
+		})
+	}
+}
+
+type limitedWriter struct {
+	limit int
+	n     int
+	calls int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.n+len(p) <= w.limit {
+		w.n += len(p)
+		return len(p), nil
+	}
+	w.calls++
+	n := w.limit - w.n
+	w.n = w.limit
+	return n, errLimitReached
+}
+
+var errLimitReached = errors.New("limit reached")
+
+func TestWriterMatchesArena(t *testing.T) {
+	var w Writer
+	var a Arena
+
+	for i := 0; i < 2; i++ {
+		// The second iteration verifies Writer re-use after Reset.
+		w.Reset()
+		w.ObjectStart()
+		w.Key("str")
+		w.String("foo\n\"bar\"\x00\xff")
+		w.Key("ke\ty")
+		w.Int64(-123)
+		w.Key("u")
+		w.Uint64(18446744073709551615)
+		w.Key("f")
+		w.Float64(1.25e-7)
+		w.Key("t")
+		w.Bool(true)
+		w.Key("false")
+		w.Bool(false)
+		w.Key("n")
+		w.Null()
+		w.Key("raw")
+		w.Raw([]byte(`{"x":[1,2]}`))
+		w.Key("arr")
+		w.ArrayStart()
+		w.Int64(1)
+		w.ArrayStart()
+		w.ArrayEnd()
+		w.ObjectStart()
+		w.ObjectEnd()
+		w.String("x")
+		w.ArrayEnd()
+		w.Key("empty")
+		w.ObjectStart()
+		w.ObjectEnd()
+		w.ObjectEnd()
+		b, err := w.Bytes()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		a.Reset()
+		o := a.NewObject()
+		o.Set("str", a.NewString("foo\n\"bar\"\x00\xff"))
+		o.Set("ke\ty", a.NewNumberInt64(-123))
+		o.Set("u", a.NewNumberUint64(18446744073709551615))
+		o.Set("f", a.NewNumberFloat64(1.25e-7))
+		o.Set("t", a.NewTrue())
+		o.Set("false", a.NewFalse())
+		o.Set("n", a.NewNull())
+		o.Set("raw", MustParse(`{"x":[1,2]}`))
+		arr := a.NewArray()
+		arr.SetArrayItem(0, a.NewNumberInt(1))
+		arr.SetArrayItem(1, a.NewArray())
+		arr.SetArrayItem(2, a.NewObject())
+		arr.SetArrayItem(3, a.NewString("x"))
+		o.Set("arr", arr)
+		o.Set("empty", a.NewObject())
+		expected := o.MarshalTo(nil)
+
+		if string(b) != string(expected) {
+			t.Fatalf("unexpected JSON\ngot\n%s\nwant\n%s", b, expected)
+		}
+		if err := ValidateBytes(b); err != nil {
+			t.Fatalf("invalid JSON built: %s", err)
+		}
+	}
+}
+
+func TestWriterTopLevel(t *testing.T) {
+	f := func(write func(w *Writer), expected string) {
+		t.Helper()
+		var w Writer
+		write(&w)
+		b, err := w.Bytes()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if string(b) != expected {
+			t.Fatalf("unexpected JSON; got %s; want %s", b, expected)
+		}
+	}
+
+	f(func(w *Writer) { w.Null() }, `null`)
+	f(func(w *Writer) { w.String("x") }, `"x"`)
+	f(func(w *Writer) { w.Int64(0) }, `0`)
+	f(func(w *Writer) { w.Raw([]byte(`[1]`)) }, `[1]`)
+	f(func(w *Writer) {
+		w.ArrayStart()
+		w.Null()
+		w.Bool(true)
+		w.ArrayEnd()
+	}, `[null,true]`)
+}
+
+func TestWriterError(t *testing.T) {
+	f := func(write func(w *Writer), errSubstr string) {
+		t.Helper()
+		var w Writer
+		write(&w)
+
+		_, err := w.Bytes()
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if !strings.Contains(err.Error(), errSubstr) {
+			t.Fatalf("unexpected error %q; it must contain %q", err, errSubstr)
+		}
+
+		// Incomplete JSON is detected only by Bytes.
+		incomplete := errSubstr == "unclosed" || errSubstr == "nothing"
+		if incomplete != (w.Err() == nil) {
+			t.Fatalf("unexpected Err result: %v", w.Err())
+		}
+		if errW := w.Err(); errW != nil {
+			// Subsequent calls must be ignored after the error.
+			w.ObjectEnd()
+			w.ArrayEnd()
+			w.Key("foo")
+			w.String("bar")
+			if w.Err() != errW {
+				t.Fatalf("unexpected error change after the first error; got %q; want %q", w.Err(), errW)
+			}
+		}
+
+		// Reset must clear the error.
+		w.Reset()
+		w.Null()
+		if b, err := w.Bytes(); err != nil || string(b) != "null" {
+			t.Fatalf("unexpected result after Reset; got %q, %v; want %q, nil", b, err, "null")
+		}
+	}
+
+	// Nothing written
+	f(func(w *Writer) {}, "nothing")
+
+	// Unclosed containers
+	f(func(w *Writer) { w.ObjectStart() }, "unclosed")
+	f(func(w *Writer) {
+		w.ArrayStart()
+		w.ArrayStart()
+		w.ArrayEnd()
+	}, "unclosed")
+
+	// Unbalanced End calls
+	f(func(w *Writer) { w.ObjectEnd() }, "ObjectEnd")
+	f(func(w *Writer) { w.ArrayEnd() }, "ArrayEnd")
+	f(func(w *Writer) {
+		w.ArrayStart()
+		w.ObjectEnd()
+	}, "ObjectEnd")
+	f(func(w *Writer) {
+		w.ObjectStart()
+		w.ArrayEnd()
+	}, "ArrayEnd")
+
+	// Keys outside object
+	f(func(w *Writer) { w.Key("foo") }, "outside object")
+	f(func(w *Writer) {
+		w.ArrayStart()
+		w.Key("foo")
+	}, "outside object")
+
+	// Missing keys and values
+	f(func(w *Writer) {
+		w.ObjectStart()
+		w.Int64(1)
+	}, "missing Key")
+	f(func(w *Writer) {
+		w.ObjectStart()
+		w.Key("foo")
+		w.Key("bar")
+	}, "missing value")
+	f(func(w *Writer) {
+		w.ObjectStart()
+		w.Key("foo")
+		w.ObjectEnd()
+	}, "missing value")
+
+	// Multiple top-level values
+	f(func(w *Writer) {
+		w.Null()
+		w.Null()
+	}, "top-level")
+
+	// Invalid values
+	f(func(w *Writer) { w.Float64(math.NaN()) }, "JSON number")
+	f(func(w *Writer) { w.Float64(math.Inf(-1)) }, "JSON number")
+	f(func(w *Writer) { w.Raw(nil) }, "empty raw value")
+}
@@ -0,0 +1,83 @@
+package fastjson
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func BenchmarkWriterObject(b *testing.B) {
+	const fieldsCount = 50
+	keys := make([]string, fieldsCount)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("metric_%d", i)
+	}
+
+	var w Writer
+	benchWriteObject(&w, keys)
+	data, err := w.Bytes()
+	if err != nil {
+		b.Fatalf("unexpected error: %s", err)
+	}
+
+	b.Run("Writer", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			var w Writer
+			var sink int
+			for pb.Next() {
+				w.Reset()
+				benchWriteObject(&w, keys)
+				b, err := w.Bytes()
+				if err != nil {
+					panic(fmt.Errorf("unexpected error: %s", err))
+				}
+				sink += len(b)
+			}
+			atomic.AddUint64(&Sink, uint64(sink))
+		})
+	})
+	b.Run("Arena", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			var a Arena
+			var buf []byte
+			var sink int
+			for pb.Next() {
+				o := a.NewObject()
+				for i, k := range keys {
+					switch i % 3 {
+					case 0:
+						o.Set(k, a.NewNumberInt64(int64(i)))
+					case 1:
+						o.Set(k, a.NewNumberFloat64(float64(i)+0.5))
+					default:
+						o.Set(k, a.NewString(k))
+					}
+				}
+				buf = o.MarshalTo(buf[:0])
+				a.Reset()
+				sink += len(buf)
+			}
+			atomic.AddUint64(&Sink, uint64(sink))
+		})
+	})
+}
+
+func benchWriteObject(w *Writer, keys []string) {
+	w.ObjectStart()
+	for i, k := range keys {
+		w.Key(k)
+		switch i % 3 {
+		case 0:
+			w.Int64(int64(i))
+		case 1:
+			w.Float64(float64(i) + 0.5)
+		default:
+			w.String(k)
+		}
+	}
+	w.ObjectEnd()
+}